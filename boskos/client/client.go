@@ -118,6 +118,7 @@ func (c *Client) Acquire(rtype, state, dest string) (*common.Resource, error) {
 	defer c.lock.Unlock()
 	if r != nil {
 		c.storage.Add(*r)
+		c.logger(r.Name, dest).WithField("type", r.Type).Info("Acquired boskos resource")
 	}
 
 	return r, nil
@@ -159,6 +160,7 @@ func (c *Client) AcquireByState(state, dest string, names []string) ([]common.Re
 	defer c.lock.Unlock()
 	for _, r := range resources {
 		c.storage.Add(r)
+		c.logger(r.Name, dest).WithField("type", r.Type).Info("Acquired boskos resource")
 	}
 	return resources, nil
 }
@@ -387,7 +389,22 @@ func (c *Client) acquireByState(state, dest string, names []string) ([]common.Re
 	return nil, fmt.Errorf("status %s, status code %v", resp.Status, resp.StatusCode)
 }
 
+// logger returns a logger for records of leases, which Spyglass's boskos lens reads from job logs.
+func (c *Client) logger(name, state string) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{"resource": name, "state": state, "owner": c.owner})
+}
+
 func (c *Client) release(name, dest string) error {
+	err := c.releaseResource(name, dest)
+	if err != nil {
+		c.logger(name, dest).WithError(err).Warning("Failed to release boskos resource")
+	} else {
+		c.logger(name, dest).Info("Released boskos resource")
+	}
+	return err
+}
+
+func (c *Client) releaseResource(name, dest string) error {
 	resp, err := c.httpPost(fmt.Sprintf("%v/release?name=%v&dest=%v&owner=%v",
 		c.url, name, dest, c.owner), "", nil)
 	if err != nil {
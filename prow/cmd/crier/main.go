@@ -40,6 +40,7 @@ import (
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/logrusutil"
 	pubsubreporter "k8s.io/test-infra/prow/pubsub/reporter"
+	snapshotreporter "k8s.io/test-infra/prow/spyglass/reporter"
 )
 
 const (
@@ -61,6 +62,9 @@ type options struct {
 	pubsubWorkers int
 	githubWorkers int
 
+	spyglassSnapshotWorkers int
+	spyglassSnapshotToken   string
+
 	dryrun      bool
 	reportAgent string
 }
@@ -76,7 +80,7 @@ func (o *options) validate() error {
 		o.gerritWorkers = 1
 	}
 
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.spyglassSnapshotWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -96,6 +100,14 @@ func (o *options) validate() error {
 		}
 	}
 
+	if o.spyglassSnapshotWorkers > 0 && o.client.DeckURI == "" {
+		return errors.New("--deck-url must be set")
+	}
+
+	if o.spyglassSnapshotWorkers > 0 && o.spyglassSnapshotToken == "" {
+		return errors.New("--spyglass-snapshot-token-file must be set")
+	}
+
 	if err := o.client.Validate(o.dryrun); err != nil {
 		return err
 	}
@@ -109,6 +121,8 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.gerritWorkers, "gerrit-workers", 0, "Number of gerrit report workers (0 means disabled)")
 	fs.IntVar(&o.pubsubWorkers, "pubsub-workers", 0, "Number of pubsub report workers (0 means disabled)")
 	fs.IntVar(&o.githubWorkers, "github-workers", 0, "Number of github report workers (0 means disabled)")
+	fs.IntVar(&o.spyglassSnapshotWorkers, "spyglass-snapshot-workers", 0, "Number of spyglass snapshot report workers, which ask the Deck at --deck-url to snapshot lenses of finished jobs (0 means disabled)")
+	fs.StringVar(&o.spyglassSnapshotToken, "spyglass-snapshot-token-file", "", "Path to the file containing the token Deck accepts for spyglass snapshot requests")
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github only)")
 
 	fs.StringVar(&o.configPath, "config-path", "", "Path to config.yaml.")
@@ -220,6 +234,24 @@ func main() {
 				wg))
 	}
 
+	if o.spyglassSnapshotWorkers > 0 {
+		secretAgent := &secret.Agent{}
+		if err := secretAgent.Start([]string{o.spyglassSnapshotToken}); err != nil {
+			logrus.WithError(err).Fatal("Error starting secrets agent")
+		}
+
+		snapshotReporter := snapshotreporter.NewReporter(o.client.DeckURI, secretAgent.GetTokenGenerator(o.spyglassSnapshotToken))
+		controllers = append(
+			controllers,
+			crier.NewController(
+				prowjobClientset,
+				kube.RateLimiter(snapshotReporter.GetName()),
+				prowjobInformerFactory.Prow().V1().ProwJobs(),
+				snapshotReporter,
+				o.spyglassSnapshotWorkers,
+				wg))
+	}
+
 	if len(controllers) == 0 {
 		logrus.Fatalf("should have at least one controller to start crier.")
 	}
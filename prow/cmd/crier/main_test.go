@@ -62,6 +62,25 @@ func TestOptions(t *testing.T) {
 				configPath: "foo",
 			},
 		},
+		{
+			name: "spyglass snapshot missing --deck-url, reject",
+			args: []string{"--spyglass-snapshot-workers=2", "--spyglass-snapshot-token-file=/token", "--config-path=foo"},
+		},
+		{
+			name: "spyglass snapshot missing --spyglass-snapshot-token-file, reject",
+			args: []string{"--spyglass-snapshot-workers=2", "--deck-url=http://deck", "--config-path=foo"},
+		},
+		{
+			name: "spyglass snapshot",
+			args: []string{"--spyglass-snapshot-workers=2", "--deck-url=http://deck", "--spyglass-snapshot-token-file=/token", "--config-path=foo"},
+			expected: &options{
+				spyglassSnapshotWorkers: 2,
+				spyglassSnapshotToken:   "/token",
+				client:                  flagutil.ExperimentalKubernetesOptions{DeckURI: "http://deck"},
+				gerritProjects:          gerritclient.ProjectsFlag{},
+				configPath:              "foo",
+			},
+		},
 	}
 
 	for _, tc := range cases {
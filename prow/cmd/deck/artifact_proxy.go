@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/htmlreport"
+)
+
+// reportPolicy is the content security policy for HTML reports. The sandbox gives the report a
+// unique origin, so it can't reach Deck's cookies or APIs, and the report may not load anything
+// from anywhere, so it can't send what it has elsewhere either.
+const reportPolicy = "sandbox allow-scripts allow-popups; default-src 'none'; " +
+	"script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src data:; font-src data:; media-src data:; " +
+	"base-uri 'none'; form-action 'none'; frame-ancestors 'self'"
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// mediaTypes covers extensions that the mime package doesn't reliably know about.
+var mediaTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+}
+
+// handleArtifactProxy streams the raw bytes of a single artifact, honouring Range requests
+// so that browsers can seek through large files like videos without downloading all of them.
+// Links to the artifacts of private buckets point here, since users can only read them through
+// Deck.
+// Query params:
+// - src: required, specifies the job source from which to fetch the artifact
+// - artifact: required, the path of the artifact relative to the job
+func handleArtifactProxy(sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := r.URL.Query().Get("src")
+		name := r.URL.Query().Get("artifact")
+		if src == "" || name == "" {
+			http.Error(w, "Both src and artifact must be specified.", http.StatusBadRequest)
+			return
+		}
+		artifacts, err := sg.FetchArtifacts(src, "", cfg().Deck.Spyglass.SizeLimit, []string{name})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to retrieve artifact: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(artifacts) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		serveArtifact(w, r, artifacts[0])
+	}
+}
+
+// handleArtifactStat serves what storage knows about many artifacts of a run at once, such as their
+// sizes, content types and generations, as JSON, without a request to storage for each.
+// Query params:
+// - src: required, specifies the job source whose artifacts are stated
+// - artifact: optional and repeatable, the paths of the artifacts relative to the job. Every
+//   artifact of the run is stated if none are given.
+func handleArtifactStat(sg *spyglass.Spyglass) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := r.URL.Query().Get("src")
+		if src == "" {
+			http.Error(w, "Missing src parameter.", http.StatusBadRequest)
+			return
+		}
+		stats, err := sg.StatArtifacts(src, r.URL.Query()["artifact"])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to stat artifacts: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			logrus.WithError(err).WithField("src", src).Warning("Failed to write artifact stats.")
+		}
+	}
+}
+
+// handleLensArtifacts serves a page of the artifacts of a run that a lens matches, as JSON, for
+// pages whose lenses match more artifacts than they are shown at once to load the rest.
+// Query params:
+// - src: required, specifies the job source of the run
+// - lens: required, the name of the lens
+// - page: required, the page of artifacts, counted from zero
+func handleLensArtifacts(sg *spyglass.Spyglass) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := r.URL.Query().Get("src")
+		lens := r.URL.Query().Get("lens")
+		if src == "" || lens == "" {
+			http.Error(w, "Both src and lens must be specified.", http.StatusBadRequest)
+			return
+		}
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 0 {
+			http.Error(w, fmt.Sprintf("Invalid page %q.", r.URL.Query().Get("page")), http.StatusBadRequest)
+			return
+		}
+		artifacts, err := sg.ArtifactPageFor(src, lens, page)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(artifacts); err != nil {
+			logrus.WithError(err).WithField("src", src).Warning("Failed to write lens artifacts.")
+		}
+	}
+}
+
+// serveArtifact writes the artifact to w, using ranged reads where the artifact supports them.
+func serveArtifact(w http.ResponseWriter, r *http.Request, artifact lenses.Artifact) {
+	name := artifact.JobPath()
+	size, err := artifact.Size()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get artifact size: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var content io.ReadSeeker = &chunkedSeeker{artifact: artifact, size: size}
+	head := make([]byte, sniffLen)
+	if size < sniffLen {
+		head = head[:size]
+	}
+	n, err := artifact.ReadAt(head, 0)
+	switch {
+	case err == lenses.ErrGzipOffsetRead:
+		// Compressed objects can't be read at an offset, so we're limited to
+		// serving the whole thing from memory.
+		data, err := artifact.ReadAll()
+		if err == lenses.ErrFileTooLarge {
+			http.Error(w, "Compressed artifact is too large to serve.", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read artifact: %v", err), http.StatusInternalServerError)
+			return
+		}
+		head = data
+		if len(head) > sniffLen {
+			head = head[:sniffLen]
+		}
+		content = bytes.NewReader(data)
+	case err != nil && err != io.EOF:
+		http.Error(w, fmt.Sprintf("Failed to read artifact: %v", err), http.StatusInternalServerError)
+		return
+	default:
+		head = head[:n]
+	}
+
+	// The artifact is served from our own origin, so we must not let it be interpreted
+	// as anything active, however it's named.
+	w.Header().Set("Content-Type", safeContentType(name, head))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	http.ServeContent(w, r, path.Base(name), time.Time{}, content)
+}
+
+// chunkedSeeker reads an artifact a few megabytes at a time from wherever it was last seeked to,
+// so that serving a range of a large artifact takes a few reads from storage rather than one for
+// each of the small reads http.ServeContent makes.
+type chunkedSeeker struct {
+	artifact lenses.Artifact
+	size     int64
+	offset   int64
+	reader   io.Reader
+}
+
+func (s *chunkedSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.reader == nil {
+		s.reader = lenses.NewSectionReader(s.artifact, s.offset, s.size-s.offset)
+	}
+	n, err := s.reader.Read(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+func (s *chunkedSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	if offset != s.offset {
+		s.offset = offset
+		s.reader = nil
+	}
+	return offset, nil
+}
+
+// safeContentType picks a content type for the artifact that can't be used to run script in our origin.
+func safeContentType(name string, head []byte) string {
+	ext := strings.ToLower(path.Ext(name))
+	contentType := mediaTypes[ext]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(ext)
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	switch {
+	case mediaType == "image/svg+xml":
+		return "text/plain; charset=utf-8"
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "image/"):
+		return mediaType
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "application/xml":
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// handleReportProxy serves a self-contained HTML report, such as a coverage report, so that the
+// HTML report lens can display it in a frame. Reports over the lens's size limit are refused.
+// Query params:
+// - src: required, specifies the job source from which to fetch the report
+// - artifact: required, the path of the report relative to the job
+func handleReportProxy(sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := r.URL.Query().Get("src")
+		name := r.URL.Query().Get("artifact")
+		if src == "" || name == "" {
+			http.Error(w, "Both src and artifact must be specified.", http.StatusBadRequest)
+			return
+		}
+		if !htmlreport.IsReport(name) {
+			http.Error(w, "Only HTML artifacts can be served as reports.", http.StatusBadRequest)
+			return
+		}
+		artifacts, err := sg.FetchArtifacts(src, "", cfg().Deck.Spyglass.SizeLimit, []string{name})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to retrieve artifact: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(artifacts) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		serveReport(w, r, artifacts[0], htmlreport.MaxBytes(sg.LensConfig(htmlreport.Name, src)))
+	}
+}
+
+// serveReport writes the report to w as HTML, under a policy that keeps it from doing harm.
+func serveReport(w http.ResponseWriter, r *http.Request, artifact lenses.Artifact, maxBytes int64) {
+	size, err := artifact.Size()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get artifact size: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if size > maxBytes {
+		http.Error(w, "Report is too large to display.", http.StatusRequestEntityTooLarge)
+		return
+	}
+	// Compressed reports may be larger once decompressed, so read one byte past the limit to
+	// tell whether they are.
+	content, err := artifact.ReadAtMost(maxBytes + 1)
+	if err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("Failed to read artifact: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if int64(len(content)) > maxBytes {
+		http.Error(w, "Report is too large to display.", http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", reportPolicy)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.ServeContent(w, r, path.Base(artifact.JobPath()), time.Time{}, bytes.NewReader(content))
+}
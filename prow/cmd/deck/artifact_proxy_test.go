@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+type fakeArtifact struct {
+	path    string
+	content []byte
+	gzipped bool
+}
+
+func (fa *fakeArtifact) JobPath() string                      { return fa.path }
+func (fa *fakeArtifact) Size() (int64, error)                 { return int64(len(fa.content)), nil }
+func (fa *fakeArtifact) CanonicalLink() string                { return "" }
+func (fa *fakeArtifact) UseContext(ctx context.Context) error { return nil }
+func (fa *fakeArtifact) ReadAll() ([]byte, error)             { return fa.content, nil }
+func (fa *fakeArtifact) ReadAtMost(n int64) ([]byte, error)   { return fa.content, nil }
+func (fa *fakeArtifact) ReadTail(n int64) ([]byte, error)     { return fa.content, nil }
+func (fa *fakeArtifact) ReadAt(b []byte, off int64) (int, error) {
+	if fa.gzipped {
+		return 0, lenses.ErrGzipOffsetRead
+	}
+	return bytes.NewReader(fa.content).ReadAt(b, off)
+}
+
+func TestServeArtifact(t *testing.T) {
+	testCases := []struct {
+		name            string
+		artifact        *fakeArtifact
+		rangeHeader     string
+		expectedStatus  int
+		expectedType    string
+		expectedContent string
+	}{
+		{
+			name:            "whole video",
+			artifact:        &fakeArtifact{path: "artifacts/video.webm", content: []byte("0123456789")},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "video/webm",
+			expectedContent: "0123456789",
+		},
+		{
+			name:            "byte range",
+			artifact:        &fakeArtifact{path: "artifacts/video.mp4", content: []byte("0123456789")},
+			rangeHeader:     "bytes=2-5",
+			expectedStatus:  http.StatusPartialContent,
+			expectedType:    "video/mp4",
+			expectedContent: "2345",
+		},
+		{
+			name:            "gzipped artifacts are served from memory",
+			artifact:        &fakeArtifact{path: "artifacts/video.webm", content: []byte("0123456789"), gzipped: true},
+			rangeHeader:     "bytes=8-",
+			expectedStatus:  http.StatusPartialContent,
+			expectedType:    "video/webm",
+			expectedContent: "89",
+		},
+		{
+			name:            "html is not served as html",
+			artifact:        &fakeArtifact{path: "artifacts/report.html", content: []byte("<script>alert(1)</script>")},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "text/plain; charset=utf-8",
+			expectedContent: "<script>alert(1)</script>",
+		},
+		{
+			name:            "svg is not served as an image",
+			artifact:        &fakeArtifact{path: "artifacts/graph.svg", content: []byte("<svg></svg>")},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "text/plain; charset=utf-8",
+			expectedContent: "<svg></svg>",
+		},
+		{
+			name:            "unknown content is served as a download",
+			artifact:        &fakeArtifact{path: "artifacts/core", content: []byte{0, 1, 2, 3}},
+			expectedStatus:  http.StatusOK,
+			expectedType:    "application/octet-stream",
+			expectedContent: "\x00\x01\x02\x03",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/spyglass/artifact", nil)
+			if tc.rangeHeader != "" {
+				req.Header.Set("Range", tc.rangeHeader)
+			}
+			rr := httptest.NewRecorder()
+			serveArtifact(rr, req, tc.artifact)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if actual := rr.Header().Get("Content-Type"); actual != tc.expectedType {
+				t.Errorf("expected content type %q, got %q", tc.expectedType, actual)
+			}
+			if rr.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("expected nosniff to be set")
+			}
+			body, _ := ioutil.ReadAll(rr.Body)
+			if string(body) != tc.expectedContent {
+				t.Errorf("expected content %q, got %q", tc.expectedContent, string(body))
+			}
+		})
+	}
+}
+
+// countingArtifact counts the reads made of an artifact at an offset.
+type countingArtifact struct {
+	fakeArtifact
+	reads int
+}
+
+func (ca *countingArtifact) ReadAt(b []byte, off int64) (int, error) {
+	ca.reads++
+	return ca.fakeArtifact.ReadAt(b, off)
+}
+
+func TestServeArtifactRangeOfLargeArtifact(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	artifact := &countingArtifact{fakeArtifact: fakeArtifact{path: "artifacts/video.webm", content: content}}
+	req := httptest.NewRequest(http.MethodGet, "/spyglass/artifact", nil)
+	req.Header.Set("Range", "bytes=100-900099")
+	rr := httptest.NewRecorder()
+	serveArtifact(rr, req, artifact)
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, rr.Code)
+	}
+	if !bytes.Equal(rr.Body.Bytes(), content[100:900100]) {
+		t.Errorf("expected bytes 100-900099 of the artifact, got %d bytes", rr.Body.Len())
+	}
+	// One read sniffs the content type, and one more reads the range.
+	if artifact.reads > 2 {
+		t.Errorf("expected the range to be read at once, but the artifact was read %d times", artifact.reads)
+	}
+}
+
+func TestServeReport(t *testing.T) {
+	testCases := []struct {
+		name           string
+		artifact       *fakeArtifact
+		maxBytes       int64
+		expectedStatus int
+	}{
+		{
+			name:           "report within the limit",
+			artifact:       &fakeArtifact{path: "artifacts/coverage.html", content: []byte("<html>covered</html>")},
+			maxBytes:       100,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "report over the limit",
+			artifact:       &fakeArtifact{path: "artifacts/coverage.html", content: []byte("<html>covered</html>")},
+			maxBytes:       10,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/spyglass/report", nil)
+			rr := httptest.NewRecorder()
+			serveReport(rr, req, tc.artifact, tc.maxBytes)
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if actual := rr.Header().Get("Content-Type"); actual != "text/html; charset=utf-8" {
+				t.Errorf("expected the report to be served as HTML, got %q", actual)
+			}
+			if actual := rr.Header().Get("Content-Security-Policy"); actual != reportPolicy {
+				t.Errorf("expected the report policy, got %q", actual)
+			}
+			body, _ := ioutil.ReadAll(rr.Body)
+			if string(body) != string(tc.artifact.content) {
+				t.Errorf("expected content %q, got %q", string(tc.artifact.content), string(body))
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"google.golang.org/api/iterator"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass"
 )
 
 const (
@@ -345,7 +346,7 @@ func (a int64slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a int64slice) Less(i, j int) bool { return a[i] < a[j] }
 
 // Gets job history from the GCS bucket specified in config.
-func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Client) (jobHistoryTemplate, error) {
+func getJobHistory(url *url.URL, config *config.Config, sg *spyglass.Spyglass) (jobHistoryTemplate, error) {
 	start := time.Now()
 	tmpl := jobHistoryTemplate{}
 
@@ -354,7 +355,11 @@ func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Clien
 		return tmpl, fmt.Errorf("invalid url %s: %v", url.String(), err)
 	}
 	tmpl.Name = root
-	bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+	bkt, err := sg.Bucket(bucketName)
+	if err != nil {
+		return tmpl, err
+	}
+	bucket := gcsBucket{bucketName, bkt}
 
 	latest, err := readLatestBuild(bucket, root)
 	if err != nil {
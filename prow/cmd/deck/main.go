@@ -37,6 +37,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/sessions"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
@@ -63,9 +64,49 @@ import (
 	// Import standard spyglass viewers
 
 	"k8s.io/test-infra/prow/spyglass/lenses"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/apicoverage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/audit"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/benchstat"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/bep"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/boskos"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/buildlog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/changedfiles"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/diff"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/dmesg"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/events"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/evtx"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/fuzz"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/ginkgo"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/goroutines"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/gotest"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/htmlreport"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/imagebuild"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/images"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/jsonview"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/junit"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/kindlogs"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/licenses"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/links"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/markdown"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/metadata"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/oom"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/perftests"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/pprof"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/prometheus"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/protoview"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/pytest"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/resources"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/sarif"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/sonobuoy"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/syslog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/tap"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/terraform"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/timeline"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/trace"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/triage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/video"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/webtests"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/yamlview"
 )
 
 type options struct {
@@ -85,7 +126,13 @@ type options struct {
 	templateFilesLocation string
 	spyglass              bool
 	spyglassFilesLocation string
+	spyglassAPITokenFile  string
+	spyglassSnapshotFile  string
+	spyglassPageCacheMB   int
+	spyglassRenderWorkers int
+	spyglassRenderQueue   int
 	gcsCredentialsFile    string
+	metricsPort           int
 }
 
 func (o *options) Validate() error {
@@ -104,6 +151,9 @@ func (o *options) Validate() error {
 			return errors.New("an OAuth URL was provided but required flag --cookie-secret was unset")
 		}
 	}
+	if o.metricsPort < 0 || o.metricsPort > 65535 {
+		return fmt.Errorf("invalid --metrics-port %d: expected a port between 1 and 65535, or 0 not to serve metrics", o.metricsPort)
+	}
 	return nil
 }
 
@@ -124,9 +174,15 @@ func gatherOptions() options {
 	fs.StringVar(&o.pregeneratedData, "pregenerated-data", "", "Use API output from another prow instance. Used by the prow/cmd/deck/runlocal script")
 	fs.BoolVar(&o.spyglass, "spyglass", false, "Use Prow built-in job viewing instead of Gubernator")
 	fs.StringVar(&o.spyglassFilesLocation, "spyglass-files-location", "/lenses", "Location of the static files for spyglass.")
+	fs.StringVar(&o.spyglassAPITokenFile, "spyglass-api-token-file", "", "Path to a file of tokens, one per line, that clients of the Spyglass JSON API authenticate with. If empty, the API is not served.")
+	fs.StringVar(&o.spyglassSnapshotFile, "spyglass-snapshot-token-file", "", "Path to a file of tokens, one per line, that crier's spyglass snapshot reporter authenticates with. If empty, snapshots can't be requested.")
+	fs.IntVar(&o.spyglassPageCacheMB, "spyglass-page-cache-size", 100, "Megabytes of Spyglass pages rendered for finished runs to keep in memory and serve again. If 0, pages are always rendered.")
+	fs.IntVar(&o.spyglassRenderWorkers, "spyglass-render-workers", 20, "Number of Spyglass lenses to render at once. If 0, renders are not limited.")
+	fs.IntVar(&o.spyglassRenderQueue, "spyglass-render-queue", 500, "Number of Spyglass lens renders that can wait for a worker before more are rejected.")
 	fs.StringVar(&o.staticFilesLocation, "static-files-location", "/static", "Path to the static files")
 	fs.StringVar(&o.templateFilesLocation, "template-files-location", "/template", "Path to the template files")
 	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file")
+	fs.IntVar(&o.metricsPort, "metrics-port", 9090, "Port to serve Prometheus metrics on at /metrics. If 0, metrics are not served.")
 	o.kubernetes.AddFlags(fs)
 	fs.Parse(os.Args[1:])
 	o.configPath = config.ConfigPath(o.configPath)
@@ -149,6 +205,14 @@ func main() {
 
 	pjutil.ServePProf()
 
+	if o.metricsPort != 0 {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			logrus.WithError(http.ListenAndServe(":"+strconv.Itoa(o.metricsPort), metricsMux)).Fatal("ListenAndServe returned while serving metrics.")
+		}()
+	}
+
 	// setup config agent, pod log clients etc.
 	configAgent := &config.Agent{}
 	if err := configAgent.Start(o.configPath, o.jobConfigPath); err != nil {
@@ -214,7 +278,7 @@ func localOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.Serve
 	mux.Handle("/github-login", gziphandler.GzipHandler(handleSimpleTemplate(o, cfg, "github-login.html", nil)))
 
 	if o.spyglass {
-		initSpyglass(cfg, o, mux, nil)
+		initSpyglass(cfg, o, mux, nil, nil)
 	}
 
 	return mux
@@ -292,16 +356,9 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 	ja.Start()
 
 	// setup prod only handlers
-	mux.Handle("/data.js", gziphandler.GzipHandler(handleData(ja)))
-	mux.Handle("/prowjobs.js", gziphandler.GzipHandler(handleProwJobs(ja)))
 	mux.Handle("/badge.svg", gziphandler.GzipHandler(handleBadge(ja)))
-	mux.Handle("/log", gziphandler.GzipHandler(handleLog(ja)))
 	mux.Handle("/rerun", gziphandler.GzipHandler(handleRerun(prowJobClient)))
 
-	if o.spyglass {
-		initSpyglass(cfg, o, mux, ja)
-	}
-
 	if o.hookURL != "" {
 		mux.Handle("/plugin-help.js",
 			gziphandler.GzipHandler(handlePluginHelp(newHelpAgent(o.hookURL))))
@@ -323,6 +380,7 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 	}
 
 	// Enable Git OAuth feature if oauthURL is provided.
+	var goac *config.GitHubOAuthConfig
 	if o.oauthURL != "" {
 		githubOAuthConfigRaw, err := loadToken(o.githubOAuthConfigFile)
 		if err != nil {
@@ -351,6 +409,7 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 		}
 		cookie := sessions.NewCookieStore(decodedSecret)
 		githubOAuthConfig.InitGitHubOAuthConfig(cookie)
+		goac = &githubOAuthConfig
 
 		goa := githuboauth.NewAgent(&githubOAuthConfig, logrus.WithField("client", "githuboauth"))
 		oauthClient := &oauth2.Config{
@@ -390,6 +449,16 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 		mux.Handle("/github-login/redirect", goa.HandleRedirect(oauthClient, githuboauth.NewGitHubClientGetter()))
 	}
 
+	// Spyglass needs GitHub OAuth, if any, to let users log in to see restricted runs.
+	auth := newSpyglassAuthorizer(cfg, spyglassDisabled{cfg}, goac)
+	if o.spyglass {
+		auth = initSpyglass(cfg, o, mux, ja, goac)
+	}
+	// The Spyglass access rules also apply to the runs listed and the pod logs served.
+	mux.Handle("/data.js", gziphandler.GzipHandler(handleData(ja, auth.visibleRuns)))
+	mux.Handle("/prowjobs.js", gziphandler.GzipHandler(handleProwJobs(ja, auth.visibleRuns)))
+	mux.Handle("/log", gziphandler.GzipHandler(auth.guardRuns(logRun, handleLog(ja))))
+
 	// optionally inject http->https redirect handler when behind loadbalancer
 	if o.redirectHTTPTo != "" {
 		redirectMux := http.NewServeMux()
@@ -416,7 +485,7 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 	return mux
 }
 
-func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.JobAgent) {
+func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.JobAgent, goac *config.GitHubOAuthConfig) *spyglassAuthorizer {
 	var c *storage.Client
 	var err error
 	if o.gcsCredentialsFile == "" {
@@ -429,12 +498,41 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	}
 	sg := spyglass.New(ja, cfg, c, context.Background())
 	sg.Start()
+	auth := newSpyglassAuthorizer(cfg, sg, goac)
+	pages := newPageCache(o.spyglassPageCacheMB << 20)
+	renders := newRenderPool(o.spyglassRenderWorkers, o.spyglassRenderQueue)
 
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
-	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
-	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o)))
-	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, c)))
-	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, c)))
+	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(auth.guardRuns(lensRequestRuns, http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg, pages, renders)))))
+	// Not gzipped, because compressing on the fly breaks byte ranges.
+	mux.Handle("/spyglass/artifact", auth.guardRuns(srcParamRun, handleArtifactProxy(sg, cfg)))
+	mux.Handle("/spyglass/artifacts", gziphandler.GzipHandler(auth.guardRuns(srcParamRun, handleLensArtifacts(sg))))
+	mux.Handle("/spyglass/stat", gziphandler.GzipHandler(auth.guardRuns(srcParamRun, handleArtifactStat(sg))))
+	mux.Handle("/spyglass/report", auth.guardRuns(srcParamRun, handleReportProxy(sg, cfg)))
+	mux.Handle("/spyglass/embed/", gziphandler.GzipHandler(auth.guardRuns(embeddedRun, handleEmbed(sg, cfg, o))))
+	mux.Handle("/spyglass/search", gziphandler.GzipHandler(auth.guardRuns(srcParamRun, handleSearch(sg, cfg))))
+	if o.spyglassSnapshotFile != "" {
+		tokens, err := loadAPITokens(o.spyglassSnapshotFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read Spyglass snapshot tokens.")
+		}
+		mux.Handle("/spyglass/snapshot", requireTokens(tokens, auth.guardRuns(srcParamRun, handleSnapshot(o, sg, cfg))))
+	}
+	mux.Handle("/spyglass/export/", gziphandler.GzipHandler(auth.guardRuns(exportedRun, handleExport(o, sg, cfg, renders))))
+	// Not gzipped, because events must reach pages as soon as they're sent.
+	mux.Handle("/spyglass/events/", auth.guardRuns(eventsRun, handleJobEvents(sg, ja, jobEventsPollInterval)))
+	if o.spyglassAPITokenFile != "" {
+		tokens, err := loadAPITokens(o.spyglassAPITokenFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read Spyglass API tokens.")
+		}
+		mux.Handle("/spyglass/api/", gziphandler.GzipHandler(requireTokens(tokens, auth.guardRuns(srcParamRun, handleSpyglassAPI(sg, cfg)))))
+	}
+	mux.Handle("/view/", gziphandler.GzipHandler(auth.guardRuns(viewedRun, handleRequestJobViews(sg, cfg, o, pages))))
+	mux.Handle("/compare/", gziphandler.GzipHandler(auth.guardComparison(sg, handleCompareViews(sg, cfg, o))))
+	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, sg, auth)))
+	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, sg, auth)))
+	return auth
 }
 
 func loadToken(file string) ([]byte, error) {
@@ -486,10 +584,16 @@ func handleNotCached(next http.Handler) http.HandlerFunc {
 	}
 }
 
-func handleProwJobs(ja *jobs.JobAgent) http.HandlerFunc {
+func handleProwJobs(ja *jobs.JobAgent, visibleRuns func(r *http.Request) func(job, buildID string) bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		jobs := ja.ProwJobs()
+		visible := visibleRuns(r)
+		jobs := []prowapi.ProwJob{}
+		for _, job := range ja.ProwJobs() {
+			if visible(job.Spec.Job, job.Status.BuildID) {
+				jobs = append(jobs, job)
+			}
+		}
 		if v := r.URL.Query().Get("omit"); v == "pod_spec" {
 			for i := range jobs {
 				jobs[i].Spec.PodSpec = nil
@@ -512,10 +616,16 @@ func handleProwJobs(ja *jobs.JobAgent) http.HandlerFunc {
 	}
 }
 
-func handleData(ja *jobs.JobAgent) http.HandlerFunc {
+func handleData(ja *jobs.JobAgent, visibleRuns func(r *http.Request) func(job, buildID string) bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		jobs := ja.Jobs()
+		visible := visibleRuns(r)
+		jobs := []jobs.Job{}
+		for _, job := range ja.Jobs() {
+			if visible(job.Job, job.BuildID) {
+				jobs = append(jobs, job)
+			}
+		}
 		jd, err := json.Marshal(jobs)
 		if err != nil {
 			logrus.WithError(err).Error("Error marshaling jobs.")
@@ -571,10 +681,18 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 //
 // Example:
 // - /job-history/kubernetes-jenkins/logs/ci-kubernetes-e2e-prow-canary
-func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client) http.HandlerFunc {
+func handleJobHistory(o options, cfg config.Getter, sg *spyglass.Spyglass, auth *spyglassAuthorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getJobHistory(r.URL, cfg(), gcsClient)
+		if bucketName, root, _, err := parseJobHistURL(r.URL); err == nil {
+			// Every run of the job is stored under its root, so a rule restricting the root's
+			// prefix restricts the whole history.
+			jobPath := path.Clean(path.Join(bucketName, root)) + "/"
+			if !auth.authorizePaths(w, r, jobOrg(cfg(), path.Base(root)), jobPath) {
+				return
+			}
+		}
+		tmpl, err := getJobHistory(r.URL, cfg(), sg)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get job history: %v", err)
 			logrus.WithField("url", r.URL).Error(msg)
@@ -589,10 +707,22 @@ func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client) h
 // The url must look like this:
 //
 // /pr-history?org=<org>&repo=<repo>&pr=<pr number>
-func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client) http.HandlerFunc {
+func handlePRHistory(o options, cfg config.Getter, sg *spyglass.Spyglass, auth *spyglassAuthorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getPRHistory(r.URL, cfg(), gcsClient)
+		if org, repo, pr, err := parsePullURL(r.URL); err == nil {
+			var prPaths []string
+			dirs, _ := getGCSDirsForPR(cfg(), org, repo, pr)
+			for bucketName, gcsPaths := range dirs {
+				for gcsPath := range gcsPaths {
+					prPaths = append(prPaths, path.Join(bucketName, gcsPath)+"/")
+				}
+			}
+			if !auth.authorizePaths(w, r, org, prPaths...) {
+				return
+			}
+		}
+		tmpl, err := getPRHistory(r.URL, cfg(), sg)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get PR history: %v", err)
 			logrus.WithField("url", r.URL).Info(msg)
@@ -611,40 +741,91 @@ func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client) ht
 // Examples:
 // - /view/gcs/kubernetes-jenkins/pr-logs/pull/test-infra/9557/pull-test-infra-verify-gofmt/15688/
 // - /view/prowjob/echo-test/1046875594609922048
-func handleRequestJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options) http.HandlerFunc {
+//
+// Storage prefixes under the browsable prefixes, which hold artifacts other than the runs of
+// ProwJobs, are viewed with the "prefix" key type:
+//
+// /view/prefix/<bucket>/<path>
+//
+// Runs can also be given by the URL of their storage, which redirects to their page:
+//
+// /view/?url=gs://kubernetes-jenkins/logs/ci-kubernetes-e2e/123
+func handleRequestJobViews(sg *spyglass.Spyglass, cfg config.Getter, o options, pages *pageCache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		setHeadersNoCaching(w)
-		src := strings.TrimPrefix(r.URL.Path, "/view/")
-
-		page, err := renderSpyglass(sg, cfg, src, o)
+		if storageURL := r.URL.Query().Get("url"); storageURL != "" {
+			src, err := sg.SourceForURL(storageURL)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Redirect(w, r, path.Join("/view", src), http.StatusFound)
+			return
+		}
+		src, err := sg.ResolveSymlink(strings.TrimPrefix(r.URL.Path, "/view/"))
 		if err != nil {
-			logrus.WithError(err).Error("error rendering spyglass page")
-			message := fmt.Sprintf("error rendering spyglass page: %v", err)
-			http.Error(w, message, http.StatusInternalServerError)
+			logrus.WithError(err).Error("error resolving spyglass page")
+			http.Error(w, fmt.Sprintf("error when resolving real path: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		fmt.Fprint(w, page)
+		version := deckConfigVersion(cfg)
+		page, cached := pages.page(src, version)
+		if !cached {
+			if run, expired := sg.Expired(src); expired {
+				logrus.WithField("source", src).Info("Serving the page of a run whose artifacts were deleted.")
+				serveExpired(w, sg, cfg, o, pages, src, run)
+				return
+			}
+			rendered, err := renderSpyglass(sg, cfg, src, o)
+			if err != nil {
+				if page, renderedAt, ok := pages.stalePage(src); ok {
+					logrus.WithError(err).WithField("source", src).Warning("Serving stale spyglass page.")
+					serveStale(w, page, renderedAt)
+					return
+				}
+				logrus.WithError(err).Error("error rendering spyglass page")
+				message := fmt.Sprintf("error rendering spyglass page: %v", err)
+				http.Error(w, message, http.StatusInternalServerError)
+				return
+			}
+			page = []byte(rendered)
+			if runFinished(sg, src) {
+				pages.addPage(src, version, page)
+			}
+		}
+
+		w.Write(page)
 		elapsed := time.Since(start)
 		logrus.WithFields(logrus.Fields{
 			"duration": elapsed.String(),
 			"endpoint": r.URL.Path,
 			"source":   src,
+			"cached":   cached,
 		}).Info("Loading view completed.")
 	}
 }
 
-// renderSpyglass returns a pre-rendered Spyglass page from the given source string
-func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o options) (string, error) {
-	renderStart := time.Now()
-
-	src = strings.TrimSuffix(src, "/")
-	realPath, err := sg.ResolveSymlink(src)
+// runFinished returns whether a run has finished, so that what is rendered for it won't change.
+// Its artifacts were listed to render its page, so listing them again is cheap.
+func runFinished(sg *spyglass.Spyglass, src string) bool {
+	artifactNames, err := sg.ListArtifacts(src)
 	if err != nil {
-		return "", fmt.Errorf("error when resolving real path: %v", err)
+		return false
 	}
-	src = realPath
+	for _, name := range artifactNames {
+		if name == "finished.json" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSpyglass returns a pre-rendered Spyglass page from the given source string, which must
+// already have been resolved if it is a symlink.
+func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o options) (string, error) {
+	renderStart := time.Now()
 
 	artifactNames, err := sg.ListArtifacts(src)
 	if err != nil {
@@ -654,25 +835,10 @@ func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o opti
 		return "", fmt.Errorf("found no artifacts for %s", src)
 	}
 
-	viewerCache := map[string][]string{}
-	viewersRegistry := cfg().Deck.Spyglass.Viewers
-	regexCache := cfg().Deck.Spyglass.RegexCache
-
-	for re, viewerNames := range viewersRegistry {
-		matches := []string{}
-		for _, a := range artifactNames {
-			if regexCache[re].MatchString(a) {
-				matches = append(matches, a)
-			}
-		}
-		if len(matches) > 0 {
-			for _, vName := range viewerNames {
-				viewerCache[vName] = matches
-			}
-		}
-	}
-
-	ls := sg.Lenses(viewerCache)
+	viewerCache := sg.MatchLenses(artifactNames)
+	ls := sg.RunLenses(src, viewerCache)
+	// Lenses that match many artifacts are given the first page of them, and load the rest later.
+	lensArtifacts, lensArtifactCounts := spyglass.FirstArtifactPages(viewerCache, cfg().Deck.Spyglass.ArtifactPageSize)
 	lensNames := []string{}
 	for _, l := range ls {
 		lensNames = append(lensNames, l.Config().Name)
@@ -689,7 +855,7 @@ func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o opti
 	if gcswebPrefix != "" {
 		runPath, err := sg.RunPath(src)
 		if err == nil {
-			artifactsLink = gcswebPrefix + runPath
+			artifactsLink = gcswebPrefix + sg.StoragePath(runPath)
 			// gcsweb wants us to end URLs with a trailing slash
 			if !strings.HasSuffix(artifactsLink, "/") {
 				artifactsLink += "/"
@@ -707,6 +873,11 @@ func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o opti
 	if err != nil {
 		return "", fmt.Errorf("error determining jobName / buildID: %v", err)
 	}
+	// Storage prefixes aren't the runs of jobs, so pages of them are titled by their path.
+	prefix := spyglass.IsPrefix(src)
+	if prefix {
+		jobName, buildID = strings.SplitN(src, "/", 2)[1], ""
+	}
 
 	announcement := ""
 	if cfg().Deck.Spyglass.Announcement != "" {
@@ -741,34 +912,53 @@ func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o opti
 		extraLinks = nil
 	}
 
+	compareLink := ""
+	if len(comparedLenses(ls, nil)) > 0 && !prefix {
+		compareLink = path.Join("/compare", src)
+	}
+
+	// Pages of runs that haven't finished listen for their job finishing to re-render their lenses.
+	eventsLink := ""
+	if !contains(artifactNames, "finished.json") && !prefix {
+		eventsLink = path.Join("/spyglass/events", src)
+	}
+
 	var viewBuf bytes.Buffer
 	type lensesTemplate struct {
-		Lenses        []lenses.Lens
-		LensNames     []string
-		Source        string
-		LensArtifacts map[string][]string
-		JobHistLink   string
-		ArtifactsLink string
-		PRHistLink    string
-		Announcement  template.HTML
-		TestgridLink  string
-		JobName       string
-		BuildID       string
-		ExtraLinks    []spyglass.ExtraLink
+		Lenses             []lenses.Lens
+		LensNames          []string
+		Source             string
+		LensArtifacts      map[string][]string
+		LensArtifactCounts map[string]int
+		JobHistLink        string
+		ArtifactsLink      string
+		PRHistLink         string
+		CompareLink        string
+		ExportLink         string
+		EventsLink         string
+		Announcement       template.HTML
+		TestgridLink       string
+		JobName            string
+		BuildID            string
+		ExtraLinks         []spyglass.ExtraLink
 	}
 	lTmpl := lensesTemplate{
-		Lenses:        ls,
-		LensNames:     lensNames,
-		Source:        src,
-		LensArtifacts: viewerCache,
-		JobHistLink:   jobHistLink,
-		ArtifactsLink: artifactsLink,
-		PRHistLink:    prHistLink,
-		Announcement:  template.HTML(announcement),
-		TestgridLink:  tgLink,
-		JobName:       jobName,
-		BuildID:       buildID,
-		ExtraLinks:    extraLinks,
+		Lenses:             ls,
+		LensNames:          lensNames,
+		Source:             src,
+		LensArtifacts:      lensArtifacts,
+		LensArtifactCounts: lensArtifactCounts,
+		JobHistLink:        jobHistLink,
+		ArtifactsLink:      artifactsLink,
+		PRHistLink:         prHistLink,
+		CompareLink:        compareLink,
+		ExportLink:         path.Join("/spyglass/export", src),
+		EventsLink:         eventsLink,
+		Announcement:       template.HTML(announcement),
+		TestgridLink:       tgLink,
+		JobName:            jobName,
+		BuildID:            buildID,
+		ExtraLinks:         extraLinks,
 	}
 	t := template.New("spyglass.html")
 
@@ -791,16 +981,29 @@ func renderSpyglass(sg *spyglass.Spyglass, cfg config.Getter, src string, o opti
 	return viewBuf.String(), nil
 }
 
+// lensPolicy is the content security policy of lens pages. It sandboxes them as the frames
+// Spyglass shows them in do, so that they can't act in Deck's origin when opened directly.
+const lensPolicy = "sandbox allow-scripts allow-top-navigation allow-popups"
+
 // handleArtifactView handles requests to load a single view for a job. This is what viewers
-// will use to call back to themselves.
+// will use to call back to themselves. Requests for <lens>/line/<n> render the lens's page
+// around line n of its artifacts, for lenses that show lines of text, so that lines can be
+// linked to. Requests for <lens>/anchor/<anchor> render the lens's page showing the part of its
+// view the anchor names, for lenses that implement AnchorLens. Requests that also give a
+// compare_src render the lens's comparison of the two runs.
 // Query params:
 // - name: required, specifies the name of the viewer to load
 // - src: required, specifies the job source from which to fetch artifacts
-func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+// - artifact: optional, the artifact a line is in, if not the first
+// - data: optional, the data to render the body of an iframe with, so other views can be linked
+// The initial views of finished runs whose pages are cached are cached along with them. Lenses
+// that aren't cached wait for one of the workers of the render pool.
+func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter, pages *pageCache, renders *renderPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
+		w.Header().Set("Content-Security-Policy", lensPolicy)
 		pathSegments := strings.Split(r.URL.Path, "/")
-		if len(pathSegments) != 2 {
+		if len(pathSegments) != 2 && !(len(pathSegments) == 3 && (pathSegments[1] == "line" || pathSegments[1] == "anchor")) {
 			http.NotFound(w, r)
 			return
 		}
@@ -823,33 +1026,124 @@ func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) htt
 			http.Error(w, fmt.Sprintf("Failed to parse request: %v", err), http.StatusBadRequest)
 			return
 		}
+		// The requests made for the lenses of a page share the artifacts they fetch.
+		sg := sg.ForRender(request.RenderID)
+
+		if request.CompareSource != "" {
+			release, ok := acquireRender(w, r, renders, request.Source, lensRenderPriority(lensConfig, resource))
+			if !ok {
+				return
+			}
+			defer release()
+			serveComparison(w, r, o, sg, cfg, lens, resource, request)
+			return
+		}
+
+		// Only the initial view of a lens is cached, not those linked to or asked for with data.
+		cacheable := resource == "iframe" && r.URL.Query().Get("data") == ""
+		version := deckConfigVersion(cfg)
+		viewKey := lensViewKey(lensName, request.Artifacts)
+		if cacheable {
+			if page, ok := pages.view(request.Source, version, viewKey); ok {
+				w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+				w.Write(page)
+				return
+			}
+		}
 
-		artifacts, err := sg.FetchArtifacts(request.Source, "", cfg().Deck.Spyglass.SizeLimit, request.Artifacts)
+		if resource == "iframe" && request.Page == 0 && cfg().Deck.Spyglass.Snapshots {
+			page, err := sg.ReadSnapshot(request.Source, lensName)
+			if err == nil {
+				if cacheable {
+					pages.addView(request.Source, version, viewKey, page)
+				}
+				w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+				w.Write(page)
+				return
+			}
+			if err != spyglass.ErrNoSnapshot {
+				logrus.WithError(err).WithField("src", request.Source).Warning("Failed to read lens snapshot.")
+			}
+		}
+
+		// Lenses that can't read the run's artifacts, or whose run's artifacts were deleted, show
+		// what they rendered before, if they can.
+		if cacheable {
+			if page, rendered, ok := pages.staleView(request.Source, viewKey); ok {
+				if _, err := sg.ListArtifacts(request.Source); err != nil {
+					logrus.WithError(err).WithField("src", request.Source).Warning("Serving stale lens view.")
+					serveStale(w, page, rendered)
+					return
+				}
+				// The page of a run whose artifacts were deleted already says so.
+				if _, expired := sg.Expired(request.Source); expired {
+					w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+					w.Write(page)
+					return
+				}
+			}
+		}
+
+		release, ok := acquireRender(w, r, renders, request.Source, lensRenderPriority(lensConfig, resource))
+		if !ok {
+			return
+		}
+		defer release()
+
+		artifacts, err := fetchLensArtifacts(sg, cfg, lensConfig, request.Source, request.Artifacts)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to retrieve expected artifacts: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		rawConfig := sg.LensConfig(lensName, request.Source)
+
 		switch resource {
 		case "iframe":
-			t, err := template.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-lens.html"))
+			page, err := renderLensPage(o, lens, lensResourcesDir, artifacts, rawConfig, sg.BodyPolicy(lensName).Sanitize(lens.Body(artifacts, lensResourcesDir, r.URL.Query().Get("data"), rawConfig)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if cacheable {
+				pages.addView(request.Source, version, viewKey, page)
+			}
+
+			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+			w.Write(page)
+		case "line":
+			line, err := strconv.Atoi(pathSegments[2])
+			if err != nil || line < 1 {
+				http.Error(w, fmt.Sprintf("Invalid line %q", pathSegments[2]), http.StatusBadRequest)
+				return
+			}
+			data, err := json.Marshal(lineRequest{Artifact: r.URL.Query().Get("artifact"), Line: line})
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to encode line request: %v", err), http.StatusInternalServerError)
+				return
+			}
+			page, err := renderLensPage(o, lens, lensResourcesDir, artifacts, rawConfig, sg.BodyPolicy(lensName).Sanitize(lens.Body(artifacts, lensResourcesDir, string(data), rawConfig)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+			w.Write(page)
+		case "anchor":
+			// Lenses without anchors are shown as they would be anyway.
+			data := ""
+			if anchored, ok := lens.(lenses.AnchorLens); ok {
+				data = anchored.AnchorData(pathSegments[2])
+			}
+			page, err := renderLensPage(o, lens, lensResourcesDir, artifacts, rawConfig, sg.BodyPolicy(lensName).Sanitize(lens.Body(artifacts, lensResourcesDir, data, rawConfig)))
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to load template: %v", err), http.StatusInternalServerError)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
 			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
-			t.Execute(w, struct {
-				Title   string
-				BaseURL string
-				Head    template.HTML
-				Body    template.HTML
-			}{
-				lensConfig.Title,
-				"/spyglass/static/" + lensName + "/",
-				template.HTML(lens.Header(artifacts, lensResourcesDir)),
-				template.HTML(lens.Body(artifacts, lensResourcesDir, "")),
-			})
+			w.Write(page)
 		case "rerender":
 			data, err := ioutil.ReadAll(r.Body)
 			if err != nil {
@@ -857,20 +1151,146 @@ func handleArtifactView(o options, sg *spyglass.Spyglass, cfg config.Getter) htt
 				return
 			}
 			w.Header().Set("Content-Type", "text/html; encoding=utf-8")
-			w.Write([]byte(lens.Body(artifacts, lensResourcesDir, string(data))))
+			w.Write([]byte(sg.BodyPolicy(lensName).Sanitize(lens.Body(artifacts, lensResourcesDir, string(data), rawConfig))))
 		case "callback":
 			data, err := ioutil.ReadAll(r.Body)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Failed to read body: %v", err), http.StatusInternalServerError)
 				return
 			}
-			w.Write([]byte(lens.Callback(artifacts, lensResourcesDir, string(data))))
+			w.Write([]byte(sg.CallbackPolicy(lensName).Sanitize(lens.Callback(artifacts, lensResourcesDir, string(data), rawConfig))))
 		default:
 			http.NotFound(w, r)
 		}
 	}
 }
 
+// fetchLensArtifacts fetches the named artifacts of the run for a lens, along with the baseline
+// and sibling artifacts the lens asks for.
+func fetchLensArtifacts(sg *spyglass.Spyglass, cfg config.Getter, lensConfig lenses.LensConfig, src string, artifactNames []string) ([]lenses.Artifact, error) {
+	artifacts, err := sg.FetchArtifacts(src, "", cfg().Deck.Spyglass.SizeLimit, artifactNames)
+	if err != nil {
+		return nil, err
+	}
+	if lensConfig.Baseline {
+		baseline, err := sg.FetchBaselineArtifacts(src, cfg().Deck.Spyglass.SizeLimit, artifactNames)
+		if err != nil {
+			// Lenses are expected to cope without a baseline, e.g. for the first run of a job.
+			logrus.WithError(err).WithField("src", src).Debug("No baseline artifacts.")
+		}
+		artifacts = append(artifacts, baseline...)
+	}
+	if lensConfig.Siblings {
+		siblings, err := sg.FetchSiblingArtifacts(src, cfg().Deck.Spyglass.SizeLimit, artifactNames)
+		if err != nil {
+			logrus.WithError(err).WithField("src", src).Warning("Failed to list sibling artifacts.")
+		}
+		artifacts = append(artifacts, siblings...)
+	}
+	return artifacts, nil
+}
+
+// lineRequest is the data lenses are rendered with to show a line of one of their artifacts.
+type lineRequest struct {
+	Artifact string `json:"artifact,omitempty"`
+	Line     int    `json:"line"`
+}
+
+// renderLensPage renders the page a lens is shown in around a body the lens rendered.
+func renderLensPage(o options, lens lenses.Lens, lensResourcesDir string, artifacts []lenses.Artifact, rawConfig json.RawMessage, body string) ([]byte, error) {
+	t, err := template.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-lens.html"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load template: %v", err)
+	}
+
+	lensConfig := lens.Config()
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, struct {
+		Title   string
+		BaseURL string
+		Head    template.HTML
+		Body    template.HTML
+	}{
+		lensConfig.Title,
+		"/spyglass/static/" + lensConfig.Name + "/",
+		template.HTML(lens.Header(artifacts, lensResourcesDir, rawConfig)),
+		template.HTML(body),
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to render template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// handleSnapshot renders the lenses for a finished run and stores their pages as snapshots,
+// which are served instead of rendering the lenses again. Lenses that already have a snapshot
+// are left alone, so that a run keeps looking as it did when it finished. It is only served to
+// requests carrying one of the snapshot tokens, and the run's access rules apply.
+// Query params:
+// - src: required, specifies the job source of the run
+func handleSnapshot(o options, sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		if !cfg().Deck.Spyglass.Snapshots {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Snapshots must be requested with POST.", http.StatusMethodNotAllowed)
+			return
+		}
+		src, err := sg.ResolveSymlink(r.URL.Query().Get("src"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to resolve src: %v", err), http.StatusBadRequest)
+			return
+		}
+		finished, err := sg.FetchArtifacts(src, "", cfg().Deck.Spyglass.SizeLimit, []string{"finished.json"})
+		if err == nil && len(finished) == 1 {
+			_, err = finished[0].ReadAll()
+		}
+		if err != nil || len(finished) != 1 {
+			http.Error(w, fmt.Sprintf("Only finished runs can be snapshotted: %v", err), http.StatusConflict)
+			return
+		}
+		artifactNames, err := sg.ListArtifacts(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log := logrus.WithField("src", src)
+		matches := sg.MatchLenses(artifactNames)
+		var rendered []string
+		for _, lens := range sg.RunLenses(src, matches) {
+			lensConfig := lens.Config()
+			if _, err := sg.ReadSnapshot(src, lensConfig.Name); err != spyglass.ErrNoSnapshot {
+				if err != nil {
+					log.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to check for lens snapshot.")
+				}
+				continue
+			}
+			artifacts, err := fetchLensArtifacts(sg, cfg, lensConfig, src, matches[lensConfig.Name])
+			if err != nil {
+				log.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to fetch lens artifacts.")
+				continue
+			}
+			lensResourcesDir := lenses.ResourceDirForLens(o.spyglassFilesLocation, lensConfig.Name)
+			rawConfig := sg.LensConfig(lensConfig.Name, src)
+			page, err := renderLensPage(o, lens, lensResourcesDir, artifacts, rawConfig, sg.BodyPolicy(lensConfig.Name).Sanitize(lens.Body(artifacts, lensResourcesDir, "", rawConfig)))
+			if err == nil {
+				err = sg.WriteSnapshot(src, lensConfig.Name, page)
+			}
+			if err != nil {
+				log.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to snapshot lens.")
+				http.Error(w, fmt.Sprintf("Failed to snapshot %s: %v", lensConfig.Name, err), http.StatusInternalServerError)
+				return
+			}
+			rendered = append(rendered, lensConfig.Name)
+		}
+		log.WithField("lenses", rendered).Info("Stored lens snapshots.")
+		fmt.Fprintf(w, "Stored snapshots of %d lenses.\n", len(rendered))
+	}
+}
+
 func handleTidePools(cfg config.Getter, ta *tideAgent) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
@@ -1016,7 +1436,7 @@ func handleRerun(prowJobClient prowv1.ProwJobInterface) http.HandlerFunc {
 			logrus.WithError(err).Warning("ProwJob not found.")
 			return
 		}
-		pjutil := pjutil.NewProwJob(pj.Spec, pj.ObjectMeta.Labels)
+		pjutil := pjutil.NewProwJobWithAnnotation(pj.Spec, pj.ObjectMeta.Labels, pj.ObjectMeta.Annotations)
 		b, err := yaml.Marshal(&pjutil)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error marshaling: %v", err), http.StatusInternalServerError)
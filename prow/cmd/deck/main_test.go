@@ -93,6 +93,14 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			name: "metrics port out of range",
+			input: options{
+				configPath:  "test",
+				metricsPort: 70000,
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -628,3 +636,16 @@ func TestListProwJobs(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleArtifactViewSandboxed(t *testing.T) {
+	cfg := func() *config.Config { return &config.Config{} }
+	handler := handleArtifactView(options{}, nil, cfg, nil, nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/no-such-lens/iframe", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown lens, got %d", http.StatusNotFound, rr.Code)
+	}
+	if actual := rr.Header().Get("Content-Security-Policy"); actual != lensPolicy {
+		t.Errorf("expected lens pages to be sandboxed by %q, got %q", lensPolicy, actual)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+// pageCache remembers the Spyglass pages and lens views rendered for finished runs, whose
+// artifacts don't change, so that popular runs are served without reading their artifacts and
+// rendering them again for every visit. Pages are kept with the version of the Deck config they
+// were rendered with and are rendered again once it changes. Lenses are built into Deck, so their
+// own versions can't change while it runs. Pages rendered with older versions are still served,
+// marked as stale, when the storage holding a run's artifacts can't be read to render it again.
+// The least recently viewed runs are evicted to keep the cache under its size.
+type pageCache struct {
+	lock     sync.Mutex
+	now      func() time.Time
+	maxBytes int
+	size     int
+	runs     map[string]*cachedRun
+}
+
+// cachedRun is the page rendered for a finished run and the lens views loaded by it.
+type cachedRun struct {
+	version string
+	page    []byte
+	// views are the pages rendered by lenses, by lensViewKey.
+	views map[string][]byte
+	size  int
+	// rendered is when the page was rendered.
+	rendered time.Time
+	used     time.Time
+}
+
+// newPageCache returns a cache of pages of up to maxBytes in total, or nil if maxBytes is not
+// positive. A nil cache caches nothing.
+func newPageCache(maxBytes int) *pageCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &pageCache{now: time.Now, maxBytes: maxBytes, runs: map[string]*cachedRun{}}
+}
+
+// deckConfigVersion returns a digest of the Deck config that pages are rendered with, or "" if it
+// can't be computed, in which case pages should not be cached.
+func deckConfigVersion(cfg config.Getter) string {
+	raw, err := json.Marshal(cfg().Deck)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// lensViewKey returns the key a lens's view of some artifacts is cached under.
+func lensViewKey(lens string, artifacts []string) string {
+	return lens + "\x00" + strings.Join(artifacts, "\x00")
+}
+
+// run returns the cached run, if it was rendered with the given version of the config. Callers
+// must hold the lock.
+func (c *pageCache) run(src, version string) (*cachedRun, bool) {
+	run, ok := c.runs[src]
+	if !ok || version == "" || run.version != version {
+		return nil, false
+	}
+	run.used = c.now()
+	return run, true
+}
+
+// page returns the page rendered for a finished run, if it is cached.
+func (c *pageCache) page(src, version string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	run, ok := c.run(src, version)
+	if !ok {
+		return nil, false
+	}
+	return run.page, true
+}
+
+// addPage caches the page rendered for a finished run, replacing anything cached for it with an
+// older version of the config. Pages that would fill the cache by themselves aren't cached.
+func (c *pageCache) addPage(src, version string, page []byte) {
+	if c == nil || version == "" || len(page) > c.maxBytes {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove(src)
+	run := &cachedRun{version: version, page: page, views: map[string][]byte{}, size: len(page), rendered: c.now(), used: c.now()}
+	c.runs[src] = run
+	c.size += run.size
+	c.evict()
+}
+
+// view returns the view a lens rendered of some artifacts of a finished run, if it is cached.
+func (c *pageCache) view(src, version, key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	run, ok := c.run(src, version)
+	if !ok {
+		return nil, false
+	}
+	view, ok := run.views[key]
+	return view, ok
+}
+
+// addView caches the view a lens rendered of some artifacts of a run. Views are only cached for
+// runs whose page is cached, which are the finished ones, and only while the run fits in the
+// cache.
+func (c *pageCache) addView(src, version, key string, view []byte) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	run, ok := c.run(src, version)
+	if !ok {
+		return
+	}
+	if old, ok := run.views[key]; ok {
+		run.size -= len(old)
+		c.size -= len(old)
+		delete(run.views, key)
+	}
+	if run.size+len(view) > c.maxBytes {
+		return
+	}
+	run.views[key] = view
+	run.size += len(view)
+	c.size += len(view)
+	c.evict()
+}
+
+// stalePage returns the page last rendered for a finished run and when it was rendered, whatever
+// version of the config it was rendered with, to serve when it can't be rendered again.
+func (c *pageCache) stalePage(src string) ([]byte, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	run, ok := c.runs[src]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	run.used = c.now()
+	return run.page, run.rendered, true
+}
+
+// staleView returns the view a lens last rendered of some artifacts of a finished run and when its
+// page was rendered, whatever version of the config it was rendered with, to serve when it can't
+// be rendered again.
+func (c *pageCache) staleView(src, key string) ([]byte, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	run, ok := c.runs[src]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	view, ok := run.views[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	run.used = c.now()
+	return view, run.rendered, true
+}
+
+// remove drops a run from the cache. Callers must hold the lock.
+func (c *pageCache) remove(src string) {
+	if run, ok := c.runs[src]; ok {
+		c.size -= run.size
+		delete(c.runs, src)
+	}
+}
+
+// evict drops the least recently viewed runs until the cache fits in its size. Callers must hold
+// the lock.
+func (c *pageCache) evict() {
+	for c.size > c.maxBytes {
+		var oldest string
+		for src, run := range c.runs {
+			if oldest == "" || run.used.Before(c.runs[oldest].used) {
+				oldest = src
+			}
+		}
+		c.remove(oldest)
+	}
+}
+
+// staleNotice is shown at the top of pages served from the cache because they couldn't be
+// rendered again.
+var staleNotice = template.Must(template.New("stale").Parse(`<div class="stale-notice" style="background-color: #fff3cd; color: #664d03; border: 1px solid #ffe69c; padding: 8px 12px; margin: 8px;">
+  The storage holding this run's artifacts can't be read right now, so this is a copy rendered at
+  {{.Format "2006-01-02 15:04:05 MST"}}. It may be out of date.
+</div>
+`))
+
+// expiredNotice is shown at the top of pages served from the cache because their run's artifacts
+// have been deleted.
+var expiredNotice = template.Must(template.New("expired").Parse(`<div class="stale-notice" style="background-color: #fff3cd; color: #664d03; border: 1px solid #ffe69c; padding: 8px 12px; margin: 8px;">
+  This run's artifacts have been deleted, so this is a copy rendered at
+  {{.Rendered.Format "2006-01-02 15:04:05 MST"}}. Parts of it may no longer load.
+  {{with .RetentionPolicy}}<div>{{.}}</div>{{end}}
+</div>
+`))
+
+// serveStale serves a page from the cache that couldn't be rendered again, with a notice that it
+// is stale after the opening tag of its body.
+func serveStale(w http.ResponseWriter, page []byte, rendered time.Time) {
+	var notice bytes.Buffer
+	if err := staleNotice.Execute(&notice, rendered.UTC()); err != nil {
+		logrus.WithError(err).Warning("Failed to render stale notice.")
+	}
+	serveWithNotice(w, page, notice.Bytes())
+}
+
+// serveExpiredCopy serves a page from the cache of a run whose artifacts have been deleted, with a
+// notice saying so and explaining the retention policy after the opening tag of its body.
+func serveExpiredCopy(w http.ResponseWriter, page []byte, rendered time.Time, policy template.HTML) {
+	var notice bytes.Buffer
+	err := expiredNotice.Execute(&notice, struct {
+		Rendered        time.Time
+		RetentionPolicy template.HTML
+	}{
+		Rendered:        rendered.UTC(),
+		RetentionPolicy: policy,
+	})
+	if err != nil {
+		logrus.WithError(err).Warning("Failed to render expired notice.")
+	}
+	serveWithNotice(w, page, notice.Bytes())
+}
+
+// serveWithNotice serves a page from the cache with a notice after the opening tag of its body.
+func serveWithNotice(w http.ResponseWriter, page, notice []byte) {
+	if start := bytes.Index(page, []byte("<body")); start != -1 {
+		if end := bytes.IndexByte(page[start:], '>'); end != -1 {
+			end += start + 1
+			page = append(append(append([]byte{}, page[:end]...), notice...), page[end:]...)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Warning", `110 - "Response is Stale"`)
+	w.Write(page)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newPageCache(10)
+	c.now = func() time.Time { return now }
+	tick := func() { now = now.Add(time.Second) }
+
+	c.addView("gcs/bucket/a", "v1", "lens", []byte("ignored"))
+	if _, ok := c.view("gcs/bucket/a", "v1", "lens"); ok {
+		t.Error("Expected no view to be cached for a run without a cached page.")
+	}
+
+	c.addPage("gcs/bucket/a", "v1", []byte("aaa"))
+	tick()
+	c.addView("gcs/bucket/a", "v1", "lens", []byte("lens"))
+	if page, ok := c.page("gcs/bucket/a", "v1"); !ok || string(page) != "aaa" {
+		t.Errorf("Expected page %q, got %q (cached: %t)", "aaa", page, ok)
+	}
+	if view, ok := c.view("gcs/bucket/a", "v1", "lens"); !ok || string(view) != "lens" {
+		t.Errorf("Expected view %q, got %q (cached: %t)", "lens", view, ok)
+	}
+	if _, ok := c.page("gcs/bucket/a", "v2"); ok {
+		t.Error("Expected a page rendered with another config version not to be served.")
+	}
+	if _, ok := c.page("gcs/bucket/a", ""); ok {
+		t.Error("Expected pages not to be served without a config version.")
+	}
+
+	tick()
+	c.addPage("gcs/bucket/b", "v1", []byte("bbb"))
+	tick()
+	c.page("gcs/bucket/a", "v1")
+	tick()
+	// a is 7 bytes and b 3, so adding c evicts b, which was viewed least recently.
+	c.addPage("gcs/bucket/c", "v1", []byte("c"))
+	if _, ok := c.page("gcs/bucket/b", "v1"); ok {
+		t.Error("Expected the least recently viewed page to be evicted.")
+	}
+	for _, src := range []string{"gcs/bucket/a", "gcs/bucket/c"} {
+		if _, ok := c.page(src, "v1"); !ok {
+			t.Errorf("Expected %s to still be cached.", src)
+		}
+	}
+	if c.size != 8 {
+		t.Errorf("Expected the cache to hold 8 bytes, got %d", c.size)
+	}
+
+	c.addPage("gcs/bucket/a", "v2", []byte("a"))
+	if _, ok := c.view("gcs/bucket/a", "v2", "lens"); ok {
+		t.Error("Expected views to be dropped when a page is rendered again.")
+	}
+	if c.size != 2 {
+		t.Errorf("Expected the cache to hold 2 bytes, got %d", c.size)
+	}
+
+	c.addPage("gcs/bucket/big", "v1", []byte("more than ten bytes"))
+	if _, ok := c.page("gcs/bucket/big", "v1"); ok {
+		t.Error("Expected a page bigger than the cache not to be cached.")
+	}
+	if _, ok := c.page("gcs/bucket/c", "v1"); !ok {
+		t.Error("Expected a page bigger than the cache not to evict others.")
+	}
+
+	var disabled *pageCache
+	disabled.addPage("gcs/bucket/a", "v1", []byte("aaa"))
+	if _, ok := disabled.page("gcs/bucket/a", "v1"); ok {
+		t.Error("Expected a disabled cache to cache nothing.")
+	}
+}
+
+func TestPageCacheStale(t *testing.T) {
+	rendered := time.Unix(1000, 0)
+	c := newPageCache(100)
+	c.now = func() time.Time { return rendered }
+	c.addPage("gcs/bucket/a", "v1", []byte("page"))
+	c.addView("gcs/bucket/a", "v1", "lens", []byte("view"))
+
+	if _, ok := c.page("gcs/bucket/a", "v2"); ok {
+		t.Error("Expected a page rendered with another config version not to be fresh.")
+	}
+	if page, when, ok := c.stalePage("gcs/bucket/a"); !ok || string(page) != "page" || !when.Equal(rendered) {
+		t.Errorf("Expected the stale page %q rendered at %v, got %q at %v (cached: %t)", "page", rendered, page, when, ok)
+	}
+	if view, when, ok := c.staleView("gcs/bucket/a", "lens"); !ok || string(view) != "view" || !when.Equal(rendered) {
+		t.Errorf("Expected the stale view %q rendered at %v, got %q at %v (cached: %t)", "view", rendered, view, when, ok)
+	}
+	if _, _, ok := c.staleView("gcs/bucket/a", "other"); ok {
+		t.Error("Expected no stale view of a lens that wasn't rendered.")
+	}
+	if _, _, ok := c.stalePage("gcs/bucket/b"); ok {
+		t.Error("Expected no stale page of a run that wasn't rendered.")
+	}
+}
+
+func TestServeStale(t *testing.T) {
+	w := httptest.NewRecorder()
+	serveStale(w, []byte(`<html><body class="lens-body"><p>view</p></body></html>`), time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC))
+	body := w.Body.String()
+	if !strings.HasPrefix(body, `<html><body class="lens-body"><div class="stale-notice"`) {
+		t.Errorf("Expected the stale notice to start the body, got %s", body)
+	}
+	if !strings.Contains(body, "2019-05-01 12:00:00 UTC") || !strings.HasSuffix(body, "</div>\n<p>view</p></body></html>") {
+		t.Errorf("Expected the notice to say when the page was rendered before its content, got %s", body)
+	}
+	if warning := w.Header().Get("Warning"); !strings.HasPrefix(warning, "110") {
+		t.Errorf("Expected a stale warning header, got %q", warning)
+	}
+}
+
+func TestServeExpiredCopy(t *testing.T) {
+	w := httptest.NewRecorder()
+	page := []byte(`<html><body><p>page</p></body></html>`)
+	serveExpiredCopy(w, page, time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC), template.HTML(`Artifacts are kept for <b>90 days</b>.`))
+	body := w.Body.String()
+	if !strings.HasPrefix(body, `<html><body><div class="stale-notice"`) || !strings.HasSuffix(body, "</div>\n<p>page</p></body></html>") {
+		t.Errorf("Expected the expired notice to start the body, got %s", body)
+	}
+	if !strings.Contains(body, "deleted") || !strings.Contains(body, "2019-05-01 12:00:00 UTC") {
+		t.Errorf("Expected the notice to say the artifacts were deleted and when the page was rendered, got %s", body)
+	}
+	if !strings.Contains(body, "<div>Artifacts are kept for <b>90 days</b>.</div>") {
+		t.Errorf("Expected the notice to explain the retention policy, got %s", body)
+	}
+}
@@ -26,13 +26,13 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/sets"
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/spyglass"
 )
 
 var pullCommitRe = regexp.MustCompile(`^[-\w]+:\w{40},\d+:(\w{40})$`)
@@ -238,7 +238,7 @@ func getGCSDirsForPR(config *config.Config, org, repo string, pr int) (map[strin
 	return toSearch, nil
 }
 
-func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client) (prHistoryTemplate, error) {
+func getPRHistory(url *url.URL, config *config.Config, sg *spyglass.Spyglass) (prHistoryTemplate, error) {
 	start := time.Now()
 	template := prHistoryTemplate{}
 
@@ -259,7 +259,11 @@ func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client
 	jobCommitBuilds := make(map[string]map[string][]buildData)
 
 	for bucketName, gcsPaths := range toSearch {
-		bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+		bkt, err := sg.Bucket(bucketName)
+		if err != nil {
+			return template, err
+		}
+		bucket := gcsBucket{bucketName, bkt}
 		for gcsPath := range gcsPaths {
 			jobPrefixes, err := bucket.listSubDirs(gcsPath)
 			if err != nil {
@@ -0,0 +1,242 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// errRenderQueueFull is returned when a lens can't be rendered because too many are waiting.
+var errRenderQueueFull = errors.New("too many lenses are waiting to be rendered")
+
+// Prometheus Metrics
+var renderMetrics = struct {
+	queued   prometheus.Gauge
+	inFlight prometheus.Gauge
+	rejected prometheus.Counter
+	wait     prometheus.Histogram
+}{
+	queued: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "spyglass_render_queue_depth",
+		Help: "Number of lens renders waiting for a worker.",
+	}),
+	inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "spyglass_renders_in_flight",
+		Help: "Number of lens renders running.",
+	}),
+	rejected: prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "spyglass_renders_rejected_total",
+		Help: "Number of lens renders rejected because the queue was full.",
+	}),
+	wait: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "spyglass_render_queue_wait_seconds",
+		Help:    "Time lens renders waited for a worker.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	}),
+}
+
+func init() {
+	prometheus.MustRegister(renderMetrics.queued)
+	prometheus.MustRegister(renderMetrics.inFlight)
+	prometheus.MustRegister(renderMetrics.rejected)
+	prometheus.MustRegister(renderMetrics.wait)
+}
+
+// exportRenderPriority is the priority of exports, which wait for the lenses of pages of their run.
+const exportRenderPriority = ^uint(0)
+
+// renderPool bounds how many lenses are rendered at once, since each reads artifacts from storage.
+// Renders beyond the bound wait in a queue of bounded depth. Waiting renders are grouped by the
+// run they are of and the runs take turns, so that a page with many lenses, or an export of one,
+// doesn't hold up the lenses of other pages. The renders of a run are served in order of priority,
+// so that the lenses at the top of its page are shown before those below.
+type renderPool struct {
+	lock      sync.Mutex
+	workers   int
+	maxQueued int
+	running   int
+	queued    int
+	// waiting are the renders waiting for a worker, by run, in the order they are served.
+	waiting map[string][]waitingRender
+	// turns are the runs with renders waiting, in the order they are next served.
+	turns []string
+}
+
+// waitingRender is a render waiting for a worker.
+type waitingRender struct {
+	ready chan struct{}
+	// priority orders the renders of a run. Lower is served first.
+	priority uint
+}
+
+// newRenderPool returns a pool of workers renders with up to maxQueued waiting for them, or nil
+// if workers is not positive. A nil pool doesn't bound renders.
+func newRenderPool(workers, maxQueued int) *renderPool {
+	if workers <= 0 {
+		return nil
+	}
+	return &renderPool{workers: workers, maxQueued: maxQueued, waiting: map[string][]waitingRender{}}
+}
+
+// acquire waits for a worker to render a lens of a run, and returns a function that must be
+// called when the lens is rendered. Renders of the run with a lower priority are served first. It
+// returns errRenderQueueFull if too many renders are already waiting, or the context's error if it
+// is done first.
+func (p *renderPool) acquire(ctx context.Context, run string, priority uint) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	p.lock.Lock()
+	if p.running < p.workers && p.queued == 0 {
+		p.running++
+		p.updateMetrics()
+		p.lock.Unlock()
+		return p.release, nil
+	}
+	if p.queued >= p.maxQueued {
+		p.lock.Unlock()
+		renderMetrics.rejected.Inc()
+		return nil, errRenderQueueFull
+	}
+	ready := make(chan struct{})
+	if len(p.waiting[run]) == 0 {
+		p.turns = append(p.turns, run)
+	}
+	waiting := p.waiting[run]
+	i := sort.Search(len(waiting), func(i int) bool { return waiting[i].priority > priority })
+	waiting = append(waiting, waitingRender{})
+	copy(waiting[i+1:], waiting[i:])
+	waiting[i] = waitingRender{ready: ready, priority: priority}
+	p.waiting[run] = waiting
+	p.queued++
+	p.updateMetrics()
+	p.lock.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		renderMetrics.wait.Observe(time.Since(start).Seconds())
+		return p.release, nil
+	case <-ctx.Done():
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		select {
+		case <-ready:
+			// The render was given a worker as the context was done, so it is given back.
+			p.running--
+			p.grant()
+		default:
+			p.dequeue(run, ready)
+		}
+		p.updateMetrics()
+		return nil, ctx.Err()
+	}
+}
+
+// release gives back the worker of a render.
+func (p *renderPool) release() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.running--
+	p.grant()
+	p.updateMetrics()
+}
+
+// grant gives free workers to waiting renders, taking a render from each run in turn. Callers
+// must hold the lock.
+func (p *renderPool) grant() {
+	for p.running < p.workers && len(p.turns) > 0 {
+		run := p.turns[0]
+		p.turns = p.turns[1:]
+		waiting := p.waiting[run]
+		close(waiting[0].ready)
+		if len(waiting) == 1 {
+			delete(p.waiting, run)
+		} else {
+			p.waiting[run] = waiting[1:]
+			p.turns = append(p.turns, run)
+		}
+		p.queued--
+		p.running++
+	}
+}
+
+// dequeue removes a render that stopped waiting. Callers must hold the lock.
+func (p *renderPool) dequeue(run string, ready chan struct{}) {
+	waiting := p.waiting[run]
+	for i, w := range waiting {
+		if w.ready == ready {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			p.queued--
+			break
+		}
+	}
+	if len(waiting) > 0 {
+		p.waiting[run] = waiting
+		return
+	}
+	delete(p.waiting, run)
+	for i, r := range p.turns {
+		if r == run {
+			p.turns = append(p.turns[:i], p.turns[i+1:]...)
+			break
+		}
+	}
+}
+
+// updateMetrics reports how many renders are running and waiting. Callers must hold the lock.
+func (p *renderPool) updateMetrics() {
+	renderMetrics.queued.Set(float64(p.queued))
+	renderMetrics.inFlight.Set(float64(p.running))
+}
+
+// acquireRender waits for a worker of the render pool to render a lens of a run, and returns a
+// function to call once it is rendered. If the render can't wait for a worker, it responds that
+// Deck is too busy and returns false.
+func acquireRender(w http.ResponseWriter, r *http.Request, renders *renderPool, run string, priority uint) (func(), bool) {
+	release, err := renders.acquire(r.Context(), run, priority)
+	if err == errRenderQueueFull {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many lenses are being rendered; try again shortly.", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Stopped waiting to render: %v", err), http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return release, true
+}
+
+// lensRenderPriority is the priority of rendering a lens's view or response to a request. The
+// views that pages load at first are rendered in the order the lenses are shown, while anything
+// else is asked for by someone using the lens, who is served first.
+func lensRenderPriority(lensConfig lenses.LensConfig, resource string) uint {
+	if resource != "iframe" {
+		return 0
+	}
+	return lensConfig.Priority + 1
+}
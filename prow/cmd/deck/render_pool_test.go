@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func queuedRenders(p *renderPool) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.queued
+}
+
+func TestRenderPool(t *testing.T) {
+	p := newRenderPool(1, 3)
+	release, err := p.acquire(context.Background(), "a", 0)
+	if err != nil {
+		t.Fatalf("Expected a free worker, got %v", err)
+	}
+
+	// Run a takes up the queue with two renders before run b waits for one.
+	served := make(chan string, 3)
+	wait := func(run string) {
+		queued := queuedRenders(p)
+		go func() {
+			release, err := p.acquire(context.Background(), run, 0)
+			if err != nil {
+				served <- err.Error()
+				return
+			}
+			served <- run
+			release()
+		}()
+		// Wait for the render to be queued, so that the renders queue in order.
+		for queuedRenders(p) == queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait("a")
+	wait("a")
+	wait("b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.acquire(ctx, "c", 0); err != errRenderQueueFull {
+		t.Errorf("Expected the render to be rejected, got %v", err)
+	}
+	cancel()
+
+	release()
+	var order []string
+	for i := 0; i < 3; i++ {
+		order = append(order, <-served)
+	}
+	if expected := []string{"a", "b", "a"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected runs to take turns, served %v instead of %v", order, expected)
+	}
+	// The last render may still be giving back its worker.
+	for i := 0; ; i++ {
+		p.lock.Lock()
+		idle := p.running == 0 && p.queued == 0 && len(p.turns) == 0 && len(p.waiting) == 0
+		p.lock.Unlock()
+		if idle {
+			break
+		}
+		if i == 1000 {
+			t.Fatal("Expected the pool to become idle.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRenderPoolPriority(t *testing.T) {
+	p := newRenderPool(1, 4)
+	release, err := p.acquire(context.Background(), "a", 0)
+	if err != nil {
+		t.Fatalf("Expected a free worker, got %v", err)
+	}
+
+	served := make(chan string, 4)
+	wait := func(render string, priority uint) {
+		queued := queuedRenders(p)
+		go func() {
+			release, err := p.acquire(context.Background(), "a", priority)
+			if err != nil {
+				served <- err.Error()
+				return
+			}
+			served <- render
+			release()
+		}()
+		for queuedRenders(p) == queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait("export", exportRenderPriority)
+	wait("buildlog", 11)
+	wait("metadata", 2)
+	wait("callback", 0)
+
+	release()
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-served)
+	}
+	if expected := []string{"callback", "metadata", "buildlog", "export"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected renders to be served by priority, served %v instead of %v", order, expected)
+	}
+}
+
+func TestRenderPoolCancel(t *testing.T) {
+	p := newRenderPool(1, 1)
+	release, err := p.acquire(context.Background(), "a", 0)
+	if err != nil {
+		t.Fatalf("Expected a free worker, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx, "b", 0); err != context.DeadlineExceeded {
+		t.Errorf("Expected the render to stop waiting, got %v", err)
+	}
+	if p.queued != 0 || len(p.turns) != 0 || len(p.waiting) != 0 {
+		t.Errorf("Expected no renders to wait, got %+v", p)
+	}
+	release()
+	if p.running != 0 {
+		t.Errorf("Expected no renders to run, got %d", p.running)
+	}
+
+	var unbounded *renderPool
+	if _, err := unbounded.acquire(context.Background(), "a", 0); err != nil {
+		t.Errorf("Expected an unbounded pool not to wait, got %v", err)
+	}
+}
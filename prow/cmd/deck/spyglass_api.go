@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// lensDataResponse is what the Spyglass API serves for a lens.
+type lensDataResponse struct {
+	Lens      string      `json:"lens"`
+	Source    string      `json:"src"`
+	Artifacts []string    `json:"artifacts"`
+	Data      interface{} `json:"data"`
+}
+
+// lensListResponse is what the Spyglass API serves for a run: the lenses with data for it.
+type lensListResponse struct {
+	Source string   `json:"src"`
+	Lenses []string `json:"lenses"`
+}
+
+// loadAPITokens reads the tokens that clients of the Spyglass API authenticate with, one per line.
+func loadAPITokens(file string) ([][]byte, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var tokens [][]byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if token := bytes.TrimSpace(line); len(token) > 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens in %s", file)
+	}
+	return tokens, nil
+}
+
+// authorized returns whether a request carries one of some tokens as a bearer token.
+func authorized(r *http.Request, tokens [][]byte) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	given := []byte(strings.TrimPrefix(header, "Bearer "))
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare(given, token) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// requireTokens serves requests with h only if they carry one of some tokens as a bearer token.
+func requireTokens(tokens [][]byte, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, tokens) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "A valid bearer token is required.", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// handleSpyglassAPI serves what lenses parse from a run's artifacts as JSON, for bots and other
+// tools that would otherwise scrape the pages lenses render. Requests for /spyglass/api/ list the
+// lenses with data for the run, and requests for /spyglass/api/<lens> serve the lens's data.
+// Query params:
+// - src: required, specifies the job source from which to fetch artifacts
+func handleSpyglassAPI(sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := strings.TrimSuffix(r.URL.Query().Get("src"), "/")
+		if src == "" {
+			http.Error(w, "Missing src parameter.", http.StatusBadRequest)
+			return
+		}
+		src, err := sg.ResolveSymlink(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to resolve src: %v", err), http.StatusNotFound)
+			return
+		}
+		artifactNames, err := sg.ListArtifacts(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusNotFound)
+			return
+		}
+		matches := sg.MatchLenses(artifactNames)
+
+		lensName := strings.TrimPrefix(r.URL.Path, "/spyglass/api/")
+		if lensName == "" {
+			list := lensListResponse{Source: src, Lenses: []string{}}
+			for _, lens := range sg.Lenses(matches) {
+				if _, ok := lens.(lenses.DataLens); ok {
+					list.Lenses = append(list.Lenses, lens.Config().Name)
+				}
+			}
+			writeJSON(w, list)
+			return
+		}
+		lens, err := lenses.GetLens(lensName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("No such lens: %s", lensName), http.StatusNotFound)
+			return
+		}
+		if len(matches[lensName]) == 0 {
+			http.Error(w, fmt.Sprintf("The %s lens has no artifacts for %s.", lensName, src), http.StatusNotFound)
+			return
+		}
+		artifacts, err := fetchLensArtifacts(sg, cfg, lens.Config(), src, matches[lensName])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to retrieve expected artifacts: %v", err), http.StatusInternalServerError)
+			return
+		}
+		serveLensData(w, lens, src, artifacts, sg.LensConfig(lensName, src))
+	}
+}
+
+// serveLensData serves the data a lens parses from a run's artifacts.
+func serveLensData(w http.ResponseWriter, lens lenses.Lens, src string, artifacts []lenses.Artifact, rawConfig json.RawMessage) {
+	dataLens, ok := lens.(lenses.DataLens)
+	if !ok {
+		http.Error(w, fmt.Sprintf("The %s lens does not serve its data.", lens.Config().Name), http.StatusNotFound)
+		return
+	}
+	data, err := dataLens.Data(artifacts, rawConfig)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read lens data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	response := lensDataResponse{Lens: lens.Config().Name, Source: src, Artifacts: []string{}, Data: data}
+	matched, _ := lenses.SplitSiblings(artifacts)
+	current, _ := lenses.SplitBaseline(matched)
+	for _, a := range current {
+		response.Artifacts = append(response.Artifacts, a.JobPath())
+	}
+	writeJSON(w, response)
+}
+
+// writeJSON serves a value encoded as JSON.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		logrus.WithError(err).Error("Error marshaling Spyglass API response.")
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
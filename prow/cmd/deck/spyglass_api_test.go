@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+type fakeLens struct{}
+
+func (fakeLens) Config() lenses.LensConfig { return lenses.LensConfig{Name: "fake", Title: "Fake"} }
+func (fakeLens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return ""
+}
+func (fakeLens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+func (fakeLens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+type fakeDataLens struct {
+	fakeLens
+}
+
+func (fakeDataLens) Data(artifacts []lenses.Artifact, config json.RawMessage) (interface{}, error) {
+	var sizes []int
+	for _, a := range artifacts {
+		size, _ := a.Size()
+		sizes = append(sizes, int(size))
+	}
+	return sizes, nil
+}
+
+func TestAuthorized(t *testing.T) {
+	tokens := [][]byte{[]byte("first"), []byte("second")}
+	testCases := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{name: "first token", header: "Bearer first", expected: true},
+		{name: "second token", header: "Bearer second", expected: true},
+		{name: "unknown token", header: "Bearer third"},
+		{name: "token not given as a bearer token", header: "first"},
+		{name: "no token"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/spyglass/api/junit?src=gcs/bucket/run", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if actual := authorized(r, tokens); actual != tc.expected {
+				t.Errorf("expected authorized to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadAPITokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyglass-api")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "tokens")
+	if err := ioutil.WriteFile(file, []byte("first\n\n  second \n"), 0600); err != nil {
+		t.Fatalf("failed to write tokens: %v", err)
+	}
+	tokens, err := loadAPITokens(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := [][]byte{[]byte("first"), []byte("second")}; !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected tokens %q, got %q", expected, tokens)
+	}
+	if err := ioutil.WriteFile(file, []byte("\n"), 0600); err != nil {
+		t.Fatalf("failed to write tokens: %v", err)
+	}
+	if _, err := loadAPITokens(file); err == nil {
+		t.Error("expected an error for a file without tokens")
+	}
+}
+
+func TestServeLensData(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeArtifact{path: "artifacts/junit_01.xml", content: []byte("123")},
+		lenses.NewSiblingArtifact(&fakeArtifact{path: "build-log.txt", content: []byte("12345")}),
+	}
+
+	w := httptest.NewRecorder()
+	serveLensData(w, fakeDataLens{}, "gcs/bucket/run", artifacts, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON, got status %d and type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	expected := `{"lens":"fake","src":"gcs/bucket/run","artifacts":["artifacts/junit_01.xml"],"data":[3,5]}`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s, got %s", expected, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	serveLensData(w, fakeLens{}, "gcs/bucket/run", artifacts, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected lenses without data to be not found, got status %d", w.Code)
+	}
+}
+
+func TestRequireTokens(t *testing.T) {
+	tokens := [][]byte{[]byte("secret")}
+	testCases := []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{name: "token", header: "Bearer secret", expected: http.StatusOK},
+		{name: "unknown token", header: "Bearer guess", expected: http.StatusUnauthorized},
+		{name: "no token", expected: http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := requireTokens(tokens, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(http.MethodPost, "/spyglass/snapshot?src=gcs/bucket/run", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.expected {
+				t.Errorf("expected status %d, got %d", tc.expected, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/spyglass"
+)
+
+const (
+	// githubUserTTL is how long the GitHub org memberships of a user who logged in are trusted.
+	githubUserTTL = 10 * time.Minute
+	// maxGitHubUsers is how many users' logins and memberships are remembered.
+	maxGitHubUsers = 1000
+
+	// The session Deck's GitHub OAuth stores users' access tokens in.
+	tokenSession = "access-token-session"
+	tokenKey     = "access-token"
+
+	githubAPIEndpoint = "https://api.github.com"
+)
+
+// githubUserClient is the part of the GitHub client used to find out who a user is.
+type githubUserClient interface {
+	BotName() (string, error)
+	IsMember(org, user string) (bool, error)
+}
+
+// accessRuleGetter looks up the access rules that restrict a run, and where runs linked to under
+// bucket aliases are stored.
+type accessRuleGetter interface {
+	AccessRules(src string) ([]config.SpyglassAccessRule, error)
+	StoragePath(p string) string
+}
+
+// spyglassDisabled looks up access rules when Spyglass is disabled, without which the rules that
+// restrict a run can't be found. If any rules are configured, no run can be seen.
+type spyglassDisabled struct {
+	cfg config.Getter
+}
+
+func (d spyglassDisabled) AccessRules(src string) ([]config.SpyglassAccessRule, error) {
+	if len(d.cfg().Deck.Spyglass.Access) > 0 {
+		return nil, errors.New("access rules can only be checked with Spyglass enabled")
+	}
+	return nil, nil
+}
+
+func (d spyglassDisabled) StoragePath(p string) string {
+	return p
+}
+
+// githubUser is a user who logged in to Deck with GitHub.
+type githubUser struct {
+	client  githubUserClient
+	login   string
+	fetched time.Time
+
+	lock    sync.Mutex
+	members map[string]bool
+}
+
+// isMember returns whether the user is a member of a GitHub org. Failures to ask are logged and
+// count as not being a member, but are asked again next time.
+func (u *githubUser) isMember(org string) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if member, ok := u.members[org]; ok {
+		return member
+	}
+	member, err := u.client.IsMember(org, u.login)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"user": u.login, "org": org}).Info("Couldn't check org membership.")
+		return false
+	}
+	u.members[org] = member
+	return member
+}
+
+// spyglassAuthorizer enforces the Spyglass access rules, which restrict who can see some runs, by
+// checking the GitHub identity of users who logged in to Deck with GitHub OAuth.
+type spyglassAuthorizer struct {
+	cfg   config.Getter
+	rules accessRuleGetter
+	// goac is nil when Deck doesn't have GitHub OAuth, in which case no one can log in to see
+	// restricted runs.
+	goac      *config.GitHubOAuthConfig
+	newClient func(token string) githubUserClient
+	now       func() time.Time
+
+	lock sync.Mutex
+	// users are keyed by their access tokens.
+	users map[string]*githubUser
+}
+
+func newSpyglassAuthorizer(cfg config.Getter, rules accessRuleGetter, goac *config.GitHubOAuthConfig) *spyglassAuthorizer {
+	return &spyglassAuthorizer{
+		cfg:   cfg,
+		rules: rules,
+		goac:  goac,
+		newClient: func(token string) githubUserClient {
+			return github.NewClient(func() []byte { return []byte(token) }, githubAPIEndpoint)
+		},
+		now:   time.Now,
+		users: map[string]*githubUser{},
+	}
+}
+
+// guardRuns serves requests with h once the user who made them is allowed to see the runs that
+// runsOf returns for them.
+func (a *spyglassAuthorizer) guardRuns(runsOf func(r *http.Request) []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rules []config.SpyglassAccessRule
+		for _, src := range runsOf(r) {
+			if src == "" {
+				continue
+			}
+			runRules, err := a.rules.AccessRules(strings.TrimSuffix(src, "/"))
+			if err != nil {
+				setHeadersNoCaching(w)
+				http.Error(w, fmt.Sprintf("Failed to look up who can see %s: %v", src, err), http.StatusNotFound)
+				return
+			}
+			rules = append(rules, runRules...)
+		}
+		if a.authorize(w, r, rules) {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// visibleRuns returns a function that reports whether the user who made a request is allowed to
+// see a run of a job, for listings of runs that leave out those the user may not see. Runs whose
+// access rules can't be looked up are left out.
+func (a *spyglassAuthorizer) visibleRuns(r *http.Request) func(job, buildID string) bool {
+	if len(a.cfg().Deck.Spyglass.Access) == 0 {
+		return func(job, buildID string) bool { return true }
+	}
+	var user *githubUser
+	if a.goac != nil {
+		var err error
+		if user, err = a.user(r); err != nil {
+			logrus.WithError(err).Warning("Failed to identify the user who logged in.")
+		}
+	}
+	return func(job, buildID string) bool {
+		rules, err := a.rules.AccessRules(prowJobRun(job, buildID))
+		if err != nil {
+			return false
+		}
+		for _, rule := range rules {
+			if user == nil || !rule.Allows(user.login, user.isMember) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// authorizePaths checks that the user who made a request is allowed to see the runs stored under
+// runPaths of a job that ran against org, either of which may be empty. It responds to the
+// request and returns false if they aren't.
+func (a *spyglassAuthorizer) authorizePaths(w http.ResponseWriter, r *http.Request, org string, runPaths ...string) bool {
+	sc := a.cfg().Deck.Spyglass
+	var rules []config.SpyglassAccessRule
+	for _, runPath := range runPaths {
+		rules = append(rules, sc.AccessRulesFor(a.rules.StoragePath(runPath), org)...)
+	}
+	if len(runPaths) == 0 {
+		rules = sc.AccessRulesFor("", org)
+	}
+	return a.authorize(w, r, rules)
+}
+
+// authorize checks that the user who made a request is allowed by all of some access rules. It
+// responds to the request and returns false if they aren't.
+func (a *spyglassAuthorizer) authorize(w http.ResponseWriter, r *http.Request, rules []config.SpyglassAccessRule) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	setHeadersNoCaching(w)
+	if a.goac == nil {
+		http.Error(w, "This job's results are restricted, and this Deck has no GitHub login.", http.StatusForbidden)
+		return false
+	}
+	user, err := a.user(r)
+	if err != nil {
+		logrus.WithError(err).Warning("Failed to identify the user who logged in.")
+		http.Error(w, "Failed to identify who you are logged in to GitHub as.", http.StatusInternalServerError)
+		return false
+	}
+	if user == nil {
+		http.Error(w, "This job's results are restricted. Log in with GitHub at /github-login to see them.", http.StatusUnauthorized)
+		return false
+	}
+	for _, rule := range rules {
+		if !rule.Allows(user.login, user.isMember) {
+			http.Error(w, fmt.Sprintf("This job's results are restricted, and %s is not allowed to see them.", user.login), http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// user returns the user who made a request, or nil if they haven't logged in with GitHub or their
+// access token no longer works.
+func (a *spyglassAuthorizer) user(r *http.Request) (*githubUser, error) {
+	session, err := a.goac.CookieStore.Get(r, tokenSession)
+	if err != nil {
+		// The cookie is stale or was tampered with, which is as good as not being logged in.
+		return nil, nil
+	}
+	token, ok := session.Values[tokenKey].(*oauth2.Token)
+	if !ok || !token.Valid() {
+		return nil, nil
+	}
+
+	a.lock.Lock()
+	user, ok := a.users[token.AccessToken]
+	a.lock.Unlock()
+	if ok && a.now().Sub(user.fetched) < githubUserTTL {
+		return user, nil
+	}
+
+	client := a.newClient(token.AccessToken)
+	login, err := client.BotName()
+	if err != nil {
+		if strings.Contains(err.Error(), "401") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	user = &githubUser{client: client, login: login, fetched: a.now(), members: map[string]bool{}}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if len(a.users) >= maxGitHubUsers {
+		a.evictOldestUser()
+	}
+	a.users[token.AccessToken] = user
+	return user, nil
+}
+
+// evictOldestUser forgets the user whose identity was looked up longest ago. It must be called
+// with the lock held.
+func (a *spyglassAuthorizer) evictOldestUser() {
+	var oldestToken string
+	var oldest time.Time
+	for token, user := range a.users {
+		if oldestToken == "" || user.fetched.Before(oldest) {
+			oldestToken, oldest = token, user.fetched
+		}
+	}
+	delete(a.users, oldestToken)
+}
+
+// jobOrg returns the org of the repo a job runs against, as configured, or "" if it is unknown.
+// Periodics run against the org of their first extra ref, if any.
+func jobOrg(c *config.Config, jobName string) string {
+	for repo, presubmits := range c.Presubmits {
+		for _, job := range presubmits {
+			if job.Name == jobName {
+				return strings.SplitN(repo, "/", 2)[0]
+			}
+		}
+	}
+	for repo, postsubmits := range c.Postsubmits {
+		for _, job := range postsubmits {
+			if job.Name == jobName {
+				return strings.SplitN(repo, "/", 2)[0]
+			}
+		}
+	}
+	for _, job := range c.Periodics {
+		if job.Name == jobName && len(job.ExtraRefs) > 0 {
+			return job.ExtraRefs[0].Org
+		}
+	}
+	return ""
+}
+
+// viewedRun returns the run a /view/ page shows.
+func viewedRun(r *http.Request) []string {
+	return []string{strings.TrimPrefix(r.URL.Path, "/view/")}
+}
+
+// comparisonResolver resolves the runs a /compare/ page shows.
+type comparisonResolver interface {
+	ResolveSymlink(src string) (string, error)
+	BaselineRun(src string) (string, error)
+}
+
+// guardComparison serves /compare/ requests with h once the user who made them is allowed to see
+// both runs the page shows. Requests that don't say which run to compare to are compared to the
+// baseline run, which is found here and handed to h as the with parameter, so that the page shows
+// the run that was authorized.
+func (a *spyglassAuthorizer) guardComparison(sg comparisonResolver, h http.Handler) http.Handler {
+	guarded := a.guardRuns(comparedRuns(sg), h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("with") != "" {
+			guarded.ServeHTTP(w, r)
+			return
+		}
+		src, err := sg.ResolveSymlink(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/compare/"), "/"))
+		if err != nil {
+			setHeadersNoCaching(w)
+			http.Error(w, fmt.Sprintf("Failed to resolve src: %v", err), http.StatusNotFound)
+			return
+		}
+		baseline, err := sg.BaselineRun(src)
+		if err != nil {
+			setHeadersNoCaching(w)
+			http.Error(w, fmt.Sprintf("Failed to find a run to compare to: %v", err), http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		query.Set("with", baseline)
+		u := *r.URL
+		u.RawQuery = query.Encode()
+		withBaseline := *r
+		withBaseline.URL = &u
+		guarded.ServeHTTP(w, &withBaseline)
+	})
+}
+
+// comparedRuns returns a function returning the runs a /compare/ page shows. As when rendering
+// the page, a build ID to compare to names a run beside the one src resolves to.
+func comparedRuns(sg comparisonResolver) func(r *http.Request) []string {
+	return func(r *http.Request) []string {
+		src := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/compare/"), "/")
+		if realPath, err := sg.ResolveSymlink(src); err == nil {
+			src = realPath
+		}
+		return []string{src, otherRunSource(src, r.URL.Query().Get("with"))}
+	}
+}
+
+// lensRequestRuns returns the runs a lens request renders artifacts of.
+func lensRequestRuns(r *http.Request) []string {
+	var request spyglass.LensRequest
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("req")), &request); err != nil {
+		// Requests that can't be parsed are rejected before any artifact is read.
+		return nil
+	}
+	return []string{request.Source, request.CompareSource}
+}
+
+// logRun returns the run whose pod log a /log request is for.
+func logRun(r *http.Request) []string {
+	job, id := r.URL.Query().Get("job"), r.URL.Query().Get("id")
+	if job == "" || id == "" {
+		// Requests that don't name a run are rejected before any log is read.
+		return nil
+	}
+	return []string{prowJobRun(job, id)}
+}
+
+// prowJobRun returns the src of a run of a job that Deck knows the ProwJob of.
+func prowJobRun(job, buildID string) string {
+	return "prowjob/" + job + "/" + buildID
+}
+
+// srcParamRun returns the run given by a request's src parameter.
+func srcParamRun(r *http.Request) []string {
+	return []string{r.URL.Query().Get("src")}
+}
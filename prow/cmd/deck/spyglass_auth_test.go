@@ -0,0 +1,304 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"golang.org/x/oauth2"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+type fakeAccessRules map[string][]config.SpyglassAccessRule
+
+func (f fakeAccessRules) AccessRules(src string) ([]config.SpyglassAccessRule, error) {
+	if strings.HasSuffix(src, "/missing") {
+		return nil, errors.New("no such run")
+	}
+	return f[src], nil
+}
+
+func (f fakeAccessRules) StoragePath(p string) string {
+	return p
+}
+
+type fakeGitHubUser struct {
+	login string
+	orgs  map[string]bool
+}
+
+func (f fakeGitHubUser) BotName() (string, error) {
+	if f.login == "" {
+		return "", errors.New("status code 401 not one of [200]")
+	}
+	return f.login, nil
+}
+
+func (f fakeGitHubUser) IsMember(org, user string) (bool, error) {
+	return f.orgs[org], nil
+}
+
+func TestSpyglassAuthorizerGuardRuns(t *testing.T) {
+	rules := fakeAccessRules{
+		"gcs/bucket/logs/secret/1": {{BucketPrefixes: []string{"bucket/logs/secret"}, Users: []string{"alice"}}},
+		"gcs/bucket/logs/team/1":   {{Orgs: []string{"team-org"}, MembersOf: []string{"team-org"}}},
+	}
+	users := map[string]fakeGitHubUser{
+		"alice-token":   {login: "alice"},
+		"bob-token":     {login: "bob", orgs: map[string]bool{"team-org": true}},
+		"revoked-token": {},
+	}
+	store := sessions.NewCookieStore([]byte("secret"))
+
+	testCases := []struct {
+		name     string
+		src      string
+		token    string
+		noOAuth  bool
+		expected int
+	}{
+		{name: "unrestricted run is served to anyone", src: "gcs/bucket/logs/public/1", expected: http.StatusOK},
+		{name: "unrestricted run is served without OAuth", src: "gcs/bucket/logs/public/1", noOAuth: true, expected: http.StatusOK},
+		{name: "restricted run without OAuth is forbidden", src: "gcs/bucket/logs/secret/1", token: "alice-token", noOAuth: true, expected: http.StatusForbidden},
+		{name: "restricted run needs a login", src: "gcs/bucket/logs/secret/1", expected: http.StatusUnauthorized},
+		{name: "revoked token needs a login", src: "gcs/bucket/logs/secret/1", token: "revoked-token", expected: http.StatusUnauthorized},
+		{name: "named user sees the run", src: "gcs/bucket/logs/secret/1", token: "alice-token", expected: http.StatusOK},
+		{name: "other user is forbidden", src: "gcs/bucket/logs/secret/1", token: "bob-token", expected: http.StatusForbidden},
+		{name: "org member sees the run", src: "gcs/bucket/logs/team/1", token: "bob-token", expected: http.StatusOK},
+		{name: "non-member is forbidden", src: "gcs/bucket/logs/team/1", token: "alice-token", expected: http.StatusForbidden},
+		{name: "unknown run is not found", src: "gcs/bucket/missing", expected: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			goac := &config.GitHubOAuthConfig{}
+			goac.InitGitHubOAuthConfig(store)
+			if tc.noOAuth {
+				goac = nil
+			}
+			auth := newSpyglassAuthorizer(func() *config.Config { return &config.Config{} }, rules, goac)
+			auth.newClient = func(token string) githubUserClient { return users[token] }
+
+			req := httptest.NewRequest(http.MethodGet, "/spyglass/artifact?src="+tc.src, nil)
+			if tc.token != "" {
+				rec := httptest.NewRecorder()
+				session, _ := store.New(req, tokenSession)
+				session.Values[tokenKey] = &oauth2.Token{AccessToken: tc.token}
+				if err := session.Save(req, rec); err != nil {
+					t.Fatalf("failed to save session: %v", err)
+				}
+				req.Header.Set("Cookie", rec.Header().Get("Set-Cookie"))
+			}
+			rec := httptest.NewRecorder()
+			auth.guardRuns(srcParamRun, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+			if rec.Code != tc.expected {
+				t.Errorf("expected status %d, got %d: %s", tc.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+type fakeComparisonResolver map[string]string
+
+func (f fakeComparisonResolver) ResolveSymlink(src string) (string, error) {
+	return src, nil
+}
+
+func (f fakeComparisonResolver) BaselineRun(src string) (string, error) {
+	if baseline, ok := f[src]; ok {
+		return baseline, nil
+	}
+	return "", errors.New("no baseline")
+}
+
+func TestSpyglassAuthorizerGuardComparison(t *testing.T) {
+	rules := fakeAccessRules{
+		"gcs/bucket/logs/secret/1": {{BucketPrefixes: []string{"bucket/logs/secret"}, Users: []string{"alice"}}},
+	}
+	baselines := fakeComparisonResolver{
+		"gcs/bucket/logs/public/2": "gcs/bucket/logs/public/1",
+		"gcs/bucket/pr-logs/job/2": "gcs/bucket/logs/secret/1",
+	}
+	users := map[string]fakeGitHubUser{"alice-token": {login: "alice"}, "bob-token": {login: "bob"}}
+	store := sessions.NewCookieStore([]byte("secret"))
+
+	testCases := []struct {
+		name         string
+		url          string
+		token        string
+		expected     int
+		expectedWith string
+	}{
+		{name: "unrestricted baseline is compared to", url: "/compare/gcs/bucket/logs/public/2", expected: http.StatusOK, expectedWith: "gcs/bucket/logs/public/1"},
+		{name: "restricted baseline needs a login", url: "/compare/gcs/bucket/pr-logs/job/2", expected: http.StatusUnauthorized},
+		{name: "restricted baseline is forbidden to other users", url: "/compare/gcs/bucket/pr-logs/job/2", token: "bob-token", expected: http.StatusForbidden},
+		{name: "restricted baseline is compared to for allowed users", url: "/compare/gcs/bucket/pr-logs/job/2", token: "alice-token", expected: http.StatusOK, expectedWith: "gcs/bucket/logs/secret/1"},
+		{name: "restricted run given by build ID is forbidden", url: "/compare/gcs/bucket/logs/secret/2?with=1", token: "bob-token", expected: http.StatusForbidden},
+		{name: "run without a baseline is not found", url: "/compare/gcs/bucket/logs/public/1", expected: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			goac := &config.GitHubOAuthConfig{}
+			goac.InitGitHubOAuthConfig(store)
+			auth := newSpyglassAuthorizer(func() *config.Config { return &config.Config{} }, rules, goac)
+			auth.newClient = func(token string) githubUserClient { return users[token] }
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.token != "" {
+				rec := httptest.NewRecorder()
+				session, _ := store.New(req, tokenSession)
+				session.Values[tokenKey] = &oauth2.Token{AccessToken: tc.token}
+				if err := session.Save(req, rec); err != nil {
+					t.Fatalf("failed to save session: %v", err)
+				}
+				req.Header.Set("Cookie", rec.Header().Get("Set-Cookie"))
+			}
+			var with string
+			rec := httptest.NewRecorder()
+			auth.guardComparison(baselines, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				with = r.URL.Query().Get("with")
+			})).ServeHTTP(rec, req)
+			if rec.Code != tc.expected {
+				t.Errorf("expected status %d, got %d: %s", tc.expected, rec.Code, rec.Body.String())
+			}
+			if with != tc.expectedWith {
+				t.Errorf("expected the page to compare to %q, got %q", tc.expectedWith, with)
+			}
+		})
+	}
+}
+
+// requestWithToken returns a request from a user who logged in with GitHub and got token, or from
+// a user who didn't log in if token is empty.
+func requestWithToken(t *testing.T, store sessions.Store, url, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if token != "" {
+		rec := httptest.NewRecorder()
+		session, _ := store.New(req, tokenSession)
+		session.Values[tokenKey] = &oauth2.Token{AccessToken: token}
+		if err := session.Save(req, rec); err != nil {
+			t.Fatalf("failed to save session: %v", err)
+		}
+		req.Header.Set("Cookie", rec.Header().Get("Set-Cookie"))
+	}
+	return req
+}
+
+func TestSpyglassAuthorizerVisibleRuns(t *testing.T) {
+	rules := fakeAccessRules{
+		"prowjob/secret-job/1": {{BucketPrefixes: []string{"bucket/logs/secret-job"}, Users: []string{"alice"}}},
+	}
+	restricted := &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		Access: []config.SpyglassAccessRule{{BucketPrefixes: []string{"bucket/logs/secret-job"}, Users: []string{"alice"}}},
+	}}}}
+	users := map[string]fakeGitHubUser{"alice-token": {login: "alice"}, "bob-token": {login: "bob"}}
+	store := sessions.NewCookieStore([]byte("secret"))
+
+	testCases := []struct {
+		name     string
+		cfg      *config.Config
+		token    string
+		job      string
+		buildID  string
+		expected bool
+	}{
+		{name: "every run is listed without rules", cfg: &config.Config{}, job: "secret-job", buildID: "1", expected: true},
+		{name: "unrestricted run is listed", cfg: restricted, job: "public-job", buildID: "1", expected: true},
+		{name: "restricted run is left out without a login", cfg: restricted, job: "secret-job", buildID: "1"},
+		{name: "restricted run is left out for other users", cfg: restricted, token: "bob-token", job: "secret-job", buildID: "1"},
+		{name: "restricted run is listed for allowed users", cfg: restricted, token: "alice-token", job: "secret-job", buildID: "1", expected: true},
+		{name: "run whose rules can't be looked up is left out", cfg: restricted, token: "alice-token", job: "job", buildID: "missing"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			goac := &config.GitHubOAuthConfig{}
+			goac.InitGitHubOAuthConfig(store)
+			auth := newSpyglassAuthorizer(func() *config.Config { return tc.cfg }, rules, goac)
+			auth.newClient = func(token string) githubUserClient { return users[token] }
+
+			visible := auth.visibleRuns(requestWithToken(t, store, "/data.js", tc.token))
+			if actual := visible(tc.job, tc.buildID); actual != tc.expected {
+				t.Errorf("expected visible to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSpyglassAuthorizerGuardLog(t *testing.T) {
+	rules := fakeAccessRules{
+		"prowjob/secret-job/1": {{BucketPrefixes: []string{"bucket/logs/secret-job"}, Users: []string{"alice"}}},
+	}
+	store := sessions.NewCookieStore([]byte("secret"))
+	goac := &config.GitHubOAuthConfig{}
+	goac.InitGitHubOAuthConfig(store)
+	auth := newSpyglassAuthorizer(func() *config.Config { return &config.Config{} }, rules, goac)
+	auth.newClient = func(token string) githubUserClient { return fakeGitHubUser{login: "alice"} }
+
+	testCases := []struct {
+		name     string
+		url      string
+		token    string
+		expected int
+	}{
+		{name: "unrestricted log is served", url: "/log?job=public-job&id=1", expected: http.StatusOK},
+		{name: "restricted log needs a login", url: "/log?job=secret-job&id=1", expected: http.StatusUnauthorized},
+		{name: "restricted log is served to allowed users", url: "/log?job=secret-job&id=1", token: "alice-token", expected: http.StatusOK},
+		{name: "log of a run whose rules can't be looked up is not found", url: "/log?job=job&id=missing", expected: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			auth.guardRuns(logRun, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, requestWithToken(t, store, tc.url, tc.token))
+			if rec.Code != tc.expected {
+				t.Errorf("expected status %d, got %d: %s", tc.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSpyglassDisabled(t *testing.T) {
+	unrestricted := spyglassDisabled{func() *config.Config { return &config.Config{} }}
+	if rules, err := unrestricted.AccessRules("prowjob/job/1"); err != nil || len(rules) != 0 {
+		t.Errorf("expected no rules without any configured, got %v (err: %v)", rules, err)
+	}
+	restricted := spyglassDisabled{func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+			Access: []config.SpyglassAccessRule{{Orgs: []string{"org"}}},
+		}}}}
+	}}
+	if _, err := restricted.AccessRules("prowjob/job/1"); err == nil {
+		t.Error("expected runs to be refused when rules are configured but can't be checked")
+	}
+}
+
+func TestJobOrg(t *testing.T) {
+	c := &config.Config{JobConfig: config.JobConfig{
+		Presubmits:  map[string][]config.Presubmit{"org/repo": {{JobBase: config.JobBase{Name: "pull-job"}}}},
+		Postsubmits: map[string][]config.Postsubmit{"other-org/repo": {{JobBase: config.JobBase{Name: "post-job"}}}},
+	}}
+	testCases := map[string]string{"pull-job": "org", "post-job": "other-org", "unknown-job": ""}
+	for job, expected := range testCases {
+		if actual := jobOrg(c, job); actual != expected {
+			t.Errorf("expected %s to run against %q, got %q", job, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// handleCompareViews handles requests to compare two runs of a job, rendering the lenses that
+// can compare runs for both of them side by side. The url specifies the run being viewed the way
+// /view/ urls do:
+//
+// /compare/<key-type>/<key>
+//
+// Query params:
+// - with: the src or build ID of the run to compare to, set to the baseline run if missing
+// - lens: optional and repeatable, the lenses to show, by default all that can compare runs
+func handleCompareViews(sg *spyglass.Spyglass, cfg config.Getter, o options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		setHeadersNoCaching(w)
+		src := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/compare/"), "/")
+
+		page, err := renderComparison(sg, cfg, src, r.URL.Query().Get("with"), r.URL.Query()["lens"], o)
+		if err != nil {
+			logrus.WithError(err).Error("error rendering spyglass comparison")
+			message := fmt.Sprintf("error rendering spyglass comparison: %v", err)
+			http.Error(w, message, http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, page)
+		logrus.WithFields(logrus.Fields{
+			"duration": time.Since(start).String(),
+			"endpoint": r.URL.Path,
+			"source":   src,
+		}).Info("Loading comparison completed.")
+	}
+}
+
+// renderComparison returns a pre-rendered page comparing the run in src to the run given by with.
+// Both runs must already be authorized.
+func renderComparison(sg *spyglass.Spyglass, cfg config.Getter, src, with string, selected []string, o options) (string, error) {
+	src, err := sg.ResolveSymlink(src)
+	if err != nil {
+		return "", fmt.Errorf("error when resolving real path: %v", err)
+	}
+	if with == "" {
+		return "", fmt.Errorf("no run to compare to")
+	}
+	compareSrc, err := sg.ResolveSymlink(otherRunSource(src, with))
+	if err != nil {
+		return "", fmt.Errorf("error when resolving real path of the run to compare to: %v", err)
+	}
+
+	artifactNames, err := sg.ListArtifacts(src)
+	if err != nil {
+		return "", fmt.Errorf("error listing artifacts: %v", err)
+	}
+	compareNames, err := sg.ListArtifacts(compareSrc)
+	if err != nil {
+		return "", fmt.Errorf("error listing artifacts of the run to compare to: %v", err)
+	}
+	viewerCache := sg.MatchLenses(artifactNames)
+	ls := comparedLenses(sg.RunLenses(src, viewerCache), selected)
+	if len(ls) == 0 {
+		return "", fmt.Errorf("no lens can compare the runs")
+	}
+	lensNames := []string{}
+	for _, l := range ls {
+		lensNames = append(lensNames, l.Config().Name)
+	}
+
+	jobName, buildID, err := sg.KeyToJob(src)
+	if err != nil {
+		return "", fmt.Errorf("error determining jobName / buildID: %v", err)
+	}
+	_, compareBuildID, err := sg.KeyToJob(compareSrc)
+	if err != nil {
+		return "", fmt.Errorf("error determining the build ID of the run to compare to: %v", err)
+	}
+
+	var viewBuf bytes.Buffer
+	type compareTemplate struct {
+		Lenses               []lenses.Lens
+		LensNames            []string
+		Source               string
+		LensArtifacts        map[string][]string
+		CompareSource        string
+		CompareLensArtifacts map[string][]string
+		JobName              string
+		BuildID              string
+		CompareBuildID       string
+	}
+	cTmpl := compareTemplate{
+		Lenses:               ls,
+		LensNames:            lensNames,
+		Source:               src,
+		LensArtifacts:        viewerCache,
+		CompareSource:        compareSrc,
+		CompareLensArtifacts: sg.MatchLenses(compareNames),
+		JobName:              jobName,
+		BuildID:              buildID,
+		CompareBuildID:       compareBuildID,
+	}
+	t := template.New("spyglass-compare.html")
+	if _, err := prepareBaseTemplate(o, cfg, t); err != nil {
+		return "", fmt.Errorf("error preparing base template: %v", err)
+	}
+	t, err = t.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-compare.html"))
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %v", err)
+	}
+	if err = t.Execute(&viewBuf, cTmpl); err != nil {
+		return "", fmt.Errorf("error rendering template: %v", err)
+	}
+	return viewBuf.String(), nil
+}
+
+// otherRunSource returns the src of the run to compare the run in src to, which is given either
+// as a src or as the build ID of another run of the same job.
+func otherRunSource(src, with string) string {
+	with = strings.TrimSuffix(with, "/")
+	if strings.Contains(with, "/") {
+		return with
+	}
+	return path.Join(path.Dir(src), with)
+}
+
+// comparedLenses returns the lenses that can compare runs, keeping only the selected lenses if any
+// are selected.
+func comparedLenses(ls []lenses.Lens, selected []string) []lenses.Lens {
+	var compared []lenses.Lens
+	for _, l := range ls {
+		if _, ok := l.(lenses.ComparisonLens); !ok {
+			continue
+		}
+		if len(selected) > 0 && !contains(selected, l.Config().Name) {
+			continue
+		}
+		compared = append(compared, l)
+	}
+	return compared
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serveComparison serves the page of a lens comparing two runs, rerenders of its body and its
+// callbacks. The artifacts of the run compared to are passed to the lens as baseline artifacts.
+func serveComparison(w http.ResponseWriter, r *http.Request, o options, sg *spyglass.Spyglass, cfg config.Getter, lens lenses.Lens, resource string, request spyglass.LensRequest) {
+	if resource == "line" || resource == "anchor" {
+		// Links to parts of the lens's view show the whole comparison.
+		resource = "iframe"
+	}
+	comparer, ok := lens.(lenses.ComparisonLens)
+	if !ok || (resource != "iframe" && resource != "rerender" && resource != "callback") {
+		http.NotFound(w, r)
+		return
+	}
+	lensConfig := lens.Config()
+	lensResourcesDir := lenses.ResourceDirForLens(o.spyglassFilesLocation, lensConfig.Name)
+	// The run compared to replaces the baseline the lens would otherwise be given.
+	lensConfig.Baseline = false
+	artifacts, err := fetchLensArtifacts(sg, cfg, lensConfig, request.Source, request.Artifacts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve expected artifacts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	compared, err := sg.FetchComparedArtifacts(request.CompareSource, cfg().Deck.Spyglass.SizeLimit, request.CompareArtifacts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve the artifacts to compare to: %v", err), http.StatusInternalServerError)
+		return
+	}
+	artifacts = append(artifacts, compared...)
+	rawConfig := sg.LensConfig(lensConfig.Name, request.Source)
+	if resource == "callback" {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read body: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(sg.CallbackPolicy(lensConfig.Name).Sanitize(lens.Callback(artifacts, lensResourcesDir, string(data), rawConfig))))
+		return
+	}
+	page := []byte(sg.BodyPolicy(lensConfig.Name).Sanitize(comparer.Compare(artifacts, lensResourcesDir, rawConfig)))
+	if resource == "iframe" {
+		page, err = renderLensPage(o, lens, lensResourcesDir, artifacts, rawConfig, string(page))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; encoding=utf-8")
+	w.Write(page)
+}
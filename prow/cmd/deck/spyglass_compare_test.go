@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+type fakeCompareLens struct {
+	fakeLens
+	name string
+}
+
+func (l fakeCompareLens) Config() lenses.LensConfig {
+	return lenses.LensConfig{Name: l.name, Title: l.name}
+}
+
+func (fakeCompareLens) Compare(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return ""
+}
+
+func TestOtherRunSource(t *testing.T) {
+	testCases := []struct {
+		name     string
+		with     string
+		expected string
+	}{
+		{name: "build ID of another run", with: "1230", expected: "gcs/bucket/logs/job/1230"},
+		{name: "src of another run", with: "gcs/bucket/logs/other-job/7/", expected: "gcs/bucket/logs/other-job/7"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := otherRunSource("gcs/bucket/logs/job/1234", tc.with); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestComparedLenses(t *testing.T) {
+	ls := []lenses.Lens{fakeCompareLens{name: "junit"}, fakeLens{}, fakeCompareLens{name: "buildlog"}}
+	testCases := []struct {
+		name     string
+		selected []string
+		expected []string
+	}{
+		{name: "all lenses that can compare runs", expected: []string{"junit", "buildlog"}},
+		{name: "selected lenses", selected: []string{"buildlog", "fake"}, expected: []string{"buildlog"}},
+		{name: "no lens selected can compare runs", selected: []string{"fake"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, l := range comparedLenses(ls, tc.selected) {
+				actual = append(actual, l.Config().Name)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected lenses %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
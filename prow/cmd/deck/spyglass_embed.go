@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// handleEmbed serves a page showing a single lens of a run with just enough around it to say
+// which run it is, for other sites to frame in their dashboards and triage tools. Only the sites
+// allowed by embed_frame_ancestors may frame it. The url names the lens, then the run the way
+// /view/ urls do:
+//
+// /spyglass/embed/<lens>/<key-type>/<key>
+func handleEmbed(sg *spyglass.Spyglass, cfg config.Getter, o options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		lensName, src := splitEmbedPath(r.URL.Path)
+		if lensName == "" || src == "" {
+			http.Error(w, "The url must name a lens and a run: /spyglass/embed/<lens>/<key-type>/<key>", http.StatusBadRequest)
+			return
+		}
+		src, err := sg.ResolveSymlink(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to resolve src: %v", err), http.StatusNotFound)
+			return
+		}
+		artifactNames, err := sg.ListArtifacts(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusNotFound)
+			return
+		}
+		matches := sg.MatchLenses(artifactNames)
+		var lens lenses.Lens
+		for _, l := range sg.RunLenses(src, matches) {
+			if l.Config().Name == lensName {
+				lens = l
+			}
+		}
+		if lens == nil {
+			http.Error(w, fmt.Sprintf("The %s lens has nothing to show for %s.", lensName, src), http.StatusNotFound)
+			return
+		}
+		page, err := renderEmbed(sg, cfg, o, src, lens, matches)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to render the %s lens: %v", lensName, err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", embedPolicy(cfg().Deck.Spyglass.EmbedFrameAncestors))
+		w.Write(page)
+	}
+}
+
+// renderEmbed renders the page that frames a single lens of a run.
+func renderEmbed(sg *spyglass.Spyglass, cfg config.Getter, o options, src string, lens lenses.Lens, matches map[string][]string) ([]byte, error) {
+	jobName, buildID, err := sg.KeyToJob(src)
+	if err != nil {
+		return nil, fmt.Errorf("error determining jobName / buildID: %v", err)
+	}
+	lensName := lens.Config().Name
+	type embedTemplate struct {
+		Lens          lenses.Lens
+		LensNames     []string
+		Source        string
+		LensArtifacts map[string][]string
+		ViewLink      string
+		JobName       string
+		BuildID       string
+	}
+	eTmpl := embedTemplate{
+		Lens:          lens,
+		LensNames:     []string{lensName},
+		Source:        src,
+		LensArtifacts: map[string][]string{lensName: matches[lensName]},
+		ViewLink:      path.Join("/view", src),
+		JobName:       jobName,
+		BuildID:       buildID,
+	}
+	t, err := template.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-embed.html"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, eTmpl); err != nil {
+		return nil, fmt.Errorf("error rendering template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// embedPolicy is the content security policy of embedded lenses, which lets the sources in
+// frameAncestors frame them, or only Deck itself if there are none.
+func embedPolicy(frameAncestors []string) string {
+	if len(frameAncestors) == 0 {
+		return "frame-ancestors 'self'"
+	}
+	return "frame-ancestors " + strings.Join(frameAncestors, " ")
+}
+
+// splitEmbedPath returns the lens and the run that an embed url names.
+func splitEmbedPath(urlPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(urlPath, "/spyglass/embed/"), "/", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], strings.TrimSuffix(parts[1], "/")
+}
+
+// embeddedRun returns the run an embedded lens shows.
+func embeddedRun(r *http.Request) []string {
+	_, src := splitEmbedPath(r.URL.Path)
+	return []string{src}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitEmbedPath(t *testing.T) {
+	testCases := []struct {
+		name string
+		path string
+		lens string
+		src  string
+	}{
+		{
+			name: "lens and run",
+			path: "/spyglass/embed/junit/gcs/bucket/logs/job/123/",
+			lens: "junit",
+			src:  "gcs/bucket/logs/job/123",
+		},
+		{
+			name: "prowjob run",
+			path: "/spyglass/embed/buildlog/prowjob/job/123",
+			lens: "buildlog",
+			src:  "prowjob/job/123",
+		},
+		{
+			name: "no run",
+			path: "/spyglass/embed/junit",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lens, src := splitEmbedPath(tc.path)
+			if lens != tc.lens || src != tc.src {
+				t.Errorf("expected lens %q and run %q, got %q and %q", tc.lens, tc.src, lens, src)
+			}
+		})
+	}
+}
+
+func TestEmbedPolicy(t *testing.T) {
+	if policy := embedPolicy(nil); policy != "frame-ancestors 'self'" {
+		t.Errorf("expected only Deck to be allowed to frame lenses by default, got %q", policy)
+	}
+	policy := embedPolicy([]string{"https://dashboard.example.com", "https://triage.example.com"})
+	if expected := "frame-ancestors https://dashboard.example.com https://triage.example.com"; policy != expected {
+		t.Errorf("expected policy %q, got %q", expected, policy)
+	}
+}
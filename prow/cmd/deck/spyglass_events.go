@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass"
+)
+
+// jobEventsPollInterval is how often the ProwJobs of open Spyglass pages are checked for state
+// changes. The job agent itself only refreshes its ProwJobs every 30 seconds.
+const jobEventsPollInterval = 5 * time.Second
+
+type prowJobGetter interface {
+	GetProwJob(job, id string) (prowapi.ProwJob, error)
+}
+
+// jobStateEvent is the data of the events sent to Spyglass pages when the state of their ProwJob
+// changes.
+type jobStateEvent struct {
+	State prowapi.ProwJobState `json:"state"`
+	// Finished is set once the job has completed, after which no more events are sent.
+	Finished bool `json:"finished"`
+}
+
+// handleJobEvents streams the state of the ProwJob of a run to the Spyglass page showing it as
+// server-sent events, so that pages of runs that haven't finished can re-render their lenses once
+// they do. A "state" event is sent with the job's current state, then whenever it changes, until
+// the job completes or the page is closed. The url specifies the run the way /view/ urls do,
+// after its symlinks are resolved:
+//
+// /spyglass/events/<key-type>/<key>
+func handleJobEvents(sg *spyglass.Spyglass, pjg prowJobGetter, poll time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := eventsRun(r)[0]
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming events is not supported", http.StatusInternalServerError)
+			return
+		}
+		jobName, buildID, err := sg.KeyToJob(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error determining jobName / buildID: %v", err), http.StatusBadRequest)
+			return
+		}
+		job, err := pjg.GetProwJob(jobName, buildID)
+		if err != nil {
+			// Pages stop listening rather than reconnect when they're not found.
+			http.Error(w, fmt.Sprintf("no ProwJob for %s: %v", src, err), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Stop proxies such as nginx from holding events back.
+		w.Header().Set("X-Accel-Buffering", "no")
+
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		var sent prowapi.ProwJobState
+		for {
+			if job.Status.State != sent || job.Complete() {
+				if err := writeJobStateEvent(w, job); err != nil {
+					logrus.WithError(err).WithField("source", src).Debug("Failed to send job state event.")
+					return
+				}
+				flusher.Flush()
+				sent = job.Status.State
+			}
+			if job.Complete() {
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+			if job, err = pjg.GetProwJob(jobName, buildID); err != nil {
+				// The job was deleted, so its page won't hear about it finishing. It reconnects
+				// and is then told that the job can't be found.
+				return
+			}
+		}
+	}
+}
+
+// writeJobStateEvent writes a "state" event with the state of a ProwJob.
+func writeJobStateEvent(w http.ResponseWriter, job prowapi.ProwJob) error {
+	data, err := json.Marshal(jobStateEvent{State: job.Status.State, Finished: job.Complete()})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+	return err
+}
+
+// eventsRun returns the run whose ProwJob's state changes are streamed.
+func eventsRun(r *http.Request) []string {
+	return []string{strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/spyglass/events/"), "/")}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass"
+)
+
+// fakeProwJobGetter returns the states of a job in turn, staying in the last one.
+type fakeProwJobGetter struct {
+	job    string
+	states []prowapi.ProwJob
+}
+
+func (f *fakeProwJobGetter) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	if job != f.job || id != "123" {
+		return prowapi.ProwJob{}, fmt.Errorf("no such job %s/%s", job, id)
+	}
+	state := f.states[0]
+	if len(f.states) > 1 {
+		f.states = f.states[1:]
+	}
+	return state, nil
+}
+
+func TestHandleJobEvents(t *testing.T) {
+	now := metav1.Now()
+	pending := prowapi.ProwJob{Status: prowapi.ProwJobStatus{State: prowapi.PendingState}}
+	success := prowapi.ProwJob{Status: prowapi.ProwJobStatus{State: prowapi.SuccessState, CompletionTime: &now}}
+	testCases := []struct {
+		name     string
+		path     string
+		states   []prowapi.ProwJob
+		code     int
+		expected string
+	}{
+		{
+			name:   "a state is sent when it changes, until the job completes",
+			path:   "/spyglass/events/gcs/bucket/logs/job/123",
+			states: []prowapi.ProwJob{pending, pending, pending, success},
+			code:   http.StatusOK,
+			expected: "event: state\ndata: {\"state\":\"pending\",\"finished\":false}\n\n" +
+				"event: state\ndata: {\"state\":\"success\",\"finished\":true}\n\n",
+		},
+		{
+			name:     "jobs that already completed send a single event",
+			path:     "/spyglass/events/prowjob/job/123",
+			states:   []prowapi.ProwJob{success},
+			code:     http.StatusOK,
+			expected: "event: state\ndata: {\"state\":\"success\",\"finished\":true}\n\n",
+		},
+		{
+			name:   "runs without a ProwJob are not found",
+			path:   "/spyglass/events/gcs/bucket/logs/other-job/123",
+			states: []prowapi.ProwJob{pending},
+			code:   http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pjg := &fakeProwJobGetter{job: "job", states: tc.states}
+			rr := httptest.NewRecorder()
+			handleJobEvents(&spyglass.Spyglass{}, pjg, time.Millisecond)(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rr.Code != tc.code {
+				t.Fatalf("expected status %d, got %d: %s", tc.code, rr.Code, rr.Body.String())
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("expected an event stream, got %q", contentType)
+			}
+			if body := rr.Body.String(); body != tc.expected {
+				t.Errorf("expected events %q, got %q", tc.expected, body)
+			}
+		})
+	}
+}
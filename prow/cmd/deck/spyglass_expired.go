@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+)
+
+// serveExpired serves the page of a run whose artifacts have been deleted: the page last rendered
+// for it, if it's still cached, or else a page of what is still known about the run from its
+// ProwJob.
+func serveExpired(w http.ResponseWriter, sg *spyglass.Spyglass, cfg config.Getter, o options, pages *pageCache, src string, run spyglass.ExpiredRun) {
+	policy := template.HTML(cfg().Deck.Spyglass.RetentionPolicy)
+	if page, renderedAt, ok := pages.stalePage(src); ok {
+		serveExpiredCopy(w, page, renderedAt, policy)
+		return
+	}
+	page, err := renderExpired(sg, cfg, o, src, run)
+	if err != nil {
+		logrus.WithError(err).Error("error rendering expired spyglass page")
+		http.Error(w, fmt.Sprintf("error rendering spyglass page: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusGone)
+	w.Write(page)
+}
+
+// renderExpired renders the page of a run whose artifacts have been deleted.
+func renderExpired(sg *spyglass.Spyglass, cfg config.Getter, o options, src string, run spyglass.ExpiredRun) ([]byte, error) {
+	jobHistLink := ""
+	if jobPath, err := sg.JobPath(src); err == nil {
+		jobHistLink = path.Join("/job-history", jobPath)
+	}
+	prHistLink := ""
+	if org, repo, number, err := sg.RunToPR(src); err == nil {
+		prHistLink = "/pr-history?org=" + org + "&repo=" + repo + "&pr=" + strconv.Itoa(number)
+	}
+
+	type expiredTemplate struct {
+		JobName         string
+		BuildID         string
+		ProwJob         *prowapi.ProwJob
+		JobHistLink     string
+		PRHistLink      string
+		RetentionPolicy template.HTML
+	}
+	eTmpl := expiredTemplate{
+		JobName:         run.JobName,
+		BuildID:         run.BuildID,
+		ProwJob:         run.ProwJob,
+		JobHistLink:     jobHistLink,
+		PRHistLink:      prHistLink,
+		RetentionPolicy: template.HTML(cfg().Deck.Spyglass.RetentionPolicy),
+	}
+	t := template.New("spyglass-expired.html")
+	if _, err := prepareBaseTemplate(o, cfg, t); err != nil {
+		return nil, fmt.Errorf("error preparing base template: %v", err)
+	}
+	t, err := t.ParseFiles(path.Join(o.templateFilesLocation, "spyglass-expired.html"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, eTmpl); err != nil {
+		return nil, fmt.Errorf("error rendering template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
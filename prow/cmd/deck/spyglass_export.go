@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// handleExport serves a run's Spyglass page as a single self-contained HTML file to download,
+// for attaching to bug reports or keeping after the run's artifacts are deleted. The url specifies
+// the run the way /view/ urls do:
+//
+// /spyglass/export/<key-type>/<key>
+func handleExport(o options, sg *spyglass.Spyglass, cfg config.Getter, renders *renderPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src, err := sg.ResolveSymlink(exportedRun(r)[0])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to resolve src: %v", err), http.StatusBadRequest)
+			return
+		}
+		// An export renders every lens, but only takes one worker of the render pool.
+		release, ok := acquireRender(w, r, renders, src, exportRenderPriority)
+		if !ok {
+			return
+		}
+		defer release()
+
+		artifactNames, err := sg.ListArtifacts(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log := logrus.WithField("src", src)
+		matches := sg.MatchLenses(artifactNames)
+		var ls []spyglass.ExportLens
+		for _, lens := range sg.RunLenses(src, matches) {
+			lensConfig := lens.Config()
+			artifacts, err := fetchLensArtifacts(sg, cfg, lensConfig, src, matches[lensConfig.Name])
+			if err != nil {
+				log.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to fetch lens artifacts.")
+				continue
+			}
+			ls = append(ls, spyglass.ExportLens{
+				Lens:        lens,
+				ResourceDir: lenses.ResourceDirForLens(o.spyglassFilesLocation, lensConfig.Name),
+				Artifacts:   artifacts,
+				Config:      sg.LensConfig(lensConfig.Name, src),
+				Policy:      sg.BodyPolicy(lensConfig.Name),
+			})
+		}
+
+		var buf bytes.Buffer
+		if err := spyglass.Export(&buf, src, o.staticFilesLocation, ls); err != nil {
+			log.WithError(err).Warning("Failed to export run.")
+			http.Error(w, fmt.Sprintf("Failed to export run: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(sg, src)))
+		w.Write(buf.Bytes())
+	}
+}
+
+// exportFilename returns the name an export of a run is downloaded as.
+func exportFilename(sg *spyglass.Spyglass, src string) string {
+	jobName, buildID, err := sg.KeyToJob(src)
+	if err != nil {
+		return "spyglass-export.html"
+	}
+	return fmt.Sprintf("%s-%s.html", jobName, buildID)
+}
+
+// exportedRun returns the run an export is of.
+func exportedRun(r *http.Request) []string {
+	return []string{strings.TrimPrefix(r.URL.Path, "/spyglass/export/")}
+}
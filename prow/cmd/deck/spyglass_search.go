@@ -0,0 +1,243 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// searchWorkers is the number of artifacts of a run searched at once.
+	searchWorkers = 10
+	// searchMaxArtifactBytes is the size of the largest artifact that is searched.
+	searchMaxArtifactBytes = 200 << 20
+	// searchMaxBytes is the number of bytes of artifacts a single search may read.
+	searchMaxBytes = 1 << 30
+	// searchMaxMatches is the number of matches returned for each artifact.
+	searchMaxMatches = 100
+	// searchMaxContext is the largest number of lines of context given around a match.
+	searchMaxContext = 10
+)
+
+// searchLimits bound how much of a run a search reads.
+type searchLimits struct {
+	workers          int
+	maxArtifactBytes int64
+	maxBytes         int64
+	maxMatches       int
+	context          int
+}
+
+// searchMatch is a line of an artifact that matched a search.
+type searchMatch struct {
+	Line   int64    `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// searchResult is what a search found in one artifact of a run. Artifacts without matches are
+// left out of the results, unless they couldn't be searched.
+type searchResult struct {
+	Artifact string        `json:"artifact"`
+	Matches  []searchMatch `json:"matches,omitempty"`
+	// Truncated is set when the artifact has more matches than were returned.
+	Truncated bool `json:"truncated,omitempty"`
+	// Skipped says why an artifact wasn't searched.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleSearch greps the text artifacts of a run for a pattern, streaming what it finds in each
+// artifact as a line of JSON as soon as the artifact has been searched, so results arrive in no
+// particular order.
+// Query params:
+// - src: required, specifies the job source whose artifacts are searched
+// - q: required, the regular expression lines must match
+// - context: optional, the number of lines to return before and after each match
+func handleSearch(sg *spyglass.Spyglass, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		src := r.URL.Query().Get("src")
+		query := r.URL.Query().Get("q")
+		if src == "" || query == "" {
+			http.Error(w, "Both src and q must be specified.", http.StatusBadRequest)
+			return
+		}
+		pattern, err := regexp.Compile(query)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid pattern: %v", err), http.StatusBadRequest)
+			return
+		}
+		lines := 0
+		if c := r.URL.Query().Get("context"); c != "" {
+			if lines, err = strconv.Atoi(c); err != nil || lines < 0 {
+				http.Error(w, fmt.Sprintf("Invalid context %q.", c), http.StatusBadRequest)
+				return
+			}
+			if lines > searchMaxContext {
+				lines = searchMaxContext
+			}
+		}
+		artifactNames, err := sg.ListArtifacts(src)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list artifacts: %v", err), http.StatusNotFound)
+			return
+		}
+		artifacts, err := sg.FetchArtifacts(src, "", cfg().Deck.Spyglass.SizeLimit, artifactNames)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to retrieve artifacts: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		limits := searchLimits{
+			workers:          searchWorkers,
+			maxArtifactBytes: searchMaxArtifactBytes,
+			maxBytes:         searchMaxBytes,
+			maxMatches:       searchMaxMatches,
+			context:          lines,
+		}
+		searchArtifacts(r.Context(), artifacts, pattern, limits, func(result searchResult) {
+			if err := encoder.Encode(result); err != nil {
+				logrus.WithError(err).WithField("source", src).Debug("Failed to write search result.")
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		})
+	}
+}
+
+// searchArtifacts greps the text artifacts among artifacts for pattern, a few at a time, and
+// passes what it finds in each to emit, one call at a time. Artifacts larger than the limits
+// allow are reported as skipped, as are any left once ctx is done.
+func searchArtifacts(ctx context.Context, artifacts []lenses.Artifact, pattern *regexp.Regexp, limits searchLimits, emit func(searchResult)) {
+	queue := make(chan lenses.Artifact)
+	results := make(chan searchResult)
+	var budget sync.Mutex
+	remaining := limits.maxBytes
+	// reserve claims the bytes an artifact needs from the search's budget, if there are enough.
+	reserve := func(size int64) bool {
+		budget.Lock()
+		defer budget.Unlock()
+		if size > remaining {
+			return false
+		}
+		remaining -= size
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < limits.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for artifact := range queue {
+				if result, found := searchArtifact(ctx, artifact, pattern, limits, reserve); found {
+					results <- result
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(queue)
+		for _, artifact := range artifacts {
+			queue <- artifact
+		}
+	}()
+	for result := range results {
+		emit(result)
+	}
+}
+
+// searchArtifact greps a single artifact, returning whether there's anything to report about it.
+func searchArtifact(ctx context.Context, artifact lenses.Artifact, pattern *regexp.Regexp, limits searchLimits, reserve func(int64) bool) (searchResult, bool) {
+	result := searchResult{Artifact: artifact.JobPath()}
+	if ctx.Err() != nil {
+		result.Skipped = "the search was cancelled"
+		return result, true
+	}
+	size, err := artifact.Size()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to get artifact size: %v", err)
+		return result, true
+	}
+	if size > limits.maxArtifactBytes {
+		result.Skipped = fmt.Sprintf("the artifact is larger than %d bytes", limits.maxArtifactBytes)
+		return result, true
+	}
+	head, err := artifact.ReadAtMost(sniffLen)
+	if err != nil && err != io.EOF {
+		result.Error = fmt.Sprintf("failed to read artifact: %v", err)
+		return result, true
+	}
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	if !isTextArtifact(artifact.JobPath(), head) {
+		return result, false
+	}
+	if !reserve(size) {
+		result.Skipped = fmt.Sprintf("the search read its limit of %d bytes", limits.maxBytes)
+		return result, true
+	}
+
+	// One more match than is returned is looked for, to tell whether there are more.
+	matches, err := lenses.Grep(artifact, pattern, limits.context, limits.context, limits.maxMatches+1)
+	if err != nil {
+		result.Error = err.Error()
+		return result, true
+	}
+	if len(matches) > limits.maxMatches {
+		matches = matches[:limits.maxMatches]
+		result.Truncated = true
+	}
+	for _, match := range matches {
+		result.Matches = append(result.Matches, searchMatch{
+			Line:   match.Line,
+			Text:   match.Text,
+			Before: match.Before,
+			After:  match.After,
+		})
+	}
+	return result, len(result.Matches) > 0
+}
+
+// isTextArtifact returns whether an artifact holds text worth searching, judging by its name
+// and its first bytes the way the artifact proxy decides whether to serve it as text.
+func isTextArtifact(name string, head []byte) bool {
+	return safeContentType(name, head) == "text/plain; charset=utf-8"
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestSearchArtifacts(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeArtifact{path: "build-log.txt", content: []byte("starting\npod foo-123 created\ndone\n")},
+		&fakeArtifact{path: "artifacts/kubelet.log", content: []byte("pod foo-123 started\npod foo-123 running\npod foo-123 deleted\n")},
+		&fakeArtifact{path: "artifacts/unrelated.log", content: []byte("nothing to see here\n")},
+		&fakeArtifact{path: "artifacts/screenshot.png", content: []byte("\x89PNG\r\n\x1a\npod foo-123\n")},
+		&fakeArtifact{path: "artifacts/huge.log", content: []byte("pod foo-123 is mentioned in a log that is far too large to be searched\n")},
+	}
+	limits := searchLimits{
+		workers:          2,
+		maxArtifactBytes: 70,
+		maxBytes:         1000,
+		maxMatches:       2,
+		context:          1,
+	}
+	var results []searchResult
+	searchArtifacts(context.Background(), artifacts, regexp.MustCompile(`foo-\d+`), limits, func(result searchResult) {
+		results = append(results, result)
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Artifact < results[j].Artifact })
+
+	expected := []searchResult{
+		{
+			Artifact: "artifacts/huge.log",
+			Skipped:  "the artifact is larger than 70 bytes",
+		},
+		{
+			Artifact: "artifacts/kubelet.log",
+			Matches: []searchMatch{
+				{Line: 1, Text: "pod foo-123 started", After: []string{"pod foo-123 running"}},
+				{Line: 2, Text: "pod foo-123 running", Before: []string{"pod foo-123 started"}, After: []string{"pod foo-123 deleted"}},
+			},
+			Truncated: true,
+		},
+		{
+			Artifact: "build-log.txt",
+			Matches: []searchMatch{
+				{Line: 2, Text: "pod foo-123 created", Before: []string{"starting"}, After: []string{"done"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected results %+v, got %+v", expected, results)
+	}
+}
+
+func TestSearchArtifactsByteLimit(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeArtifact{path: "first.log", content: []byte("match\n")},
+		&fakeArtifact{path: "second.log", content: []byte("match\n")},
+	}
+	limits := searchLimits{workers: 1, maxArtifactBytes: 100, maxBytes: 10, maxMatches: 10}
+	var results []searchResult
+	searchArtifacts(context.Background(), artifacts, regexp.MustCompile("match"), limits, func(result searchResult) {
+		results = append(results, result)
+	})
+	expected := []searchResult{
+		{Artifact: "first.log", Matches: []searchMatch{{Line: 1, Text: "match"}}},
+		{Artifact: "second.log", Skipped: "the search read its limit of 10 bytes"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected results %+v, got %+v", expected, results)
+	}
+}
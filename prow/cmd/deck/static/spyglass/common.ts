@@ -26,6 +26,17 @@ export interface UpdatePageMessage extends BaseMessage {
   data: string;
 }
 
+export interface ShowLensMessage extends BaseMessage {
+  type: 'showLens';
+  lens: string;
+  data: string;
+}
+
+export interface LinkAnchorMessage extends BaseMessage {
+  type: 'linkAnchor';
+  anchor: string;
+}
+
 export interface Response extends BaseMessage {
   type: 'response';
   data: string;
@@ -35,7 +46,7 @@ export function isResponse(data: any): data is Response {
   return isBaseMessage(data) && data.type === 'response';
 }
 
-export type Message = ContentUpdatedMessage | RequestMessage | RequestPageMessage | UpdatePageMessage | Response;
+export type Message = ContentUpdatedMessage | RequestMessage | RequestPageMessage | UpdatePageMessage | ShowLensMessage | LinkAnchorMessage | Response;
 
 export interface TransitMessage {
   id: number;
@@ -32,6 +32,41 @@ export interface Spyglass {
    * the visible content changes, so Spyglass can ensure that all content is visible.
    */
   contentUpdated(): void;
+  /**
+   * Shows a view of another lens, replacing what it shows, and scrolls to it.
+   * The view is linked to from the page's fragment, as "#<lens>/<request>".
+   *
+   * @param lens The name of the lens.
+   * @param request The view to show, such as "line/12?artifact=build-log.txt"
+   *                or "iframe?data=..." to render the lens's body from data.
+   */
+  showLens(lens: string, request: string): void;
+  /**
+   * Links the page to a part of the lens's view, as "#<lens>:<anchor>", so that
+   * the link can be shared. Spyglass renders the lens showing that part when the
+   * link is opened. Clicking an element with the class "anchor-link" and a
+   * data-anchor attribute links to its anchor.
+   *
+   * @param anchor The anchor naming the part of the view, which lenses that
+   *               implement AnchorLens understand.
+   */
+  linkAnchor(anchor: string): void;
+  /**
+   * Returns a URL from which the raw contents of the given artifact can be
+   * fetched. The URL supports HTTP range requests, so it is suitable for use
+   * as the source of <video> and <audio> elements.
+   *
+   * @param artifact The path of the artifact, as returned by JobPath().
+   */
+  artifactURL(artifact: string): string;
+  /**
+   * Returns a URL from which the given HTML artifact can be loaded as a page.
+   * The page is served with a content security policy that sandboxes it and
+   * forbids it from loading anything, so it should be self-contained.
+   *
+   * @param artifact The path of the artifact, as returned by JobPath().
+   */
+  reportURL(artifact: string): string;
 }
 
 class SpyglassImpl implements Spyglass {
@@ -44,7 +79,8 @@ class SpyglassImpl implements Spyglass {
   }
 
   public async updatePage(data: string): Promise<void> {
-    await this.postMessage({type: 'updatePage', data});
+    const result = await this.postMessage({type: 'updatePage', data});
+    document.body.innerHTML = result.data;
     this.contentUpdated();
   }
   public async requestPage(data: string): Promise<string> {
@@ -62,6 +98,30 @@ class SpyglassImpl implements Spyglass {
     this.pendingUpdateTimer = setTimeout(() => this.updateHeight(), 0);
   }
 
+  public showLens(lens: string, request: string): void {
+    this.postMessage({type: 'showLens', lens, data: request}).then();
+  }
+
+  public linkAnchor(anchor: string): void {
+    this.postMessage({type: 'linkAnchor', anchor}).then();
+  }
+
+  public artifactURL(artifact: string): string {
+    return `/spyglass/artifact?${this.artifactParams(artifact)}`;
+  }
+
+  public reportURL(artifact: string): string {
+    return `/spyglass/report?${this.artifactParams(artifact)}`;
+  }
+
+  private artifactParams(artifact: string): string {
+    const req = JSON.parse(new URLSearchParams(location.search).get('req') || '{}');
+    const params = new URLSearchParams();
+    params.set('src', req.src);
+    params.set('artifact', artifact);
+    return params.toString();
+  }
+
   private updateHeight(): void {
     // .then() to suppress complaints about unhandled promises (we just don't care here).
     this.postMessage({type: 'contentUpdated', height: document.body.offsetHeight}).then();
@@ -94,8 +154,37 @@ class SpyglassImpl implements Spyglass {
 
 const spyglass = new SpyglassImpl();
 
+// Links to the anchor of an anchor link. Links are handled before the elements they are in, which
+// may fold or unfold when clicked.
+function handleAnchorLink(e: MouseEvent): void {
+  if (!(e.target instanceof Element)) {
+    return;
+  }
+  const link = e.target.closest('a.anchor-link') as HTMLAnchorElement | null;
+  if (!link || !link.dataset.anchor) {
+    return;
+  }
+  e.preventDefault();
+  e.stopPropagation();
+  spyglass.linkAnchor(link.dataset.anchor);
+}
+
+// Scrolls to the part of the view a link shows, which lenses mark with the class "anchor-target".
+// Spyglass resizes the lens to fit its content after it loads, so this is done again then.
+function scrollToAnchor(): void {
+  const target = document.querySelector('.anchor-target');
+  if (!target) {
+    return;
+  }
+  const scroll = () => target.scrollIntoView({block: 'center'});
+  scroll();
+  window.addEventListener('resize', scroll, {once: true});
+}
+
 window.addEventListener('load', () => {
   spyglass.contentUpdated();
+  scrollToAnchor();
 });
+document.addEventListener('click', handleAnchorLink, true);
 
 (window as any).spyglass = spyglass;
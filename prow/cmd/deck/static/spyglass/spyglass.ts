@@ -3,25 +3,153 @@ import { isTransitMessage } from "./common";
 declare const src: string;
 declare const lensArtifacts: {[key: string]: string[]};
 declare const lenses: string[];
+// Set on pages comparing two runs, to the src of the run compared to and the artifacts of it
+// each lens compares.
+declare const compareSrc: string | undefined;
+declare const compareLensArtifacts: {[key: string]: string[]} | undefined;
+// Set on pages of runs that haven't finished, to the url of the events sent when the state of
+// their ProwJob changes.
+declare const jobEvents: string | undefined;
+// Set on pages of runs with lenses that match more artifacts than they are shown at once, to the
+// number of artifacts each of those lenses matches.
+declare const lensArtifactCounts: {[key: string]: number} | undefined;
+
+// Identifies this load of the page to Deck, which shares the artifacts it fetches for one lens
+// request of a page with the other requests made for the page.
+const renderID = Math.random().toString(36).slice(2);
+
+// How many lens views are loaded at once. Lenses are loaded from the top of the page down, so that
+// the views seen first don't wait behind slower lenses further down for the browser's connections
+// to Deck.
+const concurrentLensLoads = 4;
+
+// The pages of artifacts loaded after the first for lenses that match more than they are shown at
+// once, by lens. Each page is shown in its own view below the first.
+const laterArtifactPages: {[key: string]: string[][]} = {};
 
 // Loads views for this job
 function loadLenses(): void {
-  for (const lens of lenses) {
+  // The lens a link shows is loaded first, so that its view is ready as soon as possible.
+  const linked = lenses.filter((lens) => linkedRequest(lens) !== null);
+  const queue = linked.concat(lenses.filter((lens) => linked.indexOf(lens) === -1));
+  const loadNext = (): void => {
+    const lens = queue.shift();
+    if (lens === undefined) {
+      return;
+    }
     const frame = document.querySelector<HTMLIFrameElement>(`#iframe-${lens}`)!;
-    frame.src = urlForLensRequest(lens, 'iframe');
+    if (frame.hasAttribute('src')) {
+      // Another lens already linked to a view of this one.
+      loadNext();
+      return;
+    }
+    // The next lens is loaded once this one's view has loaded, or failed to.
+    frame.addEventListener('load', loadNext, {once: true});
+    frame.src = urlForLensRequest(lens, linkedRequest(lens) || 'iframe');
+  };
+  for (let i = 0; i < concurrentLensLoads; i++) {
+    loadNext();
+  }
+  if (typeof lensArtifactCounts === 'object') {
+    for (const lens of Object.keys(lensArtifactCounts)) {
+      addShowMoreButton(lens);
+    }
+  }
+}
+
+// Adds a button below a lens that matches more artifacts than it was shown, which shows the lens
+// again for the next page of its artifacts each time it is clicked.
+function addShowMoreButton(lens: string): void {
+  const container = document.querySelector<HTMLElement>(`#${lens}-view-container`);
+  if (!container || !lensArtifactCounts) {
+    return;
+  }
+  const total = lensArtifactCounts[lens];
+  let shown = lensArtifacts[lens].length;
+  const button = document.createElement('button');
+  button.className = 'mdl-button mdl-js-button show-more-artifacts';
+  const label = () => `Show more artifacts (${shown} of ${total} shown)`;
+  button.textContent = label();
+  button.addEventListener('click', async () => {
+    button.disabled = true;
+    button.textContent = 'Loading artifacts...';
+    const pages = laterArtifactPages[lens] || (laterArtifactPages[lens] = []);
+    const page = pages.length + 1;
+    const params = new URLSearchParams();
+    params.set('src', src);
+    params.set('lens', lens);
+    params.set('page', String(page));
+    const resp = await fetch(`/spyglass/artifacts?${params.toString()}`);
+    if (!resp.ok) {
+      button.disabled = false;
+      button.textContent = `Failed to load artifacts: ${await resp.text()}`;
+      return;
+    }
+    const {artifacts, more} = await resp.json();
+    pages.push(artifacts);
+    shown += artifacts.length;
+    container.appendChild(frameForPage(lens, page));
+    if (more) {
+      button.disabled = false;
+      button.textContent = label();
+    } else {
+      button.remove();
+    }
+  });
+  container.insertAdjacentElement('afterend', button);
+}
+
+// Returns a frame showing a lens's view of a later page of its artifacts.
+function frameForPage(lens: string, page: number): HTMLIFrameElement {
+  const first = document.querySelector<HTMLIFrameElement>(`#iframe-${lens}`)!;
+  const frame = first.cloneNode(false) as HTMLIFrameElement;
+  frame.id = `iframe-${lens}-${page}`;
+  frame.dataset.page = String(page);
+  frame.style.visibility = 'hidden';
+  frame.src = urlForLensRequest(lens, 'iframe', page);
+  return frame;
+}
+
+// Returns the page of its artifacts that a frame shows a lens's view of.
+function pageOfFrame(frame: HTMLIFrameElement): number {
+  return Number(frame.dataset.page || '0');
+}
+
+// Returns the request a link to a lens view asks for, if the page was linked to one. Links to
+// views are fragments of the form "#<lens>/<request>", such as
+// "#buildlog/line/12?artifact=build-log.txt", or "#<lens>:<anchor>" to show the part of a lens's
+// view that an anchor names, such as "#junit:testfoo".
+function linkedRequest(lens: string): string | null {
+  const anchorPrefix = `#${lens}:`;
+  if (location.hash.startsWith(anchorPrefix)) {
+    return `anchor/${encodeURIComponent(decodeURIComponent(location.hash.slice(anchorPrefix.length)))}`;
+  }
+  const prefix = `#${lens}/`;
+  if (!location.hash.startsWith(prefix)) {
+    return null;
   }
+  return location.hash.slice(prefix.length);
 }
 
-function queryForLens(lens: string): string {
-  const data = {
-    artifacts: lensArtifacts[lens],
+function queryForLens(lens: string, page: number): string {
+  const data: {[key: string]: string | string[] | number} = {
+    artifacts: page > 0 ? laterArtifactPages[lens][page - 1] : lensArtifacts[lens],
+    render_id: renderID,
     src,
   };
+  if (page > 0) {
+    data.page = page;
+  }
+  if (typeof compareSrc === 'string' && typeof compareLensArtifacts === 'object') {
+    data.compare_src = compareSrc;
+    data.compare_artifacts = compareLensArtifacts[lens] || [];
+  }
   return `req=${encodeURIComponent(JSON.stringify(data))}`;
 }
 
-function urlForLensRequest(lens: string, request: string): string {
-  return `/spyglass/lens/${lens}/${request}?${queryForLens(lens)}`;
+function urlForLensRequest(lens: string, request: string, page = 0): string {
+  const [path, query] = request.split('?');
+  return `/spyglass/lens/${lens}/${path}?${query ? `${query}&` : ''}${queryForLens(lens, page)}`;
 }
 
 function frameForMessage(e: MessageEvent): HTMLIFrameElement {
@@ -39,6 +167,7 @@ window.addEventListener('message', async (e) => {
     const {id, message} = data;
     const frame = frameForMessage(e);
     const lens = frame.dataset.lens!;
+    const page = pageOfFrame(frame);
 
     const respond = (response: string): void => {
       frame.contentWindow!.postMessage({id, message: {type: 'response', data: response}}, '*');
@@ -51,30 +180,49 @@ window.addEventListener('message', async (e) => {
         if (frame.dataset.hideTitle) {
           frame.parentElement!.parentElement!.classList.add('hidden-title');
         }
-        document.querySelector<HTMLElement>(`#${lens}-loading`)!.style.display = 'none';
+        if (page === 0) {
+          document.querySelector<HTMLElement>(`#${lens}-loading`)!.style.display = 'none';
+        }
         respond('');
         break;
       case "request": {
-        const req = await fetch(urlForLensRequest(lens, 'callback'),
+        const req = await fetch(urlForLensRequest(lens, 'callback', page),
           {body: message.data, method: 'POST'});
         respond(await req.text());
         break;
       }
       case "requestPage": {
-        const req = await fetch(urlForLensRequest(lens, 'rerender'),
+        const req = await fetch(urlForLensRequest(lens, 'rerender', page),
           {body: message.data, method: 'POST'});
         respond(await req.text());
         break;
       }
       case "updatePage": {
-        const spinner = document.querySelector<HTMLElement>(`#${lens}-loading`)!;
         frame.style.visibility = 'visible';
-        spinner.style.display = 'block';
-        const req = await fetch(urlForLensRequest(lens, 'rerender'),
+        if (page === 0) {
+          document.querySelector<HTMLElement>(`#${lens}-loading`)!.style.display = 'block';
+        }
+        const req = await fetch(urlForLensRequest(lens, 'rerender', page),
           {body: message.data, method: 'POST'});
         respond(await req.text());
         break;
       }
+      case "showLens": {
+        const target = document.querySelector<HTMLIFrameElement>(`#iframe-${message.lens}`);
+        if (target) {
+          history.replaceState(null, '', `#${message.lens}/${message.data}`);
+          document.querySelector<HTMLElement>(`#${message.lens}-loading`)!.style.display = 'block';
+          target.src = urlForLensRequest(message.lens, message.data);
+          target.scrollIntoView();
+        }
+        respond('');
+        break;
+      }
+      case "linkAnchor": {
+        history.replaceState(null, '', `#${lens}:${encodeURIComponent(message.anchor)}`);
+        respond('');
+        break;
+      }
       default:
         console.warn(`Unrecognised message type "${message.type}" from lens "${lens}":`, data);
         break;
@@ -82,7 +230,37 @@ window.addEventListener('message', async (e) => {
   }
 });
 
+// Shows the state of the job of a run that hasn't finished as it changes, and reloads the page
+// once the job finishes so that its lenses are shown with all of its artifacts. Pages of jobs that
+// had already finished when the page was rendered, but without a finished.json, aren't reloaded.
+function watchJobState(): void {
+  if (typeof jobEvents !== 'string' || typeof EventSource === 'undefined') {
+    return;
+  }
+  const events = new EventSource(jobEvents);
+  let sawUnfinished = false;
+  events.addEventListener('state', (e) => {
+    const {state, finished} = JSON.parse((e as MessageEvent).data);
+    document.querySelector<HTMLElement>('#job-state')!.textContent = `Job ${state}`;
+    if (!finished) {
+      sawUnfinished = true;
+      return;
+    }
+    events.close();
+    if (sawUnfinished) {
+      location.reload();
+    }
+  });
+  events.addEventListener('error', () => {
+    // The browser stops reconnecting once the job can't be found.
+    if (events.readyState === EventSource.CLOSED) {
+      document.querySelector<HTMLElement>('#job-state')!.textContent = '';
+    }
+  });
+}
+
 // We can't use DOMContentLoaded here or we end up with a bunch of flickering. This appears to be MDL's fault.
 window.addEventListener('load', () => {
     loadLenses();
+    watchJobState();
 });
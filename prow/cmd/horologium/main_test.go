@@ -107,13 +107,14 @@ func TestSync(t *testing.T) {
 			shouldStart:     false,
 		},
 	}
+	annotations := map[string]string{"spyglass.prow.k8s.io/lenses": "junit"}
 	for _, tc := range testcases {
 		cfg := config.Config{
 			ProwConfig: config.ProwConfig{
 				ProwJobNamespace: "prowjobs",
 			},
 			JobConfig: config.JobConfig{
-				Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "j"}}},
+				Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "j", Annotations: annotations}}},
 			},
 		}
 		cfg.Periodics[0].SetInterval(time.Minute)
@@ -148,9 +149,13 @@ func TestSync(t *testing.T) {
 
 		sawCreation := false
 		for _, action := range fakeProwJobClient.Fake.Actions() {
-			switch action.(type) {
+			switch action := action.(type) {
 			case clienttesting.CreateActionImpl:
 				sawCreation = true
+				pj := action.GetObject().(*prowapi.ProwJob)
+				if actual := pj.Annotations["spyglass.prow.k8s.io/lenses"]; actual != "junit" {
+					t.Errorf("For case %s, expected the job's annotations on the created ProwJob, got %v", tc.testName, pj.Annotations)
+				}
 			}
 		}
 		if tc.shouldStart != sawCreation {
@@ -190,13 +195,14 @@ func TestSyncCron(t *testing.T) {
 			shouldStart: true,
 		},
 	}
+	annotations := map[string]string{"spyglass.prow.k8s.io/lenses": "junit"}
 	for _, tc := range testcases {
 		cfg := config.Config{
 			ProwConfig: config.ProwConfig{
 				ProwJobNamespace: "prowjobs",
 			},
 			JobConfig: config.JobConfig{
-				Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "j"}, Cron: "@every 1m"}},
+				Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "j", Annotations: annotations}, Cron: "@every 1m"}},
 			},
 		}
 
@@ -230,9 +236,13 @@ func TestSyncCron(t *testing.T) {
 
 		sawCreation := false
 		for _, action := range fakeProwJobClient.Fake.Actions() {
-			switch action.(type) {
+			switch action := action.(type) {
 			case clienttesting.CreateActionImpl:
 				sawCreation = true
+				pj := action.GetObject().(*prowapi.ProwJob)
+				if actual := pj.Annotations["spyglass.prow.k8s.io/lenses"]; actual != "junit" {
+					t.Errorf("For case %s, expected the job's annotations on the created ProwJob, got %v", tc.testName, pj.Annotations)
+				}
 			}
 		}
 		if tc.shouldStart != sawCreation {
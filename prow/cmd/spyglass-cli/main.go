@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// spyglass-cli renders the Spyglass lenses of a job run without Deck, for lens authors and for
+// triaging runs offline.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/spyglass"
+
+	// Import standard spyglass viewers
+
+	_ "k8s.io/test-infra/prow/spyglass/lenses/apicoverage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/audit"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/benchstat"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/bep"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/boskos"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/buildlog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/changedfiles"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/diff"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/dmesg"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/events"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/evtx"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/fuzz"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/ginkgo"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/goroutines"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/gotest"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/htmlreport"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/imagebuild"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/images"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/jsonview"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/junit"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/kindlogs"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/licenses"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/links"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/markdown"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/metadata"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/oom"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/perftests"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/pprof"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/prometheus"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/protoview"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/pytest"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/resources"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/sarif"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/sonobuoy"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/syslog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/tap"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/terraform"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/timeline"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/trace"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/triage"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/video"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/webtests"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/yamlview"
+)
+
+const usage = `Usage: spyglass-cli render [flags] <gs://bucket/path/to/run | directory>
+       spyglass-cli export [flags] <gs://bucket/path/to/run | directory>
+
+Renders the lenses that match the artifacts of a job run, stored in GCS or downloaded to a local
+directory. render writes the pages they render to the output directory, while export writes them
+to a single self-contained HTML file.
+
+Flags:
+`
+
+type options struct {
+	command string
+	src     string
+
+	configPath            string
+	outputDir             string
+	output                string
+	spyglassFilesLocation string
+	staticFilesLocation   string
+	gcsCredentialsFile    string
+	open                  bool
+}
+
+func (o *options) Validate() error {
+	if o.src == "" {
+		return errors.New("a gs:// path or directory to render is required")
+	}
+	if o.command == "render" && o.outputDir == "" {
+		return errors.New("required flag --output-dir was unset")
+	}
+	if o.command == "export" && o.output == "" {
+		return errors.New("required flag --output was unset")
+	}
+	return nil
+}
+
+func gatherOptions(fs *flag.FlagSet, args []string) (options, error) {
+	o := options{}
+	fs.StringVar(&o.configPath, "config-path", "", "Path to a Prow config to read the Spyglass viewers and lens config from. If unset, the metadata, buildlog and junit lenses are shown for their usual artifacts.")
+	fs.StringVar(&o.outputDir, "output-dir", "spyglass-output", "Directory to write the rendered lenses to.")
+	fs.StringVar(&o.output, "output", "spyglass-export.html", "File to export the rendered lenses to.")
+	fs.StringVar(&o.spyglassFilesLocation, "spyglass-files-location", "/lenses", "Location of the static files for spyglass, e.g. prow/spyglass/lenses in a checkout of test-infra.")
+	fs.StringVar(&o.staticFilesLocation, "static-files-location", "/static", "Path to Deck's static files, which the rendered pages are styled with if present.")
+	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file. If unset, GCS is read without authentication.")
+	fs.BoolVar(&o.open, "open", false, "Open the rendered lenses in a browser.")
+	if len(args) == 0 || (args[0] != "render" && args[0] != "export") {
+		return o, errors.New("expected the render or export command")
+	}
+	o.command = args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return o, err
+	}
+	if fs.NArg() > 1 {
+		return o, fmt.Errorf("expected one gs:// path or directory, got %q", fs.Args())
+	}
+	o.src = fs.Arg(0)
+	return o, o.Validate()
+}
+
+func main() {
+	logrus.SetFormatter(
+		logrusutil.NewDefaultFieldsFormatter(&logrus.TextFormatter{}, logrus.Fields{"component": "spyglass-cli"}),
+	)
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	o, err := gatherOptions(fs, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(o.configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading config.")
+	}
+	src, err := newRunSource(o, cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error opening job run.")
+	}
+	var index string
+	if o.command == "export" {
+		index, err = exportRun(o, cfg, src)
+	} else {
+		index, err = renderRun(o, cfg, src)
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Error rendering lenses.")
+	}
+	logrus.Infof("Wrote rendered lenses to %s.", index)
+
+	if o.open {
+		if err := openBrowser(index); err != nil {
+			logrus.WithError(err).Fatal("Error opening browser.")
+		}
+	}
+}
+
+// newRunSource returns where the artifacts of the run to render are read from: GCS for storage
+// URLs such as gs:// paths, or else a local directory.
+func newRunSource(o options, cfg config.Getter) (runSource, error) {
+	if !strings.Contains(o.src, "://") {
+		if info, err := os.Stat(o.src); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", o.src)
+		}
+		return &dirRunSource{dir: o.src, cfg: cfg, sizeLimit: cfg().Deck.Spyglass.SizeLimit}, nil
+	}
+	ctx := context.Background()
+	var c *storage.Client
+	var err error
+	if o.gcsCredentialsFile == "" {
+		c, err = storage.NewClient(ctx, option.WithoutAuthentication())
+	} else {
+		c, err = storage.NewClient(ctx, option.WithCredentialsFile(o.gcsCredentialsFile))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting GCS client: %v", err)
+	}
+	sg := spyglass.New(nil, cfg, c, ctx)
+	src, err := sg.ResolveSymlink(o.src)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s: %v", o.src, err)
+	}
+	return &gcsRunSource{sg: sg, cfg: cfg, src: src}, nil
+}
+
+// openBrowser opens a file in the default browser.
+func openBrowser(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", file)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", file)
+	default:
+		cmd = exec.Command("xdg-open", file)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/sanitize"
+)
+
+// defaultViewers are the lenses shown when no Prow config is given, matching the usual
+// artifacts of a decorated job.
+var defaultViewers = map[string][]string{
+	"started.json|finished.json|prowjob.json": {"metadata"},
+	"build-log.txt":          {"buildlog"},
+	`artifacts/junit.*\.xml`: {"junit"},
+}
+
+// staticFiles are the files of Deck's static files that rendered lenses are styled and scripted
+// with, relative to them.
+var staticFiles = []string{"spyglass/lens.css", "spyglass_lens_bundle.min.js"}
+
+var lensTemplate = template.Must(template.New("lens").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Spyglass Lens: {{.Title}}</title>
+  <base href="{{.BaseURL}}">
+  <link rel="stylesheet" href="https://code.getmdl.io/1.3.0/material.indigo-pink.min.css">
+  <link href="https://fonts.googleapis.com/css?family=Roboto:400,700" rel="stylesheet">
+  <link rel="stylesheet" href="https://fonts.googleapis.com/icon?family=Material+Icons">
+  <link rel="stylesheet" href="../../static/spyglass/lens.css">
+  <script src="../../static/spyglass_lens_bundle.min.js"></script>
+  {{.Head}}
+</head>
+<body class="lens-body">
+  {{.Body}}
+</body>
+</html>
+`))
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Spyglass: {{.Source}}</title>
+</head>
+<body>
+  <h1>{{.Source}}</h1>
+  {{if .Lenses}}
+  <ul>
+    {{range .Lenses}}
+    <li><a href="{{.Page}}">{{.Title}}</a>: {{range $i, $a := .Artifacts}}{{if $i}}, {{end}}{{$a}}{{end}}</li>
+    {{end}}
+  </ul>
+  {{else}}
+  <p>No lenses matched the artifacts of this run.</p>
+  {{end}}
+</body>
+</html>
+`))
+
+// renderedLens is a lens listed on the index of rendered lenses.
+type renderedLens struct {
+	Title     string
+	Page      string
+	Artifacts []string
+}
+
+// runSource is where the artifacts of the run being rendered are read from.
+type runSource interface {
+	// String describes the run.
+	String() string
+	// list returns the names of the run's artifacts.
+	list() ([]string, error)
+	// fetch returns the named artifacts for a lens, along with the baseline and sibling
+	// artifacts it asks for.
+	fetch(lensConfig lenses.LensConfig, names []string) ([]lenses.Artifact, error)
+	// lensConfig returns the configuration of the named lens for the run.
+	lensConfig(lens string) json.RawMessage
+	// annotations returns the annotations of the run's ProwJob, if it is known.
+	annotations() map[string]string
+}
+
+// gcsRunSource reads the artifacts of a run from GCS, the way Deck does.
+type gcsRunSource struct {
+	sg  *spyglass.Spyglass
+	cfg config.Getter
+	src string
+}
+
+func (s *gcsRunSource) String() string {
+	return "gs://" + strings.TrimPrefix(s.src, "gcs/")
+}
+
+func (s *gcsRunSource) list() ([]string, error) {
+	return s.sg.ListArtifacts(s.src)
+}
+
+func (s *gcsRunSource) fetch(lensConfig lenses.LensConfig, names []string) ([]lenses.Artifact, error) {
+	sizeLimit := s.cfg().Deck.Spyglass.SizeLimit
+	fetched, err := s.sg.FetchArtifacts(s.src, "", sizeLimit, names)
+	if err != nil {
+		return nil, err
+	}
+	// Runs without a build log in GCS fall back to the log of their pod, which only Deck can reach.
+	var artifacts []lenses.Artifact
+	for _, a := range fetched {
+		if _, ok := a.(*spyglass.PodLogArtifact); !ok {
+			artifacts = append(artifacts, a)
+		}
+	}
+	if lensConfig.Baseline {
+		baseline, err := s.sg.FetchBaselineArtifacts(s.src, sizeLimit, names)
+		if err != nil {
+			logrus.WithError(err).WithField("src", s.src).Debug("No baseline artifacts.")
+		}
+		artifacts = append(artifacts, baseline...)
+	}
+	if lensConfig.Siblings {
+		siblings, err := s.sg.FetchSiblingArtifacts(s.src, sizeLimit, names)
+		if err != nil {
+			logrus.WithError(err).WithField("src", s.src).Warning("Failed to list sibling artifacts.")
+		}
+		artifacts = append(artifacts, siblings...)
+	}
+	return artifacts, nil
+}
+
+func (s *gcsRunSource) lensConfig(lens string) json.RawMessage {
+	return s.sg.LensConfig(lens, s.src)
+}
+
+func (s *gcsRunSource) annotations() map[string]string {
+	return s.sg.JobAnnotations(s.src)
+}
+
+// dirRunSource reads the artifacts of a run from a local directory they were downloaded to.
+// Local runs have no baseline.
+type dirRunSource struct {
+	dir       string
+	cfg       config.Getter
+	sizeLimit int64
+}
+
+func (s *dirRunSource) String() string {
+	return s.dir
+}
+
+func (s *dirRunSource) list() ([]string, error) {
+	return spyglass.ListLocalArtifacts(s.dir)
+}
+
+func (s *dirRunSource) artifact(name string) lenses.Artifact {
+	return spyglass.NewLocalArtifact(filepath.Join(s.dir, filepath.FromSlash(name)), name, s.sizeLimit)
+}
+
+func (s *dirRunSource) fetch(lensConfig lenses.LensConfig, names []string) ([]lenses.Artifact, error) {
+	var artifacts []lenses.Artifact
+	matched := map[string]bool{}
+	for _, name := range names {
+		artifacts = append(artifacts, s.artifact(name))
+		matched[name] = true
+	}
+	if lensConfig.Siblings {
+		all, err := s.list()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range all {
+			if !matched[name] {
+				artifacts = append(artifacts, lenses.NewSiblingArtifact(s.artifact(name)))
+			}
+		}
+	}
+	return artifacts, nil
+}
+
+func (s *dirRunSource) lensConfig(lens string) json.RawMessage {
+	return spyglass.MergeLensConfig(lens, s.cfg().Deck.Spyglass.LensConfig[lens], s.annotations())
+}
+
+// annotations reads the annotations of the run's ProwJob from its prowjob.json, if it was
+// downloaded.
+func (s *dirRunSource) annotations() map[string]string {
+	content, err := s.artifact("prowjob.json").ReadAll()
+	if err != nil {
+		return nil
+	}
+	var job prowapi.ProwJob
+	if err := json.Unmarshal(content, &job); err != nil {
+		logrus.WithError(err).Debug("Couldn't parse prowjob.json for its annotations.")
+		return nil
+	}
+	return job.Annotations
+}
+
+// loadConfig loads the Prow config at path, or if there is none, a config that shows the
+// default viewers.
+func loadConfig(path string) (config.Getter, error) {
+	var c *config.Config
+	if path != "" {
+		var err error
+		if c, err = config.Load(path, ""); err != nil {
+			return nil, err
+		}
+	} else {
+		c = &config.Config{}
+		c.Deck.Spyglass.Viewers = defaultViewers
+		c.Deck.Spyglass.SizeLimit = 100e6
+		c.Deck.Spyglass.RegexCache = map[string]*regexp.Regexp{}
+		for re := range defaultViewers {
+			c.Deck.Spyglass.RegexCache[re] = regexp.MustCompile(re)
+		}
+	}
+	return func() *config.Config { return c }, nil
+}
+
+// renderRun renders every lens that matches the artifacts of a run into a page of the output
+// directory, along with an index of the pages, and returns the path of the index. Pages are
+// static: anything a lens fetches from Deck after it is rendered, such as more lines of a log,
+// is unavailable.
+func renderRun(o options, cfg config.Getter, src runSource) (string, error) {
+	names, err := src.list()
+	if err != nil {
+		return "", fmt.Errorf("failed to list artifacts: %v", err)
+	}
+	if err := os.MkdirAll(o.outputDir, 0755); err != nil {
+		return "", err
+	}
+	sg := spyglass.New(nil, cfg, nil, context.Background())
+	matches := sg.MatchLenses(names)
+	var rendered []renderedLens
+	for _, lens := range spyglass.OrderLenses(sg.Lenses(matches), src.annotations()) {
+		lensConfig := lens.Config()
+		artifacts, err := src.fetch(lensConfig, matches[lensConfig.Name])
+		if err != nil {
+			logrus.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to fetch artifacts.")
+			continue
+		}
+		resourceDir := lenses.ResourceDirForLens(o.spyglassFilesLocation, lensConfig.Name)
+		page, err := renderLensPage(lens, resourceDir, artifacts, src.lensConfig(lensConfig.Name), sg.BodyPolicy(lensConfig.Name))
+		if err != nil {
+			return "", err
+		}
+		file := lensConfig.Name + ".html"
+		if err := ioutil.WriteFile(filepath.Join(o.outputDir, file), page, 0644); err != nil {
+			return "", err
+		}
+		if err := copyResources(resourceDir, filepath.Join(o.outputDir, "lenses", lensConfig.Name)); err != nil {
+			return "", fmt.Errorf("failed to copy the resources of the %s lens: %v", lensConfig.Name, err)
+		}
+		rendered = append(rendered, renderedLens{Title: lensConfig.Title, Page: file, Artifacts: matches[lensConfig.Name]})
+	}
+	for _, file := range staticFiles {
+		if err := copyFile(filepath.Join(o.staticFilesLocation, file), filepath.Join(o.outputDir, "static", file)); err != nil {
+			logrus.WithError(err).Debugf("Not copying static file %s.", file)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, struct {
+		Source string
+		Lenses []renderedLens
+	}{src.String(), rendered}); err != nil {
+		return "", fmt.Errorf("failed to render index: %v", err)
+	}
+	index := filepath.Join(o.outputDir, "index.html")
+	return index, ioutil.WriteFile(index, buf.Bytes(), 0644)
+}
+
+// exportRun renders every lens that matches the artifacts of a run into a single self-contained
+// HTML file, the way Deck exports runs, and returns its path.
+func exportRun(o options, cfg config.Getter, src runSource) (string, error) {
+	names, err := src.list()
+	if err != nil {
+		return "", fmt.Errorf("failed to list artifacts: %v", err)
+	}
+	sg := spyglass.New(nil, cfg, nil, context.Background())
+	matches := sg.MatchLenses(names)
+	var ls []spyglass.ExportLens
+	for _, lens := range spyglass.OrderLenses(sg.Lenses(matches), src.annotations()) {
+		lensConfig := lens.Config()
+		artifacts, err := src.fetch(lensConfig, matches[lensConfig.Name])
+		if err != nil {
+			logrus.WithError(err).WithField("lens", lensConfig.Name).Warning("Failed to fetch artifacts.")
+			continue
+		}
+		ls = append(ls, spyglass.ExportLens{
+			Lens:        lens,
+			ResourceDir: lenses.ResourceDirForLens(o.spyglassFilesLocation, lensConfig.Name),
+			Artifacts:   artifacts,
+			Config:      src.lensConfig(lensConfig.Name),
+			Policy:      sg.BodyPolicy(lensConfig.Name),
+		})
+	}
+	var buf bytes.Buffer
+	if err := spyglass.Export(&buf, src.String(), o.staticFilesLocation, ls); err != nil {
+		return "", err
+	}
+	return o.output, ioutil.WriteFile(o.output, buf.Bytes(), 0644)
+}
+
+// renderLensPage renders the page a lens is shown in, the way Deck does, sanitizing the lens's
+// body with policy if it is set.
+func renderLensPage(lens lenses.Lens, resourceDir string, artifacts []lenses.Artifact, rawConfig json.RawMessage, policy *sanitize.Policy) ([]byte, error) {
+	lensConfig := lens.Config()
+	var buf bytes.Buffer
+	if err := lensTemplate.Execute(&buf, struct {
+		Title   string
+		BaseURL string
+		Head    template.HTML
+		Body    template.HTML
+	}{
+		lensConfig.Title,
+		"lenses/" + lensConfig.Name + "/",
+		template.HTML(lens.Header(artifacts, resourceDir, rawConfig)),
+		template.HTML(policy.Sanitize(lens.Body(artifacts, resourceDir, "", rawConfig))),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render the %s lens: %v", lensConfig.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// copyResources copies the public resources of a lens, which its pages load relative to their
+// base URL. The sources kept beside them in a checkout of test-infra are left out.
+func copyResources(resourceDir, dest string) error {
+	if _, err := os.Stat(resourceDir); os.IsNotExist(err) {
+		logrus.Warningf("No resources for lens in %s.", resourceDir)
+		return nil
+	}
+	return filepath.Walk(resourceDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || isSource(file) {
+			return err
+		}
+		rel, err := filepath.Rel(resourceDir, file)
+		if err != nil {
+			return err
+		}
+		return copyFile(file, filepath.Join(dest, rel))
+	})
+}
+
+func isSource(file string) bool {
+	return strings.HasSuffix(file, ".go") || strings.HasSuffix(file, ".ts") || filepath.Base(file) == "BUILD.bazel"
+}
+
+func copyFile(src, dest string) error {
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, content, 0644)
+}
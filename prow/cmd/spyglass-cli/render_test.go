@@ -0,0 +1,200 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// sizeLens is a lens that renders the paths and sizes of its artifacts.
+type sizeLens struct{}
+
+func (sizeLens) Config() lenses.LensConfig {
+	return lenses.LensConfig{Name: "sizes", Title: "Sizes", Siblings: true}
+}
+
+func (sizeLens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return `<link rel="stylesheet" href="sizes.css">`
+}
+
+func (sizeLens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var body []string
+	for _, a := range artifacts {
+		size, _ := a.Size()
+		if _, ok := a.(*lenses.SiblingArtifact); ok {
+			body = append(body, fmt.Sprintf("sibling %s: %d", a.JobPath(), size))
+		} else {
+			body = append(body, fmt.Sprintf("%s: %d", a.JobPath(), size))
+		}
+	}
+	return strings.Join(body, "\n") + "\n" + string(config)
+}
+
+func (sizeLens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestRenderRun(t *testing.T) {
+	if err := lenses.RegisterLens(sizeLens{}); err != nil {
+		t.Fatalf("failed to register lens: %v", err)
+	}
+	defer lenses.UnregisterLens("sizes")
+
+	dir, err := ioutil.TempDir("", "spyglass-cli")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"run/build-log.txt":          "Starting.\nDone.\n",
+		"run/artifacts/junit_01.xml": "<testsuite/>",
+		"lenses/sizes/sizes.css":     "body {}",
+		"lenses/sizes/lens.go":       "package sizes",
+		"config.yaml": `deck:
+  spyglass:
+    viewers:
+      "build-log.txt":
+      - "sizes"
+    lens_config:
+      sizes: {"unit": "bytes"}
+`,
+	})
+
+	o := options{
+		configPath:            filepath.Join(dir, "config.yaml"),
+		outputDir:             filepath.Join(dir, "out"),
+		spyglassFilesLocation: filepath.Join(dir, "lenses"),
+		staticFilesLocation:   filepath.Join(dir, "static"),
+		src:                   filepath.Join(dir, "run"),
+	}
+	cfg, err := loadConfig(o.configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	src, err := newRunSource(o, cfg)
+	if err != nil {
+		t.Fatalf("failed to open run: %v", err)
+	}
+	index, err := renderRun(o, cfg, src)
+	if err != nil {
+		t.Fatalf("failed to render run: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(index)
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	if !strings.Contains(string(content), `<a href="sizes.html">Sizes</a>: build-log.txt`) {
+		t.Errorf("expected the index to link to the sizes lens, got:\n%s", content)
+	}
+	content, err = ioutil.ReadFile(filepath.Join(o.outputDir, "sizes.html"))
+	if err != nil {
+		t.Fatalf("failed to read rendered lens: %v", err)
+	}
+	for _, expected := range []string{
+		`<base href="lenses/sizes/">`,
+		`<link rel="stylesheet" href="sizes.css">`,
+		"build-log.txt: 16",
+		"sibling artifacts/junit_01.xml: 12",
+		`{"unit":"bytes"}`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected the rendered lens to contain %q, got:\n%s", expected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(o.outputDir, "lenses", "sizes", "sizes.css")); err != nil {
+		t.Errorf("expected the lens's resources to be copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(o.outputDir, "lenses", "sizes", "lens.go")); !os.IsNotExist(err) {
+		t.Errorf("expected the lens's sources not to be copied, got %v", err)
+	}
+
+	o.output = filepath.Join(dir, "export.html")
+	export, err := exportRun(o, cfg, src)
+	if err != nil {
+		t.Fatalf("failed to export run: %v", err)
+	}
+	content, err = ioutil.ReadFile(export)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	for _, expected := range []string{
+		"<h2>Sizes</h2>",
+		"build-log.txt: 16",
+		"<style>body {}</style>",
+		"<summary>build-log.txt</summary>",
+	} {
+		if !strings.Contains(html.UnescapeString(string(content)), expected) {
+			t.Errorf("expected the export to contain %q, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestGatherOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "GCS path", args: []string{"render", "gs://bucket/logs/job/123"}, expected: "gs://bucket/logs/job/123"},
+		{name: "flags before the run", args: []string{"render", "--open", "/tmp/run"}, expected: "/tmp/run"},
+		{name: "export", args: []string{"export", "--output=/tmp/run.html", "/tmp/run"}, expected: "/tmp/run"},
+		{name: "no command", args: []string{"gs://bucket/logs/job/123"}, expectedErr: true},
+		{name: "no run", args: []string{"render"}, expectedErr: true},
+		{name: "two runs", args: []string{"render", "/tmp/a", "/tmp/b"}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := gatherOptions(flag.NewFlagSet("spyglass-cli", flag.ContinueOnError), tc.args)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got options %+v", o)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if o.src != tc.expected {
+				t.Errorf("expected to render %q, got %q", tc.expected, o.src)
+			}
+		})
+	}
+}
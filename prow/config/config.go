@@ -20,6 +20,7 @@ package config
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -298,6 +299,10 @@ type Spyglass struct {
 	// expected file size + variance. To include all artifacts with high
 	// probability, use 2*maximum observed artifact size.
 	SizeLimit int64 `json:"size_limit,omitempty"`
+	// ArtifactPageSize is the most artifacts a lens is shown at once. Lenses that match more, such
+	// as those of jobs that dump whole clusters, are shown their first page of artifacts, and the
+	// rest a page at a time when asked for. If it is zero, lenses are shown all their artifacts.
+	ArtifactPageSize int `json:"artifact_page_size,omitempty"`
 	// GCSBrowserPrefix is used to generate a link to a human-usable GCS browser.
 	// If left empty, the link will be not be shown. Otherwise, a GCS path (with no
 	// prefix or scheme) will be appended to GCSBrowserPrefix and shown to the user.
@@ -306,6 +311,9 @@ type Spyglass struct {
 	// each spyglass page. Using HTML in the template is acceptable.
 	// Currently the only variable available is .ArtifactPath, which contains the GCS path for the job artifacts.
 	Announcement string `json:"announcement,omitempty"`
+	// RetentionPolicy explains how long job artifacts are kept on the pages of runs whose
+	// artifacts have been deleted. Using HTML is acceptable.
+	RetentionPolicy string `json:"retention_policy,omitempty"`
 	// TestGridConfig is the path to the TestGrid config proto. If the path begins with
 	// "gs://" it is assumed to be a GCS reference, otherwise it is read from the local filesystem.
 	// If left blank, TestGrid links will not appear.
@@ -313,6 +321,191 @@ type Spyglass struct {
 	// TestGridRoot is the root URL to the TestGrid frontend, e.g. "https://testgrid.k8s.io/".
 	// If left blank, TestGrid links will not appear.
 	TestGridRoot string `json:"testgrid_root,omitempty"`
+	// LensConfig is a map of lens names to lens-specific configuration, which is passed
+	// to the lens verbatim. See the documentation for each lens for the supported fields.
+	LensConfig map[string]json.RawMessage `json:"lens_config,omitempty"`
+	// RepoLensConfig overrides LensConfig for jobs in particular repos. It is keyed by
+	// "org" or "org/repo", and the most specific match is used.
+	RepoLensConfig map[string]map[string]json.RawMessage `json:"repo_lens_config,omitempty"`
+	// LensFeatures turns on features of lenses that are being rolled out, keyed by the name of
+	// the lens and then by the name of the feature. Lenses are told which of their features are
+	// on in the "features" field of their configuration, which lens_config and job annotations
+	// can also set to turn features on or off.
+	LensFeatures map[string]map[string]LensFeature `json:"lens_features,omitempty"`
+	// Snapshots enables lens snapshots. Deck serves the output each lens rendered when a job
+	// finished instead of rendering it again, and accepts requests to render and store that
+	// output at /spyglass/snapshot.
+	Snapshots bool `json:"snapshots,omitempty"`
+	// SnapshotLocation is the bucket, optionally followed by a path within it, that lens
+	// snapshots are stored in, e.g. "my-deck-bucket/snapshots". Deck serves snapshots as they
+	// are, so only Deck may be able to write there, never the jobs whose runs are shown.
+	// Required if Snapshots is set.
+	SnapshotLocation string `json:"snapshot_location,omitempty"`
+	// Access restricts who can see the runs of some jobs in Spyglass and on the job and PR
+	// history pages. Runs that no rule restricts can be seen by everyone; runs that some rules
+	// restrict can only be seen by users who log in to Deck with GitHub and are allowed by all
+	// of those rules. Restricting runs needs Deck's GitHub OAuth to be configured.
+	Access []SpyglassAccessRule `json:"access,omitempty"`
+	// BucketAliases lets Spyglass serve runs whose artifacts are stored in other buckets, or
+	// need other credentials to read, under the bucket names used in links to them. It is keyed
+	// by the bucket name in links, which may be that of a bucket the artifacts were moved out of.
+	BucketAliases map[string]BucketAlias `json:"bucket_aliases,omitempty"`
+	// PrivateBuckets are GCS buckets whose artifacts users can't read directly. Links to their
+	// artifacts, and to those of buckets read through an alias with its own credentials, go
+	// through Deck, which serves them to the users allowed to see their runs.
+	PrivateBuckets []string `json:"private_buckets,omitempty"`
+	// BrowsablePrefixes are storage paths, such as "my-bucket/debug-bundles/", under which
+	// Spyglass shows whatever artifacts are found at any prefix, rather than only the runs of
+	// ProwJobs. They are matched against where the artifacts are stored, like access rules.
+	BrowsablePrefixes []string `json:"browsable_prefixes,omitempty"`
+	// BaselineJobs names the periodic or postsubmit job whose runs are the baselines of runs of
+	// each presubmit, keyed by the presubmit's name. Lenses that compare runs to a baseline are
+	// given the latest passing run of that job started before the presubmit's, rather than an
+	// earlier run of the presubmit for another PR.
+	BaselineJobs map[string]string `json:"baseline_jobs,omitempty"`
+	// EmbedFrameAncestors are the sources, such as "https://dashboard.example.com", of the sites
+	// allowed to frame single lenses served from /spyglass/embed/. If it is empty, only Deck may.
+	EmbedFrameAncestors []string `json:"embed_frame_ancestors,omitempty"`
+	// SanitizedLenses are the lenses whose output is passed through an allow-list of HTML
+	// elements and attributes before it is served, keyed by lens name. The artifacts of
+	// presubmits are made by the code of whoever opened the PR, so this guards against lenses
+	// that put artifacts into HTML without escaping them.
+	SanitizedLenses map[string]LensSanitization `json:"sanitized_lenses,omitempty"`
+}
+
+// LensSanitization says what is kept of the output of a lens that is sanitized. Elements and
+// attributes that format text, lists and tables are always allowed, and those that run or load
+// code never are.
+type LensSanitization struct {
+	// Elements are the names of elements allowed besides the defaults.
+	Elements []string `json:"elements,omitempty"`
+	// Attributes are the names of attributes allowed on allowed elements besides the defaults.
+	// Names ending in "*", such as "data-*", allow every attribute starting with the rest.
+	Attributes []string `json:"attributes,omitempty"`
+	// Callbacks sanitizes the responses to the lens's callbacks too, for lenses whose callbacks
+	// respond with HTML rather than JSON.
+	Callbacks bool `json:"callbacks,omitempty"`
+}
+
+// LensFeature says which runs a feature of a lens is turned on for.
+type LensFeature struct {
+	// Enabled turns the feature on for all runs.
+	Enabled bool `json:"enabled,omitempty"`
+	// Orgs turns the feature on for the runs of jobs that ran against repos in these orgs.
+	Orgs []string `json:"orgs,omitempty"`
+	// Repos turns the feature on for the runs of jobs that ran against these "org/repo"s.
+	Repos []string `json:"repos,omitempty"`
+}
+
+// EnabledFor returns whether a feature is turned on for the runs of a job that ran against the
+// given org and repo, either of which may be empty.
+func (f LensFeature) EnabledFor(org, repo string) bool {
+	if f.Enabled {
+		return true
+	}
+	if org == "" {
+		return false
+	}
+	for _, o := range f.Orgs {
+		if strings.EqualFold(o, org) {
+			return true
+		}
+	}
+	if repo == "" {
+		return false
+	}
+	for _, r := range f.Repos {
+		if strings.EqualFold(r, org+"/"+repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// BucketAlias is where Spyglass reads the runs linked to under a bucket alias from.
+type BucketAlias struct {
+	// Bucket is the name of the GCS bucket the runs are stored in.
+	Bucket string `json:"bucket"`
+	// CredentialsFile is the path to a GCS service account key used to read the bucket. If it is
+	// empty, the bucket is read with Deck's --gcs-credentials-file.
+	CredentialsFile string `json:"credentials_file,omitempty"`
+}
+
+// SpyglassAccessRule restricts runs, either by where they are stored or by the org their job
+// ran against, to users who are named or are members of some GitHub orgs.
+type SpyglassAccessRule struct {
+	// BucketPrefixes restricts the runs stored under these paths, such as
+	// "my-bucket/logs/team-a-" or "my-bucket/pr-logs/pull/team-a_". Runs linked to under a
+	// bucket alias are matched by the bucket they are stored in.
+	BucketPrefixes []string `json:"bucket_prefixes,omitempty"`
+	// Orgs restricts the runs of jobs that ran against repos in these GitHub orgs.
+	Orgs []string `json:"orgs,omitempty"`
+	// Users are the GitHub logins of users who can see the restricted runs.
+	Users []string `json:"users,omitempty"`
+	// MembersOf are GitHub orgs whose members can see the restricted runs.
+	MembersOf []string `json:"members_of,omitempty"`
+}
+
+// Restricts returns whether a rule restricts the run stored at runPath, such as
+// "my-bucket/logs/job/123", of a job that ran against org, either of which may be empty.
+func (r SpyglassAccessRule) Restricts(runPath, org string) bool {
+	if runPath != "" {
+		for _, prefix := range r.BucketPrefixes {
+			if strings.HasPrefix(runPath, prefix) {
+				return true
+			}
+		}
+	}
+	if org != "" {
+		for _, o := range r.Orgs {
+			if strings.EqualFold(o, org) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows returns whether a rule lets the user with the given GitHub login see the runs it
+// restricts. isMember is asked whether the user is a member of the orgs in MembersOf.
+func (r SpyglassAccessRule) Allows(login string, isMember func(org string) bool) bool {
+	for _, user := range r.Users {
+		if strings.EqualFold(user, login) {
+			return true
+		}
+	}
+	for _, org := range r.MembersOf {
+		if isMember(org) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessRulesFor returns the access rules that restrict the run stored at runPath of a job that
+// ran against org, either of which may be empty.
+func (s *Spyglass) AccessRulesFor(runPath, org string) []SpyglassAccessRule {
+	var rules []SpyglassAccessRule
+	for _, rule := range s.Access {
+		if rule.Restricts(runPath, org) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// LensConfigFor returns the configuration for the named lens when viewing a job
+// from the given org and repo, either of which may be empty.
+func (s *Spyglass) LensConfigFor(lens, org, repo string) json.RawMessage {
+	if repo != "" {
+		if config, ok := s.RepoLensConfig[org+"/"+repo][lens]; ok {
+			return config
+		}
+	}
+	if config, ok := s.RepoLensConfig[org][lens]; ok {
+		return config
+	}
+	return s.LensConfig[lens]
 }
 
 // Deck holds config for deck.
@@ -923,6 +1116,10 @@ func parseProwConfig(c *Config) error {
 		return fmt.Errorf("invalid value for deck.spyglass.size_limit, must be >=0")
 	}
 
+	if c.Deck.Spyglass.ArtifactPageSize < 0 {
+		return fmt.Errorf("invalid value for deck.spyglass.artifact_page_size, must be >=0")
+	}
+
 	c.Deck.Spyglass.RegexCache = make(map[string]*regexp.Regexp)
 	for k := range c.Deck.Spyglass.Viewers {
 		r, err := regexp.Compile(k)
@@ -932,6 +1129,42 @@ func parseProwConfig(c *Config) error {
 		c.Deck.Spyglass.RegexCache[k] = r
 	}
 
+	for name, alias := range c.Deck.Spyglass.BucketAliases {
+		if alias.Bucket == "" {
+			return fmt.Errorf("deck.spyglass.bucket_aliases[%s] must name a bucket", name)
+		}
+		if strings.Contains(name, "/") || strings.Contains(alias.Bucket, "/") {
+			return fmt.Errorf("deck.spyglass.bucket_aliases[%s] must map a bucket name to a bucket name", name)
+		}
+	}
+
+	if c.Deck.Spyglass.Snapshots && c.Deck.Spyglass.SnapshotLocation == "" {
+		return fmt.Errorf("deck.spyglass.snapshot_location must be set if deck.spyglass.snapshots is")
+	}
+	if strings.Contains(c.Deck.Spyglass.SnapshotLocation, "://") {
+		return fmt.Errorf("deck.spyglass.snapshot_location must be a bucket and path, not a url")
+	}
+
+	for presubmit, job := range c.Deck.Spyglass.BaselineJobs {
+		if job == "" {
+			return fmt.Errorf("deck.spyglass.baseline_jobs[%s] must name a job", presubmit)
+		}
+	}
+
+	for i, rule := range c.Deck.Spyglass.Access {
+		if len(rule.BucketPrefixes) == 0 && len(rule.Orgs) == 0 {
+			return fmt.Errorf("deck.spyglass.access[%d] must restrict some bucket_prefixes or orgs", i)
+		}
+		if len(rule.Users) == 0 && len(rule.MembersOf) == 0 {
+			return fmt.Errorf("deck.spyglass.access[%d] must allow some users or members_of orgs", i)
+		}
+		for _, prefix := range rule.BucketPrefixes {
+			if prefix == "" {
+				return fmt.Errorf("deck.spyglass.access[%d] has an empty bucket prefix", i)
+			}
+		}
+	}
+
 	// Map old viewer names to the new ones for backwards compatibility.
 	// TODO(Katharine, #10274): remove this, eventually.
 	oldViewers := map[string]string{
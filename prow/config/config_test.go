@@ -28,6 +28,7 @@ import (
 	buildv1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowjobv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -206,6 +207,35 @@ deck:
 `,
 			expectError: true,
 		},
+		{
+			name: "Snapshots without a location",
+			spyglassConfig: `
+deck:
+  spyglass:
+    snapshots: true
+`,
+			expectError: true,
+		},
+		{
+			name: "Snapshot location given as a url",
+			spyglassConfig: `
+deck:
+  spyglass:
+    snapshots: true
+    snapshot_location: gs://deck-bucket/snapshots
+`,
+			expectError: true,
+		},
+		{
+			name: "Snapshots stored in a location",
+			spyglassConfig: `
+deck:
+  spyglass:
+    snapshots: true
+    snapshot_location: deck-bucket/snapshots
+`,
+			expectedSizeLimit: 100e6,
+		},
 	}
 	for _, tc := range testCases {
 		// save the config
@@ -271,6 +301,225 @@ deck:
 
 }
 
+func TestSpyglassLensConfigFor(t *testing.T) {
+	var spyglass Spyglass
+	if err := yaml.Unmarshal([]byte(`
+lens_config:
+  buildlog:
+    highlight: default
+  junit:
+    collapse: true
+repo_lens_config:
+  kubernetes:
+    buildlog:
+      highlight: org
+  kubernetes/test-infra:
+    buildlog:
+      highlight: repo
+`), &spyglass); err != nil {
+		t.Fatalf("failed to unmarshal spyglass config: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		lens     string
+		org      string
+		repo     string
+		expected string
+	}{
+		{
+			name:     "no org or repo uses the default",
+			lens:     "buildlog",
+			expected: `{"highlight":"default"}`,
+		},
+		{
+			name:     "unconfigured repo uses the default",
+			lens:     "buildlog",
+			org:      "kubernetes-sigs",
+			repo:     "kind",
+			expected: `{"highlight":"default"}`,
+		},
+		{
+			name:     "org override",
+			lens:     "buildlog",
+			org:      "kubernetes",
+			repo:     "kubernetes",
+			expected: `{"highlight":"org"}`,
+		},
+		{
+			name:     "repo override beats org override",
+			lens:     "buildlog",
+			org:      "kubernetes",
+			repo:     "test-infra",
+			expected: `{"highlight":"repo"}`,
+		},
+		{
+			name:     "overrides for other lenses do not apply",
+			lens:     "junit",
+			org:      "kubernetes",
+			repo:     "test-infra",
+			expected: `{"collapse":true}`,
+		},
+		{
+			name: "unconfigured lens has no config",
+			lens: "metadata",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := string(spyglass.LensConfigFor(tc.lens, tc.org, tc.repo)); actual != tc.expected {
+				t.Errorf("expected config %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLensFeatureEnabledFor(t *testing.T) {
+	var spyglass Spyglass
+	if err := yaml.Unmarshal([]byte(`
+lens_features:
+  buildlog:
+    everywhere:
+      enabled: true
+    rollout:
+      orgs:
+      - kubernetes
+      repos:
+      - kubernetes-sigs/kind
+`), &spyglass); err != nil {
+		t.Fatalf("failed to unmarshal spyglass config: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		feature  string
+		org      string
+		repo     string
+		expected bool
+	}{
+		{
+			name:     "enabled feature is on without a repo",
+			feature:  "everywhere",
+			expected: true,
+		},
+		{
+			name:    "rolled out feature is off without a repo",
+			feature: "rollout",
+		},
+		{
+			name:     "rolled out feature is on for its orgs",
+			feature:  "rollout",
+			org:      "Kubernetes",
+			repo:     "test-infra",
+			expected: true,
+		},
+		{
+			name:     "rolled out feature is on for its repos",
+			feature:  "rollout",
+			org:      "kubernetes-sigs",
+			repo:     "kind",
+			expected: true,
+		},
+		{
+			name:    "rolled out feature is off for other repos of an org with some",
+			feature: "rollout",
+			org:     "kubernetes-sigs",
+			repo:    "cluster-api",
+		},
+		{
+			name:    "unconfigured feature is off",
+			feature: "other",
+			org:     "kubernetes",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := spyglass.LensFeatures["buildlog"][tc.feature].EnabledFor(tc.org, tc.repo); actual != tc.expected {
+				t.Errorf("expected enabled %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSpyglassAccessRulesFor(t *testing.T) {
+	var spyglass Spyglass
+	if err := yaml.Unmarshal([]byte(`
+access:
+- bucket_prefixes:
+  - bucket/logs/secret-
+  users:
+  - alice
+- orgs:
+  - private-org
+  members_of:
+  - private-org
+`), &spyglass); err != nil {
+		t.Fatalf("failed to unmarshal spyglass config: %v", err)
+	}
+	members := map[string]bool{"private-org": true}
+	isMember := func(login string) func(string) bool {
+		return func(org string) bool { return login == "bob" && members[org] }
+	}
+
+	testCases := []struct {
+		name       string
+		runPath    string
+		org        string
+		login      string
+		restricted bool
+		allowed    bool
+	}{
+		{
+			name:    "unrestricted run",
+			runPath: "bucket/logs/public-job/1",
+			org:     "kubernetes",
+		},
+		{
+			name:       "run under a restricted prefix",
+			runPath:    "bucket/logs/secret-job/1",
+			login:      "mallory",
+			restricted: true,
+		},
+		{
+			name:       "named user sees a run under a restricted prefix",
+			runPath:    "bucket/logs/secret-job/1",
+			login:      "Alice",
+			restricted: true,
+			allowed:    true,
+		},
+		{
+			name:       "org member sees a run of a restricted org",
+			runPath:    "bucket/pr-logs/pull/private-org_repo/1/job/2",
+			org:        "Private-Org",
+			login:      "bob",
+			restricted: true,
+			allowed:    true,
+		},
+		{
+			name:       "run restricted by two rules needs both",
+			runPath:    "bucket/logs/secret-job/1",
+			org:        "private-org",
+			login:      "alice",
+			restricted: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := spyglass.AccessRulesFor(tc.runPath, tc.org)
+			if restricted := len(rules) > 0; restricted != tc.restricted {
+				t.Fatalf("expected restricted %t, got %t", tc.restricted, restricted)
+			}
+			allowed := tc.restricted
+			for _, rule := range rules {
+				allowed = allowed && rule.Allows(tc.login, isMember(tc.login))
+			}
+			if allowed != tc.allowed {
+				t.Errorf("expected allowed %t, got %t", tc.allowed, allowed)
+			}
+		})
+	}
+}
+
 func TestDecorationRawYaml(t *testing.T) {
 	var testCases = []struct {
 		name        string
@@ -82,6 +82,9 @@ type JobBase struct {
 	Name string `json:"name"`
 	// Labels are added to prowjobs and pods created for this job.
 	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to prowjobs created for this job, such as those that tell Spyglass
+	// how to present its results.
+	Annotations map[string]string `json:"annotations,omitempty"`
 	// MaximumConcurrency of this job, 0 implies no limit.
 	MaxConcurrency int `json:"max_concurrency,omitempty"`
 	// Agent that will take care of running this job.
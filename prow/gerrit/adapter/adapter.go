@@ -253,8 +253,9 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 	}
 
 	type jobSpec struct {
-		spec   prowapi.ProwJobSpec
-		labels map[string]string
+		spec        prowapi.ProwJobSpec
+		labels      map[string]string
+		annotations map[string]string
 	}
 
 	var jobSpecs []jobSpec
@@ -270,8 +271,9 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 				return fmt.Errorf("failed to determine if postsubmit %q should run: %v", postsubmit.Name, err)
 			} else if shouldRun {
 				jobSpecs = append(jobSpecs, jobSpec{
-					spec:   pjutil.PostsubmitSpec(postsubmit, refs),
-					labels: postsubmit.Labels,
+					spec:        pjutil.PostsubmitSpec(postsubmit, refs),
+					labels:      postsubmit.Labels,
+					annotations: postsubmit.Annotations,
 				})
 			}
 		}
@@ -294,17 +296,13 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 		}
 		for _, presubmit := range toTrigger {
 			jobSpecs = append(jobSpecs, jobSpec{
-				spec:   pjutil.PresubmitSpec(presubmit, refs),
-				labels: presubmit.Labels,
+				spec:        pjutil.PresubmitSpec(presubmit, refs),
+				labels:      presubmit.Labels,
+				annotations: presubmit.Annotations,
 			})
 		}
 	}
 
-	annotations := map[string]string{
-		client.GerritID:       change.ID,
-		client.GerritInstance: instance,
-	}
-
 	for _, jSpec := range jobSpecs {
 		labels := make(map[string]string)
 		for k, v := range jSpec.labels {
@@ -312,6 +310,13 @@ func (c *Controller) ProcessChange(instance string, change client.ChangeInfo) er
 		}
 		labels[client.GerritRevision] = change.CurrentRevision
 
+		annotations := make(map[string]string)
+		for k, v := range jSpec.annotations {
+			annotations[k] = v
+		}
+		annotations[client.GerritID] = change.ID
+		annotations[client.GerritInstance] = instance
+
 		if gerritLabel, ok := labels[client.GerritReportLabel]; !ok || gerritLabel == "" {
 			labels[client.GerritReportLabel] = client.CodeReview
 		}
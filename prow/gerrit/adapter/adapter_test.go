@@ -187,6 +187,7 @@ func TestProcessChange(t *testing.T) {
 		numPJ       int
 		pjRef       string
 		shouldError bool
+		annotations map[string]string
 	}{
 		{
 			name: "no revisions errors out",
@@ -261,6 +262,10 @@ func TestProcessChange(t *testing.T) {
 			},
 			numPJ: 1,
 			pjRef: "refs/changes/00/1/1",
+			annotations: map[string]string{
+				"spyglass.prow.k8s.io/lenses": "junit",
+				client.GerritInstance:         "https://gerrit",
+			},
 		},
 		{
 			name: "merged change should trigger postsubmit",
@@ -277,6 +282,10 @@ func TestProcessChange(t *testing.T) {
 			},
 			numPJ: 1,
 			pjRef: "refs/changes/00/1/1",
+			annotations: map[string]string{
+				"spyglass.prow.k8s.io/lenses": "buildlog",
+				client.GerritInstance:         "https://gerrit",
+			},
 		},
 		{
 			name: "merged change on project without postsubmits",
@@ -486,6 +495,10 @@ func TestProcessChange(t *testing.T) {
 							{
 								JobBase: config.JobBase{
 									Name: "other-test",
+									Annotations: map[string]string{
+										"spyglass.prow.k8s.io/lenses": "junit",
+										client.GerritInstance:         "https://elsewhere",
+									},
 								},
 								AlwaysRun: true,
 							},
@@ -495,7 +508,8 @@ func TestProcessChange(t *testing.T) {
 						"gerrit/postsubmits-project": {
 							{
 								JobBase: config.JobBase{
-									Name: "test-bar",
+									Name:        "test-bar",
+									Annotations: map[string]string{"spyglass.prow.k8s.io/lenses": "buildlog"},
 								},
 							},
 						},
@@ -540,6 +554,11 @@ func TestProcessChange(t *testing.T) {
 			if fkc.prowjobs[0].Spec.Refs.BaseSHA != "abc" {
 				t.Errorf("tc %s - BaseSHA should be abc, got %s", tc.name, fkc.prowjobs[0].Spec.Refs.BaseSHA)
 			}
+			for k, v := range tc.annotations {
+				if actual := fkc.prowjobs[0].Annotations[k]; actual != v {
+					t.Errorf("tc %s - annotation %s should be %s, got %s", tc.name, k, v, actual)
+				}
+			}
 		}
 	}
 }
@@ -588,6 +588,39 @@ func TestNewProwJobWithAnnotations(t *testing.T) {
 	}
 }
 
+func TestNewPresubmit(t *testing.T) {
+	pr := github.PullRequest{
+		Number: 1,
+		Head:   github.PullRequestBranch{SHA: "def"},
+		Base: github.PullRequestBranch{
+			Ref:  "master",
+			Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+		},
+	}
+	job := config.Presubmit{
+		JobBase: config.JobBase{
+			Name:        "job",
+			Labels:      map[string]string{"label": "foo"},
+			Annotations: map[string]string{"spyglass.prow.k8s.io/lenses": "junit"},
+		},
+	}
+
+	pj := NewPresubmit(pr, "abc", job, "guid")
+	if actual, expected := pj.Labels["label"], "foo"; actual != expected {
+		t.Errorf("expected job label %q, got %q", expected, actual)
+	}
+	if actual, expected := pj.Labels[github.EventGUID], "guid"; actual != expected {
+		t.Errorf("expected event GUID label %q, got %q", expected, actual)
+	}
+	expectedAnnotations := map[string]string{
+		"spyglass.prow.k8s.io/lenses": "junit",
+		kube.ProwJobAnnotation:        "job",
+	}
+	if actual := pj.Annotations; !reflect.DeepEqual(actual, expectedAnnotations) {
+		t.Errorf("incorrect ProwJob annotations created: %s", diff.ObjectReflectDiff(actual, expectedAnnotations))
+	}
+}
+
 func TestJobURL(t *testing.T) {
 	var testCases = []struct {
 		name     string
@@ -38,14 +38,16 @@ func Run(refs prowapi.Refs, dir, gitUserName, gitUserEmail, cookiePath string, e
 	// aborting early and returning if any command fails.
 	runCommands := func(commands []cloneCommand) error {
 		for _, command := range commands {
+			start := time.Now()
 			formattedCommand, output, err := command.run()
+			duration := time.Since(start)
 			logrus.WithFields(logrus.Fields{"command": formattedCommand, "output": output, "error": err}).Info("Ran command")
 			message := ""
 			if err != nil {
 				message = err.Error()
 				record.Failed = true
 			}
-			record.Commands = append(record.Commands, Command{Command: formattedCommand, Output: output, Error: message})
+			record.Commands = append(record.Commands, Command{Command: formattedCommand, Output: output, Error: message, Duration: duration})
 			if err != nil {
 				return err
 			}
@@ -17,6 +17,8 @@ limitations under the License.
 package clone
 
 import (
+	"time"
+
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 )
 
@@ -40,4 +42,6 @@ type Command struct {
 	Command string `json:"command"`
 	Output  string `json:"output,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Duration is how long the command took to run.
+	Duration time.Duration `json:"duration,omitempty"`
 }
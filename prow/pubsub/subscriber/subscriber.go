@@ -191,8 +191,15 @@ func (s *Subscriber) handlePeriodicJob(l *logrus.Entry, msg messageInterface, su
 		periodicJob.Labels[k] = v
 	}
 
-	// Adds annotations
-	prowJob = pjutil.NewProwJobWithAnnotation(prowJobSpec, periodicJob.Labels, pe.Annotations)
+	// Adds annotations from the job, then adds / updates those from prow job event
+	annotations := make(map[string]string)
+	for k, v := range periodicJob.Annotations {
+		annotations[k] = v
+	}
+	for k, v := range pe.Annotations {
+		annotations[k] = v
+	}
+	prowJob = pjutil.NewProwJobWithAnnotation(prowJobSpec, periodicJob.Labels, annotations)
 	// Adds / Updates Environments to containers
 	if prowJob.Spec.PodSpec != nil {
 		for _, c := range prowJob.Spec.PodSpec.Containers {
@@ -241,6 +241,7 @@ func TestHandlePeriodicJob(t *testing.T) {
 		err         string
 		reported    bool
 		clientFails bool
+		annotations map[string]string
 	}{
 		{
 			name: "PeriodicJobNoPubsub",
@@ -281,6 +282,34 @@ func TestHandlePeriodicJob(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "PeriodicJobAnnotationsMerged",
+			pe: &PeriodicProwJobEvent{
+				Name: "test",
+				Annotations: map[string]string{
+					"shared": "event",
+				},
+			},
+			config: &config.Config{
+				JobConfig: config.JobConfig{
+					Periodics: []config.Periodic{
+						{
+							JobBase: config.JobBase{
+								Name: "test",
+								Annotations: map[string]string{
+									"spyglass.prow.k8s.io/lenses": "junit",
+									"shared":                      "job",
+								},
+							},
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				"spyglass.prow.k8s.io/lenses": "junit",
+				"shared":                      "event",
+			},
+		},
 		{
 			name: "PeriodicJobPubsubSetCreationError",
 			pe: &PeriodicProwJobEvent{
@@ -372,6 +401,21 @@ func TestHandlePeriodicJob(t *testing.T) {
 			if fr.reported != tc.reported {
 				t1.Errorf("Expected Reporting: %t, found: %t", tc.reported, fr.reported)
 			}
+			for _, action := range fakeProwJobClient.Fake.Actions() {
+				create, ok := action.(clienttesting.CreateActionImpl)
+				if !ok {
+					continue
+				}
+				prowjob, ok := create.Object.(*prowapi.ProwJob)
+				if !ok {
+					continue
+				}
+				for k, v := range tc.annotations {
+					if prowjob.Annotations[k] != v {
+						t1.Errorf("Expected annotation %s=%s, got %q", k, v, prowjob.Annotations[k])
+					}
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// LensesAnnotation lists, separated by commas, the lenses shown for a job's runs in the order
+	// they are shown. Lenses it leaves out are hidden.
+	LensesAnnotation = "spyglass.prow.k8s.io/lenses"
+	// LensConfigAnnotationPrefix is followed by the name of a lens to make the annotation that
+	// holds a job's JSON options for that lens, which are merged over its configured options.
+	LensConfigAnnotationPrefix = "lens.spyglass.prow.k8s.io/"
+	// BaselineJobAnnotation names the periodic or postsubmit job whose runs are the baselines of
+	// a presubmit's runs, overriding deck.spyglass.baseline_jobs.
+	BaselineJobAnnotation = "spyglass.prow.k8s.io/baseline-job"
+)
+
+// prowJob returns the ProwJob of the run referenced by src, asking the job agent for jobs it still
+// knows and otherwise reading the run's prowjob.json.
+func (s *Spyglass) prowJob(src string) (*prowapi.ProwJob, error) {
+	_, key, err := splitSrc(strings.TrimSuffix(src, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing src: %v", err)
+	}
+	if s.JobAgent != nil {
+		if jobName, buildID, err := s.KeyToJob(key); err == nil {
+			if job, err := s.JobAgent.GetProwJob(jobName, buildID); err == nil {
+				return &job, nil
+			}
+		}
+	}
+	artifacts, err := s.FetchArtifacts(src, "", 1000000, []string{"prowjob.json"})
+	if err != nil || len(artifacts) == 0 {
+		return nil, fmt.Errorf("couldn't find prowjob.json for %q: %v", src, err)
+	}
+	content, err := artifacts[0].ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prowjob.json: %v", err)
+	}
+	var job prowapi.ProwJob
+	if err := json.Unmarshal(content, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse prowjob.json: %v", err)
+	}
+	return &job, nil
+}
+
+// JobAnnotations returns the annotations of the ProwJob of the run referenced by src, or nil if
+// it can't be found.
+func (s *Spyglass) JobAnnotations(src string) map[string]string {
+	job, err := s.prowJob(src)
+	if err != nil {
+		logrus.WithError(err).WithField("src", src).Debug("Couldn't find ProwJob for its annotations.")
+		return nil
+	}
+	return job.Annotations
+}
+
+// RunLenses returns the lenses for the run referenced by src that have matching artifacts,
+// ordered and selected by its job's annotations.
+func (s *Spyglass) RunLenses(src string, matchCache map[string][]string) []lenses.Lens {
+	return OrderLenses(s.Lenses(matchCache), s.JobAnnotations(src))
+}
+
+// OrderLenses returns the lenses that a job's annotations list, in the order they list them. All
+// lenses are returned as they are if the annotations don't list any.
+func OrderLenses(ls []lenses.Lens, annotations map[string]string) []lenses.Lens {
+	list, ok := annotations[LensesAnnotation]
+	if !ok {
+		return ls
+	}
+	byName := map[string]lenses.Lens{}
+	for _, lens := range ls {
+		byName[lens.Config().Name] = lens
+	}
+	ordered := []lenses.Lens{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if lens, ok := byName[name]; ok {
+			ordered = append(ordered, lens)
+			delete(byName, name)
+		}
+	}
+	return ordered
+}
+
+// MergeLensConfig merges the options for a lens in a job's annotations over its configured
+// options. Options that are not JSON objects replace the configured options entirely, and
+// annotations that are not JSON are ignored.
+func MergeLensConfig(lens string, rawConfig json.RawMessage, annotations map[string]string) json.RawMessage {
+	override, ok := annotations[LensConfigAnnotationPrefix+lens]
+	if !ok {
+		return rawConfig
+	}
+	if !json.Valid([]byte(override)) {
+		logrus.WithField("lens", lens).Info("Ignoring lens options in a job annotation that aren't JSON.")
+		return rawConfig
+	}
+	var base, fields map[string]json.RawMessage
+	if json.Unmarshal(rawConfig, &base) != nil || json.Unmarshal([]byte(override), &fields) != nil {
+		return json.RawMessage(override)
+	}
+	if base == nil {
+		base = map[string]json.RawMessage{}
+	}
+	for k, v := range fields {
+		base[k] = v
+	}
+	merged, err := json.Marshal(base)
+	if err != nil {
+		return rawConfig
+	}
+	return merged
+}
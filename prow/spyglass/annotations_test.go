@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+type namedLens struct {
+	dumpLens
+	name string
+}
+
+func (l namedLens) Config() lenses.LensConfig {
+	return lenses.LensConfig{Name: l.name, Title: l.name}
+}
+
+func TestOrderLenses(t *testing.T) {
+	ls := []lenses.Lens{namedLens{name: "metadata"}, namedLens{name: "buildlog"}, namedLens{name: "junit"}}
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+	}{
+		{
+			name:     "no annotations keeps all lenses",
+			expected: []string{"metadata", "buildlog", "junit"},
+		},
+		{
+			name:        "listed lenses are shown in order",
+			annotations: map[string]string{LensesAnnotation: "junit, metadata"},
+			expected:    []string{"junit", "metadata"},
+		},
+		{
+			name:        "lenses without artifacts and repeated lenses are left out",
+			annotations: map[string]string{LensesAnnotation: "buildlog,coverage,buildlog"},
+			expected:    []string{"buildlog"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := []string{}
+			for _, lens := range OrderLenses(ls, tc.annotations) {
+				actual = append(actual, lens.Config().Name)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected lenses %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMergeLensConfig(t *testing.T) {
+	testCases := []struct {
+		name       string
+		config     string
+		annotation string
+		expected   string
+	}{
+		{
+			name:     "no annotation keeps the config",
+			config:   `{"highlight":"default"}`,
+			expected: `{"highlight":"default"}`,
+		},
+		{
+			name:       "annotated options override configured ones",
+			config:     `{"highlight":"default","collapse":true}`,
+			annotation: `{"highlight":"job"}`,
+			expected:   `{"collapse":true,"highlight":"job"}`,
+		},
+		{
+			name:       "annotated options apply without config",
+			annotation: `{"highlight":"job"}`,
+			expected:   `{"highlight":"job"}`,
+		},
+		{
+			name:       "annotations that aren't JSON are ignored",
+			config:     `{"highlight":"default"}`,
+			annotation: `highlight: job`,
+			expected:   `{"highlight":"default"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tc.annotation != "" {
+				annotations[LensConfigAnnotationPrefix+"buildlog"] = tc.annotation
+			}
+			var config json.RawMessage
+			if tc.config != "" {
+				config = json.RawMessage(tc.config)
+			}
+			if actual := string(MergeLensConfig("buildlog", config, annotations)); actual != tc.expected {
+				t.Errorf("expected config %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -25,36 +25,37 @@ import (
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
-// ListArtifacts gets the names of all artifacts available from the given source
+// ListArtifacts gets the names of all artifacts available from the given source. The build log is
+// always listed for runs, since it can be read from the job's pod until it is uploaded. An error
+// is returned if the storage holding the artifacts can't be listed, so that pages aren't rendered
+// as if the run had no artifacts.
 func (s *Spyglass) ListArtifacts(src string) ([]string, error) {
-	keyType, key, err := splitSrc(src)
+	keyType, _, err := splitSrc(src)
 	if err != nil {
 		return []string{}, fmt.Errorf("error parsing src: %v", err)
 	}
-	gcsKey := ""
-	switch keyType {
-	case gcsKeyType:
-		gcsKey = key
-	case prowKeyType:
-		if gcsKey, err = s.prowToGCS(key); err != nil {
-			logrus.Warningf("Failed to get gcs source for prow job: %v", err)
-		}
+	var artifactNames []string
+	fetcher, _, key, err := s.storage(src)
+	switch {
+	case err != nil && keyType == prowKeyType:
+		logrus.Warningf("Failed to get gcs source for prow job: %v", err)
+	case err != nil:
+		return nil, err
 	default:
-		return nil, fmt.Errorf("Unrecognized key type for src: %v", src)
+		if artifactNames, err = fetcher.Artifacts(key); err != nil {
+			return nil, fmt.Errorf("failed to read storage: %v", err)
+		}
+	}
+	// Prefixes have no job whose pod a build log could be read from.
+	if keyType == prefixKeyType {
+		return artifactNames, nil
 	}
-
-	artifactNames, err := s.GCSArtifactFetcher.artifacts(gcsKey)
-	logFound := false
 	for _, name := range artifactNames {
 		if name == "build-log.txt" {
-			logFound = true
-			break
+			return artifactNames, nil
 		}
 	}
-	if err != nil || !logFound {
-		artifactNames = append(artifactNames, "build-log.txt")
-	}
-	return artifactNames, nil
+	return append(artifactNames, "build-log.txt"), nil
 }
 
 // KeyToJob takes a spyglass URL and returns the jobName and buildID.
@@ -94,7 +95,7 @@ func (s *Spyglass) prowToGCS(prowKey string) (string, error) {
 func (s *Spyglass) FetchArtifacts(src string, podName string, sizeLimit int64, artifactNames []string) ([]lenses.Artifact, error) {
 	artStart := time.Now()
 	arts := []lenses.Artifact{}
-	keyType, key, err := splitSrc(src)
+	keyType, _, err := splitSrc(src)
 	if err != nil {
 		return arts, fmt.Errorf("error parsing src: %v", err)
 	}
@@ -102,36 +103,30 @@ func (s *Spyglass) FetchArtifacts(src string, podName string, sizeLimit int64, a
 	if err != nil {
 		return arts, fmt.Errorf("could not derive job: %v", err)
 	}
-	gcsKey := ""
-	switch keyType {
-	case gcsKeyType:
-		gcsKey = strings.TrimSuffix(key, "/")
-	case prowKeyType:
-		if gcsKey, err = s.prowToGCS(key); err != nil {
-			logrus.Warningln(err)
-		}
+	fetcher, _, key, err := s.storage(src)
+	switch {
+	case err != nil && keyType == prowKeyType:
+		logrus.Warningln(err)
+	case err != nil:
+		return nil, err
 	default:
-		return nil, fmt.Errorf("invalid src: %v", src)
+		// Only the artifacts that exist are returned.
+		fetched, err := fetchExisting(fetcher, key, sizeLimit, artifactNames)
+		if err != nil {
+			logrus.WithError(err).WithField("src", src).Warning("Failed to stat artifacts.")
+		}
+		arts = append(arts, fetched...)
 	}
 
 	podLogNeeded := false
-	for _, name := range artifactNames {
-		art, err := s.GCSArtifactFetcher.artifact(gcsKey, name, sizeLimit)
-		if err == nil {
-			// Actually try making a request, because calling GCSArtifactFetcher.artifact does no I/O.
-			// (these files are being explicitly requested and so will presumably soon be accessed, so
-			// the extra network I/O should not be too problematic).
-			_, err = art.Size()
+	if keyType != prefixKeyType {
+		for _, name := range artifactNames {
+			podLogNeeded = podLogNeeded || name == "build-log.txt"
 		}
-		if err != nil {
-			if name == "build-log.txt" {
-				podLogNeeded = true
-			}
-			continue
+		for _, art := range arts {
+			podLogNeeded = podLogNeeded && art.JobPath() != "build-log.txt"
 		}
-		arts = append(arts, art)
 	}
-
 	if podLogNeeded {
 		art, err := s.PodLogArtifactFetcher.artifact(jobName, buildID, sizeLimit)
 		if err != nil {
@@ -145,6 +140,36 @@ func (s *Spyglass) FetchArtifacts(src string, podName string, sizeLimit int64, a
 	return arts, nil
 }
 
+// FetchSiblingArtifacts returns every artifact of the run in src other than the named ones, wrapped
+// so that lenses can tell them apart from the artifacts they matched. Unlike FetchArtifacts, it
+// makes no request per artifact, as a run can have thousands.
+func (s *Spyglass) FetchSiblingArtifacts(src string, sizeLimit int64, artifactNames []string) ([]lenses.Artifact, error) {
+	fetcher, _, key, err := s.storage(src)
+	if err != nil {
+		return nil, err
+	}
+	names, err := fetcher.Artifacts(key)
+	if err != nil {
+		return nil, err
+	}
+	matched := map[string]bool{}
+	for _, name := range artifactNames {
+		matched[name] = true
+	}
+	var siblings []lenses.Artifact
+	for _, name := range names {
+		if matched[name] {
+			continue
+		}
+		art, err := fetcher.Artifact(key, name, sizeLimit)
+		if err != nil {
+			continue
+		}
+		siblings = append(siblings, lenses.NewSiblingArtifact(art))
+	}
+	return siblings, nil
+}
+
 func splitSrc(src string) (keyType, key string, err error) {
 	split := strings.SplitN(src, "/", 2)
 	if len(split) < 2 {
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+// alias returns the bucket alias that runs linked to under the given bucket name are read through,
+// if any.
+func (af *GCSArtifactFetcher) alias(name string) (config.BucketAlias, bool) {
+	if af.config == nil {
+		return config.BucketAlias{}, false
+	}
+	alias, ok := af.config().Deck.Spyglass.BucketAliases[name]
+	return alias, ok
+}
+
+// Bucket returns a handle for the bucket that runs linked to under the given bucket name are
+// stored in, following any bucket alias and reading with the credentials it names.
+func (af *GCSArtifactFetcher) Bucket(name string) (*storage.BucketHandle, error) {
+	alias, ok := af.alias(name)
+	if !ok {
+		return af.client.Bucket(name), nil
+	}
+	client, err := af.clientFor(alias.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a GCS client for bucket alias %s: %v", name, err)
+	}
+	return client.Bucket(bucketName(alias.Bucket)), nil
+}
+
+// clientFor returns a client that reads with the given credentials file, or the default client if
+// there is none. Clients are created when first needed and then reused.
+func (af *GCSArtifactFetcher) clientFor(credentialsFile string) (*storage.Client, error) {
+	if credentialsFile == "" {
+		return af.client, nil
+	}
+	af.clientsLock.Lock()
+	defer af.clientsLock.Unlock()
+	if c, ok := af.clients[credentialsFile]; ok {
+		return c, nil
+	}
+	c, err := storage.NewClient(context.Background(), option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		return nil, err
+	}
+	af.clients[credentialsFile] = c
+	return c, nil
+}
+
+// StoragePath returns the path, such as "bucket/logs/job/123", where what is linked to under the
+// given path is stored, following any bucket alias the path starts with.
+func (af *GCSArtifactFetcher) StoragePath(p string) string {
+	parts := strings.SplitN(p, "/", 2)
+	alias, ok := af.alias(parts[0])
+	if !ok {
+		return p
+	}
+	parts[0] = bucketName(alias.Bucket)
+	return strings.Join(parts, "/")
+}
+
+// private returns whether users can't read the artifacts of runs linked to under the given bucket
+// name directly, because the bucket they're stored in is private or is read through an alias
+// with its own credentials.
+func (af *GCSArtifactFetcher) private(name string) bool {
+	if af.config == nil {
+		return false
+	}
+	alias, ok := af.alias(name)
+	if ok && alias.CredentialsFile != "" {
+		return true
+	}
+	bucket := af.StoragePath(name)
+	for _, private := range af.config().Deck.Spyglass.PrivateBuckets {
+		if bucketName(private) == bucket {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// ExpiredRun is what is still known about a run whose artifacts are gone from storage.
+type ExpiredRun struct {
+	JobName string
+	BuildID string
+	// ProwJob is the run's ProwJob, if it hasn't been garbage collected yet.
+	ProwJob *prowapi.ProwJob
+}
+
+// Expired returns whether the artifacts of the run at src have been deleted, usually by the
+// lifecycle rules of the bucket they were stored in, and what is still known about the run if
+// they have. A run's artifacts are taken to be gone when storage holds nothing for it and its
+// ProwJob, if there still is one, isn't waiting to run or running. Runs whose storage can't be
+// read aren't taken to have expired.
+func (s *Spyglass) Expired(src string) (ExpiredRun, bool) {
+	if IsPrefix(src) {
+		return ExpiredRun{}, false
+	}
+	fetcher, _, key, err := s.storage(src)
+	if err != nil {
+		return ExpiredRun{}, false
+	}
+	artifactNames, err := fetcher.Artifacts(key)
+	if err != nil || len(artifactNames) > 0 {
+		return ExpiredRun{}, false
+	}
+
+	jobName, buildID, err := s.KeyToJob(src)
+	if err != nil {
+		return ExpiredRun{}, false
+	}
+	run := ExpiredRun{JobName: jobName, BuildID: buildID}
+	if job, err := s.jobAgent.GetProwJob(jobName, buildID); err == nil {
+		// Jobs that haven't started may not have uploaded anything yet.
+		if job.Status.State == prowapi.TriggeredState || job.Status.State == prowapi.PendingState {
+			return ExpiredRun{}, false
+		}
+		run.ProwJob = &job
+	}
+	return run, true
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/sanitize"
+)
+
+// exportExcerptBytes is how much of the end of each artifact an export includes.
+const exportExcerptBytes = 16 * 1024
+
+// The files of Deck's static files that lens pages are styled and scripted with, relative to them.
+const (
+	lensStylesheet = "spyglass/lens.css"
+	lensScript     = "spyglass_lens_bundle.min.js"
+)
+
+// Tags of lens headers that load their resources, relative to the lens's resource directory.
+var (
+	scriptTagRe     = regexp.MustCompile(`<script([^>]*?)\ssrc="([^"]+)"([^>]*)>\s*</script>`)
+	stylesheetTagRe = regexp.MustCompile(`<link[^>]*\srel="stylesheet"[^>]*>`)
+	hrefRe          = regexp.MustCompile(`\shref="([^"]+)"`)
+)
+
+// ExportLens is a lens to include in an export of a run, with what it renders.
+type ExportLens struct {
+	Lens lenses.Lens
+	// ResourceDir is the directory of the lens's resources.
+	ResourceDir string
+	Artifacts   []lenses.Artifact
+	Config      json.RawMessage
+	// Policy sanitizes the lens's body, if it is set.
+	Policy *sanitize.Policy
+}
+
+var exportLensTemplate = template.Must(template.New("lens").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Spyglass Lens: {{.Title}}</title>
+  <link rel="stylesheet" href="https://code.getmdl.io/1.3.0/material.indigo-pink.min.css">
+  <link href="https://fonts.googleapis.com/css?family=Roboto:400,700" rel="stylesheet">
+  <link rel="stylesheet" href="https://fonts.googleapis.com/icon?family=Material+Icons">
+  {{if .Stylesheet}}<style>{{.Stylesheet}}</style>{{end}}
+  {{if .Script}}<script>{{.Script}}</script>{{end}}
+  {{.Head}}
+</head>
+<body class="lens-body">
+  {{.Body}}
+</body>
+</html>
+`))
+
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>Spyglass: {{.Source}}</title>
+  <style>
+    body { font-family: Roboto, sans-serif; margin: 20px; }
+    .lens-frame { width: 100%; height: 400px; border: 1px solid #ddd; }
+    pre { white-space: pre-wrap; background: #f5f5f5; padding: 8px; }
+  </style>
+  <script>
+    // Lenses tell the page they are shown on how tall they are, and wait for it to reply. Nothing
+    // they ask Deck for is available in an export.
+    window.addEventListener('message', function(e) {
+      var data = e.data;
+      if (!data || typeof data.id !== 'number' || !data.message) {
+        return;
+      }
+      var frames = document.querySelectorAll('iframe');
+      for (var i = 0; i < frames.length; i++) {
+        if (frames[i].contentWindow !== e.source) {
+          continue;
+        }
+        if (data.message.type === 'contentUpdated') {
+          frames[i].style.height = data.message.height + 'px';
+        }
+        if (['contentUpdated', 'linkAnchor', 'showLens'].indexOf(data.message.type) !== -1) {
+          e.source.postMessage({id: data.id, message: {type: 'response', data: ''}}, '*');
+        }
+      }
+    });
+  </script>
+</head>
+<body>
+  <h1>{{.Source}}</h1>
+  {{range .Lenses}}
+  <h2>{{.Title}}</h2>
+  <iframe class="lens-frame" sandbox="allow-scripts allow-popups" srcdoc="{{.Page}}"></iframe>
+  {{else}}
+  <p>No lenses matched the artifacts of this run.</p>
+  {{end}}
+  {{if .Excerpts}}
+  <h2>Artifacts</h2>
+  {{range .Excerpts}}
+  <details>
+    <summary>{{.Name}}{{if .Truncated}} ({{if .Head}}first{{else}}last{{end}} {{.Size}} bytes){{end}}</summary>
+    <pre>{{.Content}}</pre>
+  </details>
+  {{end}}
+  {{end}}
+</body>
+</html>
+`))
+
+// exportedLens is a lens shown in an export, whose page is escaped into its frame.
+type exportedLens struct {
+	Title string
+	Page  string
+}
+
+// exportExcerpt is part of a text artifact included in an export.
+type exportExcerpt struct {
+	Name    string
+	Content string
+	Size    int
+	// Head is set if the excerpt is the start of the artifact rather than its end.
+	Head      bool
+	Truncated bool
+}
+
+// Export writes the pages of a run's lenses as a single HTML file that can be opened without
+// Deck, with the scripts and stylesheets of the lenses inlined and an excerpt of each text
+// artifact they render included, so that it can be attached to bug reports or kept after the
+// artifacts are deleted. Lenses are shown as they are first rendered: anything they fetch from Deck afterwards,
+// such as more lines of a log, is unavailable. Deck's static files are read from staticDir, and
+// the stylesheets Deck loads from other sites are linked rather than inlined.
+func Export(w io.Writer, source, staticDir string, ls []ExportLens) error {
+	stylesheet, _ := ioutil.ReadFile(filepath.Join(staticDir, filepath.FromSlash(lensStylesheet)))
+	script, _ := ioutil.ReadFile(filepath.Join(staticDir, filepath.FromSlash(lensScript)))
+
+	var exported []exportedLens
+	var excerpts []exportExcerpt
+	excerpted := map[string]bool{}
+	for _, l := range ls {
+		lensConfig := l.Lens.Config()
+		var page bytes.Buffer
+		if err := exportLensTemplate.Execute(&page, struct {
+			Title      string
+			Stylesheet template.CSS
+			Script     template.JS
+			Head       template.HTML
+			Body       template.HTML
+		}{
+			lensConfig.Title,
+			template.CSS(stylesheet),
+			template.JS(escapeScript(string(script))),
+			template.HTML(inlineResources(l.Lens.Header(l.Artifacts, l.ResourceDir, l.Config), l.ResourceDir)),
+			template.HTML(l.Policy.Sanitize(l.Lens.Body(l.Artifacts, l.ResourceDir, "", l.Config))),
+		}); err != nil {
+			return fmt.Errorf("failed to render the %s lens: %v", lensConfig.Name, err)
+		}
+		exported = append(exported, exportedLens{Title: lensConfig.Title, Page: page.String()})
+
+		current, _ := lenses.SplitBaseline(l.Artifacts)
+		matched, _ := lenses.SplitSiblings(current)
+		for _, a := range matched {
+			if excerpted[a.JobPath()] {
+				continue
+			}
+			excerpted[a.JobPath()] = true
+			if excerpt, ok := excerptArtifact(a); ok {
+				excerpts = append(excerpts, excerpt)
+			}
+		}
+	}
+
+	return exportTemplate.Execute(w, struct {
+		Source   string
+		Lenses   []exportedLens
+		Excerpts []exportExcerpt
+	}{source, exported, excerpts})
+}
+
+// excerptArtifact returns the end of an artifact, or the start of it if it can't be read from the
+// end, if it is text.
+func excerptArtifact(a lenses.Artifact) (exportExcerpt, bool) {
+	size, err := a.Size()
+	if err != nil {
+		return exportExcerpt{}, false
+	}
+	head := false
+	content, err := a.ReadTail(exportExcerptBytes)
+	if err != nil {
+		// Compressed artifacts can't be read from the end.
+		head = true
+		if content, err = a.ReadAtMost(exportExcerptBytes); err != nil && err != io.EOF {
+			return exportExcerpt{}, false
+		}
+	}
+	truncated := size > int64(len(content))
+	// An excerpt may start or end in the middle of a character.
+	if truncated && head {
+		start := len(content) - 1
+		for start > 0 && !utf8.RuneStart(content[start]) {
+			start--
+		}
+		if start >= 0 && !utf8.FullRune(content[start:]) {
+			content = content[:start]
+		}
+	} else if truncated {
+		for len(content) > 0 && !utf8.RuneStart(content[0]) {
+			content = content[1:]
+		}
+	}
+	if !utf8.Valid(content) {
+		return exportExcerpt{}, false
+	}
+	return exportExcerpt{Name: a.JobPath(), Content: string(content), Size: len(content), Head: head, Truncated: truncated}, true
+}
+
+// inlineResources replaces the tags of a lens's header that load its scripts and stylesheets
+// with their contents, read from its resource directory. Resources that can't be read are left to
+// be loaded as they were.
+func inlineResources(header, resourceDir string) string {
+	header = scriptTagRe.ReplaceAllStringFunc(header, func(tag string) string {
+		m := scriptTagRe.FindStringSubmatch(tag)
+		content, ok := readResource(resourceDir, m[2])
+		if !ok {
+			return tag
+		}
+		return "<script" + m[1] + m[3] + ">" + escapeScript(content) + "</script>"
+	})
+	return stylesheetTagRe.ReplaceAllStringFunc(header, func(tag string) string {
+		m := hrefRe.FindStringSubmatch(tag)
+		if m == nil {
+			return tag
+		}
+		content, ok := readResource(resourceDir, m[1])
+		if !ok {
+			return tag
+		}
+		return "<style>" + strings.Replace(content, "</style", `<\/style`, -1) + "</style>"
+	})
+}
+
+// readResource reads a resource of a lens that a relative URL refers to.
+func readResource(resourceDir, ref string) (string, bool) {
+	if strings.Contains(ref, ":") || strings.HasPrefix(ref, "/") {
+		return "", false
+	}
+	if i := strings.IndexAny(ref, "?#"); i != -1 {
+		ref = ref[:i]
+	}
+	content, err := ioutil.ReadFile(filepath.Join(resourceDir, filepath.FromSlash(path.Clean("/"+ref))))
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// escapeScript keeps a script from ending the tag it is inlined in.
+func escapeScript(script string) string {
+	return strings.Replace(script, "</script", `<\/script`, -1)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// resourceLens is a dumpLens that loads resources in its header.
+type resourceLens struct {
+	dumpLens
+}
+
+func (resourceLens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return `<link rel="stylesheet" href="lens.css?v=1">
+<script type="text/javascript" src="lens.js"></script>
+<script src="missing.js"></script>
+<script src="https://example.com/remote.js"></script>`
+}
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyglass-export")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"static/spyglass/lens.css":           ".lens-body { margin: 0; }",
+		"static/spyglass_lens_bundle.min.js": "var bundle = '</script>';",
+		"lenses/resource/lens.css":           ".resource { color: red; }",
+		"lenses/resource/lens.js":            "var resource = 1;",
+		"run/build-log.txt":                  "line 1\nline 2\n",
+		"run/long-log.txt":                   strings.Repeat("€", exportExcerptBytes/3+1),
+		"run/image.png":                      "\x89PNG\xff\xfe",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	artifact := func(name string) lenses.Artifact {
+		return NewLocalArtifact(filepath.Join(dir, "run", name), name, 1e6)
+	}
+
+	var buf bytes.Buffer
+	err = Export(&buf, "gs://bucket/logs/job/1", filepath.Join(dir, "static"), []ExportLens{
+		{
+			Lens:        resourceLens{},
+			ResourceDir: filepath.Join(dir, "lenses", "resource"),
+			Artifacts:   []lenses.Artifact{artifact("build-log.txt"), lenses.NewSiblingArtifact(artifact("image.png"))},
+		},
+		{
+			Lens:        dumpLens{},
+			ResourceDir: filepath.Join(dir, "lenses", "dump"),
+			Artifacts:   []lenses.Artifact{artifact("build-log.txt"), artifact("long-log.txt"), artifact("image.png")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	export := buf.String()
+
+	frames := regexp.MustCompile(`srcdoc="([^"]*)"`).FindAllStringSubmatch(export, -1)
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 lens frames, got %d", len(frames))
+	}
+	page := html.UnescapeString(frames[0][1])
+	for _, expected := range []string{
+		"<style>.lens-body { margin: 0; }</style>",
+		`var bundle = '<\/script>';`,
+		"<style>.resource { color: red; }</style>",
+		`<script type="text/javascript">var resource = 1;</script>`,
+		`<script src="missing.js"></script>`,
+		`<script src="https://example.com/remote.js"></script>`,
+		"line 1\nline 2\n",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the exported lens page to contain %q, got:\n%s", expected, page)
+		}
+	}
+
+	if strings.Count(export, "<summary>build-log.txt</summary>") != 1 {
+		t.Errorf("Expected one excerpt of build-log.txt, got:\n%s", export)
+	}
+	if strings.Contains(export, "image.png") {
+		t.Error("Expected no excerpt of a binary artifact.")
+	}
+	if !strings.Contains(export, "<summary>long-log.txt (last 16383 bytes)</summary>") {
+		t.Errorf("Expected the excerpt of long-log.txt to start at a character, got:\n%s", export)
+	}
+}
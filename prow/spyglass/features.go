@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// Prometheus Metrics
+var lensFeatureMetrics = struct {
+	enabled *prometheus.GaugeVec
+	configs *prometheus.CounterVec
+}{
+	enabled: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "spyglass_lens_feature_enabled",
+		Help: "Whether a lens feature is turned on for all runs (1) or only those of some orgs and repos (0).",
+	}, []string{"lens", "feature"}),
+	configs: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "spyglass_lens_feature_configs_total",
+		Help: "Number of lens configs resolved with a lens feature turned on or off.",
+	}, []string{"lens", "feature", "enabled"}),
+}
+
+func init() {
+	prometheus.MustRegister(lensFeatureMetrics.enabled)
+	prometheus.MustRegister(lensFeatureMetrics.configs)
+}
+
+// withLensFeatures sets the features field of a lens's config to say which of the features
+// being rolled out for it are turned on for the runs of a job that ran against org and repo.
+// Features that the config already turns on or off, such as from a job's annotations, are left
+// as they are. Configs that are not JSON objects can't have features, so are left unchanged.
+func withLensFeatures(lens string, rawConfig json.RawMessage, features map[string]config.LensFeature, org, repo string) json.RawMessage {
+	if len(features) == 0 {
+		return rawConfig
+	}
+	var fields map[string]json.RawMessage
+	if len(rawConfig) > 0 && json.Unmarshal(rawConfig, &fields) != nil {
+		logrus.WithField("lens", lens).Info("Not turning on lens features in lens config that isn't a JSON object.")
+		return rawConfig
+	}
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	enabled := lenses.Features(rawConfig)
+	if enabled == nil {
+		enabled = map[string]bool{}
+	}
+	for name, feature := range features {
+		if _, ok := enabled[name]; !ok {
+			enabled[name] = feature.EnabledFor(org, repo)
+		}
+		lensFeatureMetrics.enabled.WithLabelValues(lens, name).Set(boolValue(feature.Enabled))
+		lensFeatureMetrics.configs.WithLabelValues(lens, name, strconv.FormatBool(enabled[name])).Inc()
+	}
+	rawFeatures, err := json.Marshal(enabled)
+	if err != nil {
+		return rawConfig
+	}
+	fields[lenses.FeaturesField] = rawFeatures
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return rawConfig
+	}
+	return merged
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestWithLensFeatures(t *testing.T) {
+	features := map[string]config.LensFeature{
+		"everywhere": {Enabled: true},
+		"rollout":    {Orgs: []string{"kubernetes"}},
+	}
+	testCases := []struct {
+		name     string
+		config   string
+		features map[string]config.LensFeature
+		org      string
+		expected string
+	}{
+		{
+			name:     "no features leaves the config alone",
+			config:   `{"a":"b"}`,
+			expected: `{"a":"b"}`,
+		},
+		{
+			name:     "features are added to the config",
+			config:   `{"a":"b"}`,
+			features: features,
+			org:      "kubernetes",
+			expected: `{"a":"b","features":{"everywhere":true,"rollout":true}}`,
+		},
+		{
+			name:     "features are off for other orgs",
+			config:   `{"a":"b"}`,
+			features: features,
+			org:      "kubernetes-sigs",
+			expected: `{"a":"b","features":{"everywhere":true,"rollout":false}}`,
+		},
+		{
+			name:     "lenses without config get features",
+			features: features,
+			expected: `{"features":{"everywhere":true,"rollout":false}}`,
+		},
+		{
+			name:     "features set in the config win",
+			config:   `{"features":{"everywhere":false,"local":true}}`,
+			features: features,
+			org:      "kubernetes",
+			expected: `{"features":{"everywhere":false,"local":true,"rollout":true}}`,
+		},
+		{
+			name:     "config that isn't an object is left alone",
+			config:   `["a"]`,
+			features: features,
+			expected: `["a"]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := withLensFeatures("buildlog", json.RawMessage(tc.config), tc.features, tc.org, "")
+			if string(actual) != tc.expected {
+				t.Errorf("expected config %q, got %q", tc.expected, string(actual))
+			}
+		})
+	}
+}
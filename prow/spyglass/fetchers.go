@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// ArtifactFetcher reads the artifacts of runs from one kind of storage. Keys are paths within the
+// storage that start with a bucket, such as "bucket/logs/job/123".
+type ArtifactFetcher interface {
+	// Artifacts returns the names of the artifacts stored under key.
+	Artifacts(key string) ([]string, error)
+	// Artifact returns the named artifact stored under key.
+	Artifact(key, artifactName string, sizeLimit int64) (lenses.Artifact, error)
+}
+
+// Fetchers may also implement any of the following interfaces, which Spyglass uses to do more
+// with, or make fewer requests to, the storage they read. The GCS fetcher implements them all.
+
+// renderFetcher is implemented by fetchers that can share the artifacts they fetch between the
+// requests made for a page render.
+type renderFetcher interface {
+	// forRender returns a fetcher sharing the artifacts it fetches with every other request made
+	// for the page render with the given ID.
+	forRender(renderID string) ArtifactFetcher
+}
+
+// existingFetcher is implemented by fetchers that can find which of many artifacts exist together,
+// rather than with a request for each.
+type existingFetcher interface {
+	// fetchExisting returns those of the named artifacts stored under key that exist, in the order
+	// they are named. Any artifacts found before an error are returned along with it.
+	fetchExisting(key string, artifactNames []string, sizeLimit int64) ([]lenses.Artifact, error)
+}
+
+// artifactStater is implemented by fetchers that can tell what storage knows about artifacts.
+type artifactStater interface {
+	// stat returns the attributes of those of the named artifacts stored under key that exist,
+	// keyed by name.
+	stat(key string, artifactNames []string) (map[string]*storage.ObjectAttrs, error)
+}
+
+// symlinkReader is implemented by fetchers of storage in which runs can link to other runs.
+type symlinkReader interface {
+	// readSymlink returns the storage URL of the run that the run stored under key links to, or
+	// "" if it isn't a symlink.
+	readSymlink(key string) (string, error)
+}
+
+// bucketReader is implemented by fetchers of storage that Spyglass can list the runs of jobs in.
+type bucketReader interface {
+	// Bucket returns a handle to the named bucket.
+	Bucket(name string) (*storage.BucketHandle, error)
+}
+
+// RegisterFetcher makes Spyglass read the runs whose srcs have keyType, such as the "gcs" of
+// "gcs/bucket/logs/job/123", with fetcher. Storage URLs with any of schemes, such as the "gs" of
+// "gs://bucket/logs/job/123", address the runs it reads. Fetchers must be registered before
+// Spyglass serves any request.
+func (s *Spyglass) RegisterFetcher(keyType string, fetcher ArtifactFetcher, schemes ...string) {
+	s.fetchers[keyType] = fetcher
+	for _, scheme := range schemes {
+		s.schemes[scheme] = keyType
+	}
+}
+
+// storageKeyType returns the key type of the storage holding the runs of srcs with keyType. Runs
+// of ProwJobs and browsable prefixes are stored in GCS.
+func storageKeyType(keyType string) string {
+	if keyType == prowKeyType || keyType == prefixKeyType {
+		return gcsKeyType
+	}
+	return keyType
+}
+
+// storage returns the fetcher registered for the storage holding the run in src, along with its
+// key type and the key of the run in it.
+func (s *Spyglass) storage(src string) (fetcher ArtifactFetcher, keyType, key string, err error) {
+	srcKeyType, key, err := splitSrc(strings.TrimSuffix(src, "/"))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("error parsing src: %v", err)
+	}
+	switch srcKeyType {
+	case prowKeyType:
+		if key, err = s.prowToGCS(key); err != nil {
+			return nil, "", "", err
+		}
+	case prefixKeyType:
+		if key, err = s.prefixKey(key); err != nil {
+			return nil, "", "", err
+		}
+	}
+	keyType = storageKeyType(srcKeyType)
+	fetcher, ok := s.fetchers[keyType]
+	if !ok {
+		return nil, "", "", fmt.Errorf("unrecognized key type for src: %v", src)
+	}
+	if r, ok := fetcher.(renderFetcher); ok && s.renderID != "" {
+		fetcher = r.forRender(s.renderID)
+	}
+	return fetcher, keyType, strings.TrimSuffix(key, "/"), nil
+}
+
+// fetchExisting fetches those of the named artifacts of the run stored under key that exist,
+// asking the fetcher which do if it can tell, and leaving out those that can't be fetched
+// otherwise. Names that could lead out of the run aren't passed to the fetcher.
+func fetchExisting(fetcher ArtifactFetcher, key string, sizeLimit int64, artifactNames []string) ([]lenses.Artifact, error) {
+	var names []string
+	for _, name := range artifactNames {
+		if !strings.HasPrefix(name, "/") && !hasDotSegment(name) {
+			names = append(names, name)
+		}
+	}
+	if f, ok := fetcher.(existingFetcher); ok {
+		return f.fetchExisting(key, names, sizeLimit)
+	}
+	var arts []lenses.Artifact
+	for _, name := range names {
+		art, err := fetcher.Artifact(key, name, sizeLimit)
+		if err != nil {
+			continue
+		}
+		arts = append(arts, art)
+	}
+	return arts, nil
+}
+
+// gcsFetcher is the GCS artifact fetcher as it is registered with Spyglass. Artifacts fetched for
+// a page render are shared by every request made for it.
+type gcsFetcher struct {
+	af       *GCSArtifactFetcher
+	renderID string
+}
+
+func (f gcsFetcher) Artifacts(key string) ([]string, error) {
+	return f.af.artifacts(key)
+}
+
+func (f gcsFetcher) Artifact(key, artifactName string, sizeLimit int64) (lenses.Artifact, error) {
+	art, err := f.af.renderArtifact(f.renderID, key, artifactName, sizeLimit)
+	if err != nil {
+		return nil, err
+	}
+	return art, nil
+}
+
+func (f gcsFetcher) forRender(renderID string) ArtifactFetcher {
+	return gcsFetcher{af: f.af, renderID: renderID}
+}
+
+// fetchExisting states the named artifacts together, mostly from a listing of the run's artifacts,
+// rather than one request at a time.
+func (f gcsFetcher) fetchExisting(key string, artifactNames []string, sizeLimit int64) ([]lenses.Artifact, error) {
+	stats, statErr := f.af.stat(key, artifactNames)
+	var arts []lenses.Artifact
+	for _, name := range artifactNames {
+		attrs, ok := stats[name]
+		if !ok {
+			continue
+		}
+		art, err := f.af.renderArtifact(f.renderID, key, name, sizeLimit)
+		if err != nil {
+			continue
+		}
+		art.setAttrs(attrs)
+		arts = append(arts, art)
+	}
+	return arts, statErr
+}
+
+func (f gcsFetcher) stat(key string, artifactNames []string) (map[string]*storage.ObjectAttrs, error) {
+	return f.af.stat(key, artifactNames)
+}
+
+func (f gcsFetcher) readSymlink(key string) (string, error) {
+	return f.af.readSymlink(key)
+}
+
+func (f gcsFetcher) Bucket(name string) (*storage.BucketHandle, error) {
+	return f.af.Bucket(name)
+}
+
+// unsupportedStorage returns the error for a storage URL whose scheme no fetcher is registered for.
+func (s *Spyglass) unsupportedStorage(storageURL string) error {
+	var schemes []string
+	for scheme := range s.schemes {
+		schemes = append(schemes, scheme+"://")
+	}
+	sort.Strings(schemes)
+	return fmt.Errorf("can't read storage at %s: only https://storage.googleapis.com/ and %s URLs are supported", storageURL, strings.Join(schemes, ", "))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// fakeFetcher reads runs from a map of keys to the names of their artifacts. Like a fetcher
+// reading from a file system, it finds artifacts by joining their names onto the key.
+type fakeFetcher map[string][]string
+
+func (f fakeFetcher) Artifacts(key string) ([]string, error) {
+	names, ok := f[key]
+	if !ok {
+		return nil, errors.New("no such run")
+	}
+	return names, nil
+}
+
+func (f fakeFetcher) Artifact(key, artifactName string, sizeLimit int64) (lenses.Artifact, error) {
+	for k, names := range f {
+		for _, name := range names {
+			if path.Join(k, name) == path.Join(key, artifactName) {
+				return NewLocalArtifact(path.Join("/runs", k, name), artifactName, sizeLimit), nil
+			}
+		}
+	}
+	return nil, errors.New("no such artifact")
+}
+
+func TestRegisterFetcher(t *testing.T) {
+	sg := New(nil, fca{}.Config, nil, context.Background())
+	sg.RegisterFetcher("s3", fakeFetcher{
+		"bucket/logs/job/1": {"artifacts/junit.xml", "build-log.txt"},
+		"bucket/logs/job/2": {"secret.txt"},
+	}, "s3")
+
+	src, err := sg.SourceForURL("s3://bucket/logs/job/1/")
+	if err != nil {
+		t.Fatalf("unexpected error resolving the storage URL: %v", err)
+	}
+	if src != "s3/bucket/logs/job/1" {
+		t.Errorf("expected the src s3/bucket/logs/job/1, got %q", src)
+	}
+	if resolved, err := sg.ResolveSymlink(src); err != nil || resolved != src {
+		t.Errorf("expected %q to resolve to itself, got %q and error %v", src, resolved, err)
+	}
+	if _, err := sg.SourceForURL("file:///tmp/logs/job/1"); err == nil {
+		t.Error("expected an error resolving a URL of unregistered storage")
+	}
+
+	names, err := sg.ListArtifacts(src)
+	if err != nil {
+		t.Fatalf("unexpected error listing artifacts: %v", err)
+	}
+	if expected := []string{"artifacts/junit.xml", "build-log.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected artifacts %v, got %v", expected, names)
+	}
+	if _, err := sg.ListArtifacts("s3/bucket/logs/job/3"); err == nil {
+		t.Error("expected an error listing a run that can't be read")
+	}
+
+	arts, err := sg.FetchArtifacts(src, "", 100, []string{"build-log.txt", "missing.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error fetching artifacts: %v", err)
+	}
+	if len(arts) != 1 || arts[0].JobPath() != "build-log.txt" {
+		t.Errorf("expected only the build log to be fetched, got %v", arts)
+	}
+	if arts, err := sg.FetchArtifacts(src, "", 100, []string{"../2/secret.txt", "/bucket/logs/job/2/secret.txt"}); err != nil || len(arts) != 0 {
+		t.Errorf("expected artifacts of other runs not to be fetched, got %v (err: %v)", arts, err)
+	}
+
+	siblings, err := sg.FetchSiblingArtifacts(src, 100, []string{"build-log.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error fetching sibling artifacts: %v", err)
+	}
+	if matched, _ := lenses.SplitSiblings(siblings); len(siblings) != 1 || len(matched) != 0 || siblings[0].JobPath() != "artifacts/junit.xml" {
+		t.Errorf("expected the JUnit artifact as the only sibling, got %v", siblings)
+	}
+
+	if _, expired := sg.Expired(src); expired {
+		t.Error("expected a run with artifacts not to have expired")
+	}
+	if jobPath, err := sg.JobPath(src); err != nil || jobPath != "bucket/logs/job" {
+		t.Errorf("expected the job path bucket/logs/job, got %q (err: %v)", jobPath, err)
+	}
+	if runPath, err := sg.RunPath(src); err != nil || runPath != "bucket/logs/job/1" {
+		t.Errorf("expected the run path bucket/logs/job/1, got %q (err: %v)", runPath, err)
+	}
+	if _, err := sg.StatArtifacts(src, nil); err == nil {
+		t.Error("expected an error stating artifacts with a fetcher that can't state them")
+	}
+	if _, err := sg.PreviousRun(src, false); err == nil {
+		t.Error("expected an error finding earlier runs with a fetcher that can't list them")
+	}
+
+	if _, err := sg.ListArtifacts("azure/bucket/logs/job/1"); err == nil {
+		t.Error("expected an error listing a run of an unregistered key type")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 
 	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
@@ -42,9 +43,22 @@ type GCSArtifact struct {
 	// sizeLimit is the max size to read before failing
 	sizeLimit int64
 
+	// size is the size of the Artifact if it is known from listing the job's artifacts, or -1
+	size int64
+
 	// ctx provides context for cancellation and timeout. Embedded in struct to preserve
 	// conformance with io.ReaderAt
 	ctx context.Context
+
+	// render is the page render the Artifact is shared by, or nil if it isn't shared.
+	render *render
+
+	lock sync.Mutex
+	// attrs are the attributes of the Artifact in GCS, once they are fetched.
+	attrs *storage.ObjectAttrs
+	// content is the content of the Artifact, if it was read in full and its render had room to
+	// keep it.
+	content []byte
 }
 
 type artifactHandle interface {
@@ -60,6 +74,7 @@ func NewGCSArtifact(ctx context.Context, handle artifactHandle, link string, pat
 		link:      link,
 		path:      path,
 		sizeLimit: sizeLimit,
+		size:      -1,
 		ctx:       ctx,
 	}
 }
@@ -72,13 +87,41 @@ func fieldsFor(a *GCSArtifact) logrus.Fields {
 
 // Size returns the size of the artifact in GCS
 func (a *GCSArtifact) Size() (int64, error) {
-	attrs, err := a.handle.Attrs(a.ctx)
+	if a.size >= 0 {
+		return a.size, nil
+	}
+	attrs, err := a.attributes()
 	if err != nil {
-		return 0, fmt.Errorf("error getting gcs attributes for artifact: %v", err)
+		return 0, err
 	}
 	return attrs.Size, nil
 }
 
+// setAttrs records the attributes of the artifact in GCS, if they were stated along with those of
+// other artifacts.
+func (a *GCSArtifact) setAttrs(attrs *storage.ObjectAttrs) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.attrs == nil {
+		a.attrs = attrs
+	}
+}
+
+// attributes returns the attributes of the artifact in GCS, fetching them the first time only.
+func (a *GCSArtifact) attributes() (*storage.ObjectAttrs, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.attrs != nil {
+		return a.attrs, nil
+	}
+	attrs, err := a.handle.Attrs(a.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting gcs attributes for artifact: %v", err)
+	}
+	a.attrs = attrs
+	return attrs, nil
+}
+
 // JobPath gets the GCS path of the artifact within the current job
 func (a *GCSArtifact) JobPath() string {
 	return a.path
@@ -197,6 +240,12 @@ func (a *GCSArtifact) ReadAll() ([]byte, error) {
 	if size > a.sizeLimit {
 		return nil, lenses.ErrFileTooLarge
 	}
+	a.lock.Lock()
+	content := a.content
+	a.lock.Unlock()
+	if content != nil {
+		return content, nil
+	}
 	reader, err := a.handle.NewReader(a.ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting artifact reader: %v", err)
@@ -206,6 +255,11 @@ func (a *GCSArtifact) ReadAll() ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error reading all from artifact: %v", err)
 	}
+	if a.render != nil && a.render.reserve(int64(len(p))) {
+		a.lock.Lock()
+		a.content = p
+		a.lock.Unlock()
+	}
 	return p, nil
 }
 
@@ -242,9 +296,9 @@ func (a *GCSArtifact) ReadTail(n int64) ([]byte, error) {
 
 // gzipped returns whether the file is gzip-encoded in GCS
 func (a *GCSArtifact) gzipped() (bool, error) {
-	attrs, err := a.handle.Attrs(a.ctx)
+	attrs, err := a.attributes()
 	if err != nil {
-		return false, fmt.Errorf("error getting gcs attributes for artifact: %v", err)
+		return false, err
 	}
 	return attrs.ContentEncoding == "gzip", nil
 }
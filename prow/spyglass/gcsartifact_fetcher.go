@@ -25,13 +25,14 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/api/iterator"
 
-	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/testgrid/util/gcs"
 )
 
@@ -45,9 +46,21 @@ var (
 	ErrCannotParseSource = errors.New("could not create job source from provided source")
 )
 
+// statWorkers bounds how many artifacts missing from a listing of their job's artifacts are stated
+// at once.
+const statWorkers = 16
+
 // GCSArtifactFetcher contains information used for fetching artifacts from GCS
 type GCSArtifactFetcher struct {
-	client *storage.Client
+	client   *storage.Client
+	listings *listingCache
+	renders  *renderCache
+	// config is used to look up bucket aliases, and may be nil if there are none.
+	config config.Getter
+
+	clientsLock sync.Mutex
+	// clients read buckets with other credentials than client, keyed by credentials file.
+	clients map[string]*storage.Client
 }
 
 // gcsJobSource is a location in GCS where Prow job-specific artifacts are stored. This implementation assumes
@@ -65,7 +78,10 @@ type gcsJobSource struct {
 // NewGCSArtifactFetcher creates a new ArtifactFetcher with a real GCS Client
 func NewGCSArtifactFetcher(c *storage.Client) *GCSArtifactFetcher {
 	return &GCSArtifactFetcher{
-		client: c,
+		client:   c,
+		listings: newListingCache(),
+		renders:  newRenderCache(),
+		clients:  map[string]*storage.Client{},
 	}
 }
 
@@ -88,7 +104,7 @@ func newGCSJobSource(src string) (*gcsJobSource, error) {
 	}
 
 	tokens := strings.FieldsFunc(gcsPath.Object(), func(c rune) bool { return c == '/' })
-	if len(tokens) < 2 {
+	if len(tokens) < 2 || hasDotSegment(gcsPath.Object()) {
 		return &gcsJobSource{}, ErrCannotParseSource
 	}
 	buildID := tokens[len(tokens)-1]
@@ -105,15 +121,64 @@ func newGCSJobSource(src string) (*gcsJobSource, error) {
 
 // Artifacts lists all artifacts available for the given job source
 func (af *GCSArtifactFetcher) artifacts(key string) ([]string, error) {
+	listing, err := af.listing(key)
+	if err != nil {
+		return listing.names, err
+	}
+	return append([]string{}, listing.names...), nil
+}
+
+// size returns the size of an artifact of the given job source, if its artifacts have been listed
+// recently enough to know it.
+func (af *GCSArtifactFetcher) size(key string, artifactName string) (int64, bool) {
+	attrs, ok := af.listedAttrs(key, artifactName)
+	if !ok {
+		return 0, false
+	}
+	return attrs.Size, true
+}
+
+// listedAttrs returns the attributes of an artifact of the given job source, if its artifacts have
+// been listed recently enough to know them.
+func (af *GCSArtifactFetcher) listedAttrs(key string, artifactName string) (*storage.ObjectAttrs, bool) {
+	listing, ok := af.cachedListing(key)
+	if !ok {
+		return nil, false
+	}
+	attrs, ok := listing.attrs[artifactName]
+	return attrs, ok
+}
+
+// cachedListing returns the listing of the artifacts of the given job source, if they have been
+// listed recently enough.
+func (af *GCSArtifactFetcher) cachedListing(key string) (*artifactListing, bool) {
 	src, err := newGCSJobSource(key)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get GCS job source from %s: %v", key, err)
+		return nil, false
+	}
+	return af.listings.get(src.jobPath())
+}
+
+// listing lists the names and attributes of the artifacts of the given job source, reusing a cached
+// listing if there is one. Failed listings aren't cached, but return the artifacts listed before
+// the failure.
+func (af *GCSArtifactFetcher) listing(key string) (*artifactListing, error) {
+	src, err := newGCSJobSource(key)
+	if err != nil {
+		return &artifactListing{}, fmt.Errorf("Failed to get GCS job source from %s: %v", key, err)
+	}
+	if listing, ok := af.listings.get(src.jobPath()); ok {
+		return listing, nil
 	}
 
 	listStart := time.Now()
 	bucketName, prefix := extractBucketPrefixPair(src.jobPath())
 	artifacts := []string{}
-	bkt := af.client.Bucket(bucketName)
+	attrs := map[string]*storage.ObjectAttrs{}
+	bkt, err := af.Bucket(bucketName)
+	if err != nil {
+		return &artifactListing{}, err
+	}
 	q := storage.Query{
 		Prefix:   prefix,
 		Versions: false,
@@ -128,18 +193,20 @@ func (af *GCSArtifactFetcher) artifacts(key string) ([]string, error) {
 		if err != nil {
 			logrus.WithFields(fieldsForJob(src)).WithError(err).Error("Error accessing GCS artifact.")
 			if i >= len(wait) {
-				return artifacts, fmt.Errorf("timed out: error accessing GCS artifact: %v", err)
+				return &artifactListing{names: artifacts, attrs: attrs}, fmt.Errorf("timed out: error accessing GCS artifact: %v", err)
 			}
 			time.Sleep((wait[i] + time.Duration(rand.Intn(10))) * time.Millisecond)
 			i++
 			continue
 		}
-		artifacts = append(artifacts, strings.TrimPrefix(oAttrs.Name, prefix))
+		name := strings.TrimPrefix(oAttrs.Name, prefix)
+		artifacts = append(artifacts, name)
+		attrs[name] = oAttrs
 		i = 0
 	}
 	listElapsed := time.Since(listStart)
 	logrus.WithField("duration", listElapsed).Infof("Listed %d artifacts.", len(artifacts))
-	return artifacts, nil
+	return af.listings.add(src.jobPath(), artifacts, attrs), nil
 }
 
 type gcsArtifactHandle struct {
@@ -157,21 +224,181 @@ func (h *gcsArtifactHandle) NewRangeReader(ctx context.Context, offset, length i
 // Artifact constructs a GCS artifact from the given GCS bucket and key. Uses the golang GCS library
 // to get read handles. If the artifactName is not a valid key in the bucket a handle will still be
 // constructed and returned, but all read operations will fail (dictated by behavior of golang GCS lib).
-func (af *GCSArtifactFetcher) artifact(key string, artifactName string, sizeLimit int64) (lenses.Artifact, error) {
+func (af *GCSArtifactFetcher) artifact(key string, artifactName string, sizeLimit int64) (*GCSArtifact, error) {
 	src, err := newGCSJobSource(key)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get GCS job source from %s: %v", key, err)
 	}
 
 	bucketName, prefix := extractBucketPrefixPair(src.jobPath())
-	bkt := af.client.Bucket(bucketName)
-	obj := &gcsArtifactHandle{bkt.Object(path.Join(prefix, artifactName))}
+	bkt, err := af.Bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	objectName, err := artifactObject(prefix, artifactName)
+	if err != nil {
+		return nil, err
+	}
+	obj := &gcsArtifactHandle{bkt.Object(objectName)}
 	artifactLink := &url.URL{
 		Scheme: httpsScheme,
 		Host:   "storage.googleapis.com",
-		Path:   path.Join(src.jobPath(), artifactName),
+		Path:   path.Join(af.StoragePath(src.jobPath()), artifactName),
+	}
+	if af.private(bucketName) {
+		// Users can't follow links to the bucket, so Deck's artifact proxy reads it for them.
+		artifactLink = &url.URL{
+			Path: "/spyglass/artifact",
+			RawQuery: url.Values{
+				"src":      []string{path.Join(gcsKeyType, src.jobPath())},
+				"artifact": []string{artifactName},
+			}.Encode(),
+		}
+	}
+	artifact := NewGCSArtifact(context.Background(), obj, artifactLink.String(), artifactName, sizeLimit)
+	if attrs, ok := af.listedAttrs(key, artifactName); ok {
+		artifact.size = attrs.Size
+		artifact.attrs = attrs
+	}
+	return artifact, nil
+}
+
+// readSymlink returns the storage URL in the object beside the directory of the run stored under
+// key, named for it with a .txt suffix, which makes the run a symlink to the run at that URL. It
+// returns "" if there is no such object.
+func (af *GCSArtifactFetcher) readSymlink(key string) (string, error) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("gcs path should have both a bucket and a path")
+	}
+	bucketName := parts[0]
+	prefix := parts[1]
+	bkt, err := af.Bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	obj := bkt.Object(prefix + ".txt")
+	reader, err := obj.NewReader(context.Background())
+	if err != nil {
+		return "", nil
+	}
+	defer reader.Close()
+	// Avoid using ReadAll here to prevent an attacker forcing us to read a giant file into memory.
+	bytes := make([]byte, 4096) // assume we won't get more than 4 kB of symlink to read
+	n, err := reader.Read(bytes)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read symlink file (which does seem to exist): %v", err)
+	}
+	if n == len(bytes) {
+		return "", fmt.Errorf("symlink destination exceeds length limit of %d bytes", len(bytes)-1)
+	}
+	return string(bytes[:n]), nil
+}
+
+// renderArtifact returns the artifact fetched for the page render with the given ID, so that every
+// lens request of the render shares it. It constructs the artifact the first time, or every time if
+// there is no render ID.
+func (af *GCSArtifactFetcher) renderArtifact(renderID, key, artifactName string, sizeLimit int64) (*GCSArtifact, error) {
+	if renderID == "" {
+		return af.artifact(key, artifactName, sizeLimit)
+	}
+	cacheKey := fmt.Sprintf("%s/%s@%d", key, artifactName, sizeLimit)
+	return af.renders.artifact(renderID, cacheKey, func(r *render) (*GCSArtifact, error) {
+		artifact, err := af.artifact(key, artifactName, sizeLimit)
+		if err != nil {
+			return nil, err
+		}
+		artifact.render = r
+		return artifact, nil
+	})
+}
+
+// stat returns the attributes of those of the named artifacts of the given job source that exist,
+// keyed by name. They are taken from a listing of the job's artifacts, which takes one request for
+// all of them, if the job's artifacts were listed recently or there are more than statWorkers of
+// them. The others are stated, statWorkers at a time, unless the job had finished when it was
+// listed and so can't have uploaded them since.
+func (af *GCSArtifactFetcher) stat(key string, artifactNames []string) (map[string]*storage.ObjectAttrs, error) {
+	stats := map[string]*storage.ObjectAttrs{}
+	listing, listed := af.cachedListing(key)
+	if !listed && len(artifactNames) > statWorkers {
+		var err error
+		listing, err = af.listing(key)
+		listed = err == nil
+	}
+	var unlisted []string
+	for _, name := range artifactNames {
+		if !listed {
+			unlisted = append(unlisted, name)
+		} else if attrs, ok := listing.attrs[name]; ok {
+			stats[name] = attrs
+		} else if !listing.finished {
+			unlisted = append(unlisted, name)
+		}
+	}
+	if len(unlisted) == 0 {
+		return stats, nil
+	}
+
+	src, err := newGCSJobSource(key)
+	if err != nil {
+		return stats, fmt.Errorf("Failed to get GCS job source from %s: %v", key, err)
+	}
+	bucketName, prefix := extractBucketPrefixPair(src.jobPath())
+	bkt, err := af.Bucket(bucketName)
+	if err != nil {
+		return stats, err
+	}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	names := make(chan string)
+	for i := 0; i < statWorkers && i < len(unlisted); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				objectName, err := artifactObject(prefix, name)
+				if err != nil {
+					continue
+				}
+				attrs, err := bkt.Object(objectName).Attrs(context.Background())
+				if err != nil {
+					continue
+				}
+				lock.Lock()
+				stats[name] = attrs
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, name := range unlisted {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+	return stats, nil
+}
+
+// artifactObject returns the name of the object the named artifact is stored in, given the prefix
+// the run's artifacts are stored under. Names that lead out of the prefix are refused, as they
+// could read the artifacts of runs the user isn't allowed to see.
+func artifactObject(prefix, artifactName string) (string, error) {
+	objectName := path.Join(prefix, artifactName)
+	if strings.HasPrefix(artifactName, "/") || !strings.HasPrefix(objectName, strings.TrimSuffix(prefix, "/")+"/") {
+		return "", fmt.Errorf("invalid artifact name %q", artifactName)
+	}
+	return objectName, nil
+}
+
+// hasDotSegment returns whether a slash-separated path has a "." or ".." segment, with which it
+// could name a different path than it appears to.
+func hasDotSegment(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
 	}
-	return NewGCSArtifact(context.Background(), obj, artifactLink.String(), artifactName, sizeLimit), nil
+	return false
 }
 
 func extractBucketPrefixPair(gcsPath string) (string, string) {
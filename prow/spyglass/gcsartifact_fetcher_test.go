@@ -18,6 +18,8 @@ package spyglass
 
 import (
 	"testing"
+
+	"k8s.io/test-infra/prow/config"
 )
 
 func TestNewGCSJobSource(t *testing.T) {
@@ -57,6 +59,11 @@ func TestNewGCSJobSource(t *testing.T) {
 			exBuildID:   "403",
 			expectedErr: nil,
 		},
+		{
+			name:        "Test GCS link with a .. segment",
+			src:         "test-bucket/logs/other-ci-run/../example-ci-run/403",
+			expectedErr: ErrCannotParseSource,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -129,6 +136,33 @@ func TestArtifacts_ListGCS(t *testing.T) {
 	}
 }
 
+// Tests that listed artifacts are remembered along with their sizes
+func TestArtifacts_CachedListing(t *testing.T) {
+	testAf := NewGCSArtifactFetcher(fakeGCSServer.Client())
+	source := "test-bucket/logs/example-ci-run/403"
+	if _, ok := testAf.size(source, "build-log.txt"); ok {
+		t.Fatal("expected no size to be known before listing artifacts")
+	}
+	if _, err := testAf.artifacts(source); err != nil {
+		t.Fatalf("Failed to get artifact names: %v", err)
+	}
+	if size, ok := testAf.size(source+"/", "build-log.txt"); !ok || size != 25 {
+		t.Errorf("expected build-log.txt to be known to have size 25, got %d (known: %t)", size, ok)
+	}
+	artifact, err := testAf.artifact(source, "build-log.txt", 500e6)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if artifact.size != 25 {
+		t.Errorf("expected the artifact to be created with its listed size, got %d", artifact.size)
+	}
+	names, _ := testAf.artifacts(source)
+	names[0] = "modified"
+	if again, _ := testAf.artifacts(source); again[0] == "modified" {
+		t.Error("expected callers not to be able to modify cached listings")
+	}
+}
+
 // Tests getting handles to objects associated with the current job in GCS
 func TestFetchArtifacts_GCS(t *testing.T) {
 	fakeGCSClient := fakeGCSServer.Client()
@@ -173,3 +207,173 @@ func TestFetchArtifacts_GCS(t *testing.T) {
 		}
 	}
 }
+
+// Tests that runs linked to under a bucket alias are read from the bucket it names
+func TestArtifacts_BucketAlias(t *testing.T) {
+	testAf := NewGCSArtifactFetcher(fakeGCSServer.Client())
+	testAf.config = fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		BucketAliases: map[string]config.BucketAlias{"old-bucket": {Bucket: "test-bucket"}},
+	}}}}}.Config
+	source := "old-bucket/logs/example-ci-run/403"
+	names, err := testAf.artifacts(source)
+	if err != nil {
+		t.Fatalf("Failed to get artifact names: %v", err)
+	}
+	if len(names) != 5 {
+		t.Errorf("expected the 5 artifacts in test-bucket, got %v", names)
+	}
+	artifact, err := testAf.artifact(source, "build-log.txt", 500e6)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if content, err := artifact.ReadAll(); err != nil || len(content) != 25 {
+		t.Errorf("expected to read build-log.txt from test-bucket, got %q (err: %v)", content, err)
+	}
+	expectedLink := "https://storage.googleapis.com/test-bucket/logs/example-ci-run/403/build-log.txt"
+	if link := artifact.CanonicalLink(); link != expectedLink {
+		t.Errorf("expected link %q, got %q", expectedLink, link)
+	}
+	if p := testAf.StoragePath("test-bucket/logs/job/1"); p != "test-bucket/logs/job/1" {
+		t.Errorf("expected paths in unaliased buckets to be unchanged, got %q", p)
+	}
+}
+
+// Tests that links to artifacts in private buckets go through Deck's artifact proxy
+func TestArtifacts_PrivateBucketLinks(t *testing.T) {
+	testAf := NewGCSArtifactFetcher(fakeGCSServer.Client())
+	testAf.config = fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		BucketAliases:  map[string]config.BucketAlias{"old-bucket": {Bucket: "test-bucket"}},
+		PrivateBuckets: []string{"test-bucket"},
+	}}}}}.Config
+	testCases := []struct {
+		name         string
+		source       string
+		expectedLink string
+	}{
+		{
+			name:         "private bucket",
+			source:       "test-bucket/logs/example-ci-run/403",
+			expectedLink: "/spyglass/artifact?artifact=build-log.txt&src=gcs%2Ftest-bucket%2Flogs%2Fexample-ci-run%2F403",
+		},
+		{
+			name:         "alias of a private bucket",
+			source:       "old-bucket/logs/example-ci-run/403",
+			expectedLink: "/spyglass/artifact?artifact=build-log.txt&src=gcs%2Fold-bucket%2Flogs%2Fexample-ci-run%2F403",
+		},
+		{
+			name:         "public bucket",
+			source:       "public-bucket/logs/example-ci-run/403",
+			expectedLink: "https://storage.googleapis.com/public-bucket/logs/example-ci-run/403/build-log.txt",
+		},
+	}
+	for _, tc := range testCases {
+		artifact, err := testAf.artifact(tc.source, "build-log.txt", 500e6)
+		if err != nil {
+			t.Fatalf("%s: failed to get artifact: %v", tc.name, err)
+		}
+		if link := artifact.CanonicalLink(); link != tc.expectedLink {
+			t.Errorf("%s: expected link %q, got %q", tc.name, tc.expectedLink, link)
+		}
+	}
+}
+
+// Tests that artifacts are stated from a listing of the job's artifacts where there is one
+func TestStat(t *testing.T) {
+	testAf := NewGCSArtifactFetcher(fakeGCSServer.Client())
+	source := "test-bucket/logs/example-ci-run/403"
+	stats, err := testAf.stat(source, []string{"build-log.txt", "missing.txt"})
+	if err != nil {
+		t.Fatalf("Failed to stat artifacts: %v", err)
+	}
+	if len(stats) != 1 || stats["build-log.txt"] == nil || stats["build-log.txt"].Size != 25 {
+		t.Errorf("expected only build-log.txt to be stated, with size 25, got %v", stats)
+	}
+
+	if _, err := testAf.artifacts(source); err != nil {
+		t.Fatalf("Failed to list artifacts: %v", err)
+	}
+	listing, _ := testAf.listings.get(source + "/")
+	stats, err = testAf.stat(source, []string{"build-log.txt", "missing.txt"})
+	if err != nil {
+		t.Fatalf("Failed to stat artifacts: %v", err)
+	}
+	if len(stats) != 1 || stats["build-log.txt"] != listing.attrs["build-log.txt"] {
+		t.Errorf("expected build-log.txt to be stated from the listing of the job's artifacts, got %v", stats)
+	}
+	artifact, err := testAf.artifact(source, "build-log.txt", 500e6)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if artifact.attrs != listing.attrs["build-log.txt"] {
+		t.Error("expected the artifact to be created with its listed attributes")
+	}
+
+	if stats, err := testAf.stat("test-bucket/logs/example-ci-run/404", []string{"build-log.txt"}); err != nil || len(stats) != 0 {
+		t.Errorf("expected no artifacts to be stated for a run without any, got %v (err: %v)", stats, err)
+	}
+	if stats, err := testAf.stat("test-bucket/logs/example-ci-run/404", []string{"../403/build-log.txt"}); err != nil || len(stats) != 0 {
+		t.Errorf("expected artifacts of other runs not to be stated, got %v (err: %v)", stats, err)
+	}
+}
+
+func TestArtifactObject(t *testing.T) {
+	testCases := []struct {
+		name         string
+		artifactName string
+		expected     string
+		expectErr    bool
+	}{
+		{
+			name:         "artifact of the run",
+			artifactName: "artifacts/junit_01.xml",
+			expected:     "logs/example-ci-run/404/artifacts/junit_01.xml",
+		},
+		{
+			name:         "artifact named with a .. that stays in the run",
+			artifactName: "artifacts/../build-log.txt",
+			expected:     "logs/example-ci-run/404/build-log.txt",
+		},
+		{
+			name:         "artifact of another run",
+			artifactName: "../403/build-log.txt",
+			expectErr:    true,
+		},
+		{
+			name:         "artifact of a run with a longer name",
+			artifactName: "../4040/build-log.txt",
+			expectErr:    true,
+		},
+		{
+			name:         "the run's own prefix",
+			artifactName: ".",
+			expectErr:    true,
+		},
+		{
+			name:         "absolute artifact name",
+			artifactName: "/logs/example-ci-run/403/build-log.txt",
+			expectErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := artifactObject("logs/example-ci-run/404/", tc.artifactName)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+
+	testAf := NewGCSArtifactFetcher(fakeGCSServer.Client())
+	if _, err := testAf.artifact("test-bucket/logs/example-ci-run/404", "../403/build-log.txt", 500e6); err == nil {
+		t.Error("expected an error fetching an artifact of another run")
+	}
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata"
+)
+
+const (
+	// maxPreviousRuns bounds how many earlier runs PreviousRun will inspect looking for one that passed.
+	maxPreviousRuns = 20
+	// maxBaselineRuns bounds how many of the latest runs of a baseline job BaselineRun will inspect
+	// looking for one that passed before the run being viewed.
+	maxBaselineRuns = 100
+)
+
+// jobBucket returns the bucket holding the runs of the job of the run in src, along with the key
+// type of the srcs of runs in it, its name and the path of the job's directory in it. Only storage
+// whose fetcher hands out bucket handles has job histories.
+func (s *Spyglass) jobBucket(src string) (bkt *storage.BucketHandle, keyType, bucketName, prefix string, err error) {
+	jobPath, err := s.JobPath(src)
+	if err != nil {
+		return nil, "", "", "", fmt.Errorf("failed to find job directory: %v", err)
+	}
+	srcKeyType, _, _ := splitSrc(src)
+	keyType = storageKeyType(srcKeyType)
+	reader, ok := s.fetchers[keyType].(bucketReader)
+	if !ok {
+		return nil, "", "", "", fmt.Errorf("the runs of jobs in %s storage can't be listed", keyType)
+	}
+	bucketName, prefix = extractBucketPrefixPair(jobPath)
+	if bkt, err = reader.Bucket(bucketName); err != nil {
+		return nil, "", "", "", err
+	}
+	return bkt, keyType, bucketName, prefix, nil
+}
+
+// PreviousRun returns the src of the most recent run of the same job that started before the one in src.
+// If passing is true, only runs that finished successfully are considered.
+func (s *Spyglass) PreviousRun(src string, passing bool) (string, error) {
+	_, buildID, err := s.KeyToJob(src)
+	if err != nil {
+		return "", err
+	}
+	current, err := strconv.ParseInt(buildID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("build ID %q is not numeric, so runs can't be ordered", buildID)
+	}
+	bkt, keyType, bucketName, prefix, err := s.jobBucket(src)
+	if err != nil {
+		return "", err
+	}
+
+	runs, err := listRuns(bkt, bucketName, prefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to list runs of %s: %v", path.Join(bucketName, prefix), err)
+	}
+	var ids []int64
+	for id := range runs {
+		if id < current {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	if len(ids) > maxPreviousRuns {
+		ids = ids[:maxPreviousRuns]
+	}
+	for _, id := range ids {
+		runPath, err := runs[id](context.Background())
+		if err != nil {
+			continue
+		}
+		if !passing || runPassed(bkt, runPath) {
+			return path.Join(keyType, bucketName, runPath), nil
+		}
+	}
+	if passing {
+		return "", fmt.Errorf("no passing run in the %d runs before %d", len(ids), current)
+	}
+	return "", fmt.Errorf("no run before %d", current)
+}
+
+// BaselineRun returns the src of the run that lenses compare the run in src to. Presubmits with a
+// baseline job, named by their BaselineJobAnnotation or deck.spyglass.baseline_jobs, are compared
+// to the latest passing run of that job that started before them, so that their artifacts are
+// compared to those of the branch they would merge into. Other runs, and presubmits whose baseline
+// job has no such run, are compared to the most recent passing run of their job before them.
+func (s *Spyglass) BaselineRun(src string) (string, error) {
+	job, err := s.prowJob(src)
+	if err != nil || job.Spec.Type != prowapi.PresubmitJob {
+		return s.PreviousRun(src, true)
+	}
+	baselineJob := job.Annotations[BaselineJobAnnotation]
+	if baselineJob == "" {
+		baselineJob = s.config().Deck.Spyglass.BaselineJobs[job.Spec.Job]
+	}
+	if baselineJob == "" {
+		return s.PreviousRun(src, true)
+	}
+	baseline, err := s.latestPassingRun(src, baselineJob, job.Status.StartTime.Time)
+	if err != nil {
+		logrus.WithError(err).WithField("src", src).Debug("Falling back to an earlier run of the presubmit as its baseline.")
+		return s.PreviousRun(src, true)
+	}
+	return baseline, nil
+}
+
+// latestPassingRun returns the src of the latest passing run of a periodic or postsubmit job that
+// started before a time, whose artifacts are in the same bucket as those of the run in src.
+func (s *Spyglass) latestPassingRun(src, jobName string, before time.Time) (string, error) {
+	bkt, keyType, bucketName, _, err := s.jobBucket(src)
+	if err != nil {
+		return "", err
+	}
+	runs, err := listRuns(bkt, bucketName, path.Join(gcs.NonPRLogs, jobName))
+	if err != nil {
+		return "", fmt.Errorf("failed to list runs of %s: %v", jobName, err)
+	}
+	var ids []int64
+	for id := range runs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	if len(ids) > maxBaselineRuns {
+		ids = ids[:maxBaselineRuns]
+	}
+	for _, id := range ids {
+		runPath, err := runs[id](context.Background())
+		if err != nil {
+			continue
+		}
+		started, ok := runStarted(bkt, runPath)
+		if !ok || (!before.IsZero() && started.After(before)) {
+			continue
+		}
+		if runPassed(bkt, runPath) {
+			return path.Join(keyType, bucketName, runPath), nil
+		}
+	}
+	return "", fmt.Errorf("no passing run of %s started before %s in its %d latest runs", jobName, before.UTC().Format(time.RFC3339), len(ids))
+}
+
+// FetchBaselineArtifacts fetches the named artifacts from the baseline run of src, wrapped so that
+// lenses can tell them apart from the artifacts of the run being viewed. Lens pages are shown to
+// everyone who can see the run in src, so baselines that someone who can see it couldn't see are
+// not read.
+func (s *Spyglass) FetchBaselineArtifacts(src string, sizeLimit int64, artifactNames []string) ([]lenses.Artifact, error) {
+	baseline, err := s.BaselineRun(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CheckVisibleWith(baseline, src); err != nil {
+		return nil, err
+	}
+	return s.FetchComparedArtifacts(baseline, sizeLimit, artifactNames)
+}
+
+// CheckVisibleWith returns an error unless everyone who can see the run in src can also see the
+// run in other, because every access rule restricting other also restricts src.
+func (s *Spyglass) CheckVisibleWith(other, src string) error {
+	otherRules, err := s.AccessRules(other)
+	if err != nil {
+		return fmt.Errorf("failed to look up who can see %s: %v", other, err)
+	}
+	if len(otherRules) == 0 {
+		return nil
+	}
+	rules, err := s.AccessRules(src)
+	if err != nil {
+		return fmt.Errorf("failed to look up who can see %s: %v", src, err)
+	}
+	for _, otherRule := range otherRules {
+		shared := false
+		for _, rule := range rules {
+			if reflect.DeepEqual(rule, otherRule) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			return fmt.Errorf("%s is restricted to fewer users than %s", other, src)
+		}
+	}
+	return nil
+}
+
+// FetchComparedArtifacts fetches the named artifacts of a run that another is compared to, wrapped
+// as baseline artifacts so that lenses can tell them apart from the artifacts of the other run.
+func (s *Spyglass) FetchComparedArtifacts(baseline string, sizeLimit int64, artifactNames []string) ([]lenses.Artifact, error) {
+	artifacts, err := s.FetchArtifacts(baseline, "", sizeLimit, artifactNames)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]lenses.Artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		// FetchArtifacts falls back to pod logs, which can't belong to a finished run.
+		if _, ok := a.(*PodLogArtifact); ok {
+			continue
+		}
+		wrapped = append(wrapped, lenses.NewBaselineArtifact(a, baseline))
+	}
+	return wrapped, nil
+}
+
+// runResolver returns the path of a run within its bucket.
+type runResolver func(ctx context.Context) (string, error)
+
+// listRuns finds the runs under a job directory. Periodic and postsubmit runs are subdirectories
+// named by build ID. Presubmit runs are spread across PR directories, so the job directory instead
+// holds <build ID>.txt files containing a gs:// link to each run.
+func listRuns(bkt *storage.BucketHandle, bucketName, prefix string) (map[int64]runResolver, error) {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	runs := map[int64]runResolver{}
+	it := bkt.Objects(context.Background(), &storage.Query{Prefix: prefix, Delimiter: "/"})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if attrs.Prefix != "" {
+			dir := strings.TrimSuffix(attrs.Prefix, "/")
+			if id, err := strconv.ParseInt(path.Base(dir), 10, 64); err == nil {
+				runs[id] = func(context.Context) (string, error) { return dir, nil }
+			}
+			continue
+		}
+		name := path.Base(attrs.Name)
+		if !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, ".txt"), 10, 64)
+		if err != nil {
+			continue
+		}
+		object := attrs.Name
+		runs[id] = func(ctx context.Context) (string, error) {
+			return resolveRunLink(ctx, bkt, bucketName, object)
+		}
+	}
+	return runs, nil
+}
+
+func resolveRunLink(ctx context.Context, bkt *storage.BucketHandle, bucketName, object string) (string, error) {
+	r, err := bkt.Object(object).NewReader(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	link, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	target := strings.TrimSpace(string(link))
+	expected := "gs://" + bucketName + "/"
+	if !strings.HasPrefix(target, expected) {
+		return "", fmt.Errorf("%s links to %q, outside of bucket %s", object, target, bucketName)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(target, expected), "/"), nil
+}
+
+// runStarted returns when a run started, according to its started.json.
+func runStarted(bkt *storage.BucketHandle, runPath string) (time.Time, bool) {
+	r, err := bkt.Object(path.Join(runPath, "started.json")).NewReader(context.Background())
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer r.Close()
+	var started metadata.Started
+	if err := json.NewDecoder(r).Decode(&started); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(started.Timestamp, 0), true
+}
+
+func runPassed(bkt *storage.BucketHandle, runPath string) bool {
+	r, err := bkt.Object(path.Join(runPath, "finished.json")).NewReader(context.Background())
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	var finished metadata.Finished
+	if err := json.NewDecoder(r).Decode(&finished); err != nil {
+		return false
+	}
+	if finished.Passed != nil {
+		return *finished.Passed
+	}
+	return finished.Result == "SUCCESS"
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Anchor returns the anchor that links name a part of a lens's view with the given title by: the
+// title's letters and digits in lower case, with each run of other characters replaced by a hyphen.
+func Anchor(title string) string {
+	var anchor strings.Builder
+	hyphen := false
+	for _, c := range title {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if hyphen && anchor.Len() > 0 {
+				anchor.WriteByte('-')
+			}
+			anchor.WriteRune(unicode.ToLower(c))
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return anchor.String()
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import "testing"
+
+func TestAnchor(t *testing.T) {
+	testCases := []struct {
+		title    string
+		expected string
+	}{
+		{title: "Run unit tests", expected: "run-unit-tests"},
+		{title: "[sig-network] Services should serve a basic endpoint [Conformance]", expected: "sig-network-services-should-serve-a-basic-endpoint-conformance"},
+		{title: "TestFoo/sub_test", expected: "testfoo-sub-test"},
+		{title: "  --- Ünïcode 2 ---  ", expected: "ünïcode-2"},
+		{title: "***"},
+	}
+	for _, tc := range testCases {
+		if actual := Anchor(tc.title); actual != tc.expected {
+			t.Errorf("Anchor(%q): expected %q, got %q", tc.title, tc.expected, actual)
+		}
+	}
+}
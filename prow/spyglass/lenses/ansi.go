@@ -0,0 +1,265 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// ansiStyle is the display style set by SGR ("select graphic rendition") escape sequences.
+type ansiStyle struct {
+	bold, faint, italic, underline bool
+	fg, bg                         ansiColor
+}
+
+// ansiColor is a color of the 256-color palette, or a 24-bit color if rgb is set.
+type ansiColor struct {
+	set, rgb bool
+	value    uint32
+}
+
+// ANSIConverter converts text containing ANSI escape sequences to HTML. Colors and styles are
+// rendered as spans: the 16 basic colors and the styles with classes ("ansi-1", "ansi-bg-1",
+// "ansi-bold", ...) that lenses style, and the other colors of the 256-color palette and 24-bit
+// colors inline. All text is escaped, and all other escape sequences are dropped.
+//
+// The style set by one piece of text applies to the pieces converted after it, so that a line
+// split into pieces, such as highlighted matches and links, is converted like the whole line.
+// The zero value is ready to use.
+type ANSIConverter struct {
+	style ansiStyle
+}
+
+// ANSIToHTML converts text containing ANSI escape sequences to HTML.
+func ANSIToHTML(text string) template.HTML {
+	var c ANSIConverter
+	return c.Convert(text)
+}
+
+// Convert converts a piece of text to HTML, in the style left by the pieces before it.
+func (c *ANSIConverter) Convert(text string) template.HTML {
+	if c.style == (ansiStyle{}) && strings.IndexByte(text, '\x1b') == -1 {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	// written is the style of the span open in the output.
+	written := ansiStyle{}
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\x1b')
+		if i == -1 {
+			i = len(text)
+		}
+		if i > 0 {
+			if c.style != written {
+				if written != (ansiStyle{}) {
+					b.WriteString("</span>")
+				}
+				if c.style != (ansiStyle{}) {
+					c.style.writeSpan(&b)
+				}
+				written = c.style
+			}
+			template.HTMLEscape(&b, []byte(text[:i]))
+		}
+		text = text[i:]
+		if len(text) > 0 {
+			text = c.escape(text)
+		}
+	}
+	if written != (ansiStyle{}) {
+		b.WriteString("</span>")
+	}
+	return template.HTML(b.String())
+}
+
+// escape interprets the escape sequence text begins with and returns the text following it.
+// Sequences cut off by the end of the text are dropped.
+func (c *ANSIConverter) escape(text string) string {
+	if len(text) < 2 {
+		return ""
+	}
+	switch text[1] {
+	case '[':
+		// Control sequences are parameter bytes, then intermediate bytes, then a final byte.
+		i := 2
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x3f {
+			i++
+		}
+		if i == len(text) {
+			return ""
+		}
+		if text[i] == 'm' {
+			c.style.apply(text[2:i])
+		}
+		return text[i+1:]
+	case ']':
+		// Operating system commands, such as those setting the window title, end with a bell or
+		// a string terminator.
+		for i := 2; i < len(text); i++ {
+			if text[i] == '\a' {
+				return text[i+1:]
+			}
+			if text[i] == '\x1b' && i+1 < len(text) && text[i+1] == '\\' {
+				return text[i+2:]
+			}
+		}
+		return ""
+	default:
+		// Other sequences are intermediate bytes, such as those designating character sets,
+		// then a final byte.
+		i := 1
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x2f {
+			i++
+		}
+		if i == len(text) {
+			return ""
+		}
+		return text[i+1:]
+	}
+}
+
+// apply applies the semicolon-separated parameters of an SGR sequence to the style.
+func (s *ansiStyle) apply(params string) {
+	var codes [16]int
+	n := 0
+	for {
+		code := 0
+		i := 0
+		for i < len(params) && params[i] >= '0' && params[i] <= '9' {
+			code = code*10 + int(params[i]-'0')
+			i++
+		}
+		if n < len(codes) {
+			codes[n] = code
+			n++
+		}
+		if i == len(params) {
+			break
+		}
+		params = params[i+1:]
+	}
+	for i := 0; i < n; i++ {
+		switch code := codes[i]; {
+		case code == 0:
+			*s = ansiStyle{}
+		case code == 1:
+			s.bold = true
+		case code == 2:
+			s.faint = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 22:
+			s.bold, s.faint = false, false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code >= 30 && code <= 37:
+			s.fg = ansiColor{set: true, value: uint32(code - 30)}
+		case code >= 90 && code <= 97:
+			s.fg = ansiColor{set: true, value: uint32(code - 90 + 8)}
+		case code == 39:
+			s.fg = ansiColor{}
+		case code >= 40 && code <= 47:
+			s.bg = ansiColor{set: true, value: uint32(code - 40)}
+		case code >= 100 && code <= 107:
+			s.bg = ansiColor{set: true, value: uint32(code - 100 + 8)}
+		case code == 49:
+			s.bg = ansiColor{}
+		case code == 38 || code == 48:
+			color, used := extendedColor(codes[i+1 : n])
+			i += used
+			if code == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor reads a 256-color ("5;n") or 24-bit ("2;r;g;b") color from the parameters
+// following a 38 or 48 code, returning it and how many parameters it used.
+func extendedColor(params []int) (ansiColor, int) {
+	if len(params) >= 2 && params[0] == 5 && params[1] <= 255 {
+		return ansiColor{set: true, value: uint32(params[1])}, 2
+	}
+	if len(params) >= 4 && params[0] == 2 && params[1] <= 255 && params[2] <= 255 && params[3] <= 255 {
+		return ansiColor{set: true, rgb: true, value: uint32(params[1]<<16 | params[2]<<8 | params[3])}, 4
+	}
+	return ansiColor{}, len(params)
+}
+
+// rgb24 returns the 24-bit value of a color.
+func (c ansiColor) rgb24() uint32 {
+	if c.rgb {
+		return c.value
+	}
+	if c.value >= 232 {
+		// The grayscale ramp.
+		gray := 8 + 10*(c.value-232)
+		return gray<<16 | gray<<8 | gray
+	}
+	// The 6x6x6 color cube.
+	levels := [6]uint32{0, 95, 135, 175, 215, 255}
+	i := c.value - 16
+	return levels[i/36]<<16 | levels[i/6%6]<<8 | levels[i%6]
+}
+
+// writeSpan writes the opening tag of a span displaying text in the style.
+func (s ansiStyle) writeSpan(b *strings.Builder) {
+	var classes, styles []string
+	if s.bold {
+		classes = append(classes, "ansi-bold")
+	}
+	if s.faint {
+		classes = append(classes, "ansi-faint")
+	}
+	if s.italic {
+		classes = append(classes, "ansi-italic")
+	}
+	if s.underline {
+		classes = append(classes, "ansi-underline")
+	}
+	if s.fg.set {
+		if !s.fg.rgb && s.fg.value < 16 {
+			classes = append(classes, fmt.Sprintf("ansi-%d", s.fg.value))
+		} else {
+			styles = append(styles, fmt.Sprintf("color: #%06x", s.fg.rgb24()))
+		}
+	}
+	if s.bg.set {
+		if !s.bg.rgb && s.bg.value < 16 {
+			classes = append(classes, fmt.Sprintf("ansi-bg-%d", s.bg.value))
+		} else {
+			styles = append(styles, fmt.Sprintf("background-color: #%06x", s.bg.rgb24()))
+		}
+	}
+	b.WriteString("<span")
+	if len(classes) > 0 {
+		fmt.Fprintf(b, ` class="%s"`, strings.Join(classes, " "))
+	}
+	if len(styles) > 0 {
+		fmt.Fprintf(b, ` style="%s"`, strings.Join(styles, "; "))
+	}
+	b.WriteString(">")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestANSIToHTML(t *testing.T) {
+	testcases := []struct {
+		name     string
+		text     string
+		expected template.HTML
+	}{
+		{
+			name:     "plain text is escaped",
+			text:     "if a < b && c > d",
+			expected: "if a &lt; b &amp;&amp; c &gt; d",
+		},
+		{
+			name:     "basic colors are classes",
+			text:     "\x1b[31mFAIL\x1b[0m ok \x1b[1;92mPASS\x1b[0m",
+			expected: `<span class="ansi-1">FAIL</span> ok <span class="ansi-bold ansi-10">PASS</span>`,
+		},
+		{
+			name:     "backgrounds and reset codes",
+			text:     "\x1b[4;44mon blue\x1b[24m plain\x1b[49m\x1b[39m",
+			expected: `<span class="ansi-underline ansi-bg-4">on blue</span><span class="ansi-bg-4"> plain</span>`,
+		},
+		{
+			name:     "256 colors beyond the basic ones are inline",
+			text:     "\x1b[38;5;9mred\x1b[38;5;196mbright\x1b[48;5;232mgray\x1b[m",
+			expected: `<span class="ansi-9">red</span><span style="color: #ff0000">bright</span><span style="color: #ff0000; background-color: #080808">gray</span>`,
+		},
+		{
+			name:     "truecolor is inline",
+			text:     "\x1b[38;2;255;128;0morange\x1b[0m",
+			expected: `<span style="color: #ff8000">orange</span>`,
+		},
+		{
+			name:     "other escape sequences are dropped",
+			text:     "\x1b]0;title\x07\x1b[2Kclear\x1b[1Gline\x1b(B\x1b[",
+			expected: "clearline",
+		},
+		{
+			name:     "styles are closed at the end of the text",
+			text:     "\x1b[33m<warning>",
+			expected: `<span class="ansi-3">&lt;warning&gt;</span>`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ANSIToHTML(tc.text); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestANSIConverterKeepsStyleAcrossPieces(t *testing.T) {
+	var c ANSIConverter
+	pieces := []string{"\x1b[31mpanic: ", "runtime error", "\x1b[0m at main.go:12"}
+	expected := []template.HTML{
+		`<span class="ansi-1">panic: </span>`,
+		`<span class="ansi-1">runtime error</span>`,
+		` at main.go:12`,
+	}
+	for i, piece := range pieces {
+		if actual := c.Convert(piece); actual != expected[i] {
+			t.Errorf("piece %d: expected %q, got %q", i, expected[i], actual)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicoverage
+
+import (
+	"sort"
+	"strings"
+)
+
+// Changes of an operation or field since the baseline run.
+const (
+	changeGained = "gained"
+	changeLost   = "lost"
+)
+
+// OperationResult is whether an operation was tested.
+type OperationResult struct {
+	*Operation
+	// Hits is the number of requests made to the operation. Operations listed as covered by
+	// experiment/coverage are tested without any hits.
+	Hits   int
+	Tested bool
+	// Change is whether the operation was gained or lost since the baseline run, if any.
+	Change string
+}
+
+// LevelSummary is the coverage of the operations of a stability level.
+type LevelSummary struct {
+	Level         string
+	Total, Tested int
+	// Delta is the change in the number of tested operations since the baseline run.
+	Delta int
+}
+
+// Percent returns the percentage of operations tested.
+func (l LevelSummary) Percent() int {
+	if l.Total == 0 {
+		return 0
+	}
+	return 100 * l.Tested / l.Total
+}
+
+// KindResult is the fields of a kind set in request bodies.
+type KindResult struct {
+	Kind string
+	// Total is the number of fields the spec defines for the kind, or 0 if it does not.
+	Total    int
+	Tested   []string
+	Untested []string
+	// Gained and Lost are the fields set since the baseline run that were not before, and
+	// those that are no longer set.
+	Gained, Lost []string
+}
+
+// Report is the API coverage of a run, compared to the baseline run if there is one.
+type Report struct {
+	Levels     []LevelSummary
+	Operations []*OperationResult
+	// Unmatched is the number of distinct paths requested that are not in the spec.
+	Unmatched int
+	Kinds     []*KindResult
+}
+
+// tested returns the number of requests made to each tested operation, and how many distinct
+// paths matched no operation.
+func tested(spec *Spec, o *Observed) (map[string]int, int) {
+	hits := map[string]int{}
+	unmatched := 0
+	for key := range o.covered {
+		hits[key] += 0
+	}
+	if spec == nil {
+		return hits, 0
+	}
+	for c, n := range o.calls {
+		if op := spec.match(c.method, c.path); op != nil {
+			hits[op.Key()] += n
+		} else {
+			unmatched++
+		}
+	}
+	return hits, unmatched
+}
+
+// operations returns the operations of the spec or, without one, those listed as covered.
+func operations(spec *Spec, o *Observed) []*Operation {
+	if spec != nil {
+		return spec.Operations
+	}
+	var ops []*Operation
+	for key := range o.covered {
+		parts := strings.SplitN(key, " ", 2)
+		ops = append(ops, &Operation{Method: parts[0], Path: parts[1], Level: level(parts[1])})
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
+
+// fieldsSet returns the fields of a kind set in requests, limited to those the spec defines
+// for the kind if it does.
+func fieldsSet(spec *Spec, o *Observed, kind string) map[string]bool {
+	set := o.fields[kind]
+	if spec == nil {
+		return set
+	}
+	defined := spec.Fields(kind)
+	if defined == nil {
+		return set
+	}
+	result := map[string]bool{}
+	for _, f := range defined {
+		if set[f] {
+			result[f] = true
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newReport measures the coverage of the current run against the spec, comparing it to the
+// baseline run if baseline is not nil.
+func newReport(spec *Spec, current, baseline *Observed) *Report {
+	r := &Report{}
+	hits, unmatched := tested(spec, current)
+	r.Unmatched = unmatched
+	var before map[string]int
+	if baseline != nil {
+		before, _ = tested(spec, baseline)
+	}
+	summaries := map[string]*LevelSummary{}
+	for _, l := range levels {
+		summaries[l] = &LevelSummary{Level: l}
+	}
+	for _, op := range operations(spec, current) {
+		n, ok := hits[op.Key()]
+		result := &OperationResult{Operation: op, Hits: n, Tested: ok}
+		s := summaries[op.Level]
+		s.Total++
+		if ok {
+			s.Tested++
+		}
+		if baseline != nil {
+			_, was := before[op.Key()]
+			switch {
+			case ok && !was:
+				result.Change = changeGained
+				s.Delta++
+			case !ok && was:
+				result.Change = changeLost
+				s.Delta--
+			}
+		}
+		r.Operations = append(r.Operations, result)
+	}
+	for _, l := range levels {
+		if s := summaries[l]; s.Total > 0 {
+			r.Levels = append(r.Levels, *s)
+		}
+	}
+
+	kinds := map[string]bool{}
+	for k := range current.fields {
+		kinds[k] = true
+	}
+	if baseline != nil {
+		for k := range baseline.fields {
+			kinds[k] = true
+		}
+	}
+	for _, kind := range sortedKeys(kinds) {
+		now := fieldsSet(spec, current, kind)
+		kr := &KindResult{Kind: kind, Tested: sortedKeys(now)}
+		if spec != nil {
+			defined := spec.Fields(kind)
+			kr.Total = len(defined)
+			for _, f := range defined {
+				if !now[f] {
+					kr.Untested = append(kr.Untested, f)
+				}
+			}
+		}
+		if baseline != nil {
+			then := fieldsSet(spec, baseline, kind)
+			for _, f := range kr.Tested {
+				if !then[f] {
+					kr.Gained = append(kr.Gained, f)
+				}
+			}
+			for _, f := range sortedKeys(then) {
+				if !now[f] {
+					kr.Lost = append(kr.Lost, f)
+				}
+			}
+		}
+		r.Kinds = append(r.Kinds, kr)
+	}
+	return r
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicoverage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAuditLog = `{"kind":"Event","stage":"RequestReceived","verb":"create","requestURI":"/api/v1/namespaces/e2e-1/pods"}
+{"kind":"Event","stage":"ResponseComplete","verb":"create","requestURI":"/api/v1/namespaces/e2e-1/pods","requestObject":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web"},"spec":{"containers":[{"image":"nginx"}]}}}
+{"kind":"Event","stage":"ResponseComplete","verb":"watch","requestURI":"/api/v1/namespaces/e2e-1/pods?watch=true"}
+{"kind":"Event","stage":"ResponseComplete","verb":"get","requestURI":"/healthz"}
+{"kind":"Event","stage":"ResponseComplete","verb"
+`
+
+const testE2ELog = `I0919 15:34:14.943642    6611 round_trippers.go:414] GET https://10.0.0.1:6443/api/v1/namespaces/e2e-1/pods/web 200 OK in 2 milliseconds
+I0919 15:34:15.943642    6611 round_trippers.go:414] DELETE https://10.0.0.1:6443/api/v1/namespaces/e2e-1/pods/web 200 OK in 2 milliseconds
+`
+
+const testAPIServerLog = `I0413 12:10:56.612005       1 wrap.go:42] GET /apis/batch/v1beta1/cronjobs: (1.671974ms) 200 [[kube-controller-manager] 127.0.0.1:44356]
+`
+
+func observe(t *testing.T, logs ...string) *Observed {
+	o := newObserved()
+	for _, l := range logs {
+		if err := o.read(strings.NewReader(l)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return o
+}
+
+func TestRead(t *testing.T) {
+	o := observe(t, testAuditLog, testE2ELog, testAPIServerLog, "GET /api/v1/nodes\n")
+	expected := map[call]int{
+		{"POST", "/api/v1/namespaces/e2e-1/pods"}:       1,
+		{"GET", "/api/v1/namespaces/e2e-1/pods"}:        1,
+		{"GET", "/healthz"}:                             1,
+		{"GET", "/api/v1/namespaces/e2e-1/pods/web"}:    1,
+		{"DELETE", "/api/v1/namespaces/e2e-1/pods/web"}: 1,
+		{"GET", "/apis/batch/v1beta1/cronjobs"}:         1,
+	}
+	if !reflect.DeepEqual(o.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, o.calls)
+	}
+	if o.Malformed != 1 || o.Requests() != 6 {
+		t.Errorf("expected 6 requests and 1 malformed event, got %d and %d", o.Requests(), o.Malformed)
+	}
+	if !o.covered["GET /api/v1/nodes"] {
+		t.Errorf("expected GET /api/v1/nodes to be covered, got %v", o.covered)
+	}
+	fields := []string{"metadata", "metadata.name", "spec", "spec.containers", "spec.containers[].image"}
+	if actual := sortedKeys(o.fields["v1 Pod"]); !reflect.DeepEqual(actual, fields) {
+		t.Errorf("expected fields %q, got %q", fields, actual)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	spec, err := parseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	baseline := observe(t, testE2ELog, `{"kind":"Event","verb":"create","requestURI":"/api/v1/namespaces/e2e-1/pods","requestObject":{"apiVersion":"v1","kind":"Pod","spec":{"nodeSelector":{"a":"b"}}}}`)
+	r := newReport(spec, observe(t, testAuditLog, testAPIServerLog), baseline)
+
+	expected := []LevelSummary{{Level: levelStable, Total: 6, Tested: 2, Delta: -1}, {Level: levelBeta, Total: 1, Tested: 1, Delta: 1}}
+	if !reflect.DeepEqual(r.Levels, expected) {
+		t.Errorf("expected levels %+v, got %+v", expected, r.Levels)
+	}
+	changes := map[string]string{}
+	for _, op := range r.Operations {
+		if op.Change != "" {
+			changes[op.Key()] = op.Change
+		}
+	}
+	expectedChanges := map[string]string{
+		"GET /api/v1/namespaces/{namespace}/pods":           changeGained,
+		"GET /apis/batch/v1beta1/cronjobs":                  changeGained,
+		"GET /api/v1/namespaces/{namespace}/pods/{name}":    changeLost,
+		"DELETE /api/v1/namespaces/{namespace}/pods/{name}": changeLost,
+	}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("expected changes %v, got %v", expectedChanges, changes)
+	}
+	if r.Unmatched != 1 {
+		t.Errorf("expected /healthz not to match, got %d unmatched", r.Unmatched)
+	}
+	if len(r.Kinds) != 1 {
+		t.Fatalf("expected one kind, got %d", len(r.Kinds))
+	}
+	pod := r.Kinds[0]
+	if pod.Total != 5 || !reflect.DeepEqual(pod.Tested, []string{"spec", "spec.containers", "spec.containers[].image"}) {
+		t.Errorf("expected 3 of 5 fields to be set, got %q of %d", pod.Tested, pod.Total)
+	}
+	if !reflect.DeepEqual(pod.Gained, []string{"spec.containers", "spec.containers[].image"}) || !reflect.DeepEqual(pod.Lost, []string{"spec.nodeSelector"}) {
+		t.Errorf("unexpected changes to fields: gained %q, lost %q", pod.Gained, pod.Lost)
+	}
+}
+
+func TestNewReportWithoutSpec(t *testing.T) {
+	r := newReport(nil, observe(t, "GET /api/v1/nodes\nPOST /apis/batch/v1beta1/cronjobs/\n"), nil)
+	if len(r.Operations) != 2 || !r.Operations[0].Tested || r.Operations[1].Level != levelBeta {
+		t.Errorf("expected the covered operations to be tested, got %+v", r.Operations)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apicoverage provides a Spyglass lens that shows which operations and fields of the
+// Kubernetes API a job exercised, compared to the last passing run.
+package apicoverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "apicoverage"
+	title    = "API Coverage"
+	priority = 44
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 512 << 20
+)
+
+// specRE matches the names of OpenAPI specs among the artifacts, such as swagger.json.
+var specRE = regexp.MustCompile(`(^|/)[^/]*(swagger|openapi)[^/]*\.json$`)
+
+// Lens is the implementation of an API coverage-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// OpenAPIURL is an http(s) or gs:// URL of the OpenAPI spec to measure coverage against
+	// when the job did not upload one.
+	OpenAPIURL string `json:"openapi_url,omitempty"`
+	// MaxBytes is how much of each log is read. Defaults to 512MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration. It asks for the same artifacts from the last passing
+// run, to show what changed since.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+		Baseline: true,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+type apiCoverageView struct {
+	Errors []string
+	// Spec is where the OpenAPI spec was read from.
+	Spec         string
+	Logs         []string
+	Requests     int
+	Malformed    int
+	BaselineLink string
+	Report       *Report
+	Gained, Lost []*OperationResult
+	Tested       []*OperationResult
+	Untested     []*OperationResult
+}
+
+// Body renders the coverage of the API by the requests in the job's logs, with the operations
+// and fields gained and lost since the last passing run.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := apiCoverageView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	current, baselineArtifacts := lenses.SplitBaseline(artifacts)
+	var previous []lenses.Artifact
+	for _, b := range baselineArtifacts {
+		previous = append(previous, b)
+	}
+	spec, currentLogs := view.readSpec(current)
+	baselineSpec, previousLogs := view.readSpec(previous)
+	if spec == nil && baselineSpec != nil {
+		spec = baselineSpec
+		view.Spec += " (from the last passing run)"
+	}
+	if spec == nil && conf.OpenAPIURL != "" {
+		var err error
+		spec, err = cache.load(conf.OpenAPIURL)
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to load %s: %v", conf.OpenAPIURL, err))
+		}
+		if spec != nil {
+			view.Spec = conf.OpenAPIURL
+		}
+	}
+
+	observed := newObserved()
+	for _, a := range currentLogs {
+		if err := readLog(a, conf.MaxBytes, observed); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		view.Logs = append(view.Logs, a.JobPath())
+	}
+	view.Requests = observed.Requests()
+	view.Malformed = observed.Malformed
+	if spec == nil && len(observed.covered) == 0 {
+		view.Errors = append(view.Errors, "No OpenAPI spec was found to measure coverage against.")
+	}
+
+	var baseline *Observed
+	if len(previousLogs) > 0 {
+		baseline = newObserved()
+		for _, a := range previousLogs {
+			if err := readLog(a, conf.MaxBytes, baseline); err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s from the last passing run: %v", a.JobPath(), err))
+			}
+		}
+		view.BaselineLink = "/view/" + baselineArtifacts[0].Source
+	}
+
+	view.Report = newReport(spec, observed, baseline)
+	for _, op := range view.Report.Operations {
+		switch op.Change {
+		case changeGained:
+			view.Gained = append(view.Gained, op)
+		case changeLost:
+			view.Lost = append(view.Lost, op)
+		}
+		if op.Tested {
+			view.Tested = append(view.Tested, op)
+		} else {
+			view.Untested = append(view.Untested, op)
+		}
+	}
+	sort.SliceStable(view.Tested, func(i, j int) bool { return view.Tested[i].Hits > view.Tested[j].Hits })
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// readSpec parses the first OpenAPI spec among artifacts, returning it with the other artifacts.
+func (view *apiCoverageView) readSpec(artifacts []lenses.Artifact) (*Spec, []lenses.Artifact) {
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	var spec *Spec
+	var logs []lenses.Artifact
+	for _, a := range artifacts {
+		if !specRE.MatchString(a.JobPath()) {
+			logs = append(logs, a)
+			continue
+		}
+		if spec != nil {
+			continue
+		}
+		content, err := a.ReadAll()
+		if err == nil {
+			spec, err = parseSpec(content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		view.Spec = a.JobPath()
+	}
+	return spec, logs
+}
+
+// readLog reads the requests in a log, up to maxBytes of it.
+func readLog(a lenses.Artifact, maxBytes int64, o *Observed) error {
+	r, err := lenses.NewChunkedReader(a, maxBytes)
+	if err != nil {
+		return err
+	}
+	if err := o.read(r); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
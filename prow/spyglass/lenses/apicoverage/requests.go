@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicoverage
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// e2eRE matches a request logged by client-go at -v=6 or higher, such as
+	// "I0919 15:34:14.943642    6611 round_trippers.go:414] GET https://10.0.0.1:6443/api/v1/pods 200 OK in 2 milliseconds".
+	e2eRE = regexp.MustCompile(`round_trippers\.go:\d+\] (GET|PUT|POST|DELETE|OPTIONS|HEAD|PATCH) (\S+)`)
+	// apiserverRE matches a request logged by the API server, such as
+	// "I0413 12:10:56.612005       1 wrap.go:42] PUT /apis/apps/v1/namespaces/default/deployments/web: (1.67ms) 200".
+	apiserverRE = regexp.MustCompile(`wrap\.go:\d+\] (GET|PUT|POST|DELETE|OPTIONS|HEAD|PATCH) (/\S*?):? `)
+	// coveredRE matches a line of the covered APIs listed by experiment/coverage with
+	// --output-covered-apis, such as "GET /api/v1/namespaces/{namespace}/pods".
+	coveredRE = regexp.MustCompile(`^(GET|PUT|POST|DELETE|OPTIONS|HEAD|PATCH) (/\S*)$`)
+)
+
+// verbMethods maps the verbs of audit events to the HTTP methods of their requests.
+var verbMethods = map[string]string{
+	"get":              "GET",
+	"list":             "GET",
+	"watch":            "GET",
+	"create":           "POST",
+	"update":           "PUT",
+	"patch":            "PATCH",
+	"delete":           "DELETE",
+	"deletecollection": "DELETE",
+	"post":             "POST",
+	"put":              "PUT",
+	"head":             "HEAD",
+	"options":          "OPTIONS",
+}
+
+// auditEvent is the subset of an audit.k8s.io Event used by the lens.
+type auditEvent struct {
+	Kind          string          `json:"kind"`
+	Stage         string          `json:"stage"`
+	Verb          string          `json:"verb"`
+	RequestURI    string          `json:"requestURI"`
+	RequestObject json.RawMessage `json:"requestObject"`
+}
+
+// call is a request to a path of the API.
+type call struct {
+	method, path string
+}
+
+// Observed is the API requests made by a run.
+type Observed struct {
+	// calls counts the requests to each path.
+	calls map[call]int
+	// Covered are operations already matched against a spec, such as by experiment/coverage.
+	covered map[string]bool
+	// fields are the fields set in request bodies, by kind.
+	fields map[string]map[string]bool
+	// Malformed is the number of audit events that could not be parsed.
+	Malformed int
+}
+
+func newObserved() *Observed {
+	return &Observed{calls: map[call]int{}, covered: map[string]bool{}, fields: map[string]map[string]bool{}}
+}
+
+// read reads requests from a log, which may be an audit log, a client-go log at -v=6 or higher,
+// an API server log or the covered APIs listed by experiment/coverage.
+func (o *Observed) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) > 0 && line[0] == '{' {
+			o.readEvent(line)
+			continue
+		}
+		text := string(line)
+		if m := coveredRE.FindStringSubmatch(text); m != nil {
+			o.covered[m[1]+" "+strings.TrimRight(m[2], "/")] = true
+			continue
+		}
+		m := e2eRE.FindStringSubmatch(text)
+		if m == nil {
+			m = apiserverRE.FindStringSubmatch(text)
+		}
+		if m != nil {
+			o.add(m[1], m[2])
+		}
+	}
+	return scanner.Err()
+}
+
+// readEvent reads a line of an audit log. Only the final stage of each request is counted.
+func (o *Observed) readEvent(line []byte) {
+	var e auditEvent
+	if err := json.Unmarshal(line, &e); err != nil || e.Kind != "Event" {
+		o.Malformed++
+		return
+	}
+	if e.Stage != "" && e.Stage != "ResponseComplete" && e.Stage != "Panic" {
+		return
+	}
+	method, ok := verbMethods[e.Verb]
+	if !ok {
+		return
+	}
+	o.add(method, e.RequestURI)
+	if len(e.RequestObject) == 0 {
+		return
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(e.RequestObject, &obj); err != nil {
+		return
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return
+	}
+	k := apiVersion + " " + kind
+	if o.fields[k] == nil {
+		o.fields[k] = map[string]bool{}
+	}
+	collectFields(obj, "", o.fields[k])
+}
+
+// add counts a request to a URL, which may be absolute or only a path with a query.
+func (o *Observed) add(method, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" {
+		return
+	}
+	o.calls[call{method: method, path: strings.TrimRight(u.Path, "/")}]++
+}
+
+// collectFields adds the paths of the fields set in an object, such as "spec.replicas".
+func collectFields(value interface{}, prefix string, fields map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			if prefix == "" && (name == "apiVersion" || name == "kind") {
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			fields[path] = true
+			collectFields(child, path, fields)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectFields(child, prefix+"[]", fields)
+		}
+	}
+}
+
+// Requests returns the number of requests counted.
+func (o *Observed) Requests() int {
+	total := 0
+	for _, n := range o.calls {
+		total += n
+	}
+	return total
+}
+
+// Kinds returns the kinds that request bodies were read for, in order.
+func (o *Observed) Kinds() []string {
+	var kinds []string
+	for k := range o.fields {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
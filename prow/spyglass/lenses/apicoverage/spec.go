@@ -0,0 +1,319 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicoverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// specTTL is how long a spec fetched from a URL is used before being fetched again.
+	specTTL = time.Hour
+	// fetchTimeout bounds how long fetching a spec from a URL may take.
+	fetchTimeout = 30 * time.Second
+	// maxFieldDepth bounds how deeply the fields of a kind are listed, as some kinds, such as
+	// CustomResourceDefinition, are recursive.
+	maxFieldDepth = 8
+)
+
+// Stability levels of API operations.
+const (
+	levelStable = "stable"
+	levelBeta   = "beta"
+	levelAlpha  = "alpha"
+)
+
+// levels are the stability levels in the order they are shown.
+var levels = []string{levelStable, levelBeta, levelAlpha}
+
+// methods are the HTTP methods of the operations of an OpenAPI path, as in the OpenAPI v2 spec.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+var (
+	alphaRE = regexp.MustCompile(`/v\d+alpha\d+(/|$)`)
+	betaRE  = regexp.MustCompile(`/v\d+beta\d+(/|$)`)
+)
+
+// Operation is an endpoint of the API, such as "GET /api/v1/namespaces/{namespace}/pods".
+type Operation struct {
+	Method string
+	Path   string
+	ID     string
+	Level  string
+}
+
+// Key identifies the operation.
+func (o *Operation) Key() string {
+	return o.Method + " " + o.Path
+}
+
+// level returns the stability level of an API path from its version.
+func level(path string) string {
+	switch {
+	case alphaRE.MatchString(path):
+		return levelAlpha
+	case betaRE.MatchString(path):
+		return levelBeta
+	default:
+		return levelStable
+	}
+}
+
+// schema is the subset of an OpenAPI v2 schema used to list the fields of kinds.
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	// AdditionalProperties is a schema for maps, but may also be a boolean, so it is not parsed.
+	GVKs []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+type swagger struct {
+	// Paths are parsed one operation at a time, as they also hold parameters.
+	Paths       map[string]map[string]json.RawMessage `json:"paths"`
+	Definitions map[string]*schema                    `json:"definitions"`
+}
+
+// Spec is the operations and kinds of an API, read from its OpenAPI v2 spec.
+type Spec struct {
+	Operations []*Operation
+	// kinds maps the kinds, such as "apps/v1 Deployment", to their definitions.
+	kinds       map[string]*schema
+	definitions map[string]*schema
+	// templates are the operations by method and number of path segments, for matching.
+	templates map[string][]*pathTemplate
+	fields    map[string][]string
+	lock      sync.Mutex
+}
+
+// param replaces the parameters of paths, which match any non-empty segment.
+const param = "{}"
+
+// pathTemplate is the path of an operation split into segments, with its parameters replaced
+// by param.
+type pathTemplate struct {
+	op       *Operation
+	segments []string
+	literals int
+}
+
+// parseSpec parses an OpenAPI v2 spec, such as Kubernetes' swagger.json.
+func parseSpec(content []byte) (*Spec, error) {
+	var raw swagger
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw.Paths) == 0 {
+		return nil, fmt.Errorf("no paths in the spec")
+	}
+	s := &Spec{
+		kinds:       map[string]*schema{},
+		definitions: raw.Definitions,
+		templates:   map[string][]*pathTemplate{},
+		fields:      map[string][]string{},
+	}
+	for path, item := range raw.Paths {
+		// Some paths end with a slash, which requests usually omit.
+		path = strings.TrimRight(path, "/")
+		for _, m := range methods {
+			raw, ok := item[m]
+			if !ok {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %v", m, path, err)
+			}
+			o := &Operation{Method: strings.ToUpper(m), Path: path, ID: op.OperationID, Level: level(path)}
+			s.Operations = append(s.Operations, o)
+			t := &pathTemplate{op: o, segments: strings.Split(path, "/")}
+			for i, seg := range t.segments {
+				if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+					t.segments[i] = param
+				} else {
+					t.literals++
+				}
+			}
+			k := fmt.Sprintf("%s %d", o.Method, len(t.segments))
+			s.templates[k] = append(s.templates[k], t)
+		}
+	}
+	sort.Slice(s.Operations, func(i, j int) bool {
+		if s.Operations[i].Path != s.Operations[j].Path {
+			return s.Operations[i].Path < s.Operations[j].Path
+		}
+		return s.Operations[i].Method < s.Operations[j].Method
+	})
+	for _, d := range raw.Definitions {
+		for _, gvk := range d.GVKs {
+			s.kinds[kindKey(gvk.Group, gvk.Version, gvk.Kind)] = d
+		}
+	}
+	return s, nil
+}
+
+// kindKey names a kind as its apiVersion and kind, such as "apps/v1 Deployment" or "v1 Pod".
+func kindKey(group, version, kind string) string {
+	if group == "" {
+		return version + " " + kind
+	}
+	return group + "/" + version + " " + kind
+}
+
+// match returns the operation that a request was made to, preferring the operation with the
+// most literal segments when several match, or nil if none does.
+func (s *Spec) match(method, path string) *Operation {
+	segments := strings.Split(strings.TrimRight(path, "/"), "/")
+	var best *pathTemplate
+	for _, t := range s.templates[fmt.Sprintf("%s %d", method, len(segments))] {
+		matches := true
+		for i, seg := range t.segments {
+			if seg != segments[i] && (seg != param || segments[i] == "") {
+				matches = false
+				break
+			}
+		}
+		if matches && (best == nil || t.literals > best.literals) {
+			best = t
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.op
+}
+
+// Fields lists the fields of a kind as paths such as "spec.containers[].image", or returns nil if
+// the spec does not define the kind.
+func (s *Spec) Fields(kind string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if fields, ok := s.fields[kind]; ok {
+		return fields
+	}
+	d, ok := s.kinds[kind]
+	if !ok {
+		return nil
+	}
+	var fields []string
+	s.walk(d, "", map[string]bool{}, 0, &fields)
+	sort.Strings(fields)
+	s.fields[kind] = fields
+	return fields
+}
+
+// walk lists the fields under a schema, following references to other definitions unless they
+// are already being walked.
+func (s *Spec) walk(d *schema, prefix string, walking map[string]bool, depth int, fields *[]string) {
+	if d == nil || depth > maxFieldDepth {
+		return
+	}
+	if d.Ref != "" {
+		name := strings.TrimPrefix(d.Ref, "#/definitions/")
+		if walking[name] {
+			return
+		}
+		walking[name] = true
+		s.walk(s.definitions[name], prefix, walking, depth, fields)
+		delete(walking, name)
+		return
+	}
+	if d.Items != nil {
+		s.walk(d.Items, prefix+"[]", walking, depth, fields)
+		return
+	}
+	for name, p := range d.Properties {
+		if prefix == "" && (name == "apiVersion" || name == "kind") {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		*fields = append(*fields, path)
+		s.walk(p, path, walking, depth+1, fields)
+	}
+}
+
+// specCache holds specs fetched from URLs, so that they are not fetched every time the lens is
+// rendered.
+type specCache struct {
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	spec   *Spec
+	loaded time.Time
+}
+
+var cache = &specCache{entries: map[string]cacheEntry{}, now: time.Now}
+
+// load returns the spec at url, fetching it if it has not been fetched recently. If fetching
+// fails, the spec fetched last is returned with the error.
+func (c *specCache) load(url string) (*Spec, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[url]
+	if ok && c.now().Sub(entry.loaded) < specTTL {
+		return entry.spec, nil
+	}
+	spec, err := fetchSpec(url)
+	if err != nil {
+		return entry.spec, err
+	}
+	c.entries[url] = cacheEntry{spec: spec, loaded: c.now()}
+	return spec, nil
+}
+
+// fetchSpec fetches a spec from an http(s) URL. gs:// URLs are fetched from the public GCS
+// endpoint, so the object must be publicly readable.
+func fetchSpec(url string) (*Spec, error) {
+	if strings.HasPrefix(url, "gs://") {
+		url = "https://storage.googleapis.com/" + strings.TrimPrefix(url, "gs://")
+	}
+	client := http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpec(content)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apicoverage
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSpec = `{
+  "swagger": "2.0",
+  "paths": {
+    "/api/v1/namespaces/{namespace}/pods": {"get": {"operationId": "listCoreV1NamespacedPod"}, "post": {"operationId": "createCoreV1NamespacedPod"}},
+    "/api/v1/namespaces/{namespace}/pods/{name}": {"get": {"operationId": "readCoreV1NamespacedPod"}, "delete": {"operationId": "deleteCoreV1NamespacedPod"}, "parameters": []},
+    "/api/v1/namespaces/{name}/status": {"get": {"operationId": "readCoreV1NamespaceStatus"}},
+    "/api/v1/namespaces/{namespace}/{name}": {"get": {"operationId": "made-up"}},
+    "/apis/batch/v1beta1/cronjobs/": {"get": {"operationId": "listBatchV1beta1CronJobForAllNamespaces"}}
+  },
+  "definitions": {
+    "io.k8s.api.core.v1.Pod": {
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "Pod"}]
+    },
+    "io.k8s.api.core.v1.PodSpec": {
+      "properties": {
+        "containers": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Container"}},
+        "nodeSelector": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    },
+    "io.k8s.api.core.v1.Container": {
+      "properties": {
+        "image": {"type": "string"},
+        "schema": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      }
+    }
+  }
+}`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := parseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var keys []string
+	for _, op := range spec.Operations {
+		keys = append(keys, op.Key()+" "+op.Level)
+	}
+	expected := []string{
+		"GET /api/v1/namespaces/{namespace}/pods stable",
+		"POST /api/v1/namespaces/{namespace}/pods stable",
+		"DELETE /api/v1/namespaces/{namespace}/pods/{name} stable",
+		"GET /api/v1/namespaces/{namespace}/pods/{name} stable",
+		"GET /api/v1/namespaces/{namespace}/{name} stable",
+		"GET /api/v1/namespaces/{name}/status stable",
+		"GET /apis/batch/v1beta1/cronjobs beta",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected operations %q, got %q", expected, keys)
+	}
+	fields := []string{"spec", "spec.containers", "spec.containers[].image", "spec.containers[].schema", "spec.nodeSelector"}
+	if actual := spec.Fields("v1 Pod"); !reflect.DeepEqual(actual, fields) {
+		t.Errorf("expected fields %q, got %q", fields, actual)
+	}
+	if actual := spec.Fields("v1 Node"); actual != nil {
+		t.Errorf("expected no fields for an undefined kind, got %q", actual)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	spec, err := parseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		method, path string
+		expected     string
+	}{
+		{"GET", "/api/v1/namespaces/default/pods", "listCoreV1NamespacedPod"},
+		{"GET", "/api/v1/namespaces/default/pods/web-1", "readCoreV1NamespacedPod"},
+		{"GET", "/api/v1/namespaces/default/status", "readCoreV1NamespaceStatus"},
+		{"GET", "/api/v1/namespaces/default/other", "made-up"},
+		{"GET", "/apis/batch/v1beta1/cronjobs/", "listBatchV1beta1CronJobForAllNamespaces"},
+		{"PUT", "/api/v1/namespaces/default/pods/web-1", ""},
+		{"GET", "/api/v1/namespaces//pods", ""},
+	}
+	for _, tc := range testCases {
+		id := ""
+		if op := spec.match(tc.method, tc.path); op != nil {
+			id = op.ID
+		}
+		if id != tc.expected {
+			t.Errorf("expected %s %s to match %q, got %q", tc.method, tc.path, tc.expected, id)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+function inputValue(id: string): string {
+  const input = document.getElementById(id) as HTMLInputElement | null;
+  return input ? input.value.trim() : '';
+}
+
+function applyFilter(verb: string, resource: string, user: string): void {
+  spyglass.updatePage(JSON.stringify({verb, resource, user})).then(bind);
+}
+
+function handleSubmit(e: Event): void {
+  e.preventDefault();
+  applyFilter(inputValue('audit-verb'), inputValue('audit-resource'), inputValue('audit-user'));
+}
+
+function handleClear(): void {
+  applyFilter('', '', '');
+}
+
+function handleSetFilter(this: HTMLAnchorElement, e: MouseEvent): void {
+  e.preventDefault();
+  const filter: {[field: string]: string} = {
+    resource: inputValue('audit-resource'),
+    user: inputValue('audit-user'),
+    verb: inputValue('audit-verb'),
+  };
+  filter[this.dataset.field || ''] = this.dataset.value || '';
+  applyFilter(filter.verb, filter.resource, filter.user);
+}
+
+// The page is replaced wholesale on update, so listeners need to be reattached.
+function bind(): void {
+  const form = document.getElementById('audit-filter');
+  if (form) {
+    form.addEventListener('submit', handleSubmit);
+  }
+  const clear = document.getElementById('audit-clear');
+  if (clear) {
+    clear.addEventListener('click', handleClear);
+  }
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.audit-set-filter'))) {
+    link.addEventListener('click', handleSetFilter);
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', bind);
@@ -0,0 +1,289 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// event is the subset of an audit.k8s.io Event used by the lens.
+type event struct {
+	Stage      string `json:"stage"`
+	Verb       string `json:"verb"`
+	RequestURI string `json:"requestURI"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time `json:"stageTimestamp"`
+}
+
+// nonResource is shown as the resource of requests for non-resource URLs such as /healthz.
+const nonResource = "(non-resource)"
+
+func (e *event) resource() string {
+	if e.ObjectRef == nil || e.ObjectRef.Resource == "" {
+		return nonResource
+	}
+	if e.ObjectRef.Subresource != "" {
+		return e.ObjectRef.Resource + "/" + e.ObjectRef.Subresource
+	}
+	return e.ObjectRef.Resource
+}
+
+func (e *event) code() int {
+	if e.ResponseStatus == nil {
+		return 0
+	}
+	return e.ResponseStatus.Code
+}
+
+// filter selects events. Empty fields match everything.
+type filter struct {
+	Verb     string `json:"verb"`
+	Resource string `json:"resource"`
+	User     string `json:"user"`
+}
+
+func (f filter) matches(e *event) bool {
+	return (f.Verb == "" || f.Verb == e.Verb) &&
+		(f.Resource == "" || f.Resource == e.resource()) &&
+		(f.User == "" || f.User == e.User.Username)
+}
+
+// Request is a single request shown in the list of the slowest.
+type Request struct {
+	Time     time.Time
+	Verb     string
+	Resource string
+	User     string
+	URI      string
+	Code     int
+	Latency  time.Duration
+}
+
+// latencyBuckets are the upper bounds of the latency histogram's buckets.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// stats aggregates the events in audit logs.
+type stats struct {
+	filter filter
+	// Events is the number of completed requests read.
+	Events int
+	// Malformed is the number of lines that could not be parsed.
+	Malformed int
+	Matched   int
+	// Failed is the number of matching requests with an error status.
+	Failed int
+
+	latencies []time.Duration
+	slowest   []Request
+	// The values of each field across all events, for suggesting filters.
+	verbs, resources, users map[string]int
+	// The values of each field across matching events.
+	matchedVerbs, matchedResources, matchedUsers map[string]int
+}
+
+func newStats(f filter) *stats {
+	return &stats{
+		filter:           f,
+		verbs:            map[string]int{},
+		resources:        map[string]int{},
+		users:            map[string]int{},
+		matchedVerbs:     map[string]int{},
+		matchedResources: map[string]int{},
+		matchedUsers:     map[string]int{},
+	}
+}
+
+// read adds the events in an audit log, one JSON object per line.
+func (s *stats) read(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.add(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %v", err)
+		}
+	}
+}
+
+func (s *stats) add(line []byte) {
+	var e event
+	if err := json.Unmarshal(line, &e); err != nil {
+		s.Malformed++
+		return
+	}
+	// Logs may have an event for each stage of a request; only count each request once.
+	if e.Stage != "" && e.Stage != "ResponseComplete" && e.Stage != "Panic" {
+		return
+	}
+	s.Events++
+	resource := e.resource()
+	s.verbs[e.Verb]++
+	s.resources[resource]++
+	s.users[e.User.Username]++
+	if !s.filter.matches(&e) {
+		return
+	}
+	s.Matched++
+	s.matchedVerbs[e.Verb]++
+	s.matchedResources[resource]++
+	s.matchedUsers[e.User.Username]++
+	if e.code() >= 400 {
+		s.Failed++
+	}
+	if e.RequestReceivedTimestamp.IsZero() || e.StageTimestamp.IsZero() {
+		return
+	}
+	latency := e.StageTimestamp.Sub(e.RequestReceivedTimestamp)
+	s.latencies = append(s.latencies, latency)
+	if len(s.slowest) < slowestCount || latency > s.slowest[len(s.slowest)-1].Latency {
+		r := Request{
+			Time:     e.RequestReceivedTimestamp,
+			Verb:     e.Verb,
+			Resource: resource,
+			User:     e.User.Username,
+			URI:      e.RequestURI,
+			Code:     e.code(),
+			Latency:  latency,
+		}
+		i := sort.Search(len(s.slowest), func(i int) bool { return s.slowest[i].Latency < latency })
+		s.slowest = append(s.slowest, Request{})
+		copy(s.slowest[i+1:], s.slowest[i:])
+		s.slowest[i] = r
+		if len(s.slowest) > slowestCount {
+			s.slowest = s.slowest[:slowestCount]
+		}
+	}
+}
+
+// Bucket is a bar of the latency histogram.
+type Bucket struct {
+	Label string
+	Count int
+	// Width is the length of the bar as a percentage of the longest.
+	Width float64
+}
+
+func (s *stats) histogram() []Bucket {
+	counts := make([]int, len(latencyBuckets)+1)
+	for _, l := range s.latencies {
+		counts[sort.Search(len(latencyBuckets), func(i int) bool { return l <= latencyBuckets[i] })]++
+	}
+	most := 0
+	for _, c := range counts {
+		if c > most {
+			most = c
+		}
+	}
+	var buckets []Bucket
+	for i, c := range counts {
+		b := Bucket{Count: c}
+		if i < len(latencyBuckets) {
+			b.Label = "≤ " + latencyBuckets[i].String()
+		} else {
+			b.Label = "> " + latencyBuckets[len(latencyBuckets)-1].String()
+		}
+		if most > 0 {
+			b.Width = 100 * float64(c) / float64(most)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// Percentile is a latency percentile of the matching requests.
+type Percentile struct {
+	Label   string
+	Latency time.Duration
+}
+
+func (s *stats) percentiles() []Percentile {
+	if len(s.latencies) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(q float64) time.Duration {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	return []Percentile{
+		{Label: "p50", Latency: at(0.5)},
+		{Label: "p90", Latency: at(0.9)},
+		{Label: "p99", Latency: at(0.99)},
+		{Label: "max", Latency: sorted[len(sorted)-1]},
+	}
+}
+
+// Count is the number of events with a value of some field.
+type Count struct {
+	Value string
+	Count int
+}
+
+// top returns the n most common values, most common first.
+func top(counts map[string]int, n int) []Count {
+	var result []Count
+	for v, c := range counts {
+		result = append(result, Count{Value: v, Count: c})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `{"stage":"RequestReceived","verb":"get","user":{"username":"kubelet"},"objectRef":{"resource":"pods","namespace":"default","name":"a"},"requestReceivedTimestamp":"2019-03-01T10:00:00.000000Z","stageTimestamp":"2019-03-01T10:00:00.000000Z"}
+{"stage":"ResponseComplete","verb":"get","requestURI":"/api/v1/namespaces/default/pods/a","user":{"username":"kubelet"},"objectRef":{"resource":"pods","namespace":"default","name":"a"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2019-03-01T10:00:00.000000Z","stageTimestamp":"2019-03-01T10:00:00.020000Z"}
+{"stage":"ResponseComplete","verb":"list","user":{"username":"e2e"},"objectRef":{"resource":"pods","namespace":"default"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2019-03-01T10:00:01.000000Z","stageTimestamp":"2019-03-01T10:00:03.000000Z"}
+not json
+{"stage":"ResponseComplete","verb":"update","user":{"username":"kubelet"},"objectRef":{"resource":"pods","subresource":"status","namespace":"default","name":"a"},"responseStatus":{"code":409},"requestReceivedTimestamp":"2019-03-01T10:00:02.000000Z","stageTimestamp":"2019-03-01T10:00:02.100000Z"}
+{"stage":"ResponseComplete","verb":"get","requestURI":"/healthz","user":{"username":"system:anonymous"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2019-03-01T10:00:04.000000Z","stageTimestamp":"2019-03-01T10:00:04.001000Z"}`
+
+func TestStats(t *testing.T) {
+	s := newStats(filter{User: "kubelet"})
+	if err := s.read(strings.NewReader(testLog)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Events != 4 || s.Malformed != 1 || s.Matched != 2 || s.Failed != 1 {
+		t.Errorf("expected 4 events, 1 malformed, 2 matched and 1 failed; got %d, %d, %d and %d", s.Events, s.Malformed, s.Matched, s.Failed)
+	}
+	expectedResources := map[string]int{"pods": 1, "pods/status": 1}
+	if !reflect.DeepEqual(s.matchedResources, expectedResources) {
+		t.Errorf("expected matched resources %v, got %v", expectedResources, s.matchedResources)
+	}
+	if s.resources[nonResource] != 1 {
+		t.Errorf("expected /healthz to be counted as a non-resource request, got %v", s.resources)
+	}
+	if len(s.slowest) != 2 || s.slowest[0].Latency != 100*time.Millisecond || s.slowest[1].Latency != 20*time.Millisecond {
+		t.Errorf("expected the slowest requests to be ordered by latency, got %v", s.slowest)
+	}
+}
+
+func TestHistogramAndPercentiles(t *testing.T) {
+	s := newStats(filter{})
+	s.latencies = []time.Duration{time.Millisecond, 3 * time.Millisecond, 20 * time.Millisecond, 2 * time.Minute}
+	buckets := s.histogram()
+	if len(buckets) != len(latencyBuckets)+1 {
+		t.Fatalf("expected %d buckets, got %d", len(latencyBuckets)+1, len(buckets))
+	}
+	if buckets[0].Count != 2 || buckets[0].Width != 100 || buckets[2].Count != 1 || buckets[2].Width != 50 {
+		t.Errorf("unexpected buckets: %v", buckets)
+	}
+	if last := buckets[len(buckets)-1]; last.Count != 1 || last.Label != "> 1m0s" {
+		t.Errorf("expected the overflow bucket to hold one request, got %v", last)
+	}
+	percentiles := s.percentiles()
+	if percentiles[0].Latency != 3*time.Millisecond || percentiles[3].Latency != 2*time.Minute {
+		t.Errorf("unexpected percentiles: %v", percentiles)
+	}
+}
+
+func TestTop(t *testing.T) {
+	counts := map[string]int{"get": 5, "list": 5, "watch": 1, "update": 3}
+	expected := []Count{{"get", 5}, {"list", 5}, {"update", 3}}
+	if actual := top(counts, 3); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
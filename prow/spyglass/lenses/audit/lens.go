@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit provides a Spyglass lens that summarizes Kubernetes API server audit logs.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	name     = "audit"
+	title    = "Audit Log"
+	priority = 20
+
+	// defaultMaxBytes is how much of each audit log is read if not configured.
+	defaultMaxBytes = 512 << 20
+	// slowestCount is the number of slowest requests listed.
+	slowestCount = 20
+	// topCount is the number of values listed in each breakdown.
+	topCount = 10
+	// suggestionCount is the number of values suggested for each filter.
+	suggestionCount = 100
+)
+
+// Lens is the implementation of an audit log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each audit log is read. Defaults to 512MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Breakdown lists the most common values of a field among the matching requests.
+type Breakdown struct {
+	Title string
+	// Field is the name of the filter field, so that a value can be selected.
+	Field  string
+	Counts []Count
+}
+
+type auditView struct {
+	Filter filter
+	Errors []string
+	// Truncated lists the logs that were only partially read.
+	Truncated []string
+	MaxBytes  string
+	Stats     *stats
+
+	Histogram   []Bucket
+	Percentiles []Percentile
+	Slowest     []Request
+	Breakdowns  []Breakdown
+
+	VerbSuggestions     []Count
+	ResourceSuggestions []Count
+	UserSuggestions     []Count
+}
+
+// Body summarizes the requests in the audit logs that match the filter requested by the frontend.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := auditView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	view.MaxBytes = chart.FormatBytes(float64(conf.MaxBytes))
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &view.Filter); err != nil {
+			logrus.WithError(err).Info("Failed to parse audit lens request.")
+		}
+	}
+
+	// Rotated logs sort in order of age.
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	s := newStats(view.Filter)
+	for _, a := range artifacts {
+		if size, err := a.Size(); err == nil && size > conf.MaxBytes {
+			view.Truncated = append(view.Truncated, a.JobPath())
+		}
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			err = s.read(r)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+		}
+	}
+
+	view.Stats = s
+	view.Histogram = s.histogram()
+	view.Percentiles = s.percentiles()
+	view.Slowest = s.slowest
+	view.Breakdowns = []Breakdown{
+		{Title: "Verb", Field: "verb", Counts: top(s.matchedVerbs, topCount)},
+		{Title: "Resource", Field: "resource", Counts: top(s.matchedResources, topCount)},
+		{Title: "User", Field: "user", Counts: top(s.matchedUsers, topCount)},
+	}
+	view.VerbSuggestions = top(s.verbs, suggestionCount)
+	view.ResourceSuggestions = top(s.resources, suggestionCount)
+	view.UserSuggestions = top(s.users, suggestionCount)
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+// BaselineArtifact is an artifact from the baseline run of the run being viewed: an earlier run of
+// its job, or for presubmits with a baseline job, a run of the job they would merge into.
+// Spyglass supplies these alongside the usual artifacts to lenses whose config sets Baseline.
+type BaselineArtifact struct {
+	Artifact
+	// Source is the src of the run the artifact came from.
+	Source string
+}
+
+// NewBaselineArtifact marks an artifact as coming from the baseline run with the given src.
+func NewBaselineArtifact(a Artifact, src string) *BaselineArtifact {
+	return &BaselineArtifact{Artifact: a, Source: src}
+}
+
+// SplitBaseline separates the artifacts of the run being viewed from those of the baseline run.
+func SplitBaseline(artifacts []Artifact) (current []Artifact, baseline []*BaselineArtifact) {
+	for _, a := range artifacts {
+		if b, ok := a.(*BaselineArtifact); ok {
+			baseline = append(baseline, b)
+		} else {
+			current = append(current, a)
+		}
+	}
+	return current, baseline
+}
+
+// BaselineArtifacts returns the artifacts of the baseline run among those passed to a lens, or
+// nil if Spyglass found no baseline run. Lenses diffing test results, coverage or benchmarks
+// against the baseline use it when they don't need the current run's artifacts separated out.
+func BaselineArtifacts(artifacts []Artifact) []*BaselineArtifact {
+	_, baseline := SplitBaseline(artifacts)
+	return baseline
+}
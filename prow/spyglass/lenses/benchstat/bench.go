@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchstat
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Benchmark identifies a benchmark: its name, including the GOMAXPROCS suffix, and the
+// package it is in, if known.
+type Benchmark struct {
+	Package string
+	Name    string
+}
+
+// Collection is the samples of every benchmark and unit in one or more outputs of go test -bench.
+type Collection struct {
+	// Benchmarks and Units are in the order they were first seen.
+	Benchmarks []Benchmark
+	Units      []string
+
+	samples map[Benchmark]map[string][]float64
+	units   map[string]bool
+}
+
+func newCollection() *Collection {
+	return &Collection{samples: map[Benchmark]map[string][]float64{}, units: map[string]bool{}}
+}
+
+// Samples returns the values of a unit recorded for a benchmark.
+func (c *Collection) Samples(b Benchmark, unit string) []float64 {
+	return c.samples[b][unit]
+}
+
+func (c *Collection) add(b Benchmark, unit string, value float64) {
+	byUnit, ok := c.samples[b]
+	if !ok {
+		byUnit = map[string][]float64{}
+		c.samples[b] = byUnit
+		c.Benchmarks = append(c.Benchmarks, b)
+	}
+	byUnit[unit] = append(byUnit[unit], value)
+	if !c.units[unit] {
+		c.units[unit] = true
+		c.Units = append(c.Units, unit)
+	}
+}
+
+// read adds the results in the output of go test -bench. Each result line is a sample, so
+// runs with -count record several samples of each benchmark. Lines that are not results or
+// "pkg:" configuration lines are ignored.
+func (c *Collection) read(content []byte) error {
+	pkg := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+			continue
+		}
+		if !strings.HasPrefix(line, "Benchmark") {
+			continue
+		}
+		fields := strings.Fields(line)
+		// A result has a name, an iteration count, and value and unit pairs.
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		b := Benchmark{Package: pkg, Name: strings.TrimPrefix(fields[0], "Benchmark")}
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			c.add(b, fields[i+1], value)
+		}
+	}
+	return scanner.Err()
+}
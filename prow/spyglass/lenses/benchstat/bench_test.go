@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchstat
+
+import (
+	"reflect"
+	"testing"
+)
+
+const oldOutput = `goos: linux
+goarch: amd64
+pkg: example.com/a
+BenchmarkParse-8   	  200000	      1000 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  200000	      1010 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  200000	       990 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  200000	      1005 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  200000	       995 ns/op	     512 B/op	       4 allocs/op
+BenchmarkGone-8    	    1000	   2000000 ns/op
+PASS
+ok  	example.com/a	5.000s
+`
+
+const newOutput = `pkg: example.com/a
+BenchmarkParse-8   	  100000	      2000 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  100000	      2020 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  100000	      1980 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  100000	      2010 ns/op	     512 B/op	       4 allocs/op
+BenchmarkParse-8   	  100000	      1990 ns/op	     512 B/op	       4 allocs/op
+BenchmarkRunning-8 	--- FAIL: BenchmarkRunning-8
+pkg: example.com/b
+BenchmarkCopy-8    	    5000	    300000 ns/op	  3495.25 MB/s
+`
+
+func TestRead(t *testing.T) {
+	c := newCollection()
+	if err := c.read([]byte(newOutput)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Benchmark{{Package: "example.com/a", Name: "Parse-8"}, {Package: "example.com/b", Name: "Copy-8"}}
+	if !reflect.DeepEqual(c.Benchmarks, expected) {
+		t.Errorf("expected benchmarks %v, got %v", expected, c.Benchmarks)
+	}
+	if units := []string{"ns/op", "B/op", "allocs/op", "MB/s"}; !reflect.DeepEqual(c.Units, units) {
+		t.Errorf("expected units %v, got %v", units, c.Units)
+	}
+	if samples := c.Samples(expected[0], "ns/op"); len(samples) != 5 || samples[1] != 2020 {
+		t.Errorf("expected 5 samples of Parse-8, got %v", samples)
+	}
+}
+
+func TestTables(t *testing.T) {
+	before, after := newCollection(), newCollection()
+	before.read([]byte(oldOutput))
+	after.read([]byte(newOutput))
+	result := tables(before, after, defaultAlpha)
+
+	if len(result) != 4 || result[0].Unit != "time/op" || result[1].Unit != "alloc/op" || result[3].Unit != "speed" {
+		t.Fatalf("unexpected tables: %v", result)
+	}
+	rows := result[0].Rows
+	if len(rows) != 3 || rows[0].Name != "Parse-8" || rows[1].Name != "Gone-8" || rows[2].Name != "Copy-8" {
+		t.Fatalf("expected rows for Parse-8, Gone-8 and Copy-8 grouped by package, got %v", rows)
+	}
+	parse := rows[0]
+	if !parse.PackageHeader || parse.Old != "1.00µs ± 1%" || parse.New != "2.00µs ± 1%" || parse.Delta != "+100.00%" || parse.Class != "worse" {
+		t.Errorf("unexpected comparison of Parse-8: %#v", parse)
+	}
+	if gone := rows[1]; gone.PackageHeader || gone.Old != "2.00ms ± 0%" || gone.New != "" || gone.Delta != "" {
+		t.Errorf("unexpected row for a removed benchmark: %#v", gone)
+	}
+	if allocs := result[1].Rows[0]; allocs.Delta != "~" || allocs.Class != "same" {
+		t.Errorf("expected no significant change in allocations, got %#v", allocs)
+	}
+	if copy := result[3].Rows[0]; !copy.PackageHeader || copy.New != "3.50GB/s ± 0%" || copy.Old != "" {
+		t.Errorf("unexpected row for a new benchmark: %#v", copy)
+	}
+
+	alone := tables(nil, after, defaultAlpha)
+	if len(alone) != 4 || alone[0].Rows[0].Old != "" || alone[0].Rows[0].Delta != "" || len(alone[0].Rows) != 2 {
+		t.Errorf("expected results without comparison, got %v", alone)
+	}
+}
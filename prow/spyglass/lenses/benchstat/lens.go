@@ -0,0 +1,318 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package benchstat provides a Spyglass lens that summarizes Go benchmark results and compares
+// them with those of the last passing run.
+package benchstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "benchstat"
+	title    = "Benchmarks"
+	priority = 31
+
+	// defaultAlpha is the significance level used if not configured.
+	defaultAlpha = 0.05
+)
+
+// Lens is the implementation of a benchmark-comparing Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// Alpha is the p-value below which a change is considered significant. Defaults to 0.05.
+	Alpha float64 `json:"alpha,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+		Baseline: true,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Row is a benchmark's results in one unit, compared with the baseline if there is one.
+type Row struct {
+	Benchmark
+	// PackageHeader is set on the first row of each package.
+	PackageHeader bool
+	Old           string
+	New           string
+	// Delta is the change in the mean, and Class says whether it is "better", "worse" or
+	// "same", which is also used for changes that are not significant.
+	Delta string
+	Class string
+	// P describes the p-value and sample sizes of the comparison.
+	P string
+}
+
+// Table is the results in one unit.
+type Table struct {
+	Unit string
+	Rows []Row
+}
+
+type benchView struct {
+	Errors       []string
+	Compared     bool
+	BaselineLink string
+	Alpha        float64
+	Tables       []Table
+}
+
+// Body renders a table of results for each unit, compared with the baseline if there is one.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := benchView{}
+	conf := config{Alpha: defaultAlpha}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.Alpha <= 0 || conf.Alpha >= 1 {
+		conf.Alpha = defaultAlpha
+	}
+	view.Alpha = conf.Alpha
+
+	current, baseline := lenses.SplitBaseline(artifacts)
+	sort.Slice(current, func(i, j int) bool { return current[i].JobPath() < current[j].JobPath() })
+	sort.Slice(baseline, func(i, j int) bool { return baseline[i].JobPath() < baseline[j].JobPath() })
+	newResults := newCollection()
+	for _, a := range current {
+		if err := readArtifact(newResults, a); err != nil {
+			view.Errors = append(view.Errors, err.Error())
+		}
+	}
+	var oldResults *Collection
+	if len(baseline) != 0 {
+		oldResults = newCollection()
+		for _, a := range baseline {
+			if err := readArtifact(oldResults, a); err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Baseline: %v", err))
+			}
+		}
+		view.BaselineLink = "/view/" + baseline[0].Source
+		view.Compared = len(oldResults.Benchmarks) != 0
+	}
+	view.Tables = tables(oldResults, newResults, conf.Alpha)
+	if len(view.Tables) == 0 {
+		view.Errors = append(view.Errors, "No benchmark results found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func readArtifact(c *Collection, a lenses.Artifact) error {
+	content, err := a.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", a.JobPath(), err)
+	}
+	if err := c.read(content); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", a.JobPath(), err)
+	}
+	return nil
+}
+
+// tables compares each benchmark in each unit, as benchstat does. If before is nil, the results
+// after are summarized without comparison.
+func tables(before, after *Collection, alpha float64) []Table {
+	units := after.Units
+	benchmarks := after.Benchmarks
+	if before != nil {
+		units = union(units, before.Units)
+		seen := map[Benchmark]bool{}
+		for _, b := range benchmarks {
+			seen[b] = true
+		}
+		for _, b := range before.Benchmarks {
+			if !seen[b] {
+				benchmarks = append(benchmarks, b)
+			}
+		}
+	}
+	// Keep each package's benchmarks together, in the order the packages were first seen.
+	packages := map[string]int{}
+	for _, b := range benchmarks {
+		if _, ok := packages[b.Package]; !ok {
+			packages[b.Package] = len(packages)
+		}
+	}
+	sort.SliceStable(benchmarks, func(i, j int) bool {
+		return packages[benchmarks[i].Package] < packages[benchmarks[j].Package]
+	})
+
+	var result []Table
+	for _, unit := range units {
+		table := Table{Unit: unitTitle(unit)}
+		lastPackage := ""
+		for _, b := range benchmarks {
+			newSamples := after.Samples(b, unit)
+			var oldSamples []float64
+			if before != nil {
+				oldSamples = before.Samples(b, unit)
+			}
+			if len(newSamples) == 0 && len(oldSamples) == 0 {
+				continue
+			}
+			row := Row{Benchmark: b, PackageHeader: b.Package != "" && b.Package != lastPackage}
+			lastPackage = b.Package
+			compare(&row, unit, oldSamples, newSamples, before != nil, alpha)
+			table.Rows = append(table.Rows, row)
+		}
+		if len(table.Rows) != 0 {
+			result = append(result, table)
+		}
+	}
+	return result
+}
+
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, s := range append(append([]string(nil), a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// compare fills in a row from the samples of a benchmark before and after.
+func compare(row *Row, unit string, oldSamples, newSamples []float64, withBaseline bool, alpha float64) {
+	row.Class = "same"
+	var before, after Metrics
+	if len(newSamples) != 0 {
+		after = newMetrics(newSamples)
+		row.New = formatMetrics(after, unit)
+	}
+	if !withBaseline {
+		return
+	}
+	if len(oldSamples) != 0 {
+		before = newMetrics(oldSamples)
+		row.Old = formatMetrics(before, unit)
+	}
+	if len(oldSamples) == 0 || len(newSamples) == 0 || before.Mean == 0 {
+		return
+	}
+	p := mannWhitneyU(before.Values, after.Values)
+	row.P = fmt.Sprintf("p=%.3f n=%d+%d", p, len(before.Values), len(after.Values))
+	if p >= alpha {
+		row.Delta = "~"
+		return
+	}
+	delta := (after.Mean - before.Mean) / math.Abs(before.Mean)
+	row.Delta = fmt.Sprintf("%+.2f%%", 100*delta)
+	// Throughput is better when higher, everything else when lower.
+	if higherIsBetter(unit) == (delta > 0) {
+		row.Class = "better"
+	} else if delta != 0 {
+		row.Class = "worse"
+	}
+}
+
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+// unitTitle names the table for a unit as benchstat does.
+func unitTitle(unit string) string {
+	switch unit {
+	case "ns/op":
+		return "time/op"
+	case "B/op":
+		return "alloc/op"
+	case "MB/s":
+		return "speed"
+	}
+	return unit
+}
+
+// formatMetrics formats the mean and variation of a benchmark, such as "1.23ms ± 2%".
+func formatMetrics(m Metrics, unit string) string {
+	return fmt.Sprintf("%s ± %.0f%%", formatValue(m.Mean, unit), 100*m.Variation)
+}
+
+// formatValue scales a value to a suitable prefix of its unit.
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "ns/op":
+		return scale(v, 1000, []string{"ns", "µs", "ms", "s"})
+	case "B/op":
+		return scale(v, 1000, []string{"B", "kB", "MB", "GB", "TB"})
+	case "MB/s":
+		return scale(v, 1000, []string{"MB/s", "GB/s", "TB/s"})
+	}
+	return scale(v, 1000, []string{"", "k", "M", "G", "T"})
+}
+
+func scale(v, factor float64, suffixes []string) string {
+	i := 0
+	for math.Abs(v) >= factor && i < len(suffixes)-1 {
+		v /= factor
+		i++
+	}
+	switch a := math.Abs(v); {
+	case a >= 100 || v == 0:
+		return fmt.Sprintf("%.0f%s", v, suffixes[i])
+	case a >= 10:
+		return fmt.Sprintf("%.1f%s", v, suffixes[i])
+	}
+	return fmt.Sprintf("%.2f%s", v, suffixes[i])
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchstat
+
+import (
+	"math"
+	"sort"
+)
+
+// maxExactSamples is the largest sample size for which the exact distribution of the
+// Mann-Whitney U statistic is computed. Larger samples use the normal approximation.
+const maxExactSamples = 20
+
+// Metrics summarizes the samples of a benchmark in one unit, as benchstat does.
+type Metrics struct {
+	// Values are the samples with outliers removed.
+	Values []float64
+	Mean   float64
+	// Variation is the largest deviation of a value from the mean, as a fraction of the mean.
+	Variation float64
+}
+
+// newMetrics discards samples more than 1.5 times the interquartile range outside the
+// quartiles, then summarizes the rest.
+func newMetrics(samples []float64) Metrics {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+	lo, hi := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	var m Metrics
+	for _, v := range sorted {
+		if v >= lo && v <= hi {
+			m.Values = append(m.Values, v)
+		}
+	}
+	if len(m.Values) == 0 {
+		return m
+	}
+	sum := 0.0
+	for _, v := range m.Values {
+		sum += v
+	}
+	m.Mean = sum / float64(len(m.Values))
+	if m.Mean != 0 {
+		spread := math.Max(m.Values[len(m.Values)-1]-m.Mean, m.Mean-m.Values[0])
+		m.Variation = spread / math.Abs(m.Mean)
+	}
+	return m
+}
+
+// quantile interpolates the q quantile of sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test that xs and ys are
+// drawn from the same distribution.
+func mannWhitneyU(xs, ys []float64) float64 {
+	n, m := len(xs), len(ys)
+	if n == 0 || m == 0 {
+		return 1
+	}
+	// Rank the combined samples, giving tied values the mean of their ranks.
+	type sample struct {
+		value float64
+		x     bool
+	}
+	all := make([]sample, 0, n+m)
+	for _, v := range xs {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range ys {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+	rankSum := 0.0
+	ties := false
+	tieCorrection := 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieCorrection += t*t*t - t
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].x {
+				rankSum += rank
+			}
+		}
+		i = j
+	}
+	u := rankSum - float64(n*(n+1))/2
+
+	if !ties && n <= maxExactSamples && m <= maxExactSamples {
+		lower := exactUCDF(n, m, u)
+		upper := 1 - exactUCDF(n, m, u-1)
+		return math.Min(1, 2*math.Min(lower, upper))
+	}
+	total := float64(n + m)
+	mean := float64(n*m) / 2
+	variance := float64(n*m) / 12 * (total + 1 - tieCorrection/(total*(total-1)))
+	if variance <= 0 {
+		return 1
+	}
+	// Apply a continuity correction towards the mean.
+	z := math.Max(math.Abs(u-mean)-0.5, 0) / math.Sqrt(variance)
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactUCDF returns the probability that U is at most u for samples of sizes n and m without
+// ties, by counting the orderings of the samples that give each U.
+func exactUCDF(n, m int, u float64) float64 {
+	if u < 0 {
+		return 0
+	}
+	max := n * m
+	// counts[i][j][k] is the number of orderings of i xs and j ys for which U is k. The largest
+	// value is either an x, which exceeds all j ys, or a y, which exceeds no xs.
+	counts := make([][][]float64, n+1)
+	for i := range counts {
+		counts[i] = make([][]float64, m+1)
+		for j := range counts[i] {
+			counts[i][j] = make([]float64, i*j+1)
+			if i == 0 || j == 0 {
+				counts[i][j][0] = 1
+				continue
+			}
+			for k := range counts[i][j] {
+				if k-j >= 0 && k-j <= (i-1)*j {
+					counts[i][j][k] += counts[i-1][j][k-j]
+				}
+				if k <= i*(j-1) {
+					counts[i][j][k] += counts[i][j-1][k]
+				}
+			}
+		}
+	}
+	cumulative, total := 0.0, 0.0
+	for k, c := range counts[n][m] {
+		total += c
+		if float64(k) <= u {
+			cumulative += c
+		}
+	}
+	if u >= float64(max) {
+		return 1
+	}
+	return cumulative / total
+}
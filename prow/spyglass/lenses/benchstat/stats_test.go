@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchstat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewMetrics(t *testing.T) {
+	m := newMetrics([]float64{10, 11, 9, 10, 50})
+	if len(m.Values) != 4 || m.Mean != 10 || m.Variation != 0.1 {
+		t.Errorf("expected the outlier to be dropped, leaving a mean of 10 ± 10%%, got %#v", m)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	testCases := []struct {
+		name     string
+		xs, ys   []float64
+		expected float64
+	}{
+		{
+			name:     "separated samples",
+			xs:       []float64{1, 2, 3, 4, 5},
+			ys:       []float64{6, 7, 8, 9, 10},
+			expected: 2.0 / 252,
+		},
+		{
+			name:     "reversed",
+			xs:       []float64{6, 7, 8, 9, 10},
+			ys:       []float64{1, 2, 3, 4, 5},
+			expected: 2.0 / 252,
+		},
+		{
+			name:     "single samples",
+			xs:       []float64{1},
+			ys:       []float64{2},
+			expected: 1,
+		},
+		{
+			name:     "identical",
+			xs:       []float64{4, 4, 4},
+			ys:       []float64{4, 4, 4},
+			expected: 1,
+		},
+		{
+			// Ties use the normal approximation: U is 0, with mean 8 and a variance corrected
+			// for two pairs of ties.
+			name:     "ties",
+			xs:       []float64{1, 1, 2, 3},
+			ys:       []float64{5, 5, 6, 7},
+			expected: math.Erfc((8 - 0.5) / math.Sqrt(16.0/12*(9-12.0/(8*7))) / math.Sqrt2),
+		},
+	}
+	for _, tc := range testCases {
+		if p := mannWhitneyU(tc.xs, tc.ys); math.Abs(p-tc.expected) > 1e-9 {
+			t.Errorf("%s: expected p=%g, got %g", tc.name, tc.expected, p)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// file is an output file referenced by an event.
+type file struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// jsonInt is an int64, which the JSON form of protobuf messages encodes as a string.
+type jsonInt int64
+
+func (i *jsonInt) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt(v)
+	return nil
+}
+
+func millis(ms jsonInt) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// event is the subset of a build_event_stream.BuildEvent used by the lens, in the form written
+// by --build_event_json_file.
+type event struct {
+	ID struct {
+		TargetCompleted *struct {
+			Label string `json:"label"`
+		} `json:"targetCompleted"`
+		TestSummary *struct {
+			Label string `json:"label"`
+		} `json:"testSummary"`
+		TestResult *struct {
+			Label   string `json:"label"`
+			Run     int    `json:"run"`
+			Shard   int    `json:"shard"`
+			Attempt int    `json:"attempt"`
+		} `json:"testResult"`
+		ActionCompleted *struct {
+			Label         string `json:"label"`
+			PrimaryOutput string `json:"primaryOutput"`
+		} `json:"actionCompleted"`
+	} `json:"id"`
+
+	Started *struct {
+		Command         string  `json:"command"`
+		StartTimeMillis jsonInt `json:"startTimeMillis"`
+	} `json:"started"`
+	Completed *struct {
+		Success bool `json:"success"`
+	} `json:"completed"`
+	Aborted *struct {
+		Reason      string `json:"reason"`
+		Description string `json:"description"`
+	} `json:"aborted"`
+	TestSummary *struct {
+		OverallStatus          string  `json:"overallStatus"`
+		TotalRunCount          int     `json:"totalRunCount"`
+		TotalRunDurationMillis jsonInt `json:"totalRunDurationMillis"`
+	} `json:"testSummary"`
+	TestResult *struct {
+		Status           string `json:"status"`
+		CachedLocally    bool   `json:"cachedLocally"`
+		TestActionOutput []file `json:"testActionOutput"`
+		ExecutionInfo    struct {
+			CachedRemotely bool `json:"cachedRemotely"`
+		} `json:"executionInfo"`
+	} `json:"testResult"`
+	Action *struct {
+		Success   bool      `json:"success"`
+		Type      string    `json:"type"`
+		ExitCode  int       `json:"exitCode"`
+		Stderr    *file     `json:"stderr"`
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+	} `json:"action"`
+	BuildMetrics *struct {
+		ActionSummary struct {
+			ActionsCreated  jsonInt `json:"actionsCreated"`
+			ActionsExecuted jsonInt `json:"actionsExecuted"`
+		} `json:"actionSummary"`
+	} `json:"buildMetrics"`
+	Finished *struct {
+		OverallSuccess bool `json:"overallSuccess"`
+		ExitCode       *struct {
+			Name string `json:"name"`
+			Code int    `json:"code"`
+		} `json:"exitCode"`
+		FinishTimeMillis jsonInt `json:"finishTimeMillis"`
+	} `json:"finished"`
+}
+
+// Target is the outcome of building a target.
+type Target struct {
+	Label   string
+	Success bool
+	// Aborted is the reason the target was not built, if it was not.
+	Aborted string
+}
+
+// Test is the summary of a test target.
+type Test struct {
+	Label    string
+	Status   string
+	Runs     int
+	Duration time.Duration
+	// Cached is true if every attempt was cached.
+	Cached bool
+	// Logs are the test.log of each attempt. Those of attempts that did not pass are named for the attempt.
+	Logs []file
+
+	attempts, cachedAttempts int
+}
+
+// Failed returns whether the test did not pass.
+func (t *Test) Failed() bool {
+	return t.Status != "PASSED" && t.Status != "FLAKY" && t.Status != "NO_STATUS"
+}
+
+// Action is an action reported in the stream. Bazel reports only failed actions unless run with
+// --build_event_publish_all_actions.
+type Action struct {
+	Label    string
+	Output   string
+	Mnemonic string
+	Success  bool
+	ExitCode int
+	Duration time.Duration
+	Stderr   *file
+}
+
+// Build is the reconstructed state of a build event stream.
+type Build struct {
+	Command  string
+	Started  time.Time
+	Finished time.Time
+	// Success is nil if the build did not finish.
+	Success  *bool
+	ExitCode string
+
+	Targets []*Target
+	Tests   []*Test
+	Actions []*Action
+
+	ActionsCreated  int64
+	ActionsExecuted int64
+	// TestAttempts and CachedTestAttempts count every attempt of every test.
+	TestAttempts       int
+	CachedTestAttempts int
+
+	tests map[string]*Test
+	// Malformed is the number of lines that could not be parsed.
+	Malformed int
+}
+
+func newBuild() *Build {
+	return &Build{tests: map[string]*Test{}}
+}
+
+func (b *Build) test(label string) *Test {
+	if t, ok := b.tests[label]; ok {
+		return t
+	}
+	t := &Test{Label: label, Status: "NO_STATUS"}
+	b.tests[label] = t
+	b.Tests = append(b.Tests, t)
+	return t
+}
+
+// add reads a JSON build event stream, one event per line.
+func (b *Build) add(content []byte) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var e event
+		if err := decoder.Decode(&e); err != nil {
+			// The rest of the stream cannot be resynchronized.
+			b.Malformed++
+			return
+		}
+		b.addEvent(&e)
+	}
+}
+
+func (b *Build) addEvent(e *event) {
+	switch {
+	case e.Started != nil:
+		b.Command = e.Started.Command
+		b.Started = time.Unix(0, int64(millis(e.Started.StartTimeMillis)))
+	case e.Finished != nil:
+		success := e.Finished.OverallSuccess
+		b.Success = &success
+		b.Finished = time.Unix(0, int64(millis(e.Finished.FinishTimeMillis)))
+		if e.Finished.ExitCode != nil {
+			b.ExitCode = fmt.Sprintf("%s (%d)", e.Finished.ExitCode.Name, e.Finished.ExitCode.Code)
+		}
+	case e.BuildMetrics != nil:
+		b.ActionsCreated = int64(e.BuildMetrics.ActionSummary.ActionsCreated)
+		b.ActionsExecuted = int64(e.BuildMetrics.ActionSummary.ActionsExecuted)
+	case e.ID.TargetCompleted != nil:
+		t := &Target{Label: e.ID.TargetCompleted.Label}
+		if e.Completed != nil {
+			t.Success = e.Completed.Success
+		} else if e.Aborted != nil {
+			t.Aborted = e.Aborted.Description
+			if t.Aborted == "" {
+				t.Aborted = e.Aborted.Reason
+			}
+		}
+		b.Targets = append(b.Targets, t)
+	case e.ID.TestSummary != nil && e.TestSummary != nil:
+		t := b.test(e.ID.TestSummary.Label)
+		t.Status = e.TestSummary.OverallStatus
+		t.Runs = e.TestSummary.TotalRunCount
+		t.Duration = millis(e.TestSummary.TotalRunDurationMillis)
+	case e.ID.TestResult != nil && e.TestResult != nil:
+		t := b.test(e.ID.TestResult.Label)
+		t.attempts++
+		b.TestAttempts++
+		if e.TestResult.CachedLocally || e.TestResult.ExecutionInfo.CachedRemotely {
+			t.cachedAttempts++
+			b.CachedTestAttempts++
+		}
+		t.Cached = t.attempts == t.cachedAttempts
+		for _, f := range e.TestResult.TestActionOutput {
+			if f.Name == "test.log" {
+				if e.TestResult.Status != "PASSED" {
+					f.Name = fmt.Sprintf("run %d shard %d attempt %d", e.ID.TestResult.Run, e.ID.TestResult.Shard, e.ID.TestResult.Attempt)
+				}
+				t.Logs = append(t.Logs, f)
+			}
+		}
+	case e.ID.ActionCompleted != nil && e.Action != nil:
+		a := &Action{
+			Label:    e.ID.ActionCompleted.Label,
+			Output:   e.ID.ActionCompleted.PrimaryOutput,
+			Mnemonic: e.Action.Type,
+			Success:  e.Action.Success,
+			ExitCode: e.Action.ExitCode,
+			Stderr:   e.Action.Stderr,
+		}
+		if !e.Action.StartTime.IsZero() && !e.Action.EndTime.IsZero() {
+			a.Duration = e.Action.EndTime.Sub(e.Action.StartTime)
+		}
+		b.Actions = append(b.Actions, a)
+	}
+}
+
+// finish orders the tests with failures first.
+func (b *Build) finish() {
+	sort.SliceStable(b.Tests, func(i, j int) bool {
+		if fi, fj := b.Tests[i].Failed(), b.Tests[j].Failed(); fi != fj {
+			return fi
+		}
+		return b.Tests[i].Label < b.Tests[j].Label
+	})
+}
+
+// slowestActions returns the n longest actions that have a duration.
+func (b *Build) slowestActions(n int) []*Action {
+	var timed []*Action
+	for _, a := range b.Actions {
+		if a.Duration > 0 {
+			timed = append(timed, a)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].Duration > timed[j].Duration })
+	if len(timed) > n {
+		timed = timed[:n]
+	}
+	return timed
+}
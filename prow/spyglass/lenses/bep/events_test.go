@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bep
+
+import (
+	"testing"
+	"time"
+)
+
+// testStream is an abridged stream from `bazel test --build_event_json_file`, in which
+// //pkg:flaky_test failed and was retried and //pkg:lib failed to build.
+const testStream = `{"id":{"started":{"uuid":"x"}},"started":{"uuid":"x","startTimeMillis":"1546300800000","command":"test"}}
+{"id":{"targetCompleted":{"label":"//pkg:lib"}},"aborted":{"reason":"ANALYSIS_FAILURE","description":"missing dependency"}}
+{"id":{"actionCompleted":{"primaryOutput":"bazel-out/k8-fastbuild/bin/pkg/gen.go","label":"//pkg:gen"}},"action":{"type":"Genrule","exitCode":1,"stderr":{"name":"stderr","uri":"bytestream://cache/blobs/abc/12"}}}
+{"id":{"actionCompleted":{"primaryOutput":"bazel-out/k8-fastbuild/bin/pkg/go_test","label":"//pkg:go_test"}},"action":{"success":true,"type":"GoLink","startTime":"2019-01-01T00:00:01Z","endTime":"2019-01-01T00:00:05Z"}}
+{"id":{"targetCompleted":{"label":"//pkg:go_test"}},"completed":{"success":true}}
+{"id":{"testResult":{"label":"//pkg:go_test","run":1,"shard":1,"attempt":1}},"testResult":{"status":"PASSED","cachedLocally":true,"testActionOutput":[{"name":"test.log","uri":"file:///home/prow/.cache/bazel/execroot/io_k8s_test_infra/bazel-out/k8-fastbuild/testlogs/pkg/go_test/test.log"}]}}
+{"id":{"testSummary":{"label":"//pkg:go_test"}},"testSummary":{"overallStatus":"PASSED","totalRunCount":1,"totalRunDurationMillis":"1500"}}
+{"id":{"targetCompleted":{"label":"//pkg:flaky_test"}},"completed":{"success":true}}
+{"id":{"testResult":{"label":"//pkg:flaky_test","run":1,"shard":1,"attempt":1}},"testResult":{"status":"FAILED","testActionOutput":[{"name":"test.log","uri":"file:///tmp/bazel-testlogs/pkg/flaky_test/test_attempts/attempt_1.log"}]}}
+{"id":{"testResult":{"label":"//pkg:flaky_test","run":1,"shard":1,"attempt":2}},"testResult":{"status":"FAILED","executionInfo":{"cachedRemotely":true},"testActionOutput":[{"name":"test.log","uri":"file:///tmp/bazel-testlogs/pkg/flaky_test/test.log"}]}}
+{"id":{"testSummary":{"label":"//pkg:flaky_test"}},"testSummary":{"overallStatus":"FAILED","totalRunCount":2,"totalRunDurationMillis":"62000"}}
+{"id":{"buildMetrics":{}},"buildMetrics":{"actionSummary":{"actionsCreated":"200","actionsExecuted":"50"}}}
+{"id":{"buildFinished":{}},"finished":{"exitCode":{"name":"BUILD_FAILURE","code":1},"finishTimeMillis":"1546300920000"}}
+`
+
+func TestBuild(t *testing.T) {
+	b := newBuild()
+	b.add([]byte(testStream))
+	b.finish()
+
+	if b.Command != "test" || b.Success == nil || *b.Success || b.ExitCode != "BUILD_FAILURE (1)" {
+		t.Errorf("expected a failed test command, got %q, %v, %q", b.Command, b.Success, b.ExitCode)
+	}
+	if d := b.Finished.Sub(b.Started); d != 2*time.Minute {
+		t.Errorf("expected the build to take 2m, got %s", d)
+	}
+	if len(b.Targets) != 3 || b.Targets[0].Success || b.Targets[0].Aborted != "missing dependency" {
+		t.Errorf("expected //pkg:lib to be aborted, got %+v", b.Targets)
+	}
+	if len(b.Tests) != 2 || b.Tests[0].Label != "//pkg:flaky_test" || !b.Tests[0].Failed() || b.Tests[1].Failed() {
+		t.Fatalf("expected the failed test first, got %+v", b.Tests)
+	}
+	flaky := b.Tests[0]
+	if flaky.Runs != 2 || flaky.Duration != 62*time.Second || flaky.Cached || len(flaky.Logs) != 2 {
+		t.Errorf("unexpected failed test: %+v", flaky)
+	}
+	if !b.Tests[1].Cached || b.TestAttempts != 3 || b.CachedTestAttempts != 2 {
+		t.Errorf("expected 2 of 3 attempts to be cached, got %d of %d", b.CachedTestAttempts, b.TestAttempts)
+	}
+	if b.ActionsCreated != 200 || b.ActionsExecuted != 50 {
+		t.Errorf("expected 50 of 200 actions executed, got %d of %d", b.ActionsExecuted, b.ActionsCreated)
+	}
+	slowest := b.slowestActions(10)
+	if len(slowest) != 1 || slowest[0].Mnemonic != "GoLink" || slowest[0].Duration != 4*time.Second {
+		t.Errorf("expected only the timed GoLink action, got %+v", slowest)
+	}
+	if b.Malformed != 0 {
+		t.Errorf("expected no malformed events, got %d", b.Malformed)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	b := newBuild()
+	b.add([]byte(`{"id":{"started":{}},"started":{"command":"build"}}
+not json
+{"id":{"buildFinished":{}},"finished":{"overallSuccess":true}}
+`))
+	if b.Command != "build" || b.Success != nil || b.Malformed != 1 {
+		t.Errorf("expected to stop at the malformed event, got %q, %v, %d", b.Command, b.Success, b.Malformed)
+	}
+}
+
+func TestLinker(t *testing.T) {
+	link := linker("https://gcsweb.example.com/bucket/logs/job/1/", "artifacts/bazel-testlogs")
+	testCases := []struct {
+		uri, url string
+	}{
+		{
+			uri: "file:///root/.cache/bazel/execroot/ws/bazel-out/k8-fastbuild/testlogs/pkg/go_test/test.log",
+			url: "https://gcsweb.example.com/bucket/logs/job/1/artifacts/bazel-testlogs/pkg/go_test/test.log",
+		},
+		{
+			uri: "file:///src/bazel-testlogs/pkg/go_test/test.log",
+			url: "https://gcsweb.example.com/bucket/logs/job/1/artifacts/bazel-testlogs/pkg/go_test/test.log",
+		},
+		{
+			uri: "https://results.example.com/invocations/x/test.log",
+			url: "https://results.example.com/invocations/x/test.log",
+		},
+		{
+			uri: "bytestream://cache/blobs/abc/12",
+		},
+	}
+	for _, tc := range testCases {
+		if l := link("test.log", tc.uri); l.URL != tc.url {
+			t.Errorf("%s: expected %q, got %q", tc.uri, tc.url, l.URL)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bep provides a Spyglass lens that renders Bazel build event streams.
+package bep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "bep"
+	title    = "Build Events"
+	priority = 23
+
+	// slowestCount is the number of slowest actions listed.
+	slowestCount = 20
+	// defaultTestLogsDir is where jobs are assumed to upload bazel-testlogs.
+	defaultTestLogsDir = "artifacts/bazel-testlogs"
+)
+
+// Lens is the implementation of a Bazel build event stream-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// TestLogsDir is where the job uploads the contents of bazel-testlogs, relative to the
+	// job's directory. Defaults to artifacts/bazel-testlogs.
+	TestLogsDir string `json:"test_logs_dir,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Link is a file referenced by the build, linked to its uploaded copy if there is one.
+type Link struct {
+	Name string
+	URI  string
+	URL  string
+}
+
+// TestView is a test with links to its logs.
+type TestView struct {
+	*Test
+	Links []Link
+}
+
+// ActionView is an action with a link to its stderr.
+type ActionView struct {
+	*Action
+	Stderr *Link
+}
+
+// BuildView summarizes one build event stream.
+type BuildView struct {
+	Name  string
+	Error string
+	Build *Build
+	// Finished is whether the build reported its outcome, and Succeeded whether it passed.
+	Finished, Succeeded bool
+	Duration            time.Duration
+	// Built is the number of targets built successfully.
+	Built         int
+	FailedTargets []*Target
+	FailedTests   []TestView
+	PassedTests   []TestView
+	Slowest       []*Action
+	FailedActions []ActionView
+	// ActionCacheHitRate and TestCacheHitRate are empty if unknown.
+	ActionCacheHitRate string
+	TestCacheHitRate   string
+}
+
+type bepView struct {
+	Error  string
+	Builds []BuildView
+}
+
+// Body renders a summary of each build event stream.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := bepView{}
+	conf := config{TestLogsDir: defaultTestLogsDir}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+		}
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		bv := BuildView{Name: a.JobPath()}
+		content, err := a.ReadAll()
+		if err != nil {
+			bv.Error = fmt.Sprintf("Failed to read: %v", err)
+			view.Builds = append(view.Builds, bv)
+			continue
+		}
+		b := newBuild()
+		b.add(content)
+		b.finish()
+		// Test logs are found relative to the job's directory, which contains the stream.
+		jobURL := ""
+		if link := a.CanonicalLink(); strings.HasSuffix(link, a.JobPath()) {
+			jobURL = strings.TrimSuffix(link, a.JobPath())
+		}
+		view.Builds = append(view.Builds, summarize(b, bv, linker(jobURL, conf.TestLogsDir)))
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func summarize(b *Build, bv BuildView, link func(name, uri string) Link) BuildView {
+	bv.Build = b
+	if b.Success != nil {
+		bv.Finished, bv.Succeeded = true, *b.Success
+		if !b.Started.IsZero() {
+			bv.Duration = b.Finished.Sub(b.Started).Round(time.Second)
+		}
+	}
+	for _, t := range b.Targets {
+		if t.Success {
+			bv.Built++
+		} else {
+			bv.FailedTargets = append(bv.FailedTargets, t)
+		}
+	}
+	for _, t := range b.Tests {
+		tv := TestView{Test: t}
+		for _, l := range t.Logs {
+			tv.Links = append(tv.Links, link(l.Name, l.URI))
+		}
+		if t.Failed() {
+			bv.FailedTests = append(bv.FailedTests, tv)
+		} else {
+			bv.PassedTests = append(bv.PassedTests, tv)
+		}
+	}
+	for _, a := range b.Actions {
+		if a.Success {
+			continue
+		}
+		av := ActionView{Action: a}
+		if a.Stderr != nil {
+			l := link("stderr", a.Stderr.URI)
+			av.Stderr = &l
+		}
+		bv.FailedActions = append(bv.FailedActions, av)
+	}
+	bv.Slowest = b.slowestActions(slowestCount)
+	if b.ActionsCreated > 0 {
+		bv.ActionCacheHitRate = fmt.Sprintf("%.0f%%", 100*float64(b.ActionsCreated-b.ActionsExecuted)/float64(b.ActionsCreated))
+	}
+	if b.TestAttempts > 0 {
+		bv.TestCacheHitRate = fmt.Sprintf("%.0f%%", 100*float64(b.CachedTestAttempts)/float64(b.TestAttempts))
+	}
+	return bv
+}
+
+// linker returns a function that links files referenced by the build to their uploaded copies.
+// Files under bazel-testlogs are assumed to have been uploaded to testLogsDir.
+func linker(jobURL, testLogsDir string) func(name, uri string) Link {
+	return func(name, uri string) Link {
+		l := Link{Name: name, URI: uri}
+		if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+			l.URL = uri
+			return l
+		}
+		if jobURL == "" || !strings.HasPrefix(uri, "file://") {
+			return l
+		}
+		for _, marker := range []string{"/bazel-testlogs/", "/testlogs/"} {
+			if i := strings.Index(uri, marker); i >= 0 {
+				l.URL = jobURL + path.Join(testLogsDir, uri[i+len(marker):])
+				return l
+			}
+		}
+		return l
+	}
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Messages logged by the boskos client in boskos/client when it acquires and releases resources.
+const (
+	msgAcquired      = "Acquired boskos resource"
+	msgReleased      = "Released boskos resource"
+	msgReleaseFailed = "Failed to release boskos resource"
+)
+
+// Statuses of leases.
+const (
+	statusReleased      = "released"
+	statusReleaseFailed = "release failed"
+	statusHeld          = "not released"
+)
+
+var (
+	// fieldRE matches a field of a line in logrus's text format, such as `msg="Acquired boskos
+	// resource"` or `state=busy`.
+	fieldRE = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\S*)`)
+	// logTimeRE matches the timestamp the standard library's log package prefixes lines with.
+	logTimeRE = regexp.MustCompile(`^(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d)\s`)
+	// updateFailedRE matches kubetest's report of a failed heartbeat for its project.
+	updateFailedRE = regexp.MustCompile(`\[Boskos\] Update of (\S+) failed with (.*)$`)
+	// releaseAllFailedRE matches kubetest's report that releasing its resources failed.
+	releaseAllFailedRE = regexp.MustCompile(`\[Boskos\] Fail To Release: (.*?)(?:, kubetest err: .*)?$`)
+)
+
+// Lease is a resource leased from boskos by the job.
+type Lease struct {
+	Name  string
+	Type  string
+	Owner string
+	// State is the state the resource was moved to when it was acquired, usually "busy".
+	State    string
+	Acquired time.Time
+	// Log and Line are the log and line the resource was acquired on.
+	Log  string
+	Line int
+	// Released is when the resource was released, if it was.
+	Released time.Time
+	// ReleasedTo is the state the resource was released to, usually "dirty".
+	ReleasedTo   string
+	ReleaseError string
+	// UpdateFailures is the number of heartbeats for the resource that failed.
+	UpdateFailures  int
+	LastUpdateError string
+
+	released bool
+}
+
+// Status returns whether the lease was released.
+func (l *Lease) Status() string {
+	switch {
+	case l.released:
+		return statusReleased
+	case l.ReleaseError != "":
+		return statusReleaseFailed
+	default:
+		return statusHeld
+	}
+}
+
+// record is a line of a log about a lease, in logrus's JSON or text format.
+type record struct {
+	Time     string `json:"time"`
+	Msg      string `json:"msg"`
+	Resource string `json:"resource"`
+	Type     string `json:"type"`
+	State    string `json:"state"`
+	Owner    string `json:"owner"`
+	Error    string `json:"error"`
+}
+
+// parseRecord parses a line in logrus's JSON or text format, returning nil if it is neither.
+func parseRecord(line string) *record {
+	if strings.HasPrefix(line, "{") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return nil
+		}
+		return &r
+	}
+	if !strings.Contains(line, "msg=") {
+		return nil
+	}
+	r := &record{}
+	for _, m := range fieldRE.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		switch m[1] {
+		case "time":
+			r.Time = value
+		case "msg":
+			r.Msg = value
+		case "resource":
+			r.Resource = value
+		case "type":
+			r.Type = value
+		case "state":
+			r.State = value
+		case "owner":
+			r.Owner = value
+		case "error":
+			r.Error = value
+		}
+	}
+	return r
+}
+
+// Leases is the resources leased by a job, in the order they were acquired.
+type Leases struct {
+	Leases []*Lease
+	// End is the time of the last line of the logs with a time, which leases that were not
+	// released are held until.
+	End time.Time
+
+	current map[string]*Lease
+}
+
+func newLeases() *Leases {
+	return &Leases{current: map[string]*Lease{}}
+}
+
+// lease returns the current lease of a resource, adding one if it was not acquired in the logs.
+func (l *Leases) lease(name string) *Lease {
+	lease, ok := l.current[name]
+	if !ok {
+		lease = &Lease{Name: name}
+		l.current[name] = lease
+		l.Leases = append(l.Leases, lease)
+	}
+	return lease
+}
+
+// read reads the records of leases from the log named source.
+func (l *Leases) read(r io.Reader, source string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		var t time.Time
+		if m := logTimeRE.FindStringSubmatch(line); m != nil {
+			t, _ = time.Parse("2006/01/02 15:04:05", m[1])
+		}
+		if m := updateFailedRE.FindStringSubmatch(line); m != nil {
+			lease := l.lease(m[1])
+			lease.UpdateFailures++
+			lease.LastUpdateError = m[2]
+			l.observe(t)
+			continue
+		}
+		if m := releaseAllFailedRE.FindStringSubmatch(line); m != nil {
+			for _, lease := range l.Leases {
+				if !lease.released && lease.ReleaseError == "" {
+					lease.ReleaseError = m[1]
+				}
+			}
+			l.observe(t)
+			continue
+		}
+		rec := parseRecord(line)
+		if rec == nil {
+			l.observe(t)
+			continue
+		}
+		if rec.Time != "" {
+			t, _ = time.Parse(time.RFC3339Nano, rec.Time)
+		}
+		l.observe(t)
+		if rec.Resource == "" {
+			continue
+		}
+		switch rec.Msg {
+		case msgAcquired:
+			if l.seen(rec.Resource, t, false) {
+				continue
+			}
+			// Acquiring a resource again starts a new lease.
+			delete(l.current, rec.Resource)
+			lease := l.lease(rec.Resource)
+			lease.Type = rec.Type
+			lease.Owner = rec.Owner
+			lease.State = rec.State
+			lease.Acquired = t
+			lease.Log = source
+			lease.Line = lineNumber
+		case msgReleased:
+			if l.seen(rec.Resource, t, true) {
+				continue
+			}
+			lease := l.lease(rec.Resource)
+			lease.released = true
+			lease.Released = t
+			lease.ReleasedTo = rec.State
+			lease.ReleaseError = ""
+			delete(l.current, rec.Resource)
+		case msgReleaseFailed:
+			lease := l.lease(rec.Resource)
+			lease.ReleasedTo = rec.State
+			lease.ReleaseError = rec.Error
+		}
+	}
+	return scanner.Err()
+}
+
+// seen returns whether a resource was already acquired or released at t, as happens when both
+// the job's log and a dedicated artifact record its leases. Records without times are never seen.
+func (l *Leases) seen(name string, t time.Time, released bool) bool {
+	if t.IsZero() {
+		return false
+	}
+	for _, lease := range l.Leases {
+		if lease.Name != name {
+			continue
+		}
+		if released && lease.released && lease.Released.Equal(t) || !released && lease.Acquired.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// observe advances the end of the logs to t.
+func (l *Leases) observe(t time.Time) {
+	if t.After(l.End) {
+		l.End = t
+	}
+}
+
+// Duration returns how long a resource was leased for, or 0 if it is unknown. Leases that were
+// not released are held until the end of the logs.
+func (l *Leases) Duration(lease *Lease) time.Duration {
+	if lease.Acquired.IsZero() {
+		return 0
+	}
+	end := lease.Released
+	if !lease.released {
+		end = l.End
+	}
+	if end.Before(lease.Acquired) {
+		return 0
+	}
+	return end.Sub(lease.Acquired)
+}
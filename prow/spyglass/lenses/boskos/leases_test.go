@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boskos
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `2019/10/01 12:00:00 main.go:726: provider gce, will acquire project type gce-project from boskos
+time="2019-10-01T12:00:01Z" level=info msg="Acquired boskos resource" owner=ci-kubernetes-e2e resource=gce-project-1 state=busy type=gce-project
+time="2019-10-01T12:00:02Z" level=info msg="Acquired boskos resource" owner=ci-kubernetes-e2e resource=gke-cluster-2 state=busy type=gke-cluster
+2019/10/01 12:05:02 main.go:741: [Boskos] Update of gce-project-1 failed with status 500 Internal Server Error, status code 500
+time="2019-10-01T12:30:01Z" level=info msg="Released boskos resource" owner=ci-kubernetes-e2e resource=gce-project-1 state=dirty
+time="2019-10-01T12:30:02Z" level=warning msg="Failed to release boskos resource" error="status 401 Unauthorized, statusCode 401 releasing gke-cluster-2" owner=ci-kubernetes-e2e resource=gke-cluster-2 state=dirty
+2019/10/01 12:30:03 main.go:302: [Boskos] Fail To Release: status 401 Unauthorized, kubetest err: <nil>
+`
+
+const testArtifact = `{"level":"info","msg":"Acquired boskos resource","owner":"ci-kubernetes-e2e","resource":"gce-project-1","state":"busy","time":"2019-10-01T12:00:01Z","type":"gce-project"}
+{"level":"info","msg":"Acquired boskos resource","owner":"ci-kubernetes-e2e","resource":"aws-account-3","state":"busy","time":"2019-10-01T12:10:00Z","type":"aws-account"}
+not a record
+`
+
+func TestRead(t *testing.T) {
+	leases := newLeases()
+	for _, log := range []string{testLog, testArtifact} {
+		if err := leases.read(strings.NewReader(log), "log"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(leases.Leases) != 3 {
+		t.Fatalf("expected 3 leases, got %d", len(leases.Leases))
+	}
+	project, cluster, account := leases.Leases[0], leases.Leases[1], leases.Leases[2]
+	if project.Name != "gce-project-1" || project.Type != "gce-project" || project.Status() != statusReleased || project.ReleasedTo != "dirty" || project.Line != 2 {
+		t.Errorf("expected gce-project-1 to be acquired on line 2 and released to dirty, got %+v", project)
+	}
+	if d := leases.Duration(project); d != 30*time.Minute {
+		t.Errorf("expected gce-project-1 to be held for 30m, got %s", d)
+	}
+	if project.UpdateFailures != 1 || !strings.HasPrefix(project.LastUpdateError, "status 500") {
+		t.Errorf("expected one failed heartbeat for gce-project-1, got %d: %q", project.UpdateFailures, project.LastUpdateError)
+	}
+	if cluster.Status() != statusReleaseFailed || cluster.ReleaseError != "status 401 Unauthorized, statusCode 401 releasing gke-cluster-2" {
+		t.Errorf("expected releasing gke-cluster-2 to fail, got %s: %q", cluster.Status(), cluster.ReleaseError)
+	}
+	if d := leases.Duration(cluster); d != 30*time.Minute+time.Second {
+		t.Errorf("expected gke-cluster-2 to be held until the end of the log, got %s", d)
+	}
+	if account.Status() != statusHeld || account.Owner != "ci-kubernetes-e2e" || account.Line != 2 {
+		t.Errorf("expected aws-account-3 not to be released, got %+v", account)
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected *record
+	}{
+		{`msg="Released boskos resource" resource=p state="dirty"`, &record{Msg: msgReleased, Resource: "p", State: "dirty"}},
+		{`level=warning msg="Failed to release boskos resource" error="status \"401\"" resource=p`, &record{Msg: msgReleaseFailed, Resource: "p", Error: `status "401"`}},
+		{`{"msg":"Acquired boskos resource","resource":"p"}`, &record{Msg: msgAcquired, Resource: "p"}},
+		{`{"msg":`, nil},
+		{"Running: gcloud config set project p", nil},
+	}
+	for _, tc := range testCases {
+		r := parseRecord(tc.line)
+		if (r == nil) != (tc.expected == nil) || r != nil && *r != *tc.expected {
+			t.Errorf("expected %q to parse as %+v, got %+v", tc.line, tc.expected, r)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package boskos provides a Spyglass lens that shows the resources a job leased from boskos, for
+// how long, and whether they were released.
+package boskos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "boskos"
+	title    = "Boskos Leases"
+	priority = 45
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of a boskos lease-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each log is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// LeaseView is a lease with its times formatted for display.
+type LeaseView struct {
+	*Lease
+	Acquired string
+	Released string
+	Duration string
+}
+
+type boskosView struct {
+	Errors   []string
+	Logs     []string
+	Leases   []LeaseView
+	Released int
+	// Leaked is the number of leases that were not released or failed to be.
+	Leaked int
+}
+
+// Body renders the leases recorded in the logs, those that were not released first.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := boskosView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	leases := newLeases()
+	for _, a := range artifacts {
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			err = leases.read(r, a.JobPath())
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		view.Logs = append(view.Logs, a.JobPath())
+	}
+	for _, l := range leases.Leases {
+		lv := LeaseView{Lease: l}
+		if !l.Acquired.IsZero() {
+			lv.Acquired = l.Acquired.Format(time.RFC3339)
+		}
+		if !l.Released.IsZero() {
+			lv.Released = l.Released.Format(time.RFC3339)
+		}
+		if d := leases.Duration(l); d > 0 {
+			lv.Duration = d.Round(time.Second).String()
+		}
+		if l.Status() == statusReleased {
+			view.Released++
+		} else {
+			view.Leaked++
+		}
+		view.Leases = append(view.Leases, lv)
+	}
+	sort.SliceStable(view.Leases, func(i, j int) bool {
+		return view.Leases[i].Status() != statusReleased && view.Leases[j].Status() == statusReleased
+	})
+	if len(view.Leases) == 0 && len(view.Errors) == 0 {
+		view.Errors = append(view.Errors, "No boskos leases found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// artifactSelector picks which of the logs the lens matched are shown, and in what order.
+type artifactSelector struct {
+	include, exclude []*regexp.Regexp
+}
+
+// artifactSelector compiles the configured artifact patterns.
+func (c config) artifactSelector() (artifactSelector, error) {
+	var s artifactSelector
+	var err error
+	if s.include, err = compileArtifactPatterns(c.IncludeArtifacts); err != nil {
+		return s, err
+	}
+	if s.exclude, err = compileArtifactPatterns(c.ExcludeArtifacts); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+func compileArtifactPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid artifact pattern %q: %v", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// rank returns the index of the first include pattern matching a path, or false if the artifact
+// at the path is not shown.
+func (s artifactSelector) rank(path string) (int, bool) {
+	for _, re := range s.exclude {
+		if re.MatchString(path) {
+			return 0, false
+		}
+	}
+	if len(s.include) == 0 {
+		return 0, true
+	}
+	for i, re := range s.include {
+		if re.MatchString(path) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// selectArtifacts returns the artifacts that are shown, ordered by the first include pattern
+// each matches and otherwise left in order.
+func (s artifactSelector) selectArtifacts(artifacts []lenses.Artifact) []lenses.Artifact {
+	var selected []lenses.Artifact
+	var ranks []int
+	for _, a := range artifacts {
+		if rank, ok := s.rank(a.JobPath()); ok {
+			selected = append(selected, a)
+			ranks = append(ranks, rank)
+		}
+	}
+	sort.Stable(byRank{selected, ranks})
+	return selected
+}
+
+// byRank sorts artifacts by their ranks.
+type byRank struct {
+	artifacts []lenses.Artifact
+	ranks     []int
+}
+
+func (r byRank) Len() int           { return len(r.artifacts) }
+func (r byRank) Less(i, j int) bool { return r.ranks[i] < r.ranks[j] }
+func (r byRank) Swap(i, j int) {
+	r.artifacts[i], r.artifacts[j] = r.artifacts[j], r.artifacts[i]
+	r.ranks[i], r.ranks[j] = r.ranks[j], r.ranks[i]
+}
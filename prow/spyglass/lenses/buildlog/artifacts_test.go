@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// namedArtifact is an artifact that only has a path.
+type namedArtifact struct {
+	lenses.Artifact
+	path string
+}
+
+func (a namedArtifact) JobPath() string { return a.path }
+
+func TestSelectArtifacts(t *testing.T) {
+	paths := []string{"artifacts/setup.log", "build-log.txt", "artifacts/test.log", "artifacts/noise.log"}
+	testcases := []struct {
+		name     string
+		config   config
+		expected []string
+	}{
+		{
+			name:     "all logs are shown in order by default",
+			expected: paths,
+		},
+		{
+			name:     "logs are ordered by the patterns they match, and others are not shown",
+			config:   config{IncludeArtifacts: []string{`test\.log$`, `\.log$`}},
+			expected: []string{"artifacts/test.log", "artifacts/setup.log", "artifacts/noise.log"},
+		},
+		{
+			name:     "excluded logs are not shown",
+			config:   config{IncludeArtifacts: []string{`test\.log$`, `.*`}, ExcludeArtifacts: []string{`noise`}},
+			expected: []string{"artifacts/test.log", "artifacts/setup.log", "build-log.txt"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var artifacts []lenses.Artifact
+			for _, path := range paths {
+				artifacts = append(artifacts, namedArtifact{path: path})
+			}
+			selector, err := tc.config.artifactSelector()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []string
+			for _, a := range selector.selectArtifacts(artifacts) {
+				actual = append(actual, a.JobPath())
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+
+	if _, err := (config{ExcludeArtifacts: []string{`(`}}).artifactSelector(); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
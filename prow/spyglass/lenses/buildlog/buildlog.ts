@@ -1,95 +1,443 @@
-function showElem(elem: HTMLElement): void {
-  elem.className = 'shown';
-  elem.innerHTML = ansiToHTML(elem.innerHTML);
-}
-
-// given a string containing ansi formatting directives, return a new one
-// with designated regions of text marked with the appropriate color directives,
-// and with all unknown directives stripped
-function ansiToHTML(orig: string): string {
-  // Given a cmd (like "32" or "0;97"), some enclosed body text, and the original string,
-  // either return the body wrapped in an element to achieve the desired result, or the
-  // original string if nothing works.
-  function annotate(cmd: string, body: string): string {
-    const code = +(cmd.replace('0;', ''));
-    if (code === 0) {
-      // reset
-      return body;
-    } else if (code === 1) {
-      // bold
-      return `<strong>${body}</strong>`;
-    } else if (code === 3) {
-      // italic
-      return `<em>${body}</em>`;
-    } else if (30 <= code && code <= 37) {
-      // foreground color
-      return `<span class="ansi-${(code - 30)}">${body}</span>`;
-    } else if (90 <= code && code <= 97) {
-      // foreground color, bright
-      return `<span class="ansi-${(code - 90 + 8)}">${body}</span>`;
-    }
-    return body;  // fallback: don't change anything
-  }
-  // Find commands, optionally followed by a bold command, with some content, then a reset command.
-  // Unpaired commands are *not* handled here, but they're very uncommon.
-  const filtered = orig.replace(/\033\[([0-9;]*)\w(\033\[1m)?([^\033]*?)\033\[0m/g, (match: string, cmd: string, bold: string, body: string, offset: number, str: string) => {
-    if (bold !== undefined) {
-      // normal code + bold
-      return `<em>${annotate(cmd, body)}</em>`;
-    }
-    return annotate(cmd, body);
-  });
-  // Strip out anything left over.
-  return filtered.replace(/\033\[([0-9;]*\w)/g, (match: string, cmd: string, offset: number, str: string) => {
-    console.log('unhandled ansi code: ', cmd, "context:", filtered);
-    return '';
-  });
+// Replaces an element with the elements in some HTML.
+function replaceWithHTML(elem: HTMLElement, html: string): void {
+  const replacements = document.createElement('div');
+  replacements.innerHTML = html;
+  for (const replacement of Array.from(replacements.children)) {
+    elem.parentNode!.insertBefore(replacement, elem);
+  }
+  elem.parentNode!.removeChild(elem);
 }
 
-async function handleShowSkipped(this: HTMLDivElement, e: MouseEvent) {
-  // Don't do anything unless they actually clicked the button.
-  if (!(e.target instanceof HTMLButtonElement)) {
+// The number of lines revealed by expanding a skipped block in one direction.
+const expansionLines = 20;
+
+async function handleShowSkipped(e: MouseEvent) {
+  // Don't do anything unless they actually clicked a button.
+  if (!(e.target instanceof Element)) {
     return;
   }
-  const {artifact, offset, length, startLine} = this.dataset;
-  const content = await spyglass.request(JSON.stringify({
-    artifact, length: +length!, offset: +offset!, startLine: +startLine!}));
-  this.innerHTML = ansiToHTML(content);
-  showElem(this);
+  const button = e.target.closest('button') as HTMLButtonElement | null;
+  const skippedBlock = e.target.closest('.show-skipped') as HTMLElement | null;
+  if (!button || !skippedBlock) {
+    return;
+  }
+  const {artifact, offset, length, startLine} = skippedBlock.dataset;
+  const {direction} = button.dataset;
+  if (direction) {
+    // The server splits the block into the lines revealed and the lines still skipped, with
+    // their exact offsets, which replace it.
+    const content = await spyglass.request(JSON.stringify({
+      artifact, length: +length!, offset: +offset!, startLine: +startLine!,
+      direction, count: expansionLines}));
+    replaceWithHTML(skippedBlock, content);
+  } else {
+    // The server shows all of the block unless it is too long, splitting it like an expansion.
+    const content = await spyglass.request(JSON.stringify({
+      artifact, length: +length!, offset: +offset!, startLine: +startLine!}));
+    replaceWithHTML(skippedBlock, content);
+  }
 
   // Remove the "show all" button if we no longer need it.
   const log = document.getElementById(`${artifact}-content`)!;
   const skipped = log.querySelectorAll<HTMLElement>(".show-skipped");
-  if (skipped.length === 0) {
-    const button = document.querySelector('button.show-all-button')!;
-    button.parentNode!.removeChild(button);
+  const showAll = document.querySelector(`button.show-all-button[data-artifact="${artifact}"]`);
+  if (skipped.length === 0 && showAll) {
+    showAll.parentNode!.removeChild(showAll);
   }
   spyglass.contentUpdated();
 }
 
+async function handleExpandGap(e: MouseEvent) {
+  if (!(e.target instanceof Element)) {
+    return;
+  }
+  const button = e.target.closest('button.expand-gap');
+  const gap = e.target.closest('.log-gap') as HTMLElement | null;
+  if (!button || !gap) {
+    return;
+  }
+  // The server returns the lines at the top of the gap, followed by what is left of it.
+  const {artifact, offset, length, startLine} = gap.dataset;
+  const content = await spyglass.request(JSON.stringify({
+    artifact, length: +length!, offset: +offset!, startLine: +startLine!, gap: true}));
+  replaceWithHTML(gap, content);
+  spyglass.contentUpdated();
+}
+
+// Live logs are checked for new lines this often, backing off while none are logged.
+const minLiveInterval = 2000;
+const maxLiveInterval = 60000;
+
+// The live tails new lines are being checked for after.
+const watched = new WeakSet<HTMLElement>();
+
+// Checks for new lines after each live tail that isn't already being checked. Tails are replaced
+// with the lines after them and a new tail, and are left behind when the log is replaced.
+function watchLiveTails(): void {
+  for (const tail of Array.from(document.querySelectorAll<HTMLElement>('.live-tail'))) {
+    if (!watched.has(tail)) {
+      watched.add(tail);
+      window.setTimeout(() => pollLiveTail(tail, minLiveInterval), minLiveInterval);
+    }
+  }
+}
+
+async function pollLiveTail(tail: HTMLElement, interval: number) {
+  if (!document.body.contains(tail)) {
+    return;
+  }
+  const {artifact, offset, startLine} = tail.dataset;
+  let content: string;
+  try {
+    content = await spyglass.request(JSON.stringify({
+      artifact, length: 0, live: true, offset: +offset!, startLine: +startLine!}));
+  } catch (e) {
+    window.setTimeout(() => pollLiveTail(tail, Math.min(interval * 2, maxLiveInterval)), interval);
+    return;
+  }
+  if (!document.body.contains(tail)) {
+    return;
+  }
+  const parent = tail.parentElement!;
+  replaceWithHTML(tail, content);
+  spyglass.contentUpdated();
+  const next = parent.querySelector<HTMLElement>('.live-tail');
+  if (next) {
+    watched.add(next);
+    const idle = next.dataset.offset === offset;
+    const nextInterval = idle ? Math.min(interval * 2, maxLiveInterval) : minLiveInterval;
+    window.setTimeout(() => pollLiveTail(next, nextInterval), nextInterval);
+  }
+}
+
 async function handleShowAll(this: HTMLButtonElement) {
+  const {artifact} = this.dataset;
+  // Showing all lines ends any filtering.
+  unfiltered.delete(artifact!);
+  const filter = this.parentElement!.querySelector<HTMLSelectElement>('select.level-filter');
+  if (filter) {
+    filter.value = '';
+  }
+
   // Remove ourselves immediately.
   if (this.parentElement) {
     this.parentElement.removeChild(this);
   }
 
-  const {artifact} = this.dataset;
   const content = await spyglass.request(JSON.stringify({artifact, offset: 0, length: -1}));
-  document.getElementById(`${artifact}-content`)!.innerHTML = `<tbody class="shown">${ansiToHTML(content)}</tbody>`;
+  document.getElementById(`${artifact}-content`)!.innerHTML = content;
   spyglass.contentUpdated();
+  watchLiveTails();
 }
 
-window.addEventListener('load', () => {
-  const shown = document.getElementsByClassName("shown");
-  for (const child of Array.from(shown)) {
-    child.innerHTML = ansiToHTML(child.innerHTML);
+// The content of each log before it was filtered, by artifact.
+const unfiltered = new Map<string, string>();
+
+async function handleLevelFilter(this: HTMLSelectElement) {
+  const {artifact} = this.dataset;
+  const log = document.getElementById(`${artifact}-content`)!;
+  if (!unfiltered.has(artifact!)) {
+    unfiltered.set(artifact!, log.innerHTML);
+  }
+  if (this.value === '') {
+    log.innerHTML = unfiltered.get(artifact!)!;
+    unfiltered.delete(artifact!);
+  } else {
+    const content = await spyglass.request(JSON.stringify({artifact, level: this.value}));
+    log.innerHTML = content;
+  }
+  spyglass.contentUpdated();
+  // Lines are not added to filtered logs, so their tails are checked again once they're restored.
+  watchLiveTails();
+}
+
+async function handleFieldFilter(this: HTMLInputElement) {
+  const {artifact} = this.dataset;
+  const content = await spyglass.request(JSON.stringify({artifact, structured: true, filter: this.value}));
+  const log = document.getElementById(`${artifact}-content`)!;
+  log.querySelector('.structured-log tbody')!.innerHTML = content;
+  spyglass.contentUpdated();
+}
+
+// Filters the records of a log of JSON lines by a field when it is clicked.
+function handleRecordField(e: MouseEvent): void {
+  if (!(e.target instanceof Element)) {
+    return;
+  }
+  const field = e.target.closest('.record-field') as HTMLElement | null;
+  const log = field ? field.closest('.loglines') as HTMLElement | null : null;
+  if (!field || !log) {
+    return;
+  }
+  const filter = document.querySelector<HTMLInputElement>(`input.field-filter[data-artifact="${log.dataset.artifact}"]`);
+  if (!filter) {
+    return;
+  }
+  const {key} = field.dataset;
+  let value = field.dataset.value || '';
+  // Values with spaces are quoted, and the filter has no way to match quotes within them.
+  if (/[\s"]/.test(value)) {
+    value = `"${value.replace(/"/g, '')}"`;
+  }
+  const term = `${key}=${value}`;
+  if (filter.value.split(' ').indexOf(term) === -1) {
+    filter.value = filter.value ? `${filter.value} ${term}` : term;
+    filter.dispatchEvent(new Event('change'));
+  }
+}
+
+interface FindResult {
+  total: number;
+  matches: Array<{line: number, offset: number}> | null;
+  error?: string;
+}
+
+// A search of a log, with the match last jumped to.
+interface Search {
+  matches: Array<{line: number, offset: number}>;
+  index: number;
+}
+
+// The search of each log, by artifact.
+const searches = new Map<string, Search>();
+
+// Searches a log for the text in its find box, returning whether any lines matched.
+async function search(bar: HTMLElement): Promise<boolean> {
+  const {artifact} = bar.dataset;
+  const input = bar.querySelector<HTMLInputElement>('input.find-input')!;
+  const regex = bar.querySelector<HTMLInputElement>('input.find-regex')!.checked;
+  const status = bar.querySelector<HTMLElement>('.find-status')!;
+  searches.delete(artifact!);
+  status.textContent = '';
+  if (!input.value) {
+    return false;
+  }
+  status.textContent = 'Searching…';
+  const result: FindResult = JSON.parse(await spyglass.request(JSON.stringify({artifact, find: input.value, regex})));
+  if (result.error) {
+    status.textContent = result.error;
+    return false;
+  }
+  const matches = result.matches || [];
+  if (matches.length === 0) {
+    status.textContent = 'No matches';
+    return false;
+  }
+  searches.set(artifact!, {matches, index: 0});
+  status.dataset.total = String(result.total);
+  return true;
+}
+
+function showSearchStatus(bar: HTMLElement): void {
+  const s = searches.get(bar.dataset.artifact!);
+  const status = bar.querySelector<HTMLElement>('.find-status')!;
+  if (!s) {
+    return;
+  }
+  const total = +status.dataset.total!;
+  const more = total > s.matches.length ? ` (only the first ${s.matches.length} can be jumped to)` : '';
+  status.textContent = `${s.index + 1} of ${total}${more}`;
+}
+
+// Jumps to the current match of the search of a log, keeping the search if the page is updated.
+async function jumpToMatch(bar: HTMLElement) {
+  const {artifact} = bar.dataset;
+  const s = searches.get(artifact!);
+  if (!s) {
+    return;
   }
+  showSearchStatus(bar);
+  const find = bar.querySelector<HTMLInputElement>('input.find-input')!.value;
+  const regex = bar.querySelector<HTMLInputElement>('input.find-regex')!.checked;
+  await jumpToLine(artifact!, s.matches[s.index].line, {find, regex, match: s.index});
+}
 
-  for (const button of Array.from(document.querySelectorAll<HTMLDivElement>(".show-skipped"))) {
-    button.addEventListener('click', handleShowSkipped);
+// Jumps to a line of a log. Lines already shown are scrolled to, and otherwise the page is
+// updated to show them, with whatever else the Body request holds.
+async function jumpToLine(artifact: string, line: number, request: object) {
+  const log = document.getElementById(`${artifact}-content`)!;
+  const linenum = Array.from(log.querySelectorAll<HTMLElement>('.shown .linenum'))
+    .find((el) => (el.textContent || '').trim() === String(line));
+  if (!linenum) {
+    await spyglass.updatePage(JSON.stringify({...request, artifact, line}));
+    bind();
+    scrollToTarget();
+    return;
   }
+  for (let section = linenum.closest('.log-section.folded'); section; section = section.closest('.log-section.folded')) {
+    section.classList.remove('folded');
+  }
+  for (const target of Array.from(document.querySelectorAll('.line-target'))) {
+    target.classList.remove('line-target');
+  }
+  linenum.parentElement!.classList.add('line-target');
+  spyglass.contentUpdated();
+  linenum.scrollIntoView({block: 'center'});
+}
+
+async function handleJumpLine(this: HTMLButtonElement) {
+  const summary = this.closest('.error-summary') as HTMLElement;
+  await jumpToLine(summary.dataset.artifact!, +this.dataset.line!, {});
+}
+
+async function handleFind(this: HTMLInputElement) {
+  const bar = this.closest('.log-find') as HTMLElement;
+  if (await search(bar)) {
+    await jumpToMatch(bar);
+  }
+}
+
+async function handleFindStep(this: HTMLButtonElement) {
+  const bar = this.closest('.log-find') as HTMLElement;
+  if (!searches.has(bar.dataset.artifact!) && !await search(bar)) {
+    return;
+  }
+  const s = searches.get(bar.dataset.artifact!)!;
+  const step = this.classList.contains('find-prev') ? -1 : 1;
+  s.index = (s.index + step + s.matches.length) % s.matches.length;
+  await jumpToMatch(bar);
+}
+
+// Restores the search a jump to a line updated the page for.
+async function restoreSearch(bar: HTMLElement) {
+  if (bar.dataset.match === undefined || !await search(bar)) {
+    return;
+  }
+  const s = searches.get(bar.dataset.artifact!)!;
+  s.index = Math.min(+bar.dataset.match, s.matches.length - 1);
+  showSearchStatus(bar);
+}
+
+function handleSectionToggle(e: MouseEvent): void {
+  if (!(e.target instanceof Element)) {
+    return;
+  }
+  const toggle = e.target.closest('.section-toggle');
+  if (!toggle) {
+    return;
+  }
+  const section = toggle.closest('.log-section')!;
+  section.classList.toggle('folded');
+  spyglass.contentUpdated();
+}
+
+// Shows or hides the lines of a traceback after its first, which are the lines after the toggle's
+// line up to the next line outside of it.
+function handleTracebackToggle(e: MouseEvent): void {
+  if (!(e.target instanceof Element) || !e.target.classList.contains('traceback-toggle')) {
+    return;
+  }
+  const start = e.target.closest('.traceback-start');
+  if (!start) {
+    return;
+  }
+  const open = !start.classList.contains('open');
+  start.classList.toggle('open', open);
+  for (let line = start.nextElementSibling; line && line.classList.contains('traceback') &&
+      !line.classList.contains('traceback-start'); line = line.nextElementSibling) {
+    line.classList.toggle('open', open);
+  }
+  spyglass.contentUpdated();
+}
+
+// Shows the failed JUnit test whose failure message was logged on a line in the junit lens.
+function handleJunitLink(e: MouseEvent): void {
+  if (!(e.target instanceof HTMLElement) || !e.target.classList.contains('junit-link')) {
+    return;
+  }
+  const data = JSON.stringify({test: e.target.dataset.test});
+  spyglass.showLens('junit', `iframe?data=${encodeURIComponent(data)}`);
+}
+
+// Links to a line by setting the fragment of the Spyglass page, which opens the lens at that
+// line when the link is followed.
+function handleLineLink(e: MouseEvent): void {
+  if (!(e.target instanceof HTMLElement) || !e.target.classList.contains('linenum')) {
+    return;
+  }
+  const line = (e.target.textContent || '').trim();
+  const log = e.target.closest('.loglines') as HTMLElement | null;
+  if (!line || !log || !log.dataset.artifact) {
+    return;
+  }
+  window.parent.location.hash = `buildlog/line/${line}?artifact=${encodeURIComponent(log.dataset.artifact)}`;
+  for (const target of Array.from(document.querySelectorAll('.line-target'))) {
+    target.classList.remove('line-target');
+  }
+  e.target.parentElement!.classList.add('line-target');
+}
+
+// Scrolls to the line a permalink targets, the section a link shows, or else the first failure. Spyglass resizes the lens to
+// fit its content after it loads, so this is done again then.
+function scrollToTarget(): void {
+  const target = document.querySelector('.line-target') || document.querySelector('.anchor-target') ||
+    document.querySelector('.first-failure');
+  if (!target) {
+    return;
+  }
+  const scroll = () => target.scrollIntoView({block: 'center'});
+  scroll();
+  window.addEventListener('resize', scroll, {once: true});
+}
+
+function bind(): void {
+  // Pages are replaced by bind's callers, so nothing is left filtered.
+  unfiltered.clear();
+  watchLiveTails();
 
   for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.show-all-button"))) {
     button.addEventListener('click', handleShowAll);
   }
+
+  for (const filter of Array.from(document.querySelectorAll<HTMLSelectElement>("select.level-filter"))) {
+    filter.addEventListener('change', handleLevelFilter);
+  }
+
+  for (const filter of Array.from(document.querySelectorAll<HTMLInputElement>("input.field-filter"))) {
+    filter.addEventListener('change', handleFieldFilter);
+  }
+
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.jump-line"))) {
+    button.addEventListener('click', handleJumpLine);
+  }
+
+  searches.clear();
+  for (const bar of Array.from(document.querySelectorAll<HTMLElement>(".log-find"))) {
+    bar.querySelector('input.find-input')!.addEventListener('change', handleFind);
+    bar.querySelector('input.find-regex')!.addEventListener('change', () => searches.delete(bar.dataset.artifact!));
+    for (const button of Array.from(bar.querySelectorAll<HTMLButtonElement>('button.find-prev, button.find-next'))) {
+      button.addEventListener('click', handleFindStep);
+    }
+    restoreSearch(bar);
+  }
+
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.raw-button"))) {
+    button.addEventListener('click', () => spyglass.updatePage(JSON.stringify({raw: true})).then(bind));
+  }
+
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.structured-button"))) {
+    button.addEventListener('click', () => spyglass.updatePage('').then(bind));
+  }
+
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.interleave-button"))) {
+    button.addEventListener('click', () => spyglass.updatePage(JSON.stringify({interleave: true})).then(bind));
+  }
+
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.separate-button"))) {
+    button.addEventListener('click', () => spyglass.updatePage('').then(bind));
+  }
+}
+
+window.addEventListener('load', () => {
+  bind();
+  // Sections, skipped blocks and gaps are replaced as lines are shown, so their buttons are
+  // handled here.
+  document.addEventListener('click', handleSectionToggle);
+  document.addEventListener('click', handleShowSkipped);
+  document.addEventListener('click', handleExpandGap);
+  document.addEventListener('click', handleLineLink);
+  document.addEventListener('click', handleRecordField);
+  document.addEventListener('click', handleTracebackToggle);
+  document.addEventListener('click', handleJunitLink);
+  scrollToTarget();
 });
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// compareContextLines is the number of unchanged lines shown around each change when two
+	// runs' logs are compared.
+	compareContextLines = 3
+	// compareMaxEdits bounds the work done diffing the logs of very different runs.
+	compareMaxEdits = 5000
+)
+
+// LogDiffView is the diff of a log of the run being viewed against the same log of the run it
+// is compared to.
+type LogDiffView struct {
+	ArtifactName string
+	ArtifactLink string
+	OtherLink    string
+	// Message explains why the logs could not be diffed, or that they do not differ.
+	Message string
+	Added   int
+	Removed int
+	Hunks   []lenses.DiffHunk
+}
+
+type compareView struct {
+	Error     string
+	OtherLink string
+	Logs      []LogDiffView
+}
+
+// Compare renders the diff of each build log of the run being viewed against the same log of
+// the run it is compared to. Numbers are ignored when matching lines, so lines differing only
+// in timestamps or durations are not shown as changed.
+func (lens Lens) Compare(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	opts, err := parseConfig(rawConfig)
+	view := compareRuns(artifacts, opts)
+	if err != nil {
+		view.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+	}
+	return executeTemplate(resourceDir, "compare", view)
+}
+
+// compareRuns diffs the logs of the run being viewed against the logs of the same name in the
+// run it is compared to.
+func compareRuns(artifacts []lenses.Artifact, opts options) compareView {
+	artifacts, _ = lenses.SplitSiblings(artifacts)
+	current, baseline := lenses.SplitBaseline(artifacts)
+	view := compareView{}
+	other := map[string]lenses.Artifact{}
+	for _, b := range baseline {
+		other[b.JobPath()] = b
+		view.OtherLink = "/view/" + b.Source
+	}
+	current = lenses.DecodeArtifacts(opts.artifacts.selectArtifacts(current))
+	for _, a := range current {
+		dv := LogDiffView{ArtifactName: a.JobPath(), ArtifactLink: a.CanonicalLink()}
+		b, ok := other[a.JobPath()]
+		if !ok {
+			dv.Message = "The other run has no log of this name."
+			view.Logs = append(view.Logs, dv)
+			continue
+		}
+		dv.OtherLink = b.CanonicalLink()
+		diffLogs(&dv, lenses.DecodeArtifact(b), a, opts)
+		view.Logs = append(view.Logs, dv)
+	}
+	return view
+}
+
+// diffLogs diffs two logs into a view, matching lines with their numbers masked and showing
+// their text with secrets scrubbed.
+func diffLogs(dv *LogDiffView, oldLog, newLog lenses.Artifact, opts options) {
+	oldLines, err := readCompared(oldLog, opts)
+	var newLines []string
+	if err == nil {
+		newLines, err = readCompared(newLog, opts)
+	}
+	if err == lenses.ErrFileTooLarge {
+		dv.Message = "The logs are too large to compare."
+		return
+	}
+	if err != nil {
+		dv.Message = fmt.Sprintf("Failed to read the logs: %v", err)
+		return
+	}
+	diff, err := lenses.DiffLines(maskNumbers(oldLines), maskNumbers(newLines), compareContextLines, compareMaxEdits)
+	if err != nil {
+		dv.Message = "The logs are too different to compare."
+		return
+	}
+	dv.Added, dv.Removed = diff.Added, diff.Removed
+	dv.Hunks = unmaskHunks(diff.Hunks, oldLines, newLines, opts.secrets)
+	if len(dv.Hunks) == 0 {
+		dv.Message = "The logs do not differ other than in numbers."
+	}
+}
+
+// readCompared reads a log to compare, treating logs longer than the lens shows in full as too
+// large.
+func readCompared(a lenses.Artifact, opts options) ([]string, error) {
+	lines, err := logLinesAll(a)
+	if err == nil && opts.maxLines > 0 && len(lines) > opts.maxLines {
+		err = lenses.ErrFileTooLarge
+	}
+	return lines, err
+}
+
+// maskNumbers replaces the numbers in lines with zeros.
+func maskNumbers(lines []string) []string {
+	masked := make([]string, len(lines))
+	for i, line := range lines {
+		masked[i] = counterRE.ReplaceAllString(line, "0")
+	}
+	return masked
+}
+
+// unmaskHunks replaces the masked text of the lines of hunks with their original text, taking
+// unchanged lines from the new log.
+func unmaskHunks(hunks []lenses.DiffHunk, oldLines, newLines []string, secrets *secretScrubber) []lenses.DiffHunk {
+	for i := range hunks {
+		for j := range hunks[i].Lines {
+			line := &hunks[i].Lines[j]
+			if line.NewLine > 0 {
+				line.Text = newLines[line.NewLine-1]
+			} else {
+				line.Text = oldLines[line.OldLine-1]
+			}
+			line.Text = secrets.scrub(line.Text)
+		}
+	}
+	return hunks
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestCompareRuns(t *testing.T) {
+	current := "Started at 12:01:07.\nBuilding.\nToken hunter2 rejected.\nFAIL: TestLogin\nDone in 41s.\n"
+	other := "Started at 11:58:42.\nBuilding.\nPASS: TestLogin\nDone in 39s.\n"
+	artifacts := []lenses.Artifact{
+		&smallArtifact{largeArtifact{content: []byte(current)}},
+		lenses.NewBaselineArtifact(&smallArtifact{largeArtifact{content: []byte(other)}}, "gcs/bucket/logs/job/1"),
+	}
+	opts, err := parseConfig([]byte(`{"secret_patterns": ["hunter2"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	view := compareRuns(artifacts, opts)
+	if view.OtherLink != "/view/gcs/bucket/logs/job/1" || len(view.Logs) != 1 {
+		t.Fatalf("expected one log compared to gcs/bucket/logs/job/1, got %+v", view)
+	}
+	log := view.Logs[0]
+	if log.Message != "" || log.Added != 2 || log.Removed != 1 || len(log.Hunks) != 1 {
+		t.Fatalf("expected one hunk adding two lines and removing one, got %+v", log)
+	}
+	var changes []lenses.DiffLine
+	for _, line := range log.Hunks[0].Lines {
+		if line.Op != "equal" {
+			changes = append(changes, line)
+		}
+	}
+	expected := []lenses.DiffLine{
+		{Op: "delete", OldLine: 3, Text: "PASS: TestLogin"},
+		{Op: "insert", NewLine: 3, Text: "Token [REDACTED] rejected."},
+		{Op: "insert", NewLine: 4, Text: "FAIL: TestLogin"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+	if last := log.Hunks[0].Lines[len(log.Hunks[0].Lines)-2]; last.Text != "Done in 41s." {
+		t.Errorf("expected unchanged lines to be shown as in the current run, got %+v", last)
+	}
+
+	unmatched := compareRuns(artifacts[:1], opts)
+	if len(unmatched.Logs) != 1 || unmatched.Logs[0].Message == "" || unmatched.Logs[0].Hunks != nil {
+		t.Errorf("expected a message for a log missing from the other run, got %+v", unmatched)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// GroupMarkers match the lines that begin and end sections of the log, which can be folded
+	// away. They replace the default markers when set.
+	GroupMarkers []groupMarker `json:"group_markers,omitempty"`
+	// TimestampGap begins a new section at a line logged at least this long after the last
+	// timestamped line, e.g. "5m". Sections are not split by time if it is unset.
+	TimestampGap string `json:"timestamp_gap,omitempty"`
+	// Levels override the regexes that detect the level of log lines, keyed by level. The levels
+	// are "error" and "warning".
+	Levels map[string]string `json:"levels,omitempty"`
+	// HeadLines and TailLines are how many lines of the beginning and end of logs too large to
+	// read in full are shown. They default to 500 and 1000.
+	HeadLines int `json:"head_lines,omitempty"`
+	TailLines int `json:"tail_lines,omitempty"`
+	// MaxLines is the most lines a log can have to be shown in full. Longer logs are shown like
+	// logs too large to read in full, and HeadLines and TailLines default to a third and two
+	// thirds of it if they would add up to more. Logs of any length are shown in full if it is
+	// unset.
+	MaxLines int `json:"max_lines,omitempty"`
+	// GapExpansionLines is how many lines of the middle of a truncated log are shown at a time.
+	// It defaults to 1000.
+	GapExpansionLines int `json:"gap_expansion_lines,omitempty"`
+	// MaxExpandedLines is the most lines shown at once by expanding the hidden lines of a log or
+	// showing all of them. There is no limit if it is unset.
+	MaxExpandedLines int `json:"max_expanded_lines,omitempty"`
+	// SecretPatterns are regexes matching secrets, which are replaced with "[REDACTED]" in the
+	// lines shown. Where a pattern has a group named "secret", only that group is replaced.
+	SecretPatterns []string `json:"secret_patterns,omitempty"`
+	// SecretDetectors name built-in patterns of common token formats to redact likewise, or are
+	// "all" for all of them.
+	SecretDetectors []string `json:"secret_detectors,omitempty"`
+	// IncludeArtifacts are regexes matching the paths of the logs shown, which are shown in the
+	// order of the first pattern each matches. All logs are shown if it is unset.
+	IncludeArtifacts []string `json:"include_artifacts,omitempty"`
+	// ExcludeArtifacts are regexes matching the paths of logs that are not shown.
+	ExcludeArtifacts []string `json:"exclude_artifacts,omitempty"`
+}
+
+// options is the compiled configuration of the lens.
+type options struct {
+	sections             sectioner
+	levels               levelDetector
+	headLines, tailLines int
+	maxLines             int
+	gapExpansionLines    int
+	maxExpandedLines     int
+	secrets              *secretScrubber
+	artifacts            artifactSelector
+}
+
+// compile compiles the configuration.
+func (c config) compile() (options, error) {
+	var opts options
+	var err error
+	if opts.sections, err = c.sectioner(); err != nil {
+		return opts, err
+	}
+	if opts.levels, err = c.levelDetector(); err != nil {
+		return opts, err
+	}
+	if opts.secrets, err = c.secretScrubber(); err != nil {
+		return opts, err
+	}
+	if opts.artifacts, err = c.artifactSelector(); err != nil {
+		return opts, err
+	}
+	if c.HeadLines < 0 || c.TailLines < 0 || c.MaxLines < 0 || c.GapExpansionLines < 0 || c.MaxExpandedLines < 0 {
+		return opts, fmt.Errorf("line counts must not be negative")
+	}
+	opts.headLines, opts.tailLines = defaultHeadLines, defaultTailLines
+	if c.HeadLines > 0 {
+		opts.headLines = c.HeadLines
+	}
+	if c.TailLines > 0 {
+		opts.tailLines = c.TailLines
+	}
+	opts.maxLines = c.MaxLines
+	if opts.maxLines > 0 && opts.headLines+opts.tailLines > opts.maxLines {
+		if c.HeadLines > 0 || c.TailLines > 0 {
+			return opts, fmt.Errorf("head_lines and tail_lines must not add up to more than max_lines")
+		}
+		opts.headLines = opts.maxLines / 3
+		opts.tailLines = opts.maxLines - opts.headLines
+	}
+	opts.gapExpansionLines = defaultGapExpansionLines
+	if c.GapExpansionLines > 0 {
+		opts.gapExpansionLines = c.GapExpansionLines
+	}
+	opts.maxExpandedLines = c.MaxExpandedLines
+	return opts, nil
+}
+
+// parseConfig returns the compiled configuration of the lens, or the default configuration
+// along with an error if it is invalid.
+func parseConfig(rawConfig json.RawMessage) (options, error) {
+	var conf config
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			return defaultOptions(), err
+		}
+	}
+	opts, err := conf.compile()
+	if err != nil {
+		return defaultOptions(), err
+	}
+	return opts, nil
+}
+
+// expansionLimit limits a number of lines to expand to the most that are shown at once.
+func (o options) expansionLimit(n int) int {
+	if o.maxExpandedLines > 0 && n > o.maxExpandedLines {
+		return o.maxExpandedLines
+	}
+	return n
+}
+
+// canShowAll returns whether all of a log's lines can be shown at once.
+func (o options) canShowAll(lines int) bool {
+	return o.maxExpandedLines == 0 || lines <= o.maxExpandedLines
+}
+
+// split splits a log into sections, scrubbing secrets from their titles.
+func (o options) split(lines []string) []sectionBounds {
+	bounds := o.sections.split(lines)
+	for i := range bounds {
+		bounds[i].Title = o.secrets.scrub(bounds[i].Title)
+	}
+	return bounds
+}
+
+// defaultOptions returns the default configuration of the lens.
+func defaultOptions() options {
+	opts, _ := config{}.compile()
+	return opts
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// LogData is a log in the data the lens serves as JSON.
+type LogData struct {
+	Artifact string `json:"artifact"`
+	Link     string `json:"link"`
+	// Errors summarizes the failures in the log, if there are any.
+	Errors *lenses.ErrorSummary `json:"errors,omitempty"`
+	// Error is set if the log could not be read.
+	Error string `json:"error,omitempty"`
+}
+
+// Data returns a summary of the failures in each log shown. Logs are read a chunk at a time, so
+// the failures of logs too large to show in full are summarized too.
+func (lens Lens) Data(artifacts []lenses.Artifact, rawConfig json.RawMessage) (interface{}, error) {
+	opts, err := parseConfig(rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lens configuration: %v", err)
+	}
+	artifacts, siblings := lenses.SplitSiblings(artifacts)
+	artifacts = opts.artifacts.selectArtifacts(artifacts)
+	logs := []LogData{}
+	for _, a := range lenses.DecodeArtifacts(artifacts) {
+		log := LogData{Artifact: a.JobPath(), Link: a.CanonicalLink()}
+		if index := readLogIndex(a, siblings); index != nil {
+			log.Errors = scrubSummary(index.Summary(), opts.secrets)
+		} else if summary, err := lenses.SummarizeErrors(a, lenses.DefaultErrorPatterns); err != nil {
+			log.Error = err.Error()
+		} else {
+			log.Errors = scrubSummary(summary, opts.secrets)
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestLogData(t *testing.T) {
+	log := "Starting.\nERROR: bad password hunter2\nRetrying.\nFAIL: TestLogin\n"
+	artifacts := []lenses.Artifact{&largeArtifact{content: []byte(log)}}
+	config := json.RawMessage(`{"secret_patterns": ["hunter2"]}`)
+
+	data, err := Lens{}.Data(artifacts, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logs, ok := data.([]LogData)
+	if !ok || len(logs) != 1 {
+		t.Fatalf("expected the data of one log, got %#v", data)
+	}
+	summary := logs[0].Errors
+	if logs[0].Artifact != "build-log.txt" || summary == nil || logs[0].Error != "" {
+		t.Fatalf("expected a summary of the failures in build-log.txt, got %+v", logs[0])
+	}
+	if summary.Total != 2 || summary.First.Text != "ERROR: bad password [REDACTED]" || summary.Last.Line != 4 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	if _, err := (Lens{}).Data(artifacts, json.RawMessage(`{"secret_patterns": ["("]}`)); err == nil {
+		t.Error("expected an error for an invalid configuration")
+	}
+}
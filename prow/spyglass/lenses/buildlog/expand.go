@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+// defaultExpansionLines is the number of lines revealed by expanding a skipped block in a
+// direction, unless the request asks for another number.
+const defaultExpansionLines = 20
+
+// The directions skipped blocks can be expanded in. Expanding down reveals the lines at the top
+// of the block, following the lines shown above it, and expanding up reveals the lines at the
+// bottom of the block.
+const (
+	expandDown = "down"
+	expandUp   = "up"
+)
+
+// expandSkipped reveals count lines of a skipped block of a log in the given direction. It
+// returns the block split into the lines revealed and the lines still skipped, in order, with
+// their exact positions in the log. The whole block is revealed if too few lines would be left
+// skipped.
+func expandSkipped(group LineGroup, lines []LogLine, direction string, count int) []LineGroup {
+	if count <= 0 {
+		count = defaultExpansionLines
+	}
+	if len(lines)-count < minLinesSkipped {
+		group.Skip = false
+		group.LogLines = collapseRepeats(lines)
+		return []LineGroup{group}
+	}
+	split := count
+	if direction == expandUp {
+		split = len(lines) - count
+	}
+	splitOffset := 0
+	for _, line := range lines[:split] {
+		splitOffset += line.Length
+	}
+	first := LineGroup{
+		Start:      group.Start,
+		End:        group.Start + split,
+		ByteOffset: group.ByteOffset,
+		ByteLength: splitOffset - 1, // -1 for trailing newline
+		LogLines:   lines[:split],
+	}
+	second := LineGroup{
+		Start:      group.Start + split,
+		End:        group.End,
+		ByteOffset: group.ByteOffset + splitOffset,
+		ByteLength: group.ByteLength - splitOffset,
+		LogLines:   lines[split:],
+	}
+	if direction == expandUp {
+		first.Skip = true
+		second.LogLines = collapseRepeats(second.LogLines)
+	} else {
+		second.Skip = true
+		first.LogLines = collapseRepeats(first.LogLines)
+	}
+	return []LineGroup{first, second}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestExpandSkipped(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		// Lines differing only by numbers would be collapsed as repeats.
+		lines = append(lines, strings.Repeat("a", i))
+	}
+	log := strings.Join(lines, "\n") + "\n"
+	// The block skips lines 6 through 35.
+	start, end := 5, 35
+	offset := len(strings.Join(lines[:start], "\n")) + 1
+	block := strings.Join(lines[start:end], "\n")
+	skipped := LineGroup{Skip: true, Start: start, End: end, ByteOffset: offset, ByteLength: len(block)}
+
+	type group struct {
+		skip       bool
+		start, end int
+	}
+	testcases := []struct {
+		name      string
+		direction string
+		count     int
+		expected  []group
+	}{
+		{
+			name:      "expanding down reveals the first lines",
+			direction: expandDown,
+			count:     10,
+			expected:  []group{{false, 5, 15}, {true, 15, 35}},
+		},
+		{
+			name:      "expanding up reveals the last lines",
+			direction: expandUp,
+			count:     10,
+			expected:  []group{{true, 5, 25}, {false, 25, 35}},
+		},
+		{
+			name:      "expanding by default reveals the default number of lines",
+			direction: expandUp,
+			expected:  []group{{true, 5, 15}, {false, 15, 35}},
+		},
+		{
+			name:      "expanding reveals the whole block if few lines would be left",
+			direction: expandDown,
+			count:     28,
+			expected:  []group{{false, 5, 35}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := expandSkipped(skipped, highlightLines(strings.Split(block, "\n"), start, lineRenderer{}), tc.direction, tc.count)
+			if len(groups) != len(tc.expected) {
+				t.Fatalf("expected %d groups, got %+v", len(tc.expected), groups)
+			}
+			for i, g := range groups {
+				if actual := (group{g.Skip, g.Start, g.End}); actual != tc.expected[i] {
+					t.Errorf("group %d: expected %+v, got %+v", i, tc.expected[i], actual)
+				}
+				expected := strings.Join(lines[g.Start:g.End], "\n")
+				if actual := log[g.ByteOffset : g.ByteOffset+g.ByteLength]; actual != expected {
+					t.Errorf("group %d: expected bytes %q, got %q", i, expected, actual)
+				}
+				if !g.Skip && (g.LogLines[0].Number != g.Start+1 || len(g.LogLines) != g.End-g.Start) {
+					t.Errorf("group %d: expected lines %d to %d, got %+v", i, g.Start+1, g.End, g.LogLines)
+				}
+			}
+		})
+	}
+}
+
+// smallArtifact is an artifact small enough to read in full.
+type smallArtifact struct {
+	largeArtifact
+}
+
+func (a *smallArtifact) ReadAll() ([]byte, error) { return a.content, nil }
+
+func TestExpansionLimit(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, strings.Repeat("a", i))
+	}
+	log := strings.Join(lines, "\n") + "\n"
+	artifacts := []lenses.Artifact{&smallArtifact{largeArtifact{content: []byte(log)}}}
+	config := json.RawMessage(`{"max_expanded_lines": 30}`)
+
+	skipped := fmt.Sprintf(`{"artifact": "build-log.txt", "offset": 0, "length": %d, "startLine": 0}`, len(log)-1)
+	content := Lens{}.Callback(artifacts, ".", skipped, config)
+	if !strings.Contains(content, `class="show-skipped"`) || !strings.Contains(content, `data-start-line="30"`) {
+		t.Errorf("expected the block to be shown up to line 30 and skipped after it, got %s", content)
+	}
+
+	content = Lens{}.Callback(artifacts, ".", `{"artifact": "build-log.txt", "offset": 0, "length": -1}`, config)
+	if expected := "build-log.txt has 101 lines, more than the 30 that can be shown at once."; content != expected {
+		t.Errorf("expected showing all lines to be refused with %q, got %q", expected, content)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"io"
+	"strings"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// failurePatterns returns the error level regex as the pattern that failures are found with.
+func (d levelDetector) failurePatterns() []lenses.ErrorPattern {
+	return []lenses.ErrorPattern{{Name: levelError, Pattern: d.error}}
+}
+
+// markFailure marks the first of some log lines at the error level, and has the lines around it
+// shown. The text of each log line is the line of the same index. It returns the index of the
+// line, or -1 if there is none.
+func markFailure(logLines []LogLine, lines []string, levels levelDetector) int {
+	for i, line := range lines {
+		if levels.error.MatchString(line) {
+			markFailureAt(logLines, i)
+			return i
+		}
+	}
+	return -1
+}
+
+// markFailureAt marks a log line as the first failure, and has the lines around it shown.
+func markFailureAt(logLines []LogLine, i int) {
+	logLines[i].Failure = true
+	showAround(logLines, i)
+}
+
+// expandGapAtFailure finds the first failure in the gap of a truncated log, scanning the log
+// from its start, and reads the lines around it. It returns the gap left above them, or nil if
+// there is none, and the lines followed by the gap left below them, or nil if the gap has no
+// failure.
+func expandGapAtFailure(a lenses.Artifact, gap LogGap, levels levelDetector, renderer lineRenderer) (*LogGap, *GapExpansion, error) {
+	end := gap.Offset + gap.Length
+	failure, err := lenses.FirstError(a, levels.failurePatterns(), end)
+	if err != nil || failure == nil || failure.Offset < gap.Offset {
+		return nil, nil, err
+	}
+	above, expansion, err := expandGapAround(a, gap, *failure, renderer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return above, &expansion, nil
+}
+
+// expandGapAround reads the lines around a failure in the gap of a truncated log. It returns the
+// gap left above them, or nil if there is none, and the lines followed by the gap left below
+// them.
+func expandGapAround(a lenses.Artifact, gap LogGap, failure lenses.ErrorMatch, renderer lineRenderer) (*LogGap, GapExpansion, error) {
+	start, before, err := linesBefore(a, gap.Offset, failure.Offset, targetContextLines)
+	if err != nil {
+		return nil, GapExpansion{}, err
+	}
+	line := int(failure.Line)
+	rest := LogGap{Artifact: gap.Artifact, Offset: start, Length: gap.Offset + gap.Length - start, StartLine: line - before - 1}
+	expansion, err := expandGap(a, rest, before+1+targetContextLines, 0, line, renderer)
+	if err != nil {
+		return nil, GapExpansion{}, err
+	}
+	var above *LogGap
+	if start > gap.Offset {
+		above = &LogGap{Artifact: gap.Artifact, Offset: gap.Offset, Length: start - gap.Offset, StartLine: gap.StartLine}
+	}
+	return above, expansion, nil
+}
+
+// linesBefore finds up to n lines before offset, which begins a line, stopping at offset from. It
+// returns the offset they begin at and how many there are.
+func linesBefore(a lenses.Artifact, from, offset int64, n int) (int64, int, error) {
+	length := int64(n+1) * 128
+	for {
+		if length > offset-from {
+			length = offset - from
+		}
+		buf := make([]byte, length)
+		if _, err := a.ReadAt(buf, offset-length); err != nil && err != io.EOF {
+			return 0, 0, err
+		}
+		// The last line read ends at offset, so splitting leaves an empty line after it.
+		lines := strings.Split(string(buf), "\n")
+		lines = lines[:len(lines)-1]
+		// The first line read is cut off unless the read reached from, so it only counts then.
+		if len(lines) > n || length == offset-from {
+			if len(lines) > n {
+				lines = lines[len(lines)-n:]
+			}
+			before := int64(len(strings.Join(lines, "\n")))
+			if len(lines) > 0 {
+				before++
+			}
+			return offset - before, len(lines), nil
+		}
+		length *= 2
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncatedViewFailure(t *testing.T) {
+	testcases := []struct {
+		name string
+		// failure is the line that fails, whose number is not known if it is in the end of the log.
+		failure int
+		number  int
+	}{
+		{name: "failure in the beginning", failure: 50, number: 50},
+		{name: "failure in the gap", failure: 500, number: 500},
+		{name: "failure at the top of the gap", failure: 103, number: 103},
+		{name: "failure in the end", failure: 950},
+		{name: "no failure"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := numberedLines(1000)
+			if tc.failure > 0 {
+				lines[tc.failure-1] = "ERROR: something broke"
+			}
+			a := &largeArtifact{content: []byte(strings.Join(lines, "\n") + "\n")}
+			opts := defaultOptions()
+			opts.headLines, opts.tailLines = 100, 100
+			av, err := truncatedView(a, opts, lineRenderer{}, 0, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var failures []LogLine
+			var groups []LineGroup
+			for _, section := range av.Sections {
+				groups = append(groups, section.LineGroups...)
+			}
+			groups = append(groups, av.Tail.LineGroups...)
+			if av.Target != nil {
+				groups = append(groups, LineGroup{LogLines: av.Target.Lines})
+			}
+			for _, group := range groups {
+				for _, line := range group.LogLines {
+					if line.Failure {
+						failures = append(failures, line)
+					}
+				}
+			}
+			if tc.failure == 0 {
+				if len(failures) != 0 {
+					t.Errorf("expected no failure to be marked, got %+v", failures)
+				}
+				return
+			}
+			if len(failures) != 1 || failures[0].Number != tc.number {
+				t.Fatalf("expected line %d to be marked as the failure, got %+v", tc.failure, failures)
+			}
+			if tc.failure > 100 && tc.failure < 900 {
+				expected := tc.failure - targetContextLines
+				if expected <= 100 {
+					expected = 101
+				}
+				if first := av.Target.Lines[0].Number; first != expected {
+					t.Errorf("expected the lines around the failure to start at line %d, got %d", expected, first)
+				}
+				if (av.Gap == nil) != (expected == 101) {
+					t.Errorf("expected a gap above the lines around the failure: %t", expected > 101)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// maxFindMatches is the most matches a search of a log returns the lines of.
+const maxFindMatches = 1000
+
+// FindMatch is a line of a log that matched a search.
+type FindMatch struct {
+	// Line is the number of the line, counting from 1.
+	Line int `json:"line"`
+	// Offset is the byte offset the line starts at.
+	Offset int64 `json:"offset"`
+}
+
+// FindResult is the result of a search of a log: the number of lines that matched, and the first
+// maxFindMatches of them. Error is set instead if the search is invalid.
+type FindResult struct {
+	Total   int         `json:"total"`
+	Matches []FindMatch `json:"matches"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// findPattern compiles a search for text, which is found regardless of case unless it is a
+// regular expression.
+func findPattern(text string, regex bool) (*regexp.Regexp, error) {
+	if regex {
+		return regexp.Compile(text)
+	}
+	return regexp.Compile("(?i)" + regexp.QuoteMeta(text))
+}
+
+// findLines scans a log a chunk at a time for lines matching a pattern. Secrets are scrubbed from
+// lines before they are matched, so searches cannot find them.
+func findLines(a lenses.Artifact, pattern *regexp.Regexp, secrets *secretScrubber, max int) (FindResult, error) {
+	cr, err := lenses.NewChunkedReader(a, math.MaxInt64)
+	if err != nil {
+		return FindResult{}, err
+	}
+	r := bufio.NewReader(cr)
+	result := FindResult{Matches: []FindMatch{}}
+	var offset int64
+	for line := 1; ; line++ {
+		raw, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return FindResult{}, fmt.Errorf("error reading log: %v", err)
+		}
+		if raw == "" {
+			break
+		}
+		if pattern.MatchString(secrets.scrub(strings.TrimSuffix(raw, "\n"))) {
+			result.Total++
+			if len(result.Matches) < max {
+				result.Matches = append(result.Matches, FindMatch{Line: line, Offset: offset})
+			}
+		}
+		offset += int64(len(raw))
+		if err == io.EOF {
+			break
+		}
+	}
+	return result, nil
+}
+
+// find searches a log for text, returning the result as JSON.
+func find(a lenses.Artifact, text string, regex bool, secrets *secretScrubber) string {
+	var result FindResult
+	pattern, err := findPattern(text, regex)
+	if err != nil {
+		result.Error = fmt.Sprintf("Invalid regular expression: %v", err)
+	} else if result, err = findLines(a, pattern, secrets, maxFindMatches); err != nil {
+		result.Error = fmt.Sprintf("Failed to search log: %v", err)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(b)
+}
+
+// FindQuery is a search of a log that is kept when the page is updated to jump to one of its
+// matches, which is the Match-th.
+type FindQuery struct {
+	Text  string
+	Regex bool
+	Match int
+}
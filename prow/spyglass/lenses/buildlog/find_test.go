@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	content := "Starting.\nERROR: first failure\nRetrying.\nerror: second failure\npassword=hunter2\n"
+	scrubber, err := config{SecretPatterns: []string{`hunter2`}}.secretScrubber()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		name     string
+		text     string
+		regex    bool
+		expected FindResult
+	}{
+		{
+			name: "text is found regardless of case",
+			text: "error",
+			expected: FindResult{Total: 2, Matches: []FindMatch{
+				{Line: 2, Offset: 10},
+				{Line: 4, Offset: 41},
+			}},
+		},
+		{
+			name:     "regular expressions",
+			text:     `^ERROR: \w+`,
+			regex:    true,
+			expected: FindResult{Total: 1, Matches: []FindMatch{{Line: 2, Offset: 10}}},
+		},
+		{
+			name:     "text is not taken as a regular expression",
+			text:     "fail.re",
+			expected: FindResult{Matches: []FindMatch{}},
+		},
+		{
+			name:     "secrets are not found",
+			text:     "hunter2",
+			expected: FindResult{Matches: []FindMatch{}},
+		},
+		{
+			name:     "invalid regular expressions",
+			text:     "(",
+			regex:    true,
+			expected: FindResult{Error: "Invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &largeArtifact{content: []byte(content)}
+			var actual FindResult
+			if err := json.Unmarshal([]byte(find(a, tc.text, tc.regex, scrubber)), &actual); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFindLinesLimitsMatches(t *testing.T) {
+	a := &largeArtifact{content: []byte(strings.Repeat("match\n", 5))}
+	pattern, err := findPattern("match", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := findLines(a, pattern, nil, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 5 || len(result.Matches) != 2 {
+		t.Errorf("expected 2 of 5 matches, got %d of %d", len(result.Matches), result.Total)
+	}
+}
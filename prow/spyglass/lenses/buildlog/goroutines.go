@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"strings"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// minDumpGoroutines is the fewest goroutines a dump has to have to be summarized. The stacks
+	// of panics in one goroutine are folded into tracebacks instead.
+	minDumpGoroutines = 2
+	// maxDumpGroups is the most groups of goroutines listed for each dump.
+	maxDumpGroups = 10
+)
+
+// GoroutineSummary is a goroutine dump in a log, with its goroutines grouped by state and stack.
+type GoroutineSummary struct {
+	*lenses.GoroutineDump
+	Total  int
+	Groups []*lenses.GoroutineGroup
+	// More is the number of groups not listed.
+	More int
+}
+
+// summarizeGoroutines summarizes the goroutine dumps in the lines of a log, scrubbing secrets from
+// the panics that caused them.
+func summarizeGoroutines(lines []string, secrets *secretScrubber) []GoroutineSummary {
+	dumps, err := lenses.ParseGoroutineDumps(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return nil
+	}
+	var summaries []GoroutineSummary
+	for _, d := range dumps {
+		if len(d.Goroutines) < minDumpGoroutines {
+			continue
+		}
+		d.Panic = secrets.scrub(d.Panic)
+		s := GoroutineSummary{GoroutineDump: d, Total: len(d.Goroutines), Groups: d.Groups()}
+		if len(s.Groups) > maxDumpGroups {
+			s.Groups, s.More = s.Groups[:maxDumpGroups], len(s.Groups)-maxDumpGroups
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeGoroutines(t *testing.T) {
+	lines := []string{
+		"panic: test timed out after 10m0s",
+		"",
+	}
+	for i := 1; i <= 12; i++ {
+		// Twelve kinds of goroutines, the last of which has the most.
+		for j := 0; j < i; j++ {
+			lines = append(lines,
+				fmt.Sprintf("goroutine %d [select]:", i*100+j),
+				fmt.Sprintf("example.com/x.worker%d(0xc000001)", i),
+				"\t/go/src/example.com/x/worker.go:20 +0x45",
+				"")
+		}
+	}
+	lines = append(lines, "FAIL\texample.com/x\t600.012s", "", "goroutine 1 [running]:", "main.main()", "\t/tmp/x.go:5 +0x4e")
+
+	summaries := summarizeGoroutines(lines, &secretScrubber{})
+	if len(summaries) != 1 {
+		t.Fatalf("expected only the dump of more than one goroutine, got %d", len(summaries))
+	}
+	s := summaries[0]
+	if s.Line != 3 || s.Total != 78 || s.Panic != "panic: test timed out after 10m0s" {
+		t.Errorf("expected 78 goroutines from line 3 after the panic, got %d from line %d after %q", s.Total, s.Line, s.Panic)
+	}
+	if len(s.Groups) != maxDumpGroups || s.More != 2 {
+		t.Fatalf("expected %d groups listed and 2 more, got %d and %d more", maxDumpGroups, len(s.Groups), s.More)
+	}
+	if f := s.Groups[0].Function(); f != "example.com/x.worker12" || s.Groups[0].Count() != 12 {
+		t.Errorf("expected the largest group first, got %d in %s", s.Groups[0].Count(), f)
+	}
+	if !strings.HasPrefix(s.Groups[maxDumpGroups-1].Function(), "example.com/x.worker3") {
+		t.Errorf("expected the smallest groups not to be listed, got %s last", s.Groups[maxDumpGroups-1].Function())
+	}
+}
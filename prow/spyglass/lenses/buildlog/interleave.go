@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// containerColors is the number of colors containers are told apart by.
+const containerColors = 6
+
+// bodyRequest is the data Body is rendered with.
+type bodyRequest struct {
+	// Interleave merges the logs of several containers into one in the order they were logged.
+	Interleave bool `json:"interleave,omitempty"`
+	// Line is the line a permalink targets, numbered from 1, in Artifact or the first log if
+	// Artifact is unset.
+	Artifact string `json:"artifact,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	// Section is the anchor of the title of the section to show, in the first log that has it.
+	Section string `json:"section,omitempty"`
+	// Raw shows logs of JSON lines as lines instead of records.
+	Raw bool `json:"raw,omitempty"`
+	// Find, Regex and Match restore the search of the targeted log a jump to a line came from.
+	Find  string `json:"find,omitempty"`
+	Regex bool   `json:"regex,omitempty"`
+	Match int    `json:"match,omitempty"`
+}
+
+// ContainerLegend names a container whose lines are shown in a color.
+type ContainerLegend struct {
+	Name  string
+	Color int
+}
+
+// InterleavedView holds the lines of the logs of several containers merged in the order they
+// were logged.
+type InterleavedView struct {
+	Containers []ContainerLegend
+	Lines      []LogLine
+}
+
+// containerLog is the log of a container.
+type containerLog struct {
+	name     string
+	lines    []string
+	logLines []LogLine
+}
+
+// containerName returns the name of the container an artifact is the log of. Decorated jobs log
+// their test container to build-log.txt.
+func containerName(artifact string) string {
+	name := path.Base(artifact)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "build-log" {
+		return "test"
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(name, "-build-log"), "-log")
+}
+
+// logTimes returns the time each line of a log was logged at. Lines without a timestamp are
+// taken to have been logged with the line before them, or with the first timestamped line if
+// none comes before them. Timestamps without a year are given the year passed.
+func logTimes(lines []string, year int) []time.Time {
+	times := make([]time.Time, len(lines))
+	var last time.Time
+	first := -1
+	for i, line := range lines {
+		if t, ok := lineTimestamp(line); ok {
+			if t.Year() == 0 {
+				t = t.AddDate(year, 0, 0)
+			}
+			last = t
+			if first == -1 {
+				first = i
+			}
+		}
+		times[i] = last
+	}
+	for i := 0; i < first; i++ {
+		times[i] = times[first]
+	}
+	return times
+}
+
+// logYear returns the year of the first timestamp with a year in any of the logs, which klog
+// timestamps are assumed to share.
+func logYear(logs []containerLog) int {
+	for _, l := range logs {
+		for _, line := range l.lines {
+			if t, ok := lineTimestamp(line); ok && t.Year() != 0 {
+				return t.Year()
+			}
+		}
+	}
+	return 0
+}
+
+// interleave merges the logs of several containers in the order their lines were logged. The
+// order of lines within each log is kept, and lines logged at the same time are ordered by log.
+func interleave(logs []containerLog) InterleavedView {
+	var view InterleavedView
+	year := logYear(logs)
+	times := make([][]time.Time, len(logs))
+	for i, l := range logs {
+		view.Containers = append(view.Containers, ContainerLegend{Name: l.name, Color: i % containerColors})
+		times[i] = logTimes(l.lines, year)
+	}
+	next := make([]int, len(logs))
+	for {
+		pick := -1
+		for i := range logs {
+			if next[i] >= len(logs[i].logLines) {
+				continue
+			}
+			if pick == -1 || times[i][next[i]].Before(times[pick][next[pick]]) {
+				pick = i
+			}
+		}
+		if pick == -1 {
+			break
+		}
+		line := logs[pick].logLines[next[pick]]
+		line.Container = logs[pick].name
+		line.ContainerColor = pick % containerColors
+		line.Skip = false
+		view.Lines = append(view.Lines, line)
+		next[pick]++
+	}
+	view.Lines = collapseRepeats(view.Lines)
+	return view
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainerName(t *testing.T) {
+	for artifact, expected := range map[string]string{
+		"build-log.txt":                   "test",
+		"artifacts/sidecar-build-log.txt": "sidecar",
+		"artifacts/initupload-log.txt":    "initupload",
+		"pod-log":                         "pod",
+	} {
+		if actual := containerName(artifact); actual != expected {
+			t.Errorf("expected container %q for %s, got %q", expected, artifact, actual)
+		}
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	test := []string{
+		"I0102 15:04:05.000000 1 main.go:1] test start",
+		"untimed test output",
+		"I0102 15:04:09.000000 1 main.go:1] test end",
+	}
+	sidecar := []string{
+		"early untimed sidecar output",
+		`{"level":"info","msg":"waiting","time":"2019-01-02T15:04:06Z"}`,
+		`{"level":"info","msg":"done","time":"2019-01-02T15:04:09Z"}`,
+		`time="2019-01-02T15:04:10Z" level=info msg="uploaded"`,
+	}
+	logs := []containerLog{
+		{name: "test", lines: test, logLines: highlightLines(test, 0, lineRenderer{})},
+		{name: "sidecar", lines: sidecar, logLines: highlightLines(sidecar, 0, lineRenderer{})},
+	}
+	view := interleave(logs)
+
+	expectedContainers := []ContainerLegend{{Name: "test"}, {Name: "sidecar", Color: 1}}
+	if !reflect.DeepEqual(view.Containers, expectedContainers) {
+		t.Errorf("expected containers %+v, got %+v", expectedContainers, view.Containers)
+	}
+	type source struct {
+		Container string
+		Number    int
+	}
+	expected := []source{
+		{"test", 1},
+		{"test", 2},
+		{"sidecar", 1},
+		{"sidecar", 2},
+		{"test", 3},
+		{"sidecar", 3},
+		{"sidecar", 4},
+	}
+	var actual []source
+	for _, line := range view.Lines {
+		actual = append(actual, source{line.Container, line.Number})
+		if line.Skip {
+			t.Errorf("expected line %d of %s not to be skipped", line.Number, line.Container)
+		}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected lines %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+// junitRE matches the names of the JUnit reports that the junit lens shows, such as
+// junit_01.xml.
+var junitRE = regexp.MustCompile(`^junit.*\.xml$`)
+
+// junitFailures returns the failed tests in the JUnit reports among the job's artifacts.
+func junitFailures(siblings []*lenses.SiblingArtifact) []junit.Result {
+	var failed []junit.Result
+	for _, s := range siblings {
+		if !junitRE.MatchString(path.Base(s.JobPath())) {
+			continue
+		}
+		contents, err := s.ReadAll()
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", s.CanonicalLink()).Info("Error reading JUnit report.")
+			continue
+		}
+		suites, err := junit.Parse(contents)
+		if err != nil {
+			continue
+		}
+		for _, suite := range suites.Suites {
+			for _, test := range suite.Results {
+				if test.Failure != nil {
+					failed = append(failed, test)
+				}
+			}
+		}
+	}
+	return failed
+}
+
+// markJunitFailures marks the lines of a log that the messages of failed tests were logged on,
+// which link back to the tests in the junit lens, and shows them. The text of each log line is
+// the line of the same index.
+func markJunitFailures(logLines []LogLine, lines []string, failed []junit.Result) {
+	if len(failed) == 0 {
+		return
+	}
+	var messages []string
+	for _, test := range failed {
+		messages = append(messages, *test.Failure)
+	}
+	found := lenses.FindFailureLines(lines, messages)
+	for _, test := range failed {
+		location, ok := found[*test.Failure]
+		if !ok {
+			continue
+		}
+		line := &logLines[location.Line-1]
+		line.JunitTests = append(line.JunitTests, test.Name)
+		line.Skip = false
+	}
+}
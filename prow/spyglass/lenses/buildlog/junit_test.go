@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestMarkJunitFailures(t *testing.T) {
+	report := `<testsuites><testsuite name="x">
+  <testcase name="TestA"><failure>a_test.go:12: expected 3 widgets, got 2</failure></testcase>
+  <testcase name="TestB"><failure>b_test.go:1: this was never logged at all</failure></testcase>
+  <testcase name="TestC"></testcase>
+</testsuite></testsuites>`
+	siblings := []*lenses.SiblingArtifact{
+		lenses.NewSiblingArtifact(namedArtifact{&smallArtifact{largeArtifact{content: []byte(report)}}, "artifacts/junit_01.xml"}),
+		lenses.NewSiblingArtifact(namedArtifact{&smallArtifact{largeArtifact{content: []byte(report)}}, "artifacts/report.xml"}),
+	}
+	failed := junitFailures(siblings)
+	if len(failed) != 2 || failed[0].Name != "TestA" || failed[1].Name != "TestB" {
+		t.Fatalf("expected the failed tests of the JUnit report only, got %+v", failed)
+	}
+
+	lines := strings.Split("=== RUN   TestA\n    a_test.go:12: expected 3 widgets, got 2\n--- FAIL: TestA (0.01s)", "\n")
+	logLines := highlightLines(lines, 0, lineRenderer{})
+	markJunitFailures(logLines, lines, failed)
+	for i, line := range logLines {
+		var expected []string
+		if i == 1 {
+			expected = []string{"TestA"}
+		}
+		if !reflect.DeepEqual(line.JunitTests, expected) {
+			t.Errorf("expected line %d to link to %v, got %v", line.Number, expected, line.JunitTests)
+		}
+		if line.Skip == (i == 1) {
+			t.Errorf("expected only the line of the failure to be shown, got line %d shown: %t", line.Number, !line.Skip)
+		}
+	}
+}
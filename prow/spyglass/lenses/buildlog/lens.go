@@ -26,9 +26,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
 )
 
 const (
@@ -49,11 +51,12 @@ func (lens Lens) Config() lenses.LensConfig {
 		Name:     name,
 		Title:    title,
 		Priority: priority,
+		Siblings: true,
 	}
 }
 
 // Header executes the "header" section of the template.
-func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
 	return executeTemplate(resourceDir, "header", BuildLogsView{})
 }
 
@@ -64,10 +67,13 @@ func init() {
 	lenses.RegisterLens(Lens{})
 }
 
-// SubLine represents an substring within a LogLine. It it used so error terms can be highlighted.
+// SubLine represents an substring within a LogLine. It it used so error terms can be highlighted
+// and references to source files linked. HTML is the text with its ANSI colors and styles.
 type SubLine struct {
 	Highlighted bool
 	Text        string
+	HTML        template.HTML
+	Link        string
 }
 
 // LogLine represents a line displayed in the LogArtifactView.
@@ -77,6 +83,32 @@ type LogLine struct {
 	Highlighted bool
 	Skip        bool
 	SubLines    []SubLine
+	// Container is set when the logs of several containers are interleaved.
+	Container      string
+	ContainerColor int
+	// Repeats is the number of repeats of the line that were collapsed into it, the last of
+	// which is line LastRepeat.
+	Repeats    int
+	LastRepeat int
+	// Target is set on the line a permalink targets.
+	Target bool
+	// Failure is set on the first line at the error level of a log that no permalink targets,
+	// which the page is scrolled to.
+	Failure bool
+	// Timed is set on lines with timestamps, which were logged Elapsed after the log started and
+	// Delta after the timestamped line before them.
+	Timed   bool
+	Elapsed time.Duration
+	Delta   time.Duration
+	// Traceback is set on the first line of a Go panic, Python traceback or Java stack trace,
+	// whose lines have InTraceback set. TopFrame is set on the lines of the frame triagers look
+	// at first, and TracebackDetail on the lines hidden while the traceback is folded.
+	Traceback       *Traceback
+	InTraceback     bool
+	TopFrame        bool
+	TracebackDetail bool
+	// JunitTests are the failed JUnit tests whose failure messages were logged on the line.
+	JunitTests []string
 }
 
 // LineGroup holds multiple lines that can be collapsed/expanded as a block
@@ -87,13 +119,54 @@ type LineGroup struct {
 	LogLines               []LogLine
 }
 
+// LogSection is a part of a log begun by a group marker or a gap in timestamps, which can be
+// folded away. Lines outside of any such part form untitled sections that cannot be folded.
+type LogSection struct {
+	Artifact   string
+	Title      string
+	Start, End int // closed, open
+	Folded     bool
+	LineGroups []LineGroup
+	// Duration is how long the lines of the section took to log, if they have timestamps.
+	Duration string
+	// Target is set on the section a link to the lens's view shows.
+	Target bool
+}
+
+// Lines returns the number of lines in a section.
+func (s LogSection) Lines() int {
+	return s.End - s.Start
+}
+
+// Anchor returns the anchor that links to the section name it by.
+func (s LogSection) Anchor() string {
+	return lenses.Anchor(s.Title)
+}
+
 // LineRequest represents a request for output lines from an artifact. If Offset is 0 and Length
-// is -1, all lines will be fetched.
+// is -1, all lines will be fetched. If EndLine is set, the lines after StartLine up to and
+// including EndLine are fetched instead of a range of bytes. If Level is set, only the lines of
+// the whole log at that level or a more severe one are fetched. If Direction is set, the range
+// of bytes is a skipped block, of which Count lines are revealed in that direction. If Gap is set,
+// the range of bytes is the gap in a truncated log, the lines at the top of which are fetched. If
+// Structured is set, the records of a log of JSON lines selected by Filter are fetched. If Live is
+// set, the lines written to a live log after Offset are fetched. If Find is set, the lines
+// matching it, as a regular expression if Regex is set, are found and returned as JSON.
 type LineRequest struct {
-	Artifact  string `json:"artifact"`
-	Offset    int64  `json:"offset"`
-	Length    int64  `json:"length"`
-	StartLine int    `json:"startLine"`
+	Artifact   string `json:"artifact"`
+	Offset     int64  `json:"offset"`
+	Length     int64  `json:"length"`
+	StartLine  int    `json:"startLine"`
+	EndLine    int64  `json:"endLine,omitempty"`
+	Level      string `json:"level,omitempty"`
+	Direction  string `json:"direction,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Gap        bool   `json:"gap,omitempty"`
+	Structured bool   `json:"structured,omitempty"`
+	Filter     string `json:"filter,omitempty"`
+	Live       bool   `json:"live,omitempty"`
+	Find       string `json:"find,omitempty"`
+	Regex      bool   `json:"regex,omitempty"`
 }
 
 // LinesSkipped returns the number of lines skipped in a line group.
@@ -105,59 +178,254 @@ func (g LineGroup) LinesSkipped() int {
 type LogArtifactView struct {
 	ArtifactName string
 	ArtifactLink string
-	LineGroups   []LineGroup
+	Sections     []LogSection
 	ViewAll      bool
+	// Gap and Tail are set when the log is too large to read in full, so that Sections are
+	// only its beginning. Target is set when a permalink targets a line in the gap, or the
+	// first failure is in it, which is split around the lines shown.
+	Gap    *LogGap
+	Target *GapExpansion
+	Tail   *LogSection
+	// Structured is set when the log is JSON lines, which are shown as records instead of
+	// Sections. CanStructure is set when they are shown as lines instead.
+	Structured   *StructuredView
+	CanStructure bool
+	// Live is set when the log is still being written, so that lines are added as they are.
+	Live *LiveTail
+	// Find is the search of the log to restore, if any.
+	Find *FindQuery
+	// Errors summarizes the failures in the log, if there are any.
+	Errors *lenses.ErrorSummary
+	// Goroutines summarizes the goroutine dumps in the log.
+	Goroutines []GoroutineSummary
 }
 
 // BuildLogsView holds each log file view
 type BuildLogsView struct {
+	Error              string
 	LogViews           []LogArtifactView
+	CanInterleave      bool
+	Interleaved        *InterleavedView
 	RawGetAllRequests  map[string]string
 	RawGetMoreRequests map[string]string
 }
 
 // Body returns the <body> content for a build log (or multiple build logs)
-func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
 	buildLogsView := BuildLogsView{
 		LogViews:           []LogArtifactView{},
 		RawGetAllRequests:  make(map[string]string),
 		RawGetMoreRequests: make(map[string]string),
 	}
 
+	opts, err := parseConfig(rawConfig)
+	if err != nil {
+		buildLogsView.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+	}
+
+	var request bodyRequest
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &request); err != nil {
+			logrus.WithError(err).Info("Ignoring invalid build log request.")
+		}
+	}
+
+	renderer := lineRenderer{linker: lenses.ReadSourceLinker(artifacts), secrets: opts.secrets}
+	artifacts, siblings := lenses.SplitSiblings(artifacts)
+	artifacts = opts.artifacts.selectArtifacts(artifacts)
+	artifacts = lenses.DecodeArtifacts(lenses.AttachLineIndexes(artifacts, siblings))
+	buildLogsView.CanInterleave = len(artifacts) > 1
+	targetArtifact := request.Artifact
+	if targetArtifact == "" && len(artifacts) > 0 {
+		targetArtifact = artifacts[0].JobPath()
+	}
+
 	// Read log artifacts and construct template structs
+	var logs []containerLog
+	var failedTests []junit.Result
+	anchored := false
 	for _, a := range artifacts {
 		av := LogArtifactView{
 			ArtifactName: a.JobPath(),
 			ArtifactLink: a.CanonicalLink(),
 		}
+		target := 0
+		var query *FindQuery
+		if a.JobPath() == targetArtifact {
+			target = request.Line
+			if request.Find != "" {
+				query = &FindQuery{Text: request.Find, Regex: request.Regex, Match: request.Match}
+			}
+		}
+		index := readLogIndex(a, siblings)
 		lines, err := logLinesAll(a)
+		if err == nil && opts.maxLines > 0 && len(lines) > opts.maxLines {
+			err = lenses.ErrFileTooLarge
+		}
+		if err == lenses.ErrFileTooLarge {
+			av, err = truncatedView(a, opts, renderer, target, index)
+			if err == errLineNotFound {
+				buildLogsView.Error = fmt.Sprintf("Line %d is not in %s, or is too far into it to be numbered.", target, a.JobPath())
+				av, err = truncatedView(a, opts, renderer, 0, index)
+			}
+			if err != nil {
+				logrus.WithError(err).Info("Error reading truncated log.")
+				continue
+			}
+			av.Find = query
+			buildLogsView.LogViews = append(buildLogsView.LogViews, av)
+			continue
+		}
 		if err != nil {
 			logrus.WithError(err).Info("Error reading log.")
 			continue
 		}
-		av.LineGroups = groupLines(highlightLines(lines, 0))
-		av.ViewAll = true
+		lines, av.Live = liveTail(a, lines)
+		logLines := highlightLines(lines, 0, renderer)
+		annotateTimes(logLines, lines, time.Time{}, time.Time{})
+		failure := 0
+		if target > 0 && !markTarget(logLines, target) {
+			buildLogsView.Error = fmt.Sprintf("Line %d is not in %s.", target, a.JobPath())
+		} else if target == 0 && index != nil {
+			failure = markIndexedFailure(logLines, index)
+		} else if target == 0 {
+			failure = markFailure(logLines, lines, opts.levels) + 1
+		}
+		if a.JobPath() == lenses.BuildLogPath {
+			if failedTests == nil {
+				failedTests = junitFailures(siblings)
+			}
+			markJunitFailures(logLines, lines, failedTests)
+		}
+		if request.Interleave && buildLogsView.CanInterleave {
+			logs = append(logs, containerLog{name: containerName(a.JobPath()), lines: lines, logLines: logLines})
+			continue
+		}
+		if len(lines) <= maxStructuredLines && isStructured(lines) {
+			if request.Raw {
+				av.CanStructure = true
+			} else {
+				records := parseStructured(lines, renderer.secrets)
+				markRecordTarget(records, target)
+				av.Structured = &StructuredView{Records: records}
+				buildLogsView.LogViews = append(buildLogsView.LogViews, av)
+				continue
+			}
+		}
+		bounds := opts.splitIndexed(lines, index)
+		av.Sections = sectionLines(a.JobPath(), logLines, bounds, false)
+		if request.Section != "" && !anchored {
+			if i := anchoredSection(bounds, request.Section); i >= 0 {
+				showSection(av.Sections, i, a.JobPath(), logLines, bounds, opts)
+				anchored = true
+			}
+		}
+		setIndexedDurations(av.Sections, index)
+		unfoldTarget(av.Sections, target)
+		unfoldTarget(av.Sections, failure)
+		av.ViewAll = opts.canShowAll(len(lines))
+		av.Find = query
+		if index != nil {
+			av.Errors = scrubSummary(index.Summary(), opts.secrets)
+		} else {
+			av.Errors = summarizeErrors(lines, opts.secrets)
+		}
+		av.Goroutines = summarizeGoroutines(lines, opts.secrets)
 		buildLogsView.LogViews = append(buildLogsView.LogViews, av)
 	}
+	if len(logs) > 0 {
+		interleaved := interleave(logs)
+		buildLogsView.Interleaved = &interleaved
+	}
 
 	return executeTemplate(resourceDir, "body", buildLogsView)
 }
 
 // Callback is used to retrieve new log segments
-func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
 	var request LineRequest
 	err := json.Unmarshal([]byte(data), &request)
 	if err != nil {
 		return "failed to unmarshal request"
 	}
-	artifact, ok := artifactByName(artifacts, request.Artifact)
+	// Body reports invalid configuration.
+	opts, _ := parseConfig(rawConfig)
+	renderer := lineRenderer{linker: lenses.ReadSourceLinker(artifacts), secrets: opts.secrets}
+	artifacts, siblings := lenses.SplitSiblings(artifacts)
+	artifact, ok := artifactByName(lenses.AttachLineIndexes(artifacts, siblings), request.Artifact)
 	if !ok {
 		return "no artifact named " + request.Artifact
 	}
+	artifact = lenses.DecodeArtifact(artifact)
 
 	var lines []string
-	if request.Offset == 0 && request.Length == -1 {
+	if request.Find != "" {
+		return find(artifact, request.Find, request.Regex, opts.secrets)
+	} else if request.Live {
+		view, err := readLiveTail(artifact, LiveTail{
+			Artifact:  request.Artifact,
+			Offset:    request.Offset,
+			StartLine: request.StartLine,
+		}, renderer)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve log lines: %v", err)
+		}
+		return executeTemplate(resourceDir, "live lines", view)
+	} else if request.Structured {
+		lines, err = logLinesAll(artifact)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve log lines: %v", err)
+		}
+		records := parseFieldFilter(request.Filter).filter(parseStructured(lines, opts.secrets))
+		return executeTemplate(resourceDir, "structured records", records)
+	} else if request.Gap {
+		expansion, err := expandGap(artifact, LogGap{
+			Artifact:  request.Artifact,
+			Offset:    request.Offset,
+			Length:    request.Length,
+			StartLine: request.StartLine,
+		}, opts.expansionLimit(opts.gapExpansionLines), 0, 0, renderer)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve log lines: %v", err)
+		}
+		return executeTemplate(resourceDir, "gap expansion", expansion)
+	} else if request.Level != "" {
+		if request.Level != levelError && request.Level != levelWarning {
+			return "unknown level " + request.Level
+		}
+		lines, err = logLinesAll(artifact)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve log lines: %v", err)
+		}
+		logLines := highlightLines(lines, 0, renderer)
+		annotateTimes(logLines, lines, time.Time{}, time.Time{})
+		return executeTemplate(resourceDir, "filtered", FilteredView{
+			Level: request.Level,
+			Lines: opts.levels.filter(logLines, lines, request.Level),
+		})
+	} else if request.EndLine > 0 {
+		if limit := int64(request.StartLine + opts.expansionLimit(int(request.EndLine)-request.StartLine)); request.EndLine > limit {
+			request.EndLine = limit
+		}
+		lines, err = lenses.LineRange(artifact, int64(request.StartLine)+1, request.EndLine)
+		// The lines are annotated with times since the lines before them, if they can be found.
+		if offset, err := lenses.LineOffset(artifact, int64(request.StartLine)+1); err == nil {
+			request.Offset = offset
+		}
+	} else if request.Offset == 0 && request.Length == -1 {
 		lines, err = logLinesAll(artifact)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve log lines: %v", err)
+		}
+		if !opts.canShowAll(len(lines)) {
+			return fmt.Sprintf("%s has %d lines, more than the %d that can be shown at once.", artifact.JobPath(), len(lines), opts.maxExpandedLines)
+		}
+		av := LogArtifactView{ArtifactName: artifact.JobPath()}
+		lines, av.Live = liveTail(artifact, lines)
+		logLines := highlightLines(lines, 0, renderer)
+		annotateTimes(logLines, lines, time.Time{}, time.Time{})
+		av.Sections = sectionLines(artifact.JobPath(), logLines, opts.split(lines), true)
+		return executeTemplate(resourceDir, "sections", av)
 	} else {
 		lines, err = logLines(artifact, request.Offset, request.Length)
 	}
@@ -165,8 +433,31 @@ func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data
 		return fmt.Sprintf("failed to retrieve log lines: %v", err)
 	}
 
-	logLines := highlightLines(lines, request.StartLine)
-	return executeTemplate(resourceDir, "line group", logLines)
+	logLines := highlightLines(lines, request.StartLine, renderer)
+	start, prev := timesBefore(artifact, request.Offset)
+	annotateTimes(logLines, lines, start, prev)
+	if request.EndLine == 0 && request.Direction == "" {
+		// All of a skipped block is shown, unless it is more than can be shown at once.
+		request.Direction, request.Count = expandDown, len(lines)
+	}
+	if request.Direction == expandDown || request.Direction == expandUp {
+		if request.Count <= 0 {
+			request.Count = defaultExpansionLines
+		}
+		request.Count = opts.expansionLimit(request.Count)
+		group := LineGroup{
+			Skip:       true,
+			Start:      request.StartLine,
+			End:        request.StartLine + len(lines),
+			ByteOffset: int(request.Offset),
+			ByteLength: int(request.Length),
+		}
+		return executeTemplate(resourceDir, "line groups", LogSection{
+			Artifact:   request.Artifact,
+			LineGroups: expandSkipped(group, logLines, request.Direction, request.Count),
+		})
+	}
+	return executeTemplate(resourceDir, "line group", collapseRepeats(logLines))
 }
 
 func artifactByName(artifacts []lenses.Artifact, name string) (lenses.Artifact, bool) {
@@ -178,9 +469,13 @@ func artifactByName(artifacts []lenses.Artifact, name string) (lenses.Artifact,
 	return nil, false
 }
 
-// logLinesAll reads all of an artifact and splits it into lines.
+// logLinesAll reads all of an artifact and splits it into lines. It returns ErrFileTooLarge
+// unwrapped if the artifact is too large to read in full.
 func logLinesAll(artifact lenses.Artifact) ([]string, error) {
 	read, err := artifact.ReadAll()
+	if err == lenses.ErrFileTooLarge {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read log %q: %v", artifact.JobPath(), err)
 	}
@@ -205,38 +500,58 @@ func logLines(artifact lenses.Artifact, offset, length int64) ([]string, error)
 	return strings.Split(string(b), "\n"), nil
 }
 
-func highlightLines(lines []string, startLine int) []LogLine {
+func highlightLines(lines []string, startLine int, renderer lineRenderer) []LogLine {
 	// mark highlighted lines
 	logLines := make([]LogLine, 0, len(lines))
 	for i, text := range lines {
 		length := len(text)
+		// Lengths are of the lines as logged, so secrets are only scrubbed from what is shown.
+		text = renderer.secrets.scrub(text)
 		subLines := []SubLine{}
+		highlighted := false
 		if length <= maxHighlightLength {
 			loc := errRE.FindStringIndex(text)
 			for loc != nil {
-				subLines = append(subLines, SubLine{false, text[:loc[0]]})
-				subLines = append(subLines, SubLine{true, text[loc[0]:loc[1]]})
+				subLines = append(subLines, linkSubLines(text[:loc[0]], renderer.linker)...)
+				subLines = append(subLines, SubLine{Highlighted: true, Text: text[loc[0]:loc[1]]})
+				highlighted = true
 				text = text[loc[1]:]
 				loc = errRE.FindStringIndex(text)
 			}
+			subLines = append(subLines, linkSubLines(text, renderer.linker)...)
+		} else {
+			subLines = append(subLines, SubLine{Text: text})
+		}
+		var converter lenses.ANSIConverter
+		for j := range subLines {
+			subLines[j].HTML = converter.Convert(subLines[j].Text)
 		}
-		subLines = append(subLines, SubLine{false, text})
 		logLines = append(logLines, LogLine{
 			Length:      length + 1, // counting the "\n"
 			SubLines:    subLines,
 			Number:      startLine + i + 1,
-			Highlighted: len(subLines) > 1,
+			Highlighted: highlighted,
 			Skip:        true,
 		})
 	}
+	markTracebacks(logLines, lines)
 	return logLines
 }
 
+// linkSubLines splits unhighlighted text into sublines, linking references to source files.
+func linkSubLines(text string, linker *lenses.SourceLinker) []SubLine {
+	var subLines []SubLine
+	for _, s := range linker.Split(text) {
+		subLines = append(subLines, SubLine{Text: s.Text, Link: s.Link})
+	}
+	return subLines
+}
+
 // breaks lines into important/unimportant groups
 func groupLines(logLines []LogLine) []LineGroup {
-	// show highlighted lines and their neighboring lines
+	// show highlighted lines, lines after hangs and their neighboring lines
 	for i, line := range logLines {
-		if line.Highlighted {
+		if line.Highlighted || line.Hang() {
 			for d := -neighborLines; d <= neighborLines; d++ {
 				if i+d < 0 {
 					continue
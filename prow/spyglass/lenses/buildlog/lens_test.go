@@ -17,7 +17,11 @@ limitations under the License.
 package buildlog
 
 import (
+	"reflect"
 	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
 func TestGroupLines(t *testing.T) {
@@ -172,7 +176,7 @@ func TestGroupLines(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := groupLines(highlightLines(test.lines, 0))
+			got := groupLines(highlightLines(test.lines, 0, lineRenderer{}))
 			if len(got) != len(test.groups) {
 				t.Fatalf("Expected %d groups, got %d", len(test.groups), len(got))
 			}
@@ -193,3 +197,18 @@ func TestGroupLines(t *testing.T) {
 		})
 	}
 }
+
+func TestHighlightLinesLinksSource(t *testing.T) {
+	linker := lenses.NewSourceLinker([]prowapi.Refs{{Org: "org", Repo: "repo", BaseSHA: "abc"}})
+	lines := highlightLines([]string{"pkg/a/a_test.go:7: FAIL in pkg/b/b.go:9"}, 0, lineRenderer{linker: linker})
+	expected := []SubLine{
+		{Text: "pkg/a/a_test.go:7", HTML: "pkg/a/a_test.go:7", Link: "https://github.com/org/repo/blob/abc/pkg/a/a_test.go#L7"},
+		{Text: ":", HTML: ":"},
+		{Highlighted: true, Text: " FAIL", HTML: " FAIL"},
+		{Text: " in ", HTML: " in "},
+		{Text: "pkg/b/b.go:9", HTML: "pkg/b/b.go:9", Link: "https://github.com/org/repo/blob/abc/pkg/b/b.go#L9"},
+	}
+	if len(lines) != 1 || !lines[0].Highlighted || !reflect.DeepEqual(lines[0].SubLines, expected) {
+		t.Errorf("expected highlighted sublines %+v, got %+v", expected, lines)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// The levels that logs can be filtered to.
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+// defaultLevels are the regexes detecting the level of log lines, which match klog, logrus and
+// the usual keywords.
+var defaultLevels = map[string]string{
+	levelError:   errRE.String() + `|^[EF]\d{4} \d\d:\d\d:\d\d\.\d+|\blevel=(?:error|fatal)\b|"level": ?"(?:error|fatal)"`,
+	levelWarning: `^W\d{4} \d\d:\d\d:\d\d\.\d+|\bWARN(?:ING)?\b|\blevel=warn(?:ing)?\b|"level": ?"warn(?:ing)?"`,
+}
+
+// levelDetector detects the level of log lines.
+type levelDetector struct {
+	error, warning *regexp.Regexp
+}
+
+// levelDetector compiles the configured level regexes.
+func (c config) levelDetector() (levelDetector, error) {
+	var d levelDetector
+	for level := range c.Levels {
+		if level != levelError && level != levelWarning {
+			return d, fmt.Errorf("unknown level %q", level)
+		}
+	}
+	compile := func(level string) (*regexp.Regexp, error) {
+		expr, ok := c.Levels[level]
+		if !ok {
+			expr = defaultLevels[level]
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s level regex: %v", level, err)
+		}
+		return re, nil
+	}
+	var err error
+	if d.error, err = compile(levelError); err != nil {
+		return d, err
+	}
+	if d.warning, err = compile(levelWarning); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// atLeast returns whether a line is at the given level or a more severe one.
+func (d levelDetector) atLeast(line, level string) bool {
+	if d.error.MatchString(line) {
+		return true
+	}
+	return level == levelWarning && d.warning.MatchString(line)
+}
+
+// filter returns the log lines at the given level or a more severe one. The text of each log
+// line is the line of the same index.
+func (d levelDetector) filter(logLines []LogLine, lines []string, level string) []LogLine {
+	var filtered []LogLine
+	for i, line := range logLines {
+		if d.atLeast(lines[i], level) {
+			line.Skip = false
+			filtered = append(filtered, line)
+		}
+	}
+	return collapseRepeats(filtered)
+}
+
+// FilteredView holds the lines of a log at a level or a more severe one.
+type FilteredView struct {
+	Level string
+	Lines []LogLine
+}
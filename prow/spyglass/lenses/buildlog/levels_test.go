@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterLevel(t *testing.T) {
+	lines := []string{
+		"I0102 15:04:05.000000 1 a.go:1] fine",
+		"W0102 15:04:05.000000 1 a.go:1] careful",
+		`time="2019-01-02T15:04:05Z" level=error msg="bad"`,
+		`{"level":"warn","msg":"careful"}`,
+		"E0102 15:04:05.000000 1 a.go:1] bad",
+		"all good",
+	}
+	testCases := []struct {
+		name     string
+		config   string
+		level    string
+		expected []int
+	}{
+		{
+			name:     "errors",
+			level:    levelError,
+			expected: []int{3, 5},
+		},
+		{
+			name:     "warnings include errors",
+			level:    levelWarning,
+			expected: []int{2, 3, 4, 5},
+		},
+		{
+			name:     "configured regexes replace the defaults",
+			config:   `{"levels": {"error": "good"}}`,
+			level:    levelError,
+			expected: []int{6},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parseConfig(json.RawMessage(tc.config))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			filtered := opts.levels.filter(highlightLines(lines, 0, lineRenderer{}), lines, tc.level)
+			var actual []int
+			for _, line := range filtered {
+				actual = append(actual, line.Number)
+				if line.Skip {
+					t.Errorf("expected line %d not to be skipped", line.Number)
+				}
+			}
+			if len(actual) != len(tc.expected) {
+				t.Fatalf("expected lines %v, got %v", tc.expected, actual)
+			}
+			for i := range actual {
+				if actual[i] != tc.expected[i] {
+					t.Fatalf("expected lines %v, got %v", tc.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestLevelConfigInvalid(t *testing.T) {
+	for _, config := range []string{
+		`{"levels": {"info": "I\\d{4}"}}`,
+		`{"levels": {"error": "("}}`,
+	} {
+		if _, err := parseConfig(json.RawMessage(config)); err == nil {
+			t.Errorf("expected an error for %s", config)
+		}
+	}
+}
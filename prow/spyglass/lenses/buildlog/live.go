@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// LiveTail marks the end of the lines shown of a log that is still being written, after which
+// lines are read as they are written. StartLine is the number of lines shown.
+type LiveTail struct {
+	Artifact  string
+	Offset    int64
+	StartLine int
+}
+
+// LiveTailLines are the lines written to a live log after a tail, followed by the tail to read
+// more from if it is still being written.
+type LiveTailLines struct {
+	Lines []LogLine
+	Tail  *LiveTail
+}
+
+// completeLines drops the last of the lines of a log being written, which may not have been
+// written in full, and returns the rest with their length in bytes.
+func completeLines(lines []string) ([]string, int64) {
+	if len(lines) == 0 {
+		return lines, 0
+	}
+	lines = lines[:len(lines)-1]
+	var length int64
+	for _, line := range lines {
+		length += int64(len(line)) + 1
+	}
+	return lines, length
+}
+
+// readLiveTail reads the lines written to a log after a tail. Only complete lines are read while
+// the log is still being written, and every line left once it no longer is.
+func readLiveTail(a lenses.Artifact, tail LiveTail, renderer lineRenderer) (LiveTailLines, error) {
+	live := lenses.IsLive(a)
+	size, err := a.Size()
+	if err != nil {
+		return LiveTailLines{}, fmt.Errorf("failed to get log size: %v", err)
+	}
+	var lines []string
+	if size > tail.Offset {
+		lines, err = logLines(a, tail.Offset, size-tail.Offset)
+		if err != nil {
+			return LiveTailLines{}, err
+		}
+	}
+	var length int64
+	if live {
+		lines, length = completeLines(lines)
+	} else if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	logLines := highlightLines(lines, tail.StartLine, renderer)
+	start, prev := timesBefore(a, tail.Offset)
+	annotateTimes(logLines, lines, start, prev)
+	view := LiveTailLines{Lines: collapseRepeats(logLines)}
+	if live {
+		tail.Offset += length
+		tail.StartLine += len(lines)
+		view.Tail = &tail
+	}
+	return view, nil
+}
+
+// liveTail drops the last line of a log if it is still being written, returning the lines left
+// and the tail to read the lines written after them from, or nil if the log is not being written.
+func liveTail(a lenses.Artifact, lines []string) ([]string, *LiveTail) {
+	if !lenses.IsLive(a) {
+		return lines, nil
+	}
+	lines, length := completeLines(lines)
+	return lines, &LiveTail{Artifact: a.JobPath(), Offset: length, StartLine: len(lines)}
+}
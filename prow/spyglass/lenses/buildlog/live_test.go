@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+// liveArtifact is a log still being written.
+type liveArtifact struct {
+	largeArtifact
+	live bool
+}
+
+func (a *liveArtifact) Live() bool { return a.live }
+
+func lineTexts(logLines []LogLine) []string {
+	var texts []string
+	for _, l := range logLines {
+		text := ""
+		for _, s := range l.SubLines {
+			text += s.Text
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+func TestLiveTail(t *testing.T) {
+	lines := []string{"Starting.", "Runn"}
+	a := &liveArtifact{largeArtifact: largeArtifact{content: []byte("Starting.\nRunn")}, live: true}
+	shown, tail := liveTail(a, lines)
+	if !reflect.DeepEqual(shown, []string{"Starting."}) {
+		t.Errorf("expected the incomplete line to be dropped, got %q", shown)
+	}
+	expected := &LiveTail{Artifact: "build-log.txt", Offset: 10, StartLine: 1}
+	if !reflect.DeepEqual(tail, expected) {
+		t.Errorf("expected tail %+v, got %+v", expected, tail)
+	}
+
+	a.live = false
+	if shown, tail := liveTail(a, lines); tail != nil || len(shown) != 2 {
+		t.Errorf("expected a finished log to be shown in full without a tail, got %q and %+v", shown, tail)
+	}
+}
+
+func TestReadLiveTail(t *testing.T) {
+	testcases := []struct {
+		name          string
+		content       string
+		live          bool
+		expectedLines []string
+		expectedTail  *LiveTail
+	}{
+		{
+			name:          "complete lines are read while the log is written",
+			content:       "Starting.\nRunning.\nStill runn",
+			live:          true,
+			expectedLines: []string{"Running."},
+			expectedTail:  &LiveTail{Artifact: "build-log.txt", Offset: 19, StartLine: 2},
+		},
+		{
+			name:         "the tail is kept while nothing is written",
+			content:      "Starting.\nRunn",
+			live:         true,
+			expectedTail: &LiveTail{Artifact: "build-log.txt", Offset: 10, StartLine: 1},
+		},
+		{
+			name:          "every line left is read once the log is finished",
+			content:       "Starting.\nRunning.\nDone.",
+			expectedLines: []string{"Running.", "Done."},
+		},
+		{
+			name:          "the empty line after a trailing newline is dropped",
+			content:       "Starting.\nDone.\n",
+			expectedLines: []string{"Done."},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &liveArtifact{largeArtifact: largeArtifact{content: []byte(tc.content)}, live: tc.live}
+			view, err := readLiveTail(a, LiveTail{Artifact: "build-log.txt", Offset: 10, StartLine: 1}, lineRenderer{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := lineTexts(view.Lines); !reflect.DeepEqual(actual, tc.expectedLines) {
+				t.Errorf("expected lines %q, got %q", tc.expectedLines, actual)
+			}
+			if len(view.Lines) > 0 && view.Lines[0].Number != 2 {
+				t.Errorf("expected the lines to be numbered from 2, got %d", view.Lines[0].Number)
+			}
+			if !reflect.DeepEqual(view.Tail, tc.expectedTail) {
+				t.Errorf("expected tail %+v, got %+v", tc.expectedTail, view.Tail)
+			}
+		})
+	}
+}
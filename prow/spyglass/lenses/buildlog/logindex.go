@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// IndexLog indexes a log as the lens configured by rawConfig would scan it, so that a log index
+// sidecar can be uploaded with the log and the lens need not scan it. The failures recorded are
+// the lines matching the default error patterns or the error level, and the sections are those
+// the log is split into, with how long each took to log.
+func IndexLog(a lenses.Artifact, rawConfig json.RawMessage) (lenses.LogIndex, error) {
+	opts, err := parseConfig(rawConfig)
+	if err != nil {
+		return lenses.LogIndex{}, fmt.Errorf("invalid lens configuration: %v", err)
+	}
+	size, err := a.Size()
+	if err != nil {
+		return lenses.LogIndex{}, fmt.Errorf("failed to get size of log %q: %v", a.JobPath(), err)
+	}
+	content, err := ioutil.ReadAll(lenses.NewSectionReader(a, 0, size))
+	if err != nil {
+		return lenses.LogIndex{}, fmt.Errorf("failed to read log %q: %v", a.JobPath(), err)
+	}
+	lines := strings.Split(string(content), "\n")
+	index := lenses.LogIndex{
+		Size:   size,
+		Errors: lenses.MatchErrorLines(lines, opts.levels.indexPatterns()),
+	}
+	logLines := make([]LogLine, len(lines))
+	annotateTimes(logLines, lines, time.Time{}, time.Time{})
+	var offset int64
+	for _, b := range opts.split(lines) {
+		section := lenses.IndexedSection{
+			Title:     b.Title,
+			StartLine: int64(b.Start + 1),
+			EndLine:   int64(b.End),
+			Offset:    offset,
+		}
+		if d, ok := linesElapsed(logLines[b.Start:b.End]); ok {
+			section.Seconds = d.Seconds()
+		}
+		for _, line := range lines[b.Start:b.End] {
+			offset += int64(len(line)) + 1
+		}
+		index.Sections = append(index.Sections, section)
+	}
+	return index, nil
+}
+
+// indexPatterns returns the patterns of the failures recorded in log indexes: the default error
+// patterns, followed by the error level regex for the lines they miss.
+func (d levelDetector) indexPatterns() []lenses.ErrorPattern {
+	return append(append([]lenses.ErrorPattern(nil), lenses.DefaultErrorPatterns...), d.failurePatterns()...)
+}
+
+// readLogIndex reads the log index sidecar of a log, returning nil if it has none.
+func readLogIndex(a lenses.Artifact, siblings []*lenses.SiblingArtifact) *lenses.LogIndex {
+	index, err := lenses.ReadLogIndex(a, siblings)
+	if err != nil {
+		logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Ignoring unreadable log index.")
+		return nil
+	}
+	return index
+}
+
+// splitIndexed divides lines at the beginning of a log into sections, taking them from the log's
+// index if it has one that covers the lines, and scrubbing secrets from their titles.
+func (o options) splitIndexed(lines []string, index *lenses.LogIndex) []sectionBounds {
+	if index == nil {
+		return o.split(lines)
+	}
+	var bounds []sectionBounds
+	for _, s := range index.Sections {
+		b := sectionBounds{Title: o.secrets.scrub(s.Title), Start: int(s.StartLine) - 1, End: int(s.EndLine)}
+		if b.Start >= len(lines) {
+			break
+		}
+		if b.End > len(lines) {
+			b.End = len(lines)
+		}
+		if b.End <= b.Start || len(bounds) == 0 && b.Start != 0 || len(bounds) > 0 && b.Start != bounds[len(bounds)-1].End {
+			return o.split(lines)
+		}
+		bounds = append(bounds, b)
+	}
+	if len(bounds) == 0 || bounds[len(bounds)-1].End != len(lines) {
+		return o.split(lines)
+	}
+	return bounds
+}
+
+// setIndexedDurations sets how long the sections of a log took to log from its index, which
+// covers the whole of sections cut off at the beginning of a truncated log.
+func setIndexedDurations(sections []LogSection, index *lenses.LogIndex) {
+	if index == nil {
+		return
+	}
+	seconds := map[int]float64{}
+	for _, s := range index.Sections {
+		seconds[int(s.StartLine)-1] = s.Seconds
+	}
+	for i := range sections {
+		if s := seconds[sections[i].Start]; s > 0 {
+			sections[i].Duration = formatDuration(time.Duration(s * float64(time.Second)))
+		}
+	}
+}
+
+// markIndexedFailure marks the first failure recorded in the index of a log, which is among the
+// given lines. It returns the number of the line, or 0 if it is not among them.
+func markIndexedFailure(logLines []LogLine, index *lenses.LogIndex) int {
+	if len(index.Errors) == 0 {
+		return 0
+	}
+	line := int(index.Errors[0].Line)
+	if line < 1 || line > len(logLines) {
+		return 0
+	}
+	markFailureAt(logLines, line-1)
+	return line
+}
+
+// markTruncatedFailure marks the first failure recorded in the index of a truncated log, reading
+// the lines around it into the view if it is in the gap. It returns the number of the line if it
+// is in the beginning of the log, and 0 otherwise.
+func markTruncatedFailure(a lenses.Artifact, av *LogArtifactView, log truncatedLog, head, tail []LogLine, index *lenses.LogIndex, renderer lineRenderer) int {
+	if len(index.Errors) == 0 {
+		return 0
+	}
+	failure := index.Errors[0]
+	end := log.gap.Offset + log.gap.Length
+	switch {
+	case failure.Offset < log.gap.Offset:
+		return markIndexedFailure(head, index)
+	case failure.Offset < end:
+		above, expansion, err := expandGapAround(a, log.gap, failure, renderer)
+		if err != nil {
+			logrus.WithError(err).Info("Error reading the first failure in truncated log.")
+			return 0
+		}
+		av.Gap, av.Target = above, &expansion
+	default:
+		// The lines of the end of the log are not numbered, so the failure is found by its offset.
+		offset := end
+		for i, line := range log.tail {
+			if offset == failure.Offset {
+				markFailureAt(tail, i)
+				break
+			}
+			offset += int64(len(line)) + 1
+		}
+	}
+	return 0
+}
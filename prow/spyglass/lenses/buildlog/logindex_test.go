@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestIndexLog(t *testing.T) {
+	lines := []string{
+		"2019-01-02T15:04:05Z Starting.",
+		"+++ [0102 15:04:06] Building",
+		"2019-01-02T15:04:06Z compiling",
+		"2019-01-02T15:04:16Z ERROR: compile failed",
+		"+++ [0102 15:04:20] Testing",
+		"E0102 15:04:21.000000 1 main.go:1] broke",
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	index, err := IndexLog(&largeArtifact{content: []byte(content)}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := lenses.LogIndex{
+		Size: int64(len(content)),
+		Errors: []lenses.ErrorMatch{
+			{Pattern: "error", Line: 4, Offset: int64(strings.Index(content, lines[3])), Text: "2019-01-02T15:04:16Z ERROR: compile failed"},
+			{Pattern: "error", Line: 6, Offset: int64(strings.Index(content, lines[5])), Text: "E0102 15:04:21.000000 1 main.go:1] broke"},
+		},
+		Sections: []lenses.IndexedSection{
+			{StartLine: 1, EndLine: 1, Offset: 0},
+			{Title: "Building", StartLine: 2, EndLine: 4, Offset: int64(strings.Index(content, lines[1])), Seconds: 10},
+			{Title: "Testing", StartLine: 5, EndLine: 7, Offset: int64(strings.Index(content, lines[4]))},
+		},
+	}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("expected %+v, got %+v", expected, index)
+	}
+}
+
+func TestTruncatedViewUsesIndex(t *testing.T) {
+	lines := numberedLines(1000)
+	content := strings.Join(lines, "\n") + "\n"
+	a := &largeArtifact{content: []byte(content)}
+	opts := defaultOptions()
+	opts.headLines, opts.tailLines = 100, 100
+	// The index records failures and sections that scanning the log would not find, so the
+	// view shows them only if it uses the index.
+	index := &lenses.LogIndex{
+		Size: int64(len(content)),
+		Errors: []lenses.ErrorMatch{
+			{Pattern: "failure", Line: 500, Offset: int64(strings.Index(content, "line 500\n"))},
+			{Pattern: "error", Line: 700, Offset: int64(strings.Index(content, "line 700\n"))},
+		},
+		Sections: []lenses.IndexedSection{
+			{StartLine: 1, EndLine: 49},
+			{Title: "Testing", StartLine: 50, EndLine: 1001, Seconds: 90},
+		},
+	}
+	av, err := truncatedView(a, opts, lineRenderer{}, 0, index)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if av.Target == nil {
+		t.Fatal("expected the lines around the indexed failure to be shown")
+	}
+	failure := 0
+	for _, line := range av.Target.Lines {
+		if line.Failure {
+			failure = line.Number
+		}
+	}
+	if failure != 500 {
+		t.Errorf("expected line 500 to be marked as the failure, got %d", failure)
+	}
+	if len(av.Sections) != 2 || av.Sections[1].Title != "Testing" || av.Sections[1].End != 100 || av.Sections[1].Duration != "1m30s" {
+		t.Errorf("expected the indexed sections cut off at line 100, got %+v", av.Sections)
+	}
+	if av.Errors == nil || av.Errors.Total != 2 || av.Errors.First.Line != 500 || av.Errors.Last.Line != 700 {
+		t.Errorf("expected the indexed failures to be summarized, got %+v", av.Errors)
+	}
+}
+
+func TestSplitIndexedFallsBack(t *testing.T) {
+	lines := []string{"+++ Building", "one", "+++ Testing", "two"}
+	opts := defaultOptions()
+	index := &lenses.LogIndex{Sections: []lenses.IndexedSection{{Title: "Building", StartLine: 1, EndLine: 1}, {StartLine: 3, EndLine: 4}}}
+	if actual, expected := opts.splitIndexed(lines, index), opts.split(lines); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected an index whose sections leave out lines to be ignored, got %+v", actual)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// targetContextLines is how many lines on either side of the line a permalink targets are shown.
+const targetContextLines = 10
+
+// errLineNotFound is returned when the line a permalink targets cannot be found.
+var errLineNotFound = fmt.Errorf("line not found")
+
+// markTarget marks the line a permalink targets, numbered from 1, and has the lines around it
+// shown. It returns false if the log does not have the line.
+func markTarget(logLines []LogLine, line int) bool {
+	i := line - 1
+	if i < 0 || i >= len(logLines) {
+		return false
+	}
+	logLines[i].Target = true
+	showAround(logLines, i)
+	return true
+}
+
+// showAround has the lines around a log line shown.
+func showAround(logLines []LogLine, i int) {
+	for j := i - targetContextLines; j <= i+targetContextLines; j++ {
+		if j >= 0 && j < len(logLines) {
+			logLines[j].Skip = false
+		}
+	}
+}
+
+// AnchorData returns the data to render the lens's body with to show the section of a log whose
+// title an anchor names.
+func (lens Lens) AnchorData(anchor string) string {
+	data, err := json.Marshal(bodyRequest{Section: anchor})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// anchoredSection returns the index of the first titled section whose title an anchor names, or
+// -1 if there is none.
+func anchoredSection(bounds []sectionBounds, anchor string) int {
+	for i, b := range bounds {
+		if b.Title != "" && lenses.Anchor(b.Title) == anchor {
+			return i
+		}
+	}
+	return -1
+}
+
+// showSection unfolds and targets the section of a log a link names, showing all of its lines if
+// there are few enough of them.
+func showSection(sections []LogSection, i int, artifact string, logLines []LogLine, bounds []sectionBounds, opts options) {
+	if opts.canShowAll(bounds[i].End - bounds[i].Start) {
+		sections[i] = sectionLines(artifact, logLines, bounds[i:i+1], true)[0]
+	}
+	sections[i].Folded = false
+	sections[i].Target = true
+}
+
+// unfoldTarget unfolds the section holding the line a permalink targets.
+func unfoldTarget(sections []LogSection, line int) {
+	for i := range sections {
+		if sections[i].Start < line && line <= sections[i].End {
+			sections[i].Folded = false
+		}
+	}
+}
+
+// expandGapAt reads the lines around the line a permalink targets in the gap of a truncated log,
+// finding where they start from the log's line index if it has one. It returns the gap left
+// above them, or nil if there is none, and the lines followed by the gap left below them.
+func expandGapAt(a lenses.Artifact, gap LogGap, line int, renderer lineRenderer) (*LogGap, GapExpansion, error) {
+	if line <= gap.StartLine {
+		return nil, GapExpansion{}, errLineNotFound
+	}
+	first := line - targetContextLines
+	if first <= gap.StartLine {
+		first = gap.StartLine + 1
+	}
+	offset, err := lenses.LineOffset(a, int64(first))
+	if err == io.EOF {
+		return nil, GapExpansion{}, errLineNotFound
+	}
+	if err != nil {
+		return nil, GapExpansion{}, err
+	}
+	end := gap.Offset + gap.Length
+	if offset < gap.Offset || offset >= end {
+		// The line is in the end of the log, whose lines are not numbered.
+		return nil, GapExpansion{}, errLineNotFound
+	}
+	rest := LogGap{Artifact: gap.Artifact, Offset: offset, Length: end - offset, StartLine: first - 1}
+	expansion, err := expandGap(a, rest, line+targetContextLines-first+1, line, 0, renderer)
+	if err != nil {
+		return nil, GapExpansion{}, err
+	}
+	var above *LogGap
+	if offset > gap.Offset {
+		above = &LogGap{Artifact: gap.Artifact, Offset: gap.Offset, Length: offset - gap.Offset, StartLine: gap.StartLine}
+	}
+	return above, expansion, nil
+}
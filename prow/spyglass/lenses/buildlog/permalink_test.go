@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestMarkTarget(t *testing.T) {
+	logLines := highlightLines(numberedLines(100), 0, lineRenderer{})
+	if !markTarget(logLines, 50) {
+		t.Fatal("expected line 50 to be found")
+	}
+	for i, line := range logLines {
+		if line.Target != (line.Number == 50) {
+			t.Errorf("line %d: expected target to be %t", line.Number, line.Number == 50)
+		}
+		shown := line.Number >= 40 && line.Number <= 60
+		if line.Skip == shown {
+			t.Errorf("line %d: expected it to be shown: %t", i+1, shown)
+		}
+	}
+	if markTarget(logLines, 101) {
+		t.Error("expected line 101 not to be found")
+	}
+}
+
+func TestExpandGapAt(t *testing.T) {
+	lines := numberedLines(1000)
+	content := strings.Join(lines, "\n") + "\n"
+	a := &largeArtifact{content: []byte(content)}
+	// The end of the log is its last 99 lines and the empty line after its trailing newline.
+	log, err := readTruncated(a, 100, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	above, expansion, err := expandGapAt(a, log.gap, 500, lineRenderer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if above == nil || content[above.Offset:above.Offset+above.Length] != strings.Join(lines[100:489], "\n")+"\n" {
+		t.Errorf("expected the gap above to hold lines 101 to 489, got %+v", above)
+	}
+	var target, count int
+	for _, line := range expansion.Lines {
+		if line.Target {
+			target = line.Number
+		}
+		count += line.Repeats + 1
+	}
+	if target != 500 || count != 2*targetContextLines+1 {
+		t.Errorf("expected %d lines around line 500, got %d around line %d", 2*targetContextLines+1, count, target)
+	}
+	if expansion.Gap == nil || expansion.Gap.StartLine != 510 || content[expansion.Gap.Offset:expansion.Gap.Offset+expansion.Gap.Length] != strings.Join(lines[510:901], "\n")+"\n" {
+		t.Errorf("expected the gap below to hold lines 511 to 901, got %+v", expansion.Gap)
+	}
+
+	if _, _, err := expandGapAt(a, log.gap, 950, lineRenderer{}); err != errLineNotFound {
+		t.Errorf("expected a line in the unnumbered end of the log not to be found, got %v", err)
+	}
+}
+
+func TestShowSection(t *testing.T) {
+	lines := []string{"setup", "+++ Build images", "a", "b", "c", "+++ Run unit tests", "d", "e", "f", "+++ failing", "FAIL: test"}
+	logLines := highlightLines(lines, 0, lineRenderer{})
+	opts := defaultOptions()
+	bounds := opts.sections.split(lines)
+	if i := anchoredSection(bounds, "no-such-section"); i != -1 {
+		t.Errorf("expected no section to be named, got %d", i)
+	}
+	i := anchoredSection(bounds, "run-unit-tests")
+	if i != 2 {
+		t.Fatalf("expected the third section to be named, got %d", i)
+	}
+	sections := sectionLines("build-log.txt", logLines, bounds, false)
+	showSection(sections, i, "build-log.txt", logLines, bounds, opts)
+	shown := sections[i]
+	if shown.Folded || !shown.Target || shown.Title != "Run unit tests" {
+		t.Errorf("expected the named section to be unfolded and targeted, got %+v", shown)
+	}
+	for _, g := range shown.LineGroups {
+		if g.Skip {
+			t.Errorf("expected no lines of the named section to be skipped, got %+v", g)
+		}
+	}
+	if !sections[1].Folded || sections[1].Target {
+		t.Errorf("expected other sections to be left alone, got %+v", sections[1])
+	}
+
+	body := Lens{}.Body([]lenses.Artifact{&smallArtifact{largeArtifact{content: []byte(strings.Join(lines, "\n"))}}}, ".", Lens{}.AnchorData("run-unit-tests"), nil)
+	if strings.Count(body, "anchor-target") != 1 || !strings.Contains(body, `data-anchor="run-unit-tests"`) {
+		t.Errorf("expected the named section to be targeted in the rendered log, got %s", body)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"regexp"
+	"strings"
+)
+
+// minRepeatsCollapsed is the fewest lines in a run of repeated lines that are collapsed.
+const minRepeatsCollapsed = 3
+
+// counterRE matches the numbers that repeated lines, such as those of retry loops, differ by.
+var counterRE = regexp.MustCompile(`\d+`)
+
+// repeatKey returns what a line must share with the lines around it to be a repeat of them,
+// which is its text with any numbers left out. Blank lines are not repeats, and neither are the
+// line a permalink targets, the first failure and lines after hangs, so that they are always shown.
+func repeatKey(line LogLine) (string, bool) {
+	if line.Target || line.Failure || line.Hang() || line.InTraceback || len(line.JunitTests) > 0 {
+		return "", false
+	}
+	var text strings.Builder
+	for _, s := range line.SubLines {
+		text.WriteString(s.Text)
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return "", false
+	}
+	return line.Container + "\x00" + counterRE.ReplaceAllString(text.String(), "0"), true
+}
+
+// collapseRepeats collapses runs of repeated lines into the first line of each run, which
+// records how many lines followed it.
+func collapseRepeats(lines []LogLine) []LogLine {
+	var collapsed []LogLine
+	for i := 0; i < len(lines); {
+		key, ok := repeatKey(lines[i])
+		end := i + 1
+		for ok && end < len(lines) {
+			if next, _ := repeatKey(lines[end]); next != key {
+				break
+			}
+			end++
+		}
+		if end-i < minRepeatsCollapsed {
+			collapsed = append(collapsed, lines[i:end]...)
+		} else {
+			line := lines[i]
+			line.Repeats = end - i - 1
+			line.LastRepeat = lines[end-1].Number
+			collapsed = append(collapsed, line)
+		}
+		i = end
+	}
+	return collapsed
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseRepeats(t *testing.T) {
+	lines := []string{
+		"starting",
+		"attempt 1: connection refused",
+		"attempt 2: connection refused",
+		"attempt 3: connection refused",
+		"attempt 4: connection refused",
+		"same",
+		"same",
+		"",
+		"",
+		"",
+		"done",
+	}
+	type shown struct {
+		Number, Repeats, LastRepeat int
+	}
+	expected := []shown{
+		{Number: 1},
+		{Number: 2, Repeats: 3, LastRepeat: 5},
+		{Number: 6},
+		{Number: 7},
+		{Number: 8},
+		{Number: 9},
+		{Number: 10},
+		{Number: 11},
+	}
+	var actual []shown
+	for _, line := range collapseRepeats(highlightLines(lines, 0, lineRenderer{})) {
+		actual = append(actual, shown{line.Number, line.Repeats, line.LastRepeat})
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected lines %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCollapseRepeatsKeepsContainersApart(t *testing.T) {
+	lines := highlightLines([]string{"retrying", "retrying", "retrying"}, 0, lineRenderer{})
+	lines[1].Container = "sidecar"
+	if collapsed := collapseRepeats(lines); len(collapsed) != 3 {
+		t.Errorf("expected lines of different containers not to be collapsed, got %+v", collapsed)
+	}
+}
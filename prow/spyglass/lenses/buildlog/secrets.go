@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// redactionMarker replaces the secrets scrubbed from log lines.
+const redactionMarker = "[REDACTED]"
+
+// allSecretDetectors names every built-in secret detector.
+const allSecretDetectors = "all"
+
+// secretDetectors are the built-in patterns of common token formats, by name. Where a pattern
+// has a group named "secret", only that group is redacted.
+var secretDetectors = map[string]string{
+	"aws_access_key":      `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"gcp_api_key":         `\bAIza[0-9A-Za-z_-]{35}\b`,
+	"github_token":        `\bgh[pousr]_[0-9A-Za-z]{36,255}\b`,
+	"slack_token":         `\bxox[abprs]-[0-9A-Za-z-]{10,}`,
+	"jwt":                 `\beyJ[0-9A-Za-z_-]{10,}\.eyJ[0-9A-Za-z_-]{10,}\.[0-9A-Za-z_-]{10,}`,
+	"bearer_token":        `(?i)\bbearer\s+(?P<secret>[0-9A-Za-z._~+/-]{16,}=*)`,
+	"password_assignment": `(?i)\b(?:password|passwd|secret|token|api_?key)["']?\s*[=:]\s*["']?(?P<secret>[^\s"',;]+)`,
+}
+
+// secretPattern is a compiled pattern of secrets, and the index of the group that is redacted
+// from its matches, or 0 for the whole match.
+type secretPattern struct {
+	re    *regexp.Regexp
+	group int
+}
+
+// secretScrubber redacts secrets from log lines. A nil scrubber redacts nothing.
+type secretScrubber struct {
+	patterns []secretPattern
+}
+
+// secretScrubber compiles the configured secret patterns and detectors. It returns nil if none
+// are configured.
+func (c config) secretScrubber() (*secretScrubber, error) {
+	var exprs []string
+	for _, name := range c.SecretDetectors {
+		if name == allSecretDetectors {
+			for _, name := range detectorNames() {
+				exprs = append(exprs, secretDetectors[name])
+			}
+			continue
+		}
+		expr, ok := secretDetectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown secret detector %q, expected one of %s or %q", name, strings.Join(detectorNames(), ", "), allSecretDetectors)
+		}
+		exprs = append(exprs, expr)
+	}
+	exprs = append(exprs, c.SecretPatterns...)
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	s := &secretScrubber{}
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %v", expr, err)
+		}
+		pattern := secretPattern{re: re}
+		for i, name := range re.SubexpNames() {
+			if name == "secret" {
+				pattern.group = i
+			}
+		}
+		s.patterns = append(s.patterns, pattern)
+	}
+	return s, nil
+}
+
+// detectorNames returns the names of the built-in secret detectors, sorted.
+func detectorNames() []string {
+	var names []string
+	for name := range secretDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scrub replaces the secrets in a line with the redaction marker.
+func (s *secretScrubber) scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	for _, p := range s.patterns {
+		matches := p.re.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[2*p.group], m[2*p.group+1]
+			if start < 0 {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(redactionMarker)
+			last = end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+// lineRenderer holds what log lines are rendered with.
+type lineRenderer struct {
+	linker  *lenses.SourceLinker
+	secrets *secretScrubber
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// groupMarker describes the lines that begin and end a section of the log.
+type groupMarker struct {
+	// Start matches the first line of a section. The section is titled by the first capture
+	// group if there is one and it is not empty, and by the whole line otherwise.
+	Start string `json:"start"`
+	// End matches the last line of a section. Sections without one end where the next begins.
+	End string `json:"end,omitempty"`
+}
+
+// defaultGroupMarkers match the status lines of the Kubernetes build scripts and the group
+// commands of GitHub Actions and Azure Pipelines.
+var defaultGroupMarkers = []groupMarker{
+	{Start: `^\+\+\+ (?:\[\d{4} [\d:]+\] )?(.*)`},
+	{Start: `^::group::(.*)`, End: `^::endgroup::`},
+	{Start: `^##\[group\](.*)`, End: `^##\[endgroup\]`},
+}
+
+// marker is a compiled groupMarker.
+type marker struct {
+	start, end *regexp.Regexp
+}
+
+// sectioner splits logs into sections.
+type sectioner struct {
+	markers []marker
+	gap     time.Duration
+}
+
+// sectioner compiles the configured group markers.
+func (c config) sectioner() (sectioner, error) {
+	var s sectioner
+	markers := c.GroupMarkers
+	if len(markers) == 0 {
+		markers = defaultGroupMarkers
+	}
+	for _, m := range markers {
+		start, err := regexp.Compile(m.Start)
+		if err != nil {
+			return s, fmt.Errorf("invalid group marker %q: %v", m.Start, err)
+		}
+		compiled := marker{start: start}
+		if m.End != "" {
+			if compiled.end, err = regexp.Compile(m.End); err != nil {
+				return s, fmt.Errorf("invalid group marker %q: %v", m.End, err)
+			}
+		}
+		s.markers = append(s.markers, compiled)
+	}
+	if c.TimestampGap != "" {
+		gap, err := time.ParseDuration(c.TimestampGap)
+		if err != nil {
+			return s, fmt.Errorf("invalid timestamp_gap: %v", err)
+		}
+		s.gap = gap
+	}
+	return s, nil
+}
+
+// sectionBounds is the extent of a section of a log, in lines.
+type sectionBounds struct {
+	Title      string
+	Start, End int // closed, open
+}
+
+// begin returns the title of the section that line begins, and the index of the marker that
+// matched it.
+func (s sectioner) begin(line string) (string, int, bool) {
+	for i, m := range s.markers {
+		match := m.start.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		title := ""
+		if len(match) > 1 {
+			title = strings.TrimSpace(match[1])
+		}
+		if title == "" {
+			title = strings.TrimSpace(line)
+		}
+		return title, i, true
+	}
+	return "", -1, false
+}
+
+// split divides a log into sections. Lines before the first section, or between a section's
+// end marker and the next section, form untitled sections.
+func (s sectioner) split(lines []string) []sectionBounds {
+	var sections []sectionBounds
+	cur := sectionBounds{}
+	add := func(end int) {
+		cur.End = end
+		if cur.End > cur.Start {
+			sections = append(sections, cur)
+		}
+	}
+	open := -1
+	var last time.Time
+	for i, line := range lines {
+		if open >= 0 && s.markers[open].end != nil && s.markers[open].end.MatchString(line) {
+			add(i + 1)
+			cur = sectionBounds{Start: i + 1}
+			open = -1
+			continue
+		}
+		title, m, ok := s.begin(line)
+		if s.gap > 0 {
+			if t, found := lineTimestamp(line); found {
+				if !ok && !last.IsZero() && t.Sub(last) >= s.gap {
+					title, ok = strings.TrimSpace(line), true
+				}
+				last = t
+			}
+		}
+		if ok {
+			add(i)
+			cur = sectionBounds{Title: title, Start: i}
+			open = m
+		}
+	}
+	add(len(lines))
+	return sections
+}
+
+// timestampRE matches the timestamps at the start of klog lines and of RFC 3339 style lines.
+var timestampRE = regexp.MustCompile(`^(?:[IWEF](\d{4} \d\d:\d\d:\d\d\.\d+)|\[?(\d{4}-\d\d-\d\d)[T ](\d\d:\d\d:\d\d(?:\.\d+)?))`)
+
+// fieldTimestampRE matches the time field of logrus lines, in text or JSON.
+var fieldTimestampRE = regexp.MustCompile(`\btime"?[:=] ?"(\d{4}-\d\d-\d\d)T(\d\d:\d\d:\d\d(?:\.\d+)?)`)
+
+// lineTimestamp returns the time a line was logged at, ignoring time zones as they are
+// expected to be the same throughout a log. The year of klog timestamps is unknown, so is zero.
+func lineTimestamp(line string) (time.Time, bool) {
+	if match := timestampRE.FindStringSubmatch(line); match != nil {
+		var t time.Time
+		var err error
+		if match[1] != "" {
+			t, err = time.Parse("0102 15:04:05.999999999", match[1])
+		} else {
+			t, err = time.Parse("2006-01-02T15:04:05.999999999", match[2]+"T"+match[3])
+		}
+		return t, err == nil
+	}
+	if match := fieldTimestampRE.FindStringSubmatch(line); match != nil {
+		t, err := time.Parse("2006-01-02T15:04:05.999999999", match[1]+"T"+match[2])
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// sectionLines groups the lines of each section of a log. Titled sections without highlighted
+// lines or hangs are folded. If showAll is set, no lines are skipped.
+func sectionLines(artifact string, logLines []LogLine, bounds []sectionBounds, showAll bool) []LogSection {
+	offsets := make([]int, len(logLines)+1)
+	for i, line := range logLines {
+		offsets[i+1] = offsets[i] + line.Length
+	}
+	var sections []LogSection
+	for _, b := range bounds {
+		section := LogSection{
+			Artifact: artifact,
+			Title:    b.Title,
+			Start:    b.Start,
+			End:      b.End,
+		}
+		lines := logLines[b.Start:b.End]
+		section.Duration = linesDuration(lines)
+		if showAll {
+			section.LineGroups = []LineGroup{{
+				Start:      b.Start,
+				End:        b.End,
+				ByteOffset: offsets[b.Start],
+				ByteLength: offsets[b.End] - offsets[b.Start] - 1,
+				LogLines:   lines,
+			}}
+		} else {
+			section.LineGroups = groupLines(lines)
+			for i := range section.LineGroups {
+				section.LineGroups[i].Start += b.Start
+				section.LineGroups[i].End += b.Start
+				section.LineGroups[i].ByteOffset += offsets[b.Start]
+			}
+		}
+		for i, g := range section.LineGroups {
+			if !g.Skip {
+				section.LineGroups[i].LogLines = collapseRepeats(g.LogLines)
+			}
+		}
+		section.Folded = section.Title != ""
+		for _, line := range lines {
+			if line.Highlighted || line.Hang() {
+				section.Folded = false
+				break
+			}
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitSections(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		lines    []string
+		expected []sectionBounds
+	}{
+		{
+			name:     "log without markers is one untitled section",
+			lines:    []string{"a", "b"},
+			expected: []sectionBounds{{Start: 0, End: 2}},
+		},
+		{
+			name: "build script status lines begin sections",
+			lines: []string{
+				"setting up",
+				"+++ [0102 15:04:05] Building go targets",
+				"go build",
+				"+++ [0102 15:10:00] Running tests",
+				"ok",
+			},
+			expected: []sectionBounds{
+				{Start: 0, End: 1},
+				{Title: "Building go targets", Start: 1, End: 3},
+				{Title: "Running tests", Start: 3, End: 5},
+			},
+		},
+		{
+			name: "groups end at their end marker",
+			lines: []string{
+				"::group::Install",
+				"npm install",
+				"::endgroup::",
+				"npm test",
+			},
+			expected: []sectionBounds{
+				{Title: "Install", Start: 0, End: 3},
+				{Start: 3, End: 4},
+			},
+		},
+		{
+			name:   "custom markers replace the defaults",
+			config: `{"group_markers": [{"start": "^=== (\\w+)"}]}`,
+			lines: []string{
+				"::group::Install",
+				"=== Setup",
+				"=== RUN",
+			},
+			expected: []sectionBounds{
+				{Start: 0, End: 1},
+				{Title: "Setup", Start: 1, End: 2},
+				{Title: "RUN", Start: 2, End: 3},
+			},
+		},
+		{
+			name:   "gaps in timestamps begin sections",
+			config: `{"timestamp_gap": "5m"}`,
+			lines: []string{
+				"I0102 15:04:05.000000 1 main.go:1] start",
+				"untimed line",
+				"I0102 15:08:05.000000 1 main.go:1] soon after",
+				"I0102 15:20:00.000000 1 main.go:1] much later",
+				"I0102 15:21:00.000000 1 main.go:1] soon after again",
+			},
+			expected: []sectionBounds{
+				{Start: 0, End: 3},
+				{Title: "I0102 15:20:00.000000 1 main.go:1] much later", Start: 3, End: 5},
+			},
+		},
+		{
+			name:   "gaps in RFC 3339 timestamps begin sections",
+			config: `{"timestamp_gap": "30m"}`,
+			lines: []string{
+				"2019-01-02T15:04:05Z start",
+				"[2019-01-02 15:21:00.123] soon after",
+				"2019-01-02T16:00:00.5+01:00 later",
+			},
+			expected: []sectionBounds{
+				{Start: 0, End: 2},
+				{Title: "2019-01-02T16:00:00.5+01:00 later", Start: 2, End: 3},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := parseConfig(json.RawMessage(tc.config))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := s.sections.split(tc.lines); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected sections %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseConfigInvalid(t *testing.T) {
+	for _, config := range []string{
+		`{"group_markers": [{"start": "("}]}`,
+		`{"group_markers": [{"start": "a", "end": "("}]}`,
+		`{"timestamp_gap": "soon"}`,
+		`not json`,
+	} {
+		s, err := parseConfig(json.RawMessage(config))
+		if err == nil {
+			t.Errorf("expected an error for %s", config)
+		}
+		if len(s.sections.markers) != len(defaultGroupMarkers) {
+			t.Errorf("expected the default markers for %s, got %d markers", config, len(s.sections.markers))
+		}
+	}
+}
+
+func TestSectionLines(t *testing.T) {
+	lines := []string{"setup", "+++ quiet", "a", "b", "c", "d", "e", "f", "+++ failing", "FAIL: test"}
+	logLines := highlightLines(lines, 0, lineRenderer{})
+	sections := sectionLines("build-log.txt", logLines, defaultOptions().sections.split(lines), false)
+	if len(sections) != 3 {
+		t.Fatalf("expected 3 sections, got %d", len(sections))
+	}
+	if sections[0].Folded || sections[0].Title != "" {
+		t.Errorf("expected the leading section to be untitled and unfolded, got %+v", sections[0])
+	}
+	if !sections[1].Folded || sections[1].Lines() != 7 {
+		t.Errorf("expected a folded section of 7 lines, got %+v", sections[1])
+	}
+	if sections[2].Folded {
+		t.Errorf("expected the section with a failure to be unfolded, got %+v", sections[2])
+	}
+	quiet := sections[1].LineGroups
+	if len(quiet) != 1 || !quiet[0].Skip || quiet[0].Start != 1 || quiet[0].End != 8 || quiet[0].ByteOffset != 6 || quiet[0].ByteLength != 21 {
+		t.Errorf("expected the quiet section's lines to be skipped with absolute positions, got %+v", quiet)
+	}
+	for _, s := range sectionLines("build-log.txt", logLines, defaultOptions().sections.split(lines), true) {
+		for _, g := range s.LineGroups {
+			if g.Skip {
+				t.Errorf("expected no lines to be skipped when showing all, got %+v", g)
+			}
+		}
+	}
+}
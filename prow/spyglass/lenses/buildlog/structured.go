@@ -0,0 +1,295 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// structuredSampleLines is how many of the first non-empty lines of a log are checked to tell
+	// whether it is a log of JSON lines.
+	structuredSampleLines = 20
+	// maxStructuredLines is the most lines a log of JSON lines can have to be shown as records.
+	maxStructuredLines = 20000
+)
+
+// The keys that structured loggers such as logrus and zap log the level, time and message of
+// records under.
+var (
+	levelKeys   = []string{"level", "severity", "lvl"}
+	timeKeys    = []string{"time", "ts", "timestamp"}
+	messageKeys = []string{"msg", "message"}
+)
+
+// StructuredField is a field of a record of a log of JSON lines.
+type StructuredField struct {
+	Key   string
+	Value string
+}
+
+// StructuredRecord is a line of a log of JSON lines, split into its level, time, message and
+// other fields. Lines that are not JSON only have a message.
+type StructuredRecord struct {
+	Number  int
+	Level   string
+	Time    string
+	Message string
+	Fields  []StructuredField
+	// Target is set on the record a permalink targets.
+	Target bool
+}
+
+// LevelClass returns the level a record is styled as: "error", "warning", "info" or "debug".
+func (r StructuredRecord) LevelClass() string {
+	switch strings.ToLower(r.Level) {
+	case "error", "fatal", "panic", "dpanic", "critical":
+		return "error"
+	case "warn", "warning":
+		return "warning"
+	case "debug", "trace":
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// field returns the value of a field of a record, counting its level and message as fields.
+func (r StructuredRecord) field(key string) (string, bool) {
+	if hasKey(levelKeys, key) {
+		return r.Level, true
+	}
+	if hasKey(messageKeys, key) {
+		return r.Message, true
+	}
+	for _, f := range r.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+func hasKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// StructuredView is a log of JSON lines shown as a table of records.
+type StructuredView struct {
+	Records []StructuredRecord
+}
+
+// isStructured returns whether most of the first lines of a log are JSON objects logged by a
+// structured logger.
+func isStructured(lines []string) bool {
+	sampled, structured := 0, 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if _, ok := parseRecord(line, 0); ok {
+			structured++
+		}
+		sampled++
+		if sampled == structuredSampleLines {
+			break
+		}
+	}
+	return sampled > 0 && structured*5 >= sampled*4
+}
+
+// parseStructured parses the lines of a log of JSON lines into records, scrubbing secrets from
+// them first. The empty line after a trailing newline is dropped.
+func parseStructured(lines []string, secrets *secretScrubber) []StructuredRecord {
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	records := make([]StructuredRecord, 0, len(lines))
+	for i, line := range lines {
+		line = secrets.scrub(line)
+		record, ok := parseRecord(line, i+1)
+		if !ok {
+			record = StructuredRecord{Number: i + 1, Message: line}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// parseRecord parses a JSON line logged by a structured logger, which has a level or a message.
+func parseRecord(line string, number int) (StructuredRecord, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return StructuredRecord{}, false
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return StructuredRecord{}, false
+	}
+	record := StructuredRecord{Number: number}
+	var hasLevel, hasMessage bool
+	record.Level, hasLevel = takeField(fields, levelKeys)
+	record.Message, hasMessage = takeField(fields, messageKeys)
+	if !hasLevel && !hasMessage {
+		return StructuredRecord{}, false
+	}
+	for _, key := range timeKeys {
+		if value, ok := fields[key]; ok {
+			record.Time = formatTime(value)
+			delete(fields, key)
+			break
+		}
+	}
+	for key, value := range fields {
+		record.Fields = append(record.Fields, StructuredField{Key: key, Value: formatValue(value)})
+	}
+	sort.Slice(record.Fields, func(i, j int) bool { return record.Fields[i].Key < record.Fields[j].Key })
+	return record, true
+}
+
+// takeField removes the first of some keys found in the fields of a record and returns its value.
+func takeField(fields map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			delete(fields, key)
+			return formatValue(value), true
+		}
+	}
+	return "", false
+}
+
+// formatValue formats the value of a field for display.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// formatTime formats the time a record was logged at. Zap logs times as seconds since the epoch
+// by default, which are shown as UTC times.
+func formatTime(value interface{}) string {
+	if n, ok := value.(json.Number); ok {
+		if seconds, err := strconv.ParseFloat(n.String(), 64); err == nil {
+			whole, fraction := math.Modf(seconds)
+			return time.Unix(int64(whole), int64(fraction*1e9)).UTC().Format("2006-01-02T15:04:05.000Z")
+		}
+	}
+	return formatValue(value)
+}
+
+// fieldFilter selects the records of a log of JSON lines that have all of some fields and
+// contain all of some text in their messages.
+type fieldFilter struct {
+	fields []StructuredField
+	text   []string
+}
+
+// parseFieldFilter parses a filter of space-separated terms, which are either key=value pairs
+// matching fields or text to find in messages. Values with spaces can be quoted.
+func parseFieldFilter(query string) fieldFilter {
+	var f fieldFilter
+	for _, term := range splitTerms(query) {
+		if i := strings.Index(term, "="); i > 0 {
+			f.fields = append(f.fields, StructuredField{Key: term[:i], Value: strings.Trim(term[i+1:], `"`)})
+		} else {
+			f.text = append(f.text, strings.ToLower(strings.Trim(term, `"`)))
+		}
+	}
+	return f
+}
+
+// splitTerms splits a query at spaces outside of double quotes.
+func splitTerms(query string) []string {
+	var terms []string
+	var term strings.Builder
+	quoted := false
+	for _, c := range query {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			term.WriteRune(c)
+		case c == ' ' && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(c)
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+// matches returns whether a record is selected by a filter.
+func (f fieldFilter) matches(r StructuredRecord) bool {
+	for _, field := range f.fields {
+		if value, ok := r.field(field.Key); !ok || value != field.Value {
+			return false
+		}
+	}
+	message := strings.ToLower(r.Message)
+	for _, text := range f.text {
+		if !strings.Contains(message, text) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter returns the records selected by a filter.
+func (f fieldFilter) filter(records []StructuredRecord) []StructuredRecord {
+	var selected []StructuredRecord
+	for _, r := range records {
+		if f.matches(r) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// markRecordTarget marks the record a permalink targets, if any.
+func markRecordTarget(records []StructuredRecord, line int) {
+	if line > 0 && line <= len(records) {
+		records[line-1].Target = true
+	}
+}
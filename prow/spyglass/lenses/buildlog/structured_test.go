@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsStructured(t *testing.T) {
+	testcases := []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{
+			name: "logrus output",
+			lines: []string{
+				`{"level":"info","msg":"Starting.","time":"2019-05-01T10:00:00Z"}`,
+				`{"level":"error","msg":"Failed.","time":"2019-05-01T10:00:01Z","error":"boom"}`,
+				"",
+			},
+			expected: true,
+		},
+		{
+			name:     "zap output",
+			lines:    []string{`{"level":"info","ts":1556704800.5,"logger":"main","msg":"Starting."}`},
+			expected: true,
+		},
+		{
+			name:     "plain text",
+			lines:    []string{"Starting.", "Failed."},
+			expected: false,
+		},
+		{
+			name:     "JSON without a level or message",
+			lines:    []string{`{"kind":"Pod"}`, `{"kind":"Node"}`},
+			expected: false,
+		},
+		{
+			name: "mostly plain text",
+			lines: []string{
+				`{"level":"info","msg":"Starting."}`,
+				"Starting.",
+				"Failed.",
+			},
+			expected: false,
+		},
+		{
+			name:     "empty",
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := isStructured(tc.lines); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseStructured(t *testing.T) {
+	scrubber, err := config{SecretPatterns: []string{`hunter2`}}.secretScrubber()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := []string{
+		`{"level":"info","msg":"Logging in with hunter2.","time":"2019-05-01T10:00:00Z","user":"bob","attempt":2}`,
+		`{"severity":"ERROR","message":"Failed.","ts":1556704800.5,"labels":{"app":"deck"}}`,
+		"panic: boom",
+		"",
+	}
+	expected := []StructuredRecord{
+		{
+			Number:  1,
+			Level:   "info",
+			Time:    "2019-05-01T10:00:00Z",
+			Message: "Logging in with [REDACTED].",
+			Fields:  []StructuredField{{Key: "attempt", Value: "2"}, {Key: "user", Value: "bob"}},
+		},
+		{
+			Number:  2,
+			Level:   "ERROR",
+			Time:    "2019-05-01T10:00:00.500Z",
+			Message: "Failed.",
+			Fields:  []StructuredField{{Key: "labels", Value: `{"app":"deck"}`}},
+		},
+		{
+			Number:  3,
+			Message: "panic: boom",
+		},
+	}
+	actual := parseStructured(lines, scrubber)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if actual[1].LevelClass() != "error" {
+		t.Errorf("expected level %q to be styled as an error, got %q", actual[1].Level, actual[1].LevelClass())
+	}
+}
+
+func TestFieldFilter(t *testing.T) {
+	records := []StructuredRecord{
+		{Number: 1, Level: "info", Message: "Syncing pod.", Fields: []StructuredField{{Key: "pod", Value: "deck 1"}}},
+		{Number: 2, Level: "error", Message: "Failed to sync pod.", Fields: []StructuredField{{Key: "pod", Value: "deck 1"}}},
+		{Number: 3, Level: "error", Message: "Failed to sync pod.", Fields: []StructuredField{{Key: "pod", Value: "hook"}}},
+	}
+	testcases := []struct {
+		name     string
+		filter   string
+		expected []int
+	}{
+		{
+			name:     "empty filter",
+			expected: []int{1, 2, 3},
+		},
+		{
+			name:     "level",
+			filter:   "level=error",
+			expected: []int{2, 3},
+		},
+		{
+			name:     "quoted field and level",
+			filter:   `pod="deck 1" level=error`,
+			expected: []int{2},
+		},
+		{
+			name:     "message text",
+			filter:   "syncing",
+			expected: []int{1},
+		},
+		{
+			name:     "missing field",
+			filter:   "node=a",
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []int
+			for _, r := range parseFieldFilter(tc.filter).filter(records) {
+				actual = append(actual, r.Number)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected records %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// summarizeErrors summarizes the failures in the lines of a log, scrubbing secrets from the lines
+// quoted. It returns nil if there are none.
+func summarizeErrors(lines []string, secrets *secretScrubber) *lenses.ErrorSummary {
+	return scrubSummary(lenses.SummarizeErrorLines(lines, lenses.DefaultErrorPatterns), secrets)
+}
+
+// scrubSummary scrubs secrets from the lines a summary of failures quotes. It returns nil if
+// there are no failures.
+func scrubSummary(summary lenses.ErrorSummary, secrets *secretScrubber) *lenses.ErrorSummary {
+	if summary.Total == 0 {
+		return nil
+	}
+	for _, match := range []*lenses.ErrorMatch{summary.First, summary.Last} {
+		match.Text = secrets.scrub(match.Text)
+	}
+	return &summary
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"testing"
+)
+
+func TestSummarizeErrors(t *testing.T) {
+	scrubber, err := config{SecretPatterns: []string{`hunter2`}}.secretScrubber()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary := summarizeErrors([]string{"Starting.", "Done."}, scrubber); summary != nil {
+		t.Errorf("expected no summary of a log without failures, got %+v", summary)
+	}
+
+	summary := summarizeErrors([]string{"Starting.", "ERROR: bad password hunter2", "Retrying.", "FAIL: TestLogin"}, scrubber)
+	if summary == nil {
+		t.Fatal("expected a summary of a log with failures")
+	}
+	if summary.Total != 2 {
+		t.Errorf("expected 2 failure lines, got %d", summary.Total)
+	}
+	if summary.First.Line != 2 || summary.First.Text != "ERROR: bad password [REDACTED]" {
+		t.Errorf("unexpected first failure %+v", summary.First)
+	}
+	if summary.Last.Line != 4 || summary.Last.Text != "FAIL: TestLogin" {
+		t.Errorf("unexpected last failure %+v", summary.Last)
+	}
+}
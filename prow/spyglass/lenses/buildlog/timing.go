@@ -0,0 +1,194 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// slowDelta is the least time since the line before a line that is called out beside it.
+	slowDelta = 10 * time.Second
+	// hangDelta is the least time since the line before a line that is called out as a hang
+	// above it.
+	hangDelta = 5 * time.Minute
+	// startScanLines is how many lines at the beginning of a log are searched for the time it
+	// started at when only part of it is read.
+	startScanLines = 500
+)
+
+// annotateTimes sets the time elapsed since the log started and since the timestamped line
+// before it on each line with a timestamp. If start is zero, the log is taken to start at the
+// first timestamp among the lines, and if prev is zero, the first timestamped line has no time
+// since the line before it. It returns the time of the last timestamped line, or prev if none is.
+func annotateTimes(logLines []LogLine, lines []string, start, prev time.Time) time.Time {
+	for i, line := range lines {
+		if i >= len(logLines) {
+			break
+		}
+		t, ok := lineTimestamp(line)
+		if !ok {
+			continue
+		}
+		if t.Year() == 0 && !start.IsZero() {
+			// klog timestamps have no year, so are given the year of the log's start.
+			t = t.AddDate(start.Year(), 0, 0)
+		}
+		if start.IsZero() {
+			start = t
+		}
+		logLines[i].Timed = true
+		logLines[i].Elapsed = t.Sub(start)
+		if !prev.IsZero() {
+			logLines[i].Delta = t.Sub(prev)
+		}
+		prev = t
+	}
+	return prev
+}
+
+// logStart returns the first timestamp in the beginning of a log, or the zero time if there is
+// none.
+func logStart(a lenses.Artifact) time.Time {
+	size, err := a.Size()
+	if err != nil {
+		return time.Time{}
+	}
+	lines, _, err := readLines(lenses.NewSectionReader(a, 0, size), startScanLines)
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range lines {
+		if t, ok := lineTimestamp(line); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// timesBefore returns the time a log started and the time of the last timestamped line in its
+// first offset bytes, so that the lines after them can be annotated with times.
+func timesBefore(a lenses.Artifact, offset int64) (start, prev time.Time) {
+	if offset <= 0 {
+		return time.Time{}, time.Time{}
+	}
+	lines, err := logLines(a, 0, offset)
+	if err != nil {
+		return time.Time{}, time.Time{}
+	}
+	for _, line := range lines {
+		if t, ok := lineTimestamp(line); ok {
+			start = t
+			break
+		}
+	}
+	return start, annotateTimes(make([]LogLine, len(lines)), lines, start, time.Time{})
+}
+
+// formatDuration formats a duration to a precision that suits its size.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+// TimeNote returns the times shown beside a timestamped line: the time elapsed since the log
+// started, and the time since the timestamped line before it if it is long.
+func (l LogLine) TimeNote() string {
+	if !l.Timed {
+		return ""
+	}
+	note := "+" + formatDuration(l.Elapsed)
+	if l.Slow() {
+		note += fmt.Sprintf(" (%s later)", formatDuration(l.Delta))
+	}
+	return note
+}
+
+// TimeTitle describes the times of a timestamped line in full.
+func (l LogLine) TimeTitle() string {
+	if !l.Timed {
+		return ""
+	}
+	title := formatDuration(l.Elapsed) + " since the log started"
+	if l.Delta != 0 {
+		title += ", " + formatDuration(l.Delta) + " since the previous timestamped line"
+	}
+	return title
+}
+
+// Slow returns whether a line was logged long after the timestamped line before it.
+func (l LogLine) Slow() bool {
+	return l.Delta >= slowDelta
+}
+
+// Hang returns whether nothing was logged for long enough before a line that the job may have
+// hung.
+func (l LogLine) Hang() bool {
+	return l.Delta >= hangDelta
+}
+
+// HangNote describes the hang before a line, e.g. "no output for 14m".
+func (l LogLine) HangNote() string {
+	if !l.Hang() {
+		return ""
+	}
+	d := l.Delta.Round(time.Minute).String()
+	d = strings.TrimSuffix(d, "0s")
+	if strings.HasSuffix(d, "h0m") {
+		d = strings.TrimSuffix(d, "0m")
+	}
+	return "no output for " + d
+}
+
+// linesDuration returns how long some lines took to log, from the first timestamped line among
+// them to the last, or the empty string if fewer than two of them are timestamped.
+func linesDuration(lines []LogLine) string {
+	d, ok := linesElapsed(lines)
+	if !ok {
+		return ""
+	}
+	return formatDuration(d)
+}
+
+// linesElapsed returns how long some lines took to log, from the first timestamped line among
+// them to the last. It returns false if fewer than two of them are timestamped.
+func linesElapsed(lines []LogLine) (time.Duration, bool) {
+	first, last := -1, -1
+	for i, l := range lines {
+		if !l.Timed {
+			continue
+		}
+		if first == -1 {
+			first = i
+		}
+		last = i
+	}
+	if first == last {
+		return 0, false
+	}
+	return lines[last].Elapsed - lines[first].Elapsed, true
+}
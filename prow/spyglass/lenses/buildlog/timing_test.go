@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var timedLines = []string{
+	"I0501 10:00:00.000000 1 main.go:1] Starting.",
+	"no timestamp",
+	"I0501 10:00:02.500000 1 main.go:2] Syncing.",
+	"I0501 10:01:00.000000 1 main.go:3] Synced.",
+}
+
+type lineTimes struct {
+	Timed          bool
+	Elapsed, Delta time.Duration
+}
+
+func timesOf(logLines []LogLine) []lineTimes {
+	var times []lineTimes
+	for _, l := range logLines {
+		times = append(times, lineTimes{Timed: l.Timed, Elapsed: l.Elapsed, Delta: l.Delta})
+	}
+	return times
+}
+
+func TestAnnotateTimes(t *testing.T) {
+	logLines := highlightLines(timedLines, 0, lineRenderer{})
+	annotateTimes(logLines, timedLines, time.Time{}, time.Time{})
+	expected := []lineTimes{
+		{Timed: true},
+		{},
+		{Timed: true, Elapsed: 2500 * time.Millisecond, Delta: 2500 * time.Millisecond},
+		{Timed: true, Elapsed: time.Minute, Delta: 57500 * time.Millisecond},
+	}
+	if actual := timesOf(logLines); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected times %+v, got %+v", expected, actual)
+	}
+	if logLines[2].Slow() || !logLines[3].Slow() {
+		t.Errorf("expected only the last line to be slow")
+	}
+	if note := logLines[3].TimeNote(); note != "+1m0s (57.5s later)" {
+		t.Errorf("expected note %q, got %q", "+1m0s (57.5s later)", note)
+	}
+	if duration := linesDuration(logLines); duration != "1m0s" {
+		t.Errorf("expected the lines to take 1m0s, got %q", duration)
+	}
+}
+
+func TestTimesBefore(t *testing.T) {
+	content := strings.Join(timedLines, "\n") + "\n"
+	a := &largeArtifact{content: []byte(content)}
+	offset := int64(strings.Index(content, "I0501 10:01"))
+	start, prev := timesBefore(a, offset)
+	lines := timedLines[3:]
+	logLines := highlightLines(lines, 3, lineRenderer{})
+	annotateTimes(logLines, lines, start, prev)
+	expected := []lineTimes{{Timed: true, Elapsed: time.Minute, Delta: 57500 * time.Millisecond}}
+	if actual := timesOf(logLines); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected times %+v, got %+v", expected, actual)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	testcases := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 1234567 * time.Microsecond, expected: "1.2s"},
+		{duration: 123456 * time.Microsecond, expected: "123ms"},
+		{duration: 61500 * time.Millisecond, expected: "1m2s"},
+	}
+	for _, tc := range testcases {
+		if actual := formatDuration(tc.duration); actual != tc.expected {
+			t.Errorf("expected %s to be formatted as %q, got %q", tc.duration, tc.expected, actual)
+		}
+	}
+}
+
+func TestHangNote(t *testing.T) {
+	testcases := []struct {
+		delta    time.Duration
+		expected string
+	}{
+		{delta: 4 * time.Minute},
+		{delta: 14*time.Minute + 20*time.Second, expected: "no output for 14m"},
+		{delta: 65 * time.Minute, expected: "no output for 1h5m"},
+		{delta: 2 * time.Hour, expected: "no output for 2h"},
+	}
+	for _, tc := range testcases {
+		if actual := (LogLine{Timed: true, Delta: tc.delta}).HangNote(); actual != tc.expected {
+			t.Errorf("expected a hang of %s to be noted as %q, got %q", tc.delta, tc.expected, actual)
+		}
+	}
+}
+
+func TestGroupLinesShowsHangs(t *testing.T) {
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2019-01-02T15:04:%02dZ step %d", i, i)
+	}
+	lines[20] = "2019-01-02T15:24:20Z step 20"
+	logLines := highlightLines(lines, 0, lineRenderer{})
+	annotateTimes(logLines, lines, time.Time{}, time.Time{})
+	var shown []int
+	for _, group := range groupLines(logLines) {
+		if group.Skip {
+			continue
+		}
+		for _, line := range group.LogLines {
+			shown = append(shown, line.Number)
+		}
+	}
+	if len(shown) != 2*neighborLines+1 || shown[neighborLines] != 21 {
+		t.Errorf("expected the lines around the hang before line 21 to be shown, got %v", shown)
+	}
+	hung := false
+	for _, line := range collapseRepeats(logLines) {
+		hung = hung || line.Number == 21 && line.Hang()
+	}
+	if !hung {
+		t.Error("expected the line after the hang not to be collapsed into the repeats before it")
+	}
+}
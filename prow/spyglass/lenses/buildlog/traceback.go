@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxShownTracebackLines is the most lines a traceback can have to be shown in full. Only the
+// key lines of longer tracebacks, such as the goroutine dumps of timed out Go tests, are shown
+// unless they are expanded.
+const maxShownTracebackLines = 100
+
+// The kinds of tracebacks recognized in logs.
+const (
+	goPanic         = "Go panic"
+	pythonTraceback = "Python traceback"
+	javaStackTrace  = "Java stack trace"
+)
+
+var (
+	goPanicRE          = regexp.MustCompile(`^(?:panic|fatal error): `)
+	goroutineRE        = regexp.MustCompile(`^goroutine \d+ \[[^\]]*\]:$`)
+	goCallRE           = regexp.MustCompile(`^(?:created by .+|\S+\(.*\))$`)
+	goLocationRE       = regexp.MustCompile(`^\t\S+:\d+`)
+	goPanicMachineryRE = regexp.MustCompile(`^(?:runtime\.|testing\.|panic\()`)
+	pythonHeaderRE     = regexp.MustCompile(`^Traceback \(most recent call last\):\s*$`)
+	pythonFileRE       = regexp.MustCompile(`^\s+File ".*", line \d+`)
+	javaHeaderRE       = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?(?:[A-Za-z_$][\w$]*\.)+[\w$]*(?:Exception|Error|Throwable)\b`)
+	javaFrameRE        = regexp.MustCompile(`^\s+at \S+\(.*\)\s*$`)
+	javaOmittedRE      = regexp.MustCompile(`^\s+\.\.\. \d+ (?:more|common frames omitted)\s*$`)
+	javaCausedByRE     = regexp.MustCompile(`^(?:\s*Suppressed: |Caused by: )`)
+)
+
+// Traceback describes a traceback in a log, on its first line.
+type Traceback struct {
+	Kind string
+	// Hidden is the number of its lines hidden while it is folded.
+	Hidden int
+}
+
+// traceback is the extent of a traceback among some lines, and the lines of it that are shown
+// while it is folded: its first line, its top frame and the errors it reports.
+type traceback struct {
+	kind       string
+	start, end int // closed, open
+	key        map[int]bool
+}
+
+// findTracebacks finds the Go panics, Python tracebacks and Java stack traces among some lines.
+func findTracebacks(lines []string) []traceback {
+	var found []traceback
+	for i := 0; i < len(lines); i++ {
+		var t *traceback
+		switch {
+		case goPanicRE.MatchString(lines[i]):
+			t = goTraceback(lines, i)
+		case pythonHeaderRE.MatchString(lines[i]):
+			t = pythonTracebackAt(lines, i)
+		case javaHeaderRE.MatchString(lines[i]):
+			t = javaTraceback(lines, i)
+		}
+		if t != nil {
+			found = append(found, *t)
+			i = t.end - 1
+		}
+	}
+	return found
+}
+
+// goTraceback reads the Go panic beginning at a line, which is followed by the stacks of one or
+// more goroutines. The top frame is the first in the panicking goroutine outside of the runtime
+// and the testing package, which recovers and repanics panics in tests.
+func goTraceback(lines []string, start int) *traceback {
+	t := &traceback{kind: goPanic, start: start, key: map[int]bool{start: true}}
+	end := start + 1
+	goroutines := 0
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		switch {
+		case goroutineRE.MatchString(line):
+			goroutines++
+		case line == "", strings.HasPrefix(line, "\t"), strings.HasPrefix(line, "[signal "), goCallRE.MatchString(line):
+		default:
+			return t.finish(lines, end, goroutines > 0)
+		}
+	}
+	return t.finish(lines, end, goroutines > 0)
+}
+
+// pythonTracebackAt reads the Python traceback beginning at a line, which ends with the
+// exception raised. The top frame is the last, where the exception was raised.
+func pythonTracebackAt(lines []string, start int) *traceback {
+	t := &traceback{kind: pythonTraceback, start: start, key: map[int]bool{start: true}}
+	for end := start + 1; end < len(lines); end++ {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			return t.finish(lines, end, end > start+1)
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			t.key[end] = true
+			return t.finish(lines, end+1, true)
+		}
+	}
+	return t.finish(lines, len(lines), len(lines) > start+1)
+}
+
+// javaTraceback reads the Java stack trace beginning at a line, which is the exception thrown
+// followed by the frames of the stack and the exceptions that caused it. The top frame is the
+// first.
+func javaTraceback(lines []string, start int) *traceback {
+	t := &traceback{kind: javaStackTrace, start: start, key: map[int]bool{start: true}}
+	end := start + 1
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if javaCausedByRE.MatchString(line) {
+			t.key[end] = true
+		} else if !javaFrameRE.MatchString(line) && !javaOmittedRE.MatchString(line) {
+			break
+		}
+	}
+	return t.finish(lines, end, end > start+1 && javaFrameRE.MatchString(lines[start+1]))
+}
+
+// finish ends a traceback before a line, trimming blank lines from its end, and marks its top
+// frame. It returns nil if the lines do not form a traceback.
+func (t *traceback) finish(lines []string, end int, ok bool) *traceback {
+	for end > t.start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	if !ok {
+		return nil
+	}
+	t.end = end
+	for _, i := range t.topFrame(lines) {
+		t.key[i] = true
+	}
+	return t
+}
+
+// topFrame returns the lines of the frame of a traceback that triagers look at first.
+func (t *traceback) topFrame(lines []string) []int {
+	switch t.kind {
+	case goPanic:
+		// Each frame is a call followed by its location. The frames before the first
+		// goroutine's are of the panic, and those of the runtime and the testing package are of
+		// its machinery.
+		var first []int
+		inGoroutine := false
+		for i := t.start + 1; i+1 < t.end; i++ {
+			if goroutineRE.MatchString(lines[i]) {
+				if inGoroutine {
+					break
+				}
+				inGoroutine = true
+				continue
+			}
+			if !inGoroutine || !goCallRE.MatchString(lines[i]) || !goLocationRE.MatchString(lines[i+1]) {
+				continue
+			}
+			frame := []int{i, i + 1}
+			if first == nil {
+				first = frame
+			}
+			if !goPanicMachineryRE.MatchString(lines[i]) {
+				return frame
+			}
+		}
+		return first
+	case pythonTraceback:
+		for i := t.end - 1; i > t.start; i-- {
+			if pythonFileRE.MatchString(lines[i]) {
+				if i+1 < t.end && !pythonFileRE.MatchString(lines[i+1]) && !t.key[i+1] {
+					return []int{i, i + 1}
+				}
+				return []int{i}
+			}
+		}
+	case javaStackTrace:
+		return []int{t.start + 1}
+	}
+	return nil
+}
+
+// markTracebacks marks the tracebacks among some log lines. The text of each log line is the line
+// of the same index. Tracebacks short enough are shown in full, and the key lines of others are.
+func markTracebacks(logLines []LogLine, lines []string) {
+	for _, t := range findTracebacks(lines) {
+		logLines[t.start].Traceback = &Traceback{Kind: t.kind, Hidden: t.end - t.start - len(t.key)}
+		for i := t.start; i < t.end; i++ {
+			logLines[i].InTraceback = true
+			logLines[i].TracebackDetail = !t.key[i]
+			if t.key[i] && i != t.start {
+				logLines[i].TopFrame = !isTracebackError(t.kind, lines[i])
+			}
+			if t.key[i] || t.end-t.start <= maxShownTracebackLines {
+				logLines[i].Skip = false
+			}
+		}
+	}
+}
+
+// isTracebackError returns whether a key line of a traceback reports an error rather than being
+// part of its top frame.
+func isTracebackError(kind, line string) bool {
+	switch kind {
+	case pythonTraceback:
+		return !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t")
+	case javaStackTrace:
+		return javaCausedByRE.MatchString(line)
+	}
+	return false
+}
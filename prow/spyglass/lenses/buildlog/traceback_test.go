@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkTracebacks(t *testing.T) {
+	testcases := []struct {
+		name string
+		log  string
+		kind string
+		// start and end are the numbers of the first and last lines of the traceback.
+		start, end int
+		topFrame   []int
+		detail     []int
+	}{
+		{
+			name: "go panic in a test",
+			log: `--- FAIL: TestX (0.00s)
+panic: boom [recovered]
+	panic: boom
+
+goroutine 6 [running]:
+testing.tRunner.func1(0xc0000b2100)
+	/usr/local/go/src/testing/testing.go:830 +0x392
+panic(0x5165a0, 0x5756d0)
+	/usr/local/go/src/runtime/panic.go:522 +0x1b5
+example.com/x.TestX(0xc0000b2100)
+	/home/x_test.go:6 +0x39
+created by testing.(*T).Run
+	/usr/local/go/src/testing/testing.go:916 +0x35a
+
+exit status 2`,
+			kind:     goPanic,
+			start:    2,
+			end:      13,
+			topFrame: []int{10, 11},
+			detail:   []int{3, 4, 5, 6, 7, 8, 9, 12, 13},
+		},
+		{
+			name: "go panic in the runtime",
+			log: `fatal error: all goroutines are asleep - deadlock!
+
+goroutine 1 [chan receive]:
+main.main()
+	/tmp/x.go:5 +0x4e`,
+			kind:     goPanic,
+			start:    1,
+			end:      5,
+			topFrame: []int{4, 5},
+			detail:   []int{2, 3},
+		},
+		{
+			name: "python traceback",
+			log: `running tests
+Traceback (most recent call last):
+  File "x.py", line 3, in <module>
+    main()
+  File "x.py", line 2, in main
+    raise ValueError("bad")
+ValueError: bad
+done`,
+			kind:     pythonTraceback,
+			start:    2,
+			end:      7,
+			topFrame: []int{5, 6},
+			detail:   []int{3, 4},
+		},
+		{
+			name: "java stack trace",
+			log: `Exception in thread "main" java.lang.IllegalStateException: bad
+	at com.x.Main.run(Main.java:10)
+	at com.x.Main.main(Main.java:5)
+Caused by: java.io.IOException: nope
+	at com.x.Io.read(Io.java:3)
+	... 1 more
+BUILD FAILED`,
+			kind:     javaStackTrace,
+			start:    1,
+			end:      6,
+			topFrame: []int{2},
+			detail:   []int{3, 5, 6},
+		},
+		{
+			name: "panic without a stack",
+			log:  "panic: boom\nexit status 2",
+		},
+		{
+			name: "exception without a stack",
+			log:  "java.lang.IllegalStateException: bad\nBUILD FAILED",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := strings.Split(tc.log, "\n")
+			logLines := highlightLines(lines, 0, lineRenderer{})
+			var kind string
+			var start, end int
+			var topFrame, detail []int
+			for _, line := range logLines {
+				if line.Traceback != nil {
+					if kind != "" {
+						t.Fatalf("expected one traceback, found another on line %d", line.Number)
+					}
+					kind = line.Traceback.Kind
+					start = line.Number
+					if line.Traceback.Hidden != len(tc.detail) {
+						t.Errorf("expected %d hidden lines, got %d", len(tc.detail), line.Traceback.Hidden)
+					}
+				}
+				if line.InTraceback {
+					end = line.Number
+					if line.Skip {
+						t.Errorf("expected line %d of a short traceback to be shown", line.Number)
+					}
+				}
+				if line.TopFrame {
+					topFrame = append(topFrame, line.Number)
+				}
+				if line.TracebackDetail {
+					detail = append(detail, line.Number)
+				}
+			}
+			if kind != tc.kind || start != tc.start || end != tc.end {
+				t.Errorf("expected %q on lines %d-%d, got %q on lines %d-%d", tc.kind, tc.start, tc.end, kind, start, end)
+			}
+			if !reflect.DeepEqual(topFrame, tc.topFrame) {
+				t.Errorf("expected top frame on lines %v, got %v", tc.topFrame, topFrame)
+			}
+			if !reflect.DeepEqual(detail, tc.detail) {
+				t.Errorf("expected detail lines %v, got %v", tc.detail, detail)
+			}
+		})
+	}
+}
+
+func TestLongTracebackKeyLines(t *testing.T) {
+	lines := []string{"panic: test timed out after 10m0s", ""}
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, "goroutine 1 [chan receive]:", "testing.(*T).Run(0xc000001)", "\t/usr/local/go/src/testing/testing.go:1 +0x1", "")
+	}
+	lines = append(lines, "FAIL\texample.com/x\t600.012s")
+	logLines := highlightLines(lines, 0, lineRenderer{})
+	var shown []int
+	for _, line := range logLines {
+		if !line.Skip {
+			shown = append(shown, line.Number)
+		}
+	}
+	if expected := []int{1, 4, 5}; !reflect.DeepEqual(shown, expected) {
+		t.Errorf("expected only lines %v of a long traceback to be shown, got %v", expected, shown)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	// defaultHeadLines and defaultTailLines are how many lines of the beginning and end of logs
+	// too large to read in full are shown.
+	defaultHeadLines = 500
+	defaultTailLines = 1000
+	// defaultGapExpansionLines is how many lines of the middle of a truncated log are shown at a
+	// time.
+	defaultGapExpansionLines = 1000
+)
+
+// LogGap is the middle of a log too large to read in full, which is not shown. Its lines are
+// not counted, so only those at its top can be numbered.
+type LogGap struct {
+	Artifact  string
+	Offset    int64
+	Length    int64
+	StartLine int
+}
+
+// Size returns the size of a gap for display.
+func (g LogGap) Size() string {
+	if g.Length < 1<<20 {
+		return fmt.Sprintf("%.1f KiB", float64(g.Length)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(g.Length)/(1<<20))
+}
+
+// GapExpansion is the lines at the top of a gap in a truncated log, and the gap left below them
+// if they did not reach its end.
+type GapExpansion struct {
+	Lines []LogLine
+	Gap   *LogGap
+}
+
+// truncatedLog is the beginning and end of a log too large to read in full, with the gap between
+// them.
+type truncatedLog struct {
+	head, tail []string
+	gap        LogGap
+}
+
+// readTruncated reads the first head and last tail lines of an artifact too large to read in
+// full.
+func readTruncated(a lenses.Artifact, head, tail int) (truncatedLog, error) {
+	size, err := a.Size()
+	if err != nil {
+		return truncatedLog{}, fmt.Errorf("failed to get size of log %q: %v", a.JobPath(), err)
+	}
+	headLines, headLength, err := readLines(lenses.NewSectionReader(a, 0, size), head)
+	if err != nil {
+		return truncatedLog{}, fmt.Errorf("failed to read beginning of log %q: %v", a.JobPath(), err)
+	}
+	tailLines, tailOffset, err := readTail(a, tail, headLength, size)
+	if err != nil {
+		return truncatedLog{}, fmt.Errorf("failed to read end of log %q: %v", a.JobPath(), err)
+	}
+	return truncatedLog{
+		head: headLines,
+		tail: tailLines,
+		gap: LogGap{
+			Artifact:  a.JobPath(),
+			Offset:    headLength,
+			Length:    tailOffset - headLength,
+			StartLine: len(headLines),
+		},
+	}, nil
+}
+
+// readLines reads up to n lines, returning them and the number of bytes they span.
+func readLines(r io.Reader, n int) ([]string, int64, error) {
+	br := bufio.NewReader(r)
+	var lines []string
+	var length int64
+	for len(lines) < n {
+		text, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if text == "" {
+			break
+		}
+		length += int64(len(text))
+		lines = append(lines, strings.TrimSuffix(text, "\n"))
+		if err == io.EOF {
+			break
+		}
+	}
+	return lines, length, nil
+}
+
+// readTail reads up to the last n lines of an artifact of the given size, stopping at offset
+// from, which begins a line. It returns them and the offset they begin at. A trailing newline
+// ends an empty last line, as when logs are read in full.
+func readTail(a lenses.Artifact, n int, from, size int64) ([]string, int64, error) {
+	length := int64(n+1) * 128
+	for {
+		if length > size-from {
+			length = size - from
+		}
+		content, err := a.ReadTail(length)
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		lines := strings.Split(string(content), "\n")
+		// The first line read is cut off unless the read reached from, so it only counts then.
+		if len(lines) > n || length == size-from {
+			if len(lines) > n {
+				lines = lines[len(lines)-n:]
+			}
+			return lines, size - int64(len(strings.Join(lines, "\n"))), nil
+		}
+		length *= 2
+	}
+}
+
+// expandGap reads the lines at the top of a gap in a truncated log, marking the line a permalink
+// targets and the line of the first failure if they are among them.
+func expandGap(a lenses.Artifact, gap LogGap, count, target, failure int, renderer lineRenderer) (GapExpansion, error) {
+	lines, length, err := readLines(lenses.NewSectionReader(a, gap.Offset, gap.Length), count)
+	if err != nil {
+		return GapExpansion{}, err
+	}
+	logLines := highlightLines(lines, gap.StartLine, renderer)
+	// The lines before the gap may be too many to read, so times since them are unknown.
+	annotateTimes(logLines, lines, logStart(a), time.Time{})
+	if i := target - gap.StartLine - 1; i >= 0 && i < len(logLines) {
+		logLines[i].Target = true
+	}
+	if i := failure - gap.StartLine - 1; i >= 0 && i < len(logLines) {
+		logLines[i].Failure = true
+	}
+	expansion := GapExpansion{Lines: collapseRepeats(logLines)}
+	if length < gap.Length {
+		gap.Offset += length
+		gap.Length -= length
+		gap.StartLine += len(lines)
+		expansion.Gap = &gap
+	}
+	return expansion, nil
+}
+
+// unnumbered removes the numbers of lines whose numbers are unknown.
+func unnumbered(lines []LogLine) []LogLine {
+	for i := range lines {
+		lines[i].Number = 0
+		lines[i].LastRepeat = 0
+	}
+	return lines
+}
+
+// truncatedView reads the beginning and end of a log too large to read in full into a view.
+// Both are shown in full, but the lines of the end cannot be numbered. If a permalink targets a
+// line, the lines around it are shown too, or errLineNotFound is returned if they can't be.
+// Otherwise the lines around the first failure are. If the log has an index, its failures and
+// sections are taken from it instead of scanning the log.
+func truncatedView(a lenses.Artifact, opts options, renderer lineRenderer, target int, index *lenses.LogIndex) (LogArtifactView, error) {
+	log, err := readTruncated(a, opts.headLines, opts.tailLines)
+	if err != nil {
+		return LogArtifactView{}, err
+	}
+	var start time.Time
+	for _, line := range log.head {
+		if t, ok := lineTimestamp(line); ok {
+			start = t
+			break
+		}
+	}
+	head := highlightLines(log.head, 0, renderer)
+	annotateTimes(head, log.head, start, time.Time{})
+	tail := highlightLines(log.tail, 0, renderer)
+	annotateTimes(tail, log.tail, start, time.Time{})
+	av := LogArtifactView{
+		ArtifactName: a.JobPath(),
+		ArtifactLink: a.CanonicalLink(),
+		Gap:          &log.gap,
+	}
+	if target > len(head) {
+		above, expansion, err := expandGapAt(a, log.gap, target, renderer)
+		if err != nil {
+			return LogArtifactView{}, err
+		}
+		av.Gap, av.Target = above, &expansion
+	} else if target > 0 {
+		markTarget(head, target)
+	} else if index != nil {
+		target = markTruncatedFailure(a, &av, log, head, tail, index, renderer)
+	} else if i := markFailure(head, log.head, opts.levels); i >= 0 {
+		// The section holding the failure is unfolded like one holding a target.
+		target = i + 1
+	} else if above, expansion, err := expandGapAtFailure(a, log.gap, opts.levels, renderer); err != nil {
+		logrus.WithError(err).Info("Error finding the first failure in truncated log.")
+	} else if expansion != nil {
+		av.Gap, av.Target = above, expansion
+	} else {
+		markFailure(tail, log.tail, opts.levels)
+	}
+	av.Tail = &LogSection{Artifact: a.JobPath(), LineGroups: []LineGroup{{LogLines: unnumbered(collapseRepeats(tail))}}}
+	av.Sections = sectionLines(a.JobPath(), head, opts.splitIndexed(log.head, index), true)
+	setIndexedDurations(av.Sections, index)
+	unfoldTarget(av.Sections, target)
+	if index != nil {
+		av.Errors = scrubSummary(index.Summary(), opts.secrets)
+	}
+	return av, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// largeArtifact is an artifact too large to read in full.
+type largeArtifact struct {
+	content []byte
+}
+
+func (a *largeArtifact) JobPath() string                  { return "build-log.txt" }
+func (a *largeArtifact) CanonicalLink() string            { return "" }
+func (a *largeArtifact) Size() (int64, error)             { return int64(len(a.content)), nil }
+func (a *largeArtifact) ReadAll() ([]byte, error)         { return nil, lenses.ErrFileTooLarge }
+func (a *largeArtifact) ReadAtMost(int64) ([]byte, error) { return nil, lenses.ErrFileTooLarge }
+
+func (a *largeArtifact) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(a.content).ReadAt(p, off)
+}
+
+func (a *largeArtifact) ReadTail(n int64) ([]byte, error) {
+	if n > int64(len(a.content)) {
+		n = int64(len(a.content))
+	}
+	return a.content[int64(len(a.content))-n:], nil
+}
+
+func numberedLines(n int) []string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return lines
+}
+
+func TestReadTruncated(t *testing.T) {
+	lines := numberedLines(2000)
+	content := strings.Join(lines, "\n") + "\n"
+	log, err := readTruncated(&largeArtifact{content: []byte(content)}, 3, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := lines[:3]; !reflect.DeepEqual(log.head, expected) {
+		t.Errorf("expected head %q, got %q", expected, log.head)
+	}
+	// The trailing newline ends an empty last line.
+	if expected := []string{"line 1998", "line 1999", "line 2000", ""}; !reflect.DeepEqual(log.tail, expected) {
+		t.Errorf("expected tail %q, got %q", expected, log.tail)
+	}
+	expectedGap := strings.Join(lines[3:1997], "\n") + "\n"
+	if actual := content[log.gap.Offset : log.gap.Offset+log.gap.Length]; actual != expectedGap {
+		t.Errorf("expected gap of %d bytes from line 4 to 1997, got %d bytes from %q", len(expectedGap), len(actual), actual[:10])
+	}
+	if log.gap.StartLine != 3 {
+		t.Errorf("expected gap to start after line 3, got %d", log.gap.StartLine)
+	}
+}
+
+func TestReadTruncatedShortLog(t *testing.T) {
+	content := "line 1\nline 2\nline 3"
+	log, err := readTruncated(&largeArtifact{content: []byte(content)}, 2, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"line 3"}; !reflect.DeepEqual(log.tail, expected) {
+		t.Errorf("expected the tail to stop at the head, got %q", log.tail)
+	}
+	if log.gap.Length != 0 {
+		t.Errorf("expected no gap, got %+v", log.gap)
+	}
+}
+
+func TestExpandGap(t *testing.T) {
+	lines := numberedLines(30)
+	a := &largeArtifact{content: []byte(strings.Join(lines, "\n") + "\n")}
+	log, err := readTruncated(a, 5, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gap := &log.gap
+	var numbers []int
+	for gap != nil {
+		expansion, err := expandGap(a, *gap, 8, 0, 0, lineRenderer{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, line := range expansion.Lines {
+			// The numbered lines differ only by numbers, so they are collapsed as repeats.
+			for i := 0; i <= line.Repeats; i++ {
+				numbers = append(numbers, line.Number+i)
+			}
+		}
+		gap = expansion.Gap
+	}
+	var expected []int
+	for i := 6; i <= 26; i++ {
+		expected = append(expected, i)
+	}
+	if !reflect.DeepEqual(numbers, expected) {
+		t.Errorf("expected expanding the gap to show lines %v, got %v", expected, numbers)
+	}
+}
+
+func TestLineLimits(t *testing.T) {
+	testcases := []struct {
+		name                 string
+		config               string
+		expectedErr          bool
+		headLines, tailLines int
+	}{
+		{
+			name:      "defaults",
+			headLines: defaultHeadLines,
+			tailLines: defaultTailLines,
+		},
+		{
+			name:      "default head and tail lines fit within the most lines",
+			config:    `{"max_lines": 300}`,
+			headLines: 100,
+			tailLines: 200,
+		},
+		{
+			name:        "head and tail lines must fit within the most lines",
+			config:      `{"max_lines": 300, "head_lines": 200}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative limit",
+			config:      `{"max_expanded_lines": -1}`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parseConfig(json.RawMessage(tc.config))
+			if err != nil != tc.expectedErr {
+				t.Fatalf("expected error: %t, got %v", tc.expectedErr, err)
+			}
+			if err == nil && (opts.headLines != tc.headLines || opts.tailLines != tc.tailLines) {
+				t.Errorf("expected %d head and %d tail lines, got %d and %d", tc.headLines, tc.tailLines, opts.headLines, opts.tailLines)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changedfiles
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+// Weights of the evidence that a changed file caused the failures.
+const (
+	mentionWeight = 4
+	packageWeight = 2
+	coveredWeight = 1
+)
+
+var (
+	// mentionRE matches a file and line mentioned in test output, such as "foo_test.go:12" or
+	// "/go/src/k8s.io/test-infra/prow/foo.go:34 +0x1d".
+	mentionRE = regexp.MustCompile(`((?:[\w.\-]*/)*[\w.\-]+\.[A-Za-z]\w*):(\d+)`)
+	// profileRE matches a block of a Go coverage profile, such as
+	// "k8s.io/test-infra/prow/foo.go:12.34,15.2 3 1".
+	profileRE = regexp.MustCompile(`^(.+):(\d+)\.\d+,(\d+)\.\d+ \d+ (\d+)$`)
+)
+
+// Failure is a failed test, or a package that failed without any of its tests failing.
+type Failure struct {
+	Package string
+	Test    string
+	Output  string
+	// Source is the artifact the failure was read from.
+	Source string
+}
+
+// Name returns the name of the failed test, or of the package if no test failed.
+func (f *Failure) Name() string {
+	if f.Test == "" {
+		return f.Package
+	}
+	if f.Package == "" {
+		return f.Test
+	}
+	return f.Package + " " + f.Test
+}
+
+// block is a range of lines of a file that a coverage profile counts the executions of.
+type block struct {
+	start, end, count int
+}
+
+// evidence is what the job's other artifacts say about its failures.
+type evidence struct {
+	Failures []*Failure
+	// coverage is the blocks of each file, named by import path as in the profiles.
+	coverage map[string][]block
+}
+
+// addJUnit adds the failed tests of a JUnit report. Go reports written by go-junit-report name
+// the package of each test as its class.
+func (e *evidence) addJUnit(content []byte, source string) error {
+	suites, err := junit.Parse(content)
+	if err != nil {
+		return err
+	}
+	for _, suite := range suites.Suites {
+		for _, r := range suite.Results {
+			if r.Failure == nil {
+				continue
+			}
+			output := *r.Failure
+			if r.Output != nil {
+				output += "\n" + *r.Output
+			}
+			e.Failures = append(e.Failures, &Failure{Package: r.ClassName, Test: r.Name, Output: output, Source: source})
+		}
+	}
+	return nil
+}
+
+// testEvent is a line of `go test -json` output.
+type testEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
+}
+
+// testKey identifies a test, or a package if test is empty.
+type testKey struct {
+	pkg, test string
+}
+
+// addGoTest adds the failed tests of `go test -json` output, and the packages that failed
+// without any of their tests failing, such as those that did not build or that panicked
+// outside a test. Lines that are not events are ignored.
+func (e *evidence) addGoTest(content []byte, source string) error {
+	output := map[testKey]*strings.Builder{}
+	var failed []testKey
+	failedTests := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var ev testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil || ev.Action == "" {
+			continue
+		}
+		k := testKey{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output":
+			if output[k] == nil {
+				output[k] = &strings.Builder{}
+			}
+			output[k].WriteString(ev.Output)
+		case "fail":
+			failed = append(failed, k)
+			if ev.Test != "" {
+				failedTests[ev.Package] = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, k := range failed {
+		// Parents of failed subtests fail too, but their output is that of their subtests.
+		if k.test == "" && failedTests[k.pkg] || isParent(k.pkg, k.test, failed) {
+			continue
+		}
+		f := &Failure{Package: k.pkg, Test: k.test, Source: source}
+		if b := output[k]; b != nil {
+			f.Output = b.String()
+		}
+		e.Failures = append(e.Failures, f)
+	}
+	return nil
+}
+
+// isParent returns whether any of failed is a subtest of test in pkg.
+func isParent(pkg, test string, failed []testKey) bool {
+	if test == "" {
+		return false
+	}
+	for _, f := range failed {
+		if f.pkg == pkg && strings.HasPrefix(f.test, test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// addCoverage adds the blocks of a Go coverage profile.
+func (e *evidence) addCoverage(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "mode: ") {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("not a Go coverage profile")
+	}
+	if e.coverage == nil {
+		e.coverage = map[string][]block{}
+	}
+	for scanner.Scan() {
+		m := profileRE.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[2])
+		end, _ := strconv.Atoi(m[3])
+		count, _ := strconv.Atoi(m[4])
+		e.coverage[m[1]] = append(e.coverage[m[1]], block{start: start, end: end, count: count})
+	}
+	return scanner.Err()
+}
+
+// HasCoverage returns whether any coverage profiles were read.
+func (e *evidence) HasCoverage() bool {
+	return e.coverage != nil
+}
+
+// Mention is a line of a changed file that a failure's output mentions.
+type Mention struct {
+	Line    int
+	Failure *Failure
+}
+
+// Suspect is a changed file that may have caused the failures, with the evidence that it did.
+type Suspect struct {
+	File  *ChangedFile
+	Score int
+	// Mentions are the lines of the file mentioned by failures.
+	Mentions []Mention
+	// Failures are the failures of the package the file is in.
+	Failures []*Failure
+	// Covered are the changed lines that tests executed, if coverage was collected.
+	Covered []int
+}
+
+// matchesPackage returns whether a Go package, named by its import path, is the directory dir
+// of the repository. roots are the import paths of the root of the repository.
+func matchesPackage(pkg, dir string, roots []string) bool {
+	if pkg == "" {
+		return false
+	}
+	pkg = strings.TrimSuffix(pkg, "_test")
+	if dir == "." {
+		for _, r := range roots {
+			if pkg == r {
+				return true
+			}
+		}
+		return false
+	}
+	return pkg == dir || strings.HasSuffix(pkg, "/"+dir)
+}
+
+// matchesPath returns whether a path, which may be absolute or prefixed by an import path, is
+// the file named name in the repository.
+func matchesPath(path, name string) bool {
+	return path == name || strings.HasSuffix(path, "/"+name)
+}
+
+// suspects cross-references the changed files against the evidence, returning the files that
+// may have caused the failures, most likely first.
+func suspects(files []*ChangedFile, e *evidence, roots []string) []*Suspect {
+	bases := map[string]int{}
+	for _, f := range files {
+		bases[f.Base()]++
+	}
+	var result []*Suspect
+	for _, f := range files {
+		if f.Status == "removed" {
+			continue
+		}
+		s := &Suspect{File: f}
+		seen := map[Mention]bool{}
+		for _, failure := range e.Failures {
+			if matchesPackage(failure.Package, f.Dir(), roots) {
+				s.Failures = append(s.Failures, failure)
+			}
+			for _, m := range mentionRE.FindAllStringSubmatch(failure.Output, -1) {
+				path := m[1]
+				if strings.Contains(path, "/") {
+					if !matchesPath(path, f.Filename) {
+						continue
+					}
+				} else if path != f.Base() || bases[path] > 1 && !matchesPackage(failure.Package, f.Dir(), roots) {
+					// Go prints only the base name of test files, so base names are resolved by
+					// the failure's package if more than one changed file has them.
+					continue
+				}
+				line, _ := strconv.Atoi(m[2])
+				mention := Mention{Line: line, Failure: failure}
+				if !seen[mention] {
+					seen[mention] = true
+					s.Mentions = append(s.Mentions, mention)
+				}
+			}
+		}
+		for name, blocks := range e.coverage {
+			if !matchesPath(name, f.Filename) {
+				continue
+			}
+			for _, line := range f.Lines {
+				for _, b := range blocks {
+					if b.count > 0 && b.start <= line && line <= b.end {
+						s.Covered = append(s.Covered, line)
+						break
+					}
+				}
+			}
+		}
+		if len(s.Mentions) > 0 {
+			s.Score += mentionWeight
+		}
+		if len(s.Failures) > 0 {
+			s.Score += packageWeight
+		}
+		if len(s.Covered) > 0 {
+			s.Score += coveredWeight
+		}
+		if s.Score > 0 {
+			result = append(result, s)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		if len(result[i].Mentions) != len(result[j].Mentions) {
+			return len(result[i].Mentions) > len(result[j].Mentions)
+		}
+		return len(result[i].Covered) > len(result[j].Covered)
+	})
+	return result
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changedfiles
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testJUnit = `<testsuites>
+  <testsuite name="k8s.io/test-infra/prow/plugins/lgtm" tests="2" failures="1">
+    <testcase classname="k8s.io/test-infra/prow/plugins/lgtm" name="TestLGTM" time="0.01">
+      <failure message="Failed" type="">lgtm_test.go:42: expected label to be added</failure>
+    </testcase>
+    <testcase classname="k8s.io/test-infra/prow/plugins/lgtm" name="TestHelp" time="0.01"></testcase>
+  </testsuite>
+</testsuites>`
+
+const testGoTest = `{"Action":"run","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook"}
+{"Action":"run","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook/events"}
+{"Action":"output","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook/events","Output":"panic: nil map\n"}
+{"Action":"output","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook/events","Output":"\t/go/src/k8s.io/test-infra/prow/hook/events.go:99 +0x1d\n"}
+{"Action":"fail","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook/events"}
+{"Action":"fail","Package":"k8s.io/test-infra/prow/hook","Test":"TestHook"}
+{"Action":"fail","Package":"k8s.io/test-infra/prow/hook"}
+not an event
+{"Action":"output","Package":"k8s.io/test-infra/prow/cmd/deck","Output":"main.go:5:2: undefined: foo\n"}
+{"Action":"fail","Package":"k8s.io/test-infra/prow/cmd/deck"}
+`
+
+const testProfile = `mode: set
+k8s.io/test-infra/prow/hook/server.go:10.2,12.3 2 1
+k8s.io/test-infra/prow/hook/server.go:20.2,25.3 4 0
+`
+
+func TestEvidence(t *testing.T) {
+	e := &evidence{}
+	if err := e.addJUnit([]byte(testJUnit), "junit.xml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := e.addGoTest([]byte(testGoTest), "go-test.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, f := range e.Failures {
+		names = append(names, f.Name())
+	}
+	expected := []string{
+		"k8s.io/test-infra/prow/plugins/lgtm TestLGTM",
+		"k8s.io/test-infra/prow/hook TestHook/events",
+		"k8s.io/test-infra/prow/cmd/deck",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected failures %q, got %q", expected, names)
+	}
+	if err := e.addCoverage([]byte("not a profile")); err == nil {
+		t.Error("expected an error for a file that is not a coverage profile")
+	}
+	if err := e.addCoverage([]byte(testProfile)); err != nil || len(e.coverage["k8s.io/test-infra/prow/hook/server.go"]) != 2 {
+		t.Errorf("expected two blocks of server.go, got %v: %v", e.coverage, err)
+	}
+}
+
+func TestSuspects(t *testing.T) {
+	e := &evidence{}
+	e.addJUnit([]byte(testJUnit), "junit.xml")
+	e.addGoTest([]byte(testGoTest), "go-test.json")
+	e.addCoverage([]byte(testProfile))
+	files := []*ChangedFile{
+		{Filename: "README.md", Lines: []int{1}},
+		{Filename: "prow/hook/server.go", Lines: []int{11, 21}},
+		{Filename: "prow/hook/events.go", Lines: []int{99}},
+		{Filename: "prow/plugins/lgtm/lgtm_test.go", Lines: []int{42}},
+		{Filename: "prow/plugins/approve/approve_test.go"},
+		{Filename: "prow/cmd/deck/main.go", Status: "removed"},
+	}
+	result := suspects(files, e, []string{"k8s.io/test-infra"})
+	var names []string
+	for _, s := range result {
+		names = append(names, s.File.Filename)
+	}
+	expected := []string{"prow/hook/events.go", "prow/plugins/lgtm/lgtm_test.go", "prow/hook/server.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected suspects %q, got %q", expected, names)
+	}
+	if s := result[0]; s.Score != mentionWeight+packageWeight || len(s.Mentions) != 1 || s.Mentions[0].Line != 99 {
+		t.Errorf("expected events.go to be mentioned on line 99, got %+v", s)
+	}
+	if s := result[2]; s.Score != packageWeight+coveredWeight || !reflect.DeepEqual(s.Covered, []int{11}) {
+		t.Errorf("expected line 11 of server.go to be covered, got %+v", s)
+	}
+}
+
+func TestMatchesPackage(t *testing.T) {
+	testCases := []struct {
+		pkg, dir string
+		expected bool
+	}{
+		{"k8s.io/test-infra/prow/hook", "prow/hook", true},
+		{"k8s.io/test-infra/prow/hook_test", "prow/hook", true},
+		{"k8s.io/test-infra/prow/hooks", "prow/hook", false},
+		{"k8s.io/test-infra", ".", true},
+		{"k8s.io/test-infra/prow", ".", false},
+		{"", "prow", false},
+	}
+	for _, tc := range testCases {
+		if actual := matchesPackage(tc.pkg, tc.dir, []string{"k8s.io/test-infra"}); actual != tc.expected {
+			t.Errorf("expected %q in %q to be %t, got %t", tc.pkg, tc.dir, tc.expected, actual)
+		}
+	}
+}
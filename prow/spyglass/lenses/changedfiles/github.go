@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changedfiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEndpoint is the GitHub API that changed files are fetched from if not configured.
+	defaultEndpoint = "https://api.github.com"
+	// perPage is how many files are requested in each page. GitHub allows at most 100.
+	perPage = 100
+	// defaultMaxFiles is how many changed files are fetched if not configured. GitHub lists at
+	// most 3000 files of a pull request.
+	defaultMaxFiles = 3000
+	// fetchTimeout bounds how long fetching each page of changed files may take.
+	fetchTimeout = 30 * time.Second
+	// maxCacheEntries bounds how many pull requests' changed files are kept in memory.
+	maxCacheEntries = 500
+)
+
+// hunkRE matches the header of a hunk of a unified diff, such as "@@ -10,7 +10,8 @@ func f() {".
+var hunkRE = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ChangedFile is a file changed by a pull request.
+type ChangedFile struct {
+	Pull      int
+	Filename  string
+	Status    string
+	Additions int
+	Deletions int
+	// Previous is the file's name before it was renamed, if it was.
+	Previous string
+	// Lines are the lines of the new file that the pull request added or changed, in order.
+	// They are unknown if GitHub omitted the patch, as it does for large and binary files.
+	Lines []int
+}
+
+// Dir returns the directory the file is in, or "." for files at the root of the repository.
+func (f *ChangedFile) Dir() string {
+	if i := strings.LastIndex(f.Filename, "/"); i >= 0 {
+		return f.Filename[:i]
+	}
+	return "."
+}
+
+// Base returns the name of the file without its directory.
+func (f *ChangedFile) Base() string {
+	return f.Filename[strings.LastIndex(f.Filename, "/")+1:]
+}
+
+// githubFile is a file in GitHub's list of the files of a pull request.
+type githubFile struct {
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	Patch            string `json:"patch"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+// changedLines returns the lines of the new file that a patch adds, in order.
+func changedLines(patch string) []int {
+	var lines []int
+	line := 0
+	for _, l := range strings.Split(patch, "\n") {
+		if m := hunkRE.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if line == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(l, "+"):
+			lines = append(lines, line)
+			line++
+		case strings.HasPrefix(l, "-"), strings.HasPrefix(l, `\`):
+			// Removed lines and "\ No newline at end of file" are not in the new file.
+		default:
+			line++
+		}
+	}
+	return lines
+}
+
+// fetcher fetches the files changed by pull requests from the GitHub API.
+type fetcher struct {
+	endpoint string
+	token    string
+	maxFiles int
+	client   *http.Client
+}
+
+// fetch lists the files changed by a pull request, fetching pages until GitHub has no more or
+// maxFiles have been listed.
+func (f *fetcher) fetch(org, repo string, number int) ([]*ChangedFile, error) {
+	var files []*ChangedFile
+	for page := 1; len(files) < f.maxFiles; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=%d&page=%d", strings.TrimSuffix(f.endpoint, "/"), org, repo, number, perPage, page)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		if f.token != "" {
+			req.Header.Set("Authorization", "token "+f.token)
+		}
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var listed []githubFile
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&listed)
+		} else {
+			err = fmt.Errorf("%s returned %s", url, resp.Status)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range listed {
+			files = append(files, &ChangedFile{
+				Pull:      number,
+				Filename:  g.Filename,
+				Status:    g.Status,
+				Additions: g.Additions,
+				Deletions: g.Deletions,
+				Previous:  g.PreviousFilename,
+				Lines:     changedLines(g.Patch),
+			})
+		}
+		if len(listed) < perPage {
+			break
+		}
+	}
+	if len(files) > f.maxFiles {
+		files = files[:f.maxFiles]
+	}
+	return files, nil
+}
+
+// fileCache holds the files changed by pull requests. Entries are keyed by the head of the pull
+// request, so they never go stale; the cache is emptied when it fills up.
+type fileCache struct {
+	lock    sync.Mutex
+	entries map[string][]*ChangedFile
+}
+
+var cache = &fileCache{entries: map[string][]*ChangedFile{}}
+
+// load returns the files changed by a pull request at a commit, fetching them if they are not
+// cached. Failures are not cached.
+func (c *fileCache) load(f *fetcher, org, repo string, number int, sha string) ([]*ChangedFile, error) {
+	key := fmt.Sprintf("%s/%s/%s#%d@%s", f.endpoint, org, repo, number, sha)
+	c.lock.Lock()
+	files, ok := c.entries[key]
+	c.lock.Unlock()
+	if ok && sha != "" {
+		return files, nil
+	}
+	files, err := f.fetch(org, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		c.entries = map[string][]*ChangedFile{}
+	}
+	c.entries[key] = files
+	return files, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changedfiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestChangedLines(t *testing.T) {
+	patch := "@@ -1,3 +1,4 @@\n package foo\n-var a = 1\n+var a = 2\n+var b = 3\n \n@@ -10,2 +11,2 @@ func f() {\n-\treturn\n+\treturn nil\n }\n\\ No newline at end of file"
+	expected := []int{2, 3, 11}
+	if lines := changedLines(patch); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected changed lines %v, got %v", expected, lines)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/org/repo/pulls/7/files" || r.Header.Get("Authorization") != "token secret" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pages = append(pages, r.URL.Query().Get("page"))
+		count := perPage
+		if page == 2 {
+			count = 5
+		}
+		var files []githubFile
+		for i := 0; i < count; i++ {
+			files = append(files, githubFile{Filename: fmt.Sprintf("dir/%d-%d.go", page, i), Status: "modified", Patch: "@@ -1 +1 @@\n-a\n+b"})
+		}
+		json.NewEncoder(w).Encode(files)
+	}))
+	defer server.Close()
+
+	f := &fetcher{endpoint: server.URL, token: "secret", maxFiles: defaultMaxFiles, client: server.Client()}
+	files, err := f.fetch("org", "repo", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != perPage+5 || !reflect.DeepEqual(pages, []string{"1", "2"}) {
+		t.Fatalf("expected %d files from pages 1 and 2, got %d from %v", perPage+5, len(files), pages)
+	}
+	if last := files[len(files)-1]; last.Filename != "dir/2-4.go" || last.Pull != 7 || !reflect.DeepEqual(last.Lines, []int{1}) {
+		t.Errorf("unexpected last file: %+v", last)
+	}
+
+	pages = nil
+	f.maxFiles = 50
+	if files, err = f.fetch("org", "repo", 7); err != nil || len(files) != 50 || len(pages) != 1 {
+		t.Errorf("expected 50 files from one page, got %d from %v: %v", len(files), pages, err)
+	}
+
+	f.token = ""
+	if _, err := f.fetch("org", "repo", 7); err == nil {
+		t.Error("expected an error for an unauthorized request")
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package changedfiles provides a Spyglass lens that lists the files changed by the pull
+// requests a job tested and suggests which of them caused its failures.
+package changedfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "changedfiles"
+	title    = "Changed Files"
+	priority = 43
+
+	// defaultMaxBytes is how much of each test result and coverage profile is read if not
+	// configured.
+	defaultMaxBytes = 20 << 20
+	// Patterns of the names of the artifacts read for evidence, if not configured.
+	defaultJUnitPattern    = `(^|/)junit[^/]*\.xml$`
+	defaultGoTestPattern   = `(^|/)[^/]*(go-?test|test2json)[^/]*\.json$`
+	defaultCoveragePattern = `(^|/)([^/]*\.cov|[^/]*coverprofile[^/]*|cover(age)?[^/]*\.out)$`
+)
+
+// Lens is the implementation of a changed file-listing Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// GitHubAPIEndpoint is the GitHub API that changed files are fetched from. Defaults to
+	// https://api.github.com.
+	GitHubAPIEndpoint string `json:"github_api_endpoint,omitempty"`
+	// GitHubTokenPath is the path to an OAuth token used to fetch changed files, such as one
+	// mounted from a Secret. Without it, requests are subject to GitHub's anonymous rate limit.
+	GitHubTokenPath string `json:"github_token_path,omitempty"`
+	// MaxFiles is how many of each pull request's changed files are fetched. Defaults to 3000.
+	MaxFiles int `json:"max_files,omitempty"`
+	// JUnitPattern matches the names of JUnit reports to read failed tests from.
+	JUnitPattern string `json:"junit_pattern,omitempty"`
+	// GoTestPattern matches the names of `go test -json` outputs to read failed tests from.
+	GoTestPattern string `json:"go_test_pattern,omitempty"`
+	// CoveragePattern matches the names of Go coverage profiles.
+	CoveragePattern string `json:"coverage_pattern,omitempty"`
+	// MaxBytes is how much of each test result and coverage profile is read. Defaults to 20MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration. It asks for the job's other artifacts, which hold the
+// failed tests and coverage.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+		Siblings: true,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// FileView is a changed file with a link to it at the head of its pull request.
+type FileView struct {
+	*ChangedFile
+	Link    string
+	Suspect bool
+}
+
+// MentionView is a mentioned line with a link to it.
+type MentionView struct {
+	Mention
+	Link string
+	// Changed is whether the pull request changed the mentioned line.
+	Changed bool
+}
+
+// SuspectView is a suspect with links to the file and its mentioned lines.
+type SuspectView struct {
+	*Suspect
+	Link     string
+	Mentions []MentionView
+}
+
+// PullView is the changed files of a pull request.
+type PullView struct {
+	Number int
+	Link   string
+	Author string
+	Error  string
+	Files  []FileView
+}
+
+type changedFilesView struct {
+	Errors []string
+	// Message explains why there is nothing to show, such as the job not testing a pull request.
+	Message  string
+	Pulls    []PullView
+	Suspects []SuspectView
+	Failures int
+	Coverage bool
+	Sources  []string
+}
+
+// Body renders the changed files of each pull request the job tested, after the files most
+// likely to have caused its failures.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := changedFilesView{}
+	conf := config{}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.GitHubAPIEndpoint == "" {
+		conf.GitHubAPIEndpoint = defaultEndpoint
+	}
+	if conf.MaxFiles <= 0 {
+		conf.MaxFiles = defaultMaxFiles
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	pattern := func(p, fallback string) *regexp.Regexp {
+		if p == "" {
+			p = fallback
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+			re = regexp.MustCompile(fallback)
+		}
+		return re
+	}
+	junitRE := pattern(conf.JUnitPattern, defaultJUnitPattern)
+	goTestRE := pattern(conf.GoTestPattern, defaultGoTestPattern)
+	coverageRE := pattern(conf.CoveragePattern, defaultCoveragePattern)
+
+	matched, siblings := lenses.SplitSiblings(artifacts)
+	refs, err := readRefs(matched)
+	if err != nil {
+		view.Errors = append(view.Errors, fmt.Sprintf("Failed to find the refs the job tested: %v", err))
+		return executeTemplate(resourceDir, "body", view)
+	}
+	if refs == nil || len(refs.Pulls) == 0 {
+		view.Message = "This job did not test a pull request."
+		return executeTemplate(resourceDir, "body", view)
+	}
+
+	token := ""
+	if conf.GitHubTokenPath != "" {
+		content, err := ioutil.ReadFile(conf.GitHubTokenPath)
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read GitHub token: %v", err))
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	f := &fetcher{
+		endpoint: conf.GitHubAPIEndpoint,
+		token:    token,
+		maxFiles: conf.MaxFiles,
+		client:   &http.Client{Timeout: fetchTimeout},
+	}
+	repoLink := refs.RepoLink
+	if repoLink == "" {
+		repoLink = fmt.Sprintf("https://github.com/%s/%s", refs.Org, refs.Repo)
+	}
+	var files []*ChangedFile
+	heads := map[int]string{}
+	for _, pull := range refs.Pulls {
+		pv := PullView{Number: pull.Number, Link: pull.Link, Author: pull.Author}
+		if pv.Link == "" {
+			pv.Link = fmt.Sprintf("%s/pull/%d", repoLink, pull.Number)
+		}
+		heads[pull.Number] = pull.SHA
+		changed, err := cache.load(f, refs.Org, refs.Repo, pull.Number, pull.SHA)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to fetch the files changed by %s/%s#%d", refs.Org, refs.Repo, pull.Number)
+			pv.Error = fmt.Sprintf("Failed to fetch changed files: %v", err)
+		}
+		for _, c := range changed {
+			fv := FileView{ChangedFile: c}
+			if c.Status != "removed" {
+				fv.Link = fileLink(repoLink, pull.SHA, c.Filename)
+			}
+			pv.Files = append(pv.Files, fv)
+		}
+		files = append(files, changed...)
+		view.Pulls = append(view.Pulls, pv)
+	}
+
+	e := &evidence{}
+	sort.Slice(siblings, func(i, j int) bool { return siblings[i].JobPath() < siblings[j].JobPath() })
+	for _, a := range siblings {
+		var add func([]byte) error
+		switch path := a.JobPath(); {
+		case junitRE.MatchString(path):
+			add = func(content []byte) error { return e.addJUnit(content, path) }
+		case goTestRE.MatchString(path):
+			add = func(content []byte) error { return e.addGoTest(content, path) }
+		case coverageRE.MatchString(path):
+			add = e.addCoverage
+		default:
+			continue
+		}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err == nil || err == io.EOF {
+			err = add(content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		view.Sources = append(view.Sources, a.JobPath())
+	}
+	view.Failures = len(e.Failures)
+	view.Coverage = e.HasCoverage()
+	if len(e.Failures) == 0 {
+		return executeTemplate(resourceDir, "body", view)
+	}
+
+	roots := []string{"github.com/" + refs.Org + "/" + refs.Repo}
+	if refs.PathAlias != "" {
+		roots = append(roots, refs.PathAlias)
+	}
+	suspected := map[*ChangedFile]bool{}
+	for _, s := range suspects(files, e, roots) {
+		suspected[s.File] = true
+		sv := SuspectView{Suspect: s, Link: fileLink(repoLink, heads[s.File.Pull], s.File.Filename)}
+		for _, m := range s.Mentions {
+			mv := MentionView{Mention: m, Link: fmt.Sprintf("%s#L%d", sv.Link, m.Line)}
+			for _, line := range s.File.Lines {
+				mv.Changed = mv.Changed || line == m.Line
+			}
+			sv.Mentions = append(sv.Mentions, mv)
+		}
+		view.Suspects = append(view.Suspects, sv)
+	}
+	for _, pv := range view.Pulls {
+		for i := range pv.Files {
+			pv.Files[i].Suspect = suspected[pv.Files[i].ChangedFile]
+		}
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// readRefs reads the refs the job tested from its prowjob.json, returning nil if the job did not
+// test any refs.
+func readRefs(artifacts []lenses.Artifact) (*prowapi.Refs, error) {
+	for _, a := range artifacts {
+		if a.JobPath() != "prowjob.json" {
+			continue
+		}
+		content, err := a.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prowjob.json: %v", err)
+		}
+		var pj prowapi.ProwJob
+		if err := json.Unmarshal(content, &pj); err != nil {
+			return nil, fmt.Errorf("failed to parse prowjob.json: %v", err)
+		}
+		return pj.Spec.Refs, nil
+	}
+	return nil, errors.New("no prowjob.json found")
+}
+
+// fileLink links to a file at a commit, or returns "" if the commit is unknown.
+func fileLink(repoLink, sha, filename string) string {
+	if sha == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/blob/%s/%s", repoLink, sha, filename)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
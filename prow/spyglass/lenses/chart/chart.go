@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chart renders simple line charts as SVG for use by Spyglass lenses.
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+const (
+	width        = 800
+	height       = 240
+	marginLeft   = 60
+	marginRight  = 10
+	marginTop    = 10
+	marginBottom = 25
+	tickCount    = 5
+)
+
+// Colors is the palette used for lines, in order.
+var Colors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// ErrNoData is returned when none of the series have any finite points to draw.
+var ErrNoData = errors.New("no finite values to chart")
+
+// Point is a single point on a line.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Series is a named line to draw. Its points should be ordered by X.
+type Series struct {
+	Label  string
+	Points []Point
+}
+
+// Options controls how a chart is drawn.
+type Options struct {
+	// FormatX formats the x axis tick labels. Defaults to FormatValue.
+	FormatX func(x float64) string
+	// FormatY formats the y axis tick labels and the last value of each line. Defaults to FormatValue.
+	FormatY func(y float64) string
+	// XTicks, if set, are the x values to label. Otherwise ticks are evenly spaced.
+	XTicks []float64
+}
+
+// Chart is a rendered line chart.
+type Chart struct {
+	Lines  []Line
+	XTicks []Tick
+	YTicks []Tick
+	Width  int
+	Height int
+	// PlotLeft and PlotRight are the horizontal bounds of the plot area.
+	PlotLeft  int
+	PlotRight int
+}
+
+// Line is a single series in a chart.
+type Line struct {
+	Label string
+	Color string
+	// Points is the value of the SVG polyline points attribute.
+	Points string
+	// Last is the formatted final value of the series.
+	Last string
+}
+
+// Tick is an axis label at a position in SVG coordinates.
+type Tick struct {
+	X     float64
+	Y     float64
+	Label string
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// New lays out a chart of the given series. The y axis always includes zero.
+func New(series []Series, opts Options) (*Chart, error) {
+	if opts.FormatX == nil {
+		opts.FormatX = FormatValue
+	}
+	if opts.FormatY == nil {
+		opts.FormatY = FormatValue
+	}
+
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := 0.0, math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			if !finite(p.X) || !finite(p.Y) {
+				continue
+			}
+			minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+			minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+		}
+	}
+	if math.IsInf(maxY, -1) {
+		return nil, ErrNoData
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	if maxY == minY {
+		maxY = minY + 1
+	}
+	plotWidth := float64(width - marginLeft - marginRight)
+	plotHeight := float64(height - marginTop - marginBottom)
+	// Coordinates are rounded to keep the markup small.
+	scaleX := func(v float64) float64 { return math.Round((marginLeft+(v-minX)/(maxX-minX)*plotWidth)*10) / 10 }
+	scaleY := func(v float64) float64 { return math.Round((marginTop+(1-(v-minY)/(maxY-minY))*plotHeight)*10) / 10 }
+
+	c := &Chart{Width: width, Height: height, PlotLeft: marginLeft, PlotRight: width - marginRight}
+	for i, s := range series {
+		var coords []string
+		last := math.NaN()
+		for _, p := range s.Points {
+			if !finite(p.X) || !finite(p.Y) {
+				continue
+			}
+			coords = append(coords, fmt.Sprintf("%g,%g", scaleX(p.X), scaleY(p.Y)))
+			last = p.Y
+		}
+		line := Line{Label: s.Label, Color: Colors[i%len(Colors)], Points: strings.Join(coords, " ")}
+		if finite(last) {
+			line.Last = opts.FormatY(last)
+		}
+		c.Lines = append(c.Lines, line)
+	}
+
+	for i := 0; i <= tickCount; i++ {
+		v := minY + (maxY-minY)*float64(i)/tickCount
+		c.YTicks = append(c.YTicks, Tick{X: marginLeft - 5, Y: scaleY(v), Label: opts.FormatY(v)})
+	}
+	xTicks := opts.XTicks
+	if xTicks == nil {
+		for i := 0; i <= tickCount; i++ {
+			xTicks = append(xTicks, minX+(maxX-minX)*float64(i)/tickCount)
+		}
+	}
+	for _, v := range xTicks {
+		c.XTicks = append(c.XTicks, Tick{X: scaleX(v), Y: height - 5, Label: opts.FormatX(v)})
+	}
+	return c, nil
+}
+
+var svgTemplate = template.Must(template.New("svg").Parse(`<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" style="max-width: 100%; height: auto;">
+{{- range .YTicks}}
+<line x1="{{$.PlotLeft}}" x2="{{$.PlotRight}}" y1="{{.Y}}" y2="{{.Y}}" stroke="#444" stroke-width="1"></line>
+<text x="{{.X}}" y="{{.Y}}" text-anchor="end" dominant-baseline="middle" fill="#9e9e9e" font-size="11">{{.Label}}</text>
+{{- end}}
+{{- range .XTicks}}
+<text x="{{.X}}" y="{{.Y}}" text-anchor="middle" fill="#9e9e9e" font-size="11">{{.Label}}</text>
+{{- end}}
+{{- range .Lines}}
+<polyline points="{{.Points}}" fill="none" stroke="{{.Color}}" stroke-width="1.5"><title>{{.Label}}</title></polyline>
+{{- end}}
+</svg>`))
+
+// SVG renders the chart as an inline SVG element.
+func (c *Chart) SVG() template.HTML {
+	var buf bytes.Buffer
+	if err := svgTemplate.Execute(&buf, c); err != nil {
+		return template.HTML(fmt.Sprintf("<!-- FAILED EXECUTING CHART TEMPLATE: %v -->", err))
+	}
+	return template.HTML(buf.String())
+}
+
+// FormatValue formats a value compactly with an SI suffix.
+func FormatValue(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case !finite(v):
+		return fmt.Sprint(v)
+	case abs >= 1e12:
+		return fmt.Sprintf("%.3gT", v/1e12)
+	case abs >= 1e9:
+		return fmt.Sprintf("%.3gG", v/1e9)
+	case abs >= 1e6:
+		return fmt.Sprintf("%.3gM", v/1e6)
+	case abs >= 1e3:
+		return fmt.Sprintf("%.3gk", v/1e3)
+	default:
+		return fmt.Sprintf("%.3g", v)
+	}
+}
+
+// FormatBytes formats a number of bytes with a binary suffix. Since the suffixes are 1024 apart,
+// it keeps an extra significant digit to avoid exponents.
+func FormatBytes(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case !finite(v):
+		return fmt.Sprint(v)
+	case abs >= 1<<40:
+		return fmt.Sprintf("%.4gTi", v/(1<<40))
+	case abs >= 1<<30:
+		return fmt.Sprintf("%.4gGi", v/(1<<30))
+	case abs >= 1<<20:
+		return fmt.Sprintf("%.4gMi", v/(1<<20))
+	case abs >= 1<<10:
+		return fmt.Sprintf("%.4gKi", v/(1<<10))
+	default:
+		return fmt.Sprintf("%.4g", v)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New([]Series{
+		{Label: "a", Points: []Point{{0, 10}, {1, 20}, {2, math.NaN()}}},
+		{Label: "b", Points: []Point{{0, 5}, {2, 0}}},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(c.Lines))
+	}
+	// The plot area runs from x=60 to x=790 and from y=10 (the max) to y=215 (zero).
+	if expected := "60,112.5 425,10"; c.Lines[0].Points != expected {
+		t.Errorf("expected points %q, got %q", expected, c.Lines[0].Points)
+	}
+	if expected := "60,163.8 790,215"; c.Lines[1].Points != expected {
+		t.Errorf("expected points %q, got %q", expected, c.Lines[1].Points)
+	}
+	if c.Lines[0].Last != "20" {
+		t.Errorf("expected the last finite value to be 20, got %q", c.Lines[0].Last)
+	}
+	if first, last := c.YTicks[0].Label, c.YTicks[len(c.YTicks)-1].Label; first != "0" || last != "20" {
+		t.Errorf("expected y ticks from 0 to 20, got %q to %q", first, last)
+	}
+	if svg := string(c.SVG()); !strings.Contains(svg, `<title>a</title>`) {
+		t.Errorf("expected SVG to label the lines, got %s", svg)
+	}
+}
+
+func TestNewNoData(t *testing.T) {
+	if _, err := New([]Series{{Label: "a", Points: []Point{{0, math.Inf(1)}}}}, Options{}); err != ErrNoData {
+		t.Errorf("expected ErrNoData, got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		value    float64
+		format   func(float64) string
+		expected string
+	}{
+		{value: 0.5, format: FormatValue, expected: "0.5"},
+		{value: 1500, format: FormatValue, expected: "1.5k"},
+		{value: 2.5e9, format: FormatValue, expected: "2.5G"},
+		{value: 512, format: FormatBytes, expected: "512"},
+		{value: 1000, format: FormatBytes, expected: "1000"},
+		{value: 3 << 20, format: FormatBytes, expected: "3Mi"},
+	}
+	for _, tc := range testCases {
+		if actual := tc.format(tc.value); actual != tc.expected {
+			t.Errorf("expected %v to be formatted as %q, got %q", tc.value, tc.expected, actual)
+		}
+	}
+}
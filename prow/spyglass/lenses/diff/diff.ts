@@ -0,0 +1,11 @@
+// The page is replaced wholesale on update, so listeners need to be reattached.
+function bind(): void {
+  const select = document.getElementById('diff-artifact') as HTMLSelectElement | null;
+  if (select) {
+    select.addEventListener('change', () => {
+      spyglass.updatePage(JSON.stringify({artifact: select.value})).then(bind);
+    });
+  }
+}
+
+window.addEventListener('DOMContentLoaded', bind);
@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diff provides a Spyglass lens that diffs artifacts against the last passing run of the job.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "diff"
+	title    = "Changes Since Last Pass"
+	priority = 16
+
+	// contextLines is the number of unchanged lines shown around each change.
+	contextLines = 3
+	// maxEdits bounds the work done diffing very different files.
+	maxEdits = 5000
+)
+
+// Lens is the implementation of an artifact-diffing Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+		Baseline: true,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// request is sent by the frontend to select an artifact.
+type request struct {
+	Artifact string `json:"artifact"`
+}
+
+type diffView struct {
+	Artifacts    []string
+	Artifact     string
+	BaselineLink string
+	Hunks        []lenses.DiffHunk
+	Added        int
+	Removed      int
+	Message      string
+}
+
+// Body renders a unified diff between the selected artifact and the same artifact from the baseline run.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	current, baseline := lenses.SplitBaseline(artifacts)
+	if len(current) == 0 {
+		return executeTemplate(resourceDir, "body", diffView{Message: "No artifacts to compare."})
+	}
+	var req request
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &req); err != nil {
+			logrus.WithError(err).Info("Failed to parse diff lens request.")
+		}
+	}
+
+	view := diffView{}
+	selected := current[0]
+	for _, a := range current {
+		view.Artifacts = append(view.Artifacts, a.JobPath())
+		if a.JobPath() == req.Artifact {
+			selected = a
+		}
+	}
+	view.Artifact = selected.JobPath()
+	if len(baseline) == 0 {
+		view.Message = "No earlier passing run of this job was found to compare against."
+		return executeTemplate(resourceDir, "body", view)
+	}
+	view.BaselineLink = "/view/" + baseline[0].Source
+
+	var old lenses.Artifact
+	for _, b := range baseline {
+		if b.JobPath() == selected.JobPath() {
+			old = b
+			break
+		}
+	}
+	if old == nil {
+		view.Message = fmt.Sprintf("%s is new since the last passing run.", selected.JobPath())
+		return executeTemplate(resourceDir, "body", view)
+	}
+
+	oldLines, err := readLines(old)
+	if err != nil {
+		view.Message = fmt.Sprintf("Failed to read %s from the last passing run: %v", old.JobPath(), err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+	newLines, err := readLines(selected)
+	if err != nil {
+		view.Message = fmt.Sprintf("Failed to read %s: %v", selected.JobPath(), err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+	diff, err := lenses.DiffLines(oldLines, newLines, contextLines, maxEdits)
+	if err != nil {
+		view.Message = fmt.Sprintf("Too many changes to show: %v.", err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+	view.Hunks, view.Added, view.Removed = diff.Hunks, diff.Added, diff.Removed
+	if len(view.Hunks) == 0 {
+		view.Message = "No changes since the last passing run."
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func readLines(a lenses.Artifact) ([]string, error) {
+	content, err := a.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines, nil
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
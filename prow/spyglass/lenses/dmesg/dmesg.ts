@@ -0,0 +1,33 @@
+function handleFilterChange(this: HTMLInputElement): void {
+  const log = this.closest('.dmesg-log');
+  if (!log) {
+    return;
+  }
+  const lines = log.querySelector('.dmesg-lines');
+  if (lines) {
+    lines.classList.toggle('only-anomalies', this.checked);
+  }
+  spyglass.contentUpdated();
+}
+
+function handleJumpClick(this: HTMLAnchorElement): void {
+  // Make sure the target is visible even if the log is filtered.
+  const log = this.closest('.dmesg-log');
+  if (!log) {
+    return;
+  }
+  const filter = log.querySelector<HTMLInputElement>('.dmesg-only-anomalies');
+  if (filter && filter.checked) {
+    filter.checked = false;
+    handleFilterChange.call(filter);
+  }
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const filter of Array.from(document.querySelectorAll<HTMLInputElement>('.dmesg-only-anomalies'))) {
+    filter.addEventListener('change', handleFilterChange);
+  }
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.dmesg-jump'))) {
+    link.addEventListener('click', handleJumpClick);
+  }
+});
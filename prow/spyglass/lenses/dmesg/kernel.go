@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dmesg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// category is a kind of kernel message worth drawing attention to.
+type category struct {
+	Name  string
+	Class string
+	re    *regexp.Regexp
+}
+
+// categories are checked in order; the first to match a message wins.
+var categories = []category{
+	{
+		Name:  "OOM killer",
+		Class: "oom",
+		re:    regexp.MustCompile(`invoked oom-killer|Out of memory: Kill|Memory cgroup out of memory|oom-kill:|Killed process \d+`),
+	},
+	{
+		Name:  "Kernel oops",
+		Class: "oops",
+		re:    regexp.MustCompile(`\bBUG: |\bOops\b|general protection fault|Kernel panic|WARNING: CPU: \d+|Unable to handle kernel`),
+	},
+	{
+		Name:  "Hung task",
+		Class: "hung",
+		re:    regexp.MustCompile(`blocked for more than \d+ seconds|hung_task_timeout_secs|soft lockup|rcu_sched self-detected stall`),
+	},
+	{
+		Name:  "Disk error",
+		Class: "disk",
+		re:    regexp.MustCompile(`I/O error|EXT4-fs error|XFS \(.*\): .*(?:[Ee]rror|[Cc]orruption)|blk_update_request: .*error|SCSI error|ata\d+(?:\.\d+)?: .*(?:failed|error)|Remounting filesystem read-only`),
+	},
+}
+
+// prefixRE matches the syslog priority and timestamp that dmesg and serial consoles prefix
+// kernel messages with, e.g. "<6>[   12.345678] ".
+var prefixRE = regexp.MustCompile(`^(?:<\d+>)?\[\s*(\d+\.\d+)\]\s?`)
+
+// syslogRE matches the prefix of kernel messages forwarded to syslog or the journal,
+// e.g. "Mar 01 10:00:00 node-1 kernel: ".
+var syslogRE = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d) \S+ kernel: `)
+
+// splitPrefix separates the timestamp from a kernel message, if it has one.
+func splitPrefix(line string) (timestamp, message string) {
+	if m := prefixRE.FindStringSubmatchIndex(line); m != nil {
+		return line[m[2]:m[3]], line[m[1]:]
+	}
+	if m := syslogRE.FindStringSubmatchIndex(line); m != nil {
+		return line[m[2]:m[3]], line[m[1]:]
+	}
+	return "", line
+}
+
+// variableRE matches the parts of a message that typically differ between repetitions,
+// such as addresses, PIDs and device numbers.
+var variableRE = regexp.MustCompile(`0x[0-9a-fA-F]+|[0-9a-fA-F]{8,}|\d+`)
+
+// normalize reduces a message to a form that is the same for repetitions of it.
+func normalize(message string) string {
+	return variableRE.ReplaceAllString(strings.TrimSpace(message), "#")
+}
+
+// classify returns the category of the message, if any.
+func classify(categories []category, message string) *category {
+	for i := range categories {
+		if categories[i].re.MatchString(message) {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+// Entry is a kernel message, or a run of repetitions of one.
+type Entry struct {
+	// Number is the line number of the first message.
+	Number    int
+	Timestamp string
+	Message   string
+	// Repeats is the number of further messages that were collapsed into this one.
+	Repeats int
+	// LastTimestamp is the timestamp of the last repetition.
+	LastTimestamp string
+	Category      *category
+
+	normalized string
+}
+
+// Anomaly summarizes the messages in a category.
+type Anomaly struct {
+	Name  string
+	Class string
+	Count int
+	// First is the first message in the category.
+	First Entry
+}
+
+// parse splits a kernel log into entries, collapsing consecutive repetitions of a message.
+func parse(content string, categories []category) ([]Entry, []Anomaly) {
+	var entries []Entry
+	anomalies := map[string]*Anomaly{}
+	for i, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		timestamp, message := splitPrefix(strings.TrimRight(line, "\r"))
+		normalized := normalize(message)
+		c := classify(categories, message)
+		if c != nil {
+			if a, ok := anomalies[c.Name]; ok {
+				a.Count++
+			} else {
+				anomalies[c.Name] = &Anomaly{Name: c.Name, Class: c.Class, Count: 1, First: Entry{Number: i + 1, Timestamp: timestamp, Message: message}}
+			}
+		}
+		if n := len(entries); n > 0 && normalized != "" && entries[n-1].normalized == normalized {
+			entries[n-1].Repeats++
+			entries[n-1].LastTimestamp = timestamp
+			continue
+		}
+		entries = append(entries, Entry{
+			Number:     i + 1,
+			Timestamp:  timestamp,
+			Message:    message,
+			Category:   c,
+			normalized: normalized,
+		})
+	}
+	var summary []Anomaly
+	for _, c := range categories {
+		if a, ok := anomalies[c.Name]; ok {
+			summary = append(summary, *a)
+		}
+	}
+	return entries, summary
+}
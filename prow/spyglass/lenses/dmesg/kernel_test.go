@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dmesg
+
+import (
+	"testing"
+)
+
+func TestSplitPrefix(t *testing.T) {
+	testCases := []struct {
+		line      string
+		timestamp string
+		message   string
+	}{
+		{line: "[    1.234567] Linux version 4.14", timestamp: "1.234567", message: "Linux version 4.14"},
+		{line: "<6>[12345.000001] eth0: link up", timestamp: "12345.000001", message: "eth0: link up"},
+		{line: "Mar  1 10:00:00 node-1 kernel: EXT4-fs error", timestamp: "Mar  1 10:00:00", message: "EXT4-fs error"},
+		{line: "no prefix here", message: "no prefix here"},
+	}
+	for _, tc := range testCases {
+		timestamp, message := splitPrefix(tc.line)
+		if timestamp != tc.timestamp || message != tc.message {
+			t.Errorf("splitPrefix(%q): expected (%q, %q), got (%q, %q)", tc.line, tc.timestamp, tc.message, timestamp, message)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	log := `[    1.000000] Booting
+[   10.000000] cbr0: port 2(veth1234abcd) entered blocking state
+[   10.100000] cbr0: port 3(veth5678ef01) entered blocking state
+[   10.200000] cbr0: port 4(veth9abc2345) entered blocking state
+[   20.000000] e2e.test invoked oom-killer: gfp_mask=0x14000c0(GFP_KERNEL), order=0
+[   20.000100] Killed process 4242 (e2e.test) total-vm:123kB
+[  300.000000] INFO: task kworker/1:2:123 blocked for more than 120 seconds.
+[  400.000000] blk_update_request: I/O error, dev sda, sector 123
+`
+	entries, anomalies := parse(log, categories)
+	if len(entries) != 6 {
+		t.Fatalf("expected the repeated messages to be collapsed into 6 entries, got %d: %v", len(entries), entries)
+	}
+	if entries[1].Repeats != 2 || entries[1].LastTimestamp != "10.200000" {
+		t.Errorf("expected the bridge messages to repeat twice more, got %#v", entries[1])
+	}
+	if entries[3].Number != 6 || entries[3].Category == nil || entries[3].Category.Class != "oom" {
+		t.Errorf("expected line 6 to be an OOM kill, got %#v", entries[3])
+	}
+
+	expected := []struct {
+		name  string
+		count int
+		first int
+	}{
+		{name: "OOM killer", count: 2, first: 5},
+		{name: "Hung task", count: 1, first: 7},
+		{name: "Disk error", count: 1, first: 8},
+	}
+	if len(anomalies) != len(expected) {
+		t.Fatalf("expected %d anomalies, got %v", len(expected), anomalies)
+	}
+	for i, e := range expected {
+		a := anomalies[i]
+		if a.Name != e.name || a.Count != e.count || a.First.Number != e.first {
+			t.Errorf("expected %s x%d first on line %d, got %s x%d first on line %d", e.name, e.count, e.first, a.Name, a.Count, a.First.Number)
+		}
+	}
+}
+
+func TestConfigCategories(t *testing.T) {
+	conf := config{Patterns: []pattern{{Name: "NFS", Regex: `nfs: server .* not responding`}}}
+	cats, err := conf.categories()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := classify(cats, "nfs: server 10.0.0.1 not responding, still trying"); c == nil || c.Name != "NFS" {
+		t.Errorf("expected the configured pattern to match, got %v", c)
+	}
+	if _, err := (config{Patterns: []pattern{{Name: "bad", Regex: "("}}}).categories(); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dmesg provides a Spyglass lens that highlights problems in kernel logs.
+package dmesg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "dmesg"
+	title    = "Kernel Log"
+	priority = 19
+)
+
+// Lens is the implementation of a kernel log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// pattern is an additional kind of message to highlight.
+type pattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// Patterns are checked before the built in categories.
+	Patterns []pattern `json:"patterns,omitempty"`
+}
+
+// categories returns the configured categories followed by the built in ones.
+func (c config) categories() ([]category, error) {
+	var result []category
+	for _, p := range c.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", p.Name, err)
+		}
+		result = append(result, category{Name: p.Name, Class: "custom", re: re})
+	}
+	return append(result, categories...), nil
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// logView is a single kernel log.
+type logView struct {
+	Name      string
+	Link      string
+	Error     string
+	Entries   []Entry
+	Anomalies []Anomaly
+}
+
+type dmesgView struct {
+	Error string
+	Logs  []logView
+}
+
+// Body renders each kernel log with its anomalies summarized and repeated messages collapsed.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := dmesgView{}
+	var conf config
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+		}
+	}
+	cats, err := conf.categories()
+	if err != nil {
+		view.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+		cats = categories
+	}
+
+	for _, a := range artifacts {
+		lv := logView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		content, err := a.ReadAll()
+		if err != nil {
+			lv.Error = fmt.Sprintf("Failed to read log: %v", err)
+		} else {
+			lv.Entries, lv.Anomalies = parse(string(content), cats)
+		}
+		view.Logs = append(view.Logs, lv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+)
+
+// sniffLength is how much of the beginning of an artifact is read to detect its encoding.
+const sniffLength = 512
+
+// encoding is a character encoding an artifact can be transcoded to UTF-8 from.
+type encoding int
+
+const (
+	encodingUTF8 encoding = iota
+	encodingUTF8BOM
+	encodingUTF16LE
+	encodingUTF16BE
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// detectEncoding detects the encoding of an artifact from its beginning. UTF-16 is detected from
+// its byte order mark, or from the zero bytes that make up half of mostly ASCII text without one.
+func detectEncoding(head []byte) encoding {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return encodingUTF8BOM
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return encodingUTF16LE
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return encodingUTF16BE
+	}
+	if len(head) < 4 {
+		return encodingUTF8
+	}
+	var evenZeros, oddZeros int
+	for i, b := range head {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	// Most characters of text encoded in UTF-16 have a zero high byte, and no other zeros.
+	units := len(head) / 2
+	switch {
+	case oddZeros*4 >= units*3 && evenZeros*10 < units:
+		return encodingUTF16LE
+	case evenZeros*4 >= units*3 && oddZeros*10 < units:
+		return encodingUTF16BE
+	default:
+		return encodingUTF8
+	}
+}
+
+// decode transcodes content in an encoding to UTF-8, dropping any byte order mark.
+func decode(content []byte, enc encoding) []byte {
+	var order binary.ByteOrder
+	var bom []byte
+	switch enc {
+	case encodingUTF8BOM:
+		return bytes.TrimPrefix(content, bomUTF8)
+	case encodingUTF16LE:
+		order, bom = binary.LittleEndian, bomUTF16LE
+	case encodingUTF16BE:
+		order, bom = binary.BigEndian, bomUTF16BE
+	default:
+		return content
+	}
+	content = bytes.TrimPrefix(content, bom)
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[2*i:])
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(units))
+	for _, r := range utf16.Decode(units) {
+		buf.WriteRune(r)
+	}
+	return buf.Bytes()
+}
+
+// decodedArtifact is an artifact transcoded to UTF-8, which is held in memory.
+type decodedArtifact struct {
+	Artifact
+	content []byte
+}
+
+func (a *decodedArtifact) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(a.content).ReadAt(p, off)
+}
+
+func (a *decodedArtifact) ReadAtMost(n int64) ([]byte, error) {
+	if n >= int64(len(a.content)) {
+		return a.content, io.EOF
+	}
+	return a.content[:n], nil
+}
+
+func (a *decodedArtifact) ReadAll() ([]byte, error) {
+	return a.content, nil
+}
+
+func (a *decodedArtifact) ReadTail(n int64) ([]byte, error) {
+	if n > int64(len(a.content)) {
+		n = int64(len(a.content))
+	}
+	return a.content[int64(len(a.content))-n:], nil
+}
+
+func (a *decodedArtifact) Size() (int64, error) {
+	return int64(len(a.content)), nil
+}
+
+// DecodeArtifact returns an artifact that reads a transcoded to UTF-8 if it is encoded in UTF-16
+// or begins with a UTF-8 byte order mark, as logs of Windows tools often are, and a otherwise.
+// Transcoded artifacts are read in full, so those too large to be are left as they are. Offsets
+// into a transcoded artifact are into its UTF-8 content, so line indexes of it are not used.
+func DecodeArtifact(a Artifact) Artifact {
+	head, err := a.ReadAtMost(sniffLength)
+	if err != nil && err != io.EOF {
+		return a
+	}
+	enc := detectEncoding(head)
+	if enc == encodingUTF8 {
+		return a
+	}
+	content, err := a.ReadAll()
+	if err != nil {
+		return a
+	}
+	return &decodedArtifact{Artifact: a, content: decode(content, enc)}
+}
+
+// DecodeArtifacts applies DecodeArtifact to each of some artifacts.
+func DecodeArtifacts(artifacts []Artifact) []Artifact {
+	decoded := make([]Artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		decoded = append(decoded, DecodeArtifact(a))
+	}
+	return decoded
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(text string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(text))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+func TestDecodeArtifact(t *testing.T) {
+	text := "Building… done\r\nTests passed ✓\n"
+	testCases := []struct {
+		name    string
+		content []byte
+	}{
+		{
+			name:    "UTF-16LE with a byte order mark",
+			content: append([]byte{0xff, 0xfe}, encodeUTF16(text, binary.LittleEndian)...),
+		},
+		{
+			name:    "UTF-16BE with a byte order mark",
+			content: append([]byte{0xfe, 0xff}, encodeUTF16(text, binary.BigEndian)...),
+		},
+		{
+			name:    "UTF-16LE without a byte order mark",
+			content: encodeUTF16(text, binary.LittleEndian),
+		},
+		{
+			name:    "UTF-8 with a byte order mark",
+			content: append([]byte{0xef, 0xbb, 0xbf}, text...),
+		},
+		{
+			name:    "UTF-8",
+			content: []byte(text),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := DecodeArtifact(&FakeArtifact{path: "build-log.txt", content: tc.content, sizeLimit: 500e6})
+			content, err := a.ReadAll()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(content) != text {
+				t.Errorf("expected %q, got %q", text, content)
+			}
+			lines, err := LastNLines(a, 1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(lines) != 1 || lines[0] != "Tests passed ✓" {
+				t.Errorf("expected the last line to be read as UTF-8, got %q", lines)
+			}
+		})
+	}
+}
+
+func TestDecodeArtifactLeavesUTF8(t *testing.T) {
+	a := &FakeArtifact{path: "build-log.txt", content: []byte("plain\n"), sizeLimit: 500e6}
+	if DecodeArtifact(a) != Artifact(a) {
+		t.Error("expected a UTF-8 artifact to be read as it is")
+	}
+}
+
+func TestDecodeArtifactTooLarge(t *testing.T) {
+	a := &FakeArtifact{path: "build-log.txt", content: encodeUTF16("too large\n", binary.LittleEndian), sizeLimit: 5}
+	if DecodeArtifact(a) != Artifact(a) {
+		t.Error("expected an artifact too large to read in full to be read as it is")
+	}
+}
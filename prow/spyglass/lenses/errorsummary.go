@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// maxErrorTextLength is the most of a line kept in an error summary, in bytes.
+const maxErrorTextLength = 1000
+
+// ErrorPattern is a named pattern matching lines that indicate a failure.
+type ErrorPattern struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultErrorPatterns match the failures the build log lens highlights.
+var DefaultErrorPatterns = []ErrorPattern{
+	{Name: "error", Pattern: regexp.MustCompile(`ERROR:|^E\d{4} \d\d:\d\d:\d\d\.\d+`)},
+	{Name: "failure", Pattern: regexp.MustCompile(`(\s|^)(FAIL|Failure \[)\b`)},
+	{Name: "panic", Pattern: regexp.MustCompile(`(\s|^)panic\b`)},
+	{Name: "timeout", Pattern: regexp.MustCompile(`timed out`)},
+}
+
+// ErrorMatch is a line that matched an error pattern.
+type ErrorMatch struct {
+	// Pattern is the name of the first pattern the line matched.
+	Pattern string `json:"pattern"`
+	// Line is the number of the line, counting from 1.
+	Line int64 `json:"line"`
+	// Offset is the byte offset the line starts at.
+	Offset int64 `json:"offset"`
+	// Text is the line, cut off at maxErrorTextLength bytes.
+	Text string `json:"text,omitempty"`
+}
+
+// PatternCount is the number of lines that matched an error pattern.
+type PatternCount struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// ErrorSummary summarizes the failures in an artifact: the first and last lines that matched an
+// error pattern, and how many lines matched each. First and Last are nil if no line matched.
+type ErrorSummary struct {
+	First *ErrorMatch `json:"first,omitempty"`
+	Last  *ErrorMatch `json:"last,omitempty"`
+	// Total is the number of lines that matched any pattern, and Counts the number that matched
+	// each pattern that any did, in the order of the patterns.
+	Total  int            `json:"total"`
+	Counts []PatternCount `json:"counts,omitempty"`
+}
+
+// errorSummarizer builds an error summary a line at a time.
+type errorSummarizer struct {
+	patterns []ErrorPattern
+	counts   []int
+	summary  ErrorSummary
+}
+
+func newErrorSummarizer(patterns []ErrorPattern) *errorSummarizer {
+	return &errorSummarizer{patterns: patterns, counts: make([]int, len(patterns))}
+}
+
+func (s *errorSummarizer) add(text string, line, offset int64) {
+	var match *ErrorMatch
+	for i, p := range s.patterns {
+		if !p.Pattern.MatchString(text) {
+			continue
+		}
+		s.counts[i]++
+		if match == nil {
+			match = newErrorMatch(p, text, line, offset)
+		}
+	}
+	if match == nil {
+		return
+	}
+	s.summary.Total++
+	if s.summary.First == nil {
+		s.summary.First = match
+	}
+	s.summary.Last = match
+}
+
+func (s *errorSummarizer) finish() ErrorSummary {
+	for i, p := range s.patterns {
+		if s.counts[i] > 0 {
+			s.summary.Counts = append(s.summary.Counts, PatternCount{Pattern: p.Name, Count: s.counts[i]})
+		}
+	}
+	return s.summary
+}
+
+func newErrorMatch(p ErrorPattern, text string, line, offset int64) *ErrorMatch {
+	return &ErrorMatch{Pattern: p.Name, Line: line, Offset: offset, Text: truncateText(text, maxErrorTextLength)}
+}
+
+// truncateText cuts text off at n bytes, between characters.
+func truncateText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	b := []byte(text[:n])
+	return string(b[:len(b)-incompleteRuneSuffix(b)])
+}
+
+// SummarizeErrors scans an artifact a chunk at a time for lines matching error patterns and
+// summarizes them. Lines are split and collapsed as by LastNLines.
+func SummarizeErrors(a Artifact, patterns []ErrorPattern) (ErrorSummary, error) {
+	s := newErrorSummarizer(patterns)
+	err := scanLines(a, math.MaxInt64, func(text string, line, offset int64) bool {
+		s.add(text, line, offset)
+		return true
+	})
+	if err != nil {
+		return ErrorSummary{}, err
+	}
+	return s.finish(), nil
+}
+
+// FirstError scans the first limit bytes of an artifact a chunk at a time for the first line
+// matching an error pattern, which it returns along with the byte offset it starts at. It
+// returns nil if no line does.
+func FirstError(a Artifact, patterns []ErrorPattern, limit int64) (*ErrorMatch, error) {
+	s := newErrorSummarizer(patterns)
+	err := scanLines(a, limit, func(text string, line, offset int64) bool {
+		s.add(text, line, offset)
+		return s.summary.First == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.summary.First, nil
+}
+
+// scanLines calls f with each line of the first limit bytes of an artifact, its number and the
+// offset it starts at, until f returns false. Lines are split and collapsed as by LastNLines.
+func scanLines(a Artifact, limit int64, f func(text string, line, offset int64) bool) error {
+	cr, err := NewChunkedReader(a, limit)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(cr)
+	var offset int64
+	for line := int64(1); ; line++ {
+		raw, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading artifact: %v", err)
+		}
+		if raw == "" {
+			return nil
+		}
+		if !f(CollapseCarriageReturns(strings.TrimSuffix(raw, "\n")), line, offset) {
+			return nil
+		}
+		offset += int64(len(raw))
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// SummarizeErrorLines summarizes the lines of an artifact already read that match error
+// patterns, like SummarizeErrors.
+func SummarizeErrorLines(lines []string, patterns []ErrorPattern) ErrorSummary {
+	s := newErrorSummarizer(patterns)
+	var offset int64
+	for i, text := range lines {
+		s.add(CollapseCarriageReturns(text), int64(i+1), offset)
+		offset += int64(len(text)) + 1
+	}
+	return s.finish()
+}
+
+// MatchErrorLines returns the lines of an artifact already read that match error patterns, in
+// order, like the first and last lines that SummarizeErrorLines finds.
+func MatchErrorLines(lines []string, patterns []ErrorPattern) []ErrorMatch {
+	var matches []ErrorMatch
+	var offset int64
+	for i, text := range lines {
+		collapsed := CollapseCarriageReturns(text)
+		for _, p := range patterns {
+			if p.Pattern.MatchString(collapsed) {
+				matches = append(matches, *newErrorMatch(p, collapsed, int64(i+1), offset))
+				break
+			}
+		}
+		offset += int64(len(text)) + 1
+	}
+	return matches
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected ErrorSummary
+	}{
+		{
+			name:    "first and last failures with counts",
+			content: "Starting.\nERROR: setup failed\n--- FAIL: TestFoo\npanic: boom\nFAIL\n",
+			expected: ErrorSummary{
+				First: &ErrorMatch{Pattern: "error", Line: 2, Offset: 10, Text: "ERROR: setup failed"},
+				Last:  &ErrorMatch{Pattern: "failure", Line: 5, Offset: 60, Text: "FAIL"},
+				Total: 4,
+				Counts: []PatternCount{
+					{Pattern: "error", Count: 1},
+					{Pattern: "failure", Count: 2},
+					{Pattern: "panic", Count: 1},
+				},
+			},
+		},
+		{
+			name:    "lines matching several patterns count towards each",
+			content: "E0501 10:00:00.000000 1 main.go:1] request timed out\n",
+			expected: ErrorSummary{
+				First: &ErrorMatch{Pattern: "error", Line: 1, Text: "E0501 10:00:00.000000 1 main.go:1] request timed out"},
+				Last:  &ErrorMatch{Pattern: "error", Line: 1, Text: "E0501 10:00:00.000000 1 main.go:1] request timed out"},
+				Total: 1,
+				Counts: []PatternCount{
+					{Pattern: "error", Count: 1},
+					{Pattern: "timeout", Count: 1},
+				},
+			},
+		},
+		{
+			name:     "no failures",
+			content:  "Starting.\nDone.\n",
+			expected: ErrorSummary{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := SummarizeErrors(&FakeArtifact{content: []byte(tc.content)}, DefaultErrorPatterns)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+			if lines := SummarizeErrorLines(strings.Split(tc.content, "\n"), DefaultErrorPatterns); !reflect.DeepEqual(lines, tc.expected) {
+				t.Errorf("expected lines to be summarized as %+v, got %+v", tc.expected, lines)
+			}
+			matches := MatchErrorLines(strings.Split(tc.content, "\n"), DefaultErrorPatterns)
+			if len(matches) != tc.expected.Total {
+				t.Errorf("expected %d matching lines, got %+v", tc.expected.Total, matches)
+			} else if len(matches) > 0 && (!reflect.DeepEqual(matches[0], *tc.expected.First) || !reflect.DeepEqual(matches[len(matches)-1], *tc.expected.Last)) {
+				t.Errorf("expected matching lines from %+v to %+v, got %+v", tc.expected.First, tc.expected.Last, matches)
+			}
+		})
+	}
+}
+
+func TestSummarizeErrorsTruncatesLines(t *testing.T) {
+	line := "ERROR: " + strings.Repeat("é", maxErrorTextLength)
+	summary := SummarizeErrorLines([]string{line}, DefaultErrorPatterns)
+	if text := summary.First.Text; len(text) > maxErrorTextLength || !strings.HasPrefix(line, text) {
+		t.Errorf("expected the line to be cut off between characters within %d bytes, got %d bytes", maxErrorTextLength, len(text))
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	content := "Starting.\nok\nFAIL: TestOne\npanic: boom\n"
+	testCases := []struct {
+		name     string
+		limit    int64
+		expected *ErrorMatch
+	}{
+		{
+			name:     "first matching line is found",
+			limit:    int64(len(content)),
+			expected: &ErrorMatch{Pattern: "failure", Line: 3, Offset: 13, Text: "FAIL: TestOne"},
+		},
+		{
+			name:  "lines past the limit are not scanned",
+			limit: 13,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := FirstError(&FakeArtifact{content: []byte(content)}, DefaultErrorPatterns, tc.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
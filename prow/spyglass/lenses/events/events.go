@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Event is an event, or a series of identical events, about an object.
+type Event struct {
+	Type    string
+	Reason  string
+	Message string
+	// Count is the number of times the event was observed.
+	Count     int32
+	First     time.Time
+	Last      time.Time
+	Source    string
+	FieldPath string
+}
+
+// Warning returns whether the event reports a problem.
+func (e Event) Warning() bool {
+	return e.Type == corev1.EventTypeWarning
+}
+
+// Object is the events about one object, in the order they happened.
+type Object struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Events    []Event
+	// Warnings is the number of warning events about the object.
+	Warnings int
+}
+
+// Reason counts the warnings given for one reason.
+type Reason struct {
+	Reason string
+	// Count is the number of times the warning was observed, and Objects the number of objects
+	// it was observed for.
+	Count   int32
+	Objects int
+}
+
+// Events is the events in one or more dumps, grouped by the object they are about.
+type Events struct {
+	Objects []*Object
+
+	objects map[string]*Object
+}
+
+func newEvents() *Events {
+	return &Events{objects: map[string]*Object{}}
+}
+
+// read adds the events in a stream of JSON Events and EventLists, as written by
+// `kubectl get events -o json`, with or without --watch.
+func (e *Events) read(content []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse events: %v", err)
+		}
+		var list struct {
+			Items []corev1.Event `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return fmt.Errorf("failed to parse events: %v", err)
+		}
+		if list.Items != nil {
+			for _, item := range list.Items {
+				e.add(item)
+			}
+			continue
+		}
+		var event corev1.Event
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return fmt.Errorf("failed to parse event: %v", err)
+		}
+		e.add(event)
+	}
+}
+
+func (e *Events) add(event corev1.Event) {
+	ref := event.InvolvedObject
+	if ref.Name == "" && event.Reason == "" {
+		return
+	}
+	key := ref.Kind + "/" + ref.Namespace + "/" + ref.Name
+	o, ok := e.objects[key]
+	if !ok {
+		o = &Object{Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+		e.objects[key] = o
+		e.Objects = append(e.Objects, o)
+	}
+	ev := Event{
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Count:     event.Count,
+		First:     firstTime(event),
+		Last:      lastTime(event),
+		Source:    event.Source.Component,
+		FieldPath: ref.FieldPath,
+	}
+	if ev.Source == "" {
+		ev.Source = event.ReportingController
+	}
+	if event.Series != nil && event.Series.Count > ev.Count {
+		ev.Count = event.Series.Count
+	}
+	if ev.Count == 0 {
+		ev.Count = 1
+	}
+	if ev.Warning() {
+		o.Warnings++
+	}
+	o.Events = append(o.Events, ev)
+}
+
+// firstTime and lastTime fall back to the fields set by newer clients, which report events
+// with an eventTime and a series rather than timestamps and a count.
+func firstTime(event corev1.Event) time.Time {
+	switch {
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+func lastTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	}
+	return firstTime(event)
+}
+
+// finish orders each object's events by time, and the objects with warnings first, then by
+// when their first event happened.
+func (e *Events) finish() {
+	for _, o := range e.Objects {
+		sort.SliceStable(o.Events, func(i, j int) bool { return o.Events[i].First.Before(o.Events[j].First) })
+	}
+	sort.SliceStable(e.Objects, func(i, j int) bool {
+		a, b := e.Objects[i], e.Objects[j]
+		if (a.Warnings > 0) != (b.Warnings > 0) {
+			return a.Warnings > 0
+		}
+		return a.Events[0].First.Before(b.Events[0].First)
+	})
+}
+
+// warningReasons counts the warnings by reason, most frequent first.
+func (e *Events) warningReasons() []Reason {
+	var reasons []Reason
+	index := map[string]int{}
+	for _, o := range e.Objects {
+		seen := map[string]bool{}
+		for _, ev := range o.Events {
+			if !ev.Warning() {
+				continue
+			}
+			i, ok := index[ev.Reason]
+			if !ok {
+				i = len(reasons)
+				index[ev.Reason] = i
+				reasons = append(reasons, Reason{Reason: ev.Reason})
+			}
+			reasons[i].Count += ev.Count
+			if !seen[ev.Reason] {
+				seen[ev.Reason] = true
+				reasons[i].Objects++
+			}
+		}
+	}
+	sort.SliceStable(reasons, func(i, j int) bool {
+		if reasons[i].Count != reasons[j].Count {
+			return reasons[i].Count > reasons[j].Count
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+	return reasons
+}
@@ -0,0 +1,63 @@
+// filter shows the events in a file that match its query and warning filter, and the objects
+// that have any.
+function filter(file: Element): void {
+  const queryInput = file.querySelector<HTMLInputElement>('.events-query');
+  const warningsInput = file.querySelector<HTMLInputElement>('.events-warnings-only');
+  const query = queryInput ? queryInput.value.trim().toLowerCase() : '';
+  const warningsOnly = warningsInput ? warningsInput.checked : false;
+  let shown = 0;
+  for (const object of Array.from(file.querySelectorAll<HTMLElement>('.events-object'))) {
+    const summary = object.querySelector('summary');
+    const objectMatches = !!summary && (summary.textContent || '').toLowerCase().includes(query);
+    let objectShown = 0;
+    for (const event of Array.from(object.querySelectorAll<HTMLElement>('.events-event'))) {
+      const show = (!warningsOnly || event.classList.contains('warning')) &&
+          (objectMatches || (event.textContent || '').toLowerCase().includes(query));
+      event.hidden = !show;
+      if (show) {
+        objectShown++;
+      }
+    }
+    object.hidden = objectShown === 0;
+    if (query !== '' && objectShown > 0) {
+      object.open = true;
+    }
+    shown += objectShown;
+  }
+  const none = file.querySelector<HTMLElement>('.events-none');
+  if (none) {
+    none.hidden = shown > 0;
+  }
+  spyglass.contentUpdated();
+}
+
+function handleReasonClick(this: HTMLAnchorElement, e: MouseEvent): void {
+  e.preventDefault();
+  const file = this.closest('.events-file');
+  if (!file) {
+    return;
+  }
+  const queryInput = file.querySelector<HTMLInputElement>('.events-query');
+  const warningsInput = file.querySelector<HTMLInputElement>('.events-warnings-only');
+  if (queryInput) {
+    queryInput.value = this.dataset.reason || '';
+  }
+  if (warningsInput) {
+    warningsInput.checked = true;
+  }
+  filter(file);
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const file of Array.from(document.querySelectorAll('.events-file'))) {
+    for (const input of Array.from(file.querySelectorAll('.events-query, .events-warnings-only'))) {
+      input.addEventListener('input', () => filter(file));
+      input.addEventListener('change', () => filter(file));
+    }
+  }
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.events-reason'))) {
+    link.addEventListener('click', handleReasonClick);
+  }
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+});
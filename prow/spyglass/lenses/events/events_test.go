@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+// dump is an EventList followed by a watched event that uses the newer series fields.
+const dump = `{"kind":"EventList","apiVersion":"v1","items":[
+{"metadata":{"name":"web.1","namespace":"default"},"involvedObject":{"kind":"Pod","namespace":"default","name":"web"},"reason":"Scheduled","message":"Successfully assigned default/web to node-1","type":"Normal","count":1,"firstTimestamp":"2019-05-01T10:00:00Z","lastTimestamp":"2019-05-01T10:00:00Z","source":{"component":"default-scheduler"}},
+{"metadata":{"name":"db.1","namespace":"default"},"involvedObject":{"kind":"Pod","namespace":"default","name":"db"},"reason":"FailedScheduling","message":"0/3 nodes are available: 3 Insufficient cpu.","type":"Warning","count":4,"firstTimestamp":"2019-05-01T10:00:05Z","lastTimestamp":"2019-05-01T10:02:00Z","source":{"component":"default-scheduler"}},
+{"metadata":{"name":"web.2","namespace":"default"},"involvedObject":{"kind":"Pod","namespace":"default","name":"web","fieldPath":"spec.containers{web}"},"reason":"Failed","message":"Failed to pull image \"web:nope\"","type":"Warning","count":2,"firstTimestamp":"2019-05-01T10:00:10Z","lastTimestamp":"2019-05-01T10:00:40Z","source":{"component":"kubelet"}},
+{"metadata":{"name":"node-1.1"},"involvedObject":{"kind":"Node","name":"node-1"},"reason":"Starting","message":"Starting kubelet.","type":"Normal","firstTimestamp":"2019-05-01T09:00:00Z","lastTimestamp":"2019-05-01T09:00:00Z"}
+]}
+{"metadata":{"name":"web.3","namespace":"default","creationTimestamp":"2019-05-01T10:01:00Z"},"involvedObject":{"kind":"Pod","namespace":"default","name":"web"},"reason":"Failed","message":"Error: ImagePullBackOff","type":"Warning","eventTime":"2019-05-01T10:00:50.000000Z","series":{"count":3,"lastObservedTime":"2019-05-01T10:03:00.000000Z"},"reportingComponent":"kubelet"}`
+
+func TestEvents(t *testing.T) {
+	e := newEvents()
+	if err := e.read([]byte(dump)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e.finish()
+
+	var order []string
+	for _, o := range e.Objects {
+		order = append(order, o.Kind+" "+o.Name)
+	}
+	if len(order) != 3 || order[0] != "Pod web" || order[1] != "Pod db" || order[2] != "Node node-1" {
+		t.Fatalf("expected objects with warnings first, then by time, got %v", order)
+	}
+	web := e.Objects[0]
+	if len(web.Events) != 3 || web.Warnings != 2 || web.Events[0].Reason != "Scheduled" {
+		t.Fatalf("unexpected events for web: %#v", web)
+	}
+	watched := web.Events[2]
+	if watched.Count != 3 || watched.Source != "kubelet" || !watched.First.Equal(time.Date(2019, 5, 1, 10, 0, 50, 0, time.UTC)) || !watched.Last.Equal(time.Date(2019, 5, 1, 10, 3, 0, 0, time.UTC)) {
+		t.Errorf("expected the series to be used for the watched event, got %#v", watched)
+	}
+	if e.Objects[2].Events[0].Count != 1 {
+		t.Errorf("expected events without a count to count once, got %d", e.Objects[2].Events[0].Count)
+	}
+
+	reasons := e.warningReasons()
+	if len(reasons) != 2 || reasons[0] != (Reason{Reason: "Failed", Count: 5, Objects: 1}) || reasons[1] != (Reason{Reason: "FailedScheduling", Count: 4, Objects: 1}) {
+		t.Errorf("unexpected warning reasons: %#v", reasons)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	if err := newEvents().read([]byte(`{"items": [`)); err == nil {
+		t.Error("expected an error for a truncated dump")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events provides a Spyglass lens that renders dumps of Kubernetes events.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "events"
+	title    = "Cluster Events"
+	priority = 29
+)
+
+// Lens is the implementation of a Kubernetes event-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// EventView is an event with its times formatted for display.
+type EventView struct {
+	Event
+	// Time is when the event was first seen, and Range when it was first and last seen.
+	Time  string
+	Range string
+}
+
+// ObjectView is an object and its events.
+type ObjectView struct {
+	*Object
+	Events []EventView
+}
+
+// FileView is the events in one dump.
+type FileView struct {
+	Name     string
+	Error    string
+	Objects  []ObjectView
+	Reasons  []Reason
+	Total    int
+	Warnings int
+}
+
+type eventsView struct {
+	Files []FileView
+}
+
+// Body renders the events in each dump, grouped by the object they are about.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	view := eventsView{}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		fv := FileView{Name: a.JobPath()}
+		content, err := a.ReadAll()
+		if err != nil {
+			fv.Error = fmt.Sprintf("Failed to read: %v", err)
+			view.Files = append(view.Files, fv)
+			continue
+		}
+		e := newEvents()
+		if err := e.read(content); err != nil {
+			fv.Error = err.Error()
+		}
+		e.finish()
+		for _, o := range e.Objects {
+			ov := ObjectView{Object: o}
+			for _, ev := range o.Events {
+				ov.Events = append(ov.Events, EventView{Event: ev, Time: formatTime(ev.First), Range: formatRange(ev.First, ev.Last)})
+			}
+			fv.Objects = append(fv.Objects, ov)
+			fv.Total += len(o.Events)
+			fv.Warnings += o.Warnings
+		}
+		fv.Reasons = e.warningReasons()
+		view.Files = append(view.Files, fv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.UTC().Format("15:04:05")
+}
+
+func formatRange(first, last time.Time) string {
+	if first.IsZero() {
+		return "time unknown"
+	}
+	if last.Equal(first) {
+		return first.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s to %s", first.UTC().Format(time.RFC3339), last.UTC().Format(time.RFC3339))
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
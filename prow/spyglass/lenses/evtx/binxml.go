@@ -0,0 +1,542 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evtx
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// The binary XML in event records is documented by the libevtx project, at
+// https://github.com/libyal/libevtx/blob/master/documentation/Windows%20XML%20Event%20Log%20(EVTX).asciidoc
+
+// Binary XML tokens. Tokens with the more flag (0x40) set are followed by more of the same kind,
+// except for elements, where it means the element has attributes.
+const (
+	tokenEOF               = 0x00
+	tokenOpenStartElement  = 0x01
+	tokenCloseStartElement = 0x02
+	tokenCloseEmptyElement = 0x03
+	tokenEndElement        = 0x04
+	tokenValue             = 0x05
+	tokenAttribute         = 0x06
+	tokenCDATA             = 0x07
+	tokenCharRef           = 0x08
+	tokenEntityRef         = 0x09
+	tokenPITarget          = 0x0a
+	tokenPIData            = 0x0b
+	tokenTemplateInstance  = 0x0c
+	tokenSubstitution      = 0x0d
+	tokenOptionalSubst     = 0x0e
+	tokenFragmentHeader    = 0x0f
+
+	tokenMoreFlag = 0x40
+)
+
+// Types of values.
+const (
+	typeNull      = 0x00
+	typeString    = 0x01
+	typeANSI      = 0x02
+	typeInt8      = 0x03
+	typeUint8     = 0x04
+	typeInt16     = 0x05
+	typeUint16    = 0x06
+	typeInt32     = 0x07
+	typeUint32    = 0x08
+	typeInt64     = 0x09
+	typeUint64    = 0x0a
+	typeFloat32   = 0x0b
+	typeFloat64   = 0x0c
+	typeBool      = 0x0d
+	typeBinary    = 0x0e
+	typeGUID      = 0x0f
+	typeSizeT     = 0x10
+	typeFileTime  = 0x11
+	typeSysTime   = 0x12
+	typeSID       = 0x13
+	typeHexInt32  = 0x14
+	typeHexInt64  = 0x15
+	typeBinXML    = 0x21
+	typeArrayFlag = 0x80
+)
+
+// fixedSizes are the sizes of the values that have one.
+var fixedSizes = map[byte]int{
+	typeInt8: 1, typeUint8: 1, typeInt16: 2, typeUint16: 2, typeInt32: 4, typeUint32: 4,
+	typeInt64: 8, typeUint64: 8, typeFloat32: 4, typeFloat64: 8, typeBool: 4, typeGUID: 16,
+	typeFileTime: 8, typeSysTime: 16, typeHexInt32: 4, typeHexInt64: 8,
+}
+
+// maxDepth limits the nesting of elements and templates, which malformed records could make
+// unbounded.
+const maxDepth = 64
+
+var errTruncated = errors.New("record is truncated")
+
+// node is an element or a piece of text in an event's XML.
+type node struct {
+	// Name is empty for text.
+	Name       string
+	Attributes []attribute
+	Children   []*node
+	Text       string
+}
+
+type attribute struct {
+	Name  string
+	Value string
+}
+
+// attribute returns the value of the named attribute of an element.
+func (n *node) attribute(name string) string {
+	for _, a := range n.Attributes {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with the given name.
+func (n *node) child(name string) *node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the text within an element.
+func (n *node) text() string {
+	if n.Name == "" {
+		return n.Text
+	}
+	var parts []string
+	for _, c := range n.Children {
+		parts = append(parts, c.text())
+	}
+	return strings.Join(parts, "")
+}
+
+// substitution is a value substituted into a template.
+type substitution struct {
+	valueType byte
+	// text is the value of a scalar, and nodes that of binary XML.
+	text  string
+	nodes []*node
+}
+
+// cursor reads binary XML from a chunk. Offsets in binary XML are relative to the start of the
+// chunk, so the cursor works on the whole chunk. Reads past the end of the chunk set err, after
+// which all reads return zero.
+type cursor struct {
+	chunk []byte
+	pos   int
+	err   error
+}
+
+func (c *cursor) bytes(n int) []byte {
+	if c.err != nil {
+		return nil
+	}
+	if n < 0 || c.pos+n > len(c.chunk) {
+		c.err = errTruncated
+		return nil
+	}
+	b := c.chunk[c.pos : c.pos+n]
+	c.pos += n
+	return b
+}
+
+func (c *cursor) u8() byte {
+	if b := c.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (c *cursor) u16() uint16 {
+	if b := c.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (c *cursor) u32() uint32 {
+	if b := c.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (c *cursor) peek() byte {
+	if c.err != nil || c.pos >= len(c.chunk) {
+		c.err = errTruncated
+		return tokenEOF
+	}
+	return c.chunk[c.pos]
+}
+
+// utf16String reads n UTF-16 code units.
+func (c *cursor) utf16String(n int) string {
+	return decodeUTF16(c.bytes(2 * n))
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// name reads the name at offset. Names are stored once per chunk, inline where they are first
+// used, so if the name is after start it is read from the cursor.
+func (c *cursor) name(offset uint32, start int) string {
+	at := &cursor{chunk: c.chunk, pos: int(offset)}
+	at.u32() // The offset of the next name with the same hash.
+	at.u16() // The hash of the name.
+	s := at.utf16String(int(at.u16()))
+	at.u16() // The terminating NUL.
+	if at.err != nil {
+		c.err = at.err
+		return ""
+	}
+	if int(offset) > start {
+		c.pos = at.pos
+	}
+	return s
+}
+
+// parseFragment reads a fragment of binary XML until its end, substituting subs into any
+// template it uses.
+func (c *cursor) parseFragment(subs []substitution, depth int) []*node {
+	var nodes []*node
+	for c.err == nil && c.pos < len(c.chunk) {
+		start := c.pos
+		token := c.peek()
+		switch token &^ tokenMoreFlag {
+		case tokenEOF:
+			c.pos++
+			return nodes
+		case tokenFragmentHeader:
+			c.bytes(4)
+		case tokenOpenStartElement:
+			nodes = append(nodes, c.parseElement(subs, depth+1))
+		case tokenTemplateInstance:
+			nodes = append(nodes, c.parseTemplateInstance(depth+1)...)
+		default:
+			nodes = append(nodes, c.parseContent(subs, depth)...)
+		}
+		if c.err == nil && c.pos == start {
+			c.err = fmt.Errorf("unexpected token 0x%02x at offset %d", token, start)
+		}
+	}
+	return nodes
+}
+
+// parseElement reads an element, starting at its open start element token.
+func (c *cursor) parseElement(subs []substitution, depth int) *node {
+	if depth > maxDepth {
+		c.err = errors.New("elements are nested too deeply")
+		return nil
+	}
+	start := c.pos
+	token := c.u8()
+	c.u16() // The dependency identifier.
+	c.u32() // The size of the element.
+	n := &node{Name: c.name(c.u32(), start)}
+	if token&tokenMoreFlag != 0 {
+		c.u32() // The size of the attribute list.
+		for c.err == nil && c.peek()&^tokenMoreFlag == tokenAttribute {
+			attrStart := c.pos
+			c.u8()
+			a := attribute{Name: c.name(c.u32(), attrStart)}
+			var values []string
+			for _, v := range c.parseContent(subs, depth) {
+				values = append(values, v.text())
+			}
+			if len(values) != 0 {
+				a.Value = strings.Join(values, "")
+				n.Attributes = append(n.Attributes, a)
+			}
+		}
+	}
+	switch c.u8() {
+	case tokenCloseEmptyElement:
+		return n
+	case tokenCloseStartElement:
+	default:
+		c.err = fmt.Errorf("expected the start of element %s to close at offset %d", n.Name, c.pos-1)
+		return n
+	}
+	for c.err == nil {
+		switch c.peek() &^ tokenMoreFlag {
+		case tokenEndElement:
+			c.pos++
+			return n
+		case tokenOpenStartElement:
+			n.Children = append(n.Children, c.parseElement(subs, depth+1))
+		case tokenTemplateInstance:
+			n.Children = append(n.Children, c.parseTemplateInstance(depth+1)...)
+		default:
+			content := c.parseContent(subs, depth)
+			if len(content) == 0 && c.err == nil {
+				c.err = fmt.Errorf("unexpected token 0x%02x in element %s at offset %d", c.peek(), n.Name, c.pos)
+			}
+			n.Children = append(n.Children, content...)
+		}
+	}
+	return n
+}
+
+// parseContent reads consecutive values, substitutions and references.
+func (c *cursor) parseContent(subs []substitution, depth int) []*node {
+	var nodes []*node
+	for c.err == nil {
+		start := c.pos
+		switch c.peek() &^ tokenMoreFlag {
+		case tokenValue:
+			c.u8()
+			c.u8() // The type, which is always a string.
+			nodes = append(nodes, &node{Text: c.utf16String(int(c.u16()))})
+		case tokenCDATA:
+			c.u8()
+			nodes = append(nodes, &node{Text: c.utf16String(int(c.u16()))})
+		case tokenCharRef:
+			c.u8()
+			nodes = append(nodes, &node{Text: string(rune(c.u16()))})
+		case tokenEntityRef:
+			c.u8()
+			nodes = append(nodes, &node{Text: entity(c.name(c.u32(), start))})
+		case tokenPITarget:
+			c.u8()
+			c.name(c.u32(), start)
+		case tokenPIData:
+			c.u8()
+			c.utf16String(int(c.u16()))
+		case tokenSubstitution, tokenOptionalSubst:
+			c.u8()
+			index := int(c.u16())
+			c.u8() // The type, which the substitution itself also has.
+			if index < len(subs) {
+				s := subs[index]
+				if s.nodes != nil {
+					nodes = append(nodes, s.nodes...)
+				} else if s.valueType != typeNull {
+					nodes = append(nodes, &node{Text: s.text})
+				}
+			}
+		default:
+			return nodes
+		}
+	}
+	return nodes
+}
+
+// entity returns the character an XML entity reference stands for.
+func entity(name string) string {
+	switch name {
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "amp":
+		return "&"
+	case "quot":
+		return `"`
+	case "apos":
+		return "'"
+	}
+	return "&" + name + ";"
+}
+
+// parseTemplateInstance reads a template instance and its substitutions, and returns the
+// template's nodes with the substitutions made.
+func (c *cursor) parseTemplateInstance(depth int) []*node {
+	if depth > maxDepth {
+		c.err = errors.New("templates are nested too deeply")
+		return nil
+	}
+	start := c.pos
+	c.u8()
+	c.u8()  // Unknown.
+	c.u32() // The template identifier.
+	definition := int(c.u32())
+	if definition > start {
+		// The definition is inline: the offset of the next template, its GUID, the size of its
+		// binary XML, and the binary XML.
+		c.u32()
+		c.bytes(16)
+		c.bytes(int(c.u32()))
+	}
+	count := int(c.u32())
+	if c.err != nil || count > len(c.chunk)/4 {
+		c.err = errTruncated
+		return nil
+	}
+	type descriptor struct {
+		size      int
+		valueType byte
+	}
+	descriptors := make([]descriptor, count)
+	for i := range descriptors {
+		descriptors[i].size = int(c.u16())
+		descriptors[i].valueType = c.u8()
+		c.u8()
+	}
+	subs := make([]substitution, count)
+	for i, d := range descriptors {
+		valueStart := c.pos
+		data := c.bytes(d.size)
+		if c.err != nil {
+			return nil
+		}
+		subs[i].valueType = d.valueType
+		if d.valueType == typeBinXML {
+			nested := &cursor{chunk: c.chunk[:valueStart+d.size], pos: valueStart}
+			subs[i].nodes = nested.parseFragment(nil, depth+1)
+			if subs[i].nodes == nil {
+				subs[i].nodes = []*node{}
+			}
+			continue
+		}
+		subs[i].text = formatValue(d.valueType, data)
+	}
+	t := &cursor{chunk: c.chunk, pos: definition + 24}
+	nodes := t.parseFragment(subs, depth)
+	if t.err != nil {
+		c.err = t.err
+	}
+	return nodes
+}
+
+// fileTime converts a Windows FILETIME, in 100ns intervals since 1601, to a time.
+func fileTime(ft uint64) time.Time {
+	const epochDifference = 116444736000000000
+	if ft < epochDifference {
+		return time.Time{}
+	}
+	ft -= epochDifference
+	return time.Unix(int64(ft/1e7), int64(ft%1e7)*100).UTC()
+}
+
+// formatValue formats a substituted value as it would be shown in XML.
+func formatValue(valueType byte, data []byte) string {
+	if valueType&typeArrayFlag != 0 {
+		return formatArray(valueType&^typeArrayFlag, data)
+	}
+	le := binary.LittleEndian
+	size := fixedSizes[valueType]
+	if len(data) < size {
+		return hex.EncodeToString(data)
+	}
+	switch valueType {
+	case typeNull:
+		return ""
+	case typeString:
+		return decodeUTF16(data)
+	case typeANSI:
+		return strings.TrimRight(string(data), "\x00")
+	case typeInt8:
+		return strconv.Itoa(int(int8(data[0])))
+	case typeUint8:
+		return strconv.Itoa(int(data[0]))
+	case typeInt16:
+		return strconv.Itoa(int(int16(le.Uint16(data))))
+	case typeUint16:
+		return strconv.Itoa(int(le.Uint16(data)))
+	case typeInt32:
+		return strconv.Itoa(int(int32(le.Uint32(data))))
+	case typeUint32:
+		return strconv.FormatUint(uint64(le.Uint32(data)), 10)
+	case typeInt64:
+		return strconv.FormatInt(int64(le.Uint64(data)), 10)
+	case typeUint64:
+		return strconv.FormatUint(le.Uint64(data), 10)
+	case typeFloat32:
+		return strconv.FormatFloat(float64(math.Float32frombits(le.Uint32(data))), 'g', -1, 32)
+	case typeFloat64:
+		return strconv.FormatFloat(math.Float64frombits(le.Uint64(data)), 'g', -1, 64)
+	case typeBool:
+		return strconv.FormatBool(le.Uint32(data) != 0)
+	case typeGUID:
+		return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}", le.Uint32(data), le.Uint16(data[4:]), le.Uint16(data[6:]), data[8:10], data[10:16])
+	case typeSizeT, typeHexInt32, typeHexInt64:
+		if len(data) == 4 {
+			return fmt.Sprintf("0x%08x", le.Uint32(data))
+		}
+		if len(data) == 8 {
+			return fmt.Sprintf("0x%016x", le.Uint64(data))
+		}
+	case typeFileTime:
+		return fileTime(le.Uint64(data)).Format(time.RFC3339Nano)
+	case typeSysTime:
+		t := time.Date(int(le.Uint16(data)), time.Month(le.Uint16(data[2:])), int(le.Uint16(data[6:])),
+			int(le.Uint16(data[8:])), int(le.Uint16(data[10:])), int(le.Uint16(data[12:])),
+			int(le.Uint16(data[14:]))*int(time.Millisecond), time.UTC)
+		return t.Format(time.RFC3339Nano)
+	case typeSID:
+		return formatSID(data)
+	}
+	return hex.EncodeToString(data)
+}
+
+// formatArray formats an array of values, separated by commas.
+func formatArray(valueType byte, data []byte) string {
+	var items []string
+	switch valueType {
+	case typeString:
+		items = strings.Split(strings.TrimRight(decodeUTF16(data), "\x00"), "\x00")
+	case typeANSI:
+		items = strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	default:
+		size := fixedSizes[valueType]
+		if size == 0 {
+			return hex.EncodeToString(data)
+		}
+		for i := 0; i+size <= len(data); i += size {
+			items = append(items, formatValue(valueType, data[i:i+size]))
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+// formatSID formats a security identifier as a string such as S-1-5-18.
+func formatSID(data []byte) string {
+	if len(data) < 8 {
+		return hex.EncodeToString(data)
+	}
+	var authority uint64
+	for _, b := range data[2:8] {
+		authority = authority<<8 | uint64(b)
+	}
+	parts := []string{"S", strconv.Itoa(int(data[0])), strconv.FormatUint(authority, 10)}
+	for i := 0; i < int(data[1]) && 8+4*i+4 <= len(data); i++ {
+		parts = append(parts, strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data[8+4*i:])), 10))
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An EVTX file is a 4KiB header followed by 64KiB chunks, each of which has a 512 byte header
+// followed by event records.
+const (
+	fileHeaderSize  = 4096
+	chunkSize       = 64 << 10
+	chunkHeaderSize = 512
+	// recordHeaderSize is the size of a record's signature, size, identifier and time.
+	recordHeaderSize = 24
+)
+
+var (
+	fileSignature   = []byte("ElfFile\x00")
+	chunkSignature  = []byte("ElfChnk\x00")
+	recordSignature = []byte("**\x00\x00")
+)
+
+// Levels of events.
+const (
+	levelCritical    = 1
+	levelError       = 2
+	levelWarning     = 3
+	levelInformation = 4
+	levelVerbose     = 5
+)
+
+// levelNames are the names Event Viewer gives levels. Level 0 is logged regardless of the level
+// being collected and is shown as information.
+var levelNames = []string{"Information", "Critical", "Error", "Warning", "Information", "Verbose"}
+
+// DataItem is a named item of an event's data.
+type DataItem struct {
+	Name  string
+	Value string
+}
+
+// Event is an event record.
+type Event struct {
+	RecordID uint64
+	Time     time.Time
+	Provider string
+	EventID  int
+	Level    int
+	Channel  string
+	Computer string
+	// Data is the event's EventData or UserData. Event Viewer formats it into a message using the
+	// provider's message table, which is not in the file.
+	Data []DataItem
+}
+
+// LevelName returns the name of the event's level.
+func (e Event) LevelName() string {
+	if e.Level >= 0 && e.Level < len(levelNames) {
+		return levelNames[e.Level]
+	}
+	return "Level " + strconv.Itoa(e.Level)
+}
+
+// parseFile reads the events in an EVTX file. Records that cannot be read are counted rather than
+// failing the whole file, since files copied from a running system may have partly written chunks.
+func parseFile(content []byte) ([]Event, int, error) {
+	if !bytes.HasPrefix(content, fileSignature) {
+		return nil, 0, fmt.Errorf("not an EVTX file")
+	}
+	var events []Event
+	failed := 0
+	for offset := fileHeaderSize; offset+chunkHeaderSize <= len(content); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		chunk := content[offset:end]
+		if !bytes.HasPrefix(chunk, chunkSignature) {
+			continue
+		}
+		chunkEvents, chunkFailed := parseChunk(chunk)
+		events = append(events, chunkEvents...)
+		failed += chunkFailed
+	}
+	return events, failed, nil
+}
+
+// parseChunk reads the records in a chunk, up to its free space.
+func parseChunk(chunk []byte) ([]Event, int) {
+	var events []Event
+	failed := 0
+	free := int(binary.LittleEndian.Uint32(chunk[48:]))
+	if free > len(chunk) || free < chunkHeaderSize {
+		free = len(chunk)
+	}
+	for offset := chunkHeaderSize; offset+recordHeaderSize <= free; {
+		if !bytes.Equal(chunk[offset:offset+4], recordSignature) {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(chunk[offset+4:]))
+		if size < recordHeaderSize+4 || offset+size > len(chunk) {
+			failed++
+			break
+		}
+		e, err := parseRecord(chunk, offset, size)
+		if err != nil {
+			failed++
+		} else {
+			events = append(events, e)
+		}
+		offset += size
+	}
+	return events, failed
+}
+
+// parseRecord reads the record at offset in a chunk.
+func parseRecord(chunk []byte, offset, size int) (Event, error) {
+	e := Event{
+		RecordID: binary.LittleEndian.Uint64(chunk[offset+8:]),
+		Time:     fileTime(binary.LittleEndian.Uint64(chunk[offset+16:])),
+	}
+	// The record ends with a copy of its size.
+	c := &cursor{chunk: chunk[:offset+size-4], pos: offset + recordHeaderSize}
+	nodes := c.parseFragment(nil, 0)
+	if c.err != nil {
+		return e, c.err
+	}
+	var root *node
+	for _, n := range nodes {
+		if n.Name == "Event" {
+			root = n
+		}
+	}
+	if root == nil {
+		return e, fmt.Errorf("record %d has no event", e.RecordID)
+	}
+	e.fill(root)
+	return e, nil
+}
+
+// fill reads an event from its XML.
+func (e *Event) fill(root *node) {
+	if system := root.child("System"); system != nil {
+		if n := system.child("Provider"); n != nil {
+			e.Provider = n.attribute("Name")
+			if e.Provider == "" {
+				e.Provider = n.attribute("EventSourceName")
+			}
+		}
+		if n := system.child("EventID"); n != nil {
+			e.EventID, _ = strconv.Atoi(strings.TrimSpace(n.text()))
+		}
+		if n := system.child("Level"); n != nil {
+			e.Level, _ = strconv.Atoi(strings.TrimSpace(n.text()))
+		}
+		if n := system.child("TimeCreated"); n != nil {
+			if t, err := time.Parse(time.RFC3339Nano, n.attribute("SystemTime")); err == nil {
+				e.Time = t
+			}
+		}
+		if n := system.child("Channel"); n != nil {
+			e.Channel = n.text()
+		}
+		if n := system.child("Computer"); n != nil {
+			e.Computer = n.text()
+		}
+	}
+	if data := root.child("EventData"); data != nil {
+		for _, d := range data.Children {
+			if d.Name == "" {
+				continue
+			}
+			e.Data = append(e.Data, DataItem{Name: d.attribute("Name"), Value: d.text()})
+		}
+	}
+	if data := root.child("UserData"); data != nil {
+		for _, wrapper := range data.Children {
+			for _, d := range wrapper.Children {
+				if d.Name != "" {
+					e.Data = append(e.Data, DataItem{Name: d.Name, Value: d.text()})
+				}
+			}
+		}
+	}
+}
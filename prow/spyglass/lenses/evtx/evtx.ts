@@ -0,0 +1,29 @@
+// filter shows the events of a log with the checked levels and the selected provider.
+function filter(log: Element): void {
+  const levels = Array.from(log.querySelectorAll<HTMLInputElement>('.evtx-level-filter'))
+      .filter((input) => input.checked).map((input) => input.value);
+  const providerSelect = log.querySelector<HTMLSelectElement>('.evtx-provider-filter');
+  const provider = providerSelect ? providerSelect.value : '';
+  let shown = 0;
+  for (const event of Array.from(log.querySelectorAll<HTMLElement>('.evtx-event'))) {
+    const show = levels.indexOf(event.dataset.level || '') >= 0 &&
+        (provider === '' || event.dataset.provider === provider);
+    event.hidden = !show;
+    if (show) {
+      shown++;
+    }
+  }
+  const none = log.querySelector<HTMLElement>('.evtx-none');
+  if (none) {
+    none.hidden = shown > 0;
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const log of Array.from(document.querySelectorAll('.evtx-log'))) {
+    for (const input of Array.from(log.querySelectorAll('.evtx-level-filter, .evtx-provider-filter'))) {
+      input.addEventListener('change', () => filter(log));
+    }
+  }
+});
@@ -0,0 +1,257 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evtx
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// builder writes a chunk of binary XML, storing names and templates once as Windows does.
+type builder struct {
+	buf       []byte
+	names     map[string]int
+	templates map[int]int
+}
+
+func (b *builder) u8(v byte) { b.buf = append(b.buf, v) }
+
+func (b *builder) u16(v uint16) {
+	b.buf = append(b.buf, 0, 0)
+	binary.LittleEndian.PutUint16(b.buf[len(b.buf)-2:], v)
+}
+
+func (b *builder) u32(v uint32) {
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-4:], v)
+}
+
+func (b *builder) u64(v uint64) {
+	b.u32(uint32(v))
+	b.u32(uint32(v >> 32))
+}
+
+func (b *builder) utf16(s string) {
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.u16(u)
+	}
+}
+
+func (b *builder) name(s string) {
+	if offset, ok := b.names[s]; ok {
+		b.u32(uint32(offset))
+		return
+	}
+	b.names[s] = len(b.buf) + 4
+	b.u32(uint32(len(b.buf) + 4))
+	b.u32(0)
+	b.u16(0)
+	b.u16(uint16(len(s)))
+	b.utf16(s)
+	b.u16(0)
+}
+
+// open starts an element, with an attribute set to a substitution if attr is set.
+func (b *builder) open(name, attr string, sub uint16) {
+	if attr == "" {
+		b.u8(tokenOpenStartElement)
+	} else {
+		b.u8(tokenOpenStartElement | tokenMoreFlag)
+	}
+	b.u16(0xffff)
+	b.u32(0)
+	b.name(name)
+	if attr != "" {
+		b.u32(0)
+		b.u8(tokenAttribute)
+		b.name(attr)
+		b.u8(tokenOptionalSubst)
+		b.u16(sub)
+		b.u8(typeString)
+	}
+}
+
+// element writes an element containing a substitution.
+func (b *builder) element(name string, sub uint16, valueType byte) {
+	b.open(name, "", 0)
+	b.u8(tokenCloseStartElement)
+	b.u8(tokenSubstitution)
+	b.u16(sub)
+	b.u8(valueType)
+	b.u8(tokenEndElement)
+}
+
+func (b *builder) definition() {
+	b.buf = append(b.buf, tokenFragmentHeader, 1, 1, 0)
+	b.open("Event", "", 0)
+	b.u8(tokenCloseStartElement)
+	b.open("System", "", 0)
+	b.u8(tokenCloseStartElement)
+	b.open("Provider", "Name", 0)
+	b.u8(tokenCloseEmptyElement)
+	b.element("EventID", 1, typeUint16)
+	b.element("Level", 2, typeUint8)
+	b.open("TimeCreated", "SystemTime", 3)
+	b.u8(tokenCloseEmptyElement)
+	b.open("Channel", "", 0)
+	b.u8(tokenCloseStartElement)
+	b.u8(tokenValue)
+	b.u8(typeString)
+	b.u16(6)
+	b.utf16("System")
+	b.u8(tokenEndElement)
+	b.u8(tokenEndElement)
+	b.open("EventData", "", 0)
+	b.u8(tokenCloseStartElement)
+	b.open("Data", "Name", 5)
+	b.u8(tokenCloseStartElement)
+	b.u8(tokenSubstitution)
+	b.u16(4)
+	b.u8(typeString)
+	b.u8(tokenEndElement)
+	b.u8(tokenEndElement)
+	b.u8(tokenEndElement)
+	b.u8(tokenEOF)
+}
+
+// record writes a record using the test template.
+func (b *builder) record(id uint64, provider string, eventID uint16, level byte, message string) {
+	start := len(b.buf)
+	b.buf = append(b.buf, recordSignature...)
+	b.u32(0)
+	b.u64(id)
+	b.u64(0)
+	b.buf = append(b.buf, tokenFragmentHeader, 1, 1, 0)
+	b.u8(tokenTemplateInstance)
+	b.u8(1)
+	b.u32(1)
+	if offset, ok := b.templates[1]; ok {
+		b.u32(uint32(offset))
+	} else {
+		b.templates[1] = len(b.buf) + 4
+		b.u32(uint32(len(b.buf) + 4))
+		b.u32(0)
+		b.buf = append(b.buf, make([]byte, 16)...)
+		sizeAt := len(b.buf)
+		b.u32(0)
+		b.definition()
+		binary.LittleEndian.PutUint32(b.buf[sizeAt:], uint32(len(b.buf)-sizeAt-4))
+	}
+	var values builder
+	values.names = map[string]int{}
+	values.utf16(provider)
+	providerSize := len(values.buf)
+	values.u16(eventID)
+	values.u8(level)
+	values.u64(uint64(time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC).Unix())*1e7 + 116444736000000000)
+	messageStart := len(values.buf)
+	values.utf16(message)
+	messageSize := len(values.buf) - messageStart
+	values.utf16("param1")
+	b.u32(6)
+	for _, d := range []struct {
+		size      int
+		valueType byte
+	}{{providerSize, typeString}, {2, typeUint16}, {1, typeUint8}, {8, typeFileTime}, {messageSize, typeString}, {12, typeString}} {
+		b.u16(uint16(d.size))
+		b.u8(d.valueType)
+		b.u8(0)
+	}
+	b.buf = append(b.buf, values.buf...)
+	b.u8(tokenEOF)
+	size := len(b.buf) - start + 4
+	b.u32(uint32(size))
+	binary.LittleEndian.PutUint32(b.buf[start+4:], uint32(size))
+}
+
+func testFile() []byte {
+	b := &builder{buf: make([]byte, chunkHeaderSize), names: map[string]int{}, templates: map[int]int{}}
+	copy(b.buf, chunkSignature)
+	b.record(1, "Service Control Manager", 7036, levelInformation, "The kubelet service entered the running state.")
+	b.record(2, "Microsoft-Windows-Kernel-Power", 41, levelCritical, "The system has rebooted without cleanly shutting down first.")
+	// A record whose binary XML is garbage.
+	b.buf = append(b.buf, recordSignature...)
+	b.u32(recordHeaderSize + 8)
+	b.u64(3)
+	b.u64(0)
+	b.u32(0xffffffff)
+	b.u32(recordHeaderSize + 8)
+	binary.LittleEndian.PutUint32(b.buf[48:], uint32(len(b.buf)))
+
+	file := make([]byte, fileHeaderSize+chunkSize)
+	copy(file, fileSignature)
+	copy(file[fileHeaderSize:], b.buf)
+	return file
+}
+
+func TestParseFile(t *testing.T) {
+	events, failed, err := parseFile(testFile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 record to fail, got %d", failed)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	expected := Event{
+		RecordID: 2,
+		Time:     time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC),
+		Provider: "Microsoft-Windows-Kernel-Power",
+		EventID:  41,
+		Level:    levelCritical,
+		Channel:  "System",
+		Data:     []DataItem{{Name: "param1", Value: "The system has rebooted without cleanly shutting down first."}},
+	}
+	e := events[1]
+	if e.RecordID != expected.RecordID || !e.Time.Equal(expected.Time) || e.Provider != expected.Provider ||
+		e.EventID != expected.EventID || e.Level != expected.Level || e.Channel != expected.Channel ||
+		len(e.Data) != 1 || e.Data[0] != expected.Data[0] {
+		t.Errorf("expected %#v, got %#v", expected, e)
+	}
+	if events[0].Provider != "Service Control Manager" || events[0].LevelName() != "Information" {
+		t.Errorf("unexpected first event %#v", events[0])
+	}
+
+	if _, _, err := parseFile([]byte("not an event log")); err == nil {
+		t.Error("expected an error for a file that is not an event log")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	testCases := []struct {
+		valueType byte
+		data      []byte
+		expected  string
+	}{
+		{typeInt32, []byte{0xfe, 0xff, 0xff, 0xff}, "-2"},
+		{typeHexInt32, []byte{0x10, 0, 0, 0}, "0x00000010"},
+		{typeBool, []byte{1, 0, 0, 0}, "true"},
+		{typeSID, []byte{1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0}, "S-1-5-18"},
+		{typeGUID, []byte{0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x78, 0x56, 1, 2, 3, 4, 5, 6, 7, 8}, "{12345678-1234-5678-0102-030405060708}"},
+		{typeString | typeArrayFlag, []byte{'a', 0, 0, 0, 'b', 0, 0, 0}, "a, b"},
+		{typeUint16 | typeArrayFlag, []byte{1, 0, 2, 0}, "1, 2"},
+	}
+	for _, tc := range testCases {
+		if value := formatValue(tc.valueType, tc.data); value != tc.expected {
+			t.Errorf("expected type 0x%02x to be %q, got %q", tc.valueType, tc.expected, value)
+		}
+	}
+}
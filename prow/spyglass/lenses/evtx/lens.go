@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package evtx provides a Spyglass lens that renders Windows event logs.
+package evtx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "evtx"
+	title    = "Windows Events"
+	priority = 38
+
+	// defaultMaxBytes is how much of each event log is read if not configured.
+	defaultMaxBytes = 100 << 20
+	// defaultMaxEvents is how many of the most recent events of each log are shown if not
+	// configured.
+	defaultMaxEvents = 5000
+)
+
+// Lens is the implementation of a Windows event log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each event log is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// MaxEvents is how many of the most recent events of each log are shown. Defaults to 5000.
+	MaxEvents int `json:"max_events,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Count is the number of events with a level or from a provider.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// EventView is an event with its time formatted for display.
+type EventView struct {
+	Event
+	Time string
+}
+
+// LogView is the events of one log.
+type LogView struct {
+	Name   string
+	Link   string
+	Error  string
+	Events []EventView
+	// Omitted is the number of older events not shown, and Failed the number of records that
+	// could not be read.
+	Omitted   int
+	Failed    int
+	Levels    []Count
+	Providers []Count
+}
+
+type evtxView struct {
+	Errors []string
+	Logs   []LogView
+}
+
+// Body renders the events of each log, with counts of their levels and providers to filter by.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := evtxView{}
+	conf := config{MaxBytes: defaultMaxBytes, MaxEvents: defaultMaxEvents}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	if conf.MaxEvents <= 0 {
+		conf.MaxEvents = defaultMaxEvents
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		lv := LogView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			lv.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+			view.Logs = append(view.Logs, lv)
+			continue
+		}
+		events, failed, err := parseFile(content)
+		if err != nil {
+			lv.Error = fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err)
+		}
+		lv.Failed = failed
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+		if len(events) > conf.MaxEvents {
+			lv.Omitted = len(events) - conf.MaxEvents
+			events = events[lv.Omitted:]
+		}
+		levels := map[string]int{}
+		providers := map[string]int{}
+		for _, e := range events {
+			levels[e.LevelName()]++
+			providers[e.Provider]++
+			lv.Events = append(lv.Events, EventView{Event: e, Time: e.Time.Format("2006-01-02 15:04:05.000")})
+		}
+		for _, level := range []string{"Critical", "Error", "Warning", "Information", "Verbose"} {
+			if levels[level] != 0 {
+				lv.Levels = append(lv.Levels, Count{Name: level, Count: levels[level]})
+			}
+		}
+		lv.Providers = sortedCounts(providers)
+		view.Logs = append(view.Logs, lv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// sortedCounts returns counts sorted by count, most first.
+func sortedCounts(counts map[string]int) []Count {
+	var result []Count
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakeartifact provides an artifact held in memory, for testing lenses.
+package fakeartifact
+
+import (
+	"bytes"
+	"context"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// Artifact is a lenses.Artifact whose content is held in memory.
+type Artifact struct {
+	Path    string
+	Content []byte
+	// SizeLimit, if set, is the size above which ReadAll fails with lenses.ErrFileTooLarge.
+	SizeLimit int64
+	// SizeErr, if set, is returned by Size, as it is for artifacts that don't exist.
+	SizeErr error
+	// Gzipped makes ReadTail fail as it does for artifacts stored gzip-compressed.
+	Gzipped bool
+}
+
+var _ lenses.Artifact = &Artifact{}
+
+func (a *Artifact) JobPath() string {
+	return a.Path
+}
+
+func (a *Artifact) Size() (int64, error) {
+	if a.SizeErr != nil {
+		return 0, a.SizeErr
+	}
+	return int64(len(a.Content)), nil
+}
+
+func (a *Artifact) CanonicalLink() string {
+	return "https://example.com/" + a.Path
+}
+
+func (a *Artifact) UseContext(ctx context.Context) error {
+	return nil
+}
+
+func (a *Artifact) ReadAt(b []byte, off int64) (int, error) {
+	return bytes.NewReader(a.Content).ReadAt(b, off)
+}
+
+func (a *Artifact) ReadAll() ([]byte, error) {
+	if a.SizeLimit > 0 && int64(len(a.Content)) > a.SizeLimit {
+		return nil, lenses.ErrFileTooLarge
+	}
+	return a.Content, nil
+}
+
+func (a *Artifact) ReadAtMost(n int64) ([]byte, error) {
+	if n < int64(len(a.Content)) {
+		return a.Content[:n], nil
+	}
+	return a.Content, nil
+}
+
+func (a *Artifact) ReadTail(n int64) ([]byte, error) {
+	if a.Gzipped {
+		return nil, lenses.ErrGzipOffsetRead
+	}
+	if n < int64(len(a.Content)) {
+		return a.Content[int64(len(a.Content))-n:], nil
+	}
+	return a.Content, nil
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/json"
+)
+
+// FeaturesField is the field of a lens's configuration that says which of its features that
+// are being rolled out are turned on for the run being viewed.
+const FeaturesField = "features"
+
+// FeatureEnabled returns whether the named feature is turned on in a lens's configuration, so
+// that lenses can gate new behaviour on the lens_features in Deck's config.
+func FeatureEnabled(config json.RawMessage, feature string) bool {
+	return Features(config)[feature]
+}
+
+// Features returns whether each feature named in a lens's configuration is turned on.
+func Features(config json.RawMessage) map[string]bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(config, &fields) != nil {
+		return nil
+	}
+	var features map[string]bool
+	if json.Unmarshal(fields[FeaturesField], &features) != nil {
+		return nil
+	}
+	return features
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFeatureEnabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		expected bool
+	}{
+		{
+			name:     "turned on",
+			config:   `{"features":{"new-parser":true}}`,
+			expected: true,
+		},
+		{
+			name:   "turned off",
+			config: `{"features":{"new-parser":false}}`,
+		},
+		{
+			name:   "other features turned on",
+			config: `{"features":{"old-parser":true}}`,
+		},
+		{
+			name:   "no features",
+			config: `{"highlight":true}`,
+		},
+		{
+			name: "no config",
+		},
+		{
+			name:   "config that isn't an object",
+			config: `["new-parser"]`,
+		},
+		{
+			name:   "features that aren't an object",
+			config: `{"features":["new-parser"]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := FeatureEnabled(json.RawMessage(tc.config), "new-parser"); actual != tc.expected {
+				t.Errorf("expected enabled %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fuzz
+
+import (
+	"bufio"
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+// signatureFrames is the number of frames used to tell crashes apart.
+const signatureFrames = 3
+
+// Frame is a frame of a crash's stack.
+type Frame struct {
+	Function string
+	Location string
+}
+
+// Crash is a crash reported by a fuzzer.
+type Crash struct {
+	// Summary is the first line of the report, such as the panic message or sanitizer error.
+	Summary string
+	Stack   []Frame
+	// Reproducer is the name of the file the fuzzer wrote the crashing input to, if it said.
+	Reproducer string
+	// Input is the crashing input, if the report included it.
+	Input []byte
+}
+
+var (
+	// sanitizerErrorRE matches the start of a sanitizer or libFuzzer report.
+	sanitizerErrorRE = regexp.MustCompile(`^==\d+==\s*ERROR: (.+)$`)
+	// goPanicRE matches the start of a Go panic or fatal error.
+	goPanicRE = regexp.MustCompile(`^(?:panic|fatal error): `)
+	// sanitizerFrameRE matches a frame of a sanitizer stack, such as
+	// "#0 0x4f3c2a in parse /src/parse.c:42:7".
+	sanitizerFrameRE = regexp.MustCompile(`^\s*#\d+\s+0x[0-9a-fA-F]+\s+in\s+(.+)$`)
+	// goLocationRE matches the second line of a frame of a Go stack.
+	goLocationRE = regexp.MustCompile(`^\t(\S+:\d+)`)
+	// reproducerRE matches libFuzzer's note of where it wrote the crashing input.
+	reproducerRE = regexp.MustCompile(`Test unit written to (\S+)`)
+	base64RE     = regexp.MustCompile(`^Base64: (\S*)$`)
+	// numberRE matches the numbers that vary between reports of the same crash.
+	numberRE = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+)
+
+// runtimeFramePrefixes identify frames in fuzzing engines, sanitizers and language runtimes,
+// which are left out of crash signatures.
+var runtimeFramePrefixes = []string{
+	"runtime.", "panic", "github.com/dvyukov/go-fuzz/go-fuzz-dep.", "go-fuzz-dep.", "main.main",
+	// go-fuzz-build's entry point for libFuzzer.
+	"main.LLVMFuzzerTestOneInput",
+	"__asan", "__msan", "__ubsan", "__lsan", "__sanitizer", "__interceptor", "__GI_", "__libc_",
+	"fuzzer::", "_start", "abort", "raise", "malloc", "calloc", "realloc", "free", "operator new",
+	"operator delete",
+}
+
+// Signature identifies a crash by its message with numbers removed and the first frames of its
+// stack outside of the runtime, so that repeats of the same crash can be grouped.
+func (c *Crash) Signature() string {
+	parts := []string{numberRE.ReplaceAllString(c.Summary, "N")}
+	for _, f := range c.Stack {
+		if len(parts) > signatureFrames {
+			break
+		}
+		if !isRuntimeFrame(f.Function) {
+			parts = append(parts, f.Function)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func isRuntimeFrame(function string) bool {
+	for _, prefix := range runtimeFramePrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSanitizerFrame splits a sanitizer frame into its function and location. The location,
+// if there is one, is a path or a parenthesized module and offset.
+func parseSanitizerFrame(s string) Frame {
+	if i := strings.LastIndex(s, " "); i > 0 {
+		if last := s[i+1:]; strings.HasPrefix(last, "/") || strings.HasPrefix(last, "(") {
+			return Frame{Function: s[:i], Location: last}
+		}
+	}
+	return Frame{Function: s}
+}
+
+// parseReports finds the crashes in the output of a fuzzer: go-fuzz's .output files, and the logs
+// of libFuzzer and engines built on it, such as OSS-Fuzz. A Go panic followed by libFuzzer's
+// report of the resulting signal is one crash.
+func parseReports(content string) []*Crash {
+	var crashes []*Crash
+	var current *Crash
+	// inStack is whether the frames of the current crash's first stack are being read, and
+	// stackDone whether they have all been read.
+	inStack, stackDone, goFunction := false, false, ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if m := sanitizerErrorRE.FindStringSubmatch(line); m != nil {
+			// libFuzzer reports the signal raised by a Go panic it has already printed.
+			if current != nil && goPanicRE.MatchString(current.Summary) && strings.Contains(m[1], "deadly signal") {
+				inStack, stackDone = false, true
+				continue
+			}
+			current = &Crash{Summary: strings.TrimSpace(m[1])}
+			crashes = append(crashes, current)
+			inStack, stackDone = false, false
+			continue
+		}
+		if goPanicRE.MatchString(line) {
+			current = &Crash{Summary: line}
+			crashes = append(crashes, current)
+			inStack, stackDone, goFunction = false, false, ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := reproducerRE.FindStringSubmatch(line); m != nil {
+			current.Reproducer = m[1]
+			continue
+		}
+		if m := base64RE.FindStringSubmatch(line); m != nil {
+			if input, err := base64.StdEncoding.DecodeString(m[1]); err == nil {
+				current.Input = input
+			}
+			continue
+		}
+		if stackDone {
+			continue
+		}
+		if m := sanitizerFrameRE.FindStringSubmatch(line); m != nil {
+			inStack = true
+			current.Stack = append(current.Stack, parseSanitizerFrame(m[1]))
+			continue
+		}
+		if strings.HasPrefix(line, "goroutine ") {
+			inStack = true
+			continue
+		}
+		if inStack && goPanicRE.MatchString(current.Summary) {
+			if m := goLocationRE.FindStringSubmatch(line); m != nil && goFunction != "" {
+				current.Stack = append(current.Stack, Frame{Function: goFunction, Location: m[1]})
+				goFunction = ""
+				continue
+			}
+			if line != "" && !strings.HasPrefix(line, "\t") {
+				// Drop the arguments of the call.
+				goFunction = line
+				if i := strings.LastIndex(line, "("); i > 0 {
+					goFunction = line[:i]
+				}
+				continue
+			}
+		}
+		// The first stack ends at a blank line. Later ones describe allocations and threads.
+		if inStack && strings.TrimSpace(line) == "" {
+			inStack, stackDone = false, true
+		}
+	}
+	return crashes
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fuzz
+
+import (
+	"reflect"
+	"testing"
+)
+
+const asanLog = `INFO: Seed: 1234
+#1024	pulse  cov: 100 ft: 120 corp: 10/100b exec/s: 512 rss: 30Mb
+=================================================================
+==42==ERROR: AddressSanitizer: heap-buffer-overflow on address 0x602000000011 at pc 0x4f3c2a bp 0x7ffd sp 0x7ffc
+READ of size 1 at 0x602000000011 thread T0
+    #0 0x4f3c2a in parse_header /src/proj/parse.c:42:7
+    #1 0x4f3d10 in LLVMFuzzerTestOneInput /src/proj/fuzz.c:10:3
+    #2 0x4215a3 in fuzzer::Fuzzer::ExecuteCallback(unsigned char const*, unsigned long) /src/libfuzzer/FuzzerLoop.cpp:556:15
+    #3 0x7f1e2b in __libc_start_main (/lib/x86_64-linux-gnu/libc.so.6+0x21b96)
+
+0x602000000011 is located 0 bytes to the right of 1-byte region
+allocated by thread T0 here:
+    #0 0x4c0a3d in malloc /src/llvm/compiler-rt/lib/asan/asan_malloc_linux.cc:145:3
+
+SUMMARY: AddressSanitizer: heap-buffer-overflow /src/proj/parse.c:42:7 in parse_header
+MS: 1 ChangeByte-; base unit: adc83b19e793491b1c6ea0fd8b46cd9f32e592fc
+artifact_prefix='./'; Test unit written to ./crash-7a3d2e
+Base64: QUI=
+`
+
+const goLibFuzzerLog = `panic: runtime error: index out of range [5] with length 3
+
+goroutine 17 [running, locked to thread]:
+example.com/pkg.Decode(0x7f, 0x6, 0x6)
+	/go/src/example.com/pkg/decode.go:20 +0x1d0
+example.com/pkg.Fuzz(...)
+	/go/src/example.com/pkg/fuzz.go:8
+main.LLVMFuzzerTestOneInput(0x7f, 0x6, 0x9)
+	/tmp/go-fuzz-build/main.go:35 +0x84
+==7== ERROR: libFuzzer: deadly signal
+    #0 0x45c3b1 in __sanitizer_print_stack_trace
+    #1 0x43f2c4 in fuzzer::PrintStackTrace()
+
+artifact_prefix='./'; Test unit written to ./crash-99aa
+`
+
+const goFuzzOutput = `panic: runtime error: index out of range [7] with length 3
+
+goroutine 1 [running]:
+example.com/pkg.Decode(0x7f, 0x8, 0x8)
+	/go/src/example.com/pkg/decode.go:20 +0x1d0
+example.com/pkg.Fuzz(...)
+	/go/src/example.com/pkg/fuzz.go:8
+go-fuzz-dep.Main(0xc000010000, 0x1, 0x1)
+	go-fuzz-dep/main.go:36 +0x1ad
+main.main()
+	/tmp/go-fuzz-build/main.go:15 +0x52
+exit status 2`
+
+func TestParseReports(t *testing.T) {
+	crashes := parseReports(asanLog)
+	if len(crashes) != 1 {
+		t.Fatalf("expected 1 crash, got %d", len(crashes))
+	}
+	c := crashes[0]
+	expectedStack := []Frame{
+		{Function: "parse_header", Location: "/src/proj/parse.c:42:7"},
+		{Function: "LLVMFuzzerTestOneInput", Location: "/src/proj/fuzz.c:10:3"},
+		{Function: "fuzzer::Fuzzer::ExecuteCallback(unsigned char const*, unsigned long)", Location: "/src/libfuzzer/FuzzerLoop.cpp:556:15"},
+		{Function: "__libc_start_main", Location: "(/lib/x86_64-linux-gnu/libc.so.6+0x21b96)"},
+	}
+	if c.Summary != "AddressSanitizer: heap-buffer-overflow on address 0x602000000011 at pc 0x4f3c2a bp 0x7ffd sp 0x7ffc" {
+		t.Errorf("unexpected summary %q", c.Summary)
+	}
+	if !reflect.DeepEqual(c.Stack, expectedStack) {
+		t.Errorf("expected only the first stack %v, got %v", expectedStack, c.Stack)
+	}
+	if c.Reproducer != "./crash-7a3d2e" || string(c.Input) != "AB" {
+		t.Errorf("expected the reproducer and input to be found, got %q and %q", c.Reproducer, c.Input)
+	}
+	if sig := c.Signature(); sig != "AddressSanitizer: heap-buffer-overflow on address N at pc N bp N sp N\nparse_header\nLLVMFuzzerTestOneInput" {
+		t.Errorf("unexpected signature %q", sig)
+	}
+}
+
+func TestGoPanics(t *testing.T) {
+	fromLibFuzzer := parseReports(goLibFuzzerLog)
+	if len(fromLibFuzzer) != 1 {
+		t.Fatalf("expected the panic and the signal to be one crash, got %d", len(fromLibFuzzer))
+	}
+	c := fromLibFuzzer[0]
+	if len(c.Stack) != 3 || c.Stack[0] != (Frame{Function: "example.com/pkg.Decode", Location: "/go/src/example.com/pkg/decode.go:20"}) || c.Reproducer != "./crash-99aa" {
+		t.Errorf("expected the Go stack and the reproducer, got %#v", c)
+	}
+
+	fromGoFuzz := parseReports(goFuzzOutput)
+	if len(fromGoFuzz) != 1 || len(fromGoFuzz[0].Stack) != 4 {
+		t.Fatalf("expected 1 crash with 4 frames, got %#v", fromGoFuzz)
+	}
+	expected := "panic: runtime error: index out of range [N] with length N\nexample.com/pkg.Decode\nexample.com/pkg.Fuzz"
+	if a, b := c.Signature(), fromGoFuzz[0].Signature(); a != expected || b != expected {
+		t.Errorf("expected both crashes to have the signature %q, got %q and %q", expected, a, b)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fuzz provides a Spyglass lens that shows the crashes found by fuzzers.
+package fuzz
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "fuzz"
+	title    = "Fuzzing Crashes"
+	priority = 32
+
+	// maxInputBytes is how much of a crashing input is read.
+	maxInputBytes = 64 << 10
+	// maxHexBytes and maxEscapedBytes are how much of a crashing input is shown in each form.
+	maxHexBytes     = 1 << 10
+	maxEscapedBytes = 4 << 10
+)
+
+// reproducerNameRE matches the names of the files fuzzers write crashing inputs to: those of
+// libFuzzer, and the SHA-1 names go-fuzz gives the files in its crashers directory.
+var reproducerNameRE = regexp.MustCompile(`^(?:(?:crash|leak|timeout|oom|slow-unit)-[0-9a-f]+|[0-9a-f]{40})$`)
+
+// Lens is the implementation of a fuzzing crash-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// Reproducer is a file holding a crashing input.
+type Reproducer struct {
+	Name string
+	Link string
+}
+
+// Input is a crashing input, formatted for display.
+type Input struct {
+	Name string
+	Size int
+	// Hex is a hex dump of the start of the input, and Escaped the start of the input as a
+	// quoted Go string.
+	Hex       string
+	Escaped   string
+	Truncated bool
+}
+
+// CrashGroup is the reports of one crash, identified by its signature.
+type CrashGroup struct {
+	Summary string
+	Stack   []Frame
+	// Count is the number of times the crash was reported.
+	Count int
+	// Sources are the files the crash was reported in.
+	Sources     []string
+	Reproducers []Reproducer
+	// Input is the first crashing input that could be read.
+	Input *Input
+}
+
+type fuzzView struct {
+	Errors  []string
+	Crashes []*CrashGroup
+}
+
+// Body groups the crashes reported by fuzzers by signature, with their crashing inputs.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	view := fuzzView{}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	reproducers := map[string]lenses.Artifact{}
+	var reports []lenses.Artifact
+	for _, a := range artifacts {
+		base := path.Base(a.JobPath())
+		switch {
+		case reproducerNameRE.MatchString(base):
+			reproducers[base] = a
+		case strings.HasSuffix(base, ".quoted"):
+			// go-fuzz's quoted copy of an input is redundant with the input itself.
+		default:
+			reports = append(reports, a)
+		}
+	}
+
+	groups := map[string]*CrashGroup{}
+	used := map[string]bool{}
+	add := func(c *Crash, source string) {
+		sig := c.Signature()
+		g, ok := groups[sig]
+		if !ok {
+			g = &CrashGroup{Summary: c.Summary, Stack: c.Stack}
+			groups[sig] = g
+			view.Crashes = append(view.Crashes, g)
+		}
+		g.Count++
+		if len(g.Sources) == 0 || g.Sources[len(g.Sources)-1] != source {
+			g.Sources = append(g.Sources, source)
+		}
+		if r, ok := reproducers[path.Base(c.Reproducer)]; ok && c.Reproducer != "" {
+			if !used[r.JobPath()] {
+				used[r.JobPath()] = true
+				g.Reproducers = append(g.Reproducers, Reproducer{Name: path.Base(r.JobPath()), Link: r.CanonicalLink()})
+			}
+			if g.Input == nil {
+				input, err := readInput(r)
+				if err != nil {
+					view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", r.JobPath(), err))
+				}
+				g.Input = input
+			}
+		} else if c.Input != nil && g.Input == nil {
+			g.Input = formatInput("input in "+source, c.Input, len(c.Input))
+		}
+	}
+	for _, a := range reports {
+		content, err := a.ReadAll()
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		for _, c := range parseReports(string(content)) {
+			// go-fuzz writes the output of each crasher beside it.
+			if strings.HasSuffix(a.JobPath(), ".output") && c.Reproducer == "" {
+				c.Reproducer = strings.TrimSuffix(path.Base(a.JobPath()), ".output")
+			}
+			add(c, a.JobPath())
+		}
+	}
+	// Inputs that no report mentions are grouped together.
+	var names []string
+	for base, r := range reproducers {
+		if !used[r.JobPath()] {
+			names = append(names, base)
+		}
+	}
+	sort.Strings(names)
+	for _, base := range names {
+		add(&Crash{Summary: "No report was found for these inputs.", Reproducer: base}, reproducers[base].JobPath())
+	}
+
+	sort.SliceStable(view.Crashes, func(i, j int) bool { return view.Crashes[i].Count > view.Crashes[j].Count })
+	if len(view.Crashes) == 0 && len(view.Errors) == 0 {
+		view.Errors = append(view.Errors, "No crashes found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func readInput(a lenses.Artifact) (*Input, error) {
+	content, err := a.ReadAtMost(maxInputBytes)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	size := len(content)
+	if s, err := a.Size(); err == nil {
+		size = int(s)
+	}
+	return formatInput(path.Base(a.JobPath()), content, size), nil
+}
+
+// formatInput formats the start of an input as a hex dump and as a quoted string.
+func formatInput(name string, content []byte, size int) *Input {
+	input := &Input{Name: name, Size: size, Truncated: size > len(content)}
+	dumped := content
+	if len(dumped) > maxHexBytes {
+		dumped = dumped[:maxHexBytes]
+		input.Truncated = true
+	}
+	input.Hex = hex.Dump(dumped)
+	escaped := content
+	if len(escaped) > maxEscapedBytes {
+		escaped = escaped[:maxEscapedBytes]
+		input.Truncated = true
+	}
+	input.Escaped = strconv.Quote(string(escaped))
+	return input
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,13 @@
+window.addEventListener('DOMContentLoaded', () => {
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+  for (const checkbox of Array.from(document.querySelectorAll<HTMLInputElement>('.ginkgo-failed-only'))) {
+    checkbox.addEventListener('change', () => {
+      const log = checkbox.closest('.ginkgo-log');
+      if (log) {
+        log.classList.toggle('failed-only', checkbox.checked);
+        spyglass.contentUpdated();
+      }
+    });
+  }
+});
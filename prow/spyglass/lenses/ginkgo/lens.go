@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ginkgo provides a Spyglass lens that splits logs written by Ginkgo, such as the
+// Kubernetes e2e.log, into specs.
+package ginkgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "ginkgo"
+	title    = "E2E Specs"
+	priority = 36
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of a Ginkgo log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each log is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// SpecView is a spec with an anchor to link to it by.
+type SpecView struct {
+	*Spec
+	ID string
+}
+
+// LogView is the specs of one log.
+type LogView struct {
+	Name                    string
+	Link                    string
+	Error                   string
+	Passed, Failed, Skipped int
+	Summary                 []string
+	Specs                   []SpecView
+	Failures                []SpecView
+}
+
+type ginkgoView struct {
+	Errors []string
+	Logs   []LogView
+}
+
+// Body renders the specs of each log with their output, failures first.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := ginkgoView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for i, a := range artifacts {
+		lv := LogView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		var run *Run
+		if err == nil {
+			run, err = parseLog(r)
+		}
+		if err != nil {
+			lv.Error = err.Error()
+		}
+		if run == nil || len(run.Specs) == 0 && lv.Error == "" {
+			continue
+		}
+		lv.Summary = run.Summary
+		lv.Skipped = run.Skipped
+		for j, s := range run.Specs {
+			sv := SpecView{Spec: s, ID: fmt.Sprintf("spec-%d-%d", i, j)}
+			switch {
+			case s.Failed():
+				lv.Failed++
+				lv.Failures = append(lv.Failures, sv)
+			case s.Status == statusPassed:
+				lv.Passed++
+			default:
+				lv.Skipped++
+			}
+			lv.Specs = append(lv.Specs, sv)
+		}
+		view.Logs = append(view.Logs, lv)
+	}
+	if len(view.Logs) == 0 {
+		view.Errors = append(view.Errors, "No Ginkgo specs found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
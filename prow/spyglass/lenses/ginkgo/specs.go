@@ -0,0 +1,307 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outcomes of specs. Specs whose blocks end without a result, usually because the suite timed
+// out or was killed, are incomplete.
+const (
+	statusPassed     = "passed"
+	statusFailed     = "failed"
+	statusPanicked   = "panicked"
+	statusTimedOut   = "timed out"
+	statusSkipped    = "skipped"
+	statusPending    = "pending"
+	statusIncomplete = "incomplete"
+)
+
+var (
+	// separatorRE matches the line Ginkgo prints between specs.
+	separatorRE = regexp.MustCompile(`^-{30}$`)
+	// resultRE matches the line announcing the outcome of a spec, such as "• Failure [12.345 seconds]"
+	// or "• [SLOW TEST:11.234 seconds]". The Kubernetes e2e framework follows it with a JSON
+	// progress report, which is dropped before matching.
+	resultRE = regexp.MustCompile(`^(•|S|P)(?: (Failure|Panic|Timeout|\[SKIPPING\]|\[PENDING\]|\[MEASUREMENT\]|\[SLOW TEST:([\d.]+) seconds\]))?(?: in Spec (Setup|Teardown) \((\w+)\))?(?: \[([\d.]+) seconds\])?$`)
+	// phaseRE matches the line Ginkgo prints when it runs each node of a spec.
+	phaseRE = regexp.MustCompile(`^\[(BeforeEach|JustBeforeEach|It|Measure|AfterEach|JustAfterEach|BeforeSuite|AfterSuite|SynchronizedBeforeSuite|SynchronizedAfterSuite)\]`)
+	// locationRE matches the source locations Ginkgo prints after spec names.
+	locationRE = regexp.MustCompile(`^\s*\S+\.go:\d+$`)
+	// skippedRE matches the S printed for each spec skipped without running.
+	skippedRE = regexp.MustCompile(`^S+$`)
+	// timestampRE matches the timestamp of log lines written by the Kubernetes e2e framework.
+	timestampRE = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d\.\d{3}): `)
+	// summaryRE matches the lines summarizing the whole run.
+	summaryRE = regexp.MustCompile(`^(?:Ran \d+ of \d+ Specs? in |(?:SUCCESS|FAIL)! -- )`)
+)
+
+// timestampLayout is the layout of the timestamps matched by timestampRE.
+const timestampLayout = "Jan _2 15:04:05.000"
+
+// maxSpecLines is how many lines of each spec are kept.
+const maxSpecLines = 5000
+
+// Spec is a spec and the lines logged while running it.
+type Spec struct {
+	Name   string
+	Status string
+	// Line is the line of the log the spec's output starts on.
+	Line     int
+	Duration time.Duration
+	// Body is the lines logged before the spec's AfterEach.
+	Body []string
+	// AfterEach is the lines logged by the spec's AfterEach, where the Kubernetes e2e framework
+	// dumps the state of the cluster when a spec fails.
+	AfterEach []string
+	// Failure is the failure message of a spec that did not pass, and FailedIn where it failed if
+	// not in the spec itself, such as "Setup (BeforeEach)".
+	Failure  string
+	FailedIn string
+	// Truncated is the number of lines not kept.
+	Truncated int
+}
+
+// Failed returns whether the spec did not pass or get skipped.
+func (s *Spec) Failed() bool {
+	switch s.Status {
+	case statusPassed, statusSkipped, statusPending:
+		return false
+	}
+	return true
+}
+
+// Run is a Ginkgo suite run.
+type Run struct {
+	Specs []*Spec
+	// Skipped is the number of specs skipped without running, which are not in Specs.
+	Skipped int
+	// Summary is Ginkgo's summary of the run, if the log reached it.
+	Summary []string
+}
+
+type line struct {
+	number int
+	text   string
+}
+
+// parseLog splits a log written by Ginkgo into specs.
+func parseLog(r io.Reader) (*Run, error) {
+	run := &Run{}
+	var block []line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if separatorRE.MatchString(text) {
+			run.addBlock(block)
+			block = nil
+			continue
+		}
+		block = append(block, line{number: number, text: text})
+	}
+	run.addBlock(block)
+	return run, scanner.Err()
+}
+
+// addBlock adds the spec in the lines between two separators, if there is one.
+func (run *Run) addBlock(block []line) {
+	result, match := -1, []string(nil)
+	for i, l := range block {
+		text := strings.TrimSpace(l.text)
+		if summaryRE.MatchString(text) {
+			run.Summary = append(run.Summary, text)
+		}
+		if skippedRE.MatchString(text) {
+			// Specs skipped without running may be reported before or after a spec.
+			run.Skipped += len(text)
+			continue
+		}
+		if j := strings.Index(text, "{"); j > 0 {
+			text = text[:j]
+		}
+		if match == nil {
+			if match = resultRE.FindStringSubmatch(text); match != nil {
+				result = i
+			}
+		}
+	}
+
+	spec := &Spec{}
+	header := len(block)
+	afterEach := -1
+	end := len(block)
+	if result >= 0 {
+		end = result
+	}
+	hasPhase := false
+	for i := 0; i < end; i++ {
+		text := block[i].text
+		if m := phaseRE.FindStringSubmatch(text); m != nil {
+			hasPhase = true
+			if header == len(block) {
+				header = i
+			}
+			if m[1] == "AfterEach" && afterEach < 0 {
+				afterEach = i
+			}
+		} else if header == len(block) && (strings.HasPrefix(text, "STEP: ") || timestampRE.MatchString(text)) {
+			header = i
+		}
+	}
+	if header > end {
+		header = end
+	}
+	if result < 0 && !hasPhase {
+		// Blocks with neither a result nor any spec nodes are the suite's preamble, its summary or
+		// specs skipped without running.
+		return
+	}
+	if len(block) != 0 {
+		spec.Line = block[0].number
+	}
+	spec.Name = specName(block[:header])
+
+	if afterEach < 0 {
+		afterEach = end
+	}
+	spec.Body = spec.keep(block[header:afterEach])
+	spec.AfterEach = spec.keep(block[afterEach:end])
+
+	if result < 0 {
+		spec.Status = statusIncomplete
+	} else {
+		spec.Status, spec.FailedIn = outcome(match)
+		seconds := match[6]
+		if seconds == "" {
+			seconds = match[3]
+		}
+		if s, err := strconv.ParseFloat(seconds, 64); err == nil {
+			spec.Duration = time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+		}
+		recap, message := splitResult(block[result+1:])
+		if spec.Name == "" {
+			spec.Name = specName(recap)
+		}
+		if spec.Failed() || spec.Status == statusSkipped {
+			spec.Failure = message
+		}
+	}
+	if spec.Duration == 0 {
+		spec.Duration = logSpan(block[header:end])
+	}
+	run.Specs = append(run.Specs, spec)
+}
+
+// keep returns the text of lines, up to the spec's limit.
+func (s *Spec) keep(lines []line) []string {
+	var texts []string
+	for _, l := range lines {
+		if len(s.Body)+len(texts) >= maxSpecLines {
+			s.Truncated++
+			continue
+		}
+		texts = append(texts, l.text)
+	}
+	return texts
+}
+
+// outcome returns the status and the failing node of a spec from the match of its result line.
+func outcome(match []string) (string, string) {
+	failedIn := ""
+	if match[4] != "" {
+		failedIn = match[4] + " (" + match[5] + ")"
+	}
+	switch {
+	case match[1] == "S" || match[2] == "[SKIPPING]":
+		return statusSkipped, failedIn
+	case match[1] == "P" || match[2] == "[PENDING]":
+		return statusPending, failedIn
+	case match[2] == "Failure":
+		return statusFailed, failedIn
+	case match[2] == "Panic":
+		return statusPanicked, failedIn
+	case match[2] == "Timeout":
+		return statusTimedOut, failedIn
+	}
+	return statusPassed, failedIn
+}
+
+// specName joins the lines naming a spec, which are its containers and its own text, each
+// followed by its location.
+func specName(lines []line) string {
+	var parts []string
+	for _, l := range lines {
+		text := strings.TrimSpace(l.text)
+		if text == "" || locationRE.MatchString(text) || skippedRE.MatchString(text) {
+			continue
+		}
+		// Ginkgo marks the node that failed in its recap.
+		text = strings.TrimSuffix(text, " [It]")
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// splitResult splits the lines after a result into the recap of the spec's name and the failure
+// message, which are separated by a blank line. Skipped specs reported without running are dropped
+// from the message.
+func splitResult(lines []line) ([]line, string) {
+	recap := len(lines)
+	for i, l := range lines {
+		if strings.TrimSpace(l.text) == "" {
+			recap = i
+			break
+		}
+	}
+	var message []string
+	for _, l := range lines[recap:] {
+		if skippedRE.MatchString(strings.TrimSpace(l.text)) {
+			continue
+		}
+		message = append(message, strings.TrimPrefix(l.text, "  "))
+	}
+	return lines[:recap], strings.TrimSpace(strings.Join(message, "\n"))
+}
+
+// logSpan returns the time between the first and last timestamped lines.
+func logSpan(lines []line) time.Duration {
+	var first, last time.Time
+	for _, l := range lines {
+		m := timestampRE.FindStringSubmatch(l.text)
+		if m == nil {
+			continue
+		}
+		t, err := time.Parse(timestampLayout, m[1])
+		if err != nil {
+			continue
+		}
+		if first.IsZero() {
+			first = t
+		}
+		last = t
+	}
+	return last.Sub(first)
+}
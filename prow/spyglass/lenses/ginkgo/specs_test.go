@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ginkgo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `Running Suite: Kubernetes e2e suite
+===================================
+Will run 3 of 10 specs
+
+SSS
+------------------------------
+[sig-node] Pods 
+  should run [Conformance]
+  /workspace/test/e2e/framework/framework.go:698
+[BeforeEach] [sig-node] Pods
+  /workspace/test/e2e/framework/framework.go:151
+STEP: Creating a kubernetes client
+[It] should run [Conformance]
+  /workspace/test/e2e/framework/framework.go:698
+[AfterEach] [sig-node] Pods
+  /workspace/test/e2e/framework/framework.go:152
+•{"msg":"PASSED [sig-node] Pods should run [Conformance]","total":3,"completed":1}
+SS
+------------------------------
+[sig-network] DNS 
+  should resolve
+  /workspace/test/e2e/framework/framework.go:698
+[BeforeEach] [sig-network] DNS
+  /workspace/test/e2e/framework/framework.go:151
+Oct  1 12:00:00.000: INFO: >>> kubeConfig: /tmp/kubeconfig
+[It] should resolve
+  /workspace/test/e2e/framework/framework.go:698
+Oct  1 12:00:10.000: INFO: lookup failed
+[AfterEach] [sig-network] DNS
+  /workspace/test/e2e/framework/framework.go:152
+STEP: Collecting events from namespace "dns-1234".
+Oct  1 12:00:12.000: INFO: At 0001-01-01 00:00:00 +0000 UTC - event for dns-test: {kubelet node-1} BackOff
+• Failure [12.345 seconds]
+[sig-network] DNS
+/workspace/test/e2e/network/dns.go:33
+  should resolve [It]
+  /workspace/test/e2e/framework/framework.go:698
+
+  Oct  1 12:00:10.000: timed out waiting for the condition
+
+  /workspace/test/e2e/network/dns_common.go:500
+------------------------------
+[sig-storage] CSI 
+  should mount
+  /workspace/test/e2e/storage/csi.go:20
+[BeforeEach] [sig-storage] CSI
+  /workspace/test/e2e/framework/framework.go:151
+Oct  1 12:01:00.000: INFO: still waiting
+Oct  1 12:01:30.000: INFO: still waiting
+`
+
+func TestParseLog(t *testing.T) {
+	run, err := parseLog(strings.NewReader(testLog))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.Skipped != 5 {
+		t.Errorf("expected 5 specs skipped without running, got %d", run.Skipped)
+	}
+	if len(run.Specs) != 3 {
+		t.Fatalf("expected 3 specs, got %d", len(run.Specs))
+	}
+	pods, dns, csi := run.Specs[0], run.Specs[1], run.Specs[2]
+	if pods.Name != "[sig-node] Pods should run [Conformance]" || pods.Status != statusPassed || pods.Failure != "" {
+		t.Errorf("expected the pods spec to pass, got %q %s", pods.Name, pods.Status)
+	}
+	if dns.Name != "[sig-network] DNS should resolve" || dns.Status != statusFailed || dns.Line != 20 {
+		t.Errorf("expected the DNS spec on line 20 to fail, got %q %s on line %d", dns.Name, dns.Status, dns.Line)
+	}
+	if dns.Duration != 12345*time.Millisecond {
+		t.Errorf("expected the DNS spec to take 12.345s, got %s", dns.Duration)
+	}
+	expected := "Oct  1 12:00:10.000: timed out waiting for the condition\n\n/workspace/test/e2e/network/dns_common.go:500"
+	if dns.Failure != expected {
+		t.Errorf("expected failure %q, got %q", expected, dns.Failure)
+	}
+	if len(dns.Body) != 6 || dns.Body[0] != "[BeforeEach] [sig-network] DNS" {
+		t.Errorf("unexpected body: %q", dns.Body)
+	}
+	if len(dns.AfterEach) != 4 || !strings.HasPrefix(dns.AfterEach[2], "STEP: Collecting events") {
+		t.Errorf("unexpected AfterEach: %q", dns.AfterEach)
+	}
+	if csi.Status != statusIncomplete || csi.Name != "[sig-storage] CSI should mount" || csi.Duration != 30*time.Second {
+		t.Errorf("expected the CSI spec to be incomplete after 30s, got %q %s after %s", csi.Name, csi.Status, csi.Duration)
+	}
+}
+
+func TestResultRE(t *testing.T) {
+	testCases := []struct {
+		line     string
+		status   string
+		failedIn string
+	}{
+		{"•", statusPassed, ""},
+		{"• [SLOW TEST:61.234 seconds]", statusPassed, ""},
+		{"• Failure in Spec Setup (BeforeEach) [5.000 seconds]", statusFailed, "Setup (BeforeEach)"},
+		{"• Panic [1.000 seconds]", statusPanicked, ""},
+		{"S [SKIPPING] in Spec Setup (BeforeEach) [0.500 seconds]", statusSkipped, "Setup (BeforeEach)"},
+		{"P [PENDING]", statusPending, ""},
+	}
+	for _, tc := range testCases {
+		match := resultRE.FindStringSubmatch(tc.line)
+		if match == nil {
+			t.Errorf("expected %q to match", tc.line)
+			continue
+		}
+		if status, failedIn := outcome(match); status != tc.status || failedIn != tc.failedIn {
+			t.Errorf("expected %q to be %s in %q, got %s in %q", tc.line, tc.status, tc.failedIn, status, failedIn)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// goroutineHeaderRE matches the line that starts each goroutine of a dump, such as
+	// "goroutine 7 [chan receive, 5 minutes]:".
+	goroutineHeaderRE = regexp.MustCompile(`^goroutine (\d+) \[([^\]]*)\]:$`)
+	// frameLocationRE matches the second line of a frame, such as "\t/src/main.go:12 +0x1d".
+	frameLocationRE = regexp.MustCompile(`^\t(\S+:\d+)(?: \+0x[0-9a-f]+)?`)
+	// blockedMinutesRE matches how long a goroutine has been blocked.
+	blockedMinutesRE = regexp.MustCompile(`^(\d+) minutes$`)
+	// goPanicRE matches the message that precedes the dump of a crashed program.
+	goPanicRE = regexp.MustCompile(`^(?:panic|fatal error): `)
+)
+
+// StackFrame is a function call in a goroutine's stack.
+type StackFrame struct {
+	Function string
+	Location string
+}
+
+// Goroutine is a goroutine in a dump.
+type Goroutine struct {
+	ID    int
+	State string
+	// Minutes is how long the goroutine has been blocked, if it was reported.
+	Minutes int
+	Stack   []StackFrame
+	// CreatedBy is the call that started the goroutine.
+	CreatedBy *StackFrame
+}
+
+// key identifies goroutines with identical states and stacks.
+func (g *Goroutine) key() string {
+	parts := []string{g.State}
+	for _, f := range g.Stack {
+		parts = append(parts, f.Function+" "+f.Location)
+	}
+	if g.CreatedBy != nil {
+		parts = append(parts, "created by "+g.CreatedBy.Function+" "+g.CreatedBy.Location)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// GoroutineGroup is goroutines with identical states and stacks.
+type GoroutineGroup struct {
+	State     string
+	Stack     []StackFrame
+	CreatedBy *StackFrame
+	IDs       []int
+	// MinMinutes and MaxMinutes are the range of how long the goroutines have been blocked.
+	MinMinutes, MaxMinutes int
+}
+
+// Count returns the number of goroutines in the group.
+func (g *GoroutineGroup) Count() int {
+	return len(g.IDs)
+}
+
+// Function returns the first function in the group's stack outside of the runtime, which is
+// where its goroutines are.
+func (g *GoroutineGroup) Function() string {
+	function := ""
+	for _, f := range g.Stack {
+		if function == "" || !strings.HasPrefix(f.Function, "runtime.") {
+			function = f.Function
+		}
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			break
+		}
+	}
+	return function
+}
+
+// Wait describes how long the group's goroutines have been blocked, if it was reported.
+func (g *GoroutineGroup) Wait() string {
+	switch {
+	case g.MaxMinutes == 0:
+		return ""
+	case g.MinMinutes == g.MaxMinutes:
+		return fmt.Sprintf("%d minutes", g.MaxMinutes)
+	default:
+		return fmt.Sprintf("%d-%d minutes", g.MinMinutes, g.MaxMinutes)
+	}
+}
+
+// IDList lists the IDs of at most the first n of the group's goroutines.
+func (g *GoroutineGroup) IDList(n int) string {
+	var ids []string
+	for i, id := range g.IDs {
+		if i == n {
+			ids = append(ids, "…")
+			break
+		}
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// GoroutineStateCount is the number of goroutines in a state.
+type GoroutineStateCount struct {
+	State string
+	Count int
+}
+
+// GoroutineDump is a consecutive run of goroutines in an artifact.
+type GoroutineDump struct {
+	// Line is the line of the artifact the dump starts on.
+	Line int
+	// Panic is the panic or fatal error that caused the dump, if any.
+	Panic      string
+	Goroutines []*Goroutine
+}
+
+// Groups returns the dump's goroutines grouped by state and stack, largest groups first.
+// Running goroutines, which include the one that panicked, come before all others.
+func (d *GoroutineDump) Groups() []*GoroutineGroup {
+	var groups []*GoroutineGroup
+	index := map[string]*GoroutineGroup{}
+	for _, g := range d.Goroutines {
+		k := g.key()
+		group, ok := index[k]
+		if !ok {
+			group = &GoroutineGroup{State: g.State, Stack: g.Stack, CreatedBy: g.CreatedBy, MinMinutes: g.Minutes, MaxMinutes: g.Minutes}
+			index[k] = group
+			groups = append(groups, group)
+		}
+		group.IDs = append(group.IDs, g.ID)
+		if g.Minutes < group.MinMinutes {
+			group.MinMinutes = g.Minutes
+		}
+		if g.Minutes > group.MaxMinutes {
+			group.MaxMinutes = g.Minutes
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if ri, rj := groups[i].State == "running", groups[j].State == "running"; ri != rj {
+			return ri
+		}
+		return groups[i].Count() > groups[j].Count()
+	})
+	return groups
+}
+
+// States counts the dump's goroutines in each state, most common first.
+func (d *GoroutineDump) States() []GoroutineStateCount {
+	counts := map[string]int{}
+	for _, g := range d.Goroutines {
+		counts[g.State]++
+	}
+	var states []GoroutineStateCount
+	for state, count := range counts {
+		states = append(states, GoroutineStateCount{State: state, Count: count})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Count != states[j].Count {
+			return states[i].Count > states[j].Count
+		}
+		return states[i].State < states[j].State
+	})
+	return states
+}
+
+// parseGoroutineState splits the bracketed state of a goroutine, such as "IO wait, 3 minutes,
+// locked to thread", into the state and how long it has been blocked.
+func parseGoroutineState(s string) (string, int) {
+	parts := strings.Split(s, ", ")
+	minutes := 0
+	for _, p := range parts[1:] {
+		if m := blockedMinutesRE.FindStringSubmatch(p); m != nil {
+			minutes, _ = strconv.Atoi(m[1])
+		}
+	}
+	return parts[0], minutes
+}
+
+// callFunction drops the arguments from a call in a stack.
+func callFunction(call string) string {
+	if strings.HasSuffix(call, ")") {
+		if i := strings.LastIndex(call, "("); i > 0 {
+			return call[:i]
+		}
+	}
+	return call
+}
+
+// ParseGoroutineDumps finds the goroutine dumps in a log, such as those Go programs write when
+// they panic or are sent SIGQUIT. Lines that are not part of a dump separate dumps, except for
+// the blank lines between goroutines.
+func ParseGoroutineDumps(r io.Reader) ([]*GoroutineDump, error) {
+	var dumps []*GoroutineDump
+	var current *GoroutineDump
+	var goroutine *Goroutine
+	// pending is a function awaiting its location, and created whether it started the goroutine.
+	pending, created := "", false
+	lastPanic, lastPanicLine := "", 0
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if m := goroutineHeaderRE.FindStringSubmatch(line); m != nil {
+			if pending != "" {
+				// The line before had no location, so it was not part of a stack.
+				current = nil
+			}
+			if current == nil {
+				current = &GoroutineDump{Line: lineNumber}
+				// The panic message is separated from the dump by a blank line.
+				if lastPanic != "" && lineNumber-lastPanicLine <= 3 {
+					current.Panic = lastPanic
+				}
+				dumps = append(dumps, current)
+			}
+			id, _ := strconv.Atoi(m[1])
+			state, minutes := parseGoroutineState(m[2])
+			goroutine = &Goroutine{ID: id, State: state, Minutes: minutes}
+			current.Goroutines = append(current.Goroutines, goroutine)
+			pending, created = "", false
+			continue
+		}
+		if goPanicRE.MatchString(line) {
+			lastPanic, lastPanicLine = line, lineNumber
+		}
+		if current == nil {
+			continue
+		}
+		switch {
+		case goroutine != nil && pending != "" && frameLocationRE.MatchString(line):
+			frame := StackFrame{Function: pending, Location: frameLocationRE.FindStringSubmatch(line)[1]}
+			if created {
+				goroutine.CreatedBy = &frame
+			} else {
+				goroutine.Stack = append(goroutine.Stack, frame)
+			}
+			pending = ""
+		case line == "":
+			// Goroutines are separated by blank lines.
+			goroutine, pending = nil, ""
+		case goroutine != nil && strings.HasPrefix(line, "created by "):
+			// Newer versions of Go add the goroutine that created this one.
+			pending, created = strings.TrimPrefix(line, "created by "), true
+			if i := strings.Index(pending, " in goroutine "); i >= 0 {
+				pending = pending[:i]
+			}
+		case goroutine != nil && pending == "" && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, " "):
+			if line == "...additional frames elided..." {
+				goroutine.Stack = append(goroutine.Stack, StackFrame{Function: line})
+				continue
+			}
+			pending = callFunction(line)
+		default:
+			// Anything else ends the dump.
+			current, goroutine, pending = nil, nil, ""
+		}
+	}
+	return dumps, scanner.Err()
+}
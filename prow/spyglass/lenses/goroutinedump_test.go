@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLog = `I0102 15:04:05.000000 starting
+panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a2b1c]
+
+goroutine 1 [running]:
+main.(*server).handle(0x0, 0xc000010000)
+	/go/src/example.com/server/main.go:42 +0x1c
+main.main()
+	/go/src/example.com/server/main.go:12 +0x2f
+
+goroutine 7 [chan receive, 5 minutes]:
+main.worker(0xc00001e0c0)
+	/go/src/example.com/server/worker.go:20 +0x45
+created by main.main in goroutine 1
+	/go/src/example.com/server/main.go:10 +0x1a
+
+goroutine 8 [chan receive, 12 minutes]:
+main.worker(0xc00001e0c0)
+	/go/src/example.com/server/worker.go:20 +0x45
+created by main.main in goroutine 1
+	/go/src/example.com/server/main.go:10 +0x1a
+
+goroutine 9 [IO wait]:
+internal/poll.runtime_pollWait(0x7f0000000000, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:343 +0x85
+...additional frames elided...
+exit status 2
+goroutine 3 [select]:
+main.loop()
+	/go/src/example.com/server/loop.go:5 +0x10
+`
+
+func TestParseGoroutineDumps(t *testing.T) {
+	dumps, err := ParseGoroutineDumps(strings.NewReader(testLog))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dumps) != 2 {
+		t.Fatalf("expected 2 dumps, got %d", len(dumps))
+	}
+	d := dumps[0]
+	if d.Line != 5 || d.Panic != "panic: runtime error: invalid memory address or nil pointer dereference" {
+		t.Errorf("expected the dump on line 5 to have the panic, got line %d and %q", d.Line, d.Panic)
+	}
+	if len(d.Goroutines) != 4 {
+		t.Fatalf("expected 4 goroutines, got %d", len(d.Goroutines))
+	}
+	if s := d.Goroutines[0].Stack; len(s) != 2 || s[0] != (StackFrame{Function: "main.(*server).handle", Location: "/go/src/example.com/server/main.go:42"}) {
+		t.Errorf("unexpected stack of goroutine 1: %v", s)
+	}
+	if c := d.Goroutines[1].CreatedBy; c == nil || *c != (StackFrame{Function: "main.main", Location: "/go/src/example.com/server/main.go:10"}) {
+		t.Errorf("expected goroutine 7 to be created by main.main, got %v", c)
+	}
+	if s := d.Goroutines[3].Stack; len(s) != 2 || s[1].Function != "...additional frames elided..." {
+		t.Errorf("expected elided frames to be kept, got %v", s)
+	}
+
+	groups := d.Groups()
+	if len(groups) != 3 || groups[0].State != "running" || groups[1].State != "chan receive" {
+		t.Fatalf("expected running, chan receive and IO wait groups, got %v", groups)
+	}
+	if !reflect.DeepEqual(groups[1].IDs, []int{7, 8}) || groups[1].MinMinutes != 5 || groups[1].MaxMinutes != 12 {
+		t.Errorf("expected goroutines 7 and 8 blocked 5-12 minutes, got %v blocked %d-%d minutes", groups[1].IDs, groups[1].MinMinutes, groups[1].MaxMinutes)
+	}
+	expected := []GoroutineStateCount{{"chan receive", 2}, {"IO wait", 1}, {"running", 1}}
+	if states := d.States(); !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected states %v, got %v", expected, states)
+	}
+
+	if dumps[1].Panic != "" || len(dumps[1].Goroutines) != 1 || dumps[1].Goroutines[0].State != "select" {
+		t.Errorf("expected a second dump with one selecting goroutine, got %#v", dumps[1])
+	}
+}
+
+func TestGoroutineGroupSummary(t *testing.T) {
+	g := &GoroutineGroup{
+		Stack: []StackFrame{
+			{Function: "runtime.gopark"},
+			{Function: "runtime.chanrecv"},
+			{Function: "main.worker"},
+			{Function: "main.main"},
+		},
+		IDs:        []int{3, 5, 8},
+		MinMinutes: 2,
+		MaxMinutes: 9,
+	}
+	if f := g.Function(); f != "main.worker" {
+		t.Errorf("expected the first function outside of the runtime, got %q", f)
+	}
+	if w := g.Wait(); w != "2-9 minutes" {
+		t.Errorf("expected a wait of 2-9 minutes, got %q", w)
+	}
+	if ids := g.IDList(2); ids != "3, 5, …" {
+		t.Errorf("expected the first two IDs, got %q", ids)
+	}
+
+	g = &GoroutineGroup{Stack: []StackFrame{{Function: "runtime.gopark"}}, IDs: []int{1}}
+	if f := g.Function(); f != "runtime.gopark" {
+		t.Errorf("expected the first function of a stack in the runtime, got %q", f)
+	}
+	if w := g.Wait(); w != "" {
+		t.Errorf("expected no wait, got %q", w)
+	}
+	if ids := g.IDList(2); ids != "1" {
+		t.Errorf("expected all IDs, got %q", ids)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package goroutines provides a Spyglass lens that summarizes Go panics and goroutine dumps.
+package goroutines
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "goroutines"
+	title    = "Goroutine Dumps"
+	priority = 33
+
+	// defaultMaxBytes is how much of each artifact is searched if not configured.
+	defaultMaxBytes = 100 << 20
+	// maxIDs is the number of goroutine IDs listed for each group.
+	maxIDs = 20
+)
+
+// Lens is the implementation of a goroutine dump-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each artifact is searched for dumps. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// GroupView is a group of goroutines.
+type GroupView struct {
+	*lenses.GoroutineGroup
+	// IDs lists the first of the goroutines' IDs.
+	IDs string
+}
+
+// DumpView is a goroutine dump.
+type DumpView struct {
+	*lenses.GoroutineDump
+	Total  int
+	States []lenses.GoroutineStateCount
+	Groups []GroupView
+}
+
+// FileView is the dumps in one artifact.
+type FileView struct {
+	Name  string
+	Link  string
+	Dumps []DumpView
+}
+
+type goroutinesView struct {
+	Errors []string
+	Files  []FileView
+}
+
+// Body renders the goroutines of each dump, grouped by state and stack.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := goroutinesView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		var dumps []*lenses.GoroutineDump
+		if err == nil {
+			dumps, err = lenses.ParseGoroutineDumps(r)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to search %s: %v", a.JobPath(), err))
+		}
+		if len(dumps) == 0 {
+			continue
+		}
+		fv := FileView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		for _, d := range dumps {
+			fv.Dumps = append(fv.Dumps, summarize(d))
+		}
+		view.Files = append(view.Files, fv)
+	}
+	if len(view.Files) == 0 {
+		view.Errors = append(view.Errors, "No goroutine dumps found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func summarize(d *lenses.GoroutineDump) DumpView {
+	dv := DumpView{GoroutineDump: d, Total: len(d.Goroutines), States: d.States()}
+	for _, g := range d.Groups() {
+		dv.Groups = append(dv.Groups, GroupView{GoroutineGroup: g, IDs: g.IDList(maxIDs)})
+	}
+	return dv
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// event is a line of test2json output, as documented by `go doc test2json`.
+type event struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// Statuses of tests and packages. Tests that never report an outcome, usually because the
+// test binary timed out or crashed, are incomplete.
+const (
+	statusPass       = "pass"
+	statusFail       = "fail"
+	statusSkip       = "skip"
+	statusIncomplete = "incomplete"
+)
+
+// Test is a test or subtest and its output.
+type Test struct {
+	// Name is the full name of the test, including its parents.
+	Name string
+	// Short is the name of the test within its parent.
+	Short    string
+	Status   string
+	Elapsed  time.Duration
+	Output   string
+	Subtests []*Test
+}
+
+// Package is the tests run in a package.
+type Package struct {
+	Name    string
+	Status  string
+	Elapsed time.Duration
+	// Output is the output not attributed to any test, such as build failures and panics.
+	Output string
+	Tests  []*Test
+
+	tests map[string]*Test
+	// Counts of the outcomes of every test, including subtests.
+	Passed, Failed, Skipped, Incomplete int
+}
+
+// Results is the reconstructed output of one or more `go test -json` runs.
+type Results struct {
+	Packages []*Package
+	// Other is any output that was not a test2json event.
+	Other string
+
+	packages map[string]*Package
+}
+
+func newResults() *Results {
+	return &Results{packages: map[string]*Package{}}
+}
+
+// framingRE matches the lines the testing package uses to mark the start and end of tests,
+// which are redundant once output is grouped by test.
+var framingRE = regexp.MustCompile(`^\s*(?:=== (?:RUN|PAUSE|CONT)\s|--- (?:PASS|FAIL|SKIP): .* \(\d+\.\d+s\)$)`)
+
+func (r *Results) pkg(name string) *Package {
+	if p, ok := r.packages[name]; ok {
+		return p
+	}
+	p := &Package{Name: name, Status: statusIncomplete, tests: map[string]*Test{}}
+	r.packages[name] = p
+	r.Packages = append(r.Packages, p)
+	return p
+}
+
+// test returns the test with the given name, creating it and any missing parents.
+func (p *Package) test(name string) *Test {
+	if t, ok := p.tests[name]; ok {
+		return t
+	}
+	t := &Test{Name: name, Short: name, Status: statusIncomplete}
+	p.tests[name] = t
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent := p.test(name[:i])
+		t.Short = name[i+1:]
+		parent.Subtests = append(parent.Subtests, t)
+	} else {
+		p.Tests = append(p.Tests, t)
+	}
+	return t
+}
+
+// add reads a stream of test2json events. Output from several packages may be interleaved.
+func (r *Results) add(content []byte) {
+	var other strings.Builder
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e event
+		if line[0] != '{' || json.Unmarshal(line, &e) != nil || e.Action == "" {
+			other.Write(line)
+			other.WriteByte('\n')
+			continue
+		}
+		p := r.pkg(e.Package)
+		elapsed := time.Duration(e.Elapsed * float64(time.Second)).Round(time.Millisecond)
+		if e.Test == "" {
+			switch e.Action {
+			case "output":
+				p.Output += e.Output
+			case statusPass, statusFail, statusSkip:
+				p.Status = e.Action
+				p.Elapsed = elapsed
+			}
+			continue
+		}
+		t := p.test(e.Test)
+		switch e.Action {
+		case "output":
+			if !framingRE.MatchString(strings.TrimRight(e.Output, "\n")) {
+				t.Output += e.Output
+			}
+		case statusPass, statusFail, statusSkip:
+			t.Status = e.Action
+			t.Elapsed = elapsed
+		}
+	}
+	r.Other += other.String()
+}
+
+// finish tallies the outcomes of each package's tests.
+func (r *Results) finish() {
+	for _, p := range r.Packages {
+		for _, t := range p.tests {
+			switch t.Status {
+			case statusPass:
+				p.Passed++
+			case statusFail:
+				p.Failed++
+			case statusSkip:
+				p.Skipped++
+			default:
+				p.Incomplete++
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gotest
+
+import (
+	"testing"
+	"time"
+)
+
+// testStream interleaves two packages and runs the subtests of TestB in parallel.
+const testStream = `{"Action":"run","Package":"example.com/a","Test":"TestA"}
+{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"run","Package":"example.com/b","Test":"TestB"}
+{"Action":"run","Package":"example.com/b","Test":"TestB/one"}
+{"Action":"pause","Package":"example.com/b","Test":"TestB/one"}
+{"Action":"run","Package":"example.com/b","Test":"TestB/two"}
+{"Action":"pause","Package":"example.com/b","Test":"TestB/two"}
+{"Action":"cont","Package":"example.com/b","Test":"TestB/one"}
+{"Action":"cont","Package":"example.com/b","Test":"TestB/two"}
+{"Action":"output","Package":"example.com/b","Test":"TestB/two","Output":"    b_test.go:12: two broke\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"--- PASS: TestA (0.01s)\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0.01}
+{"Action":"output","Package":"example.com/b","Test":"TestB/one","Output":"    b_test.go:8: one is fine\n"}
+{"Action":"pass","Package":"example.com/b","Test":"TestB/one","Elapsed":0.5}
+{"Action":"fail","Package":"example.com/b","Test":"TestB/two","Elapsed":1.25}
+{"Action":"fail","Package":"example.com/b","Test":"TestB","Elapsed":1.3}
+{"Action":"output","Package":"example.com/a","Output":"PASS\n"}
+{"Action":"pass","Package":"example.com/a","Elapsed":0.02}
+{"Action":"run","Package":"example.com/b","Test":"TestHang"}
+# example.com/c
+c.go:3: undefined: x
+{"Action":"fail","Package":"example.com/b","Elapsed":600}`
+
+func TestResults(t *testing.T) {
+	r := newResults()
+	r.add([]byte(testStream))
+	r.finish()
+
+	if len(r.Packages) != 2 || r.Packages[0].Name != "example.com/a" || r.Packages[1].Name != "example.com/b" {
+		t.Fatalf("expected packages a and b in order, got %v", r.Packages)
+	}
+	a, b := r.Packages[0], r.Packages[1]
+	if a.Status != statusPass || a.Output != "PASS\n" || a.Passed != 1 {
+		t.Errorf("unexpected package a: %#v", a)
+	}
+	if a.Tests[0].Output != "" {
+		t.Errorf("expected framing lines to be dropped, got %q", a.Tests[0].Output)
+	}
+
+	if b.Status != statusFail || b.Elapsed != 10*time.Minute {
+		t.Errorf("expected package b to fail after 10m, got %s after %s", b.Status, b.Elapsed)
+	}
+	if len(b.Tests) != 2 || b.Tests[0].Name != "TestB" || b.Tests[1].Status != statusIncomplete {
+		t.Fatalf("expected TestB and an incomplete TestHang, got %v", b.Tests)
+	}
+	subtests := b.Tests[0].Subtests
+	if len(subtests) != 2 || subtests[0].Short != "one" || subtests[1].Short != "two" {
+		t.Fatalf("expected subtests one and two, got %v", subtests)
+	}
+	if subtests[0].Output != "    b_test.go:8: one is fine\n" || subtests[1].Output != "    b_test.go:12: two broke\n" {
+		t.Errorf("expected interleaved output to be attributed to each subtest, got %q and %q", subtests[0].Output, subtests[1].Output)
+	}
+	if subtests[1].Status != statusFail || subtests[1].Elapsed != 1250*time.Millisecond {
+		t.Errorf("expected two to fail after 1.25s, got %s after %s", subtests[1].Status, subtests[1].Elapsed)
+	}
+	if b.Passed != 1 || b.Failed != 2 || b.Incomplete != 1 {
+		t.Errorf("expected 1 passed, 2 failed and 1 incomplete, got %d, %d and %d", b.Passed, b.Failed, b.Incomplete)
+	}
+	if r.Other != "# example.com/c\nc.go:3: undefined: x\n" {
+		t.Errorf("expected the build failure to be kept, got %q", r.Other)
+	}
+}
@@ -0,0 +1,19 @@
+function setAllOpen(open: boolean): void {
+  for (const details of Array.from(document.querySelectorAll<HTMLDetailsElement>('details'))) {
+    details.open = open;
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  const expand = document.getElementById('gotest-expand');
+  if (expand) {
+    expand.addEventListener('click', () => setAllOpen(true));
+  }
+  const collapse = document.getElementById('gotest-collapse');
+  if (collapse) {
+    collapse.addEventListener('click', () => setAllOpen(false));
+  }
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+});
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+)
+
+// GrepMatch is a line of an artifact that matched a pattern, along with the lines around it.
+type GrepMatch struct {
+	// Line is the number of the matching line, counting from 1.
+	Line int64
+	// Offset is the byte offset at which the matching line starts.
+	Offset int64
+	// Length is the length of the matching line in bytes, including its line ending.
+	Length int64
+	Text   string
+	// Before and After hold the lines preceding and following the match, up to the number asked
+	// for. They may themselves match.
+	Before []string
+	After  []string
+}
+
+// Grep scans an artifact a chunk at a time for lines matching pattern, returning at most
+// maxMatches of them (or all of them if maxMatches is less than 1) with up to before and after
+// lines of context. Lines are split and collapsed as by LastNLines.
+func Grep(a Artifact, pattern *regexp.Regexp, before, after, maxMatches int) ([]GrepMatch, error) {
+	cr, err := NewChunkedReader(a, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(cr)
+	var matches []GrepMatch
+	var preceding []string
+	// pending holds the indices of the matches still collecting lines after them.
+	var pending []int
+	var offset int64
+	for line := int64(1); ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading artifact: %v", err)
+		}
+		if len(raw) == 0 {
+			break
+		}
+		text := CollapseCarriageReturns(string(bytes.TrimSuffix(raw, []byte("\n"))))
+
+		stillPending := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, text)
+			if len(matches[i].After) < after {
+				stillPending = append(stillPending, i)
+			}
+		}
+		pending = stillPending
+
+		if (maxMatches < 1 || len(matches) < maxMatches) && pattern.MatchString(text) {
+			matches = append(matches, GrepMatch{
+				Line:   line,
+				Offset: offset,
+				Length: int64(len(raw)),
+				Text:   text,
+				Before: append([]string(nil), preceding...),
+			})
+			if after > 0 {
+				pending = append(pending, len(matches)-1)
+			}
+		}
+		if maxMatches > 0 && len(matches) >= maxMatches && len(pending) == 0 {
+			break
+		}
+
+		if before > 0 {
+			if len(preceding) == before {
+				preceding = preceding[1:]
+			}
+			preceding = append(preceding, text)
+		}
+		offset += int64(len(raw))
+		if err == io.EOF {
+			break
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	log := "ok 1\nFAIL: a\nok 2\nok 3\r\nFAIL: b\nok 4\nprogress 50%\rFAIL: c\n"
+	testCases := []struct {
+		name          string
+		before, after int
+		maxMatches    int
+		expected      []GrepMatch
+	}{
+		{
+			name: "all matches without context",
+			expected: []GrepMatch{
+				{Line: 2, Offset: 5, Length: 8, Text: "FAIL: a"},
+				{Line: 5, Offset: 24, Length: 8, Text: "FAIL: b"},
+				{Line: 7, Offset: 37, Length: 21, Text: "FAIL: c"},
+			},
+		},
+		{
+			name:   "context lines are collected around matches",
+			before: 1,
+			after:  2,
+			expected: []GrepMatch{
+				{Line: 2, Offset: 5, Length: 8, Text: "FAIL: a", Before: []string{"ok 1"}, After: []string{"ok 2", "ok 3"}},
+				{Line: 5, Offset: 24, Length: 8, Text: "FAIL: b", Before: []string{"ok 3"}, After: []string{"ok 4", "FAIL: c"}},
+				{Line: 7, Offset: 37, Length: 21, Text: "FAIL: c", Before: []string{"ok 4"}},
+			},
+		},
+		{
+			name:       "matching stops at the limit once context is collected",
+			before:     2,
+			after:      1,
+			maxMatches: 1,
+			expected: []GrepMatch{
+				{Line: 2, Offset: 5, Length: 8, Text: "FAIL: a", Before: []string{"ok 1"}, After: []string{"ok 2"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifact := &FakeArtifact{path: "build-log.txt", content: []byte(log), sizeLimit: 500e6}
+			actual, err := Grep(artifact, regexp.MustCompile(`^FAIL`), tc.before, tc.after, tc.maxMatches)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected matches %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
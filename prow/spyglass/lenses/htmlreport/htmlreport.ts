@@ -0,0 +1,9 @@
+window.addEventListener('DOMContentLoaded', () => {
+  for (const frame of Array.from(document.querySelectorAll<HTMLIFrameElement>('iframe[data-artifact]'))) {
+    frame.addEventListener('load', () => spyglass.contentUpdated());
+    frame.src = spyglass.reportURL(frame.dataset.artifact!);
+  }
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.htmlreport-open'))) {
+    link.href = spyglass.reportURL(link.dataset.artifact!);
+  }
+});
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package htmlreport provides a Spyglass lens that displays self-contained HTML reports, such as
+// coverage reports, in a sandboxed frame.
+package htmlreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	// Name is the name of the lens, under which its configuration is found.
+	Name     = "htmlreport"
+	title    = "HTML Reports"
+	priority = 26
+
+	// defaultMaxBytes is the size of the largest report that is displayed if not configured.
+	defaultMaxBytes = 20 << 20
+)
+
+// Lens is the implementation of an HTML report-displaying Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is the size of the largest report that is displayed. Defaults to 20MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// MaxBytes returns the size of the largest report that may be displayed under the given lens
+// configuration. Reports are served by Deck, which must enforce the same limit.
+func MaxBytes(rawConfig json.RawMessage) int64 {
+	conf := config{}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			logrus.WithError(err).Info("Invalid HTML report lens configuration.")
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		return defaultMaxBytes
+	}
+	return conf.MaxBytes
+}
+
+// IsReport returns whether the named artifact may be served as an HTML report.
+func IsReport(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     Name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Report is a single HTML report.
+type Report struct {
+	Name string
+	Path string
+	Link string
+	Size string
+	// TooLarge is whether the report is over the size limit, in which case it is only linked.
+	TooLarge bool
+}
+
+type reportView struct {
+	Reports  []Report
+	MaxBytes string
+}
+
+// Body renders a frame for each report. The frames load the reports from Deck, which serves
+// them with a content security policy that keeps them from running in Deck's origin or
+// fetching anything.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	maxBytes := MaxBytes(rawConfig)
+	view := reportView{MaxBytes: chart.FormatBytes(float64(maxBytes))}
+	for _, a := range artifacts {
+		if !IsReport(a.JobPath()) {
+			continue
+		}
+		r := Report{
+			Name: path.Base(a.JobPath()),
+			Path: a.JobPath(),
+			Link: a.CanonicalLink(),
+		}
+		if size, err := a.Size(); err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Failed to get report size.")
+		} else {
+			r.Size = chart.FormatBytes(float64(size))
+			r.TooLarge = size > maxBytes
+		}
+		view.Reports = append(view.Reports, r)
+	}
+	sort.Slice(view.Reports, func(i, j int) bool { return view.Reports[i].Path < view.Reports[j].Path })
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
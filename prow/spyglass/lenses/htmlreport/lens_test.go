@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package htmlreport
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func TestIsReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "artifacts/coverage.html", expected: true},
+		{name: "artifacts/coverage.htm", expected: true},
+		{name: "artifacts/COVERAGE.HTML", expected: true},
+		{name: "artifacts/coverage.html.gz"},
+		{name: "artifacts/coverage.txt"},
+		{name: "artifacts/html"},
+		{name: "artifacts/html/index"},
+	}
+	for _, tc := range testCases {
+		if actual := IsReport(tc.name); actual != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   json.RawMessage
+		expected int64
+	}{
+		{
+			name:     "no config uses the default",
+			expected: defaultMaxBytes,
+		},
+		{
+			name:     "configured limit is used",
+			config:   json.RawMessage(`{"max_bytes": 1024}`),
+			expected: 1024,
+		},
+		{
+			name:     "non-positive limit uses the default",
+			config:   json.RawMessage(`{"max_bytes": -1}`),
+			expected: defaultMaxBytes,
+		},
+		{
+			name:     "invalid config uses the default",
+			config:   json.RawMessage(`not json`),
+			expected: defaultMaxBytes,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := MaxBytes(tc.config); actual != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/b.html", Content: []byte("<p>b</p>")},
+		&fakeartifact.Artifact{Path: "artifacts/a.html", Content: []byte("<p>a</p>")},
+		&fakeartifact.Artifact{Path: "artifacts/large.html", Content: []byte(strings.Repeat("x", 11))},
+		&fakeartifact.Artifact{Path: "artifacts/build-log.txt", Content: []byte("log")},
+	}
+	body := (Lens{}).Body(artifacts, ".", "", json.RawMessage(`{"max_bytes": 10}`))
+
+	a, b := strings.Index(body, `data-artifact="artifacts/a.html"`), strings.Index(body, `data-artifact="artifacts/b.html"`)
+	if a == -1 || b == -1 {
+		t.Fatalf("expected frames for both reports, got %s", body)
+	}
+	if a > b {
+		t.Errorf("expected reports to be sorted by path")
+	}
+	if !strings.Contains(body, `sandbox="allow-scripts allow-popups"`) {
+		t.Errorf("expected reports to be framed in a sandbox, got %s", body)
+	}
+	if !strings.Contains(body, `href="https://example.com/artifacts/large.html"`) {
+		t.Errorf("expected a download link for the report over the limit, got %s", body)
+	}
+	if strings.Contains(body, `data-artifact="artifacts/large.html"`) {
+		t.Errorf("expected the report over the limit not to be framed, got %s", body)
+	}
+	if !strings.Contains(body, "can only be downloaded") {
+		t.Errorf("expected the report over the limit to be explained, got %s", body)
+	}
+	if strings.Contains(body, "build-log.txt") {
+		t.Errorf("expected artifacts that aren't reports to be ignored, got %s", body)
+	}
+}
+
+func TestBodyWithoutReports(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/build-log.txt", Content: []byte("log")},
+	}
+	body := (Lens{}).Body(artifacts, ".", "", nil)
+	if !strings.Contains(body, "No HTML reports were found.") {
+		t.Errorf("expected a message when there are no reports, got %s", body)
+	}
+}
@@ -0,0 +1,390 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagebuild
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formatDocker   = "docker"
+	formatBuildKit = "BuildKit"
+	formatKaniko   = "kaniko"
+
+	cacheHit  = "cached"
+	cacheMiss = "not cached"
+
+	// maxStepLines is how many lines of each step are kept.
+	maxStepLines = 2000
+	// maxTrailerLines is how many lines following a failed BuildKit build are kept as its error
+	// summary.
+	maxTrailerLines = 50
+)
+
+var (
+	// timestampRE matches a timestamp some log collectors add to each line.
+	timestampRE = regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:\d\d)) `)
+
+	// dockerStepRE matches the start of a step of the classic builder, such as
+	// "Step 3/7 : RUN make".
+	dockerStepRE   = regexp.MustCompile(`^Step (\d+)/\d+ : (.*)$`)
+	dockerFailedRE = regexp.MustCompile(`^The command .* returned a non-zero code: \d+$`)
+
+	// buildKitRE matches the lines of BuildKit's plain progress output, which are prefixed with
+	// the number of the step they belong to, such as "#5 [2/4] RUN make".
+	buildKitRE     = regexp.MustCompile(`^#(\d+) (.*)$`)
+	buildKitDoneRE = regexp.MustCompile(`^DONE (\d+(?:\.\d+)?)s$`)
+	// buildKitStageRE matches the names of steps that come from the Dockerfile, such as
+	// "[builder 2/4] RUN make", rather than BuildKit's own, such as "[internal] load .dockerignore".
+	buildKitStageRE = regexp.MustCompile(`^\[(?:\S+ )?\d+/\d+\] `)
+
+	// kanikoRE matches kaniko's log lines, which are prefixed with their level and the seconds
+	// since the build started, such as "INFO[0012] RUN make".
+	kanikoRE        = regexp.MustCompile(`^(INFO|WARN|ERRO|FATA|DEBU|TRAC)\[(\d+)\] (.*)$`)
+	kanikoCommandRE = regexp.MustCompile(`^(RUN|COPY|ADD|ENV|ARG|WORKDIR|USER|LABEL|EXPOSE|VOLUME|ENTRYPOINT|CMD|SHELL|HEALTHCHECK|STOPSIGNAL|ONBUILD) `)
+	kanikoHitRE     = regexp.MustCompile(`^Using caching version of cmd: (.*)$`)
+	kanikoMissRE    = regexp.MustCompile(`^No cached layer found for cmd (.*)$`)
+	kanikoPushRE    = regexp.MustCompile(`^(Pushing image|Pushed |Skipping push)`)
+)
+
+// Step is a step of an image build.
+type Step struct {
+	// ID is the step's number. BuildKit numbers its own steps as well as the Dockerfile's.
+	ID      string
+	Command string
+	// Line is the line of the log the step starts on.
+	Line  int
+	Lines []string
+	// Truncated is the number of lines of the step not kept.
+	Truncated int
+	// Cache is whether the step's layer came from the cache, if the build said.
+	Cache    string
+	Failed   bool
+	Duration time.Duration
+	// timed is whether Duration is known. start and end are offsets from the start of the log
+	// for builders that only report when lines were written.
+	timed      bool
+	start, end time.Duration
+}
+
+func (s *Step) add(line string) {
+	if len(s.Lines) < maxStepLines {
+		s.Lines = append(s.Lines, line)
+	} else {
+		s.Truncated++
+	}
+}
+
+// Timed reports whether the step's duration is known.
+func (s *Step) Timed() bool {
+	return s.timed
+}
+
+// Build is an image build in a log.
+type Build struct {
+	Format string
+	// Line is the line of the log the build starts on.
+	Line  int
+	Steps []*Step
+	// Output is the build's lines that belong to no step, such as BuildKit's error summary.
+	Output []string
+	Failed bool
+}
+
+// Cached counts the steps whose layers came from the cache.
+func (b *Build) Cached() int {
+	n := 0
+	for _, s := range b.Steps {
+		if s.Cache == cacheHit {
+			n++
+		}
+	}
+	return n
+}
+
+// Duration adds up the known durations of the build's steps. BuildKit runs steps in parallel, so
+// this can be longer than the build took.
+func (b *Build) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range b.Steps {
+		d += s.Duration
+	}
+	return d
+}
+
+// parser finds the image builds in a log.
+type parser struct {
+	builds  []*Build
+	build   *Build
+	step    *Step
+	lineNum int
+	// offset is when the current line was written, if known, and first when the first line
+	// with a time was.
+	offset         time.Duration
+	timed          bool
+	first          time.Time
+	buildKitSteps  map[string]*Step
+	kanikoCache    map[string]string
+	kanikoLast     int
+	trailer        int
+	dockerLastStep int
+}
+
+// parseBuilds finds the docker, BuildKit and kaniko builds in a log.
+func parseBuilds(r io.Reader) ([]*Build, error) {
+	p := &parser{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		p.lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		p.timed = false
+		if m := timestampRE.FindStringSubmatch(line); m != nil {
+			if t, err := time.Parse(time.RFC3339Nano, m[1]); err == nil {
+				if p.first.IsZero() {
+					p.first = t
+				}
+				p.offset, p.timed = t.Sub(p.first), true
+				line = line[len(m[0]):]
+			}
+		}
+		p.parseLine(line)
+	}
+	p.finish()
+	return p.builds, scanner.Err()
+}
+
+func (p *parser) parseLine(line string) {
+	if p.build != nil {
+		var handled bool
+		switch p.build.Format {
+		case formatDocker:
+			handled = p.docker(line)
+		case formatBuildKit:
+			handled = p.buildKit(line)
+		case formatKaniko:
+			handled = p.kanikoLine(line)
+		}
+		if handled {
+			return
+		}
+		p.finish()
+	}
+	switch {
+	case dockerStepRE.MatchString(line):
+		p.start(formatDocker)
+		p.docker(line)
+	case buildKitRE.MatchString(line):
+		p.start(formatBuildKit)
+		p.buildKit(line)
+	case kanikoRE.MatchString(line):
+		p.start(formatKaniko)
+		p.kanikoLine(line)
+	}
+}
+
+func (p *parser) start(format string) {
+	p.build = &Build{Format: format, Line: p.lineNum}
+	p.builds = append(p.builds, p.build)
+	p.step = nil
+	p.buildKitSteps = map[string]*Step{}
+	p.kanikoCache = map[string]string{}
+	p.kanikoLast = 0
+	p.trailer = 0
+	p.dockerLastStep = 0
+}
+
+// newStep starts a step of the current build.
+func (p *parser) newStep(id, command string) *Step {
+	s := &Step{ID: id, Command: command, Line: p.lineNum}
+	if p.timed && p.build.Format == formatDocker {
+		s.start, s.end = p.offset, p.offset
+	}
+	p.build.Steps = append(p.build.Steps, s)
+	return s
+}
+
+// addLine adds a line to a step of the current build, updating when the step was last written to
+// if the log's lines have times.
+func (p *parser) addLine(s *Step, line string) {
+	s.add(line)
+	if p.timed && p.build.Format == formatDocker {
+		s.end = p.offset
+	}
+}
+
+// finish ends the current build, working out the durations of its steps from when they started.
+func (p *parser) finish() {
+	if p.build == nil {
+		return
+	}
+	// The classic builder only has durations if the log's lines have times.
+	if p.build.Format == formatKaniko || p.build.Format == formatDocker && !p.first.IsZero() {
+		steps := p.build.Steps
+		for i, s := range steps {
+			end := s.end
+			if i+1 < len(steps) {
+				end = steps[i+1].start
+			}
+			if end >= s.start {
+				s.Duration, s.timed = end-s.start, true
+			}
+		}
+	}
+	if p.step != nil && p.build.Failed && p.build.Format != formatBuildKit {
+		p.step.Failed = true
+	}
+	p.build, p.step = nil, nil
+}
+
+// docker handles a line of the classic builder's output, reporting false if it ends the build.
+func (p *parser) docker(line string) bool {
+	if m := dockerStepRE.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n <= p.dockerLastStep {
+			return false
+		}
+		p.dockerLastStep = n
+		p.step = p.newStep(m[1], m[2])
+		return true
+	}
+	if p.step == nil {
+		return false
+	}
+	switch {
+	case line == " ---> Using cache":
+		p.step.Cache = cacheHit
+	case strings.HasPrefix(line, " ---> Running in "):
+		p.step.Cache = cacheMiss
+	case strings.HasPrefix(line, "Successfully built "):
+		p.build.Output = append(p.build.Output, line)
+		p.finish()
+		return true
+	case dockerFailedRE.MatchString(line):
+		p.addLine(p.step, line)
+		p.build.Failed = true
+		p.finish()
+		return true
+	}
+	p.addLine(p.step, line)
+	return true
+}
+
+// buildKit handles a line of BuildKit's plain progress output, reporting false if it ends the
+// build.
+func (p *parser) buildKit(line string) bool {
+	m := buildKitRE.FindStringSubmatch(line)
+	if line == "" {
+		// Steps are separated by blank lines.
+		return true
+	}
+	if m == nil {
+		// Lines without a step number follow a failed build, explaining what failed.
+		if !p.build.Failed || p.trailer >= maxTrailerLines {
+			return false
+		}
+		p.trailer++
+		p.build.Output = append(p.build.Output, line)
+		return true
+	}
+	id, text := m[1], m[2]
+	s, ok := p.buildKitSteps[id]
+	if !ok {
+		if id == "1" && len(p.buildKitSteps) > 0 {
+			// The numbering starts again for the next build.
+			return false
+		}
+		s = p.newStep(id, text)
+		p.buildKitSteps[id] = s
+		if buildKitStageRE.MatchString(text) {
+			s.Cache = cacheMiss
+		}
+		return true
+	}
+	switch {
+	case text == s.Command:
+		// BuildKit repeats a step's name when its output resumes after another step's.
+	case text == "CACHED":
+		s.Cache = cacheHit
+	case buildKitDoneRE.MatchString(text):
+		seconds, _ := strconv.ParseFloat(buildKitDoneRE.FindStringSubmatch(text)[1], 64)
+		s.Duration, s.timed = time.Duration(seconds*float64(time.Second)), true
+	case strings.HasPrefix(text, "ERROR"):
+		p.addLine(s, text)
+		s.Failed = true
+		p.build.Failed = true
+	default:
+		p.addLine(s, text)
+	}
+	return true
+}
+
+// kanikoLine handles a line of kaniko's output, reporting false if it ends the build.
+func (p *parser) kanikoLine(line string) bool {
+	if strings.Contains(line, "error building image") {
+		p.build.Output = append(p.build.Output, line)
+		p.build.Failed = true
+		p.finish()
+		return true
+	}
+	m := kanikoRE.FindStringSubmatch(line)
+	if m == nil {
+		// Commands' output is not prefixed, so only ends the build once all steps are done.
+		if p.step == nil {
+			return false
+		}
+		p.addLine(p.step, line)
+		return true
+	}
+	seconds, _ := strconv.Atoi(m[2])
+	if seconds < p.kanikoLast {
+		return false
+	}
+	p.kanikoLast = seconds
+	offset := time.Duration(seconds) * time.Second
+	text := m[3]
+	switch {
+	case kanikoHitRE.MatchString(text):
+		p.kanikoCache[kanikoHitRE.FindStringSubmatch(text)[1]] = cacheHit
+	case kanikoMissRE.MatchString(text):
+		p.kanikoCache[kanikoMissRE.FindStringSubmatch(text)[1]] = cacheMiss
+	}
+	switch {
+	case kanikoCommandRE.MatchString(text):
+		p.step = p.newStep(strconv.Itoa(len(p.build.Steps)+1), text)
+		p.step.start, p.step.end = offset, offset
+		p.step.Cache = p.kanikoCache[text]
+		return true
+	case kanikoPushRE.MatchString(text):
+		if p.step != nil {
+			p.step.end = offset
+		}
+		p.step = nil
+	}
+	if p.step != nil {
+		p.addLine(p.step, line)
+		p.step.end = offset
+	} else {
+		p.build.Output = append(p.build.Output, line)
+	}
+	return true
+}
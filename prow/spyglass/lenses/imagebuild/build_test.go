@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagebuild
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stepSummary is the parts of a step the tests check.
+type stepSummary struct {
+	ID       string
+	Command  string
+	Cache    string
+	Failed   bool
+	Duration time.Duration
+	Timed    bool
+	Lines    int
+}
+
+func summarize(b *Build) []stepSummary {
+	var steps []stepSummary
+	for _, s := range b.Steps {
+		steps = append(steps, stepSummary{ID: s.ID, Command: s.Command, Cache: s.Cache, Failed: s.Failed, Duration: s.Duration, Timed: s.Timed(), Lines: len(s.Lines)})
+	}
+	return steps
+}
+
+func TestParseBuilds(t *testing.T) {
+	testCases := []struct {
+		name    string
+		log     string
+		formats []string
+		failed  []bool
+		steps   [][]stepSummary
+		output  []int
+	}{
+		{
+			name: "classic docker build with times",
+			log: `+ docker build -t example .
+2019-06-01T10:00:00Z Sending build context to Docker daemon  2.048kB
+2019-06-01T10:00:00Z Step 1/3 : FROM golang:1.12
+2019-06-01T10:00:00Z  ---> 7ced090ee82e
+2019-06-01T10:00:01Z Step 2/3 : COPY . /src
+2019-06-01T10:00:01Z  ---> Using cache
+2019-06-01T10:00:01Z  ---> 3f2e1ad2b1c0
+2019-06-01T10:00:02Z Step 3/3 : RUN make
+2019-06-01T10:00:02Z  ---> Running in 0d5b8c0e2a1f
+2019-06-01T10:00:30Z go build ./...
+2019-06-01T10:00:32.5Z Removing intermediate container 0d5b8c0e2a1f
+2019-06-01T10:00:32.5Z  ---> 9a8b7c6d5e4f
+2019-06-01T10:00:32.5Z Successfully built 9a8b7c6d5e4f
+Successfully tagged example:latest
+`,
+			formats: []string{formatDocker},
+			failed:  []bool{false},
+			steps: [][]stepSummary{{
+				{ID: "1", Command: "FROM golang:1.12", Duration: time.Second, Timed: true, Lines: 1},
+				{ID: "2", Command: "COPY . /src", Cache: cacheHit, Duration: time.Second, Timed: true, Lines: 2},
+				{ID: "3", Command: "RUN make", Cache: cacheMiss, Duration: 30500 * time.Millisecond, Timed: true, Lines: 4},
+			}},
+			output: []int{1},
+		},
+		{
+			name: "failed classic docker builds without times",
+			log: `Step 1/2 : FROM alpine
+ ---> 055936d39205
+Step 2/2 : RUN false
+ ---> Running in 1a2b3c4d5e6f
+The command '/bin/sh -c false' returned a non-zero code: 1
+Step 1/1 : FROM alpine
+ ---> 055936d39205
+Successfully built 055936d39205
+`,
+			formats: []string{formatDocker, formatDocker},
+			failed:  []bool{true, false},
+			steps: [][]stepSummary{{
+				{ID: "1", Command: "FROM alpine", Lines: 1},
+				{ID: "2", Command: "RUN false", Cache: cacheMiss, Failed: true, Lines: 2},
+			}, {
+				{ID: "1", Command: "FROM alpine", Lines: 1},
+			}},
+			output: []int{0, 1},
+		},
+		{
+			name: "BuildKit build with interleaved steps and an error",
+			log: `#1 [internal] load build definition from Dockerfile
+#1 transferring dockerfile: 120B done
+#1 DONE 0.1s
+
+#2 [1/3] FROM docker.io/library/golang:1.12
+#2 DONE 0.0s
+
+#3 [2/3] COPY . /src
+#3 CACHED
+
+#4 [3/3] RUN make test
+#4 0.512 go test ./...
+#2 [1/3] FROM docker.io/library/golang:1.12
+#4 [3/3] RUN make test
+#4 3.001 --- FAIL: TestFoo
+#4 ERROR: executor failed running [/bin/sh -c make test]: exit code: 2
+------
+ > [3/3] RUN make test:
+------
+failed to solve: exit code: 2
++ echo done
+done
+`,
+			formats: []string{formatBuildKit},
+			failed:  []bool{true},
+			steps: [][]stepSummary{{
+				{ID: "1", Command: "[internal] load build definition from Dockerfile", Duration: 100 * time.Millisecond, Timed: true, Lines: 1},
+				{ID: "2", Command: "[1/3] FROM docker.io/library/golang:1.12", Cache: cacheMiss, Timed: true},
+				{ID: "3", Command: "[2/3] COPY . /src", Cache: cacheHit},
+				{ID: "4", Command: "[3/3] RUN make test", Cache: cacheMiss, Failed: true, Lines: 3},
+			}},
+			output: []int{6},
+		},
+		{
+			name: "kaniko build",
+			log: `INFO[0000] Resolved base name golang:1.12 to golang:1.12
+INFO[0001] Checking for cached layer gcr.io/example/cache:abc...
+INFO[0001] Using caching version of cmd: RUN go mod download
+INFO[0002] No cached layer found for cmd RUN make
+INFO[0003] RUN go mod download
+INFO[0003] Found cached layer, extracting to filesystem
+INFO[0010] RUN make
+INFO[0010] cmd: /bin/sh
+go build ./...
+INFO[0042] Taking snapshot of full filesystem...
+INFO[0045] Pushing image to gcr.io/example/app:latest
+INFO[0050] Pushed image to 1 destinations
++ echo pushed
+`,
+			formats: []string{formatKaniko},
+			failed:  []bool{false},
+			steps: [][]stepSummary{{
+				{ID: "1", Command: "RUN go mod download", Cache: cacheHit, Duration: 7 * time.Second, Timed: true, Lines: 1},
+				{ID: "2", Command: "RUN make", Cache: cacheMiss, Duration: 35 * time.Second, Timed: true, Lines: 3},
+			}},
+			output: []int{6},
+		},
+		{
+			name: "failed kaniko build",
+			log: `INFO[0000] Resolved base name alpine to alpine
+INFO[0001] RUN false
+INFO[0001] cmd: /bin/sh
+error building image: error building stage: failed to execute command: waiting for process to exit: exit status 1
+`,
+			formats: []string{formatKaniko},
+			failed:  []bool{true},
+			steps: [][]stepSummary{{
+				{ID: "1", Command: "RUN false", Failed: true, Timed: true, Lines: 1},
+			}},
+			output: []int{2},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			builds, err := parseBuilds(strings.NewReader(tc.log))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(builds) != len(tc.formats) {
+				t.Fatalf("expected %d builds, got %d", len(tc.formats), len(builds))
+			}
+			for i, b := range builds {
+				if b.Format != tc.formats[i] {
+					t.Errorf("build %d: expected format %s, got %s", i, tc.formats[i], b.Format)
+				}
+				if b.Failed != tc.failed[i] {
+					t.Errorf("build %d: expected failed to be %v", i, tc.failed[i])
+				}
+				if steps := summarize(b); !reflect.DeepEqual(steps, tc.steps[i]) {
+					t.Errorf("build %d: expected steps %+v, got %+v", i, tc.steps[i], steps)
+				}
+				if len(b.Output) != tc.output[i] {
+					t.Errorf("build %d: expected %d lines of other output, got %d: %q", i, tc.output[i], len(b.Output), b.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBuildsTruncatesSteps(t *testing.T) {
+	log := "Step 1/1 : RUN yes\n" + strings.Repeat("y\n", maxStepLines+10)
+	builds, err := parseBuilds(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := builds[0].Steps[0]
+	if len(s.Lines) != maxStepLines || s.Truncated != 10 {
+		t.Errorf("expected %d lines and 10 truncated, got %d and %d", maxStepLines, len(s.Lines), s.Truncated)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagebuild provides a Spyglass lens that splits container image build logs, from docker,
+// BuildKit and kaniko, into their steps.
+package imagebuild
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "imagebuild"
+	title    = "Image Build"
+	priority = 40
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of an image build log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each log is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// StepView is a step with its duration formatted for display.
+type StepView struct {
+	*Step
+	Time string
+}
+
+// BuildView is a build with its steps' total duration formatted for display.
+type BuildView struct {
+	*Build
+	StepViews []StepView
+	Time      string
+}
+
+// LogView is the builds in one log.
+type LogView struct {
+	Name   string
+	Link   string
+	Error  string
+	Builds []BuildView
+}
+
+type imageBuildView struct {
+	Errors []string
+	Logs   []LogView
+}
+
+// Body renders the steps of each build, with the steps that succeeded collapsed.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := imageBuildView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		lv := LogView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		var builds []*Build
+		if err == nil {
+			builds, err = parseBuilds(r)
+		}
+		if err != nil {
+			lv.Error = err.Error()
+		}
+		if len(builds) == 0 && lv.Error == "" {
+			continue
+		}
+		for _, b := range builds {
+			bv := BuildView{Build: b}
+			for _, s := range b.Steps {
+				sv := StepView{Step: s}
+				if s.Timed() {
+					sv.Time = formatDuration(s.Duration)
+				}
+				bv.StepViews = append(bv.StepViews, sv)
+			}
+			if d := b.Duration(); d > 0 {
+				bv.Time = formatDuration(d)
+			}
+			lv.Builds = append(lv.Builds, bv)
+		}
+		view.Logs = append(view.Logs, lv)
+	}
+	if len(view.Logs) == 0 {
+		view.Errors = append(view.Errors, "No image builds found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// formatDuration rounds a duration to a tenth of a second.
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t := template.New("template.html").Funcs(template.FuncMap{
+		"ansi": lenses.ANSIToHTML,
+	})
+	t, err := t.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,43 @@
+async function loadThumbnail(figure: HTMLElement): Promise<void> {
+  const container = figure.querySelector<HTMLDivElement>('.thumbnail-image')!;
+  const uri = await spyglass.request(JSON.stringify({artifact: figure.dataset.artifact}));
+  if (!uri.startsWith('data:image/')) {
+    container.querySelector('.thumbnail-message')!.textContent = 'Preview unavailable';
+    return;
+  }
+  const img = document.createElement('img');
+  img.src = uri;
+  img.alt = figure.dataset.artifact!;
+  container.innerHTML = '';
+  container.appendChild(img);
+  container.addEventListener('click', () => showLightbox(uri, figure.dataset.artifact!));
+}
+
+function showLightbox(uri: string, caption: string): void {
+  const lightbox = document.getElementById('lightbox')!;
+  document.querySelector<HTMLImageElement>('#lightbox-image')!.src = uri;
+  document.getElementById('lightbox-caption')!.textContent = caption;
+  lightbox.classList.remove('hidden');
+  // The lightbox needs room to show a useful image.
+  document.body.style.minHeight = `${Math.max(window.innerHeight, 600)}px`;
+  spyglass.contentUpdated();
+}
+
+function hideLightbox(): void {
+  document.getElementById('lightbox')!.classList.add('hidden');
+  document.body.style.minHeight = '';
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  const lightbox = document.getElementById('lightbox');
+  if (lightbox) {
+    lightbox.addEventListener('click', hideLightbox);
+  }
+  const figures = Array.from(document.querySelectorAll<HTMLElement>('figure.thumbnail'));
+  for (const figure of figures) {
+    if (!figure.dataset.tooLarge) {
+      loadThumbnail(figure).then(() => spyglass.contentUpdated());
+    }
+  }
+});
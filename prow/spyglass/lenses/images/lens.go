@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package images provides a Spyglass lens that renders image artifacts as a gallery.
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "images"
+	title    = "Images"
+	priority = 11
+
+	// maxImageSize is the largest image the lens will inline. Larger images are only linked.
+	maxImageSize = 10 * 1024 * 1024
+)
+
+// allowedTypes are the content types we are willing to hand to the browser as images.
+// Notably, this excludes SVG, which can contain script.
+var allowedTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// Lens is the implementation of an image gallery Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Image is a single entry in the gallery.
+type Image struct {
+	Name    string
+	Path    string
+	Link    string
+	Size    int64
+	TooLong bool
+}
+
+// Body renders a placeholder for each image. The images themselves are fetched lazily through Callback.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var images []Image
+	for _, a := range artifacts {
+		img := Image{
+			Name: path.Base(a.JobPath()),
+			Path: a.JobPath(),
+			Link: a.CanonicalLink(),
+		}
+		size, err := a.Size()
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Failed to get image size.")
+			continue
+		}
+		img.Size = size
+		img.TooLong = size > maxImageSize
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Path < images[j].Path })
+	return executeTemplate(resourceDir, "body", images)
+}
+
+// request is sent by the frontend to retrieve a single image.
+type request struct {
+	Artifact string `json:"artifact"`
+}
+
+// Callback returns the requested image as a data: URI, or an empty string if it can't be displayed.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var req request
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		logrus.WithError(err).Info("Failed to parse images lens request.")
+		return ""
+	}
+	for _, a := range artifacts {
+		if a.JobPath() != req.Artifact {
+			continue
+		}
+		uri, err := dataURI(a)
+		if err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Failed to load image.")
+			return ""
+		}
+		return uri
+	}
+	return ""
+}
+
+func dataURI(a lenses.Artifact) (string, error) {
+	size, err := a.Size()
+	if err != nil {
+		return "", fmt.Errorf("failed to get size: %v", err)
+	}
+	if size > maxImageSize {
+		return "", lenses.ErrFileTooLarge
+	}
+	content, err := a.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to read: %v", err)
+	}
+	// Never trust the file extension: the artifact could be anything.
+	contentType := http.DetectContentType(content)
+	if !allowedTypes[contentType] {
+		return "", fmt.Errorf("refusing to serve content of type %q as an image", contentType)
+	}
+	var buf strings.Builder
+	buf.WriteString("data:" + contentType + ";base64,")
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+	enc.Write(content)
+	enc.Close()
+	return buf.String(), nil
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
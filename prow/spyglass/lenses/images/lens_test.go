@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCallback(t *testing.T) {
+	pngData := testPNG(t)
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/screenshot.png", Content: pngData},
+		&fakeartifact.Artifact{Path: "artifacts/evil.png", Content: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)},
+		&fakeartifact.Artifact{Path: "artifacts/huge.png", Content: append(pngData, make([]byte, maxImageSize)...)},
+	}
+	testCases := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			name:     "png is returned as a data URI",
+			request:  `{"artifact": "artifacts/screenshot.png"}`,
+			expected: "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData),
+		},
+		{
+			name:    "content is sniffed rather than trusting the extension",
+			request: `{"artifact": "artifacts/evil.png"}`,
+		},
+		{
+			name:    "images over the size limit are not returned",
+			request: `{"artifact": "artifacts/huge.png"}`,
+		},
+		{
+			name:    "unknown artifacts are not returned",
+			request: `{"artifact": "artifacts/missing.png"}`,
+		},
+		{
+			name:    "malformed requests are ignored",
+			request: `not json`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := (Lens{}).Callback(artifacts, "", tc.request, nil); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/b.png", Content: testPNG(t)},
+		&fakeartifact.Artifact{Path: "artifacts/a.png", Content: testPNG(t)},
+	}
+	body := (Lens{}).Body(artifacts, ".", "", nil)
+	a, b := strings.Index(body, "artifacts/a.png"), strings.Index(body, "artifacts/b.png")
+	if a == -1 || b == -1 {
+		t.Fatalf("expected both images in the gallery, got %s", body)
+	}
+	if a > b {
+		t.Errorf("expected images to be sorted by path")
+	}
+}
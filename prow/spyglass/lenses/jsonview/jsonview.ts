@@ -0,0 +1,53 @@
+function artifactFor(el: Element): string {
+  return el.closest<HTMLElement>('.json-file')!.dataset.artifact!;
+}
+
+async function fetchChildren(el: HTMLElement, offset: number): Promise<string> {
+  return spyglass.request(JSON.stringify({
+    artifact: artifactFor(el),
+    offset,
+    path: JSON.parse(el.dataset.path!),
+  }));
+}
+
+async function expandLazy(details: HTMLDetailsElement): Promise<void> {
+  const path = details.dataset.path;
+  if (!path) {
+    return;
+  }
+  // Only fetch once.
+  delete details.dataset.path;
+  const children = details.querySelector<HTMLDivElement>('.json-children')!;
+  children.innerHTML = await fetchChildren(details, 0);
+  bind(children);
+  spyglass.contentUpdated();
+}
+
+async function showMore(button: HTMLButtonElement): Promise<void> {
+  button.disabled = true;
+  const html = await fetchChildren(button, Number(button.dataset.offset));
+  const template = document.createElement('template');
+  template.innerHTML = html;
+  const parent = button.parentElement!;
+  bind(template.content);
+  parent.replaceChild(template.content, button);
+  spyglass.contentUpdated();
+}
+
+function bind(root: ParentNode): void {
+  for (const details of Array.from(root.querySelectorAll<HTMLDetailsElement>('details[data-path]'))) {
+    details.addEventListener('toggle', () => {
+      if (details.open) {
+        expandLazy(details);
+      }
+    });
+  }
+  for (const button of Array.from(root.querySelectorAll<HTMLButtonElement>('button.json-more'))) {
+    button.addEventListener('click', () => showMore(button));
+  }
+  for (const details of Array.from(root.querySelectorAll<HTMLDetailsElement>('details'))) {
+    details.addEventListener('toggle', () => spyglass.contentUpdated());
+  }
+}
+
+window.addEventListener('DOMContentLoaded', () => bind(document));
@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonview provides a Spyglass lens that renders JSON artifacts as collapsible trees.
+package jsonview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "jsonview"
+	title    = "JSON"
+	priority = 13
+
+	// maxRenderNodes bounds the number of nodes rendered at once. Containers beyond the budget
+	// are left for the user to expand, which fetches them through Callback.
+	maxRenderNodes = 2000
+	// maxChildren is the number of children of a single container rendered at once.
+	maxChildren = 200
+	// openDepth is the depth to which containers are initially expanded.
+	openDepth = 2
+)
+
+// Lens is the implementation of a JSON-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Node is a rendered JSON value.
+type Node struct {
+	Key string
+	// Index is set instead of Key for array elements.
+	Index *int
+	Kind  string
+	Value string
+	// Path is the JSON-encoded path to this node, set for containers that must be fetched lazily.
+	Path     string
+	Lazy     bool
+	Open     bool
+	Size     int
+	Children []*Node
+	// More is the number of children not rendered, which can be fetched starting at Offset.
+	More     int
+	Offset   int
+	MorePath string
+}
+
+// File is a single rendered JSON artifact.
+type File struct {
+	Artifact string
+	Link     string
+	Root     *Node
+	Error    string
+}
+
+// Body renders a tree for each JSON artifact.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var files []File
+	for _, a := range artifacts {
+		f := File{Artifact: a.JobPath(), Link: a.CanonicalLink()}
+		v, err := readValue(a)
+		if err != nil {
+			f.Error = err.Error()
+		} else {
+			budget := maxRenderNodes / len(artifacts)
+			f.Root = render(v, nil, 0, &budget, 0)
+		}
+		files = append(files, f)
+	}
+	return executeTemplate(resourceDir, "body", files)
+}
+
+// request is sent by the frontend to expand part of a tree.
+type request struct {
+	Artifact string        `json:"artifact"`
+	Path     []interface{} `json:"path"`
+	Offset   int           `json:"offset"`
+}
+
+// Callback renders the children of the container at the requested path, starting at the requested offset.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var req request
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		return executeTemplate(resourceDir, "error", fmt.Sprintf("failed to parse request: %v", err))
+	}
+	for _, a := range artifacts {
+		if a.JobPath() != req.Artifact {
+			continue
+		}
+		v, err := readValue(a)
+		if err != nil {
+			return executeTemplate(resourceDir, "error", err.Error())
+		}
+		target, err := v.lookup(req.Path)
+		if err != nil {
+			return executeTemplate(resourceDir, "error", err.Error())
+		}
+		budget := maxRenderNodes
+		return executeTemplate(resourceDir, "children", render(target, req.Path, req.Offset, &budget, 0))
+	}
+	return executeTemplate(resourceDir, "error", fmt.Sprintf("no artifact named %q", req.Artifact))
+}
+
+func readValue(a lenses.Artifact) (*value, error) {
+	content, err := a.ReadAll()
+	if err == lenses.ErrFileTooLarge {
+		return nil, fmt.Errorf("%s is too large to display", a.JobPath())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", a.JobPath(), err)
+	}
+	v, err := parseValue(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", a.JobPath(), err)
+	}
+	return v, nil
+}
+
+// render converts v to a Node, rendering children from offset onwards until the budget runs out.
+func render(v *value, path []interface{}, offset int, budget *int, depth int) *Node {
+	*budget--
+	n := &Node{Kind: v.kind, Value: v.scalar, Size: len(v.items), Open: depth < openDepth}
+	if v.kind != kindObject && v.kind != kindArray {
+		return n
+	}
+	if *budget <= 0 {
+		n.Lazy = true
+		n.Open = false
+		n.Path = encodePath(path)
+		return n
+	}
+	end := offset + maxChildren
+	if end > len(v.items) {
+		end = len(v.items)
+	}
+	for i := offset; i < end; i++ {
+		var child *Node
+		if v.kind == kindObject {
+			child = render(v.items[i], appendPath(path, v.keys[i]), 0, budget, depth+1)
+			child.Key = v.keys[i]
+		} else {
+			child = render(v.items[i], appendPath(path, i), 0, budget, depth+1)
+			index := i
+			child.Index = &index
+		}
+		n.Children = append(n.Children, child)
+	}
+	if end < len(v.items) {
+		n.More = len(v.items) - end
+		n.Offset = end
+		n.MorePath = encodePath(path)
+	}
+	return n
+}
+
+// appendPath copies path, so siblings don't share a backing array.
+func appendPath(path []interface{}, element interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, element)
+}
+
+func encodePath(path []interface{}) string {
+	if path == nil {
+		path = []interface{}{}
+	}
+	b, err := json.Marshal(path)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode JSON path.")
+	}
+	return string(b)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t := template.New("template.html").Funcs(template.FuncMap{
+		"plural": func(n int, singular string) string {
+			if n == 1 {
+				return "1 " + singular
+			}
+			return strconv.Itoa(n) + " " + singular + "s"
+		},
+	})
+	t, err := t.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonview
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseValuePreservesOrder(t *testing.T) {
+	v, err := parseValue([]byte(`{"zebra": 1, "apple": [true, null, "x"], "mango": {"n": 1.5e3}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(v.keys, []string{"zebra", "apple", "mango"}) {
+		t.Errorf("expected keys in document order, got %v", v.keys)
+	}
+	var scalars []string
+	for _, item := range v.items[1].items {
+		scalars = append(scalars, item.scalar)
+	}
+	if !reflect.DeepEqual(scalars, []string{"true", "null", `"x"`}) {
+		t.Errorf("unexpected array contents %v", scalars)
+	}
+	if n := v.items[2].items[0].scalar; n != "1.5e3" {
+		t.Errorf("expected numbers to be preserved verbatim, got %s", n)
+	}
+}
+
+func TestParseValueErrors(t *testing.T) {
+	for _, data := range []string{`{"a": `, `[1, 2] [3]`, ``, `{"a" 1}`} {
+		if _, err := parseValue([]byte(data)); err == nil {
+			t.Errorf("expected an error parsing %q", data)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	v, err := parseValue([]byte(`{"items": [{"name": "a"}, {"name": "b"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		path        []interface{}
+		expected    string
+		expectedErr bool
+	}{
+		{path: []interface{}{"items", float64(1), "name"}, expected: `"b"`},
+		{path: []interface{}{"items", float64(2)}, expectedErr: true},
+		{path: []interface{}{"items", "name"}, expectedErr: true},
+		{path: []interface{}{"missing"}, expectedErr: true},
+		{path: []interface{}{"items", float64(0), "name", "deeper"}, expectedErr: true},
+	}
+	for _, tc := range testCases {
+		target, err := v.lookup(tc.path)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if target.scalar != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.path, tc.expected, target.scalar)
+		}
+	}
+}
+
+func TestRenderBudget(t *testing.T) {
+	v, err := parseValue([]byte(`{"a": {"b": [1, 2, 3]}, "c": {"d": 4}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	budget := 3
+	root := render(v, nil, 0, &budget, 0)
+	a := root.Children[0]
+	if a.Lazy || len(a.Children) != 1 {
+		t.Fatalf("expected a to be rendered, got %#v", a)
+	}
+	if b := a.Children[0]; !b.Lazy || b.Path != `["a","b"]` {
+		t.Errorf("expected b to be lazy with path [\"a\",\"b\"], got %#v", b)
+	}
+	if c := root.Children[1]; !c.Lazy || c.Path != `["c"]` {
+		t.Errorf("expected c to be lazy with path [\"c\"], got %#v", c)
+	}
+}
+
+func TestRenderMore(t *testing.T) {
+	items := make([]*value, maxChildren+5)
+	for i := range items {
+		items[i] = &value{kind: kindNumber, scalar: "0"}
+	}
+	budget := maxRenderNodes
+	n := render(&value{kind: kindArray, items: items}, []interface{}{"list"}, 0, &budget, 0)
+	if len(n.Children) != maxChildren || n.More != 5 || n.Offset != maxChildren || n.MorePath != `["list"]` {
+		t.Errorf("expected %d children and 5 more at offset %d, got %d, %d more at %d (%s)", maxChildren, maxChildren, len(n.Children), n.More, n.Offset, n.MorePath)
+	}
+	if *n.Children[3].Index != 3 {
+		t.Errorf("expected child 3 to have index 3, got %d", *n.Children[3].Index)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kinds of JSON value, which are also used as CSS classes.
+const (
+	kindObject = "object"
+	kindArray  = "array"
+	kindString = "string"
+	kindNumber = "number"
+	kindBool   = "bool"
+	kindNull   = "null"
+)
+
+// value is a parsed JSON value. Unlike the result of json.Unmarshal into an interface{},
+// it preserves the order of object keys, which is usually meaningful to whoever wrote the file.
+type value struct {
+	kind string
+	// scalar holds the JSON encoding of strings, numbers, bools and null.
+	scalar string
+	// keys holds the keys of an object, in the same order as items.
+	keys  []string
+	items []*value
+}
+
+// parseValue parses a single JSON document.
+func parseValue(data []byte) (*value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (*value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			v := &value{kind: kindObject}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected object key, got %v", keyTok)
+				}
+				item, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				v.keys = append(v.keys, key)
+				v.items = append(v.items, item)
+			}
+			_, err := dec.Token()
+			return v, err
+		case '[':
+			v := &value{kind: kindArray}
+			for dec.More() {
+				item, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				v.items = append(v.items, item)
+			}
+			_, err := dec.Token()
+			return v, err
+		}
+		return nil, fmt.Errorf("unexpected delimiter %v", t)
+	case string:
+		// Escaping is left to the template, so that strings display as they were written.
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(t); err != nil {
+			return nil, err
+		}
+		return &value{kind: kindString, scalar: strings.TrimSuffix(buf.String(), "\n")}, nil
+	case json.Number:
+		return &value{kind: kindNumber, scalar: t.String()}, nil
+	case bool:
+		return &value{kind: kindBool, scalar: fmt.Sprintf("%t", t)}, nil
+	case nil:
+		return &value{kind: kindNull, scalar: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+// lookup follows a path of object keys (strings) and array indices (numbers) from v.
+func (v *value) lookup(path []interface{}) (*value, error) {
+	for i, p := range path {
+		switch v.kind {
+		case kindObject:
+			key, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("element %d of path: expected object key, got %v", i, p)
+			}
+			found := false
+			for j, k := range v.keys {
+				if k == key {
+					v = v.items[j]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("element %d of path: no key %q", i, key)
+			}
+		case kindArray:
+			index, ok := p.(float64)
+			if !ok || index < 0 || int(index) >= len(v.items) || float64(int(index)) != index {
+				return nil, fmt.Errorf("element %d of path: invalid index %v", i, p)
+			}
+			v = v.items[int(index)]
+		default:
+			return nil, fmt.Errorf("element %d of path: cannot descend into %s", i, v.kind)
+		}
+	}
+	return v, nil
+}
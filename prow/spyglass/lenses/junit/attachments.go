@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+// attachmentRE matches the references to attachments that the Jenkins JUnit Attachments plugin
+// reads from a test's output, such as "[[ATTACHMENT|/workspace/_artifacts/screenshot.png]]".
+var attachmentRE = regexp.MustCompile(`\[\[ATTACHMENT\|([^\]]+)\]\]`)
+
+// Attachment is a file a test referred to from its output or properties.
+type Attachment struct {
+	// Path is the path the test gave, usually on the machine that ran it.
+	Path string
+	// Link is where the artifact the attachment was uploaded as can be viewed, if it was found.
+	Link string
+}
+
+// Name returns the attachment's file name.
+func (a Attachment) Name() string {
+	return path.Base(a.Path)
+}
+
+// attachmentPaths returns the paths of the attachments a test refers to, in the order they are
+// referred to.
+func attachmentPaths(test junit.Result) []string {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		p = strings.TrimSpace(p)
+		if p != "" && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, s := range []*string{test.Output, test.Error, test.Failure} {
+		if s == nil {
+			continue
+		}
+		for _, m := range attachmentRE.FindAllStringSubmatch(*s, -1) {
+			add(m[1])
+		}
+	}
+	if test.Properties != nil {
+		for _, p := range test.Properties.PropertyList {
+			if strings.HasPrefix(p.Name, "attachment") {
+				add(p.Value)
+			}
+		}
+	}
+	return paths
+}
+
+// attachmentIndex finds the artifacts that attachments were uploaded as.
+type attachmentIndex struct {
+	// bySuffix has each artifact by the last one or more elements of its path.
+	bySuffix map[string]lenses.Artifact
+	// conflicts are the suffixes of more than one artifact.
+	conflicts map[string]bool
+}
+
+func newAttachmentIndex(artifacts []lenses.Artifact) *attachmentIndex {
+	idx := &attachmentIndex{bySuffix: map[string]lenses.Artifact{}, conflicts: map[string]bool{}}
+	for _, a := range artifacts {
+		parts := strings.Split(a.JobPath(), "/")
+		for i := range parts {
+			suffix := strings.Join(parts[i:], "/")
+			if _, ok := idx.bySuffix[suffix]; ok {
+				idx.conflicts[suffix] = true
+			}
+			idx.bySuffix[suffix] = a
+		}
+	}
+	return idx
+}
+
+// find returns the artifact an attachment of a junit file was uploaded as. Relative paths are
+// relative to the junit file. Otherwise the artifact whose path has the longest unambiguous
+// suffix in common with the attachment's is used, as attachments are referred to by where they
+// were written on the machine that ran the tests.
+func (idx *attachmentIndex) find(junitPath, p string) lenses.Artifact {
+	if !path.IsAbs(p) {
+		if a, ok := idx.bySuffix[path.Join(path.Dir(junitPath), p)]; ok {
+			return a
+		}
+	}
+	parts := strings.Split(strings.Trim(path.Clean(p), "/"), "/")
+	minimum := 2
+	if len(parts) < minimum {
+		minimum = len(parts)
+	}
+	for i := 0; i <= len(parts)-minimum; i++ {
+		suffix := strings.Join(parts[i:], "/")
+		if a, ok := idx.bySuffix[suffix]; ok && !idx.conflicts[suffix] {
+			return a
+		}
+	}
+	return nil
+}
+
+// attachments returns the attachments a test of a junit file refers to.
+func (idx *attachmentIndex) attachments(junitPath string, test junit.Result) []Attachment {
+	var attachments []Attachment
+	for _, p := range attachmentPaths(test) {
+		attachment := Attachment{Path: p}
+		if a := idx.find(junitPath, p); a != nil {
+			attachment.Link = a.CanonicalLink()
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
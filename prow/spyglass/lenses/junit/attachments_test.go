@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+func TestAttachments(t *testing.T) {
+	out := "took a screenshot\n[[ATTACHMENT|/workspace/_artifacts/shots/login/failed.png]]\n[[ATTACHMENT|trace.zip]]"
+	errOut := "[[ATTACHMENT|/tmp/missing.log]] [[ATTACHMENT|trace.zip]]"
+	test := junit.Result{
+		Output: &out,
+		Error:  &errOut,
+		Properties: &junit.Properties{PropertyList: []junit.Property{
+			{Name: "attachment.video", Value: "/workspace/_artifacts/videos/login.mp4"},
+			{Name: "browser", Value: "chrome"},
+		}},
+	}
+	var artifacts []lenses.Artifact
+	for _, p := range []string{
+		"artifacts/junit_01.xml",
+		"artifacts/trace.zip",
+		"artifacts/e2e/trace.zip",
+		"artifacts/shots/login/failed.png",
+		"artifacts/shots/logout/failed.png",
+		"artifacts/videos/login.mp4",
+	} {
+		artifacts = append(artifacts, &fakeartifact.Artifact{Path: p})
+	}
+	idx := newAttachmentIndex(artifacts)
+	expected := []Attachment{
+		{Path: "/workspace/_artifacts/shots/login/failed.png", Link: "https://example.com/artifacts/shots/login/failed.png"},
+		{Path: "trace.zip", Link: "https://example.com/artifacts/trace.zip"},
+		{Path: "/tmp/missing.log"},
+		{Path: "/workspace/_artifacts/videos/login.mp4", Link: "https://example.com/artifacts/videos/login.mp4"},
+	}
+	if actual := idx.attachments("artifacts/junit_01.xml", test); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected attachments %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+// The statuses of a test in one of two compared runs, which are also used as CSS classes.
+const (
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+	statusMissing = "missing"
+)
+
+// RunResult is the result of a test in one of two compared runs.
+type RunResult struct {
+	Status   string
+	Duration time.Duration
+	Failure  string
+}
+
+// ComparedTest is a test of two compared runs, aligned by its class and name.
+type ComparedTest struct {
+	Name    string
+	Current RunResult
+	Other   RunResult
+}
+
+// Changed returns whether the test has a different status in the two runs.
+func (t ComparedTest) Changed() bool {
+	return t.Current.Status != t.Other.Status
+}
+
+type compareView struct {
+	OtherLink string
+	// Changed are the tests whose status differs between the runs, those failing in the run
+	// being viewed first.
+	Changed []ComparedTest
+	// Failing are the tests that failed in both runs.
+	Failing []ComparedTest
+	// Unchanged are the other tests, which passed or were skipped in both runs.
+	Unchanged []ComparedTest
+}
+
+// Compare renders the tests of two runs side by side in a table aligned by test, with the tests
+// whose status changed between the runs first.
+func (lens Lens) Compare(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	view := compareRuns(artifacts)
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "compare", view); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// compareRuns aligns the tests of the run being viewed with those of the run it is compared to.
+func compareRuns(artifacts []lenses.Artifact) compareView {
+	artifacts, _ = lenses.SplitSiblings(artifacts)
+	current, baseline := lenses.SplitBaseline(artifacts)
+	var other []lenses.Artifact
+	view := compareView{}
+	for _, b := range baseline {
+		other = append(other, b)
+		view.OtherLink = "/view/" + b.Source
+	}
+
+	tests := map[string]*ComparedTest{}
+	var names []string
+	record := func(results []testResults, set func(*ComparedTest, RunResult)) {
+		for _, result := range results {
+			for _, test := range result.junit {
+				key := test.ClassName + "/" + test.Name
+				if tests[key] == nil {
+					tests[key] = &ComparedTest{Name: test.Name, Current: RunResult{Status: statusMissing}, Other: RunResult{Status: statusMissing}}
+					names = append(names, key)
+				}
+				set(tests[key], runResult(test))
+			}
+		}
+	}
+	record(readResults(current), func(t *ComparedTest, r RunResult) { t.Current = r })
+	record(readResults(other), func(t *ComparedTest, r RunResult) { t.Other = r })
+
+	sort.Strings(names)
+	for _, key := range names {
+		test := *tests[key]
+		switch {
+		case test.Changed():
+			view.Changed = append(view.Changed, test)
+		case test.Current.Status == statusFailed:
+			view.Failing = append(view.Failing, test)
+		default:
+			view.Unchanged = append(view.Unchanged, test)
+		}
+	}
+	sort.SliceStable(view.Changed, func(i, j int) bool {
+		return view.Changed[i].Current.Status == statusFailed && view.Changed[j].Current.Status != statusFailed
+	})
+	return view
+}
+
+func runResult(test junit.Result) RunResult {
+	result := RunResult{Status: statusPassed, Duration: JunitResult{test}.Duration()}
+	switch {
+	case test.Failure != nil:
+		result.Status = statusFailed
+		result.Failure = *test.Failure
+	case test.Skipped != nil:
+		result.Status = statusSkipped
+	}
+	return result
+}
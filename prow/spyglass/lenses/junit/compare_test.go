@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func TestCompareRuns(t *testing.T) {
+	current := `<testsuite>
+  <testcase name="TestFixed" classname="pkg" time="1"/>
+  <testcase name="TestBroken" classname="pkg" time="2"><failure>expected 3, got 2</failure></testcase>
+  <testcase name="TestFlaky" classname="pkg"><failure>timed out</failure></testcase>
+  <testcase name="TestStable" classname="pkg"/>
+  <testcase name="TestNew" classname="pkg"/>
+</testsuite>`
+	other := `<testsuite>
+  <testcase name="TestFixed" classname="pkg"><failure>boom</failure></testcase>
+  <testcase name="TestBroken" classname="pkg"/>
+  <testcase name="TestFlaky" classname="pkg"><failure>timed out</failure></testcase>
+  <testcase name="TestStable" classname="pkg"/>
+  <testcase name="TestStable" classname="other"><skipped/></testcase>
+</testsuite>`
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/junit_01.xml", Content: []byte(current)},
+		lenses.NewBaselineArtifact(&fakeartifact.Artifact{Path: "artifacts/junit_01.xml", Content: []byte(other)}, "gcs/bucket/logs/job/1"),
+	}
+
+	view := compareRuns(artifacts)
+	if view.OtherLink != "/view/gcs/bucket/logs/job/1" {
+		t.Errorf("expected a link to the compared run, got %q", view.OtherLink)
+	}
+	summarize := func(tests []ComparedTest) string {
+		var summary []string
+		for _, test := range tests {
+			summary = append(summary, test.Name+":"+test.Current.Status+"/"+test.Other.Status)
+		}
+		return strings.Join(summary, " ")
+	}
+	if expected, actual := "TestBroken:failed/passed TestStable:missing/skipped TestFixed:passed/failed TestNew:passed/missing", summarize(view.Changed); actual != expected {
+		t.Errorf("expected changed tests %q, got %q", expected, actual)
+	}
+	if expected, actual := "TestFlaky:failed/failed", summarize(view.Failing); actual != expected {
+		t.Errorf("expected failing tests %q, got %q", expected, actual)
+	}
+	if expected, actual := "TestStable:passed/passed", summarize(view.Unchanged); actual != expected {
+		t.Errorf("expected unchanged tests %q, got %q", expected, actual)
+	}
+	if failure := view.Changed[0].Current.Failure; failure != "expected 3, got 2" {
+		t.Errorf("expected TestBroken's failure, got %q", failure)
+	}
+}
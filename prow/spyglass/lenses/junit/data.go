@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// TestData is a test in the data the lens serves as JSON.
+type TestData struct {
+	Name      string  `json:"name"`
+	ClassName string  `json:"class_name,omitempty"`
+	Seconds   float64 `json:"seconds"`
+	// Report is the path of the JUnit report the test is in.
+	Report  string `json:"report"`
+	Failure string `json:"failure,omitempty"`
+	// LogLine is the line of the build log the failure message was logged on, if it was found.
+	LogLine int64 `json:"log_line,omitempty"`
+}
+
+// ResultsData is the data the lens serves as JSON: the tests in the job's JUnit reports.
+type ResultsData struct {
+	Passed  []TestData `json:"passed"`
+	Failed  []TestData `json:"failed"`
+	Skipped []TestData `json:"skipped"`
+	// Errors describes the reports that could not be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Data returns the tests in the JUnit reports, sorted into those that passed, failed and were
+// skipped as they are shown.
+func (lens Lens) Data(artifacts []lenses.Artifact, config json.RawMessage) (interface{}, error) {
+	artifacts, siblings := lenses.SplitSiblings(artifacts)
+	data := ResultsData{Passed: []TestData{}, Failed: []TestData{}, Skipped: []TestData{}}
+	var messages []string
+	for _, result := range readResults(artifacts) {
+		if result.err != nil {
+			data.Errors = append(data.Errors, fmt.Sprintf("Failed to read %s: %v", result.path, result.err))
+			continue
+		}
+		for _, test := range result.junit {
+			td := TestData{Name: test.Name, ClassName: test.ClassName, Seconds: test.Time, Report: result.path}
+			switch {
+			case test.Failure != nil:
+				td.Failure = *test.Failure
+				messages = append(messages, td.Failure)
+				data.Failed = append(data.Failed, td)
+			case test.Skipped != nil:
+				data.Skipped = append(data.Skipped, td)
+			default:
+				data.Passed = append(data.Passed, td)
+			}
+		}
+	}
+	found := findInLog(buildLog(siblings), messages)
+	for i, test := range data.Failed {
+		if location, ok := found[test.Failure]; ok {
+			data.Failed[i].LogLine = location.Line
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func TestResultsData(t *testing.T) {
+	report := `<testsuite>
+  <testcase name="TestA" classname="pkg" time="1.5"><failure>a_test.go:12: expected 3 widgets, got 2</failure></testcase>
+  <testcase name="TestB" classname="pkg" time="0.5"/>
+  <testcase name="TestC" classname="pkg"><skipped/></testcase>
+</testsuite>`
+	log := "=== RUN   TestA\n    a_test.go:12: expected 3 widgets, got 2\n--- FAIL: TestA (1.50s)\n"
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/junit_01.xml", Content: []byte(report)},
+		&fakeartifact.Artifact{Path: "artifacts/junit_02.xml", Content: []byte("<testsuite")},
+		lenses.NewSiblingArtifact(&fakeartifact.Artifact{Path: lenses.BuildLogPath, Content: []byte(log)}),
+	}
+
+	data, err := Lens{}.Data(artifacts, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results := data.(ResultsData)
+	if len(results.Passed) != 1 || results.Passed[0].Name != "TestB" || results.Passed[0].Seconds != 0.5 {
+		t.Errorf("expected TestB to pass, got %+v", results.Passed)
+	}
+	if len(results.Skipped) != 1 || results.Skipped[0].Name != "TestC" {
+		t.Errorf("expected TestC to be skipped, got %+v", results.Skipped)
+	}
+	if len(results.Failed) != 1 {
+		t.Fatalf("expected TestA to fail, got %+v", results.Failed)
+	}
+	failed := results.Failed[0]
+	if failed.Name != "TestA" || failed.Report != "artifacts/junit_01.xml" || failed.LogLine != 2 {
+		t.Errorf("expected TestA's failure to be found on line 2 of the build log, got %+v", failed)
+	}
+	if len(results.Errors) != 1 {
+		t.Errorf("expected an error for the malformed report, got %q", results.Errors)
+	}
+}
@@ -19,6 +19,7 @@ package junit
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"path/filepath"
@@ -50,11 +51,12 @@ func (lens Lens) Config() lenses.LensConfig {
 		Name:     name,
 		Title:    title,
 		Priority: priority,
+		Siblings: true,
 	}
 }
 
 // Header renders the content of <head> from template.html.
-func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
 	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
 	if err != nil {
 		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
@@ -67,10 +69,19 @@ func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string
 }
 
 // Callback does nothing.
-func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
 	return ""
 }
 
+// AnchorData returns the data to render the lens's body with to show the test an anchor names.
+func (lens Lens) AnchorData(anchor string) string {
+	data, err := json.Marshal(viewRequest{Anchor: anchor})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
 type JunitResult struct {
 	junit.Result
 }
@@ -81,58 +92,50 @@ func (jr JunitResult) Duration() time.Duration {
 
 // TestResult holds data about a test extracted from junit output
 type TestResult struct {
-	Junit JunitResult
-	Link  string
+	Junit       JunitResult
+	Link        string
+	Attachments []Attachment
+	// Failure is the failure message, with references to source files linked.
+	Failure []lenses.SourceSegment
+	// LogLine is the line of the build log the failure message was logged on, if it was found.
+	LogLine int64
+	// Target is set on the test a link to the lens's view shows.
+	Target bool
 }
 
-// Body renders the <body> for JUnit tests
-func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
-	type testResults struct {
-		junit []junit.Result
-		link  string
-		path  string
-		err   error
-	}
-	resultChan := make(chan testResults)
-	for _, artifact := range artifacts {
-		go func(artifact lenses.Artifact) {
-			result := testResults{
-				link: artifact.CanonicalLink(),
-				path: artifact.JobPath(),
-			}
-			var contents []byte
-			contents, result.err = artifact.ReadAll()
-			if result.err != nil {
-				logrus.WithError(result.err).WithField("artifact", artifact.CanonicalLink()).Warn("Error reading artifact")
-				resultChan <- result
-				return
-			}
-			var suites junit.Suites
-			suites, result.err = junit.Parse(contents)
-			if result.err != nil {
-				logrus.WithError(result.err).WithField("artifact", artifact.CanonicalLink()).Info("Error parsing junit file.")
-				resultChan <- result
-				return
-			}
-			for _, suite := range suites.Suites {
-				for _, test := range suite.Results {
-					result.junit = append(result.junit, test)
-				}
-			}
-			resultChan <- result
-		}(artifact)
+// Anchor returns the anchor that links to the test name it by.
+func (tr TestResult) Anchor() string {
+	return lenses.Anchor(tr.Junit.Name)
+}
+
+// Properties returns the test's properties.
+func (tr TestResult) Properties() []junit.Property {
+	if tr.Junit.Properties == nil {
+		return nil
 	}
-	results := make([]testResults, 0, len(artifacts))
-	for range artifacts {
-		results = append(results, <-resultChan)
+	return tr.Junit.Properties.PropertyList
+}
+
+// Body renders the <body> for JUnit tests
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	linker := lenses.ReadSourceLinker(artifacts)
+	artifacts, siblings := lenses.SplitSiblings(artifacts)
+	all := append([]lenses.Artifact(nil), artifacts...)
+	for _, s := range siblings {
+		all = append(all, s)
 	}
-	sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	idx := newAttachmentIndex(all)
+	request := parseViewRequest(data)
+	results := readResults(artifacts)
 
 	jvd := struct {
 		NumTests int
 		Passed   []TestResult
 		Failed   []TestResult
 		Skipped  []TestResult
+		// ShowPassed and ShowSkipped unfold the passed or skipped tests when a link shows one.
+		ShowPassed  bool
+		ShowSkipped bool
 	}{}
 	for _, result := range results {
 		if result.err != nil {
@@ -141,24 +144,34 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 		for _, test := range result.junit {
 			if test.Failure != nil {
 				jvd.Failed = append(jvd.Failed, TestResult{
-					Junit: JunitResult{test},
-					Link:  result.link,
+					Junit:       JunitResult{test},
+					Link:        result.link,
+					Attachments: idx.attachments(result.path, test),
+					Failure:     linker.Split(*test.Failure),
+					Target:      request.targets(test.Name),
 				})
 			} else if test.Skipped != nil {
 				jvd.Skipped = append(jvd.Skipped, TestResult{
-					Junit: JunitResult{test},
-					Link:  result.link,
+					Junit:       JunitResult{test},
+					Link:        result.link,
+					Attachments: idx.attachments(result.path, test),
+					Target:      request.targets(test.Name),
 				})
+				jvd.ShowSkipped = jvd.ShowSkipped || request.targets(test.Name)
 			} else {
 				jvd.Passed = append(jvd.Passed, TestResult{
-					Junit: JunitResult{test},
-					Link:  result.link,
+					Junit:       JunitResult{test},
+					Link:        result.link,
+					Attachments: idx.attachments(result.path, test),
+					Target:      request.targets(test.Name),
 				})
+				jvd.ShowPassed = jvd.ShowPassed || request.targets(test.Name)
 			}
 		}
 	}
 
 	jvd.NumTests = len(jvd.Passed) + len(jvd.Failed) + len(jvd.Skipped)
+	linkToLog(jvd.Failed, buildLog(siblings))
 
 	junitTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
 	if err != nil {
@@ -173,3 +186,50 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 
 	return buf.String()
 }
+
+// testResults holds the tests in a JUnit report.
+type testResults struct {
+	junit []junit.Result
+	link  string
+	path  string
+	err   error
+}
+
+// readResults reads the tests in some JUnit reports, ordered by the reports' paths.
+func readResults(artifacts []lenses.Artifact) []testResults {
+	resultChan := make(chan testResults)
+	for _, artifact := range artifacts {
+		go func(artifact lenses.Artifact) {
+			result := testResults{
+				link: artifact.CanonicalLink(),
+				path: artifact.JobPath(),
+			}
+			var contents []byte
+			contents, result.err = artifact.ReadAll()
+			if result.err != nil {
+				logrus.WithError(result.err).WithField("artifact", artifact.CanonicalLink()).Warn("Error reading artifact")
+				resultChan <- result
+				return
+			}
+			var suites junit.Suites
+			suites, result.err = junit.Parse(contents)
+			if result.err != nil {
+				logrus.WithError(result.err).WithField("artifact", artifact.CanonicalLink()).Info("Error parsing junit file.")
+				resultChan <- result
+				return
+			}
+			for _, suite := range suites.Suites {
+				for _, test := range suite.Results {
+					result.junit = append(result.junit, test)
+				}
+			}
+			resultChan <- result
+		}(artifact)
+	}
+	results := make([]testResults, 0, len(artifacts))
+	for range artifacts {
+		results = append(results, <-resultChan)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	return results
+}
@@ -50,10 +50,22 @@ function addStdoutOpeners(): void {
   }
 }
 
+// Shows the line of the build log that a failure message was logged on.
+function addLogLinks(): void {
+  const links = document.querySelectorAll<HTMLAnchorElement>('a.log-link');
+  for (const link of Array.from(links)) {
+    link.onclick = (e) => {
+      e.preventDefault();
+      spyglass.showLens('buildlog', `line/${link.dataset.line}?artifact=build-log.txt`);
+    };
+  }
+}
+
 function loaded(): void {
   addTestExpanders();
   addStdoutOpeners();
   addSectionExpanders();
+  addLogLinks();
 }
 
 window.addEventListener('DOMContentLoaded', loaded);
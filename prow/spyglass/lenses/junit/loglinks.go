@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// maxLogSearchBytes is how much of the build log is searched for the messages of failed tests.
+const maxLogSearchBytes = 50 << 20
+
+// viewRequest is the data the lens's body is rendered with when a view of it is linked to.
+type viewRequest struct {
+	// Test is the name of the failed test to show.
+	Test string `json:"test,omitempty"`
+	// Anchor is the anchor of the name of the test to show, which may have passed or been skipped.
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// targets returns whether a request shows the test of the given name.
+func (r viewRequest) targets(name string) bool {
+	return (r.Test != "" && name == r.Test) || (r.Anchor != "" && lenses.Anchor(name) == r.Anchor)
+}
+
+// parseViewRequest parses the data the lens's body is rendered with, which is empty unless a
+// view of the lens is linked to.
+func parseViewRequest(data string) viewRequest {
+	var request viewRequest
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &request); err != nil {
+			logrus.WithError(err).Debug("Ignoring data the junit lens was not linked to with.")
+		}
+	}
+	return request
+}
+
+// buildLog returns the job's build log from among the artifacts the lens did not match, if it
+// has one.
+func buildLog(siblings []*lenses.SiblingArtifact) lenses.Artifact {
+	for _, s := range siblings {
+		if s.JobPath() == lenses.BuildLogPath {
+			return s
+		}
+	}
+	return nil
+}
+
+// linkToLog finds the lines of the build log that the messages of failed tests were logged on,
+// so that they can be jumped to.
+func linkToLog(failed []TestResult, log lenses.Artifact) {
+	var messages []string
+	for _, test := range failed {
+		messages = append(messages, *test.Junit.Failure)
+	}
+	found := findInLog(log, messages)
+	for i, test := range failed {
+		if location, ok := found[*test.Junit.Failure]; ok {
+			failed[i].LogLine = location.Line
+		}
+	}
+}
+
+// findInLog finds the lines of the build log that some failure messages were logged on. It
+// finds none if there is no build log.
+func findInLog(log lenses.Artifact, messages []string) map[string]lenses.LogLocation {
+	if log == nil || len(messages) == 0 {
+		return nil
+	}
+	found, err := lenses.FindFailures(log, messages, maxLogSearchBytes)
+	if err != nil {
+		logrus.WithError(err).WithField("artifact", log.CanonicalLink()).Warn("Error searching the build log for failures.")
+	}
+	return found
+}
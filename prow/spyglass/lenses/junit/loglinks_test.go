@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+func TestLinkToLog(t *testing.T) {
+	log := "=== RUN   TestA\n    a_test.go:12: expected 3 widgets, got 2\n--- FAIL: TestA (0.01s)\n"
+	siblings := []*lenses.SiblingArtifact{
+		lenses.NewSiblingArtifact(&fakeartifact.Artifact{Path: "artifacts/build-log.txt", Content: []byte("a_test.go:12: expected 3 widgets, got 2")}),
+		lenses.NewSiblingArtifact(&fakeartifact.Artifact{Path: lenses.BuildLogPath, Content: []byte(log)}),
+	}
+	logged, unlogged := "a_test.go:12: expected 3 widgets, got 2", "b_test.go:1: this was never logged at all"
+	failed := []TestResult{
+		{Junit: JunitResult{junit.Result{Name: "TestA", Failure: &logged}}},
+		{Junit: JunitResult{junit.Result{Name: "TestB", Failure: &unlogged}}},
+	}
+	linkToLog(failed, buildLog(siblings))
+	if failed[0].LogLine != 2 {
+		t.Errorf("expected TestA's failure to be found on line 2 of the build log, got %d", failed[0].LogLine)
+	}
+	if failed[1].LogLine != 0 {
+		t.Errorf("expected TestB's failure not to be found, got line %d", failed[1].LogLine)
+	}
+
+	if request := parseViewRequest(`{"test":"TestA"}`); request.Test != "TestA" {
+		t.Errorf("expected a view of TestA, got %+v", request)
+	}
+	if request := parseViewRequest("not json"); request.Test != "" {
+		t.Errorf("expected invalid data to be ignored, got %+v", request)
+	}
+}
+
+func TestViewRequestTargets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		test     string
+		expected bool
+	}{
+		{name: "test linked to by name", data: `{"test":"[sig-node] Pods run"}`, test: "[sig-node] Pods run", expected: true},
+		{name: "test linked to by anchor", data: Lens{}.AnchorData("sig-node-pods-run"), test: "[sig-node] Pods run", expected: true},
+		{name: "other test", data: Lens{}.AnchorData("sig-node-pods-run"), test: "[sig-node] Pods stop"},
+		{name: "no link", test: "[sig-node] Pods run"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := parseViewRequest(tc.data).targets(tc.test); actual != tc.expected {
+				t.Errorf("expected %q to be targeted: %t, got %t", tc.test, tc.expected, actual)
+			}
+		})
+	}
+}
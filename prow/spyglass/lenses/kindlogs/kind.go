@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kindlogs
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// containerLogRE matches the name of a log in a node's containers directory, such as
+	// "kube-apiserver-kind-control-plane_kube-system_kube-apiserver-0123abcd.log".
+	containerLogRE = regexp.MustCompile(`^(.+)_([^_]+)_(.+)-[0-9a-f]{64}\.log$`)
+	// podDirRE matches the name of a pod's directory in a node's pods directory, such as
+	// "kube-system_kube-apiserver-kind-control-plane_0123abcd".
+	podDirRE = regexp.MustCompile(`^([^_]+)_(.+)_([^_]+)$`)
+	// restartRE matches the name of a container's log in a pod's directory, such as "0.log".
+	restartRE = regexp.MustCompile(`^(\d+)\.log$`)
+)
+
+// nodeFiles are files that `kind export logs` writes for every node, so their directories are
+// recognized as nodes.
+var nodeFiles = map[string]bool{
+	"journal.log":            true,
+	"kubelet.log":            true,
+	"containerd.log":         true,
+	"docker.log":             true,
+	"kubernetes-version.txt": true,
+	"inspect.json":           true,
+	"serial.log":             true,
+}
+
+// File is a log or other file exported from a cluster.
+type File struct {
+	// Name is the file's name within its node or cluster.
+	Name string
+	// Path is the file's path among the job's artifacts.
+	Path string
+}
+
+// Container is the logs of a container of a pod.
+type Container struct {
+	Namespace string
+	Pod       string
+	Name      string
+	// Logs are the container's logs, the current one first. A container that restarted has a
+	// log for each run.
+	Logs []File
+}
+
+// Restarts returns the number of times the container restarted, as far as the logs show.
+func (c *Container) Restarts() int {
+	if len(c.Logs) == 0 {
+		return 0
+	}
+	return len(c.Logs) - 1
+}
+
+// Node is the logs exported from a node of a cluster.
+type Node struct {
+	Name string
+	// Files are the node's own logs, such as kubelet.log and journal.log.
+	Files      []File
+	Containers []*Container
+
+	containers map[string]*Container
+	// fromPods is whether containers were read from the pods directory, which is preferred to
+	// the containers directory as it keeps the logs of restarted containers.
+	fromPods bool
+}
+
+// Cluster is the logs exported from a cluster by `kind export logs`.
+type Cluster struct {
+	// Dir is the directory the logs were exported to.
+	Dir string
+	// Files are the cluster's own files, such as kind-version.txt.
+	Files []File
+	Nodes []*Node
+
+	nodes map[string]*Node
+}
+
+// parseClusters finds the clusters among paths, recognizing nodes by the files that
+// `kind export logs` writes for each of them. Paths outside any cluster are ignored.
+func parseClusters(paths []string) []*Cluster {
+	// First find the nodes, as only they identify a directory as holding a cluster.
+	nodeDirs := map[string]bool{}
+	for _, p := range paths {
+		dir, file := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		if nodeFiles[file] {
+			nodeDirs[dir] = true
+			continue
+		}
+		for _, sub := range []string{"/containers/", "/pods/"} {
+			if i := strings.Index(p, sub); i > 0 {
+				nodeDirs[p[:i]] = true
+			}
+		}
+	}
+	clusters := map[string]*Cluster{}
+	var result []*Cluster
+	cluster := func(dir string) *Cluster {
+		c, ok := clusters[dir]
+		if !ok {
+			c = &Cluster{Dir: dir, nodes: map[string]*Node{}}
+			clusters[dir] = c
+			result = append(result, c)
+		}
+		return c
+	}
+	for dir := range nodeDirs {
+		c := cluster(path.Dir(dir))
+		name := path.Base(dir)
+		c.nodes[name] = &Node{Name: name, containers: map[string]*Container{}}
+	}
+
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	for _, p := range sorted {
+		node, rest := findNode(p, nodeDirs)
+		if node == "" {
+			dir := path.Dir(p)
+			if c, ok := clusters[dir]; ok {
+				c.Files = append(c.Files, File{Name: path.Base(p), Path: p})
+			}
+			continue
+		}
+		c := clusters[path.Dir(node)]
+		c.nodes[path.Base(node)].add(rest, p)
+	}
+
+	for _, c := range result {
+		for _, n := range c.nodes {
+			n.finish()
+			c.Nodes = append(c.Nodes, n)
+		}
+		sort.Slice(c.Nodes, func(i, j int) bool { return c.Nodes[i].Name < c.Nodes[j].Name })
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Dir < result[j].Dir })
+	return result
+}
+
+// findNode returns the node directory that p is in and the rest of p within it, or "" if p is
+// not in a node's directory.
+func findNode(p string, nodeDirs map[string]bool) (string, string) {
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if nodeDirs[dir] {
+			return dir, strings.TrimPrefix(p, dir+"/")
+		}
+	}
+	return "", ""
+}
+
+// container returns the container with the given names, adding it if it is new.
+func (n *Node) container(namespace, pod, name string) *Container {
+	key := namespace + "/" + pod + "/" + name
+	c, ok := n.containers[key]
+	if !ok {
+		c = &Container{Namespace: namespace, Pod: pod, Name: name}
+		n.containers[key] = c
+	}
+	return c
+}
+
+// add adds the file at p, whose path within the node's directory is rest.
+func (n *Node) add(rest, p string) {
+	parts := strings.Split(rest, "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "pods":
+		pod := podDirRE.FindStringSubmatch(parts[1])
+		if pod == nil || !restartRE.MatchString(parts[3]) {
+			break
+		}
+		if !n.fromPods {
+			// Logs from the pods directory replace those from the containers directory.
+			n.fromPods = true
+			n.containers = map[string]*Container{}
+		}
+		c := n.container(pod[1], pod[2], parts[2])
+		c.Logs = append(c.Logs, File{Name: parts[3], Path: p})
+		return
+	case len(parts) == 2 && parts[0] == "containers":
+		m := containerLogRE.FindStringSubmatch(parts[1])
+		if m == nil {
+			break
+		}
+		if !n.fromPods {
+			c := n.container(m[2], m[1], m[3])
+			c.Logs = append(c.Logs, File{Name: parts[1], Path: p})
+		}
+		return
+	}
+	n.Files = append(n.Files, File{Name: rest, Path: p})
+}
+
+// finish orders the node's containers by namespace, pod and name, and each container's logs
+// from the most recent run.
+func (n *Node) finish() {
+	for _, c := range n.containers {
+		sort.Slice(c.Logs, func(i, j int) bool {
+			ri, _ := strconv.Atoi(strings.TrimSuffix(c.Logs[i].Name, ".log"))
+			rj, _ := strconv.Atoi(strings.TrimSuffix(c.Logs[j].Name, ".log"))
+			return ri > rj
+		})
+		n.Containers = append(n.Containers, c)
+	}
+	sort.Slice(n.Containers, func(i, j int) bool {
+		a, b := n.Containers[i], n.Containers[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Name < b.Name
+	})
+}
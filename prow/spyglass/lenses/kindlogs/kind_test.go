@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kindlogs
+
+import (
+	"reflect"
+	"testing"
+)
+
+const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseClusters(t *testing.T) {
+	clusters := parseClusters([]string{
+		"build-log.txt",
+		"artifacts/logs/kind-version.txt",
+		"artifacts/logs/docker-info.txt",
+		"artifacts/logs/kind-control-plane/kubelet.log",
+		"artifacts/logs/kind-control-plane/journal.log",
+		"artifacts/logs/kind-control-plane/containers/kube-apiserver-kind-control-plane_kube-system_kube-apiserver-" + id + ".log",
+		"artifacts/logs/kind-control-plane/pods/kube-system_kube-apiserver-kind-control-plane_1234/kube-apiserver/0.log",
+		"artifacts/logs/kind-control-plane/pods/kube-system_kube-apiserver-kind-control-plane_1234/kube-apiserver/1.log",
+		"artifacts/logs/kind-control-plane/pods/kube-system_etcd-kind-control-plane_5678/etcd/0.log",
+		"artifacts/logs/kind-worker/containerd.log",
+		"artifacts/logs/kind-worker/containers/coredns-5c98db65d4-abcde_kube-system_coredns-" + id + ".log",
+	})
+	if len(clusters) != 1 {
+		t.Fatalf("expected one cluster, got %d", len(clusters))
+	}
+	c := clusters[0]
+	if c.Dir != "artifacts/logs" || !reflect.DeepEqual(fileNames(c.Files), []string{"docker-info.txt", "kind-version.txt"}) {
+		t.Errorf("unexpected cluster %q with files %q", c.Dir, fileNames(c.Files))
+	}
+	if len(c.Nodes) != 2 {
+		t.Fatalf("expected two nodes, got %d", len(c.Nodes))
+	}
+	control, worker := c.Nodes[0], c.Nodes[1]
+	if control.Name != "kind-control-plane" || !reflect.DeepEqual(fileNames(control.Files), []string{"journal.log", "kubelet.log"}) {
+		t.Errorf("unexpected node %q with files %q", control.Name, fileNames(control.Files))
+	}
+	if len(control.Containers) != 2 {
+		t.Fatalf("expected the containers of the control plane to be read from its pods, got %+v", control.Containers)
+	}
+	apiserver := control.Containers[1]
+	if apiserver.Pod != "kube-apiserver-kind-control-plane" || apiserver.Name != "kube-apiserver" || apiserver.Restarts() != 1 || apiserver.Logs[0].Name != "1.log" {
+		t.Errorf("expected the API server to have restarted once, got %+v", apiserver)
+	}
+	if len(worker.Containers) != 1 {
+		t.Fatalf("expected one container on the worker, got %d", len(worker.Containers))
+	}
+	coredns := worker.Containers[0]
+	if coredns.Namespace != "kube-system" || coredns.Pod != "coredns-5c98db65d4-abcde" || coredns.Name != "coredns" || coredns.Restarts() != 0 {
+		t.Errorf("unexpected container %+v", coredns)
+	}
+}
+
+func TestParseClustersWithoutKind(t *testing.T) {
+	if clusters := parseClusters([]string{"build-log.txt", "artifacts/junit_01.xml"}); len(clusters) != 0 {
+		t.Errorf("expected no clusters, got %d", len(clusters))
+	}
+}
+
+func fileNames(files []File) []string {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+async function showLog(link: HTMLAnchorElement): Promise<void> {
+  const cluster = link.closest<HTMLElement>('.kindlogs-cluster')!;
+  for (const selected of Array.from(cluster.querySelectorAll('.kindlogs-selected'))) {
+    selected.classList.remove('kindlogs-selected');
+  }
+  link.classList.add('kindlogs-selected');
+  const viewer = cluster.querySelector<HTMLElement>('.kindlogs-viewer')!;
+  viewer.innerHTML = '<div class="kindlogs-muted">Loading…</div>';
+  spyglass.contentUpdated();
+  viewer.innerHTML = await spyglass.request(JSON.stringify({artifact: link.dataset.artifact}));
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.kindlogs-file'))) {
+    link.addEventListener('click', (e) => {
+      e.preventDefault();
+      showLog(link);
+    });
+  }
+  for (const details of Array.from(document.querySelectorAll<HTMLDetailsElement>('details'))) {
+    details.addEventListener('toggle', () => spyglass.contentUpdated());
+  }
+});
@@ -0,0 +1,208 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kindlogs provides a Spyglass lens that presents the logs exported by `kind export logs`
+// by node and container, reading only the log that is selected.
+package kindlogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	name     = "kindlogs"
+	title    = "Kind Logs"
+	priority = 46
+
+	// defaultMaxBytes is how much of the end of the selected log is read if not configured.
+	defaultMaxBytes = 10 << 20
+)
+
+var (
+	// errorRE and warningRE match the lines of klog and logrus logs at those levels.
+	errorRE   = regexp.MustCompile(`^[EF]\d{4} |level=(?:error|fatal)\b`)
+	warningRE = regexp.MustCompile(`^W\d{4} |level=warn(?:ing)?\b`)
+)
+
+// Lens is the implementation of a kind log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of the end of the selected log is read. Defaults to 10MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+func parseConfig(rawConfig json.RawMessage) (config, error) {
+	conf := config{MaxBytes: defaultMaxBytes}
+	var err error
+	if len(rawConfig) != 0 {
+		err = json.Unmarshal(rawConfig, &conf)
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	return conf, err
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+type kindView struct {
+	Errors   []string
+	Clusters []*Cluster
+}
+
+// Body renders a picker of the nodes and containers of each cluster. Logs are read when they are
+// selected.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := kindView{}
+	if _, err := parseConfig(rawConfig); err != nil {
+		view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+	}
+	var paths []string
+	for _, a := range artifacts {
+		paths = append(paths, a.JobPath())
+	}
+	view.Clusters = parseClusters(paths)
+	if len(view.Clusters) == 0 {
+		view.Errors = append(view.Errors, "No logs exported by kind found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// request is sent by the frontend to read a log.
+type request struct {
+	Artifact string `json:"artifact"`
+}
+
+// Line is a line of a log.
+type Line struct {
+	Number int
+	Text   string
+	Level  string
+}
+
+// LogView is the part of a log that was read.
+type LogView struct {
+	Name string
+	Link string
+	// Skipped is the size of the start of the log that was not read, if any.
+	Skipped string
+	Lines   []Line
+	Error   string
+}
+
+// Callback renders the end of the log requested by the frontend.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	var req request
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		return executeTemplate(resourceDir, "log", LogView{Error: fmt.Sprintf("Failed to parse request: %v", err)})
+	}
+	conf, _ := parseConfig(rawConfig)
+	for _, a := range artifacts {
+		if a.JobPath() == req.Artifact {
+			return executeTemplate(resourceDir, "log", readLog(a, conf.MaxBytes))
+		}
+	}
+	return executeTemplate(resourceDir, "log", LogView{Error: fmt.Sprintf("No artifact named %q.", req.Artifact)})
+}
+
+// readLog reads the last maxBytes of a log, or the first maxBytes if it is compressed and so
+// cannot be read from the end.
+func readLog(a lenses.Artifact, maxBytes int64) LogView {
+	view := LogView{Name: a.JobPath(), Link: a.CanonicalLink()}
+	size, err := a.Size()
+	if err != nil {
+		view.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+		return view
+	}
+	fromEnd := true
+	content, err := a.ReadTail(maxBytes)
+	if err == lenses.ErrGzipOffsetRead {
+		fromEnd = false
+		content, err = a.ReadAtMost(maxBytes)
+	}
+	if err != nil && err != io.EOF {
+		view.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+		return view
+	}
+	numbered := true
+	if fromEnd && size > int64(len(content)) {
+		// Lines can't be numbered without reading the start of the log, and the first line
+		// read is probably partial.
+		numbered = false
+		skipped := size - int64(len(content))
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			skipped += int64(i + 1)
+			content = content[i+1:]
+		}
+		view.Skipped = chart.FormatBytes(float64(skipped))
+	}
+	for i, text := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		line := Line{Text: text}
+		if numbered {
+			line.Number = i + 1
+		}
+		switch {
+		case errorRE.MatchString(text):
+			line.Level = "error"
+		case warningRE.MatchString(text):
+			line.Level = "warning"
+		}
+		view.Lines = append(view.Lines, line)
+	}
+	return view
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
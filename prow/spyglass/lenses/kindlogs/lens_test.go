@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kindlogs
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+const testLog = `I1001 12:00:00.000000       1 server.go:10] starting
+W1001 12:00:01.000000       1 server.go:20] slow
+E1001 12:00:02.000000       1 server.go:30] failed
+`
+
+func TestReadLog(t *testing.T) {
+	view := readLog(&fakeartifact.Artifact{Path: "kubelet.log", Content: []byte(testLog)}, 1<<20)
+	if view.Error != "" || view.Skipped != "" || len(view.Lines) != 3 {
+		t.Fatalf("expected the whole log to be read, got %+v", view)
+	}
+	for i, level := range []string{"", "warning", "error"} {
+		if l := view.Lines[i]; l.Number != i+1 || l.Level != level {
+			t.Errorf("expected line %d to be at level %q, got %d at %q", i+1, level, l.Number, l.Level)
+		}
+	}
+
+	view = readLog(&fakeartifact.Artifact{Path: "kubelet.log", Content: []byte(testLog)}, 70)
+	if len(view.Lines) != 1 || view.Lines[0].Number != 0 || view.Lines[0].Level != "error" || view.Skipped == "" {
+		t.Errorf("expected only the last, unnumbered line to be read, got %+v", view)
+	}
+
+	view = readLog(&fakeartifact.Artifact{Path: "kubelet.log", Content: []byte(testLog), Gzipped: true}, 70)
+	if len(view.Lines) != 2 || view.Lines[0].Number != 1 || view.Skipped != "" {
+		t.Errorf("expected the start of a compressed log to be read, got %+v", view)
+	}
+}
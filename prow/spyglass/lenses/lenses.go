@@ -20,11 +20,17 @@ package lenses
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"math"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -42,6 +48,9 @@ var (
 	// ErrContextUnsupported is thrown when attempting to use a context with an artifact that
 	// does not support context operations (cancel, withtimeout, etc.)
 	ErrContextUnsupported = errors.New("artifact does not support context operations")
+	// ErrInvalidContinuationToken is returned when a continuation token does not point into the
+	// artifact it is used to read.
+	ErrInvalidContinuationToken = errors.New("invalid continuation token")
 )
 
 type LensConfig struct {
@@ -53,20 +62,58 @@ type LensConfig struct {
 	Priority uint
 	// HideTitle will hide the lens title after loading if set to true.
 	HideTitle bool
+	// Baseline asks Spyglass to also supply the matching artifacts from the baseline run of the
+	// one being viewed: the most recent passing run of its job before it, or for presubmits with
+	// a baseline job, the latest passing run of that job started before it. Use SplitBaseline or
+	// BaselineArtifacts to tell them apart.
+	Baseline bool
+	// Siblings asks Spyglass to also supply every other artifact of the run being viewed, so that
+	// lenses can link to files their artifacts refer to. Use SplitSiblings to tell them apart.
+	Siblings bool
 }
 
 // Lens defines the interface that lenses are required to implement in order to be used by Spyglass.
+// The config passed to each method is the lens's entry in the Spyglass lens config for the job being
+// viewed, or nil if there is none. Its format is defined by the lens.
 type Lens interface {
 	// Config returns a LensConfig that describes the lens.
 	Config() LensConfig
 	// Header returns a a string that is injected into the rendered lens's <head>
-	Header(artifacts []Artifact, resourceDir string) string
+	Header(artifacts []Artifact, resourceDir string, config json.RawMessage) string
 	// Body returns a string that is initially injected into the rendered lens's <body>.
 	// The lens's front-end code may call back to Body again, passing in some data string of its choosing.
-	Body(artifacts []Artifact, resourceDir string, data string) string
+	Body(artifacts []Artifact, resourceDir string, data string, config json.RawMessage) string
 	// Callback receives a string sent by the lens's front-end code and returns another string to be returned
 	// to that frontend code.
-	Callback(artifacts []Artifact, resourceDir string, data string) string
+	Callback(artifacts []Artifact, resourceDir string, data string, config json.RawMessage) string
+}
+
+// DataLens is a lens whose parsed data can also be served as JSON, for tools that would otherwise
+// have to scrape the HTML it renders.
+type DataLens interface {
+	Lens
+	// Data returns what the lens parses from some artifacts, to be encoded as JSON.
+	Data(artifacts []Artifact, config json.RawMessage) (interface{}, error)
+}
+
+// ComparisonLens is a lens that can also render two runs of a job side by side.
+type ComparisonLens interface {
+	Lens
+	// Compare returns a string that is injected into the rendered lens's <body> in place of Body
+	// when two runs are compared. The artifacts of the run being compared to are BaselineArtifacts
+	// whose Source is that run; use SplitBaseline to tell them apart from the run being viewed.
+	// The lens's front-end code may still call Callback, which is passed the artifacts of both.
+	Compare(artifacts []Artifact, resourceDir string, config json.RawMessage) string
+}
+
+// AnchorLens is a lens whose view has parts, such as failed tests or sections of a log, that
+// links can name with anchors. Spyglass pages linked to as "#<lens>:<anchor>" render the lens
+// showing the named part expanded. Lenses give parts the anchor returned by Anchor for their titles.
+type AnchorLens interface {
+	Lens
+	// AnchorData returns the data to render the lens's body with to show the part of its view
+	// an anchor names.
+	AnchorData(anchor string) string
 }
 
 // Artifact represents some output of a prow job
@@ -87,6 +134,25 @@ type Artifact interface {
 	Size() (int64, error)
 }
 
+// LiveArtifact is an artifact that may still be being written, such as the log of a running pod.
+type LiveArtifact interface {
+	Artifact
+	// Live returns whether the artifact may still grow.
+	Live() bool
+}
+
+// IsLive returns whether an artifact may still be being written.
+func IsLive(a Artifact) bool {
+	if decoded, ok := a.(*decodedArtifact); ok {
+		a = decoded.Artifact
+	}
+	if indexed, ok := a.(*IndexedArtifact); ok {
+		a = indexed.Artifact
+	}
+	live, ok := a.(LiveArtifact)
+	return ok && live.Live()
+}
+
 // ResourceDirForLens returns the path to a lens's public resource directory.
 func ResourceDirForLens(baseDir, name string) string {
 	return filepath.Join(baseDir, name)
@@ -126,56 +192,215 @@ func UnregisterLens(viewerName string) {
 	logrus.Infof("Spyglass unregistered viewer %s.", viewerName)
 }
 
-// LastNLines reads the last n lines from an artifact.
+// initialLineLength is the line length assumed when deciding how much of an artifact to read
+// for its last lines, before any lines have been seen.
+const initialLineLength = 128
+
+// LastNLines reads the last n lines from an artifact. Lines may end in "\n" or "\r\n", and
+// lines rewritten with bare carriage returns, as progress bars do, are collapsed to their final
+// state.
 func LastNLines(a Artifact, n int64) ([]string, error) {
-	// 300B, a reasonable log line length, probably a bit more scalable than a hard-coded value
-	return LastNLinesChunked(a, n, 300*n+1)
+	return LastNLinesChunked(a, n, initialLineLength*(n+1))
 }
 
-// LastNLinesChunked reads the last n lines from an artifact by reading chunks of size chunkSize
-// from the end of the artifact. Best performance is achieved by:
-// argmin 0<chunkSize<INTMAX, f(chunkSize) = chunkSize - n * avgLineLength
+// LastNLinesChunked reads the last n lines from an artifact by reading chunks from the end of the
+// artifact, the first of size chunkSize. Later chunks grow geometrically, or straight to the size
+// the remaining lines are expected to take given the average length of the lines already read.
 func LastNLinesChunked(a Artifact, n, chunkSize int64) ([]string, error) {
-	toRead := chunkSize + 1 // Add 1 for exclusive upper bound read range
-	chunks := int64(1)
-	var contents []byte
-	var linesInContents int64
-	artifactSize, err := a.Size()
+	lines, _, err := LastNLinesChunkedContext(context.Background(), a, n, chunkSize)
+	return lines, err
+}
+
+// LastNLinesContext reads the last n lines from an artifact like LastNLines, but gives up reading
+// once ctx is done or its deadline is too near to read another chunk. It then returns the lines
+// read so far, the first of which may be incomplete, and reports that they were truncated.
+func LastNLinesContext(ctx context.Context, a Artifact, n int64) ([]string, bool, error) {
+	return LastNLinesChunkedContext(ctx, a, n, initialLineLength*(n+1))
+}
+
+// LastNLinesChunkedContext reads the last n lines from an artifact like LastNLinesChunked, but
+// gives up reading once ctx is done or its deadline is too near to read another chunk, returning
+// the lines read so far and whether they were truncated.
+func LastNLinesChunkedContext(ctx context.Context, a Artifact, n, chunkSize int64) ([]string, bool, error) {
+	end, err := a.Size()
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting artifact size: %v", err)
+	}
+	contents, start, truncated, err := readTailChunks(ctx, a, n, chunkSize, end, math.MaxInt64)
+	if err != nil {
+		return nil, false, err
+	}
+	if start > 0 {
+		// The first chunk read may begin partway through a character.
+		contents = trimRuneContinuation(contents)
+	}
+	lines, err := splitLines(contents)
+	if err != nil {
+		return nil, false, err
+	}
+	l := int64(len(lines))
+	if l < n {
+		return lines, truncated, nil
+	}
+	return lines[l-n:], truncated, nil
+}
+
+// TailPage is a page of the last lines of an artifact read by LastNLinesPaged.
+type TailPage struct {
+	Lines []string
+	// Truncated is set when the first line was cut off, as it alone was longer than the cap.
+	Truncated bool
+	// Next is the continuation token for the lines before these, or empty if they begin the
+	// artifact.
+	Next string
+}
+
+// LastNLinesPaged reads up to the last n lines of an artifact before the point a continuation
+// token returned by an earlier call leaves off at, or before its end if token is empty. At most
+// maxBytes are read, so fewer lines are returned if they are long, and a line longer than that is
+// cut off. Lines are split and collapsed as by LastNLines.
+func LastNLinesPaged(a Artifact, n, maxBytes int64, token string) (TailPage, error) {
+	end, err := a.Size()
 	if err != nil {
-		return nil, fmt.Errorf("error getting artifact size: %v", err)
-	}
-	offset := artifactSize - chunks*chunkSize
-	lastOffset := offset
-	var lastRead int64
-	for linesInContents < n && offset != 0 {
-		offset = lastOffset - lastRead
-		if offset < 0 {
-			toRead = offset + chunkSize + 1
-			offset = 0
+		return TailPage{}, fmt.Errorf("error getting artifact size: %v", err)
+	}
+	if token != "" {
+		offset, err := strconv.ParseInt(token, 10, 64)
+		if err != nil || offset < 0 || offset > end {
+			return TailPage{}, ErrInvalidContinuationToken
+		}
+		end = offset
+	}
+	chunkSize := initialLineLength * (n + 1)
+	if chunkSize > maxBytes {
+		chunkSize = maxBytes
+	}
+	contents, start, _, err := readTailChunks(context.Background(), a, n, chunkSize, end, maxBytes)
+	if err != nil {
+		return TailPage{}, err
+	}
+	var page TailPage
+	if start > 0 {
+		// The first line read is cut off, so it is dropped unless no other line was read.
+		if i := bytes.IndexByte(contents, '\n'); i >= 0 && i < len(contents)-1 {
+			contents = contents[i+1:]
+			start += int64(i + 1)
+		} else {
+			trimmed := trimRuneContinuation(contents)
+			start += int64(len(contents) - len(trimmed))
+			contents = trimmed
+			page.Truncated = true
+		}
+	}
+	// Only the last n lines are kept, and the next page ends where they begin.
+	for lines := countLines(contents); lines > n; lines-- {
+		i := bytes.IndexByte(contents, '\n')
+		contents = contents[i+1:]
+		start += int64(i + 1)
+		page.Truncated = false
+	}
+	page.Lines, err = splitLines(contents)
+	if err != nil {
+		return TailPage{}, err
+	}
+	if start > 0 {
+		page.Next = strconv.FormatInt(start, 10)
+	}
+	return page, nil
+}
+
+// readTailChunks reads chunks of an artifact backwards from end until n lines are read, at most
+// maxBytes are read, or ctx is done or its deadline is too near to read another chunk. The first
+// chunk is of size chunkSize, and later ones are sized by nextChunkSize. It returns the contents
+// read, the offset they begin at, and whether reading was cut short by ctx.
+func readTailChunks(ctx context.Context, a Artifact, n, chunkSize, end, maxBytes int64) ([]byte, int64, bool, error) {
+	var truncated bool
+	var lastReadTime time.Duration
+	var contents []byte
+	// The newline ending the artifact does not separate it from another line.
+	var linesInContents int64 = -1
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	for linesInContents < n && end > 0 && int64(len(contents)) < maxBytes {
+		// Reads are assumed to take about as long as the last one did.
+		if deadline, ok := ctx.Deadline(); ctx.Err() != nil || ok && time.Until(deadline) < lastReadTime {
+			truncated = true
+			break
 		}
-		bytesRead := make([]byte, toRead)
-		numBytesRead, err := a.ReadAt(bytesRead, offset)
+		if remaining := maxBytes - int64(len(contents)); chunkSize > remaining {
+			chunkSize = remaining
+		}
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		readStart := time.Now()
+		numBytesRead, err := a.ReadAt(chunk, start)
 		if err != nil && err != io.EOF {
-			return nil, fmt.Errorf("error reading artifact: %v", err)
+			return nil, 0, false, fmt.Errorf("error reading artifact: %v", err)
 		}
-		lastRead = int64(numBytesRead)
-		lastOffset = offset
-		bytesRead = bytes.Trim(bytesRead, "\x00")
-		linesInContents += int64(bytes.Count(bytesRead, []byte("\n")))
-		contents = append(bytesRead, contents...)
-		chunks++
+		lastReadTime = time.Since(readStart)
+		chunk = chunk[:numBytesRead]
+		if contents == nil && !bytes.HasSuffix(chunk, []byte("\n")) {
+			linesInContents = 0
+		}
+		linesInContents += int64(bytes.Count(chunk, []byte("\n")))
+		contents = append(chunk, contents...)
+		end = start
+		chunkSize = nextChunkSize(chunkSize, int64(len(contents)), linesInContents, n)
+	}
+	return contents, end, truncated, nil
+}
+
+// countLines returns the number of lines in some contents, where a trailing newline ends the
+// last line rather than beginning another.
+func countLines(contents []byte) int64 {
+	lines := int64(bytes.Count(contents, []byte("\n")))
+	if len(contents) > 0 && !bytes.HasSuffix(contents, []byte("\n")) {
+		lines++
 	}
+	return lines
+}
 
+// splitLines splits contents into lines, collapsing lines rewritten with carriage returns.
+func splitLines(contents []byte) ([]string, error) {
 	var lines []string
 	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	// Lines rewritten by progress bars can grow far beyond the default limit.
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(contents)+1)
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
-		line := scanner.Text()
-		lines = append(lines, line)
+		lines = append(lines, CollapseCarriageReturns(scanner.Text()))
 	}
-	l := int64(len(lines))
-	if l < n {
-		return lines, nil
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error splitting artifact into lines: %v", err)
+	}
+	return lines, nil
+}
+
+// nextChunkSize returns the size of the next chunk to read when looking for the last lines of an
+// artifact. It doubles the chunk size, unless the lines still wanted are expected to take even
+// more given the average length of the lines found so far.
+func nextChunkSize(chunkSize, bytesRead, linesFound, linesWanted int64) int64 {
+	next := 2 * chunkSize
+	if linesFound > 0 {
+		// One more line than wanted is read, as the first line found is usually incomplete.
+		if expected := bytesRead / linesFound * (linesWanted - linesFound + 1); expected > next {
+			next = expected
+		}
+	}
+	return next
+}
+
+// CollapseCarriageReturns returns the final state of a line that was rewritten by returning to
+// its start with a bare carriage return, which is the text after the last carriage return that
+// was followed by any. A trailing carriage return, as left by a "\r\n" line ending, is ignored.
+func CollapseCarriageReturns(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i != -1 {
+		return line[i+1:]
 	}
-	return lines[l-n:], nil
+	return line
 }
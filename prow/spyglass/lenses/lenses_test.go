@@ -19,8 +19,14 @@ package lenses
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 )
@@ -76,8 +82,8 @@ func (fa *FakeArtifact) UseContext(ctx context.Context) error {
 
 func (fa *FakeArtifact) ReadAtMost(n int64) ([]byte, error) {
 	buf := make([]byte, n)
-	_, err := fa.ReadAt(buf, 0)
-	return buf, err
+	read, err := fa.ReadAt(buf, 0)
+	return buf[:read], err
 }
 
 type dumpLens struct{}
@@ -89,11 +95,11 @@ func (dumpLens) Config() LensConfig {
 	}
 }
 
-func (dumpLens) Header(artifacts []Artifact, resourceDir string) string {
+func (dumpLens) Header(artifacts []Artifact, resourceDir string, config json.RawMessage) string {
 	return ""
 }
 
-func (dumpLens) Body(artifacts []Artifact, resourceDir, data string) string {
+func (dumpLens) Body(artifacts []Artifact, resourceDir, data string, config json.RawMessage) string {
 	var view []byte
 	for _, a := range artifacts {
 		data, err := a.ReadAll()
@@ -106,7 +112,7 @@ func (dumpLens) Body(artifacts []Artifact, resourceDir, data string) string {
 	return string(view)
 }
 
-func (dumpLens) Callback(artifacts []Artifact, resourceDir, data string) string {
+func (dumpLens) Callback(artifacts []Artifact, resourceDir, data string, config json.RawMessage) string {
 	return ""
 }
 
@@ -163,7 +169,7 @@ crazy`,
 		if tc.err == nil && lens == nil {
 			t.Fatalf("Expected lens %s but got nil.", tc.lensName)
 		}
-		if lens != nil && lens.Body(tc.artifacts, "", tc.raw) != tc.expected {
+		if lens != nil && lens.Body(tc.artifacts, "", tc.raw, nil) != tc.expected {
 			t.Errorf("%s expected view to be %s but got %s", tc.name, tc.expected, lens)
 		}
 	}
@@ -210,6 +216,27 @@ func TestLastNLines_GCS(t *testing.T) {
 				"everywhere a log log",
 			},
 		},
+		{
+			name:     "Read last 2 lines of a file with CRLF line endings",
+			n:        2,
+			path:     "log.txt",
+			contents: []byte("Oh wow\r\nlogs\r\nthis is\r\ncrazy\r\n"),
+			expected: []string{"this is", "crazy"},
+		},
+		{
+			name:     "Lines rewritten with bare carriage returns are collapsed",
+			n:        3,
+			path:     "log.txt",
+			contents: []byte("Downloading\n 10% [=>   ]\r 50% [==>  ]\r100% [=====]\r\nDone\n"),
+			expected: []string{"Downloading", "100% [=====]", "Done"},
+		},
+		{
+			name:     "Rewritten lines longer than the scanner's default limit are read",
+			n:        1,
+			path:     "log.txt",
+			contents: []byte(strings.Repeat("progress\r", 10000) + "finished\n"),
+			expected: []string{"finished"},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -240,3 +267,356 @@ func TestLastNLines_GCS(t *testing.T) {
 		})
 	}
 }
+
+// countingArtifact counts the reads made of it.
+type countingArtifact struct {
+	FakeArtifact
+	reads int
+}
+
+func (ca *countingArtifact) ReadAt(b []byte, off int64) (int, error) {
+	ca.reads++
+	return ca.FakeArtifact.ReadAt(b, off)
+}
+
+func TestLastNLinesLongLines(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf(`{"line": %d, "data": "%s"}`, i, strings.Repeat("x", 5000)))
+	}
+	artifact := &countingArtifact{
+		FakeArtifact: FakeArtifact{path: "log.json", content: []byte(strings.Join(lines, "\n") + "\n"), sizeLimit: 500e6},
+	}
+	actual, err := LastNLines(artifact, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(actual, lines[100:]) {
+		t.Errorf("expected the last 100 lines, got %d lines starting with %.20q", len(actual), actual[0])
+	}
+	if artifact.reads > 3 {
+		t.Errorf("expected no more than 3 reads, got %d", artifact.reads)
+	}
+}
+
+// cancellingArtifact cancels a context once it has been read from a number of times.
+type cancellingArtifact struct {
+	FakeArtifact
+	reads  int
+	cancel context.CancelFunc
+}
+
+func (ca *cancellingArtifact) ReadAt(b []byte, off int64) (int, error) {
+	ca.reads--
+	if ca.reads == 0 {
+		ca.cancel()
+	}
+	return ca.FakeArtifact.ReadAt(b, off)
+}
+
+func TestLastNLinesPaged(t *testing.T) {
+	lines := "one\ntwo\nthree\nfour\n"
+	longLine := "short\n" + strings.Repeat("x", 100) + "\n"
+	testCases := []struct {
+		name        string
+		contents    string
+		n           int64
+		maxBytes    int64
+		token       string
+		expected    TailPage
+		expectedErr error
+	}{
+		{
+			name:     "last lines continue from where they begin",
+			contents: lines,
+			n:        2,
+			maxBytes: 100,
+			expected: TailPage{Lines: []string{"three", "four"}, Next: "8"},
+		},
+		{
+			name:     "continuation reads the lines before",
+			contents: lines,
+			n:        2,
+			maxBytes: 100,
+			token:    "8",
+			expected: TailPage{Lines: []string{"one", "two"}},
+		},
+		{
+			name:     "fewer lines are read when the cap is reached",
+			contents: lines,
+			n:        3,
+			maxBytes: 12,
+			expected: TailPage{Lines: []string{"three", "four"}, Next: "8"},
+		},
+		{
+			name:     "line longer than the cap is cut off",
+			contents: longLine,
+			n:        2,
+			maxBytes: 10,
+			expected: TailPage{Lines: []string{strings.Repeat("x", 9)}, Truncated: true, Next: "97"},
+		},
+		{
+			name:        "token past the end is rejected",
+			contents:    lines,
+			n:           2,
+			maxBytes:    100,
+			token:       "100",
+			expectedErr: ErrInvalidContinuationToken,
+		},
+		{
+			name:        "malformed token is rejected",
+			contents:    lines,
+			n:           2,
+			maxBytes:    100,
+			token:       "next",
+			expectedErr: ErrInvalidContinuationToken,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifact := &FakeArtifact{path: "log.txt", content: []byte(tc.contents), sizeLimit: 500e6}
+			actual, err := LastNLinesPaged(artifact, tc.n, tc.maxBytes, tc.token)
+			if err != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected page %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestChunkedReaderSplitsBetweenCharacters(t *testing.T) {
+	content := strings.Repeat("a", readChunkSize-1) + "日本語"
+	r, err := NewChunkedReader(&FakeArtifact{content: []byte(content)}, int64(len(content)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var read []byte
+	p := make([]byte, readChunkSize+1)
+	for {
+		n, err := r.Read(p)
+		if !utf8.Valid(p[:n]) {
+			t.Errorf("expected each read to be valid UTF-8, got %q", p[:n])
+		}
+		read = append(read, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+	}
+	if string(read) != content {
+		t.Errorf("expected %d bytes of content, got %d", len(content), len(read))
+	}
+}
+
+func TestLastNLinesChunkedContext(t *testing.T) {
+	testCases := []struct {
+		name              string
+		contents          string
+		reads             int
+		expected          []string
+		expectedTruncated bool
+	}{
+		{
+			name:     "lines found before the context is done are not truncated",
+			contents: "one\ntwo\nthree\n",
+			// The first chunk begins at "two", which can't be known to be a whole line until
+			// the newline before it is read.
+			reads:    2,
+			expected: []string{"two", "three"},
+		},
+		{
+			name:     "last line without a trailing newline is not truncated",
+			contents: "one\ntwo\nthree",
+			reads:    1,
+			expected: []string{"two", "three"},
+		},
+		{
+			name:              "artifact without newlines is truncated when the context is done",
+			contents:          strings.Repeat("x", 100),
+			reads:             1,
+			expected:          []string{strings.Repeat("x", 10)},
+			expectedTruncated: true,
+		},
+		{
+			name:              "lines read so far are returned when the context is done",
+			contents:          "first\n" + strings.Repeat("x", 30) + "\nlast",
+			reads:             1,
+			expected:          []string{"xxxxx", "last"},
+			expectedTruncated: true,
+		},
+		{
+			name:              "characters cut off by the first chunk read are dropped",
+			contents:          "first\n日本語\nlast",
+			reads:             1,
+			expected:          []string{"語", "last"},
+			expectedTruncated: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			artifact := &cancellingArtifact{
+				FakeArtifact: FakeArtifact{path: "log.txt", content: []byte(tc.contents), sizeLimit: 500e6},
+				reads:        tc.reads,
+				cancel:       cancel,
+			}
+			actual, truncated, err := LastNLinesChunkedContext(ctx, artifact, 2, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if truncated != tc.expectedTruncated {
+				t.Errorf("expected truncated to be %t, got %t", tc.expectedTruncated, truncated)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected lines %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSplitBaseline(t *testing.T) {
+	current := &FakeArtifact{path: "current.txt"}
+	baseline := NewBaselineArtifact(&FakeArtifact{path: "current.txt"}, "gcs/bucket/logs/job/1")
+	c, b := SplitBaseline([]Artifact{current, baseline})
+	if len(c) != 1 || c[0] != current {
+		t.Errorf("expected only the current artifact, got %v", c)
+	}
+	if len(b) != 1 || b[0] != baseline {
+		t.Errorf("expected only the baseline artifact, got %v", b)
+	}
+	if b[0].JobPath() != "current.txt" {
+		t.Errorf("expected the baseline artifact to keep its path, got %q", b[0].JobPath())
+	}
+}
+
+func TestBaselineArtifacts(t *testing.T) {
+	current := &FakeArtifact{path: "coverage.out"}
+	baseline := NewBaselineArtifact(&FakeArtifact{path: "coverage.out"}, "gcs/bucket/logs/ci-job/1")
+	if b := BaselineArtifacts([]Artifact{current, baseline}); len(b) != 1 || b[0] != baseline || b[0].Source != "gcs/bucket/logs/ci-job/1" {
+		t.Errorf("expected only the baseline artifact, got %v", b)
+	}
+	if b := BaselineArtifacts([]Artifact{current}); b != nil {
+		t.Errorf("expected no baseline artifacts without a baseline run, got %v", b)
+	}
+}
+
+func TestSplitSiblings(t *testing.T) {
+	matched := &FakeArtifact{path: "report.json"}
+	sibling := NewSiblingArtifact(&FakeArtifact{path: "screenshots/login.png"})
+	m, s := SplitSiblings([]Artifact{sibling, matched})
+	if len(m) != 1 || m[0] != matched {
+		t.Errorf("expected only the matched artifact, got %v", m)
+	}
+	if len(s) != 1 || s[0] != sibling {
+		t.Errorf("expected only the sibling artifact, got %v", s)
+	}
+	if s[0].JobPath() != "screenshots/login.png" {
+		t.Errorf("expected the sibling artifact to keep its path, got %q", s[0].JobPath())
+	}
+}
+
+type liveArtifact struct {
+	FakeArtifact
+}
+
+func (la *liveArtifact) Live() bool {
+	return true
+}
+
+func TestIsLive(t *testing.T) {
+	live := &liveArtifact{FakeArtifact{path: "build-log.txt"}}
+	if !IsLive(live) {
+		t.Error("expected a live artifact to be live")
+	}
+	if !IsLive(&IndexedArtifact{Artifact: live}) {
+		t.Error("expected a live artifact with a line index to be live")
+	}
+	if IsLive(&FakeArtifact{path: "build-log.txt"}) {
+		t.Error("expected an artifact in storage not to be live")
+	}
+}
+
+type gzippedArtifact struct {
+	FakeArtifact
+}
+
+func (ga *gzippedArtifact) ReadAt(b []byte, off int64) (int, error) {
+	return 0, ErrGzipOffsetRead
+}
+
+func (ga *gzippedArtifact) ReadAtMost(n int64) ([]byte, error) {
+	return ga.content, nil
+}
+
+func TestChunkedReader(t *testing.T) {
+	content := strings.Repeat("0123456789", readChunkSize/5)
+	testCases := []struct {
+		name     string
+		artifact Artifact
+		limit    int64
+		expected string
+	}{
+		{
+			name:     "whole artifact across chunks",
+			artifact: &FakeArtifact{content: []byte(content)},
+			limit:    int64(len(content)),
+			expected: content,
+		},
+		{
+			name:     "limited",
+			artifact: &FakeArtifact{content: []byte(content)},
+			limit:    readChunkSize + 5,
+			expected: content[:readChunkSize+5],
+		},
+		{
+			name:     "limit cutting off a character",
+			artifact: &FakeArtifact{content: []byte("ab日本")},
+			limit:    3,
+			expected: "ab",
+		},
+		{
+			name:     "empty",
+			artifact: &FakeArtifact{},
+			limit:    100,
+			expected: "",
+		},
+		{
+			name:     "gzipped",
+			artifact: &gzippedArtifact{FakeArtifact{content: []byte("0123456789")}},
+			limit:    5,
+			expected: "01234",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewChunkedReader(tc.artifact, tc.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error reading: %v", err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("expected %d bytes of content, got %d", len(tc.expected), len(actual))
+			}
+		})
+	}
+}
+
+func TestSectionReader(t *testing.T) {
+	content := strings.Repeat("0123456789", readChunkSize/5)
+	offset := int64(readChunkSize - 3)
+	actual, err := ioutil.ReadAll(NewSectionReader(&FakeArtifact{content: []byte(content)}, offset, 10))
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	if expected := content[offset : offset+10]; string(actual) != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
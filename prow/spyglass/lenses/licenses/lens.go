@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package licenses provides a Spyglass lens that shows the licenses of a project's dependencies,
+// as found by license scanners such as go-licenses and FOSSA.
+package licenses
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "licenses"
+	title    = "Licenses"
+	priority = 34
+
+	// maxReportBytes is how much of each report is read.
+	maxReportBytes = 20 << 20
+)
+
+// defaultDisallowedClasses are the classes of licenses that are violations if not configured.
+var defaultDisallowedClasses = []string{classForbidden, classUnknown}
+
+// Lens is the implementation of a license scan-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// DisallowedClasses are the classes of licenses that dependencies may not have. Defaults to
+	// forbidden and unknown.
+	DisallowedClasses []string `json:"disallowed_classes,omitempty"`
+	// LicenseClasses classifies licenses the lens does not know, or reclassifies those it does.
+	LicenseClasses map[string]string `json:"license_classes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+type licensesView struct {
+	Errors       []string
+	Dependencies int
+	Violations   int
+	Groups       []ClassGroup
+}
+
+// Body shows the dependencies in every report, grouped by the class of their licenses.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := licensesView{}
+	disallowed, overrides, err := parseConfig(rawConfig)
+	if err != nil {
+		view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+	}
+
+	var deps []Dependency
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		content, err := a.ReadAtMost(maxReportBytes)
+		if err != nil && err != io.EOF {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		var found []Dependency
+		if strings.HasSuffix(a.JobPath(), ".json") {
+			found, err = parseFOSSA(content)
+		} else {
+			found, err = parseCSV(content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err))
+		}
+		deps = append(deps, found...)
+	}
+
+	view.Groups = group(deps, disallowed, overrides)
+	for _, g := range view.Groups {
+		view.Dependencies += len(g.Dependencies)
+		if g.Violation {
+			view.Violations += len(g.Dependencies)
+		}
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// parseConfig returns the disallowed classes and the license classifications of the config.
+// The defaults are returned along with any error.
+func parseConfig(rawConfig json.RawMessage) (map[string]bool, map[string]string, error) {
+	disallowed := map[string]bool{}
+	for _, class := range defaultDisallowedClasses {
+		disallowed[class] = true
+	}
+	conf := config{}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			return disallowed, nil, err
+		}
+	}
+	for _, class := range conf.DisallowedClasses {
+		if rank(class) == 0 {
+			return disallowed, nil, fmt.Errorf("unknown license class %q", class)
+		}
+	}
+	if conf.DisallowedClasses != nil {
+		disallowed = map[string]bool{}
+		for _, class := range conf.DisallowedClasses {
+			disallowed[class] = true
+		}
+	}
+	overrides := map[string]string{}
+	for license, class := range conf.LicenseClasses {
+		if rank(class) == 0 {
+			return disallowed, nil, fmt.Errorf("unknown license class %q for %s", class, license)
+		}
+		overrides[strings.ToLower(license)] = class
+	}
+	return disallowed, overrides, nil
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
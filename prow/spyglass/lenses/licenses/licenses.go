@@ -0,0 +1,243 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package licenses
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Classes of licenses, as used by go-licenses and the license classifier it is built on.
+const (
+	classForbidden    = "forbidden"
+	classUnknown      = "unknown"
+	classRestricted   = "restricted"
+	classReciprocal   = "reciprocal"
+	classNotice       = "notice"
+	classUnencumbered = "unencumbered"
+)
+
+// classOrder is the classes from most to least encumbering.
+var classOrder = []string{classForbidden, classUnknown, classRestricted, classReciprocal, classNotice, classUnencumbered}
+
+// knownClasses maps lower-case SPDX identifiers to their classes.
+var knownClasses = map[string]string{}
+
+// classPrefixes classify families of licenses by the start of their identifiers, most specific
+// first.
+var classPrefixes = []struct{ prefix, class string }{
+	{"cc-by-nc", classForbidden},
+	{"cc-by-nd", classRestricted},
+	{"cc-by-sa", classRestricted},
+	{"cc-by-", classNotice},
+}
+
+func init() {
+	for class, licenses := range map[string][]string{
+		classForbidden: {"AGPL-1.0", "AGPL-3.0", "Beerware", "SSPL-1.0", "WTFPL"},
+		classRestricted: {"BCL", "GPL-1.0", "GPL-2.0", "GPL-2.0-with-classpath-exception", "GPL-3.0",
+			"LGPL-2.0", "LGPL-2.1", "LGPL-3.0", "NPL-1.0", "NPL-1.1", "OSL-1.0", "OSL-1.1", "OSL-2.0",
+			"OSL-2.1", "OSL-3.0", "QPL-1.0", "Sleepycat"},
+		classReciprocal: {"APSL-1.0", "APSL-1.1", "APSL-1.2", "APSL-2.0", "CDDL-1.0", "CDDL-1.1",
+			"CPL-1.0", "EPL-1.0", "EPL-2.0", "FreeImage", "IPL-1.0", "MPL-1.0", "MPL-1.1", "MPL-2.0", "Ruby"},
+		classNotice: {"AFL-1.1", "AFL-1.2", "AFL-2.0", "AFL-2.1", "AFL-3.0", "Apache-1.0", "Apache-1.1",
+			"Apache-2.0", "Artistic-1.0", "Artistic-2.0", "BSD-1-Clause", "BSD-2-Clause",
+			"BSD-2-Clause-FreeBSD", "BSD-3-Clause", "BSD-4-Clause", "BSL-1.0", "FTL", "ISC", "LPL-1.02",
+			"MIT", "MS-PL", "NCSA", "OpenSSL", "PHP-3.0", "PHP-3.01", "PostgreSQL", "Python-2.0",
+			"Unicode-DFS-2016", "W3C", "X11", "Zlib"},
+		classUnencumbered: {"0BSD", "CC0-1.0", "Unlicense"},
+	} {
+		for _, license := range licenses {
+			knownClasses[strings.ToLower(license)] = class
+		}
+	}
+}
+
+// rank returns how encumbering a class is, with higher ranks more so.
+func rank(class string) int {
+	for i, c := range classOrder {
+		if c == class {
+			return len(classOrder) - i
+		}
+	}
+	return 0
+}
+
+// classify returns the class of a license, which may be an SPDX expression. Any of the
+// alternatives of an OR may be chosen, so the least encumbering is used, while every license of
+// an AND applies, so the most encumbering is used. Parentheses are ignored. overrides maps
+// lower-case licenses to classes and takes precedence over the built-in classes.
+func classify(license string, overrides map[string]string) string {
+	license = strings.NewReplacer("(", " ", ")", " ").Replace(license)
+	best := ""
+	for _, alternative := range strings.Split(license, " OR ") {
+		worst := ""
+		for _, part := range strings.Split(alternative, " AND ") {
+			if class := classifyOne(part, overrides); worst == "" || rank(class) > rank(worst) {
+				worst = class
+			}
+		}
+		if best == "" || rank(worst) < rank(best) {
+			best = worst
+		}
+	}
+	return best
+}
+
+func classifyOne(license string, overrides map[string]string) string {
+	id := strings.ToLower(strings.TrimSpace(license))
+	if class, ok := overrides[id]; ok {
+		return class
+	}
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-only")
+	id = strings.TrimSuffix(id, "-or-later")
+	if class, ok := overrides[id]; ok {
+		return class
+	}
+	if class, ok := knownClasses[id]; ok {
+		return class
+	}
+	for _, p := range classPrefixes {
+		if strings.HasPrefix(id, p.prefix) {
+			return p.class
+		}
+	}
+	return classUnknown
+}
+
+// Dependency is a dependency found by a license scanner.
+type Dependency struct {
+	Name    string
+	Version string
+	License string
+	// URL is where the dependency's license was found, if known.
+	URL   string
+	Class string
+	// Violation is whether the dependency's license is not allowed.
+	Violation bool
+}
+
+// parseCSV reads the output of `go-licenses csv`, which is a module, the URL of its license and
+// the license's identifier on each line.
+func parseCSV(content []byte) ([]Dependency, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	var deps []Dependency
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return deps, nil
+		}
+		if err != nil {
+			return deps, err
+		}
+		if len(record) < 3 {
+			return deps, fmt.Errorf("expected a module, URL and license, got %q", strings.Join(record, ","))
+		}
+		deps = append(deps, Dependency{Name: record[0], URL: record[1], License: record[2]})
+	}
+}
+
+// fossaDependency is a dependency in a FOSSA attribution report.
+type fossaDependency struct {
+	Dependency string `json:"dependency"`
+	Version    string `json:"version"`
+	ProjectURL string `json:"projectUrl"`
+	Licenses   []struct {
+		Name string `json:"name"`
+	} `json:"licenses"`
+}
+
+// parseFOSSA reads a FOSSA attribution report, as exported by `fossa report attribution --json`.
+// A dependency with several licenses is bound by all of them.
+func parseFOSSA(content []byte) ([]Dependency, error) {
+	var report struct {
+		DirectDependencies []fossaDependency `json:"directDependencies"`
+		DeepDependencies   []fossaDependency `json:"deepDependencies"`
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return nil, err
+	}
+	var deps []Dependency
+	for _, d := range append(report.DirectDependencies, report.DeepDependencies...) {
+		var licenses []string
+		for _, l := range d.Licenses {
+			licenses = append(licenses, l.Name)
+		}
+		deps = append(deps, Dependency{
+			Name:    d.Dependency,
+			Version: d.Version,
+			URL:     d.ProjectURL,
+			License: strings.Join(licenses, " AND "),
+		})
+	}
+	return deps, nil
+}
+
+// ClassGroup is the dependencies whose licenses are of a class.
+type ClassGroup struct {
+	Class string
+	// Violation is whether the class is not allowed.
+	Violation    bool
+	Dependencies []Dependency
+}
+
+// group classifies dependencies and groups them by class, most encumbering first. Dependencies
+// reported more than once are only shown once.
+func group(deps []Dependency, disallowed map[string]bool, overrides map[string]string) []ClassGroup {
+	byClass := map[string][]Dependency{}
+	seen := map[string]bool{}
+	for _, d := range deps {
+		key := d.Name + "@" + d.Version + " " + d.License
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if strings.TrimSpace(d.License) == "" {
+			d.Class = classUnknown
+		} else {
+			d.Class = classify(d.License, overrides)
+		}
+		d.Violation = disallowed[d.Class]
+		if !strings.HasPrefix(d.URL, "https://") && !strings.HasPrefix(d.URL, "http://") {
+			// go-licenses reports licenses it cannot link to as "Unknown".
+			d.URL = ""
+		}
+		byClass[d.Class] = append(byClass[d.Class], d)
+	}
+	var groups []ClassGroup
+	for _, class := range classOrder {
+		deps := byClass[class]
+		if len(deps) == 0 {
+			continue
+		}
+		sort.Slice(deps, func(i, j int) bool {
+			if deps[i].Name != deps[j].Name {
+				return deps[i].Name < deps[j].Name
+			}
+			return deps[i].Version < deps[j].Version
+		})
+		groups = append(groups, ClassGroup{Class: class, Violation: disallowed[class], Dependencies: deps})
+	}
+	return groups
+}
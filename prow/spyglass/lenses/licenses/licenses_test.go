@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package licenses
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	overrides := map[string]string{"custom-1.0": classNotice}
+	testCases := []struct {
+		license  string
+		expected string
+	}{
+		{"MIT", classNotice},
+		{"apache-2.0", classNotice},
+		{"GPL-2.0-only", classRestricted},
+		{"LGPL-2.1+", classRestricted},
+		{"AGPL-3.0-or-later", classForbidden},
+		{"CC-BY-NC-4.0", classForbidden},
+		{"CC-BY-4.0", classNotice},
+		{"Custom-1.0", classNotice},
+		{"Proprietary", classUnknown},
+		{"MIT OR GPL-3.0", classNotice},
+		{"MIT AND MPL-2.0", classReciprocal},
+		{"(MIT AND GPL-2.0) OR CC0-1.0", classUnencumbered},
+	}
+	for _, tc := range testCases {
+		if class := classify(tc.license, overrides); class != tc.expected {
+			t.Errorf("expected %s to be %s, got %s", tc.license, tc.expected, class)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	deps, err := parseCSV([]byte(`github.com/a/b,https://github.com/a/b/blob/master/LICENSE,MIT
+golang.org/x/net,https://go.googlesource.com/net/+/refs/heads/master/LICENSE,BSD-3-Clause
+example.com/private,Unknown,Unknown
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deps) != 3 || deps[0] != (Dependency{Name: "github.com/a/b", URL: "https://github.com/a/b/blob/master/LICENSE", License: "MIT"}) {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+	if _, err := parseCSV([]byte("github.com/a/b,MIT\n")); err == nil {
+		t.Error("expected an error for a line without a URL")
+	}
+}
+
+func TestParseFOSSA(t *testing.T) {
+	deps, err := parseFOSSA([]byte(`{
+  "directDependencies": [{"dependency": "lodash", "version": "4.17.15", "licenses": [{"name": "MIT"}]}],
+  "deepDependencies": [{"dependency": "dual", "version": "1.0.0", "projectUrl": "https://example.com/dual", "licenses": [{"name": "MIT"}, {"name": "GPL-2.0"}]}]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deps) != 2 || deps[1].License != "MIT AND GPL-2.0" || deps[1].URL != "https://example.com/dual" {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	deps := []Dependency{
+		{Name: "b", License: "MIT"},
+		{Name: "a", License: "MIT"},
+		{Name: "a", License: "MIT"},
+		{Name: "c", License: "GPL-3.0"},
+		{Name: "d", License: "", URL: "Unknown"},
+	}
+	groups := group(deps, map[string]bool{classUnknown: true}, nil)
+	if len(groups) != 3 || groups[0].Class != classUnknown || groups[1].Class != classRestricted || groups[2].Class != classNotice {
+		t.Fatalf("expected unknown, restricted and notice groups, got %v", groups)
+	}
+	if !groups[0].Violation || !groups[0].Dependencies[0].Violation || groups[0].Dependencies[0].URL != "" {
+		t.Errorf("expected the unknown license to be a violation without a link, got %v", groups[0])
+	}
+	if notice := groups[2].Dependencies; len(notice) != 2 || notice[0].Name != "a" || notice[1].Name != "b" {
+		t.Errorf("expected deduplicated, sorted notice dependencies, got %v", notice)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	disallowed, overrides, err := parseConfig(nil)
+	if err != nil || !disallowed[classForbidden] || !disallowed[classUnknown] || disallowed[classRestricted] {
+		t.Errorf("unexpected defaults: %v, %v", disallowed, err)
+	}
+	disallowed, overrides, err = parseConfig(json.RawMessage(`{"disallowed_classes": ["restricted"], "license_classes": {"Custom": "notice"}}`))
+	if err != nil || !disallowed[classRestricted] || disallowed[classForbidden] || overrides["custom"] != classNotice {
+		t.Errorf("unexpected config: %v, %v, %v", disallowed, overrides, err)
+	}
+	if _, _, err := parseConfig(json.RawMessage(`{"license_classes": {"Custom": "permissive"}}`)); err == nil {
+		t.Error("expected an error for an unknown class")
+	}
+}
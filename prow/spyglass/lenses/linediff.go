@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import "fmt"
+
+// This file implements a line-based diff using Myers' O(ND) algorithm.
+// See "An O(ND) Difference Algorithm and Its Variations", Eugene W. Myers, 1986.
+
+// Operations on lines, which are also used as CSS classes.
+const (
+	opEqual  = "equal"
+	opDelete = "delete"
+	opInsert = "insert"
+)
+
+// edit is a single line of a diff.
+type edit struct {
+	op string
+	// oldLine and newLine are 1-based line numbers, or 0 if the line isn't in that file.
+	oldLine int
+	newLine int
+	text    string
+}
+
+// errTooDifferent is returned when the inputs differ by more than the allowed number of edits.
+type errTooDifferent struct {
+	maxEdits int
+}
+
+func (e errTooDifferent) Error() string {
+	return fmt.Sprintf("the files differ by more than %d lines", e.maxEdits)
+}
+
+// editLines returns the edits that turn a into b, giving up if more than maxEdits are needed.
+func editLines(a, b []string, maxEdits int) ([]edit, error) {
+	// Common prefixes and suffixes are cheap to find and typically make up most of the input.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{op: opEqual, oldLine: i + 1, newLine: i + 1, text: a[i]})
+	}
+	middle, err := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], maxEdits)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range middle {
+		if e.oldLine != 0 {
+			e.oldLine += prefix
+		}
+		if e.newLine != 0 {
+			e.newLine += prefix
+		}
+		edits = append(edits, e)
+	}
+	for i := 0; i < suffix; i++ {
+		oldIndex, newIndex := len(a)-suffix+i, len(b)-suffix+i
+		edits = append(edits, edit{op: opEqual, oldLine: oldIndex + 1, newLine: newIndex + 1, text: a[oldIndex]})
+	}
+	return edits, nil
+}
+
+func myers(a, b []string, maxEdits int) ([]edit, error) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil, nil
+	}
+	if max > maxEdits {
+		max = maxEdits
+	}
+	// v[k+offset] is the furthest x reached on diagonal k. trace holds a copy of v for each d,
+	// which is what lets us walk the path back afterwards.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d, k), nil
+			}
+		}
+	}
+	return nil, errTooDifferent{maxEdits: maxEdits}
+}
+
+func backtrack(a, b []string, trace [][]int, offset, d, k int) []edit {
+	x, y := len(a), len(b)
+	var reversed []edit
+	for ; d > 0; d-- {
+		v := trace[d]
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{op: opEqual, oldLine: x + 1, newLine: y + 1, text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, edit{op: opInsert, newLine: y + 1, text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, edit{op: opDelete, oldLine: x + 1, text: a[x]})
+		}
+		k = prevK
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, edit{op: opEqual, oldLine: x + 1, newLine: y + 1, text: a[x]})
+	}
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+// LineDiff is a unified diff between two lists of lines.
+type LineDiff struct {
+	Hunks   []DiffHunk
+	Added   int
+	Removed int
+}
+
+// DiffLines diffs two lists of lines like diff -u, with the given number of lines of context
+// around each change. It gives up if more than maxEdits lines would need to change.
+func DiffLines(a, b []string, context, maxEdits int) (*LineDiff, error) {
+	edits, err := editLines(a, b, maxEdits)
+	if err != nil {
+		return nil, err
+	}
+	diff := &LineDiff{Hunks: hunks(edits, context)}
+	for _, e := range edits {
+		switch e.op {
+		case opInsert:
+			diff.Added++
+		case opDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+// DiffHunk is a group of nearby changes along with their surrounding context.
+type DiffHunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+	Lines              []DiffLine
+}
+
+// DiffLine is a single line of a hunk.
+type DiffLine struct {
+	Op      string
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+// hunks groups edits into hunks with the given number of lines of context, like diff -u.
+func hunks(edits []edit, context int) []DiffHunk {
+	var result []DiffHunk
+	i := 0
+	for i < len(edits) {
+		// Find the next change.
+		for i < len(edits) && edits[i].op == opEqual {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk until we find a run of more than 2*context unchanged lines.
+		end := i
+		for end < len(edits) {
+			if edits[end].op != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == opEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end += context
+				if end > run {
+					end = run
+				}
+				break
+			}
+			end = run
+		}
+		var h DiffHunk
+		for _, e := range edits[start:end] {
+			h.Lines = append(h.Lines, DiffLine{Op: e.op, OldLine: e.oldLine, NewLine: e.newLine, Text: e.text})
+			if e.op != opInsert {
+				if h.OldStart == 0 {
+					h.OldStart = e.oldLine
+				}
+				h.OldCount++
+			}
+			if e.op != opDelete {
+				if h.NewStart == 0 {
+					h.NewStart = e.newLine
+				}
+				h.NewCount++
+			}
+		}
+		result = append(result, h)
+		i = end
+	}
+	return result
+}
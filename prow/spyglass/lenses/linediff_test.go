@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// renderEdits formats edits like the body of a unified diff.
+func renderEdits(edits []edit) string {
+	var lines []string
+	for _, e := range edits {
+		switch e.op {
+		case opEqual:
+			lines = append(lines, " "+e.text)
+		case opDelete:
+			lines = append(lines, "-"+e.text)
+		case opInsert:
+			lines = append(lines, "+"+e.text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestEditLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "identical",
+			a:        "a b c",
+			b:        "a b c",
+			expected: " a\n b\n c",
+		},
+		{
+			name:     "insertion",
+			a:        "a c",
+			b:        "a b c",
+			expected: " a\n+b\n c",
+		},
+		{
+			name:     "deletion",
+			a:        "a b c",
+			b:        "a c",
+			expected: " a\n-b\n c",
+		},
+		{
+			name:     "replacement",
+			a:        "a b c d",
+			b:        "a x c y",
+			expected: " a\n-b\n+x\n c\n-d\n+y",
+		},
+		{
+			name:     "everything new",
+			a:        "",
+			b:        "a b",
+			expected: "+a\n+b",
+		},
+		{
+			name:     "classic example",
+			a:        "a b c a b b a",
+			b:        "c b a b a c",
+			expected: "-a\n-b\n c\n+b\n a\n b\n-b\n a\n+c",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edits, err := editLines(strings.Fields(tc.a), strings.Fields(tc.b), 100)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := renderEdits(edits); actual != tc.expected {
+				t.Errorf("expected diff:\n%s\ngot:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEditLinesLineNumbers(t *testing.T) {
+	edits, err := editLines([]string{"a", "b", "c"}, []string{"a", "x", "c"}, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []edit{
+		{op: opEqual, oldLine: 1, newLine: 1, text: "a"},
+		{op: opDelete, oldLine: 2, text: "b"},
+		{op: opInsert, newLine: 2, text: "x"},
+		{op: opEqual, oldLine: 3, newLine: 3, text: "c"},
+	}
+	if !reflect.DeepEqual(edits, expected) {
+		t.Errorf("expected %#v, got %#v", expected, edits)
+	}
+}
+
+func TestEditLinesTooDifferent(t *testing.T) {
+	if _, err := editLines(strings.Fields("a b c d"), strings.Fields("w x y z"), 3); err == nil {
+		t.Error("expected an error when the files differ by more than the limit")
+	}
+}
+
+func TestHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		a = append(a, string(rune('a'+i)))
+	}
+	b = append(b, a...)
+	b[2] = "changed"
+	b[4] = "changed"
+	b[17] = "changed"
+	edits, err := editLines(a, b, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hs := hunks(edits, 3)
+	if len(hs) != 2 {
+		t.Fatalf("expected nearby changes to share a hunk, giving 2 hunks, got %d", len(hs))
+	}
+	if h := hs[0]; h.OldStart != 1 || h.OldCount != 8 || h.NewStart != 1 || h.NewCount != 8 {
+		t.Errorf("expected first hunk @@ -1,8 +1,8 @@, got @@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+	}
+	if h := hs[1]; h.OldStart != 15 || h.OldCount != 6 || h.NewStart != 15 || h.NewCount != 6 {
+		t.Errorf("expected second hunk @@ -15,6 +15,6 @@, got @@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	diff, err := DiffLines(strings.Fields("a b c d e f"), strings.Fields("a x c d e f g"), 1, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff.Added != 2 || diff.Removed != 1 {
+		t.Errorf("expected +2 -1, got +%d -%d", diff.Added, diff.Removed)
+	}
+	if len(diff.Hunks) != 2 {
+		t.Errorf("expected changes more than two lines of context apart to be in separate hunks, got %d hunks", len(diff.Hunks))
+	}
+}
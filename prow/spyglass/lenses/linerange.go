@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LineIndexSuffix is appended to the path of an artifact to name its line index sidecar. The
+// sidecar is a JSON LineIndex, letting lines deep into a large artifact be read without scanning
+// it from the top.
+const LineIndexSuffix = ".lineidx"
+
+// LineIndex records where every Interval'th line of an artifact starts.
+type LineIndex struct {
+	// Interval is the number of lines between indexed lines.
+	Interval int64 `json:"interval"`
+	// Offsets holds the byte offset at which lines 1, Interval+1, 2*Interval+1 and so on start.
+	Offsets []int64 `json:"offsets"`
+}
+
+// nearest returns the closest indexed line at or before line, and its byte offset.
+func (idx LineIndex) nearest(line int64) (int64, int64) {
+	if idx.Interval < 1 || len(idx.Offsets) == 0 {
+		return 1, 0
+	}
+	i := (line - 1) / idx.Interval
+	if i >= int64(len(idx.Offsets)) {
+		i = int64(len(idx.Offsets)) - 1
+	}
+	return i*idx.Interval + 1, idx.Offsets[i]
+}
+
+// IndexedArtifact is an artifact accompanied by its line index sidecar.
+type IndexedArtifact struct {
+	Artifact
+	Index Artifact
+}
+
+// AttachLineIndexes pairs artifacts with the line index sidecars found among their siblings, so
+// that LineRange can use them.
+func AttachLineIndexes(artifacts []Artifact, siblings []*SiblingArtifact) []Artifact {
+	indexes := map[string]Artifact{}
+	for _, s := range siblings {
+		indexes[s.JobPath()] = s.Artifact
+	}
+	attached := make([]Artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		if index, ok := indexes[a.JobPath()+LineIndexSuffix]; ok {
+			a = &IndexedArtifact{Artifact: a, Index: index}
+		}
+		attached = append(attached, a)
+	}
+	return attached
+}
+
+// LineRange reads lines startLine to endLine inclusive of an artifact, numbering lines from 1.
+// Fewer lines are returned if the artifact ends first. Lines are split and collapsed as by
+// LastNLines. Reading starts from the nearest line recorded in the artifact's line index if it is
+// an IndexedArtifact, and from the top of the artifact otherwise.
+func LineRange(a Artifact, startLine, endLine int64) ([]string, error) {
+	if startLine < 1 || endLine < startLine {
+		return nil, fmt.Errorf("invalid line range %d-%d", startLine, endLine)
+	}
+	a, line, offset := nearestIndexedLine(a, startLine)
+	lines, err := scanLineRange(a, line, offset, startLine, endLine)
+	if err == ErrGzipOffsetRead && offset > 0 {
+		// Gzipped artifacts can only be read from the top.
+		lines, err = scanLineRange(a, 1, 0, startLine, endLine)
+	}
+	return lines, err
+}
+
+// LineOffset returns the byte offset at which a line of an artifact starts, numbering lines
+// from 1, or io.EOF if the artifact ends first. Like LineRange, it reads from the nearest line
+// recorded in the artifact's line index if it is an IndexedArtifact.
+func LineOffset(a Artifact, line int64) (int64, error) {
+	if line < 1 {
+		return 0, fmt.Errorf("invalid line %d", line)
+	}
+	a, start, offset := nearestIndexedLine(a, line)
+	lineOffset, err := scanLineOffset(a, start, offset, line)
+	if err == ErrGzipOffsetRead && offset > 0 {
+		// Gzipped artifacts can only be read from the top.
+		lineOffset, err = scanLineOffset(a, 1, 0, line)
+	}
+	return lineOffset, err
+}
+
+// nearestIndexedLine returns the closest line at or before line recorded in the line index of an
+// IndexedArtifact, with its byte offset and the artifact it indexes. Other artifacts are read
+// from the top.
+func nearestIndexedLine(a Artifact, line int64) (Artifact, int64, int64) {
+	indexed, ok := a.(*IndexedArtifact)
+	if !ok {
+		return a, 1, 0
+	}
+	index, err := readLineIndex(indexed.Index)
+	if err != nil {
+		logrus.WithError(err).WithField("artifact", indexed.JobPath()).Info("Ignoring unreadable line index.")
+		return indexed.Artifact, 1, 0
+	}
+	start, offset := index.nearest(line)
+	return indexed.Artifact, start, offset
+}
+
+// scanLineOffset finds the byte offset of a line of an artifact, scanning from line start, which
+// starts at offset.
+func scanLineOffset(a Artifact, start, offset, line int64) (int64, error) {
+	size, err := a.Size()
+	if err != nil {
+		return 0, fmt.Errorf("error getting artifact size: %v", err)
+	}
+	r := bufio.NewReader(&chunkedReader{artifact: a, offset: offset, end: size, limit: math.MaxInt64})
+	for start < line {
+		text, err := r.ReadSlice('\n')
+		offset += int64(len(text))
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		start++
+	}
+	return offset, nil
+}
+
+// readLineIndex reads a line index sidecar.
+func readLineIndex(a Artifact) (LineIndex, error) {
+	var index LineIndex
+	content, err := a.ReadAll()
+	if err != nil {
+		return index, fmt.Errorf("failed to read line index: %v", err)
+	}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return index, fmt.Errorf("failed to parse line index: %v", err)
+	}
+	if !sort.SliceIsSorted(index.Offsets, func(i, j int) bool { return index.Offsets[i] < index.Offsets[j] }) {
+		return index, fmt.Errorf("line index offsets are not in order")
+	}
+	return index, nil
+}
+
+// scanLineRange reads lines startLine to endLine of an artifact, scanning from line, which starts
+// at offset.
+func scanLineRange(a Artifact, line, offset, startLine, endLine int64) ([]string, error) {
+	size, err := a.Size()
+	if err != nil {
+		return nil, fmt.Errorf("error getting artifact size: %v", err)
+	}
+	if offset >= size {
+		return nil, nil
+	}
+	// Gzipped artifacts have to be read in full, and Size gives their compressed size, so they
+	// cannot be limited to it.
+	r := bufio.NewReader(&chunkedReader{artifact: a, offset: offset, end: size, limit: math.MaxInt64})
+	var lines []string
+	for ; line <= endLine; line++ {
+		text, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(text) == 0 && err == io.EOF {
+			break
+		}
+		if line >= startLine {
+			lines = append(lines, CollapseCarriageReturns(string(bytes.TrimSuffix(text, []byte("\n")))))
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// offsetArtifact records the offset of the first read made of it.
+type offsetArtifact struct {
+	FakeArtifact
+	firstOffset int64
+	read        bool
+}
+
+func (oa *offsetArtifact) ReadAt(b []byte, off int64) (int, error) {
+	if !oa.read {
+		oa.firstOffset = off
+		oa.read = true
+	}
+	return oa.FakeArtifact.ReadAt(b, off)
+}
+
+func TestLineRange(t *testing.T) {
+	var lines []string
+	var offsets []int64
+	var offset int64
+	for i := 1; i <= 100; i++ {
+		if (i-1)%10 == 0 {
+			offsets = append(offsets, offset)
+		}
+		line := fmt.Sprintf("line %d", i)
+		lines = append(lines, line)
+		offset += int64(len(line)) + 1
+	}
+	content := []byte(strings.Join(lines, "\n") + "\n")
+	index, err := json.Marshal(LineIndex{Interval: 10, Offsets: offsets})
+	if err != nil {
+		t.Fatalf("failed to marshal line index: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		index          []byte
+		start, end     int64
+		expected       []string
+		expectedOffset int64
+		expectErr      bool
+	}{
+		{
+			name:     "lines are read from the top without an index",
+			start:    45,
+			end:      47,
+			expected: []string{"line 45", "line 46", "line 47"},
+		},
+		{
+			name:           "lines are read from the nearest indexed line",
+			index:          index,
+			start:          45,
+			end:            47,
+			expected:       []string{"line 45", "line 46", "line 47"},
+			expectedOffset: offsets[4],
+		},
+		{
+			name:           "range past the end of the artifact is cut short",
+			index:          index,
+			start:          99,
+			end:            120,
+			expected:       []string{"line 99", "line 100"},
+			expectedOffset: offsets[9],
+		},
+		{
+			name:     "unreadable index is ignored",
+			index:    []byte("not json"),
+			start:    1,
+			end:      2,
+			expected: []string{"line 1", "line 2"},
+		},
+		{
+			name:      "inverted range is rejected",
+			start:     5,
+			end:       4,
+			expectErr: true,
+		},
+		{
+			name:      "line numbers start at one",
+			start:     0,
+			end:       4,
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := &offsetArtifact{FakeArtifact: FakeArtifact{path: "build-log.txt", content: content, sizeLimit: 500e6}}
+			var artifact Artifact = log
+			if tc.index != nil {
+				sidecar := NewSiblingArtifact(&FakeArtifact{path: "build-log.txt" + LineIndexSuffix, content: tc.index, sizeLimit: 500e6})
+				artifact = AttachLineIndexes([]Artifact{log}, []*SiblingArtifact{sidecar})[0]
+			}
+			actual, err := LineRange(artifact, tc.start, tc.end)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected lines %q, got %q", tc.expected, actual)
+			}
+			if log.firstOffset != tc.expectedOffset {
+				t.Errorf("expected reading to start at %d, started at %d", tc.expectedOffset, log.firstOffset)
+			}
+		})
+	}
+}
+
+func TestLineOffset(t *testing.T) {
+	var lines []string
+	var offsets []int64
+	var offset int64
+	for i := 1; i <= 100; i++ {
+		offsets = append(offsets, offset)
+		line := fmt.Sprintf("line %d", i)
+		lines = append(lines, line)
+		offset += int64(len(line)) + 1
+	}
+	content := []byte(strings.Join(lines, "\n"))
+	index, err := json.Marshal(LineIndex{Interval: 10, Offsets: []int64{offsets[0], offsets[10], offsets[20]}})
+	if err != nil {
+		t.Fatalf("failed to marshal line index: %v", err)
+	}
+	log := &offsetArtifact{FakeArtifact: FakeArtifact{path: "build-log.txt", content: content, sizeLimit: 500e6}}
+	sidecar := NewSiblingArtifact(&FakeArtifact{path: "build-log.txt" + LineIndexSuffix, content: index, sizeLimit: 500e6})
+	artifact := AttachLineIndexes([]Artifact{log}, []*SiblingArtifact{sidecar})[0]
+
+	for _, line := range []int64{1, 15, 25, 100} {
+		actual, err := LineOffset(artifact, line)
+		if err != nil {
+			t.Fatalf("line %d: unexpected error: %v", line, err)
+		}
+		if expected := offsets[line-1]; actual != expected {
+			t.Errorf("line %d: expected offset %d, got %d", line, expected, actual)
+		}
+	}
+	if _, err := LineOffset(artifact, 102); err != io.EOF {
+		t.Errorf("expected io.EOF for a line past the end, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package links provides a Spyglass lens that lists links to external pages published by a job.
+package links
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name  = "links"
+	title = "Links"
+	// Links are shown near the top of the page, since they are easily missed otherwise.
+	priority = 2
+
+	// maxLinkFileBytes is how much of each link file is read.
+	maxLinkFileBytes = 64 << 10
+)
+
+// Lens is the implementation of a link-listing Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// Link is a link to an external page, such as a dashboard or a cloud console.
+type Link struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	// Host is the host the link points to, shown so that users know where it leads.
+	Host string `json:"-"`
+}
+
+type linksView struct {
+	Errors []string
+	Links  []Link
+}
+
+// Body lists the links in each link file.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	view := linksView{}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		content, err := a.ReadAtMost(maxLinkFileBytes)
+		if err != nil && err != io.EOF {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		var links []Link
+		if strings.HasSuffix(a.JobPath(), ".json") {
+			links, err = parseJSON(content)
+		} else {
+			links, err = parseText(path.Base(a.JobPath()), content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err))
+		}
+		view.Links = append(view.Links, links...)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// parseText reads a link file in the artifacts/links/ convention: the file is named for the
+// link, its first line is the URL and any further lines describe it.
+func parseText(fileName string, content []byte) ([]Link, error) {
+	linkTitle := strings.TrimSuffix(fileName, path.Ext(fileName))
+	linkTitle = strings.Replace(linkTitle, "_", " ", -1)
+	lines := strings.SplitN(strings.TrimSpace(string(content)), "\n", 2)
+	link := Link{Title: linkTitle, URL: strings.TrimSpace(lines[0])}
+	if len(lines) > 1 {
+		link.Description = strings.TrimSpace(lines[1])
+	}
+	if err := validate(&link); err != nil {
+		return nil, err
+	}
+	return []Link{link}, nil
+}
+
+// parseJSON reads a links.json file, which is a list of links or an object with a list of
+// links under "links".
+func parseJSON(content []byte) ([]Link, error) {
+	var links []Link
+	if err := json.Unmarshal(content, &links); err != nil {
+		var wrapped struct {
+			Links []Link `json:"links"`
+		}
+		if json.Unmarshal(content, &wrapped) != nil {
+			return nil, err
+		}
+		links = wrapped.Links
+	}
+	var valid []Link
+	var problems []string
+	for _, link := range links {
+		if err := validate(&link); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		valid = append(valid, link)
+	}
+	if len(problems) != 0 {
+		return valid, fmt.Errorf("invalid links: %s", strings.Join(problems, "; "))
+	}
+	return valid, nil
+}
+
+// validate checks that a link is to an http or https URL, and fills in its host and a title if
+// it has none.
+func validate(link *Link) error {
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid URL: %v", link.URL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", link.URL)
+	}
+	link.Host = u.Host
+	if link.Title == "" {
+		link.Title = u.Host
+	}
+	return nil
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
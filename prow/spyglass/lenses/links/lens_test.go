@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package links
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		content  string
+		expected []Link
+		err      bool
+	}{
+		{
+			name:     "URL only",
+			fileName: "Grafana_dashboard.txt",
+			content:  "https://grafana.example.com/d/abc\n",
+			expected: []Link{{Title: "Grafana dashboard", URL: "https://grafana.example.com/d/abc", Host: "grafana.example.com"}},
+		},
+		{
+			name:     "with a description",
+			fileName: "logs.txt",
+			content:  "https://console.cloud.google.com/logs?project=p\nLogs of the test cluster.\nKept for 30 days.",
+			expected: []Link{{Title: "logs", URL: "https://console.cloud.google.com/logs?project=p", Description: "Logs of the test cluster.\nKept for 30 days.", Host: "console.cloud.google.com"}},
+		},
+		{
+			name:     "not a web URL",
+			fileName: "evil.txt",
+			content:  "javascript:alert(1)",
+			err:      true,
+		},
+	}
+	for _, tc := range testCases {
+		links, err := parseText(tc.fileName, []byte(tc.content))
+		if (err != nil) != tc.err {
+			t.Errorf("%s: expected error %t, got %v", tc.name, tc.err, err)
+		}
+		if !reflect.DeepEqual(links, tc.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tc.name, tc.expected, links)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	expected := []Link{
+		{Title: "Dashboard", URL: "https://dash.example.com/", Description: "Cluster metrics", Host: "dash.example.com"},
+		{Title: "kibana.example.com", URL: "http://kibana.example.com/app", Host: "kibana.example.com"},
+	}
+	list := `[{"title": "Dashboard", "url": "https://dash.example.com/", "description": "Cluster metrics"}, {"url": "http://kibana.example.com/app"}, {"title": "Bad", "url": "file:///etc/passwd"}]`
+	links, err := parseJSON([]byte(list))
+	if err == nil {
+		t.Error("expected an error for the file URL")
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("expected %#v, got %#v", expected, links)
+	}
+
+	wrapped := `{"links": [{"title": "Dashboard", "url": "https://dash.example.com/", "description": "Cluster metrics"}]}`
+	links, err = parseJSON([]byte(wrapped))
+	if err != nil || !reflect.DeepEqual(links, expected[:1]) {
+		t.Errorf("expected %#v, got %#v and %v", expected[:1], links, err)
+	}
+
+	if _, err := parseJSON([]byte(`{"links": `)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// LogIndexSuffix is appended to the path of a log to name its log index sidecar. The sidecar is
+// a JSON LogIndex, generated when the log is uploaded, letting lenses show the failures and
+// sections of the log without scanning it.
+const LogIndexSuffix = ".logidx"
+
+// LogIndex records where the failures and sections of a log are.
+type LogIndex struct {
+	// Size is the size of the log indexed, in bytes. The index is stale, and ignored, if the log
+	// is not that size.
+	Size int64 `json:"size"`
+	// Errors are the lines of the log that matched error patterns, in order.
+	Errors []ErrorMatch `json:"errors"`
+	// Sections are the sections the log is split into, in order.
+	Sections []IndexedSection `json:"sections"`
+}
+
+// IndexedSection is a section of an indexed log.
+type IndexedSection struct {
+	// Title is the title of the section, or empty for lines outside of titled sections.
+	Title string `json:"title,omitempty"`
+	// StartLine and EndLine are the first and last lines of the section, counting from 1.
+	StartLine int64 `json:"start_line"`
+	EndLine   int64 `json:"end_line"`
+	// Offset is the byte offset the section starts at.
+	Offset int64 `json:"offset"`
+	// Seconds is how long the section took to log, from its first timestamped line to its
+	// last, or 0 if fewer than two of its lines are timestamped.
+	Seconds float64 `json:"seconds,omitempty"`
+}
+
+// ReadLogIndex reads the log index sidecar of an artifact from among its siblings. It returns
+// nil if there is none, or if it is stale. Indexes of artifacts transcoded to UTF-8 are ignored,
+// since their offsets are of the artifact as uploaded.
+func ReadLogIndex(a Artifact, siblings []*SiblingArtifact) (*LogIndex, error) {
+	if _, ok := a.(*decodedArtifact); ok {
+		return nil, nil
+	}
+	var sidecar Artifact
+	for _, s := range siblings {
+		if s.JobPath() == a.JobPath()+LogIndexSuffix {
+			sidecar = s.Artifact
+		}
+	}
+	if sidecar == nil {
+		return nil, nil
+	}
+	content, err := sidecar.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log index: %v", err)
+	}
+	var index LogIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse log index: %v", err)
+	}
+	if !sort.SliceIsSorted(index.Errors, func(i, j int) bool { return index.Errors[i].Line < index.Errors[j].Line }) {
+		return nil, fmt.Errorf("log index errors are not in order")
+	}
+	if !sort.SliceIsSorted(index.Sections, func(i, j int) bool { return index.Sections[i].StartLine < index.Sections[j].StartLine }) {
+		return nil, fmt.Errorf("log index sections are not in order")
+	}
+	size, err := a.Size()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact size: %v", err)
+	}
+	if size != index.Size {
+		return nil, nil
+	}
+	return &index, nil
+}
+
+// Summary summarizes the failures recorded in a log index, counting the lines that matched each
+// pattern in the order the patterns first matched.
+func (idx LogIndex) Summary() ErrorSummary {
+	var summary ErrorSummary
+	if len(idx.Errors) == 0 {
+		return summary
+	}
+	first, last := idx.Errors[0], idx.Errors[len(idx.Errors)-1]
+	summary.First, summary.Last = &first, &last
+	summary.Total = len(idx.Errors)
+	counts := map[string]int{}
+	for _, e := range idx.Errors {
+		if counts[e.Pattern] == 0 {
+			summary.Counts = append(summary.Counts, PatternCount{Pattern: e.Pattern})
+		}
+		counts[e.Pattern]++
+	}
+	for i, c := range summary.Counts {
+		summary.Counts[i].Count = counts[c.Pattern]
+	}
+	return summary
+}
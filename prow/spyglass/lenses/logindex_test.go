@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadLogIndex(t *testing.T) {
+	log := &FakeArtifact{path: "build-log.txt", content: []byte("Starting.\nERROR: broke\n"), sizeLimit: 500e6}
+	testCases := []struct {
+		name        string
+		index       string
+		expected    *LogIndex
+		expectedErr bool
+	}{
+		{
+			name: "no index",
+		},
+		{
+			name:  "index is read",
+			index: `{"size": 23, "errors": [{"pattern": "error", "line": 2, "offset": 10}], "sections": [{"start_line": 1, "end_line": 2, "offset": 0}]}`,
+			expected: &LogIndex{
+				Size:     23,
+				Errors:   []ErrorMatch{{Pattern: "error", Line: 2, Offset: 10}},
+				Sections: []IndexedSection{{StartLine: 1, EndLine: 2}},
+			},
+		},
+		{
+			name:  "stale index is ignored",
+			index: `{"size": 10, "errors": [{"pattern": "error", "line": 2, "offset": 10}]}`,
+		},
+		{
+			name:        "invalid index",
+			index:       `{"size": 23, "errors": [{"line": 2}, {"line": 1}]}`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var siblings []*SiblingArtifact
+			if tc.index != "" {
+				siblings = append(siblings, NewSiblingArtifact(&FakeArtifact{path: "build-log.txt" + LogIndexSuffix, content: []byte(tc.index), sizeLimit: 500e6}))
+			}
+			index, err := ReadLogIndex(log, siblings)
+			if err != nil != tc.expectedErr {
+				t.Fatalf("expected error: %t, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(index, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, index)
+			}
+		})
+	}
+}
+
+func TestLogIndexSummary(t *testing.T) {
+	index := LogIndex{Errors: []ErrorMatch{
+		{Pattern: "failure", Line: 3},
+		{Pattern: "error", Line: 5},
+		{Pattern: "failure", Line: 8},
+	}}
+	expected := ErrorSummary{
+		First:  &ErrorMatch{Pattern: "failure", Line: 3},
+		Last:   &ErrorMatch{Pattern: "failure", Line: 8},
+		Total:  3,
+		Counts: []PatternCount{{Pattern: "failure", Count: 2}, {Pattern: "error", Count: 1}},
+	}
+	if actual := index.Summary(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if actual := (LogIndex{}).Summary(); !reflect.DeepEqual(actual, ErrorSummary{}) {
+		t.Errorf("expected an empty summary of an index without errors, got %+v", actual)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"strings"
+)
+
+const (
+	// BuildLogPath is the path of the log of a job's test container among its artifacts.
+	BuildLogPath = "build-log.txt"
+	// minNeedleLength is the fewest bytes the line of a failure message looked for in a log can
+	// have, so that short lines such as "FAIL" do not match lines the failure did not log.
+	minNeedleLength = 20
+)
+
+// LogLocation is where a line of a log is: its number, counting from 1, and the byte offset it
+// starts at.
+type LogLocation struct {
+	Line   int64
+	Offset int64
+}
+
+// failureNeedle returns the line of a failure message to look for in a log, which is its first
+// line long enough not to match by chance, or "" if none is.
+func failureNeedle(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); len(line) >= minNeedleLength {
+			return line
+		}
+	}
+	return ""
+}
+
+// failureFinder finds the lines of a log that failure messages were logged on.
+type failureFinder struct {
+	// needles has the messages not found yet by the line of them looked for.
+	needles map[string][]string
+	found   map[string]LogLocation
+}
+
+func newFailureFinder(messages []string) *failureFinder {
+	f := &failureFinder{needles: map[string][]string{}, found: map[string]LogLocation{}}
+	for _, m := range messages {
+		if needle := failureNeedle(m); needle != "" {
+			f.needles[needle] = append(f.needles[needle], m)
+		}
+	}
+	return f
+}
+
+// add looks for the messages not found yet in a line of the log, returning whether any are left.
+func (f *failureFinder) add(text string, line, offset int64) bool {
+	for needle, messages := range f.needles {
+		if strings.Contains(text, needle) {
+			for _, m := range messages {
+				f.found[m] = LogLocation{Line: line, Offset: offset}
+			}
+			delete(f.needles, needle)
+		}
+	}
+	return len(f.needles) > 0
+}
+
+// FindFailures scans the first limit bytes of a log a chunk at a time for the first line that
+// each of some failure messages, such as those of failed JUnit tests, was logged on. Messages
+// are looked for by their first line long enough not to match by chance, and those not found
+// are left out.
+func FindFailures(a Artifact, messages []string, limit int64) (map[string]LogLocation, error) {
+	f := newFailureFinder(messages)
+	if len(f.needles) == 0 {
+		return f.found, nil
+	}
+	if err := scanLines(a, limit, f.add); err != nil {
+		return nil, err
+	}
+	return f.found, nil
+}
+
+// FindFailureLines finds failure messages in the lines of a log already read, like FindFailures.
+func FindFailureLines(lines []string, messages []string) map[string]LogLocation {
+	f := newFailureFinder(messages)
+	var offset int64
+	for i, text := range lines {
+		if len(f.needles) == 0 || !f.add(CollapseCarriageReturns(text), int64(i+1), offset) {
+			break
+		}
+		offset += int64(len(text)) + 1
+	}
+	return f.found
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindFailures(t *testing.T) {
+	log := strings.Join([]string{
+		"=== RUN   TestA",
+		"    a_test.go:12: expected 3 widgets, got 2",
+		"--- FAIL: TestA (0.01s)",
+		"=== RUN   TestB",
+		"    b_test.go:7: unexpected error: connection refused",
+		"FAIL",
+	}, "\n") + "\n"
+	messages := []string{
+		"a_test.go:12: expected 3 widgets, got 2",
+		"\nb_test.go:7: unexpected error: connection refused\nmore detail",
+		"FAIL",
+		"c_test.go:1: this was never logged at all",
+	}
+	expected := map[string]LogLocation{
+		messages[0]: {Line: 2, Offset: int64(strings.Index(log, "    a_test"))},
+		messages[1]: {Line: 5, Offset: int64(strings.Index(log, "    b_test"))},
+	}
+
+	found, err := FindFailures(&FakeArtifact{path: BuildLogPath, content: []byte(log)}, messages, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+	if lines := FindFailureLines(strings.Split(log, "\n"), messages); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected the lines read to match the same, got %v", lines)
+	}
+}
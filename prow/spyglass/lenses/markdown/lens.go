@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package markdown provides a Spyglass lens that renders Markdown artifacts.
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "markdown"
+	title    = "Markdown"
+	priority = 27
+)
+
+// Lens is the implementation of a Markdown-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// Document is a single rendered Markdown artifact.
+type Document struct {
+	Path    string
+	Link    string
+	Content template.HTML
+	Error   string
+}
+
+// Body renders each Markdown artifact. Relative links and images are resolved against the
+// artifact's own location.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var docs []Document
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		doc := Document{Path: a.JobPath(), Link: a.CanonicalLink()}
+		content, err := a.ReadAll()
+		switch {
+		case err == lenses.ErrFileTooLarge:
+			doc.Error = "This file is too large to display."
+		case err != nil:
+			doc.Error = fmt.Sprintf("Failed to read: %v", err)
+		default:
+			base, err := url.Parse(a.CanonicalLink())
+			if err != nil || !base.IsAbs() {
+				base = nil
+			}
+			// Render escapes everything it doesn't generate itself.
+			doc.Content = template.HTML(Render(string(content), base))
+		}
+		docs = append(docs, doc)
+	}
+	return executeTemplate(resourceDir, "body", docs)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,857 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The renderer supports CommonMark's block and inline structure along with GitHub's tables, task
+// lists, strikethrough and autolinks. It is safe by construction: all text is escaped, raw HTML is
+// shown as text, and only links to http, https and mailto URLs and to fragments are kept.
+
+// linkRef is a link reference definition, such as `[label]: https://example.com "Title"`.
+type linkRef struct {
+	dest, title string
+}
+
+type renderer struct {
+	out strings.Builder
+	// base is the URL relative links are resolved against, if known.
+	base  *url.URL
+	refs  map[string]linkRef
+	slugs map[string]int
+}
+
+// Render renders Markdown as HTML. Relative links and images are resolved against base, if set.
+func Render(content string, base *url.URL) string {
+	r := &renderer{base: base, refs: map[string]linkRef{}, slugs: map[string]int{}}
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		lines = append(lines, expandTabs(line))
+	}
+	r.blocks(r.collectRefs(lines), false)
+	return r.out.String()
+}
+
+// expandTabs replaces tabs in a line's indentation with spaces, as they count as four columns.
+func expandTabs(line string) string {
+	var b strings.Builder
+	col := 0
+	for i, c := range line {
+		switch c {
+		case '\t':
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case ' ':
+			b.WriteByte(' ')
+			col++
+		default:
+			b.WriteString(line[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+var (
+	refDefRE      = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*<?([^\s>]+)>?(?:\s+(?:"([^"]*)"|'([^']*)'|\(([^)]*)\)))?\s*$`)
+	fenceRE       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})\\s*([^`\\s]*)[^`]*$")
+	headingRE     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicRE    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextRE      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	quoteRE       = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemRE    = regexp.MustCompile(`^( {0,3})([-*+]|(\d{1,9})[.)])( +|$)`)
+	tableDelimRE  = regexp.MustCompile(`^ {0,3}\|?(?:\s*:?-+:?\s*\|)*\s*:?-+:?\s*\|?\s*$`)
+	indentedRE    = regexp.MustCompile(`^ {4}`)
+	taskRE        = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	escapableRE   = regexp.MustCompile(`^[!"#$%&'()*+,\-./:;<=>?@\[\\\]^_` + "`" + `{|}~]`)
+	autolinkRE    = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+|[^\s<>@]+@[^\s<>@]+\.[^\s<>@]+)>`)
+	bareURLRE     = regexp.MustCompile(`^(?:https?://|www\.)[^\s<]+`)
+	tagRE         = regexp.MustCompile(`<[^>]*>`)
+	whitespaceRE  = regexp.MustCompile(`\s+`)
+	trailingURLRE = regexp.MustCompile(`[.,:;!?"'*_~]+$`)
+)
+
+// collectRefs removes link reference definitions, which may appear anywhere outside code, and
+// records them.
+func (r *renderer) collectRefs(lines []string) []string {
+	var kept []string
+	fence := ""
+	paragraph := false
+	for _, line := range lines {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			kept = append(kept, line)
+			continue
+		}
+		if m := fenceRE.FindStringSubmatch(line); m != nil {
+			fence = m[2]
+			kept = append(kept, line)
+			paragraph = false
+			continue
+		}
+		// Definitions cannot interrupt a paragraph.
+		if m := refDefRE.FindStringSubmatch(line); m != nil && !paragraph {
+			label := normalizeLabel(m[1])
+			if _, ok := r.refs[label]; !ok {
+				r.refs[label] = linkRef{dest: m[2], title: m[3] + m[4] + m[5]}
+			}
+			continue
+		}
+		paragraph = strings.TrimSpace(line) != "" && !indentedRE.MatchString(line)
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(whitespaceRE.ReplaceAllString(strings.TrimSpace(label), " "))
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// interrupts returns whether a line starts a block that ends a paragraph.
+func interrupts(line string) bool {
+	if headingRE.MatchString(line) || thematicRE.MatchString(line) || fenceRE.MatchString(line) || quoteRE.MatchString(line) {
+		return true
+	}
+	// Only lists that start with a bullet or 1 and have content may interrupt a paragraph.
+	if m := listItemRE.FindStringSubmatch(line); m != nil && !isBlank(line[len(m[0]):]) {
+		return m[3] == "" || m[3] == "1"
+	}
+	return false
+}
+
+// blocks renders a sequence of lines as blocks. In tight lists, paragraphs are not wrapped in <p>.
+func (r *renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case fenceRE.MatchString(line):
+			i = r.fencedCode(lines, i)
+		case indentedRE.MatchString(line):
+			i = r.indentedCode(lines, i)
+		case headingRE.MatchString(line):
+			m := headingRE.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+		case thematicRE.MatchString(line):
+			r.out.WriteString("<hr>\n")
+			i++
+		case quoteRE.MatchString(line):
+			i = r.blockquote(lines, i)
+		case listItemRE.MatchString(line):
+			i = r.list(lines, i)
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableDelimRE.MatchString(lines[i+1]) &&
+			len(splitRow(line)) == len(splitRow(lines[i+1])):
+			i = r.table(lines, i)
+		default:
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+func (r *renderer) fencedCode(lines []string, i int) int {
+	m := fenceRE.FindStringSubmatch(lines[i])
+	indent, fence, info := len(m[1]), m[2], m[3]
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+	r.out.WriteString("<pre><code")
+	if info != "" {
+		fmt.Fprintf(&r.out, ` class="language-%s"`, html.EscapeString(info))
+	}
+	r.out.WriteString(">")
+	for _, line := range code {
+		r.out.WriteString(html.EscapeString(line))
+		r.out.WriteString("\n")
+	}
+	r.out.WriteString("</code></pre>\n")
+	return i
+}
+
+func (r *renderer) indentedCode(lines []string, i int) int {
+	var code []string
+	for ; i < len(lines) && (indentedRE.MatchString(lines[i]) || isBlank(lines[i])); i++ {
+		code = append(code, strings.TrimPrefix(lines[i], "    "))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	r.out.WriteString("<pre><code>")
+	for _, line := range code {
+		r.out.WriteString(html.EscapeString(line))
+		r.out.WriteString("\n")
+	}
+	r.out.WriteString("</code></pre>\n")
+	return i
+}
+
+// heading renders a heading with an anchor, named as GitHub would name it.
+func (r *renderer) heading(level int, text string) {
+	content := r.inline(strings.TrimSpace(text))
+	id := r.slug(html.UnescapeString(tagRE.ReplaceAllString(content, "")))
+	fmt.Fprintf(&r.out, `<h%d id="%s"><a class="md-anchor" href="#%s" aria-hidden="true">#</a>%s</h%d>`+"\n", level, id, id, content, level)
+}
+
+func (r *renderer) slug(text string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c), unicode.IsDigit(c), c == '-', c == '_':
+			b.WriteRune(c)
+		case c == ' ':
+			b.WriteByte('-')
+		}
+	}
+	slug := b.String()
+	n := r.slugs[slug]
+	r.slugs[slug]++
+	if n > 0 {
+		slug = fmt.Sprintf("%s-%d", slug, n)
+	}
+	return slug
+}
+
+func (r *renderer) blockquote(lines []string, i int) int {
+	var inner []string
+	for i < len(lines) {
+		line := lines[i]
+		if loc := quoteRE.FindStringIndex(line); loc != nil {
+			inner = append(inner, line[loc[1]:])
+		} else if !isBlank(line) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !interrupts(line) {
+			// A lazy continuation of a paragraph in the quote.
+			inner = append(inner, line)
+		} else {
+			break
+		}
+		i++
+	}
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+// listItem is the content of an item, with the item's indentation removed.
+type listItem struct {
+	lines []string
+}
+
+func (r *renderer) list(lines []string, i int) int {
+	first := listItemRE.FindStringSubmatch(lines[i])
+	ordered := first[3] != ""
+	marker := first[2][len(first[2])-1:]
+	start := 1
+	if ordered {
+		start, _ = strconv.Atoi(first[3])
+	}
+
+	// continues returns whether a line starts another item of this list.
+	continues := func(line string) bool {
+		m := listItemRE.FindStringSubmatch(line)
+		return m != nil && (m[3] != "") == ordered && m[2][len(m[2])-1:] == marker && !thematicRE.MatchString(line)
+	}
+	var items []listItem
+	loose := false
+	for i < len(lines) && continues(lines[i]) {
+		m := listItemRE.FindStringSubmatch(lines[i])
+		// Content is indented to the column after the marker, unless it is itself indented code.
+		width := len(m[0])
+		rest := lines[i][width:]
+		if len(m[4]) > 4 {
+			width = len(m[1]) + len(m[2]) + 1
+			rest = lines[i][width:]
+		}
+		item := listItem{lines: []string{rest}}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				item.lines = append(item.lines, "")
+				i++
+				continue
+			}
+			if strings.HasPrefix(line, strings.Repeat(" ", width)) {
+				item.lines = append(item.lines, line[width:])
+				i++
+				continue
+			}
+			last := item.lines[len(item.lines)-1]
+			if !isBlank(last) && !interrupts(line) && !listItemRE.MatchString(line) {
+				// A lazy continuation of a paragraph in the item.
+				item.lines = append(item.lines, line)
+				i++
+				continue
+			}
+			break
+		}
+		// Blank lines between items, or between blocks within one, make the list loose.
+		trailing := 0
+		for n := len(item.lines) - 1; n >= 0 && isBlank(item.lines[n]); n-- {
+			trailing++
+		}
+		content := item.lines[:len(item.lines)-trailing]
+		for n := 1; n < len(content); n++ {
+			if isBlank(content[n]) {
+				loose = true
+			}
+		}
+		if trailing > 0 && i < len(lines) && continues(lines[i]) {
+			loose = true
+		}
+		item.lines = content
+		items = append(items, item)
+	}
+
+	switch {
+	case !ordered:
+		r.out.WriteString("<ul>\n")
+	case start != 1:
+		fmt.Fprintf(&r.out, "<ol start=\"%d\">\n", start)
+	default:
+		r.out.WriteString("<ol>\n")
+	}
+	for _, item := range items {
+		r.out.WriteString("<li>")
+		if len(item.lines) > 0 {
+			if m := taskRE.FindStringSubmatch(item.lines[0]); m != nil {
+				if m[1] == " " {
+					r.out.WriteString(`<input type="checkbox" disabled> `)
+				} else {
+					r.out.WriteString(`<input type="checkbox" checked disabled> `)
+				}
+				item.lines[0] = item.lines[0][len(m[0]):]
+			}
+		}
+		r.blocks(item.lines, !loose)
+		r.out.WriteString("</li>\n")
+	}
+	if ordered {
+		r.out.WriteString("</ol>\n")
+	} else {
+		r.out.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// splitRow splits a table row into cells, ignoring escaped pipes and those in code spans.
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (r *renderer) table(lines []string, i int) int {
+	header := splitRow(lines[i])
+	var aligns []string
+	for _, d := range splitRow(lines[i+1]) {
+		left, right := strings.HasPrefix(d, ":"), strings.HasSuffix(d, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case right:
+			aligns = append(aligns, "right")
+		case left:
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	row := func(cells []string, tag string) {
+		r.out.WriteString("<tr>")
+		for n, align := range aligns {
+			if align != "" {
+				fmt.Fprintf(&r.out, `<%s style="text-align: %s">`, tag, align)
+			} else {
+				fmt.Fprintf(&r.out, "<%s>", tag)
+			}
+			if n < len(cells) {
+				r.out.WriteString(r.inline(cells[n]))
+			}
+			fmt.Fprintf(&r.out, "</%s>", tag)
+		}
+		r.out.WriteString("</tr>\n")
+	}
+	r.out.WriteString("<table>\n<thead>\n")
+	row(header, "th")
+	r.out.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && !isBlank(lines[i]) && !interrupts(lines[i]); i++ {
+		row(splitRow(lines[i]), "td")
+	}
+	r.out.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+func (r *renderer) paragraph(lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(text) > 0 {
+			if m := setextRE.FindStringSubmatch(line); m != nil {
+				level := 2
+				if m[1][0] == '=' {
+					level = 1
+				}
+				r.heading(level, strings.Join(text, "\n"))
+				return i + 1
+			}
+			if interrupts(line) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+	content := r.inline(strings.TrimRight(strings.Join(text, "\n"), " "))
+	if tight {
+		r.out.WriteString(content)
+		r.out.WriteString("\n")
+		return i
+	}
+	r.out.WriteString("<p>")
+	r.out.WriteString(content)
+	r.out.WriteString("</p>\n")
+	return i
+}
+
+// inline renders the inline content of a block.
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+		case c == '\\' && escapableRE.MatchString(s[i+1:]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == ' ' && strings.HasPrefix(strings.TrimLeft(s[i:], " "), "\n"):
+			// Trailing spaces are only significant as a hard break.
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], " "))
+			if n >= 2 {
+				b.WriteString("<br>")
+			}
+			i += n
+			continue
+		case c == '`':
+			if code, n, ok := codeSpan(s[i:]); ok {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(code))
+				b.WriteString("</code>")
+				i += n
+				continue
+			}
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if out, n, ok := r.link(s[i+1:], true); ok {
+				b.WriteString(out)
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if out, n, ok := r.link(s[i:], false); ok {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+		case c == '<':
+			if m := autolinkRE.FindStringSubmatch(s[i:]); m != nil {
+				dest := m[1]
+				if !strings.Contains(dest, ":") {
+					dest = "mailto:" + dest
+				}
+				if u, ok := r.safeURL(dest, false); ok {
+					fmt.Fprintf(&b, `<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`, html.EscapeString(u), html.EscapeString(m[1]))
+					i += len(m[0])
+					continue
+				}
+			}
+		case c == 'h' || c == 'w':
+			if i == 0 || strings.ContainsRune(" \n(*_~", rune(s[i-1])) {
+				if m := bareURLRE.FindString(s[i:]); m != "" {
+					m = trimURL(m)
+					dest := m
+					if strings.HasPrefix(dest, "www.") {
+						dest = "http://" + dest
+					}
+					if u, ok := r.safeURL(dest, false); ok {
+						fmt.Fprintf(&b, `<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`, html.EscapeString(u), html.EscapeString(m))
+						i += len(m)
+						continue
+					}
+				}
+			}
+		case c == '*' || c == '_' || c == '~':
+			if out, n, ok := r.emphasis(s, i); ok {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], string(c)))
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// codeSpan returns the content of the code span at the start of s and its length.
+func codeSpan(s string) (string, int, bool) {
+	n := len(s) - len(strings.TrimLeft(s, "`"))
+	delim := s[:n]
+	for j := n; j < len(s); {
+		k := strings.Index(s[j:], delim)
+		if k < 0 {
+			return "", 0, false
+		}
+		k += j
+		end := k + n
+		if end < len(s) && s[end] == '`' {
+			// A longer run of backticks doesn't close the span.
+			j = end + len(s[end:]) - len(strings.TrimLeft(s[end:], "`"))
+			continue
+		}
+		code := strings.Replace(s[n:k], "\n", " ", -1)
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+			code = code[1 : len(code)-1]
+		}
+		return code, end, true
+	}
+	return "", 0, false
+}
+
+// trimURL removes trailing punctuation, and closing parentheses without an opening one, from a
+// bare URL.
+func trimURL(u string) string {
+	for {
+		trimmed := trailingURLRE.ReplaceAllString(u, "")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, ")") > strings.Count(trimmed, "(") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
+
+// emphasis renders emphasis, strong emphasis or strikethrough starting at s[i], and returns the
+// number of bytes consumed.
+func (r *renderer) emphasis(s string, i int) (string, int, bool) {
+	c := s[i]
+	run := len(s[i:]) - len(strings.TrimLeft(s[i:], string(c)))
+	// Underscores do not open emphasis within a word.
+	if c == '_' && i > 0 && isWordChar(s[i-1]) {
+		return "", 0, false
+	}
+	var widths []int
+	switch {
+	case c == '~' && run == 2:
+		widths = []int{2}
+	case c == '~':
+		return "", 0, false
+	case run >= 3:
+		widths = []int{3, 2, 1}
+	case run == 2:
+		widths = []int{2, 1}
+	default:
+		widths = []int{1}
+	}
+	open := i + run
+	if open >= len(s) || unicode.IsSpace(rune(s[open])) {
+		return "", 0, false
+	}
+	for _, w := range widths {
+		delim := strings.Repeat(string(c), w)
+		for j := open + 1; j < len(s); j++ {
+			if s[j] == '`' {
+				// Delimiters in code spans don't count.
+				if _, n, ok := codeSpan(s[j:]); ok {
+					j += n - 1
+				}
+				continue
+			}
+			if s[j] == '\\' {
+				j++
+				continue
+			}
+			if !strings.HasPrefix(s[j:], delim) || unicode.IsSpace(rune(s[j-1])) || s[j-1] == c {
+				continue
+			}
+			// The closing delimiter must be a whole run of the same length.
+			closeRun := len(s[j:]) - len(strings.TrimLeft(s[j:], string(c)))
+			if closeRun != w {
+				j += closeRun - 1
+				continue
+			}
+			if c == '_' && j+w < len(s) && isWordChar(s[j+w]) {
+				continue
+			}
+			inner := r.inline(s[open:j])
+			prefix := strings.Repeat(string(c), run-w)
+			switch {
+			case c == '~':
+				return prefix + "<del>" + inner + "</del>", j + w - i, true
+			case w == 3:
+				return prefix + "<em><strong>" + inner + "</strong></em>", j + w - i, true
+			case w == 2:
+				return prefix + "<strong>" + inner + "</strong>", j + w - i, true
+			default:
+				return prefix + "<em>" + inner + "</em>", j + w - i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// link renders the link or image starting at s, which begins with "[", and returns the number
+// of bytes consumed.
+func (r *renderer) link(s string, image bool) (string, int, bool) {
+	// Find the matching bracket.
+	depth, end := 0, -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if _, n, ok := codeSpan(s[j:]); ok {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 {
+		return "", 0, false
+	}
+	text := s[1:end]
+	rest := s[end+1:]
+	var dest, title string
+	consumed := end + 1
+	switch {
+	case strings.HasPrefix(rest, "("):
+		d, t, n, ok := inlineDestination(rest)
+		if !ok {
+			return "", 0, false
+		}
+		dest, title = d, t
+		consumed += n
+	default:
+		label := text
+		if strings.HasPrefix(rest, "[]") {
+			consumed += 2
+		} else if strings.HasPrefix(rest, "[") {
+			if k := strings.Index(rest, "]"); k > 1 {
+				label = rest[1:k]
+				consumed += k + 1
+			}
+		}
+		ref, ok := r.refs[normalizeLabel(label)]
+		if !ok {
+			return "", 0, false
+		}
+		dest, title = ref.dest, ref.title
+	}
+
+	u, ok := r.safeURL(unescape(dest), image)
+	if image {
+		alt := html.UnescapeString(tagRE.ReplaceAllString(r.inline(text), ""))
+		if !ok {
+			return html.EscapeString(alt), consumed, true
+		}
+		out := fmt.Sprintf(`<img src="%s" alt="%s"`, html.EscapeString(u), html.EscapeString(alt))
+		if title != "" {
+			out += fmt.Sprintf(` title="%s"`, html.EscapeString(unescape(title)))
+		}
+		return out + ">", consumed, true
+	}
+	content := r.inline(text)
+	if !ok {
+		return content, consumed, true
+	}
+	out := fmt.Sprintf(`<a href="%s"`, html.EscapeString(u))
+	if title != "" {
+		out += fmt.Sprintf(` title="%s"`, html.EscapeString(unescape(title)))
+	}
+	if !strings.HasPrefix(u, "#") {
+		out += ` target="_blank" rel="noopener noreferrer"`
+	}
+	return out + ">" + content + "</a>", consumed, true
+}
+
+// inlineDestination parses the destination and title of an inline link, such as
+// `(https://example.com "Title")`, and returns the number of bytes consumed.
+func inlineDestination(s string) (dest, title string, n int, ok bool) {
+	i := 1
+	skipSpace := func() {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+			i++
+		}
+	}
+	skipSpace()
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i:], ">\n")
+		if end < 0 || s[i+end] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : i+end]
+		i += end + 1
+	} else {
+		start, depth := i, 0
+		for ; i < len(s) && s[i] != ' ' && s[i] != '\n'; i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '(' {
+				depth++
+			} else if s[i] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		if i > len(s) {
+			i = len(s)
+		}
+		dest = s[start:i]
+	}
+	skipSpace()
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closing := s[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		end := strings.IndexByte(s[i+1:], closing)
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = s[i+1 : i+1+end]
+		i += end + 2
+		skipSpace()
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return dest, title, i + 1, true
+}
+
+// unescape removes backslash escapes and decodes entities.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && escapableRE.MatchString(s[i+1:]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+// safeURL resolves a link destination against the base URL, and returns whether it is safe to
+// link to. Images are only loaded over http and https.
+func (r *renderer) safeURL(dest string, image bool) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(dest))
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		if strings.HasPrefix(dest, "#") && !image {
+			return dest, true
+		}
+		if r.base == nil {
+			return "", false
+		}
+		u = r.base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.String(), true
+	case "mailto":
+		return u.String(), !image
+	}
+	return "", false
+}
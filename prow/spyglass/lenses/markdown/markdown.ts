@@ -0,0 +1,23 @@
+window.addEventListener('DOMContentLoaded', () => {
+  // Links are resolved against the lens's <base>, which would send fragments elsewhere, so
+  // scroll to headings ourselves.
+  document.addEventListener('click', (e) => {
+    const link = (e.target as Element).closest('a');
+    if (!link) {
+      return;
+    }
+    const href = link.getAttribute('href');
+    if (!href || href[0] !== '#') {
+      return;
+    }
+    e.preventDefault();
+    const heading = document.getElementById(decodeURIComponent(href.substring(1)));
+    if (heading) {
+      heading.scrollIntoView();
+    }
+  });
+  // Images change the height of the content as they load.
+  for (const img of Array.from(document.querySelectorAll('img'))) {
+    img.addEventListener('load', () => spyglass.contentUpdated());
+  }
+});
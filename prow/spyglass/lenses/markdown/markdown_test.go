@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package markdown
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	base, _ := url.Parse("https://gcsweb.example.com/bucket/logs/job/1/artifacts/notes.md")
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "headings get anchors",
+			input:    "# Release *v1.2*\n\n## Release *v1.2*",
+			expected: "<h1 id=\"release-v12\"><a class=\"md-anchor\" href=\"#release-v12\" aria-hidden=\"true\">#</a>Release <em>v1.2</em></h1>\n<h2 id=\"release-v12-1\"><a class=\"md-anchor\" href=\"#release-v12-1\" aria-hidden=\"true\">#</a>Release <em>v1.2</em></h2>\n",
+		},
+		{
+			name:     "setext heading",
+			input:    "Notes\n=====",
+			expected: "<h1 id=\"notes\"><a class=\"md-anchor\" href=\"#notes\" aria-hidden=\"true\">#</a>Notes</h1>\n",
+		},
+		{
+			name:     "emphasis",
+			input:    "*a* **b** ***c*** ~~d~~ snake_case_name `*e*`",
+			expected: "<p><em>a</em> <strong>b</strong> <em><strong>c</strong></em> <del>d</del> snake_case_name <code>*e*</code></p>\n",
+		},
+		{
+			name:     "raw html is escaped",
+			input:    "<script>alert(1)</script>",
+			expected: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name:     "unsafe links are dropped",
+			input:    "[click](javascript:alert(1)) ![x](data:image/png;base64,AAAA)",
+			expected: "<p>click x</p>\n",
+		},
+		{
+			name:     "relative links are resolved against the artifact",
+			input:    "[docs](docs/a.md \"Docs\") ![graph](graph.png) [top](#notes)",
+			expected: "<p><a href=\"https://gcsweb.example.com/bucket/logs/job/1/artifacts/docs/a.md\" title=\"Docs\" target=\"_blank\" rel=\"noopener noreferrer\">docs</a> <img src=\"https://gcsweb.example.com/bucket/logs/job/1/artifacts/graph.png\" alt=\"graph\"> <a href=\"#notes\">top</a></p>\n",
+		},
+		{
+			name:     "reference links and autolinks",
+			input:    "[k8s][] and <https://k8s.io> and https://example.com/a).\n\n[k8s]: https://kubernetes.io",
+			expected: "<p><a href=\"https://kubernetes.io\" target=\"_blank\" rel=\"noopener noreferrer\">k8s</a> and <a href=\"https://k8s.io\" target=\"_blank\" rel=\"noopener noreferrer\">https://k8s.io</a> and <a href=\"https://example.com/a\" target=\"_blank\" rel=\"noopener noreferrer\">https://example.com/a</a>).</p>\n",
+		},
+		{
+			name:     "hard breaks",
+			input:    "one  \ntwo\\\nthree\nfour",
+			expected: "<p>one<br>\ntwo<br>\nthree\nfour</p>\n",
+		},
+		{
+			name:     "tight nested list with tasks",
+			input:    "- [x] done\n- [ ] todo\n  - nested",
+			expected: "<ul>\n<li><input type=\"checkbox\" checked disabled> done\n</li>\n<li><input type=\"checkbox\" disabled> todo\n<ul>\n<li>nested\n</li>\n</ul>\n</li>\n</ul>\n",
+		},
+		{
+			name:     "loose ordered list",
+			input:    "3. one\n\n4. two",
+			expected: "<ol start=\"3\">\n<li><p>one</p>\n</li>\n<li><p>two</p>\n</li>\n</ol>\n",
+		},
+		{
+			name:     "blockquote with lazy continuation",
+			input:    "> quoted\nlazy",
+			expected: "<blockquote>\n<p>quoted\nlazy</p>\n</blockquote>\n",
+		},
+		{
+			name:     "code blocks",
+			input:    "```go\nfmt.Println(\"<hi>\")\n```\n\n    indented",
+			expected: "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)\n</code></pre>\n<pre><code>indented\n</code></pre>\n",
+		},
+		{
+			name:     "table",
+			input:    "| a | b |\n|:--|--:|\n| x \\| y | `1|2` |",
+			expected: "<table>\n<thead>\n<tr><th style=\"text-align: left\">a</th><th style=\"text-align: right\">b</th></tr>\n</thead>\n<tbody>\n<tr><td style=\"text-align: left\">x | y</td><td style=\"text-align: right\"><code>1|2</code></td></tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "thematic break",
+			input:    "a\n\n---\n\nb",
+			expected: "<p>a</p>\n<hr>\n<p>b</p>\n",
+		},
+	}
+	for _, tc := range testCases {
+		if actual := Render(tc.input, base); actual != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// ComparedField is a field of the metadata of two compared runs.
+type ComparedField struct {
+	Name    string
+	Current string
+	Other   string
+}
+
+// Changed returns whether the field differs between the runs.
+func (f ComparedField) Changed() bool {
+	return f.Current != f.Other
+}
+
+type compareView struct {
+	OtherLink string
+	Fields    []ComparedField
+}
+
+// Compare renders the metadata of two runs side by side, highlighting the fields that differ.
+func (lens Lens) Compare(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "compare", compareRuns(artifacts)); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// compareRuns lines up the metadata of the run being viewed with that of the run it is compared to.
+func compareRuns(artifacts []lenses.Artifact) compareView {
+	current, baseline := lenses.SplitBaseline(artifacts)
+	view := compareView{}
+	var other []lenses.Artifact
+	for _, b := range baseline {
+		other = append(other, b)
+		view.OtherLink = "/view/" + b.Source
+	}
+	a, b := viewData(current), viewData(other)
+	view.Fields = []ComparedField{
+		{Name: "Status", Current: a.Status, Other: b.Status},
+		{Name: "Started", Current: formatTime(a.StartTime), Other: formatTime(b.StartTime)},
+		{Name: "Elapsed", Current: a.Elapsed.String(), Other: b.Elapsed.String()},
+	}
+
+	var keys []string
+	for k := range a.Metadata {
+		keys = append(keys, k)
+	}
+	for k := range b.Metadata {
+		if _, ok := a.Metadata[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if a.Metadata[k] != "" || b.Metadata[k] != "" {
+			view.Fields = append(view.Fields, ComparedField{Name: k, Current: a.Metadata[k], Other: b.Metadata[k]})
+		}
+	}
+
+	repos := map[string]*ComparedField{}
+	var names []string
+	for _, repo := range a.Repos {
+		repos[repo.Name()] = &ComparedField{Name: repo.Name(), Current: describeRepo(repo)}
+		names = append(names, repo.Name())
+	}
+	for _, repo := range b.Repos {
+		if repos[repo.Name()] == nil {
+			repos[repo.Name()] = &ComparedField{Name: repo.Name()}
+			names = append(names, repo.Name())
+		}
+		repos[repo.Name()].Other = describeRepo(repo)
+	}
+	for _, name := range names {
+		view.Fields = append(view.Fields, *repos[name])
+	}
+	return view
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+// describeRepo summarizes what was checked out of a repository: its base, the pulls merged into
+// it and the commit the clone ended on.
+func describeRepo(repo RepoView) string {
+	parts := []string{repo.Refs.BaseRef + shortSHA(repo.Refs.BaseSHA)}
+	for _, pull := range repo.Refs.Pulls {
+		parts = append(parts, fmt.Sprintf("#%d%s", pull.Number, shortSHA(pull.SHA)))
+	}
+	switch {
+	case repo.Failed:
+		parts = append(parts, "clone failed")
+	case repo.FinalSHA != "":
+		parts = append(parts, "checked out"+shortSHA(repo.FinalSHA))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func shortSHA(sha string) string {
+	if sha == "" {
+		return ""
+	}
+	if len(sha) > 10 {
+		sha = sha[:10]
+	}
+	return " @ " + sha
+}
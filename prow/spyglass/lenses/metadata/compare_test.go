@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func TestCompareRuns(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "started.json", Content: []byte(`{"timestamp": 1560000000, "node": "node-a", "metadata": {"image": "v2"}}`)},
+		&fakeartifact.Artifact{Path: "finished.json", Content: []byte(`{"timestamp": 1560000100, "result": "FAILURE"}`)},
+		lenses.NewBaselineArtifact(&fakeartifact.Artifact{Path: "started.json", Content: []byte(`{"timestamp": 1559990000, "node": "node-a", "metadata": {"image": "v1", "zone": "us-central1-f"}}`)}, "gcs/bucket/logs/job/1"),
+		lenses.NewBaselineArtifact(&fakeartifact.Artifact{Path: "finished.json", Content: []byte(`{"timestamp": 1559990200, "result": "SUCCESS"}`)}, "gcs/bucket/logs/job/1"),
+	}
+
+	view := compareRuns(artifacts)
+	if view.OtherLink != "/view/gcs/bucket/logs/job/1" {
+		t.Errorf("expected a link to the compared run, got %q", view.OtherLink)
+	}
+	expected := []ComparedField{
+		{Name: "Status", Current: "FAILURE", Other: "SUCCESS"},
+		{Name: "Started", Current: "2019-06-08 13:20:00 UTC", Other: "2019-06-08 10:33:20 UTC"},
+		{Name: "Elapsed", Current: "1m40s", Other: "3m20s"},
+		{Name: "image", Current: "v2", Other: "v1"},
+		{Name: "node", Current: "node-a", Other: "node-a"},
+		{Name: "zone", Other: "us-central1-f"},
+	}
+	if !reflect.DeepEqual(view.Fields, expected) {
+		t.Errorf("expected fields:\n%+v\ngot:\n%+v", expected, view.Fields)
+	}
+	if view.Fields[4].Changed() || !view.Fields[5].Changed() {
+		t.Error("expected only fields that differ to be changed")
+	}
+}
@@ -27,6 +27,8 @@ import (
 	"path/filepath"
 
 	"github.com/sirupsen/logrus"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/clone"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 	"k8s.io/test-infra/testgrid/metadata"
@@ -56,7 +58,7 @@ func (lens Lens) Config() lenses.LensConfig {
 }
 
 // Header renders the <head> from template.html.
-func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
 	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
 	if err != nil {
 		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
@@ -69,23 +71,49 @@ func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string
 }
 
 // Callback does nothing.
-func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
 	return ""
 }
 
+// ViewData is the metadata of a prow job shown by the lens.
+type ViewData struct {
+	Status       string            `json:"status"`
+	StartTime    time.Time         `json:"start_time"`
+	FinishedTime time.Time         `json:"finished_time"`
+	Elapsed      time.Duration     `json:"elapsed"`
+	Metadata     map[string]string `json:"metadata"`
+	Repos        []RepoView        `json:"repos,omitempty"`
+	CloneFailed  bool              `json:"clone_failed"`
+}
+
+// Data returns the prow job's metadata.
+func (lens Lens) Data(artifacts []lenses.Artifact, config json.RawMessage) (interface{}, error) {
+	return viewData(artifacts), nil
+}
+
 // Body creates a view for prow job metadata.
-func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
 	var buf bytes.Buffer
-	type MetadataViewData struct {
-		Status       string
-		StartTime    time.Time
-		FinishedTime time.Time
-		Elapsed      time.Duration
-		Metadata     map[string]string
+	metadataViewData := viewData(artifacts)
+
+	metadataTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
 	}
-	metadataViewData := MetadataViewData{Status: "Pending"}
+
+	if err := metadataTemplate.ExecuteTemplate(&buf, "body", metadataViewData); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// viewData reads the metadata of a prow job from its artifacts.
+func viewData(artifacts []lenses.Artifact) ViewData {
+	metadataViewData := ViewData{Status: "Pending"}
 	started := gcs.Started{}
 	finished := gcs.Finished{}
+	var cloneRecords []clone.Record
+	var prowJob *prowapi.ProwJob
 	for _, a := range artifacts {
 		read, err := a.ReadAll()
 		if err != nil {
@@ -104,6 +132,24 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 				metadataViewData.FinishedTime = time.Unix(*finished.Timestamp, 0)
 			}
 			metadataViewData.Status = finished.Result
+		} else if a.JobPath() == "clone-records.json" {
+			if err = json.Unmarshal(read, &cloneRecords); err != nil {
+				logrus.WithError(err).Error("Error unmarshaling clone-records.json")
+			}
+		} else if a.JobPath() == "prowjob.json" {
+			pj := prowapi.ProwJob{}
+			if err = json.Unmarshal(read, &pj); err != nil {
+				logrus.WithError(err).Error("Error unmarshaling prowjob.json")
+			} else {
+				prowJob = &pj
+			}
+		}
+	}
+
+	metadataViewData.Repos = repoViews(cloneRecords, prowJob)
+	for _, repo := range metadataViewData.Repos {
+		if repo.Failed {
+			metadataViewData.CloneFailed = true
 		}
 	}
 
@@ -118,6 +164,12 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 	}
 
 	metadataViewData.Metadata = map[string]string{"node": started.Node}
+	if prowJob != nil {
+		metadataViewData.Metadata["job"] = prowJob.Spec.Job
+		metadataViewData.Metadata["type"] = string(prowJob.Spec.Type)
+		metadataViewData.Metadata["cluster"] = prowJob.Spec.Cluster
+		metadataViewData.Metadata["pod"] = prowJob.Status.PodName
+	}
 
 	metadatas := []metadata.Metadata{started.Metadata, finished.Metadata}
 	for _, m := range metadatas {
@@ -127,14 +179,5 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 			}
 		}
 	}
-
-	metadataTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
-	if err != nil {
-		return fmt.Sprintf("Failed to load template: %v", err)
-	}
-
-	if err := metadataTemplate.ExecuteTemplate(&buf, "body", metadataViewData); err != nil {
-		logrus.WithError(err).Error("Error executing template.")
-	}
-	return buf.String()
+	return metadataViewData
 }
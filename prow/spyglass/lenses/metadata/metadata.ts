@@ -7,16 +7,38 @@ function handleClick(e: MouseEvent): void {
   const button = document.getElementById('show-table-link')!;
   const table = document.getElementById('data-table')!;
   table.classList.toggle('hidden');
-  if (table.classList.contains('hidden')) {
+  const hidden = table.classList.contains('hidden');
+  if (hidden) {
     button.innerText = 'more info';
   } else {
     button.innerText = 'less info';
   }
+  // The refs table is always shown when a clone failed.
+  const refs = document.getElementById('refs-table');
+  if (refs && !refs.hasAttribute('data-pinned')) {
+    refs.classList.toggle('hidden', hidden);
+  }
   spyglass.contentUpdated();
 }
 
+function handleCloneRowClick(this: HTMLTableRowElement): void {
+  const commands = this.nextElementSibling;
+  if (commands && commands.classList.contains('clone-commands')) {
+    commands.classList.toggle('hidden');
+    spyglass.contentUpdated();
+  }
+}
+
 function getLocalStartTime(): void {
-  document.getElementById('show-table-link')!.onclick = handleClick;
+  const link = document.getElementById('show-table-link');
+  if (!link) {
+    // Compared runs are shown as a single table, without a summary.
+    return;
+  }
+  link.onclick = handleClick;
+  for (const row of Array.from(document.querySelectorAll<HTMLTableRowElement>('tr.clone-row'))) {
+    row.addEventListener('click', handleCloneRowClick);
+  }
   const elem = document.getElementById("summary-start-time")!;
   elem.innerText = moment(elem.innerText, DATE_FORMAT).calendar().replace(/Last|Yesterday|Today|Tomorrow/,
       (m) => m.charAt(0).toLowerCase() + m.substr(1));
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/clone"
+)
+
+// RepoView describes a single repository that was checked out for the job.
+type RepoView struct {
+	Refs prowapi.Refs
+	// Cloned is true if a clone record exists for the repository, in which case
+	// the fields below are populated.
+	Cloned   bool
+	Failed   bool
+	FinalSHA string
+	Duration time.Duration
+	Commands []clone.Command
+	// FailedCommand is the command that caused the clone to fail, if any.
+	FailedCommand *clone.Command
+}
+
+// Name returns the org/repo name of the repository.
+func (r RepoView) Name() string {
+	return r.Refs.Org + "/" + r.Refs.Repo
+}
+
+// repoViews builds the list of repositories to display. Clone records are preferred
+// as they describe what actually happened, but the refs in the ProwJob are used when
+// no records were uploaded and to fill in links the records do not carry.
+func repoViews(records []clone.Record, pj *prowapi.ProwJob) []RepoView {
+	var specRefs []prowapi.Refs
+	if pj != nil {
+		if pj.Spec.Refs != nil {
+			specRefs = append(specRefs, *pj.Spec.Refs)
+		}
+		specRefs = append(specRefs, pj.Spec.ExtraRefs...)
+	}
+
+	if len(records) == 0 {
+		var views []RepoView
+		for _, refs := range specRefs {
+			views = append(views, RepoView{Refs: refs})
+		}
+		return views
+	}
+
+	var views []RepoView
+	for _, record := range records {
+		view := RepoView{
+			Refs:     record.Refs,
+			Cloned:   true,
+			Failed:   record.Failed,
+			FinalSHA: record.FinalSHA,
+			Commands: record.Commands,
+		}
+		for i, command := range record.Commands {
+			view.Duration += command.Duration
+			if command.Error != "" {
+				view.FailedCommand = &record.Commands[i]
+			}
+		}
+		for _, refs := range specRefs {
+			if refs.Org == record.Refs.Org && refs.Repo == record.Refs.Repo {
+				view.Refs = mergeLinks(view.Refs, refs)
+				break
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// mergeLinks copies any links present in the ProwJob refs into the recorded refs.
+func mergeLinks(recorded, spec prowapi.Refs) prowapi.Refs {
+	if recorded.RepoLink == "" {
+		recorded.RepoLink = spec.RepoLink
+	}
+	if recorded.BaseLink == "" && recorded.BaseSHA == spec.BaseSHA {
+		recorded.BaseLink = spec.BaseLink
+	}
+	pulls := make([]prowapi.Pull, len(recorded.Pulls))
+	copy(pulls, recorded.Pulls)
+	for i, pull := range pulls {
+		for _, specPull := range spec.Pulls {
+			if pull.Number != specPull.Number {
+				continue
+			}
+			if pull.Link == "" {
+				pulls[i].Link = specPull.Link
+			}
+			if pull.AuthorLink == "" {
+				pulls[i].AuthorLink = specPull.AuthorLink
+			}
+			if pull.CommitLink == "" && pull.SHA == specPull.SHA {
+				pulls[i].CommitLink = specPull.CommitLink
+			}
+			if pull.Title == "" {
+				pulls[i].Title = specPull.Title
+			}
+		}
+	}
+	recorded.Pulls = pulls
+	return recorded
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/clone"
+)
+
+func TestRepoViews(t *testing.T) {
+	pj := &prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{
+			Refs: &prowapi.Refs{
+				Org:      "kubernetes",
+				Repo:     "test-infra",
+				RepoLink: "https://github.com/kubernetes/test-infra",
+				BaseRef:  "master",
+				BaseSHA:  "abcdef",
+				Pulls:    []prowapi.Pull{{Number: 1, SHA: "123456", Link: "https://github.com/kubernetes/test-infra/pull/1"}},
+			},
+			ExtraRefs: []prowapi.Refs{{Org: "kubernetes", Repo: "kubernetes", BaseRef: "master"}},
+		},
+	}
+
+	t.Run("refs from the prowjob are used without clone records", func(t *testing.T) {
+		views := repoViews(nil, pj)
+		if len(views) != 2 {
+			t.Fatalf("expected 2 repos, got %d", len(views))
+		}
+		if views[0].Name() != "kubernetes/test-infra" || views[1].Name() != "kubernetes/kubernetes" {
+			t.Errorf("unexpected repos: %q, %q", views[0].Name(), views[1].Name())
+		}
+		if views[0].Cloned {
+			t.Errorf("expected repo without a clone record not to be marked as cloned")
+		}
+	})
+
+	t.Run("clone records are preferred and annotated with links", func(t *testing.T) {
+		records := []clone.Record{
+			{
+				Refs: prowapi.Refs{
+					Org:     "kubernetes",
+					Repo:    "test-infra",
+					BaseRef: "master",
+					BaseSHA: "abcdef",
+					Pulls:   []prowapi.Pull{{Number: 1, SHA: "123456"}},
+				},
+				Commands: []clone.Command{
+					{Command: "git init", Duration: time.Second},
+					{Command: "git fetch", Error: "exit status 128", Duration: 2 * time.Second},
+				},
+				Failed: true,
+			},
+		}
+		views := repoViews(records, pj)
+		if len(views) != 1 {
+			t.Fatalf("expected 1 repo, got %d", len(views))
+		}
+		view := views[0]
+		if !view.Cloned || !view.Failed {
+			t.Errorf("expected repo to be cloned and failed, got %#v", view)
+		}
+		if view.Duration != 3*time.Second {
+			t.Errorf("expected total duration of 3s, got %v", view.Duration)
+		}
+		if view.FailedCommand == nil || view.FailedCommand.Command != "git fetch" {
+			t.Errorf("expected failed command to be git fetch, got %#v", view.FailedCommand)
+		}
+		if view.Refs.RepoLink != pj.Spec.Refs.RepoLink {
+			t.Errorf("expected repo link to be copied from prowjob, got %q", view.Refs.RepoLink)
+		}
+		if view.Refs.Pulls[0].Link != pj.Spec.Refs.Pulls[0].Link {
+			t.Errorf("expected pull link to be copied from prowjob, got %q", view.Refs.Pulls[0].Link)
+		}
+		if records[0].Refs.Pulls[0].Link != "" {
+			t.Errorf("expected clone record not to be modified")
+		}
+	})
+}
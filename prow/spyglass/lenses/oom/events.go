@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oom
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of events.
+const (
+	kindOOM      = "oom"
+	kindEviction = "eviction"
+	kindPressure = "pressure"
+)
+
+var (
+	// klogRE matches the prefix of a klog line, such as "E1001 12:00:00.123456".
+	klogRE = regexp.MustCompile(`^[IWEF](\d{2})(\d{2}) (\d{2}:\d{2}:\d{2}(?:\.\d+)?)\s`)
+	// syslogRE matches the prefix of a syslog or journal line, such as "Oct  1 12:00:00".
+	syslogRE = regexp.MustCompile(`^([A-Z][a-z]{2}) +(\d{1,2}) (\d{2}:\d{2}:\d{2})\s`)
+	// dmesgTimeRE matches the prefix of a line of `dmesg -T`, such as "[Tue Oct  1 12:00:00 2019]".
+	dmesgTimeRE = regexp.MustCompile(`^\[([A-Z][a-z]{2} [A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2} \d{4})\]`)
+	// isoRE matches a timestamp at the start of a line, such as "2019-10-01T12:00:00.123Z".
+	isoRE = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2}))`)
+
+	// killedRE matches the kernel's report of the process it killed.
+	killedRE = regexp.MustCompile(`(Memory cgroup out of memory|Out of memory): Kill(?:ed)? process (\d+) \(([^)]+)\)`)
+	// oomKillRE matches the kernel's summary of an OOM kill, which names the victim's cgroup.
+	oomKillRE = regexp.MustCompile(`oom-kill:.*?task_memcg=([^,]*),task=([^,]+),pid=(\d+)`)
+	// systemOOMRE matches the kubelet's report of an OOM kill outside any container.
+	systemOOMRE = regexp.MustCompile(`System OOM encountered, victim process: ([^,]+), pid: (\d+)`)
+	// oomKilledRE matches the kubelet's reports of containers that were OOM killed.
+	oomKilledRE = regexp.MustCompile(`\bOOMKilled\b`)
+	// evictedRE matches the kubelet eviction manager's reports of evicted pods.
+	evictedRE = regexp.MustCompile(`(?i)eviction manager: pods? (\S+?) (?:is )?evicted successfully|"Eviction manager: pods? (?:is )?evicted successfully".*?pods?="?\[?([^"\] ]+)`)
+	// reclaimRE matches the kubelet eviction manager's reports of resource pressure.
+	reclaimRE = regexp.MustCompile(`(?i)eviction manager: attempting to reclaim"?\s*(?:resourceName=)?"?([\w.-]+)`)
+	// lowOnRE matches the message of Evicted events.
+	lowOnRE = regexp.MustCompile(`The node (?:was|had) low on resource: \[?([\w.-]+)`)
+
+	// podCgroupRE finds a pod's UID and container's ID in a cgroup path, with either the
+	// cgroupfs or systemd cgroup driver.
+	podCgroupRE = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(?:\.slice)?/(?:[a-z-]+-)?([0-9a-f]{12,64})`)
+	// podRE and containerRE find the pod and container named in a kubelet log line.
+	podRE       = regexp.MustCompile(`\bpods?[= ]"?\[?([\w.-]+/[\w.-]+|[\w.-]+_[\w.-]+\([0-9a-f-]+\))`)
+	containerRE = regexp.MustCompile(`\bcontainer(?:Name)?[= ]"?([\w.-]+)`)
+)
+
+// Event is an OOM kill, eviction or resource pressure reported in a log.
+type Event struct {
+	Kind string
+	// Time is when the event happened, if the log line has an absolute time.
+	Time time.Time
+	// Process and PID are the process killed by the OOM killer.
+	Process string
+	PID     int
+	// Pod and Container are the pod and container affected, as far as the log names them. Pods
+	// may be named by namespace and name or, from cgroups, only by UID.
+	Pod       string
+	Container string
+	// Resource is the resource the node was short of, for evictions and pressure.
+	Resource string
+	Message  string
+	// Source and Line are the log and line the event was reported on.
+	Source string
+	Line   int
+}
+
+// Victim describes what the event affected.
+func (e *Event) Victim() string {
+	var parts []string
+	if e.Pod != "" {
+		parts = append(parts, "pod "+e.Pod)
+	}
+	if e.Container != "" {
+		parts = append(parts, "container "+e.Container)
+	}
+	if e.Process != "" {
+		p := "process " + e.Process
+		if e.PID != 0 {
+			p += " (" + strconv.Itoa(e.PID) + ")"
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, ", ")
+}
+
+var months = map[string]time.Month{
+	"Jan": time.January, "Feb": time.February, "Mar": time.March, "Apr": time.April,
+	"May": time.May, "Jun": time.June, "Jul": time.July, "Aug": time.August,
+	"Sep": time.September, "Oct": time.October, "Nov": time.November, "Dec": time.December,
+}
+
+// lineTime parses the time at the start of a log line. klog and syslog times have no year, so
+// the year of reference is used, or the year before if that would put the time well after
+// reference. Times are assumed to be UTC.
+func lineTime(line string, reference time.Time) time.Time {
+	if m := isoRE.FindStringSubmatch(line); m != nil {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999-0700"} {
+			if t, err := time.Parse(layout, m[1]); err == nil {
+				return t
+			}
+		}
+	}
+	if m := dmesgTimeRE.FindStringSubmatch(line); m != nil {
+		if t, err := time.Parse("Mon Jan _2 15:04:05 2006", m[1]); err == nil {
+			return t
+		}
+	}
+	var month time.Month
+	var day int
+	var clock string
+	if m := syslogRE.FindStringSubmatch(line); m != nil {
+		month = months[m[1]]
+		day, _ = strconv.Atoi(m[2])
+		clock = m[3]
+	} else if m := klogRE.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		month = time.Month(n)
+		day, _ = strconv.Atoi(m[2])
+		clock = m[3]
+	}
+	if month == 0 || reference.IsZero() {
+		return time.Time{}
+	}
+	c, err := time.Parse("15:04:05.999999999", clock)
+	if err != nil {
+		return time.Time{}
+	}
+	t := time.Date(reference.Year(), month, day, c.Hour(), c.Minute(), c.Second(), c.Nanosecond(), time.UTC)
+	if t.Sub(reference) > 180*24*time.Hour {
+		// The job started in the previous year.
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// scanner finds the events in the logs of a job.
+type scanner struct {
+	reference time.Time
+	Events    []*Event
+}
+
+// scan adds the events in a log. The kernel reports each OOM kill on several lines, which are
+// merged into one event.
+func (s *scanner) scan(r io.Reader, source string) error {
+	kills := map[int]*Event{}
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1<<20)
+	number := 0
+	for lines.Scan() {
+		number++
+		line := lines.Text()
+		var e *Event
+		switch {
+		case killedRE.MatchString(line):
+			m := killedRE.FindStringSubmatch(line)
+			pid, _ := strconv.Atoi(m[2])
+			e = s.kill(kills, pid, source, number, line)
+			e.Process = m[3]
+			continue
+		case oomKillRE.MatchString(line):
+			m := oomKillRE.FindStringSubmatch(line)
+			pid, _ := strconv.Atoi(m[3])
+			e = s.kill(kills, pid, source, number, line)
+			e.Process = m[2]
+			if c := podCgroupRE.FindStringSubmatch(m[1]); c != nil {
+				e.Pod = strings.Replace(c[1], "_", "-", -1)
+				e.Container = shortID(c[2])
+			}
+			continue
+		case systemOOMRE.MatchString(line):
+			m := systemOOMRE.FindStringSubmatch(line)
+			e = &Event{Kind: kindOOM, Process: m[1]}
+			e.PID, _ = strconv.Atoi(m[2])
+		case oomKilledRE.MatchString(line):
+			e = &Event{Kind: kindOOM}
+		case evictedRE.MatchString(line):
+			m := evictedRE.FindStringSubmatch(line)
+			e = &Event{Kind: kindEviction, Pod: m[1] + m[2]}
+		case lowOnRE.MatchString(line):
+			e = &Event{Kind: kindEviction, Resource: lowOnRE.FindStringSubmatch(line)[1]}
+		case reclaimRE.MatchString(line):
+			e = &Event{Kind: kindPressure, Resource: reclaimRE.FindStringSubmatch(line)[1]}
+		default:
+			continue
+		}
+		if e.Pod == "" {
+			if m := podRE.FindStringSubmatch(line); m != nil {
+				e.Pod = m[1]
+			}
+		}
+		if e.Container == "" {
+			if m := containerRE.FindStringSubmatch(line); m != nil {
+				e.Container = m[1]
+			}
+		}
+		e.Time = lineTime(line, s.reference)
+		e.Message, e.Source, e.Line = strings.TrimSpace(line), source, number
+		s.Events = append(s.Events, e)
+	}
+	return lines.Err()
+}
+
+// kill returns the OOM kill of pid reported on lines near this one, or adds a new one.
+func (s *scanner) kill(kills map[int]*Event, pid int, source string, number int, line string) *Event {
+	if e, ok := kills[pid]; ok && number-e.Line <= maxKillLines {
+		return e
+	}
+	e := &Event{Kind: kindOOM, PID: pid, Time: lineTime(line, s.reference), Message: strings.TrimSpace(line), Source: source, Line: number}
+	kills[pid] = e
+	s.Events = append(s.Events, e)
+	return e
+}
+
+// maxKillLines is how far apart the lines reporting one OOM kill can be.
+const maxKillLines = 100
+
+// shortID shortens a container ID as docker and crictl do.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+const testKernelLog = `Oct  1 12:00:05 kind-worker kernel: stress invoked oom-killer: gfp_mask=0x6000c0(GFP_KERNEL), order=0, oom_score_adj=999
+Oct  1 12:00:05 kind-worker kernel: oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=abc,mems_allowed=0,oom_memcg=/kubepods/burstable/pod0f3c1a2b-1111-2222-3333-444455556666/0123456789abcdef0123,task_memcg=/kubepods/burstable/pod0f3c1a2b-1111-2222-3333-444455556666/0123456789abcdef0123,task=stress,pid=4242,uid=0
+Oct  1 12:00:05 kind-worker kernel: Memory cgroup out of memory: Killed process 4242 (stress) total-vm:1000kB, anon-rss:900kB, file-rss:0kB
+Oct  1 12:00:30 kind-worker kubelet[99]: W1001 12:00:30.000000      99 eviction_manager.go:333] eviction manager: attempting to reclaim memory
+Oct  1 12:00:31 kind-worker kubelet[99]: I1001 12:00:31.000000      99 eviction_manager.go:566] eviction manager: pod hog_default(abc-123) is evicted successfully
+[  123.456] Out of memory: Kill process 77 (java) score 900 or sacrifice child
+`
+
+func TestScan(t *testing.T) {
+	s := scanner{reference: time.Date(2019, time.October, 1, 11, 59, 0, 0, time.UTC)}
+	if err := s.scan(strings.NewReader(testKernelLog), "artifacts/kind/kind-worker/journal.log"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.Events) != 4 {
+		t.Fatalf("expected 4 events, got %d: %+v", len(s.Events), s.Events)
+	}
+	kill, reclaim, evicted, system := s.Events[0], s.Events[1], s.Events[2], s.Events[3]
+	if kill.Kind != kindOOM || kill.Process != "stress" || kill.PID != 4242 || kill.Line != 2 {
+		t.Errorf("expected the kill of stress on line 2, got %+v", kill)
+	}
+	if kill.Pod != "0f3c1a2b-1111-2222-3333-444455556666" || kill.Container != "0123456789ab" {
+		t.Errorf("expected the victim's pod and container from its cgroup, got %q and %q", kill.Pod, kill.Container)
+	}
+	if expected := time.Date(2019, time.October, 1, 12, 0, 5, 0, time.UTC); !kill.Time.Equal(expected) {
+		t.Errorf("expected the kill at %s, got %s", expected, kill.Time)
+	}
+	if reclaim.Kind != kindPressure || reclaim.Resource != "memory" {
+		t.Errorf("expected memory pressure, got %+v", reclaim)
+	}
+	if evicted.Kind != kindEviction || evicted.Pod != "hog_default(abc-123)" {
+		t.Errorf("expected the eviction of hog, got %+v", evicted)
+	}
+	if system.Kind != kindOOM || system.Process != "java" || !system.Time.IsZero() {
+		t.Errorf("expected the kill of java at an unknown time, got %+v", system)
+	}
+}
+
+func TestLineTime(t *testing.T) {
+	reference := time.Date(2020, time.January, 1, 0, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		line     string
+		expected time.Time
+	}{
+		{"E1231 23:59:58.500000    1 foo.go:1] bar", time.Date(2019, time.December, 31, 23, 59, 58, 500000000, time.UTC)},
+		{"Jan  1 00:31:00 node kernel: foo", time.Date(2020, time.January, 1, 0, 31, 0, 0, time.UTC)},
+		{"[Wed Jan  1 00:32:00 2020] foo", time.Date(2020, time.January, 1, 0, 32, 0, 0, time.UTC)},
+		{"2020-01-01T00:33:00.000+0000 node kubelet: foo", time.Date(2020, time.January, 1, 0, 33, 0, 0, time.UTC)},
+		{"[   12.345678] foo", time.Time{}},
+	}
+	for _, tc := range testCases {
+		if actual := lineTime(tc.line, reference); !actual.Equal(tc.expected) {
+			t.Errorf("expected %q at %s, got %s", tc.line, tc.expected, actual)
+		}
+	}
+}
+
+func TestRunning(t *testing.T) {
+	failure := "boom"
+	suites := []junit.Suite{
+		{
+			Timestamp: "2019-10-01T12:00:00",
+			Results: []junit.Result{
+				{Name: "first", Time: 4},
+				{Name: "skipped", Time: 100, Skipped: &failure},
+				{Name: "second", ClassName: "pkg", Time: 30, Failure: &failure},
+			},
+		},
+		{Results: []junit.Result{{Name: "untimed", Time: 1000}}},
+	}
+	tests := testWindows(suites)
+	if len(tests) != 2 {
+		t.Fatalf("expected 2 tests, got %+v", tests)
+	}
+	found := running(tests, time.Date(2019, time.October, 1, 12, 0, 5, 0, time.UTC))
+	if len(found) != 1 || found[0].Name != "pkg second" || !found[0].Failed {
+		t.Errorf("expected the failed second test to be running, got %+v", found)
+	}
+}
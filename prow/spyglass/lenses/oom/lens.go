@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oom provides a Spyglass lens that finds OOM kills and evictions in kubelet and kernel
+// logs, and the tests that were running when they happened.
+package oom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+const (
+	name     = "oom"
+	title    = "OOM Kills & Evictions"
+	priority = 47
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of an OOM kill and eviction-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each log is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// EventView is an event with the tests that were running when it happened.
+type EventView struct {
+	*Event
+	When  string
+	Link  string
+	Tests []Test
+}
+
+type oomView struct {
+	Errors                     []string
+	Logs                       []string
+	Events                     []EventView
+	Kills, Evictions, Pressure int
+	// Timed is whether any junit recorded when its tests ran.
+	Timed bool
+}
+
+// Body renders the OOM kills, evictions and resource pressure found in the logs, in the order
+// they happened.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := oomView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	var logs []lenses.Artifact
+	var suites []junit.Suite
+	var started time.Time
+	for _, a := range artifacts {
+		base := path.Base(a.JobPath())
+		switch {
+		case base == "started.json":
+			content, err := a.ReadAll()
+			var s gcs.Started
+			if err == nil {
+				err = json.Unmarshal(content, &s)
+			}
+			if err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+				continue
+			}
+			started = time.Unix(s.Timestamp, 0).UTC()
+		case strings.HasSuffix(base, ".xml"):
+			content, err := a.ReadAll()
+			var s junit.Suites
+			if err == nil {
+				s, err = junit.Parse(content)
+			}
+			if err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+				continue
+			}
+			suites = append(suites, s.Suites...)
+		default:
+			logs = append(logs, a)
+		}
+	}
+	tests := testWindows(suites)
+	view.Timed = len(tests) != 0
+	if started.IsZero() && len(tests) != 0 {
+		started = tests[0].Start
+	}
+
+	s := scanner{reference: started}
+	links := map[string]string{}
+	for _, a := range logs {
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			err = s.scan(r, a.JobPath())
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		view.Logs = append(view.Logs, a.JobPath())
+		links[a.JobPath()] = a.CanonicalLink()
+	}
+	sort.SliceStable(s.Events, func(i, j int) bool {
+		ti, tj := s.Events[i].Time, s.Events[j].Time
+		if ti.IsZero() || tj.IsZero() {
+			return !ti.IsZero() && tj.IsZero()
+		}
+		return ti.Before(tj)
+	})
+	for _, e := range s.Events {
+		ev := EventView{Event: e, Link: links[e.Source]}
+		if !e.Time.IsZero() {
+			ev.When = e.Time.Format(time.RFC3339)
+			ev.Tests = running(tests, e.Time)
+		}
+		switch e.Kind {
+		case kindOOM:
+			view.Kills++
+		case kindEviction:
+			view.Evictions++
+		case kindPressure:
+			view.Pressure++
+		}
+		view.Events = append(view.Events, ev)
+	}
+	if len(view.Events) == 0 && len(view.Errors) == 0 {
+		view.Errors = append(view.Errors, "No OOM kills or evictions found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oom
+
+import (
+	"time"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+// junitTimeLayouts are the timestamp formats junit producers are known to use.
+// Timestamps without a zone are assumed to be UTC.
+var junitTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+func parseJunitTime(s string) (time.Time, bool) {
+	for _, layout := range junitTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Test is a test case and when it ran.
+type Test struct {
+	Name       string
+	Failed     bool
+	Start, End time.Time
+}
+
+// testWindows estimates when each test of the suites ran. Junit records only when each suite
+// started and how long each test took, so the tests are assumed to have run one after another in
+// the order they are listed. Suites without a start time are left out.
+func testWindows(suites []junit.Suite) []Test {
+	var tests []Test
+	for _, s := range suites {
+		cursor, ok := parseJunitTime(s.Timestamp)
+		if !ok {
+			continue
+		}
+		for _, r := range s.Results {
+			if r.Skipped != nil {
+				continue
+			}
+			end := cursor.Add(time.Duration(r.Time * float64(time.Second)))
+			name := r.Name
+			if r.ClassName != "" {
+				name = r.ClassName + " " + name
+			}
+			tests = append(tests, Test{Name: name, Failed: r.Failure != nil, Start: cursor, End: end})
+			cursor = end
+		}
+	}
+	return tests
+}
+
+// running returns the tests that were running at t.
+func running(tests []Test, t time.Time) []Test {
+	var found []Test
+	for _, test := range tests {
+		if !t.Before(test.Start) && !t.After(test.End) {
+			found = append(found, test)
+		}
+	}
+	return found
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package perftests provides a Spyglass lens that charts the latency percentiles measured by
+// perf-tests, such as clusterloader2, and checks them against SLOs.
+package perftests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	name     = "perftests"
+	title    = "Performance SLOs"
+	priority = 48
+
+	// defaultMaxBytes is how much of each measurement is read if not configured.
+	defaultMaxBytes = 20 << 20
+)
+
+// Lens is the implementation of a perf-tests measurement-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// Thresholds are the SLOs measurements are checked against, the first that applies to each
+	// item being used. Defaults to the Kubernetes scalability SLOs for API call and pod startup
+	// latency.
+	Thresholds []Threshold `json:"thresholds,omitempty"`
+	// MaxBytes is how much of each measurement is read. Defaults to 20MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// ValueView is a value of an item, with the width of its bar as a percentage of the chart.
+type ValueView struct {
+	Name     string
+	Value    string
+	Width    float64
+	Violated bool
+}
+
+// ItemView is an item of a measurement and how it compares to its SLO.
+type ItemView struct {
+	Label  string
+	Count  int
+	Values []ValueView
+	Check  *Check
+	// Marker is the position of the SLO's threshold as a percentage of the chart.
+	Marker float64
+	// largest is the largest value of the item, for sorting.
+	largest float64
+}
+
+// Violated says whether the item did not meet its SLO.
+func (iv ItemView) Violated() bool {
+	return iv.Check != nil && iv.Check.Violated
+}
+
+// Max formats the item's threshold.
+func (iv ItemView) Max() string {
+	return formatValue(iv.Check.Threshold.Max, "ms")
+}
+
+// MeasurementView is the items of one measurement.
+type MeasurementView struct {
+	Name        string
+	Identifier  string
+	Path        string
+	Link        string
+	Error       string
+	Percentiles []string
+	Items       []ItemView
+	Checked     int
+	Violations  int
+}
+
+// Violation is an item that did not meet its SLO.
+type Violation struct {
+	Measurement string
+	Item        ItemView
+}
+
+type perftestsView struct {
+	Errors       []string
+	Measurements []MeasurementView
+	Violations   []Violation
+	Checked      int
+}
+
+// Body renders the measurements, those with SLO violations first.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := perftestsView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	if len(conf.Thresholds) == 0 {
+		conf.Thresholds = defaultThresholds
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		mv := MeasurementView{Path: a.JobPath(), Link: a.CanonicalLink()}
+		mv.Name, mv.Identifier = measurementName(a.JobPath())
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			mv.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+			view.Measurements = append(view.Measurements, mv)
+			continue
+		}
+		perfData, err := parsePerfData(content)
+		if err == errNotPerfData {
+			// Perf-tests also write summaries in other formats.
+			continue
+		}
+		if err != nil {
+			mv.Error = fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err)
+			view.Measurements = append(view.Measurements, mv)
+			continue
+		}
+		newMeasurementView(&mv, perfData, conf.Thresholds)
+		for _, item := range mv.Items {
+			if item.Violated() {
+				view.Violations = append(view.Violations, Violation{Measurement: mv.Name, Item: item})
+			}
+		}
+		view.Checked += mv.Checked
+		view.Measurements = append(view.Measurements, mv)
+	}
+	sort.SliceStable(view.Measurements, func(i, j int) bool {
+		return view.Measurements[i].Violations > view.Measurements[j].Violations
+	})
+	if len(view.Measurements) == 0 && len(view.Errors) == 0 {
+		view.Errors = append(view.Errors, "No perf-tests measurements found.")
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// newMeasurementView fills in the items of a measurement, checked against the thresholds. The
+// values of all items are drawn to the same scale, which includes their thresholds.
+func newMeasurementView(mv *MeasurementView, data *PerfData, thresholds []Threshold) {
+	mv.Percentiles = percentileNames(data.DataItems)
+	scale := 0.0
+	for i := range data.DataItems {
+		item := &data.DataItems[i]
+		iv := ItemView{Label: item.Label(), Count: item.Count(), Check: check(thresholds, mv.Name, item)}
+		if iv.Check != nil {
+			mv.Checked++
+			if iv.Violated() {
+				mv.Violations++
+			}
+			scale = math.Max(scale, iv.Check.Threshold.Max)
+		}
+		for _, p := range mv.Percentiles {
+			v, ok := item.Data[p]
+			if !ok {
+				iv.Values = append(iv.Values, ValueView{Name: p})
+				continue
+			}
+			unit := item.Unit
+			if s, ok := unitScale[unit]; ok {
+				v, unit = v*s, "ms"
+			}
+			vv := ValueView{Name: p, Value: formatValue(v, unit), Width: v}
+			if iv.Check != nil && iv.Check.Threshold.percentile() == p {
+				vv.Violated = iv.Check.Violated
+			}
+			iv.Values = append(iv.Values, vv)
+			iv.largest = math.Max(iv.largest, v)
+			scale = math.Max(scale, v)
+		}
+		mv.Items = append(mv.Items, iv)
+	}
+	for i := range mv.Items {
+		iv := &mv.Items[i]
+		for j := range iv.Values {
+			iv.Values[j].Width = percentOf(iv.Values[j].Width, scale)
+		}
+		if iv.Check != nil {
+			iv.Marker = percentOf(iv.Check.Threshold.Max, scale)
+		}
+	}
+	sort.SliceStable(mv.Items, func(i, j int) bool {
+		if vi, vj := mv.Items[i].Violated(), mv.Items[j].Violated(); vi != vj {
+			return vi
+		}
+		return mv.Items[i].largest > mv.Items[j].largest
+	})
+}
+
+func percentOf(v, scale float64) float64 {
+	if scale <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return math.Min(100, 100*v/scale)
+}
+
+// formatValue formats a value, with durations in milliseconds formatted as such.
+func formatValue(v float64, unit string) string {
+	switch {
+	case unit != "ms":
+		if unit == "" {
+			return chart.FormatValue(v)
+		}
+		return chart.FormatValue(v) + " " + unit
+	case v >= 1000:
+		return fmt.Sprintf("%.2fs", v/1000)
+	default:
+		return fmt.Sprintf("%.1fms", v)
+	}
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
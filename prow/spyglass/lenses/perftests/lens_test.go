@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perftests
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+const apiResponsiveness = `{
+  "version": "v1",
+  "dataItems": [
+    {"data": {"Perc50": 2.1, "Perc90": 10.5, "Perc99": 120}, "unit": "ms", "labels": {"Count": "40", "Resource": "pods", "Scope": "resource", "Subresource": "", "Verb": "GET"}},
+    {"data": {"Perc50": 800, "Perc90": 4000, "Perc99": 6500}, "unit": "ms", "labels": {"Count": "12", "Resource": "pods", "Scope": "namespace", "Subresource": "", "Verb": "LIST"}},
+    {"data": {"Perc50": 1, "Perc90": 2, "Perc99": 3}, "unit": "ms", "labels": {"Count": "3", "Resource": "nodes", "Verb": "WATCH"}}
+  ]
+}`
+
+func TestMeasurementName(t *testing.T) {
+	testCases := []struct {
+		path, name, identifier string
+	}{
+		{"artifacts/APIResponsivenessPrometheus_load_2019-10-01T12:00:00Z.json", "APIResponsivenessPrometheus", "load"},
+		{"artifacts/PodStartupLatency_PodStartupLatency_density_2019-10-01T12:00:00.123+02:00.json", "PodStartupLatency", "PodStartupLatency_density"},
+		{"artifacts/MetricsForE2E.json", "MetricsForE2E", ""},
+	}
+	for _, tc := range testCases {
+		if name, identifier := measurementName(tc.path); name != tc.name || identifier != tc.identifier {
+			t.Errorf("expected %s to be %q %q, got %q %q", tc.path, tc.name, tc.identifier, name, identifier)
+		}
+	}
+}
+
+func TestNewMeasurementView(t *testing.T) {
+	data, err := parsePerfData([]byte(apiResponsiveness))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mv := MeasurementView{Name: "APIResponsivenessPrometheus"}
+	newMeasurementView(&mv, data, defaultThresholds)
+	if mv.Checked != 2 || mv.Violations != 1 {
+		t.Errorf("expected 1 of 2 checked items to violate its SLO, got %d of %d", mv.Violations, mv.Checked)
+	}
+	if strings.Join(mv.Percentiles, " ") != "Perc50 Perc90 Perc99" {
+		t.Errorf("unexpected percentiles %q", mv.Percentiles)
+	}
+	list := mv.Items[0]
+	if !list.Violated() || list.Label != "Resource=pods Scope=namespace Verb=LIST" || list.Count != 12 {
+		t.Errorf("expected the LIST of pods first, got %+v", list)
+	}
+	if list.Values[2].Value != "6.50s" || !list.Values[2].Violated || list.Values[2].Width != 100 {
+		t.Errorf("expected its p99 to be the violation at the top of the scale, got %+v", list.Values[2])
+	}
+	if list.Marker != 100*5000/6500.0 || list.Max() != "5.00s" {
+		t.Errorf("expected the SLO of 5s to be marked at %.1f%%, got %s at %.1f%%", 100*5000/6500.0, list.Max(), list.Marker)
+	}
+	if watch := mv.Items[2]; watch.Check != nil || watch.Label != "Resource=nodes Verb=WATCH" {
+		t.Errorf("expected watches to be unchecked and last, got %+v", watch)
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/APIResponsiveness_load_2019-10-01T12:00:00Z.json", Content: []byte(apiResponsiveness)},
+		&fakeartifact.Artifact{Path: "artifacts/SchedulingThroughput_load_2019-10-01T12:00:00Z.json", Content: []byte(`{"perc50": 20, "max": 30}`)},
+	}
+	body := Lens{}.Body(artifacts, ".", "", nil)
+	for _, expected := range []string{"1 SLO violation", "Perc99 was", "6.50s", "over 5.00s"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the body to contain %q, got:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "SchedulingThroughput") {
+		t.Errorf("expected measurements in other formats to be left out, got:\n%s", body)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perftests
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// timestampRE matches the timestamp clusterloader2 appends to the names of the files it writes
+// measurements to, such as "_2019-10-01T12:00:00Z".
+var timestampRE = regexp.MustCompile(`_\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})$`)
+
+// errNotPerfData is returned for JSON that is not in the perfdata format.
+var errNotPerfData = errors.New("not in the perfdata format")
+
+// DataItem is a set of values measured for one thing, such as the latency of one kind of API
+// call, in the perfdata format.
+type DataItem struct {
+	// Data has the values by name, such as "Perc50", "Perc90" and "Perc99".
+	Data   map[string]float64 `json:"data"`
+	Unit   string             `json:"unit"`
+	Labels map[string]string  `json:"labels,omitempty"`
+}
+
+// Label returns the item's labels, sorted, except for the count of samples.
+func (d *DataItem) Label() string {
+	var parts []string
+	for k, v := range d.Labels {
+		if k == "Count" || v == "" {
+			continue
+		}
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// Count returns the number of samples the item was measured from, if it was recorded.
+func (d *DataItem) Count() int {
+	n, _ := strconv.Atoi(d.Labels["Count"])
+	return n
+}
+
+// PerfData is the measurements perf-tests write, such as clusterloader2's APIResponsiveness and
+// PodStartupLatency.
+type PerfData struct {
+	Version   string            `json:"version"`
+	DataItems []DataItem        `json:"dataItems"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// parsePerfData parses a measurement.
+func parsePerfData(content []byte) (*PerfData, error) {
+	var data PerfData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	if data.DataItems == nil {
+		return nil, errNotPerfData
+	}
+	return &data, nil
+}
+
+// measurementName splits the name of a file clusterloader2 wrote a measurement to, such as
+// "APIResponsivenessPrometheus_load_2019-10-01T12:00:00Z.json", into the name of the
+// measurement and what identifies this run of it.
+func measurementName(p string) (name, identifier string) {
+	base := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	base = timestampRE.ReplaceAllString(base, "")
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// unitScale is how many milliseconds there are in each unit of time.
+var unitScale = map[string]float64{
+	"ns": 1e-6,
+	"us": 1e-3,
+	"µs": 1e-3,
+	"ms": 1,
+	"s":  1e3,
+	"m":  60e3,
+	"h":  3600e3,
+}
+
+// percentileNames returns the names of the values measured by any of the items, in a natural
+// order: percentiles in increasing order, then other values by name.
+func percentileNames(items []DataItem) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, item := range items {
+		for k := range item.Data {
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, oki := percentile(names[i])
+		pj, okj := percentile(names[j])
+		if oki != okj {
+			return oki
+		}
+		if oki && pi != pj {
+			return pi < pj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// percentile returns the percentile a value is, such as 99 for "Perc99".
+func percentile(name string) (float64, bool) {
+	if !strings.HasPrefix(name, "Perc") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(strings.TrimPrefix(name, "Perc"), 64)
+	return p, err == nil
+}
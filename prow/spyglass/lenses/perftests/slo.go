@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perftests
+
+import (
+	"strings"
+)
+
+// Threshold is an SLO for the values measured for some items.
+type Threshold struct {
+	// Measurement is the prefix of the names of the measurements the threshold applies to, such
+	// as "APIResponsiveness".
+	Measurement string `json:"measurement"`
+	// Labels must all have these values for the threshold to apply to an item.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Percentile is the value compared against the threshold. Defaults to "Perc99".
+	Percentile string `json:"percentile,omitempty"`
+	// Max is the largest value meeting the SLO, in milliseconds for items measured in units of
+	// time and in the item's unit otherwise.
+	Max float64 `json:"max"`
+	// Description says what the SLO is.
+	Description string `json:"description,omitempty"`
+}
+
+// defaultThresholds are the Kubernetes scalability SLOs for API call and pod startup latency.
+var defaultThresholds = []Threshold{
+	{
+		Measurement: "APIResponsiveness",
+		Labels:      map[string]string{"Scope": "resource"},
+		Max:         1000,
+		Description: "p99 of single object API calls at most 1s",
+	},
+	{
+		Measurement: "APIResponsiveness",
+		Labels:      map[string]string{"Scope": "namespace"},
+		Max:         5000,
+		Description: "p99 of namespace-scoped LIST calls at most 5s",
+	},
+	{
+		Measurement: "APIResponsiveness",
+		Labels:      map[string]string{"Scope": "cluster"},
+		Max:         30000,
+		Description: "p99 of cluster-scoped LIST calls at most 30s",
+	},
+	{
+		Measurement: "PodStartupLatency",
+		Labels:      map[string]string{"Metric": "pod_startup"},
+		Max:         5000,
+		Description: "p99 of pod startup at most 5s",
+	},
+}
+
+// percentile returns the name of the value the threshold applies to.
+func (t *Threshold) percentile() string {
+	if t.Percentile == "" {
+		return "Perc99"
+	}
+	return t.Percentile
+}
+
+// matches says whether the threshold applies to an item of a measurement.
+func (t *Threshold) matches(measurement string, item *DataItem) bool {
+	if !strings.HasPrefix(measurement, t.Measurement) {
+		return false
+	}
+	for k, v := range t.Labels {
+		if item.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Check is an item's value compared against the threshold that applies to it.
+type Check struct {
+	Threshold *Threshold
+	// Value is the item's value, in the threshold's unit.
+	Value    float64
+	Violated bool
+}
+
+// Percentile returns the name of the value that was compared.
+func (c *Check) Percentile() string {
+	return c.Threshold.percentile()
+}
+
+// check compares an item against the first of the thresholds that applies to it, returning nil
+// if none do or the item has no value to compare.
+func check(thresholds []Threshold, measurement string, item *DataItem) *Check {
+	for i := range thresholds {
+		t := &thresholds[i]
+		if !t.matches(measurement, item) {
+			continue
+		}
+		v, ok := item.Data[t.percentile()]
+		if !ok {
+			return nil
+		}
+		if scale, ok := unitScale[item.Unit]; ok {
+			v *= scale
+		}
+		return &Check{Threshold: t, Value: v, Violated: v > t.Max}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pprof provides a Spyglass lens that renders flame graphs and top tables for pprof profiles.
+package pprof
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "pprof"
+	title    = "Profiles"
+	priority = 15
+
+	// minFlameFraction is the smallest fraction of the total a frame must account for to be drawn.
+	minFlameFraction = 0.005
+	// maxFlameDepth bounds the depth of the rendered flame graph.
+	maxFlameDepth = 64
+	// maxTopEntries is the number of functions shown in the top table.
+	maxTopEntries = 50
+	// flameColors is the number of colour classes available in the stylesheet.
+	flameColors = 8
+	// maxProfileSize bounds the size of a gzip-compressed profile once decompressed, matching
+	// Spyglass's default artifact size limit.
+	maxProfileSize = 100e6
+)
+
+// Lens is the implementation of a pprof-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// request is sent by the frontend to select a profile and sample type.
+type request struct {
+	Artifact string `json:"artifact"`
+	Sample   *int   `json:"sample,omitempty"`
+}
+
+// FlameNode is a single frame in the rendered flame graph.
+type FlameNode struct {
+	Name  string
+	Value string
+	// Percent is the share of the whole profile, Width is the share of the parent frame.
+	Percent  float64
+	Width    float64
+	Color    int
+	Children []*FlameNode
+}
+
+// TopEntry is a row in the top table.
+type TopEntry struct {
+	Name        string
+	Flat        string
+	FlatPercent float64
+	Cum         string
+	CumPercent  float64
+}
+
+type profileView struct {
+	Artifacts   []string
+	Artifact    string
+	SampleTypes []valueType
+	Sample      int
+	Total       string
+	Flame       *FlameNode
+	Top         []TopEntry
+	Error       string
+}
+
+// Body renders the flame graph and top table for the selected profile.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	if len(artifacts) == 0 {
+		return executeTemplate(resourceDir, "body", profileView{Error: "no profiles found"})
+	}
+	var req request
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &req); err != nil {
+			logrus.WithError(err).Info("Failed to parse pprof lens request.")
+		}
+	}
+
+	view := profileView{}
+	selected := artifacts[0]
+	for _, a := range artifacts {
+		view.Artifacts = append(view.Artifacts, a.JobPath())
+		if a.JobPath() == req.Artifact {
+			selected = a
+		}
+	}
+	view.Artifact = selected.JobPath()
+
+	content, err := selected.ReadAll()
+	if err != nil {
+		view.Error = fmt.Sprintf("failed to read %s: %v", selected.JobPath(), err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+	p, err := parseProfile(content, maxProfileSize)
+	if err != nil {
+		view.Error = fmt.Sprintf("failed to parse %s: %v", selected.JobPath(), err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+	if len(p.sampleTypes) == 0 {
+		view.Error = fmt.Sprintf("%s contains no sample types", selected.JobPath())
+		return executeTemplate(resourceDir, "body", view)
+	}
+	view.SampleTypes = p.sampleTypes
+	// Like the pprof tool, default to the last sample type (e.g. inuse_space for heap profiles).
+	view.Sample = len(p.sampleTypes) - 1
+	if req.Sample != nil && *req.Sample >= 0 && *req.Sample < len(p.sampleTypes) {
+		view.Sample = *req.Sample
+	}
+
+	unit := p.sampleTypes[view.Sample].Unit
+	root, total := buildTree(p, view.Sample)
+	view.Total = formatValue(total, unit)
+	if total > 0 {
+		view.Flame = flameGraph(root, total, unit, 0)
+		view.Flame.Width = 100
+	}
+	view.Top = topTable(p, view.Sample, total, unit)
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// treeNode accumulates sample values by call stack.
+type treeNode struct {
+	name     string
+	value    int64
+	children map[string]*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	if n.children == nil {
+		n.children = map[string]*treeNode{}
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &treeNode{name: name}
+		n.children[name] = c
+	}
+	return c
+}
+
+func buildTree(p *profile, sampleIndex int) (*treeNode, int64) {
+	root := &treeNode{name: "root"}
+	for _, s := range p.samples {
+		if sampleIndex >= len(s.values) {
+			continue
+		}
+		v := s.values[sampleIndex]
+		if v == 0 {
+			continue
+		}
+		root.value += v
+		n := root
+		for _, fn := range p.stack(s) {
+			n = n.child(fn)
+			n.value += v
+		}
+	}
+	return root, root.value
+}
+
+func flameGraph(n *treeNode, total int64, unit string, depth int) *FlameNode {
+	f := &FlameNode{
+		Name:    n.name,
+		Value:   formatValue(n.value, unit),
+		Percent: 100 * float64(n.value) / float64(total),
+		Color:   colorFor(n.name),
+	}
+	if depth >= maxFlameDepth {
+		return f
+	}
+	var children []*treeNode
+	for _, c := range n.children {
+		if float64(c.value) >= minFlameFraction*float64(total) {
+			children = append(children, c)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].value == children[j].value {
+			return children[i].name < children[j].name
+		}
+		return children[i].value > children[j].value
+	})
+	for _, c := range children {
+		child := flameGraph(c, total, unit, depth+1)
+		child.Width = 100 * float64(c.value) / float64(n.value)
+		f.Children = append(f.Children, child)
+	}
+	return f
+}
+
+func topTable(p *profile, sampleIndex int, total int64, unit string) []TopEntry {
+	flat := map[string]int64{}
+	cum := map[string]int64{}
+	for _, s := range p.samples {
+		if sampleIndex >= len(s.values) || s.values[sampleIndex] == 0 {
+			continue
+		}
+		v := s.values[sampleIndex]
+		stack := p.stack(s)
+		if len(stack) == 0 {
+			continue
+		}
+		flat[stack[len(stack)-1]] += v
+		// Recursive functions must only be counted once per sample.
+		seen := map[string]bool{}
+		for _, fn := range stack {
+			if !seen[fn] {
+				cum[fn] += v
+				seen[fn] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(cum))
+	for fn := range cum {
+		names = append(names, fn)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if flat[names[i]] != flat[names[j]] {
+			return flat[names[i]] > flat[names[j]]
+		}
+		if cum[names[i]] != cum[names[j]] {
+			return cum[names[i]] > cum[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxTopEntries {
+		names = names[:maxTopEntries]
+	}
+	var entries []TopEntry
+	for _, fn := range names {
+		e := TopEntry{Name: fn, Flat: formatValue(flat[fn], unit), Cum: formatValue(cum[fn], unit)}
+		if total > 0 {
+			e.FlatPercent = 100 * float64(flat[fn]) / float64(total)
+			e.CumPercent = 100 * float64(cum[fn]) / float64(total)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// formatValue renders a sample value in a human-friendly way for the common pprof units.
+func formatValue(v int64, unit string) string {
+	switch unit {
+	case "nanoseconds":
+		return time.Duration(v).String()
+	case "bytes":
+		const unit = 1024
+		if v < unit && v > -unit {
+			return fmt.Sprintf("%dB", v)
+		}
+		f := float64(v)
+		for _, suffix := range []string{"kB", "MB", "GB", "TB"} {
+			f /= unit
+			if f < unit && f > -unit {
+				return fmt.Sprintf("%.2f%s", f, suffix)
+			}
+		}
+		return fmt.Sprintf("%.2fPB", f/unit)
+	default:
+		return fmt.Sprintf("%d", v)
+	}
+}
+
+// colorFor picks a stable colour class for a function name.
+func colorFor(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % flameColors)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,30 @@
+function selectedValue(id: string): string | undefined {
+  const select = document.getElementById(id) as HTMLSelectElement | null;
+  return select ? select.value : undefined;
+}
+
+function handleArtifactChange(): void {
+  // Sample types differ between profiles, so let the server pick a default.
+  spyglass.updatePage(JSON.stringify({artifact: selectedValue('pprof-artifact')})).then(bind);
+}
+
+function handleSampleChange(): void {
+  spyglass.updatePage(JSON.stringify({
+    artifact: selectedValue('pprof-artifact'),
+    sample: Number(selectedValue('pprof-sample')),
+  })).then(bind);
+}
+
+// The page is replaced wholesale on update, so listeners need to be reattached.
+function bind(): void {
+  const artifact = document.getElementById('pprof-artifact');
+  if (artifact) {
+    artifact.addEventListener('change', handleArtifactChange);
+  }
+  const sample = document.getElementById('pprof-sample');
+  if (sample) {
+    sample.addEventListener('change', handleSampleChange);
+  }
+}
+
+window.addEventListener('DOMContentLoaded', bind);
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pprof
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The messages below declare the subset of the pprof profile.proto format that the lens reads, so
+// that profiles can be decoded with the protobuf library without generated code; other fields are
+// skipped. See https://github.com/google/pprof/blob/master/proto/profile.proto for the full format.
+
+type pbProfile struct {
+	SampleType  []*pbValueType `protobuf:"bytes,1,rep,name=sample_type,proto3"`
+	Sample      []*pbSample    `protobuf:"bytes,2,rep,name=sample,proto3"`
+	Location    []*pbLocation  `protobuf:"bytes,4,rep,name=location,proto3"`
+	Function    []*pbFunction  `protobuf:"bytes,5,rep,name=function,proto3"`
+	StringTable []string       `protobuf:"bytes,6,rep,name=string_table,proto3"`
+}
+
+type pbValueType struct {
+	Type int64 `protobuf:"varint,1,opt,name=type,proto3"`
+	Unit int64 `protobuf:"varint,2,opt,name=unit,proto3"`
+}
+
+type pbSample struct {
+	LocationID []uint64 `protobuf:"varint,1,rep,packed,name=location_id,proto3"`
+	Value      []int64  `protobuf:"varint,2,rep,packed,name=value,proto3"`
+}
+
+type pbLocation struct {
+	ID   uint64    `protobuf:"varint,1,opt,name=id,proto3"`
+	Line []*pbLine `protobuf:"bytes,4,rep,name=line,proto3"`
+}
+
+type pbLine struct {
+	FunctionID uint64 `protobuf:"varint,1,opt,name=function_id,proto3"`
+}
+
+type pbFunction struct {
+	ID   uint64 `protobuf:"varint,1,opt,name=id,proto3"`
+	Name int64  `protobuf:"varint,2,opt,name=name,proto3"`
+}
+
+func (m *pbProfile) Reset()         { *m = pbProfile{} }
+func (m *pbProfile) String() string { return proto.CompactTextString(m) }
+func (*pbProfile) ProtoMessage()    {}
+
+func (m *pbValueType) Reset()         { *m = pbValueType{} }
+func (m *pbValueType) String() string { return proto.CompactTextString(m) }
+func (*pbValueType) ProtoMessage()    {}
+
+func (m *pbSample) Reset()         { *m = pbSample{} }
+func (m *pbSample) String() string { return proto.CompactTextString(m) }
+func (*pbSample) ProtoMessage()    {}
+
+func (m *pbLocation) Reset()         { *m = pbLocation{} }
+func (m *pbLocation) String() string { return proto.CompactTextString(m) }
+func (*pbLocation) ProtoMessage()    {}
+
+func (m *pbLine) Reset()         { *m = pbLine{} }
+func (m *pbLine) String() string { return proto.CompactTextString(m) }
+func (*pbLine) ProtoMessage()    {}
+
+func (m *pbFunction) Reset()         { *m = pbFunction{} }
+func (m *pbFunction) String() string { return proto.CompactTextString(m) }
+func (*pbFunction) ProtoMessage()    {}
+
+// valueType describes the type and unit of a sample value, e.g. "cpu" and "nanoseconds".
+type valueType struct {
+	Type string
+	Unit string
+}
+
+type sample struct {
+	locationIDs []uint64
+	values      []int64
+}
+
+type location struct {
+	// functionIDs holds one entry per line, innermost (inlined) function first.
+	functionIDs []uint64
+}
+
+type profile struct {
+	sampleTypes []valueType
+	samples     []sample
+	locations   map[uint64]location
+	functions   map[uint64]string
+}
+
+// parseProfile parses a profile, which may be gzip-compressed. Profiles larger than maxSize bytes
+// once decompressed are rejected.
+func parseProfile(data []byte, maxSize int64) (*profile, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress profile: %v", err)
+		}
+		if data, err = ioutil.ReadAll(io.LimitReader(gz, maxSize+1)); err != nil {
+			return nil, fmt.Errorf("failed to decompress profile: %v", err)
+		}
+		if int64(len(data)) > maxSize {
+			return nil, fmt.Errorf("decompressed profile is larger than %d bytes", maxSize)
+		}
+	}
+
+	var pb pbProfile
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %v", err)
+	}
+
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(pb.StringTable)) {
+			return ""
+		}
+		return pb.StringTable[i]
+	}
+	p := &profile{locations: map[uint64]location{}, functions: map[uint64]string{}}
+	for _, st := range pb.SampleType {
+		p.sampleTypes = append(p.sampleTypes, valueType{Type: str(st.Type), Unit: str(st.Unit)})
+	}
+	for _, s := range pb.Sample {
+		p.samples = append(p.samples, sample{locationIDs: s.LocationID, values: s.Value})
+	}
+	for _, l := range pb.Location {
+		var loc location
+		for _, line := range l.Line {
+			loc.functionIDs = append(loc.functionIDs, line.FunctionID)
+		}
+		p.locations[l.ID] = loc
+	}
+	for _, f := range pb.Function {
+		p.functions[f.ID] = str(f.Name)
+	}
+	return p, nil
+}
+
+// stack returns the function names of a sample, root first.
+func (p *profile) stack(s sample) []string {
+	var names []string
+	// Locations are stored leaf first, and lines within a location innermost first.
+	for i := len(s.locationIDs) - 1; i >= 0; i-- {
+		loc := p.locations[s.locationIDs[i]]
+		for j := len(loc.functionIDs) - 1; j >= 0; j-- {
+			name := p.functions[loc.functionIDs[j]]
+			if name == "" {
+				name = "<unknown>"
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pprof
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// message is a tiny protobuf encoder, just capable enough to produce test profiles.
+type message []byte
+
+func (m message) varint(field int, v uint64) message {
+	m = appendUvarint(m, uint64(field<<3|proto.WireVarint))
+	return appendUvarint(m, v)
+}
+
+func (m message) bytes(field int, b []byte) message {
+	m = appendUvarint(m, uint64(field<<3|proto.WireBytes))
+	m = appendUvarint(m, uint64(len(b)))
+	return append(m, b...)
+}
+
+func (m message) packed(field int, vs ...uint64) message {
+	var b []byte
+	for _, v := range vs {
+		b = appendUvarint(b, v)
+	}
+	return m.bytes(field, b)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	return append(b, proto.EncodeVarint(v)...)
+}
+
+// testProfile has two samples: main -> work -> spin (30) and main -> work (10), inlining spin into work's location.
+func testProfile() []byte {
+	var p message
+	for _, s := range []string{"", "samples", "count", "cpu", "nanoseconds", "main", "work", "spin"} {
+		p = p.bytes(6, []byte(s))
+	}
+	p = p.bytes(1, message{}.varint(1, 1).varint(2, 2))
+	p = p.bytes(1, message{}.varint(1, 3).varint(2, 4))
+	for id, nameIndex := range map[uint64]uint64{1: 5, 2: 6, 3: 7} {
+		p = p.bytes(5, message{}.varint(1, id).varint(2, nameIndex))
+	}
+	p = p.bytes(4, message{}.varint(1, 1).bytes(4, message{}.varint(1, 1)))
+	// Location 2 holds spin inlined into work: innermost line first.
+	p = p.bytes(4, message{}.varint(1, 2).bytes(4, message{}.varint(1, 3)).bytes(4, message{}.varint(1, 2)))
+	p = p.bytes(4, message{}.varint(1, 3).bytes(4, message{}.varint(1, 2)))
+	p = p.bytes(2, message{}.packed(1, 2, 1).packed(2, 3, 30))
+	// Unpacked encoding is also valid.
+	p = p.bytes(2, message{}.varint(1, 3).varint(1, 1).varint(2, 1).varint(2, 10))
+	return p
+}
+
+func TestParseProfile(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write(testProfile())
+	w.Close()
+
+	for name, data := range map[string][]byte{"raw": testProfile(), "gzipped": gzipped.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			p, err := parseProfile(data, maxProfileSize)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedTypes := []valueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}}
+			if !reflect.DeepEqual(p.sampleTypes, expectedTypes) {
+				t.Errorf("expected sample types %v, got %v", expectedTypes, p.sampleTypes)
+			}
+			if len(p.samples) != 2 {
+				t.Fatalf("expected 2 samples, got %d", len(p.samples))
+			}
+			if stack := p.stack(p.samples[0]); !reflect.DeepEqual(stack, []string{"main", "work", "spin"}) {
+				t.Errorf("expected stack main -> work -> spin, got %v", stack)
+			}
+			if !reflect.DeepEqual(p.samples[1].values, []int64{1, 10}) {
+				t.Errorf("expected values [1 10], got %v", p.samples[1].values)
+			}
+		})
+	}
+}
+
+func TestParseProfileTruncated(t *testing.T) {
+	data := testProfile()
+	if _, err := parseProfile(data[:len(data)-3], maxProfileSize); err == nil {
+		t.Error("expected an error parsing a truncated profile")
+	}
+}
+
+func TestParseProfileTooLarge(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write(make([]byte, 1000))
+	w.Close()
+	if _, err := parseProfile(gzipped.Bytes(), 999); err == nil {
+		t.Error("expected an error parsing a profile that decompresses past the limit")
+	}
+}
+
+func TestTopTable(t *testing.T) {
+	p, err := parseProfile(testProfile(), maxProfileSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, total := buildTree(p, 1)
+	if total != 40 {
+		t.Fatalf("expected total of 40, got %d", total)
+	}
+	top := topTable(p, 1, total, "count")
+	expected := []TopEntry{
+		{Name: "spin", Flat: "30", FlatPercent: 75, Cum: "30", CumPercent: 75},
+		{Name: "work", Flat: "10", FlatPercent: 25, Cum: "40", CumPercent: 100},
+		{Name: "main", Flat: "0", FlatPercent: 0, Cum: "40", CumPercent: 100},
+	}
+	if !reflect.DeepEqual(top, expected) {
+		t.Errorf("expected top table %v, got %v", expected, top)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	testCases := []struct {
+		value    int64
+		unit     string
+		expected string
+	}{
+		{value: 1500000000, unit: "nanoseconds", expected: "1.5s"},
+		{value: 512, unit: "bytes", expected: "512B"},
+		{value: 3 * 1024 * 1024, unit: "bytes", expected: "3.00MB"},
+		{value: 42, unit: "count", expected: "42"},
+	}
+	for _, tc := range testCases {
+		if actual := formatValue(tc.value, tc.unit); actual != tc.expected {
+			t.Errorf("formatValue(%d, %q): expected %q, got %q", tc.value, tc.unit, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+// Chart is the rendered form of a single query.
+type Chart struct {
+	Title string
+	Query string
+	Unit  string
+	Error string
+	// Plot is populated if any series has more than one point, otherwise Values is.
+	Plot   *chart.Chart
+	Values []Value
+	// XLabel describes the x axis.
+	XLabel string
+	// Omitted is the number of matching series that were not drawn.
+	Omitted int
+}
+
+// Value is the latest value of a series, for series that were only observed once.
+type Value struct {
+	Label string
+	Value string
+}
+
+// newEmptyChart returns a chart for the query with no content.
+func newEmptyChart(q query) Chart {
+	c := Chart{Title: q.Title, Query: q.Query, Unit: q.Unit}
+	if c.Title == "" {
+		c.Title = q.Query
+	}
+	return c
+}
+
+// newChart renders the given series. Series should already be transformed (e.g. by rate) and
+// have their points in order.
+func newChart(q query, matched []*series) Chart {
+	c := newEmptyChart(q)
+	if len(matched) == 0 {
+		c.Error = "No series matched this query."
+		return c
+	}
+	if len(matched) > maxSeriesPerChart {
+		c.Omitted = len(matched) - maxSeriesPerChart
+		matched = matched[:maxSeriesPerChart]
+	}
+
+	plotted := false
+	for _, s := range matched {
+		if len(s.Points) > 1 {
+			plotted = true
+			break
+		}
+	}
+	if !plotted {
+		for _, s := range matched {
+			v := Value{Label: s.String(), Value: "no data"}
+			if len(s.Points) > 0 {
+				v.Value = chart.FormatValue(s.Points[len(s.Points)-1].Value)
+			}
+			c.Values = append(c.Values, v)
+		}
+		return c
+	}
+
+	timed := true
+	for _, s := range matched {
+		timed = timed && s.timed()
+	}
+	var lines []chart.Series
+	minX := math.Inf(1)
+	snapshots := map[int]bool{}
+	for _, s := range matched {
+		line := chart.Series{Label: s.String()}
+		for _, p := range s.Points {
+			x := float64(p.Snapshot)
+			if timed {
+				x = float64(p.Time)
+			}
+			minX = math.Min(minX, x)
+			snapshots[p.Snapshot] = true
+			line.Points = append(line.Points, chart.Point{X: x, Y: p.Value})
+		}
+		lines = append(lines, line)
+	}
+
+	var opts chart.Options
+	if timed {
+		c.XLabel = "time since first sample"
+		opts.FormatX = func(x float64) string {
+			return (time.Duration(x-minX) * time.Millisecond).Round(time.Second).String()
+		}
+	} else {
+		c.XLabel = "snapshot"
+		var indices []int
+		for i := range snapshots {
+			indices = append(indices, i)
+		}
+		sort.Ints(indices)
+		step := (len(indices) + tickCount) / (tickCount + 1)
+		for i := 0; i < len(indices); i += step {
+			opts.XTicks = append(opts.XTicks, float64(indices[i]))
+		}
+		opts.FormatX = func(x float64) string {
+			return fmt.Sprintf("#%d", int(x)+1)
+		}
+	}
+	plot, err := chart.New(lines, opts)
+	if err != nil {
+		c.Error = "The matching series have no finite values."
+		return c
+	}
+	c.Plot = plot
+	return c
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prometheus provides a Spyglass lens that charts metrics from Prometheus text format dumps.
+package prometheus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "prometheus"
+	title    = "Metrics"
+	priority = 17
+
+	// maxSeriesPerChart bounds the number of lines drawn on one chart.
+	maxSeriesPerChart = 10
+	// tickCount is the maximum number of snapshots labelled on the x axis.
+	tickCount = 6
+)
+
+// Lens is the implementation of a Prometheus metrics-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// query is a chart to draw.
+type query struct {
+	// Title is shown above the chart. Defaults to the query.
+	Title string `json:"title,omitempty"`
+	// Query is a Prometheus series selector, e.g. apiserver_request_count{verb="GET"}.
+	Query string `json:"query"`
+	// Unit is shown next to the title.
+	Unit string `json:"unit,omitempty"`
+	// Rate charts the per-second rate of increase of a counter instead of its value.
+	Rate bool `json:"rate,omitempty"`
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// Queries are charted in order.
+	Queries []query `json:"queries,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+type metricsView struct {
+	Snapshots []string
+	Errors    []string
+	Charts    []Chart
+	Families  []family
+	// Configured is true if any queries were configured for the lens.
+	Configured bool
+	// Query is the ad-hoc query entered by the user, if any.
+	Query query
+}
+
+// Body charts the configured queries, and any query requested by the frontend.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := metricsView{}
+	var conf config
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	view.Configured = len(conf.Queries) > 0
+	queries := conf.Queries
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &view.Query); err != nil {
+			logrus.WithError(err).Info("Failed to parse prometheus lens request.")
+		} else if view.Query.Query != "" {
+			queries = append([]query{view.Query}, queries...)
+		}
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	metrics := newMetricSet()
+	for i, a := range artifacts {
+		view.Snapshots = append(view.Snapshots, a.JobPath())
+		content, err := a.ReadAll()
+		if err == nil {
+			err = metrics.add(i, content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+		}
+	}
+
+	for _, q := range queries {
+		view.Charts = append(view.Charts, render(metrics, q))
+	}
+	view.Families = metrics.sortedFamilies()
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// render evaluates a query and draws a chart of the result.
+func render(metrics *metricSet, q query) Chart {
+	sel, err := parseSelector(q.Query)
+	if err != nil {
+		c := newEmptyChart(q)
+		c.Error = fmt.Sprintf("Invalid query: %v", err)
+		return c
+	}
+	var matched []*series
+	for _, s := range metrics.query(sel) {
+		timed := s.timed()
+		points := append([]point(nil), s.Points...)
+		sort.SliceStable(points, func(i, j int) bool {
+			if timed {
+				return points[i].Time < points[j].Time
+			}
+			return points[i].Snapshot < points[j].Snapshot
+		})
+		if q.Rate {
+			points = rate(points, timed)
+		}
+		matched = append(matched, &series{Name: s.Name, Labels: s.Labels, Points: points})
+	}
+	return newChart(q, matched)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
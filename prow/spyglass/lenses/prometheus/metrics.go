@@ -0,0 +1,457 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// labelPair is a single label on a series.
+type labelPair struct {
+	Name  string
+	Value string
+}
+
+// point is a single observation of a series.
+type point struct {
+	// Snapshot is the index of the dump the point came from.
+	Snapshot int
+	// Time is the sample timestamp in milliseconds, or zero if the dump did not include one.
+	Time  int64
+	Value float64
+}
+
+// series is every observation of a metric with a particular set of labels.
+type series struct {
+	Name   string
+	Labels []labelPair
+	Points []point
+}
+
+// String formats the series the way Prometheus does, e.g. foo{bar="baz"}.
+func (s *series) String() string {
+	if len(s.Labels) == 0 {
+		return s.Name
+	}
+	var parts []string
+	for _, l := range s.Labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", l.Name, l.Value))
+	}
+	return s.Name + "{" + strings.Join(parts, ", ") + "}"
+}
+
+// timed reports whether every point in the series carries a timestamp.
+func (s *series) timed() bool {
+	for _, p := range s.Points {
+		if p.Time == 0 {
+			return false
+		}
+	}
+	return len(s.Points) > 0
+}
+
+// family describes a metric family found in the dumps.
+type family struct {
+	Name   string
+	Type   string
+	Help   string
+	Series int
+}
+
+// metricSet accumulates the series from a sequence of metric dumps.
+type metricSet struct {
+	families map[string]*family
+	series   map[string]*series
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{families: map[string]*family{}, series: map[string]*series{}}
+}
+
+// add parses a dump in the Prometheus text format (or the compatible subset of OpenMetrics)
+// and records its samples as the given snapshot.
+func (ms *metricSet) add(snapshot int, data []byte) error {
+	// The parser requires the final line to end with a newline.
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data[:len(data):len(data)], '\n')
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(normalizeOpenMetrics(data)))
+	if err != nil {
+		return err
+	}
+	for name, mf := range families {
+		f, ok := ms.families[name]
+		if !ok {
+			f = &family{Name: name, Type: strings.ToLower(mf.GetType().String()), Help: mf.GetHelp()}
+			ms.families[name] = f
+		}
+		for _, m := range mf.Metric {
+			labels := make([]labelPair, 0, len(m.Label))
+			for _, l := range m.Label {
+				labels = append(labels, labelPair{Name: l.GetName(), Value: l.GetValue()})
+			}
+			p := point{Snapshot: snapshot, Time: m.GetTimestampMs()}
+			record := func(name string, value float64, extra ...labelPair) {
+				if ms.record(name, append(labels[:len(labels):len(labels)], extra...), p, value) {
+					f.Series++
+				}
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				record(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				record(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				record(name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					record(name, q.GetValue(), labelPair{Name: "quantile", Value: formatFloat(q.GetQuantile())})
+				}
+				record(name+"_sum", s.GetSampleSum())
+				record(name+"_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.Bucket {
+					record(name+"_bucket", float64(b.GetCumulativeCount()), labelPair{Name: "le", Value: formatFloat(b.GetUpperBound())})
+				}
+				record(name+"_bucket", float64(h.GetSampleCount()), labelPair{Name: "le", Value: "+Inf"})
+				record(name+"_sum", h.GetSampleSum())
+				record(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return nil
+}
+
+// record adds a point to a series, and returns true if the series is new.
+func (ms *metricSet) record(name string, labels []labelPair, p point, value float64) bool {
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	s := &series{Name: name, Labels: labels}
+	key := s.String()
+	existing, ok := ms.series[key]
+	if ok {
+		s = existing
+	} else {
+		ms.series[key] = s
+	}
+	p.Value = value
+	s.Points = append(s.Points, p)
+	return !ok
+}
+
+// sortedFamilies returns the metric families ordered by name.
+func (ms *metricSet) sortedFamilies() []family {
+	var result []family
+	for _, f := range ms.families {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// query returns the series matching the selector, ordered by name.
+func (ms *metricSet) query(sel *selector) []*series {
+	var result []*series
+	for _, s := range ms.series {
+		if sel.matches(s) {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var openMetricsTypes = map[string]string{
+	"unknown":        "untyped",
+	"info":           "untyped",
+	"stateset":       "untyped",
+	"gaugehistogram": "untyped",
+}
+
+// normalizeOpenMetrics rewrites the OpenMetrics-specific parts of a dump so that it can be
+// read by the Prometheus text parser. Exemplars and unsupported metadata are dropped, types
+// the parser doesn't know are made untyped, and timestamps are converted from seconds to
+// milliseconds. Plain Prometheus text dumps are returned unchanged.
+func normalizeOpenMetrics(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	openMetrics := false
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := bytes.TrimSpace(lines[i]); len(line) > 0 {
+			openMetrics = bytes.Equal(line, []byte("# EOF"))
+			break
+		}
+	}
+	if !openMetrics {
+		return data
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		s := strings.TrimSpace(string(line))
+		if s == "" || s == "# EOF" || strings.HasPrefix(s, "# UNIT ") {
+			continue
+		}
+		if strings.HasPrefix(s, "# TYPE ") {
+			fields := strings.Fields(s)
+			if len(fields) == 4 {
+				if t, ok := openMetricsTypes[fields[3]]; ok {
+					s = strings.Join(append(fields[:3], t), " ")
+				}
+			}
+		} else if !strings.HasPrefix(s, "#") {
+			s = normalizeOpenMetricsSample(s)
+		}
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func normalizeOpenMetricsSample(s string) string {
+	// Label values may contain spaces and '#', so only split what follows the label set.
+	nameEnd := labelSetEnd(s)
+	if nameEnd == -1 {
+		return s
+	}
+	fields := strings.Fields(s[nameEnd:])
+	// Exemplars follow the value and optional timestamp, separated by '#'.
+	for i, f := range fields {
+		if f == "#" {
+			fields = fields[:i]
+			break
+		}
+	}
+	if len(fields) == 2 {
+		if ts, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			fields[1] = strconv.FormatInt(int64(math.Round(ts*1000)), 10)
+		}
+	}
+	return s[:nameEnd] + " " + strings.Join(fields, " ")
+}
+
+// labelSetEnd returns the index just past the metric name and label set of a sample line.
+func labelSetEnd(s string) int {
+	i := 0
+	for i < len(s) && isNameChar(s[i], i == 0) {
+		i++
+	}
+	if i == len(s) || s[i] != '{' {
+		return i
+	}
+	inQuote := false
+	for ; i < len(s); i++ {
+		switch {
+		case inQuote && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == '}':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// selector is a Prometheus instant vector selector, such as foo{bar="baz",qux=~"a|b"}.
+type selector struct {
+	name     string
+	matchers []matcher
+}
+
+type matcher struct {
+	label string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (m matcher) matches(value string) bool {
+	switch m.op {
+	case "=":
+		return value == m.value
+	case "!=":
+		return value != m.value
+	case "=~":
+		return m.re.MatchString(value)
+	case "!~":
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (sel *selector) matches(s *series) bool {
+	if sel.name != "" && sel.name != s.Name {
+		return false
+	}
+	for _, m := range sel.matchers {
+		value := ""
+		if m.label == "__name__" {
+			value = s.Name
+		} else {
+			for _, l := range s.Labels {
+				if l.Name == m.label {
+					value = l.Value
+					break
+				}
+			}
+		}
+		if !m.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || c == ':' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+// parseSelector parses a vector selector. Functions and range selectors are not supported.
+func parseSelector(q string) (*selector, error) {
+	q = strings.TrimSpace(q)
+	sel := &selector{}
+	i := 0
+	for i < len(q) && isNameChar(q[i], i == 0) {
+		i++
+	}
+	sel.name = q[:i]
+	rest := strings.TrimSpace(q[i:])
+	if rest == "" {
+		if sel.name == "" {
+			return nil, fmt.Errorf("empty selector")
+		}
+		return sel, nil
+	}
+	if rest[0] != '{' || rest[len(rest)-1] != '}' {
+		return nil, fmt.Errorf("unexpected %q in selector %q", rest, q)
+	}
+	rest = rest[1 : len(rest)-1]
+	for {
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			break
+		}
+		j := 0
+		for j < len(rest) && isNameChar(rest[j], j == 0) {
+			j++
+		}
+		if j == 0 {
+			return nil, fmt.Errorf("expected a label name at %q", rest)
+		}
+		m := matcher{label: rest[:j]}
+		rest = strings.TrimSpace(rest[j:])
+		for _, op := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(rest, op) {
+				m.op = op
+				break
+			}
+		}
+		if m.op == "" {
+			return nil, fmt.Errorf("expected a label matcher operator at %q", rest)
+		}
+		rest = strings.TrimSpace(rest[len(m.op):])
+		value, n, err := unquotePrefix(rest)
+		if err != nil {
+			return nil, err
+		}
+		m.value = value
+		rest = strings.TrimSpace(rest[n:])
+		if m.op == "=~" || m.op == "!~" {
+			if m.re, err = regexp.Compile("^(?:" + m.value + ")$"); err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", m.value, err)
+			}
+		}
+		sel.matchers = append(sel.matchers, m)
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("expected ',' at %q", rest)
+		}
+		rest = rest[1:]
+	}
+	if sel.name == "" && len(sel.matchers) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+// unquotePrefix unquotes the quoted string at the start of s, returning the value and the
+// number of bytes consumed.
+func unquotePrefix(s string) (string, int, error) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", 0, fmt.Errorf("expected a quoted label value at %q", s)
+	}
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			raw := s[:i+1]
+			if quote == '\'' {
+				raw = `"` + strings.Replace(raw[1:i], `"`, `\"`, -1) + `"`
+			}
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid label value %s: %v", s[:i+1], err)
+			}
+			return value, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated label value %q", s)
+}
+
+// rate converts the points of a cumulative counter into per-second rates, or per-snapshot
+// increases if the points have no timestamps. Counter resets are handled as in Prometheus.
+func rate(points []point, timed bool) []point {
+	var result []point
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		increase := cur.Value - prev.Value
+		if increase < 0 {
+			increase = cur.Value
+		}
+		interval := 1.0
+		if timed {
+			interval = float64(cur.Time-prev.Time) / 1000
+			if interval <= 0 {
+				continue
+			}
+		}
+		cur.Value = increase / interval
+		result = append(result, cur)
+	}
+	return result
+}
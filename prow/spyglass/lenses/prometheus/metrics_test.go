@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDump = `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{code="200",verb="GET"} 10
+requests_total{code="500",verb="GET"} 2
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 3
+latency_seconds_bucket{le="1"} 5
+latency_seconds_bucket{le="+Inf"} 6
+latency_seconds_sum 4.5
+latency_seconds_count 6
+`
+
+func TestMetricSetAdd(t *testing.T) {
+	metrics := newMetricSet()
+	if err := metrics.add(0, []byte(testDump)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := metrics.add(1, []byte(`requests_total{verb="GET",code="200"} 15`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, ok := metrics.series[`requests_total{code="200", verb="GET"}`]
+	if !ok {
+		t.Fatalf("expected series to be recorded, got %v", metrics.series)
+	}
+	expected := []point{{Snapshot: 0, Value: 10}, {Snapshot: 1, Value: 15}}
+	if !reflect.DeepEqual(s.Points, expected) {
+		t.Errorf("expected points %v, got %v", expected, s.Points)
+	}
+
+	for _, name := range []string{
+		`latency_seconds_bucket{le="0.1"}`,
+		`latency_seconds_bucket{le="+Inf"}`,
+		`latency_seconds_sum`,
+		`latency_seconds_count`,
+	} {
+		if _, ok := metrics.series[name]; !ok {
+			t.Errorf("expected histogram to produce series %s", name)
+		}
+	}
+
+	families := metrics.sortedFamilies()
+	if len(families) != 2 || families[0].Name != "latency_seconds" || families[1].Name != "requests_total" {
+		t.Fatalf("unexpected families: %v", families)
+	}
+	if families[1].Type != "counter" || families[1].Help != "Total requests." || families[1].Series != 2 {
+		t.Errorf("unexpected family: %#v", families[1])
+	}
+}
+
+func TestNormalizeOpenMetrics(t *testing.T) {
+	metrics := newMetricSet()
+	dump := `# TYPE build info
+build_info{version="1.2 # 3"} 1
+# TYPE requests counter
+# UNIT requests requests
+requests_total 5 1550000000.5 # {trace_id="abc"} 1.0
+# EOF
+`
+	if err := metrics.add(0, []byte(dump)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := metrics.series[`build_info{version="1.2 # 3"}`]; !ok {
+		t.Errorf("expected info metric to be read, got %v", metrics.series)
+	}
+	s, ok := metrics.series["requests_total"]
+	if !ok {
+		t.Fatalf("expected counter to be read, got %v", metrics.series)
+	}
+	if expected := []point{{Time: 1550000000500, Value: 5}}; !reflect.DeepEqual(s.Points, expected) {
+		t.Errorf("expected points %v, got %v", expected, s.Points)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	testCases := []struct {
+		query   string
+		matches []string
+		err     bool
+	}{
+		{
+			query:   "requests_total",
+			matches: []string{`requests_total{code="200", verb="GET"}`, `requests_total{code="500", verb="GET"}`, `requests_total{code="200", verb="POST"}`},
+		},
+		{
+			query:   `requests_total{code="200"}`,
+			matches: []string{`requests_total{code="200", verb="GET"}`, `requests_total{code="200", verb="POST"}`},
+		},
+		{
+			query:   `requests_total{code!="200", verb='GET',}`,
+			matches: []string{`requests_total{code="500", verb="GET"}`},
+		},
+		{
+			query:   `{__name__=~"requests.*", verb!~"G.*"}`,
+			matches: []string{`requests_total{code="200", verb="POST"}`},
+		},
+		{
+			query:   `requests_total{missing=""}`,
+			matches: []string{`requests_total{code="200", verb="GET"}`, `requests_total{code="500", verb="GET"}`, `requests_total{code="200", verb="POST"}`},
+		},
+		{query: "", err: true},
+		{query: "{}", err: true},
+		{query: `requests_total{code="200"`, err: true},
+		{query: `requests_total{code=200}`, err: true},
+		{query: `requests_total{code=~"("}`, err: true},
+		{query: `rate(requests_total[5m])`, err: true},
+	}
+	all := []*series{
+		{Name: "requests_total", Labels: []labelPair{{"code", "200"}, {"verb", "GET"}}},
+		{Name: "requests_total", Labels: []labelPair{{"code", "500"}, {"verb", "GET"}}},
+		{Name: "requests_total", Labels: []labelPair{{"code", "200"}, {"verb", "POST"}}},
+		{Name: "latency_seconds_count"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			sel, err := parseSelector(tc.query)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var matches []string
+			for _, s := range all {
+				if sel.matches(s) {
+					matches = append(matches, s.String())
+				}
+			}
+			if !reflect.DeepEqual(matches, tc.matches) {
+				t.Errorf("expected matches %v, got %v", tc.matches, matches)
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	points := []point{
+		{Time: 1000, Value: 10},
+		{Time: 3000, Value: 20},
+		{Time: 5000, Value: 4},
+	}
+	expected := []point{
+		{Time: 3000, Value: 5},
+		{Time: 5000, Value: 2},
+	}
+	if actual := rate(points, true); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected timed rate %v, got %v", expected, actual)
+	}
+	expected = []point{
+		{Time: 3000, Value: 10},
+		{Time: 5000, Value: 4},
+	}
+	if actual := rate(points, false); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected untimed increase %v, got %v", expected, actual)
+	}
+}
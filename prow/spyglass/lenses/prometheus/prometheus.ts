@@ -0,0 +1,34 @@
+function chart(query: string, rate: boolean): void {
+  spyglass.updatePage(JSON.stringify({query, rate})).then(bind);
+}
+
+function handleSubmit(e: Event): void {
+  e.preventDefault();
+  const input = document.getElementById('metrics-query-input') as HTMLInputElement;
+  const rate = document.getElementById('metrics-query-rate') as HTMLInputElement;
+  chart(input.value, rate.checked);
+}
+
+function handleFamilyClick(this: HTMLAnchorElement, e: MouseEvent): void {
+  e.preventDefault();
+  // Counters are rarely interesting as absolute values.
+  chart(this.dataset.metric || '', this.dataset.counter === 'true');
+}
+
+// The page is replaced wholesale on update, so listeners need to be reattached.
+function bind(): void {
+  const form = document.getElementById('metrics-query');
+  if (form) {
+    form.addEventListener('submit', handleSubmit);
+  }
+  for (const link of Array.from(document.querySelectorAll<HTMLAnchorElement>('a.metrics-family'))) {
+    link.addEventListener('click', handleFamilyClick);
+  }
+  const details = document.querySelector('details.metrics-families');
+  if (details) {
+    details.addEventListener('toggle', () => spyglass.contentUpdated());
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', bind);
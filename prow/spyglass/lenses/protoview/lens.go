@@ -0,0 +1,247 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package protoview provides a Spyglass lens that renders protocol buffers in the text format and
+// the binary wire format, decoding the latter with uploaded or compiled-in descriptors.
+package protoview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "protoview"
+	title    = "Protobuf"
+	priority = 39
+
+	// defaultMaxBytes is how much of each artifact is read if not configured.
+	defaultMaxBytes = 10 << 20
+	// maxMessages is how many messages of a length-delimited dump are shown.
+	maxMessages = 1000
+	// openDepth is how many levels of nested messages are unfolded when first shown.
+	openDepth = 2
+)
+
+var (
+	// textRE matches artifacts in the text format. Anything else is taken to be binary.
+	textRE = regexp.MustCompile(`\.(textproto|textpb|txtpb|pbtxt|prototxt|asciipb)$`)
+	// descriptorSetRE matches FileDescriptorSets uploaded alongside the artifacts to decode them.
+	descriptorSetRE = regexp.MustCompile(`(\.protoset|\.desc|descriptor_set\.pb)$`)
+)
+
+// Lens is the implementation of a protobuf-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// messageType is the type of the binary artifacts whose paths match a regex.
+type messageType struct {
+	Path string `json:"path"`
+	// Type is the message's full name, such as "testgrid.state.Grid".
+	Type string `json:"type"`
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MessageTypes are the types of binary artifacts, the first match winning. Binary
+	// artifacts with no type are decoded without field names.
+	MessageTypes []messageType `json:"message_types,omitempty"`
+	// MaxBytes is how much of each artifact is read. Defaults to 10MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+type compiledType struct {
+	path *regexp.Regexp
+	name string
+}
+
+// parseConfig reads the lens's configuration and compiles its paths.
+func parseConfig(rawConfig json.RawMessage) (config, []compiledType, error) {
+	conf := config{}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			return config{MaxBytes: defaultMaxBytes}, nil, err
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	var types []compiledType
+	for _, t := range conf.MessageTypes {
+		re, err := regexp.Compile(t.Path)
+		if err != nil {
+			return conf, types, fmt.Errorf("bad path regex %q for %s: %v", t.Path, t.Type, err)
+		}
+		types = append(types, compiledType{path: re, name: t.Type})
+	}
+	return conf, types, nil
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// Message is a message in an artifact.
+type Message struct {
+	Fields []*Field
+}
+
+// ArtifactView is the messages of one artifact.
+type ArtifactView struct {
+	Name string
+	Link string
+	// Type is the message type the artifact was decoded with, if any.
+	Type     string
+	Error    string
+	Messages []Message
+	// Omitted is the number of messages of a length-delimited dump not shown.
+	Omitted int
+}
+
+type protoView struct {
+	Errors    []string
+	Artifacts []ArtifactView
+}
+
+// Body renders each artifact's messages with each nested message folded.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := protoView{}
+	conf, types, err := parseConfig(rawConfig)
+	if err != nil {
+		view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	reg := newRegistry()
+	var messages []lenses.Artifact
+	for _, a := range artifacts {
+		if !descriptorSetRE.MatchString(a.JobPath()) {
+			messages = append(messages, a)
+			continue
+		}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		if err := reg.addSet(content); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read descriptors from %s: %v", a.JobPath(), err))
+		}
+	}
+
+	for _, a := range messages {
+		av := ArtifactView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			av.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+			view.Artifacts = append(view.Artifacts, av)
+			continue
+		}
+		if textRE.MatchString(a.JobPath()) {
+			fields, err := parseTextProto(string(content))
+			if err != nil {
+				av.Error = fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err)
+			}
+			av.Messages = []Message{{Fields: fields}}
+		} else {
+			for _, t := range types {
+				if t.path.MatchString(a.JobPath()) {
+					av.Type = t.name
+					break
+				}
+			}
+			av.Messages, av.Omitted, err = decodeArtifact(content, reg, av.Type)
+			if err != nil {
+				av.Error = fmt.Sprintf("Failed to decode %s: %v", a.JobPath(), err)
+			}
+		}
+		for _, m := range av.Messages {
+			unfold(m.Fields, openDepth)
+		}
+		view.Artifacts = append(view.Artifacts, av)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// decodeArtifact decodes a binary artifact, which is either a single message or a
+// length-delimited dump of messages, and returns at most maxMessages of them and how many more
+// there were.
+func decodeArtifact(content []byte, reg *registry, typeName string) ([]Message, int, error) {
+	d := &decoder{reg: reg}
+	msg := reg.message(typeName)
+	if typeName != "" && msg == nil {
+		return nil, 0, fmt.Errorf("unknown message type %s; upload a descriptor set or configure a compiled-in type", typeName)
+	}
+	if records, ok := splitDelimited(content); ok {
+		var messages []Message
+		for i, r := range records {
+			if i == maxMessages {
+				return messages, len(records) - maxMessages, nil
+			}
+			fields, err := d.decode(r, msg)
+			if err != nil {
+				messages = nil
+				break
+			}
+			messages = append(messages, Message{Fields: fields})
+		}
+		if messages != nil {
+			return messages, 0, nil
+		}
+	}
+	fields, err := d.decode(content, msg)
+	return []Message{{Fields: fields}}, 0, err
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,21 @@
+function setAllOpen(artifact: Element, open: boolean): void {
+  for (const details of Array.from(artifact.querySelectorAll<HTMLDetailsElement>('details'))) {
+    details.open = open;
+  }
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const artifact of Array.from(document.querySelectorAll('.proto-artifact'))) {
+    const collapse = artifact.querySelector('.proto-collapse');
+    if (collapse) {
+      collapse.addEventListener('click', () => setAllOpen(artifact, false));
+    }
+    const expand = artifact.querySelector('.proto-expand');
+    if (expand) {
+      expand.addEventListener('click', () => setAllOpen(artifact, true));
+    }
+  }
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+});
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protoview
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field is a field of a message. Repeated fields are a Field for each value.
+type Field struct {
+	// Name is the field's name, or its number if it is not known.
+	Name string
+	// Value is the text format of a scalar. Messages have Fields instead.
+	Value   string
+	Message bool
+	Fields  []*Field
+	// Open is whether a message is unfolded when first shown.
+	Open bool
+}
+
+// unfold opens the messages nested less than depth deep.
+func unfold(fields []*Field, depth int) {
+	for _, f := range fields {
+		f.Open = depth > 0
+		unfold(f.Fields, depth-1)
+	}
+}
+
+// textParser reads the protobuf text format without knowing the message's type, which the text
+// format does not need.
+type textParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (p *textParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skip skips whitespace and comments.
+func (p *textParser) skip() {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next character after any whitespace, or 0 at the end.
+func (p *textParser) peek() byte {
+	p.skip()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseTextProto parses a message in the text format.
+func parseTextProto(s string) ([]*Field, error) {
+	p := &textParser{s: s, line: 1}
+	fields, err := p.message(0)
+	if err == nil && p.peek() != 0 {
+		err = p.errorf("unexpected %q", p.s[p.pos])
+	}
+	return fields, err
+}
+
+// message reads fields until the given closing character, or the end if it is zero.
+func (p *textParser) message(end byte) ([]*Field, error) {
+	var fields []*Field
+	for {
+		c := p.peek()
+		if c == end {
+			if c != 0 {
+				p.pos++
+			}
+			return fields, nil
+		}
+		if c == 0 {
+			return fields, p.errorf("expected %q before the end", end)
+		}
+		name, err := p.name()
+		if err != nil {
+			return fields, err
+		}
+		if p.peek() == ':' {
+			p.pos++
+		}
+		if p.peek() == '[' {
+			p.pos++
+			for p.peek() != ']' {
+				f, err := p.value(name)
+				if f != nil {
+					fields = append(fields, f)
+				}
+				if err != nil {
+					return fields, err
+				}
+				if p.peek() == ',' {
+					p.pos++
+				} else if p.peek() != ']' {
+					return fields, p.errorf("expected ',' or ']' in the list of %s", name)
+				}
+			}
+			p.pos++
+		} else {
+			f, err := p.value(name)
+			if f != nil {
+				fields = append(fields, f)
+			}
+			if err != nil {
+				return fields, err
+			}
+		}
+		if c := p.peek(); c == ',' || c == ';' {
+			p.pos++
+		}
+	}
+}
+
+// name reads a field name, which may be an extension or Any type URL in brackets.
+func (p *textParser) name() (string, error) {
+	start := p.pos
+	if p.s[p.pos] == '[' {
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return "", p.errorf("unterminated extension name")
+		}
+		p.pos += end + 1
+		return p.s[start:p.pos], nil
+	}
+	for p.pos < len(p.s) && isIdentChar(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a field name, got %q", p.s[p.pos])
+	}
+	return p.s[start:p.pos], nil
+}
+
+// value reads the value of a field. A message that is not closed is returned with the fields
+// read before the error.
+func (p *textParser) value(name string) (*Field, error) {
+	f := &Field{Name: name}
+	switch c := p.peek(); c {
+	case '{', '<':
+		p.pos++
+		end := byte('}')
+		if c == '<' {
+			end = '>'
+		}
+		fields, err := p.message(end)
+		f.Message = true
+		f.Fields = fields
+		return f, err
+	case '"', '\'':
+		// Adjacent strings are concatenated.
+		var parts []string
+		for p.peek() == '"' || p.peek() == '\'' {
+			s, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		}
+		f.Value = strings.Join(parts, " ")
+		return f, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && isIdentChar(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a value for %s", name)
+	}
+	f.Value = p.s[start:p.pos]
+	return f, nil
+}
+
+// quoted reads a quoted string, keeping its quotes and escapes.
+func (p *textParser) quoted() (string, error) {
+	quote := p.s[p.pos]
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case quote:
+			p.pos++
+			return p.s[start:p.pos], nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protoview
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTextProto(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []*Field
+		err      bool
+	}{
+		{
+			name:  "scalars and comments",
+			input: "# a comment\nname: \"kubernetes\"\ncount: 3 # trailing\nenabled: true\n",
+			expected: []*Field{
+				{Name: "name", Value: `"kubernetes"`},
+				{Name: "count", Value: "3"},
+				{Name: "enabled", Value: "true"},
+			},
+		},
+		{
+			name:  "nested messages with either brackets and optional colons",
+			input: "test_group { name: 'ci' column_header < configuration_value: \"Commit\" > }\nresult: { value: -1.5e3 };",
+			expected: []*Field{
+				{Name: "test_group", Message: true, Fields: []*Field{
+					{Name: "name", Value: "'ci'"},
+					{Name: "column_header", Message: true, Fields: []*Field{
+						{Name: "configuration_value", Value: `"Commit"`},
+					}},
+				}},
+				{Name: "result", Message: true, Fields: []*Field{
+					{Name: "value", Value: "-1.5e3"},
+				}},
+			},
+		},
+		{
+			name:  "lists, extensions and concatenated strings",
+			input: "ids: [1, 2, 3]\n[foo.bar.ext]: FOO\nmessage: \"first \\\" line\"\n  \"second line\"",
+			expected: []*Field{
+				{Name: "ids", Value: "1"},
+				{Name: "ids", Value: "2"},
+				{Name: "ids", Value: "3"},
+				{Name: "[foo.bar.ext]", Value: "FOO"},
+				{Name: "message", Value: `"first \" line" "second line"`},
+			},
+		},
+		{
+			name:  "unclosed message",
+			input: "a {\n  b: 1\n",
+			expected: []*Field{
+				{Name: "a", Message: true, Fields: []*Field{{Name: "b", Value: "1"}}},
+			},
+			err: true,
+		},
+		{
+			name:     "unterminated string",
+			input:    "a: \"oops\n",
+			expected: nil,
+			err:      true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := parseTextProto(tc.input)
+			if err != nil && !tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.err {
+				t.Fatal("expected an error")
+			}
+			if !reflect.DeepEqual(fields, tc.expected) {
+				t.Errorf("expected %s, got %s", dump(tc.expected), dump(fields))
+			}
+		})
+	}
+}
+
+func TestUnfold(t *testing.T) {
+	fields, err := parseTextProto("a { b { c { d: 1 } } }")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unfold(fields, 2)
+	a := fields[0]
+	b := a.Fields[0]
+	c := b.Fields[0]
+	if !a.Open || !b.Open || c.Open {
+		t.Errorf("expected only the top two levels to be open, got %v, %v and %v", a.Open, b.Open, c.Open)
+	}
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protoview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// maxDepth is how deeply messages may be nested before decoding gives up.
+const maxDepth = 64
+
+// registry holds the message and enum types available to decode with, by full name without the
+// leading dot.
+type registry struct {
+	messages map[string]*descriptor.DescriptorProto
+	enums    map[string]*descriptor.EnumDescriptorProto
+	files    map[string]bool
+}
+
+func newRegistry() *registry {
+	return &registry{
+		messages: map[string]*descriptor.DescriptorProto{},
+		enums:    map[string]*descriptor.EnumDescriptorProto{},
+		files:    map[string]bool{},
+	}
+}
+
+// addSet adds the types of a serialized FileDescriptorSet, as written by
+// protoc --descriptor_set_out.
+func (r *registry) addSet(b []byte) error {
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return err
+	}
+	for _, f := range set.File {
+		r.addFile(f)
+	}
+	return nil
+}
+
+func (r *registry) addFile(f *descriptor.FileDescriptorProto) {
+	r.files[f.GetName()] = true
+	prefix := f.GetPackage()
+	for _, m := range f.MessageType {
+		r.addMessage(prefix, m)
+	}
+	for _, e := range f.EnumType {
+		r.enums[qualify(prefix, e.GetName())] = e
+	}
+}
+
+func (r *registry) addMessage(prefix string, m *descriptor.DescriptorProto) {
+	name := qualify(prefix, m.GetName())
+	r.messages[name] = m
+	for _, nested := range m.NestedType {
+		r.addMessage(name, nested)
+	}
+	for _, e := range m.EnumType {
+		r.enums[qualify(name, e.GetName())] = e
+	}
+}
+
+// addCompiled adds a gzipped FileDescriptorProto compiled into the binary, and the files it
+// imports.
+func (r *registry) addCompiled(gz []byte) error {
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	f := &descriptor.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, f); err != nil {
+		return err
+	}
+	r.addFile(f)
+	for _, dep := range f.Dependency {
+		if gz := proto.FileDescriptor(dep); gz != nil && !r.files[dep] {
+			if err := r.addCompiled(gz); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// message looks up a message type, falling back to the Go types compiled into Deck.
+func (r *registry) message(name string) *descriptor.DescriptorProto {
+	name = strings.TrimPrefix(name, ".")
+	if m, ok := r.messages[name]; ok {
+		return m
+	}
+	t := proto.MessageType(name)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	described, ok := reflect.New(t.Elem()).Interface().(interface {
+		Descriptor() ([]byte, []int)
+	})
+	if !ok {
+		return nil
+	}
+	gz, _ := described.Descriptor()
+	if err := r.addCompiled(gz); err != nil {
+		return nil
+	}
+	return r.messages[name]
+}
+
+func (r *registry) enum(name string) *descriptor.EnumDescriptorProto {
+	return r.enums[strings.TrimPrefix(name, ".")]
+}
+
+func qualify(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// decoder reads the protobuf wire format. Fields are named and formatted by their descriptors if
+// the message's type is known, and otherwise decoded the way protoc --decode_raw does.
+type decoder struct {
+	reg *registry
+}
+
+func readVarint(b []byte) (uint64, []byte, error) {
+	v, n := proto.DecodeVarint(b)
+	if n == 0 {
+		return 0, nil, errors.New("truncated varint")
+	}
+	return v, b[n:], nil
+}
+
+// decode decodes a message of the given type, which may be nil if it is unknown.
+func (d *decoder) decode(b []byte, msg *descriptor.DescriptorProto) ([]*Field, error) {
+	fields, rest, err := d.fields(b, msg, 0, 0)
+	if err == nil && len(rest) != 0 {
+		err = errors.New("unexpected end group")
+	}
+	return fields, err
+}
+
+// fields decodes fields until the end of b, or the end of the group with the given field number
+// if it is not zero, and returns what follows.
+func (d *decoder) fields(b []byte, msg *descriptor.DescriptorProto, group int32, depth int) ([]*Field, []byte, error) {
+	if depth > maxDepth {
+		return nil, nil, errors.New("messages are nested too deeply")
+	}
+	var fields []*Field
+	for len(b) > 0 {
+		key, rest, err := readVarint(b)
+		if err != nil {
+			return fields, nil, err
+		}
+		b = rest
+		number, wireType := int32(key>>3), key&7
+		if number <= 0 {
+			return fields, nil, fmt.Errorf("invalid field number %d", number)
+		}
+		desc := fieldByNumber(msg, number)
+		name := strconv.Itoa(int(number))
+		if desc != nil {
+			name = desc.GetName()
+		}
+		switch wireType {
+		case proto.WireVarint:
+			v, rest, err := readVarint(b)
+			if err != nil {
+				return fields, nil, err
+			}
+			b = rest
+			fields = append(fields, &Field{Name: name, Value: d.formatVarint(v, desc)})
+		case proto.WireFixed64:
+			if len(b) < 8 {
+				return fields, nil, errors.New("truncated fixed64")
+			}
+			fields = append(fields, &Field{Name: name, Value: formatFixed64(binary.LittleEndian.Uint64(b), desc)})
+			b = b[8:]
+		case proto.WireFixed32:
+			if len(b) < 4 {
+				return fields, nil, errors.New("truncated fixed32")
+			}
+			fields = append(fields, &Field{Name: name, Value: formatFixed32(binary.LittleEndian.Uint32(b), desc)})
+			b = b[4:]
+		case proto.WireBytes:
+			n, rest, err := readVarint(b)
+			if err != nil {
+				return fields, nil, err
+			}
+			if n > uint64(len(rest)) {
+				return fields, nil, fmt.Errorf("field %s is longer than its message", name)
+			}
+			value := rest[:n]
+			b = rest[n:]
+			f, err := d.bytesField(name, value, desc, depth)
+			if err != nil {
+				return fields, nil, err
+			}
+			fields = append(fields, f...)
+		case proto.WireStartGroup:
+			var nested *descriptor.DescriptorProto
+			if desc != nil {
+				nested = d.reg.message(desc.GetTypeName())
+			}
+			children, rest, err := d.fields(b, nested, number, depth+1)
+			if err != nil {
+				return fields, nil, err
+			}
+			b = rest
+			fields = append(fields, &Field{Name: name, Message: true, Fields: children})
+		case proto.WireEndGroup:
+			if number != group {
+				return fields, nil, fmt.Errorf("unexpected end of group %d", number)
+			}
+			return fields, b, nil
+		default:
+			return fields, nil, fmt.Errorf("invalid wire type %d", wireType)
+		}
+	}
+	if group != 0 {
+		return fields, nil, fmt.Errorf("group %d is not ended", group)
+	}
+	return fields, nil, nil
+}
+
+// bytesField decodes a length-delimited field, which is a string, bytes, a message or packed
+// repeated numbers.
+func (d *decoder) bytesField(name string, b []byte, desc *descriptor.FieldDescriptorProto, depth int) ([]*Field, error) {
+	if desc == nil {
+		// Text is taken to be a string and anything else that decodes to be a message. Both
+		// guesses can be wrong, as they are for protoc --decode_raw.
+		if isText(b) {
+			return []*Field{{Name: name, Value: strconv.Quote(string(b))}}, nil
+		}
+		if children, rest, err := d.fields(b, nil, 0, depth+1); err == nil && len(rest) == 0 {
+			return []*Field{{Name: name, Message: true, Fields: children}}, nil
+		}
+		return []*Field{{Name: name, Value: quoteBytes(b)}}, nil
+	}
+	switch desc.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return []*Field{{Name: name, Value: strconv.Quote(string(b))}}, nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return []*Field{{Name: name, Value: quoteBytes(b)}}, nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		children, _, err := d.fields(b, d.reg.message(desc.GetTypeName()), 0, depth+1)
+		return []*Field{{Name: name, Message: true, Fields: children}}, err
+	}
+	// Anything else is packed.
+	var fields []*Field
+	for len(b) > 0 {
+		switch desc.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+			if len(b) < 8 {
+				return fields, errors.New("truncated packed fixed64")
+			}
+			fields = append(fields, &Field{Name: name, Value: formatFixed64(binary.LittleEndian.Uint64(b), desc)})
+			b = b[8:]
+		case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_FIXED32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+			if len(b) < 4 {
+				return fields, errors.New("truncated packed fixed32")
+			}
+			fields = append(fields, &Field{Name: name, Value: formatFixed32(binary.LittleEndian.Uint32(b), desc)})
+			b = b[4:]
+		default:
+			v, rest, err := readVarint(b)
+			if err != nil {
+				return fields, err
+			}
+			fields = append(fields, &Field{Name: name, Value: d.formatVarint(v, desc)})
+			b = rest
+		}
+	}
+	return fields, nil
+}
+
+func fieldByNumber(msg *descriptor.DescriptorProto, number int32) *descriptor.FieldDescriptorProto {
+	if msg == nil {
+		return nil
+	}
+	for _, f := range msg.Field {
+		if f.GetNumber() == number {
+			return f
+		}
+	}
+	return nil
+}
+
+func (d *decoder) formatVarint(v uint64, desc *descriptor.FieldDescriptorProto) string {
+	if desc == nil {
+		return strconv.FormatUint(v, 10)
+	}
+	switch desc.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT32:
+		return strconv.FormatInt(int64(int32(v)), 10)
+	case descriptor.FieldDescriptorProto_TYPE_INT64:
+		return strconv.FormatInt(int64(v), 10)
+	case descriptor.FieldDescriptorProto_TYPE_UINT32:
+		return strconv.FormatUint(uint64(uint32(v)), 10)
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return strconv.FormatInt(int64(int32(uint32(v)>>1)^-int32(v&1)), 10)
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return strconv.FormatInt(int64(v>>1)^-int64(v&1), 10)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return strconv.FormatBool(v != 0)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if e := d.reg.enum(desc.GetTypeName()); e != nil {
+			for _, value := range e.Value {
+				if value.GetNumber() == int32(v) {
+					return value.GetName()
+				}
+			}
+		}
+		return strconv.FormatInt(int64(int32(v)), 10)
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+func formatFixed64(v uint64, desc *descriptor.FieldDescriptorProto) string {
+	if desc != nil {
+		switch desc.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+			return strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64)
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+			return strconv.FormatInt(int64(v), 10)
+		case descriptor.FieldDescriptorProto_TYPE_FIXED64:
+			return strconv.FormatUint(v, 10)
+		}
+	}
+	return fmt.Sprintf("0x%016x", v)
+}
+
+func formatFixed32(v uint32, desc *descriptor.FieldDescriptorProto) string {
+	if desc != nil {
+		switch desc.GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+			return strconv.FormatFloat(float64(math.Float32frombits(v)), 'g', -1, 32)
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+			return strconv.FormatInt(int64(int32(v)), 10)
+		case descriptor.FieldDescriptorProto_TYPE_FIXED32:
+			return strconv.FormatUint(uint64(v), 10)
+		}
+	}
+	return fmt.Sprintf("0x%08x", v)
+}
+
+// isText reports whether b is printable UTF-8.
+func isText(b []byte) bool {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteBytes quotes bytes the way the text format does, escaping anything that is not printable
+// ASCII.
+func quoteBytes(b []byte) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "\\%03o", c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// splitDelimited splits a stream of messages each preceded by its varint length, as written by
+// writeDelimitedTo in Java and C++. It reports false if b is not such a stream, in which case it
+// is taken to be a single message.
+func splitDelimited(b []byte) ([][]byte, bool) {
+	var messages [][]byte
+	for len(b) > 0 {
+		n, rest, err := readVarint(b)
+		if err != nil || n > uint64(len(rest)) {
+			return nil, false
+		}
+		messages = append(messages, rest[:n])
+		b = rest[n:]
+	}
+	return messages, len(messages) > 0
+}
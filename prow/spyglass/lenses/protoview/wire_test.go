@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protoview
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// dump formats fields for test failures.
+func dump(fields []*Field) string {
+	var parts []string
+	for _, f := range fields {
+		if f.Message {
+			parts = append(parts, fmt.Sprintf("%s {%s}", f.Name, dump(f.Fields)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s", f.Name, f.Value))
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// wire builds messages in the wire format.
+type wire []byte
+
+func (w wire) varint(number int, v uint64) wire {
+	w = append(w, proto.EncodeVarint(uint64(number<<3|proto.WireVarint))...)
+	return append(w, proto.EncodeVarint(v)...)
+}
+
+func (w wire) bytes(number int, b []byte) wire {
+	w = append(w, proto.EncodeVarint(uint64(number<<3|proto.WireBytes))...)
+	w = append(w, proto.EncodeVarint(uint64(len(b)))...)
+	return append(w, b...)
+}
+
+func (w wire) fixed64(number int, v uint64) wire {
+	w = append(w, proto.EncodeVarint(uint64(number<<3|proto.WireFixed64))...)
+	for i := uint(0); i < 8; i++ {
+		w = append(w, byte(v>>(8*i)))
+	}
+	return w
+}
+
+func field(name string, number int32, t descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   t.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// descriptorSet is a descriptor set like protoc --descriptor_set_out would write for:
+//
+//	package example;
+//	enum Status { UNKNOWN = 0; PASS = 1; FAIL = 2; }
+//	message Result {
+//	  message Child { string name = 1; }
+//	  int32 id = 1;
+//	  string name = 2;
+//	  Status status = 3;
+//	  repeated sint32 deltas = 4;
+//	  Child child = 5;
+//	  double ratio = 6;
+//	}
+func descriptorSet(t *testing.T) []byte {
+	set := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("example.proto"),
+			Package: proto.String("example"),
+			EnumType: []*descriptor.EnumDescriptorProto{{
+				Name: proto.String("Status"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+					{Name: proto.String("PASS"), Number: proto.Int32(1)},
+					{Name: proto.String("FAIL"), Number: proto.Int32(2)},
+				},
+			}},
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Result"),
+				NestedType: []*descriptor.DescriptorProto{{
+					Name:  proto.String("Child"),
+					Field: []*descriptor.FieldDescriptorProto{field("name", 1, descriptor.FieldDescriptorProto_TYPE_STRING, "")},
+				}},
+				Field: []*descriptor.FieldDescriptorProto{
+					field("id", 1, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+					field("name", 2, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					field("status", 3, descriptor.FieldDescriptorProto_TYPE_ENUM, ".example.Status"),
+					field("deltas", 4, descriptor.FieldDescriptorProto_TYPE_SINT32, ""),
+					field("child", 5, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".example.Result.Child"),
+					field("ratio", 6, descriptor.FieldDescriptorProto_TYPE_DOUBLE, ""),
+				},
+			}},
+		}},
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	return b
+}
+
+func TestDecodeWithDescriptors(t *testing.T) {
+	reg := newRegistry()
+	if err := reg.addSet(descriptorSet(t)); err != nil {
+		t.Fatalf("failed to add descriptor set: %v", err)
+	}
+	// deltas are packed: -1, 2 and -3 zigzag to 1, 4 and 5.
+	result := wire(nil).
+		varint(1, uint64(math.MaxUint64)).
+		bytes(2, []byte("e2e")).
+		varint(3, 2).
+		bytes(4, []byte{1, 4, 5}).
+		bytes(5, wire(nil).bytes(1, []byte("kid"))).
+		fixed64(6, math.Float64bits(0.25)).
+		varint(9, 7)
+	messages, omitted, err := decodeArtifact(result, reg, "example.Result")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if omitted != 0 || len(messages) != 1 {
+		t.Fatalf("expected one message, got %d and %d omitted", len(messages), omitted)
+	}
+	expected := []*Field{
+		{Name: "id", Value: "-1"},
+		{Name: "name", Value: `"e2e"`},
+		{Name: "status", Value: "FAIL"},
+		{Name: "deltas", Value: "-1"},
+		{Name: "deltas", Value: "2"},
+		{Name: "deltas", Value: "-3"},
+		{Name: "child", Message: true, Fields: []*Field{{Name: "name", Value: `"kid"`}}},
+		{Name: "ratio", Value: "0.25"},
+		{Name: "9", Value: "7"},
+	}
+	if !reflect.DeepEqual(messages[0].Fields, expected) {
+		t.Errorf("expected %s, got %s", dump(expected), dump(messages[0].Fields))
+	}
+}
+
+func TestDecodeDelimited(t *testing.T) {
+	reg := newRegistry()
+	if err := reg.addSet(descriptorSet(t)); err != nil {
+		t.Fatalf("failed to add descriptor set: %v", err)
+	}
+	var stream []byte
+	for i := 1; i <= 3; i++ {
+		m := wire(nil).varint(1, uint64(i))
+		stream = append(stream, proto.EncodeVarint(uint64(len(m)))...)
+		stream = append(stream, m...)
+	}
+	messages, _, err := decodeArtifact(stream, reg, "example.Result")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	for i, m := range messages {
+		expected := []*Field{{Name: "id", Value: fmt.Sprint(i + 1)}}
+		if !reflect.DeepEqual(m.Fields, expected) {
+			t.Errorf("message %d: expected %s, got %s", i, dump(expected), dump(m.Fields))
+		}
+	}
+}
+
+func TestDecodeRaw(t *testing.T) {
+	raw := wire(nil).
+		varint(1, 150).
+		bytes(2, []byte("hello\nworld")).
+		bytes(3, wire(nil).varint(1, 1).fixed64(2, 1)).
+		bytes(4, []byte{0xff, 0x00})
+	messages, _, err := decodeArtifact(raw, newRegistry(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []*Field{
+		{Name: "1", Value: "150"},
+		{Name: "2", Value: `"hello\nworld"`},
+		{Name: "3", Message: true, Fields: []*Field{
+			{Name: "1", Value: "1"},
+			{Name: "2", Value: "0x0000000000000001"},
+		}},
+		{Name: "4", Value: `"\377\000"`},
+	}
+	if len(messages) != 1 || !reflect.DeepEqual(messages[0].Fields, expected) {
+		t.Errorf("expected %s, got %v", dump(expected), messages)
+	}
+}
+
+func TestDecodeCompiledType(t *testing.T) {
+	b, err := proto.Marshal(&descriptor.DescriptorProto{
+		Name:  proto.String("Foo"),
+		Field: []*descriptor.FieldDescriptorProto{field("bar", 1, descriptor.FieldDescriptorProto_TYPE_BOOL, "")},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	messages, _, err := decodeArtifact(b, newRegistry(), "google.protobuf.DescriptorProto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []*Field{
+		{Name: "name", Value: `"Foo"`},
+		{Name: "field", Message: true, Fields: []*Field{
+			{Name: "name", Value: `"bar"`},
+			{Name: "number", Value: "1"},
+			{Name: "type", Value: "TYPE_BOOL"},
+		}},
+	}
+	if len(messages) != 1 || !reflect.DeepEqual(messages[0].Fields, expected) {
+		t.Errorf("expected %s, got %v", dump(expected), messages)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    []byte
+		typeName string
+	}{
+		{name: "unknown type", input: wire(nil).varint(1, 1), typeName: "example.Missing"},
+		{name: "truncated field", input: wire(nil).bytes(1, []byte("abc"))[:3]},
+		{name: "invalid wire type", input: []byte{0x0f}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := decodeArtifact(tc.input, newRegistry(), tc.typeName); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pytest provides a Spyglass lens that renders the results of pytest runs from their junit
+// and report log output.
+package pytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "pytest"
+	title    = "pytest"
+	priority = 41
+
+	// defaultMaxBytes is how much of each artifact is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of a pytest result-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each artifact is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+type pytestView struct {
+	Errors           []string
+	Counts           []OutcomeCount
+	Time             string
+	CollectionErrors []CollectionError
+	// FixtureErrors are the tests that errored in setup or teardown, and Failures those that
+	// failed.
+	FixtureErrors []*Test
+	Failures      []*Test
+	Modules       []*Module
+}
+
+// isReportLog reports whether an artifact is --report-log, rather than --junitxml, output.
+func isReportLog(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".jsonl" || ext == ".json"
+}
+
+// Body renders the failures and fixture errors of the run, and every test grouped by module.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := pytestView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	// Report logs are read last so their outcomes replace those of the junit output.
+	sort.Slice(artifacts, func(i, j int) bool {
+		if ri, rj := isReportLog(artifacts[i].JobPath()), isReportLog(artifacts[j].JobPath()); ri != rj {
+			return rj
+		}
+		return artifacts[i].JobPath() < artifacts[j].JobPath()
+	})
+	report := newReport()
+	for _, a := range artifacts {
+		if isReportLog(a.JobPath()) {
+			r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+			if err == nil {
+				err = report.addReportLog(r)
+			}
+			if err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err))
+			}
+			continue
+		}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+			continue
+		}
+		if err := report.addJUnit(content); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err))
+		}
+	}
+
+	var total float64
+	for _, t := range report.Tests {
+		total += t.Duration
+		switch t.Outcome {
+		case outcomeError:
+			view.FixtureErrors = append(view.FixtureErrors, t)
+		case outcomeFailed:
+			view.Failures = append(view.Failures, t)
+		}
+	}
+	if len(report.Tests) == 0 && len(report.CollectionErrors) == 0 {
+		view.Errors = append(view.Errors, "No pytest results found.")
+	}
+	view.Counts = report.Counts()
+	view.Time = strconv.FormatFloat(total, 'f', 2, 64) + "s"
+	view.CollectionErrors = report.CollectionErrors
+	view.Modules = report.Modules()
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
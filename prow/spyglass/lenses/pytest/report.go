@@ -0,0 +1,481 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pytest
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The outcomes of a test, as pytest reports them.
+const (
+	outcomePassed  = "passed"
+	outcomeFailed  = "failed"
+	outcomeError   = "error"
+	outcomeSkipped = "skipped"
+	outcomeXFailed = "xfailed"
+	outcomeXPassed = "xpassed"
+
+	// maxSectionBytes is how much of each captured output is kept.
+	maxSectionBytes = 100 << 10
+)
+
+// outcomes are the outcomes in the order pytest summarizes them.
+var outcomes = []string{outcomeFailed, outcomeError, outcomePassed, outcomeSkipped, outcomeXFailed, outcomeXPassed}
+
+// Section is output captured while a test ran, such as "Captured stdout call".
+type Section struct {
+	Name    string
+	Content string
+	// Truncated is the number of bytes of the output not kept.
+	Truncated int
+}
+
+// Test is a test, or a parametrized instance of one.
+type Test struct {
+	// Module is the test's file and any classes, such as "tests/test_api.py::TestClient".
+	Module string
+	// Function is the test's name without its parameters, and Params the ID of its parameters,
+	// such as "1-True" for "test_add[1-True]".
+	Function string
+	Params   string
+	Outcome  string
+	// Phase is the phase an error happened in: "setup" if a fixture failed, or "teardown".
+	Phase    string
+	Message  string
+	Details  string
+	Duration float64
+	Sections []Section
+}
+
+// Name returns the test's name with its parameters.
+func (t *Test) Name() string {
+	if t.Params == "" {
+		return t.Function
+	}
+	return t.Function + "[" + t.Params + "]"
+}
+
+// NodeID returns the ID pytest uses for the test.
+func (t *Test) NodeID() string {
+	if t.Module == "" {
+		return t.Name()
+	}
+	return t.Module + "::" + t.Name()
+}
+
+// Time returns how long the test took for display.
+func (t *Test) Time() string {
+	return strconv.FormatFloat(t.Duration, 'f', 2, 64) + "s"
+}
+
+// key identifies a test in both the junit and the report log formats, which name them differently.
+func (t *Test) key() string {
+	module := strings.Replace(t.Module, "::", ".", -1)
+	module = strings.Replace(strings.Replace(module, ".py", "", 1), "/", ".", -1)
+	return module + "::" + t.Name()
+}
+
+func (t *Test) addSection(name, content string) {
+	if content == "" {
+		return
+	}
+	for _, s := range t.Sections {
+		// Each phase's report repeats the output captured in the phases before it.
+		if s.Name == name {
+			return
+		}
+	}
+	s := Section{Name: name, Content: content}
+	if len(s.Content) > maxSectionBytes {
+		s.Truncated = len(s.Content) - maxSectionBytes
+		s.Content = s.Content[:maxSectionBytes]
+	}
+	t.Sections = append(t.Sections, s)
+}
+
+// CollectionError is a module pytest failed to import or collect tests from.
+type CollectionError struct {
+	Module  string
+	Message string
+}
+
+// Report is the tests of a pytest run.
+type Report struct {
+	Tests            []*Test
+	CollectionErrors []CollectionError
+	index            map[string]*Test
+}
+
+func newReport() *Report {
+	return &Report{index: map[string]*Test{}}
+}
+
+// test returns the test with the given node ID, adding it if it is new.
+func (r *Report) test(module, name string) *Test {
+	t := &Test{Module: module, Function: name}
+	if i := strings.Index(name, "["); i > 0 && strings.HasSuffix(name, "]") {
+		t.Function, t.Params = name[:i], name[i+1:len(name)-1]
+	}
+	if existing, ok := r.index[t.key()]; ok {
+		return existing
+	}
+	r.index[t.key()] = t
+	r.Tests = append(r.Tests, t)
+	return t
+}
+
+// Counts returns the number of tests with each outcome, in the order pytest summarizes them.
+func (r *Report) Counts() []OutcomeCount {
+	counts := map[string]int{}
+	for _, t := range r.Tests {
+		counts[t.Outcome]++
+	}
+	var result []OutcomeCount
+	for _, o := range outcomes {
+		if counts[o] != 0 {
+			result = append(result, OutcomeCount{Outcome: o, Count: counts[o]})
+		}
+	}
+	return result
+}
+
+// OutcomeCount is the number of tests with an outcome.
+type OutcomeCount struct {
+	Outcome string
+	Count   int
+}
+
+// junitSuites is pytest's --junitxml output, which may have a <testsuites> root or not.
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Cases []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	File      string        `xml:"file,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *junitProblem `xml:"skipped"`
+	SystemOut string        `xml:"system-out"`
+	SystemErr string        `xml:"system-err"`
+}
+
+type junitProblem struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// addJUnit adds the tests of pytest's --junitxml output to the report.
+func (r *Report) addJUnit(content []byte) error {
+	var suites junitSuites
+	if err := xml.Unmarshal(content, &suites); err != nil {
+		return err
+	}
+	if len(suites.Suites) == 0 {
+		// The root is a single <testsuite>.
+		var suite junitSuite
+		if err := xml.Unmarshal(content, &suite); err != nil {
+			return err
+		}
+		suites.Suites = []junitSuite{suite}
+	}
+	for _, s := range suites.Suites {
+		for _, c := range s.Cases {
+			if c.ClassName == "" && c.Error != nil {
+				r.CollectionErrors = append(r.CollectionErrors, CollectionError{Module: c.Name, Message: strings.TrimSpace(c.Error.Text)})
+				continue
+			}
+			t := r.test(junitModule(c.ClassName, c.File), c.Name)
+			t.Duration += c.Time
+			switch {
+			case c.Error != nil:
+				// pytest reports fixtures that fail as errors "failed on setup with ..." or
+				// "failed on teardown with ...", in addition to any failure of the test itself.
+				if t.Outcome != outcomeFailed {
+					t.Outcome = outcomeError
+					t.Message, t.Details = c.Error.Message, c.Error.Text
+				}
+				if strings.Contains(c.Error.Message, "teardown") {
+					t.Phase = "teardown"
+				} else {
+					t.Phase = "setup"
+				}
+			case c.Failure != nil:
+				t.Outcome = outcomeFailed
+				t.Message, t.Details = c.Failure.Message, c.Failure.Text
+			case c.Skipped != nil:
+				t.Outcome = outcomeSkipped
+				if c.Skipped.Type == "pytest.xfail" {
+					t.Outcome = outcomeXFailed
+				}
+				t.Message = c.Skipped.Message
+			case t.Outcome == "":
+				t.Outcome = outcomePassed
+			}
+			t.addSection("Captured stdout", c.SystemOut)
+			t.addSection("Captured stderr", c.SystemErr)
+		}
+	}
+	return nil
+}
+
+// junitModule works out a test's module from its junit class name, which has the test's path with
+// dots instead of slashes, and the file if it was recorded.
+func junitModule(className, file string) string {
+	if file == "" {
+		return className
+	}
+	// "tests/test_api.py" and "tests.test_api.TestClient" give "tests/test_api.py::TestClient".
+	base := strings.Replace(strings.TrimSuffix(file, ".py"), "/", ".", -1)
+	if !strings.HasPrefix(className, base) {
+		return className
+	}
+	module := file
+	for _, class := range strings.Split(strings.TrimPrefix(className, base), ".") {
+		if class != "" {
+			module += "::" + class
+		}
+	}
+	return module
+}
+
+// reportLogEntry is a line of pytest's --report-log output, from the pytest-reportlog plugin.
+type reportLogEntry struct {
+	Type     string          `json:"$report_type"`
+	NodeID   string          `json:"nodeid"`
+	Outcome  string          `json:"outcome"`
+	When     string          `json:"when"`
+	Duration float64         `json:"duration"`
+	LongRepr json.RawMessage `json:"longrepr"`
+	WasXFail *string         `json:"wasxfail"`
+	Sections [][]string      `json:"sections"`
+}
+
+// addReportLog adds the tests of pytest's --report-log output to the report. Its outcomes replace
+// those of the junit output, which has less detail.
+func (r *Report) addReportLog(rd io.Reader) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e reportLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		switch e.Type {
+		case "CollectReport":
+			if e.Outcome == outcomeFailed {
+				message, _ := longRepr(e.LongRepr)
+				r.CollectionErrors = append(r.CollectionErrors, CollectionError{Module: e.NodeID, Message: message})
+			}
+		case "TestReport":
+			r.addTestReport(e)
+		}
+	}
+	return scanner.Err()
+}
+
+// addTestReport adds the report of one phase of a test.
+func (r *Report) addTestReport(e reportLogEntry) {
+	module, name := "", e.NodeID
+	// Parameters can contain "::", so look for the last one before them.
+	base := e.NodeID
+	if j := strings.Index(base, "["); j >= 0 {
+		base = base[:j]
+	}
+	if i := strings.LastIndex(base, "::"); i >= 0 {
+		module, name = e.NodeID[:i], e.NodeID[i+2:]
+	}
+	t := r.test(module, name)
+	if e.When == "setup" {
+		// The report log is the more detailed, so replace anything from the junit output.
+		t.Outcome, t.Phase, t.Message, t.Details, t.Duration, t.Sections = "", "", "", "", 0, nil
+	}
+	t.Duration += e.Duration
+	for _, s := range e.Sections {
+		if len(s) == 2 {
+			t.addSection(s[0], s[1])
+		}
+	}
+	switch {
+	case e.Outcome == outcomeFailed && e.When != "call":
+		if t.Outcome != outcomeFailed {
+			t.Outcome, t.Phase = outcomeError, e.When
+			t.Message, t.Details = longRepr(e.LongRepr)
+		}
+	case e.Outcome == outcomeFailed:
+		t.Outcome = outcomeFailed
+		t.Message, t.Details = longRepr(e.LongRepr)
+	case e.Outcome == outcomeSkipped && e.WasXFail != nil:
+		t.Outcome, t.Message = outcomeXFailed, *e.WasXFail
+		if _, details := longRepr(e.LongRepr); details != "" {
+			t.Details = details
+		}
+	case e.Outcome == outcomeSkipped:
+		t.Outcome, t.Message = outcomeSkipped, skipReason(e.LongRepr)
+	case e.Outcome == outcomePassed && e.WasXFail != nil:
+		t.Outcome, t.Message = outcomeXPassed, *e.WasXFail
+	case e.Outcome == outcomePassed && t.Outcome == "":
+		t.Outcome = outcomePassed
+	}
+}
+
+// repr is the serialized longrepr of a failure.
+type repr struct {
+	ReprCrash *struct {
+		Path    string `json:"path"`
+		Line    int    `json:"lineno"`
+		Message string `json:"message"`
+	} `json:"reprcrash"`
+	ReprTraceback *struct {
+		Entries []struct {
+			Data struct {
+				Lines        []string `json:"lines"`
+				FileLocation *struct {
+					Path    string `json:"path"`
+					Line    int    `json:"lineno"`
+					Message string `json:"message"`
+				} `json:"reprfileloc"`
+			} `json:"data"`
+		} `json:"reprentries"`
+	} `json:"reprtraceback"`
+}
+
+// longRepr returns the message and traceback of a failure, which is either text or the traceback
+// pytest would have printed, serialized.
+func longRepr(raw json.RawMessage) (string, string) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return lastLine(text), text
+	}
+	var r repr
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return "", string(raw)
+	}
+	var message string
+	if r.ReprCrash != nil {
+		message = r.ReprCrash.Message
+	}
+	if r.ReprTraceback == nil {
+		return message, ""
+	}
+	var lines []string
+	for i, entry := range r.ReprTraceback.Entries {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, entry.Data.Lines...)
+		if loc := entry.Data.FileLocation; loc != nil {
+			lines = append(lines, "", fmt.Sprintf("%s:%d: %s", loc.Path, loc.Line, loc.Message))
+		}
+	}
+	return message, strings.Join(lines, "\n")
+}
+
+// skipReason returns the reason for a skip, whose longrepr is a path, line and reason.
+func skipReason(raw json.RawMessage) string {
+	var parts []interface{}
+	if err := json.Unmarshal(raw, &parts); err == nil && len(parts) == 3 {
+		if reason, ok := parts[2].(string); ok {
+			return strings.TrimPrefix(reason, "Skipped: ")
+		}
+	}
+	message, _ := longRepr(raw)
+	return message
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+// Function is a test function and its parametrized instances.
+type Function struct {
+	Name  string
+	Tests []*Test
+}
+
+// Outcome returns the worst outcome of the function's tests.
+func (f *Function) Outcome() string {
+	for _, o := range outcomes {
+		for _, t := range f.Tests {
+			if t.Outcome == o {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// Parametrized reports whether the function's tests have parameters.
+func (f *Function) Parametrized() bool {
+	return len(f.Tests) > 1 || len(f.Tests) == 1 && f.Tests[0].Params != ""
+}
+
+// Module is a test file, or a class in one, and its test functions.
+type Module struct {
+	Name      string
+	Functions []*Function
+	Failed    bool
+}
+
+// Modules groups the report's tests by module and function, sorted by name, keeping the order
+// parametrized instances ran in.
+func (r *Report) Modules() []*Module {
+	var modules []*Module
+	moduleIndex := map[string]*Module{}
+	functionIndex := map[string]*Function{}
+	for _, t := range r.Tests {
+		m, ok := moduleIndex[t.Module]
+		if !ok {
+			m = &Module{Name: t.Module}
+			moduleIndex[t.Module] = m
+			modules = append(modules, m)
+		}
+		k := t.Module + "::" + t.Function
+		f, ok := functionIndex[k]
+		if !ok {
+			f = &Function{Name: t.Function}
+			functionIndex[k] = f
+			m.Functions = append(m.Functions, f)
+		}
+		f.Tests = append(f.Tests, t)
+		if t.Outcome == outcomeFailed || t.Outcome == outcomeError {
+			m.Failed = true
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pytest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const junitXML = `<?xml version="1.0" encoding="utf-8"?>
+<testsuites>
+  <testsuite name="pytest" errors="2" failures="1" skipped="2" tests="7" time="1.5">
+    <testcase classname="tests.test_math" file="tests/test_math.py" line="3" name="test_add[1-2]" time="0.01"/>
+    <testcase classname="tests.test_math" file="tests/test_math.py" line="3" name="test_add[2-3]" time="0.02">
+      <failure message="assert 5 == 6">def test_add(a, b):
+&gt;       assert a + b == 6
+E       assert 5 == 6</failure>
+      <system-out>adding 2 and 3</system-out>
+    </testcase>
+    <testcase classname="tests.test_math.TestDivide" file="tests/test_math.py" line="10" name="test_zero" time="0.5">
+      <error message="failed on setup with &quot;ConnectionError&quot;">@pytest.fixture
+def db():
+&gt;   raise ConnectionError
+E   ConnectionError</error>
+    </testcase>
+    <testcase classname="tests.test_math.TestDivide" file="tests/test_math.py" line="14" name="test_skip" time="0">
+      <skipped type="pytest.skip" message="not on linux">tests/test_math.py:14: not on linux</skipped>
+    </testcase>
+    <testcase classname="tests.test_math.TestDivide" file="tests/test_math.py" line="18" name="test_flaky" time="0">
+      <skipped type="pytest.xfail" message="known bug"/>
+    </testcase>
+    <testcase classname="" name="tests.test_broken" time="0">
+      <error message="collection failure">ImportError: No module named foo</error>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+const reportLog = `{"pytest_version": "5.4.1", "$report_type": "SessionStart"}
+{"nodeid": "tests/test_math.py::test_add[1-2]", "outcome": "passed", "when": "setup", "duration": 0.001, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_add[1-2]", "outcome": "passed", "when": "call", "duration": 0.01, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_add[1-2]", "outcome": "passed", "when": "teardown", "duration": 0.001, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_add[2-3]", "outcome": "passed", "when": "setup", "duration": 0.001, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_add[2-3]", "outcome": "failed", "when": "call", "duration": 0.02, "longrepr": {"reprcrash": {"path": "/src/tests/test_math.py", "lineno": 5, "message": "assert 5 == 6"}, "reprtraceback": {"reprentries": [{"type": "ReprEntry", "data": {"lines": ["    def test_add(a, b):", ">       assert a + b == 6", "E       assert 5 == 6"], "reprfileloc": {"path": "tests/test_math.py", "lineno": 5, "message": "AssertionError"}, "style": "long"}}]}}, "sections": [["Captured stdout call", "adding 2 and 3\n"]], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_add[2-3]", "outcome": "failed", "when": "teardown", "duration": 0.001, "longrepr": "def cleanup():\n>   raise OSError\nE   OSError", "sections": [["Captured stdout call", "adding 2 and 3\n"]], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::TestDivide::test_zero", "outcome": "failed", "when": "setup", "duration": 0.5, "longrepr": "@pytest.fixture\ndef db():\n>   raise ConnectionError\nE   ConnectionError", "sections": [["Captured log setup", "connecting"]], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::TestDivide::test_zero", "outcome": "passed", "when": "teardown", "duration": 0.001, "longrepr": null, "sections": [["Captured log setup", "connecting"]], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::TestDivide::test_skip", "outcome": "skipped", "when": "setup", "duration": 0.0, "longrepr": ["tests/test_math.py", 14, "Skipped: not on linux"], "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::TestDivide::test_flaky", "outcome": "passed", "when": "setup", "duration": 0.0, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::TestDivide::test_flaky", "outcome": "skipped", "when": "call", "duration": 0.0, "longrepr": "E   AssertionError", "wasxfail": "known bug", "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_ids[a::b]", "outcome": "passed", "when": "setup", "duration": 0.0, "longrepr": null, "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_math.py::test_ids[a::b]", "outcome": "passed", "when": "call", "duration": 0.0, "longrepr": null, "wasxfail": "", "sections": [], "$report_type": "TestReport"}
+{"nodeid": "tests/test_broken.py", "outcome": "failed", "longrepr": "ImportError while importing test module\nE   ModuleNotFoundError: No module named 'foo'", "result": [], "sections": [], "$report_type": "CollectReport"}
+{"exitstatus": 1, "$report_type": "SessionFinish"}
+`
+
+// testSummary is the parts of a test the tests check.
+type testSummary struct {
+	NodeID  string
+	Outcome string
+	Phase   string
+	Message string
+}
+
+func summarize(r *Report) []testSummary {
+	var tests []testSummary
+	for _, t := range r.Tests {
+		tests = append(tests, testSummary{NodeID: t.NodeID(), Outcome: t.Outcome, Phase: t.Phase, Message: t.Message})
+	}
+	return tests
+}
+
+func TestAddJUnit(t *testing.T) {
+	r := newReport()
+	if err := r.addJUnit([]byte(junitXML)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []testSummary{
+		{NodeID: "tests/test_math.py::test_add[1-2]", Outcome: outcomePassed},
+		{NodeID: "tests/test_math.py::test_add[2-3]", Outcome: outcomeFailed, Message: "assert 5 == 6"},
+		{NodeID: "tests/test_math.py::TestDivide::test_zero", Outcome: outcomeError, Phase: "setup", Message: `failed on setup with "ConnectionError"`},
+		{NodeID: "tests/test_math.py::TestDivide::test_skip", Outcome: outcomeSkipped, Message: "not on linux"},
+		{NodeID: "tests/test_math.py::TestDivide::test_flaky", Outcome: outcomeXFailed, Message: "known bug"},
+	}
+	if tests := summarize(r); !reflect.DeepEqual(tests, expected) {
+		t.Errorf("expected %+v, got %+v", expected, tests)
+	}
+	if len(r.CollectionErrors) != 1 || r.CollectionErrors[0].Module != "tests.test_broken" {
+		t.Errorf("expected a collection error for tests.test_broken, got %+v", r.CollectionErrors)
+	}
+	if s := r.Tests[1].Sections; len(s) != 1 || s[0].Content != "adding 2 and 3" {
+		t.Errorf("expected the captured stdout of test_add[2-3], got %+v", s)
+	}
+}
+
+func TestAddReportLog(t *testing.T) {
+	r := newReport()
+	if err := r.addReportLog(strings.NewReader(reportLog)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []testSummary{
+		{NodeID: "tests/test_math.py::test_add[1-2]", Outcome: outcomePassed},
+		{NodeID: "tests/test_math.py::test_add[2-3]", Outcome: outcomeFailed, Message: "assert 5 == 6"},
+		{NodeID: "tests/test_math.py::TestDivide::test_zero", Outcome: outcomeError, Phase: "setup", Message: "E   ConnectionError"},
+		{NodeID: "tests/test_math.py::TestDivide::test_skip", Outcome: outcomeSkipped, Message: "not on linux"},
+		{NodeID: "tests/test_math.py::TestDivide::test_flaky", Outcome: outcomeXFailed, Message: "known bug"},
+		{NodeID: "tests/test_math.py::test_ids[a::b]", Outcome: outcomeXPassed},
+	}
+	if tests := summarize(r); !reflect.DeepEqual(tests, expected) {
+		t.Errorf("expected %+v, got %+v", expected, tests)
+	}
+	failed := r.Tests[1]
+	expectedDetails := "    def test_add(a, b):\n>       assert a + b == 6\nE       assert 5 == 6\n\ntests/test_math.py:5: AssertionError"
+	if failed.Details != expectedDetails {
+		t.Errorf("expected details %q, got %q", expectedDetails, failed.Details)
+	}
+	if len(failed.Sections) != 1 {
+		t.Errorf("expected the repeated section to be kept once, got %+v", failed.Sections)
+	}
+	if len(r.CollectionErrors) != 1 || r.CollectionErrors[0].Module != "tests/test_broken.py" {
+		t.Errorf("expected a collection error for tests/test_broken.py, got %+v", r.CollectionErrors)
+	}
+}
+
+func TestReportLogReplacesJUnit(t *testing.T) {
+	r := newReport()
+	if err := r.addJUnit([]byte(junitXML)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.addReportLog(strings.NewReader(reportLog)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Tests) != 6 {
+		t.Fatalf("expected the tests of both to be merged into 6, got %d", len(r.Tests))
+	}
+	if zero := r.Tests[2]; zero.Message != "E   ConnectionError" || len(zero.Sections) != 1 || zero.Sections[0].Name != "Captured log setup" {
+		t.Errorf("expected the report log to replace the junit result, got %+v", zero)
+	}
+	expectedCounts := []OutcomeCount{
+		{Outcome: outcomeFailed, Count: 1},
+		{Outcome: outcomeError, Count: 1},
+		{Outcome: outcomePassed, Count: 1},
+		{Outcome: outcomeSkipped, Count: 1},
+		{Outcome: outcomeXFailed, Count: 1},
+		{Outcome: outcomeXPassed, Count: 1},
+	}
+	if counts := r.Counts(); !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("expected counts %+v, got %+v", expectedCounts, counts)
+	}
+}
+
+func TestModules(t *testing.T) {
+	r := newReport()
+	if err := r.addReportLog(strings.NewReader(reportLog)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	modules := r.Modules()
+	if len(modules) != 2 {
+		t.Fatalf("expected 2 modules, got %d", len(modules))
+	}
+	module := modules[0]
+	if module.Name != "tests/test_math.py" || !module.Failed || len(module.Functions) != 2 {
+		t.Fatalf("expected tests/test_math.py to have failed with 2 functions, got %+v", module)
+	}
+	add := module.Functions[0]
+	if add.Name != "test_add" || !add.Parametrized() || len(add.Tests) != 2 || add.Outcome() != outcomeFailed {
+		t.Errorf("expected test_add to be parametrized twice and to have failed, got %+v", add)
+	}
+	if ids := module.Functions[1]; ids.Name != "test_ids" || ids.Tests[0].Params != "a::b" {
+		t.Errorf("expected test_ids with parameters a::b, got %+v", ids)
+	}
+	if class := modules[1]; class.Name != "tests/test_math.py::TestDivide" || len(class.Functions) != 3 {
+		t.Errorf("expected TestDivide with 3 functions, got %+v", class)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// readChunkSize is the amount of an artifact fetched by each read of a chunked reader.
+const readChunkSize = 4 << 20
+
+// chunkedReader reads an artifact from start to end in fixed size chunks. Chunks are split
+// between characters, so that no read returns part of a multi-byte UTF-8 character unless it is
+// too short to hold the whole of one. If truncated
+// is set, end is short of the end of the artifact, and a character it cuts off is dropped.
+type chunkedReader struct {
+	artifact  Artifact
+	offset    int64
+	end       int64
+	limit     int64
+	truncated bool
+	buf       []byte
+	// partial is the end of the last chunk read, which cuts off a character.
+	partial []byte
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.offset >= r.end {
+			return 0, io.EOF
+		}
+		size := int64(readChunkSize)
+		if remaining := r.end - r.offset; remaining < size {
+			size = remaining
+		}
+		chunk := make([]byte, size)
+		n, err := r.artifact.ReadAt(chunk, r.offset)
+		if err == ErrGzipOffsetRead && r.offset == 0 {
+			return r.readGzipped(p)
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if n == 0 {
+			// The artifact ended early, so whatever is left of it is read as it is.
+			r.offset = r.end
+			r.buf, r.partial = r.partial, nil
+			continue
+		}
+		r.offset += int64(n)
+		r.buf = r.completeRunes(chunk[:n])
+	}
+	n := copy(p, r.buf)
+	if n < len(r.buf) && n > incompleteRuneSuffix(p[:n]) {
+		n -= incompleteRuneSuffix(p[:n])
+	}
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// completeRunes prepends the end of the last chunk to a chunk if it cut off a character, and
+// holds back the end of this chunk if it cuts one off in turn.
+func (r *chunkedReader) completeRunes(chunk []byte) []byte {
+	if len(r.partial) > 0 {
+		chunk = append(r.partial, chunk...)
+		r.partial = nil
+	}
+	cut := incompleteRuneSuffix(chunk)
+	switch {
+	case cut == 0:
+		return chunk
+	case r.offset < r.end:
+		r.partial = append([]byte(nil), chunk[len(chunk)-cut:]...)
+		return chunk[:len(chunk)-cut]
+	case r.truncated:
+		return chunk[:len(chunk)-cut]
+	default:
+		return chunk
+	}
+}
+
+// incompleteRuneSuffix returns the number of bytes at the end of b that begin a multi-byte UTF-8
+// character without finishing it.
+func incompleteRuneSuffix(b []byte) int {
+	// A character cut off has at most utf8.UTFMax-1 of its bytes left.
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if utf8.FullRune(b[len(b)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// trimRuneContinuation drops the bytes at the start of b that finish a multi-byte UTF-8
+// character begun before b.
+func trimRuneContinuation(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && len(b) > 0 && !utf8.RuneStart(b[0]); i++ {
+		b = b[1:]
+	}
+	return b
+}
+
+// readGzipped reads the whole of a gzipped artifact, which cannot be read at an offset,
+// into the buffer.
+func (r *chunkedReader) readGzipped(p []byte) (int, error) {
+	content, err := r.artifact.ReadAtMost(r.limit)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read gzipped artifact: %v", err)
+	}
+	// The limit applies to the compressed size, so the content may be larger.
+	if int64(len(content)) >= r.limit {
+		content = content[:r.limit]
+		content = content[:len(content)-incompleteRuneSuffix(content)]
+	}
+	r.offset = r.end
+	r.buf = content
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	return r.Read(p)
+}
+
+// NewChunkedReader returns a reader over the first limit bytes of an artifact that fetches it
+// a chunk at a time, so that artifacts too large to read in full can still be scanned.
+// Gzipped artifacts cannot be read at an offset, so up to limit bytes of them are read at once.
+func NewChunkedReader(artifact Artifact, limit int64) (io.Reader, error) {
+	size, err := artifact.Size()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact size: %v", err)
+	}
+	truncated := size > limit
+	if truncated {
+		size = limit
+	}
+	return &chunkedReader{artifact: artifact, end: size, limit: limit, truncated: truncated}, nil
+}
+
+// NewSectionReader returns a reader over length bytes of an artifact from offset that fetches
+// them a chunk at a time. Gzipped artifacts cannot be read at an offset, so reading them fails
+// with ErrGzipOffsetRead unless offset is 0.
+func NewSectionReader(artifact Artifact, offset, length int64) io.Reader {
+	return &chunkedReader{artifact: artifact, offset: offset, end: offset + length, limit: offset + length}
+}
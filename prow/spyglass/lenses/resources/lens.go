@@ -0,0 +1,238 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resources provides a Spyglass lens that charts the CPU and memory usage of a job's containers.
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	name     = "resources"
+	title    = "Resource Usage"
+	priority = 18
+
+	// maxContainersPerChart bounds the number of lines drawn on one chart.
+	maxContainersPerChart = 10
+	// throttleWarning is the fraction of throttled CFS periods above which a container is flagged.
+	throttleWarning = 0.25
+	// memoryWarning is the fraction of the memory limit above which a container is flagged.
+	memoryWarning = 0.9
+)
+
+// Lens is the implementation of a resource usage-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// Namespaces, if set, limits the lens to containers in these namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Containers, if set, limits the lens to containers with these names.
+	Containers []string `json:"containers,omitempty"`
+}
+
+func (c config) includes(ctr *container) bool {
+	return matchesAny(c.Namespaces, ctr.Namespace) && matchesAny(c.Containers, ctr.Name)
+}
+
+func matchesAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// ContainerView summarizes the usage of one container.
+type ContainerView struct {
+	ID         string
+	Color      string
+	PeakCPU    string
+	MeanCPU    string
+	PeakMemory string
+	Limit      string
+	Throttled  string
+	OOMEvents  int
+	// Flagged is true if the container was throttled, OOM killed or close to its memory limit.
+	Flagged bool
+}
+
+type usageView struct {
+	Errors     []string
+	Warnings   []string
+	CPU        *chart.Chart
+	Memory     *chart.Chart
+	Containers []ContainerView
+	// Omitted is the number of containers that were not charted.
+	Omitted int
+}
+
+// Body charts the usage of each container and flags throttling and OOM kills.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := usageView{}
+	var conf config
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+
+	u := newUsage()
+	for _, a := range artifacts {
+		content, err := a.ReadAll()
+		if err == nil {
+			err = u.add(content)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+		}
+	}
+
+	var containers []*container
+	for _, c := range u.sortedContainers() {
+		if conf.includes(c) {
+			containers = append(containers, c)
+		}
+	}
+	charted := containers
+	if len(charted) > maxContainersPerChart {
+		view.Omitted = len(charted) - maxContainersPerChart
+		charted = charted[:maxContainersPerChart]
+	}
+	view.CPU = plot(charted, func(c *container) []point { return c.CPU }, chart.FormatValue)
+	view.Memory = plot(charted, func(c *container) []point { return c.Memory }, chart.FormatBytes)
+
+	for i, c := range containers {
+		cv, warnings := summarize(c)
+		if i < len(charted) {
+			cv.Color = chart.Colors[i%len(chart.Colors)]
+		}
+		view.Containers = append(view.Containers, cv)
+		view.Warnings = append(view.Warnings, warnings...)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// summarize describes the usage of a container, and returns any problems with it.
+func summarize(c *container) (ContainerView, []string) {
+	cv := ContainerView{
+		ID:         c.ID(),
+		PeakCPU:    fmt.Sprintf("%.3g", peak(c.CPU)),
+		MeanCPU:    fmt.Sprintf("%.3g", mean(c.CPU)),
+		PeakMemory: chart.FormatBytes(peak(c.Memory)),
+		OOMEvents:  c.OOMEvents(),
+	}
+	var warnings []string
+	if c.MemoryLimit > 0 {
+		cv.Limit = chart.FormatBytes(c.MemoryLimit)
+		if peak(c.Memory) >= memoryWarning*c.MemoryLimit {
+			warnings = append(warnings, fmt.Sprintf("%s used up to %s of its %s memory limit.", cv.ID, cv.PeakMemory, cv.Limit))
+		}
+	}
+	if throttled := c.ThrottledFraction(); !math.IsNaN(throttled) {
+		cv.Throttled = fmt.Sprintf("%.0f%%", throttled*100)
+		if throttled >= throttleWarning {
+			warnings = append(warnings, fmt.Sprintf("%s was CPU throttled in %s of scheduling periods.", cv.ID, cv.Throttled))
+		}
+	}
+	if cv.OOMEvents == 1 {
+		warnings = append(warnings, fmt.Sprintf("%s was OOM killed.", cv.ID))
+	} else if cv.OOMEvents > 1 {
+		warnings = append(warnings, fmt.Sprintf("%s was OOM killed %d times.", cv.ID, cv.OOMEvents))
+	}
+	cv.Flagged = len(warnings) > 0
+	return cv, warnings
+}
+
+// plot charts one resource of each container against the time since the first sample.
+// It returns nil if there is nothing to chart.
+func plot(containers []*container, resource func(*container) []point, format func(float64) string) *chart.Chart {
+	var start time.Time
+	for _, c := range containers {
+		for _, p := range resource(c) {
+			if start.IsZero() || p.Time.Before(start) {
+				start = p.Time
+			}
+		}
+	}
+	var series []chart.Series
+	for _, c := range containers {
+		s := chart.Series{Label: c.ID()}
+		for _, p := range resource(c) {
+			s.Points = append(s.Points, chart.Point{X: p.Time.Sub(start).Seconds(), Y: p.Value})
+		}
+		series = append(series, s)
+	}
+	plot, err := chart.New(series, chart.Options{
+		FormatX: func(x float64) string { return (time.Duration(x) * time.Second).String() },
+		FormatY: format,
+	})
+	if err != nil {
+		return nil
+	}
+	return plot
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+// point is a single observation.
+type point struct {
+	Time  time.Time
+	Value float64
+}
+
+// container is the resource usage of one container over the course of the job.
+type container struct {
+	Namespace string
+	Pod       string
+	Name      string
+	// CPU is in cores and Memory is the working set in bytes.
+	CPU    []point
+	Memory []point
+	// MemoryLimit is zero if the container had no limit or it is unknown.
+	MemoryLimit float64
+
+	// Cumulative counters from cadvisor.
+	cpuSeconds       []point
+	throttledPeriods []point
+	periods          []point
+	oomEvents        []point
+}
+
+// ID identifies the container.
+func (c *container) ID() string {
+	return c.Namespace + "/" + c.Pod + "/" + c.Name
+}
+
+// usage is the resource usage of every container found in the artifacts.
+type usage struct {
+	containers map[string]*container
+}
+
+func newUsage() *usage {
+	return &usage{containers: map[string]*container{}}
+}
+
+func (u *usage) container(namespace, pod, name string) *container {
+	c := &container{Namespace: namespace, Pod: pod, Name: name}
+	if existing, ok := u.containers[c.ID()]; ok {
+		return existing
+	}
+	u.containers[c.ID()] = c
+	return c
+}
+
+// add reads an artifact, which may be either kubelet stats summaries or a cadvisor metrics dump.
+func (u *usage) add(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return u.addSummaries(trimmed)
+	}
+	return u.addCadvisor(data)
+}
+
+// summary is the subset of the kubelet's /stats/summary response used by the lens.
+type summary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name string `json:"name"`
+			CPU  *struct {
+				Time           time.Time `json:"time"`
+				UsageNanoCores *uint64   `json:"usageNanoCores"`
+			} `json:"cpu"`
+			Memory *struct {
+				Time            time.Time `json:"time"`
+				WorkingSetBytes *uint64   `json:"workingSetBytes"`
+			} `json:"memory"`
+		} `json:"containers"`
+	} `json:"pods"`
+}
+
+// addSummaries reads one or more concatenated kubelet stats summaries.
+func (u *usage) addSummaries(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var s summary
+		if err := decoder.Decode(&s); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to parse stats summary: %v", err)
+		}
+		for _, pod := range s.Pods {
+			for _, c := range pod.Containers {
+				ctr := u.container(pod.PodRef.Namespace, pod.PodRef.Name, c.Name)
+				if c.CPU != nil && c.CPU.UsageNanoCores != nil {
+					ctr.CPU = append(ctr.CPU, point{Time: c.CPU.Time, Value: float64(*c.CPU.UsageNanoCores) / 1e9})
+				}
+				if c.Memory != nil && c.Memory.WorkingSetBytes != nil {
+					ctr.Memory = append(ctr.Memory, point{Time: c.Memory.Time, Value: float64(*c.Memory.WorkingSetBytes)})
+				}
+			}
+		}
+	}
+}
+
+// unlimitedMemory is the smallest limit that cadvisor reports for containers with no memory limit.
+const unlimitedMemory = 1 << 62
+
+// addCadvisor reads a dump of cadvisor's Prometheus metrics. Samples without timestamps are ignored.
+func (u *usage) addCadvisor(data []byte) error {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data[:len(data):len(data)], '\n')
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics: %v", err)
+	}
+	for family, mf := range families {
+		for _, m := range mf.Metric {
+			labels := map[string]string{}
+			for _, l := range m.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			// Older versions of cadvisor use pod_name and container_name.
+			pod, name := labels["pod"], labels["container"]
+			if pod == "" {
+				pod = labels["pod_name"]
+			}
+			if name == "" {
+				name = labels["container_name"]
+			}
+			// Cgroups for whole pods have no container name, and "POD" is the pause container.
+			if pod == "" || name == "" || name == "POD" || m.TimestampMs == nil {
+				continue
+			}
+			value := m.GetUntyped().GetValue() + m.GetCounter().GetValue() + m.GetGauge().GetValue()
+			p := point{Time: time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond)), Value: value}
+			switch family {
+			case "container_cpu_usage_seconds_total":
+				// Some versions of cadvisor split usage by CPU; only the total is needed.
+				if cpu := labels["cpu"]; cpu != "" && cpu != "total" {
+					continue
+				}
+				ctr := u.container(labels["namespace"], pod, name)
+				ctr.cpuSeconds = append(ctr.cpuSeconds, p)
+			case "container_memory_working_set_bytes":
+				ctr := u.container(labels["namespace"], pod, name)
+				ctr.Memory = append(ctr.Memory, p)
+			case "container_spec_memory_limit_bytes":
+				if value > 0 && value < unlimitedMemory {
+					u.container(labels["namespace"], pod, name).MemoryLimit = value
+				}
+			case "container_cpu_cfs_throttled_periods_total":
+				ctr := u.container(labels["namespace"], pod, name)
+				ctr.throttledPeriods = append(ctr.throttledPeriods, p)
+			case "container_cpu_cfs_periods_total":
+				ctr := u.container(labels["namespace"], pod, name)
+				ctr.periods = append(ctr.periods, p)
+			case "container_oom_events_total":
+				ctr := u.container(labels["namespace"], pod, name)
+				ctr.oomEvents = append(ctr.oomEvents, p)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedContainers finalizes the collected data and returns every container that has
+// any usage data, ordered by peak memory usage.
+func (u *usage) sortedContainers() []*container {
+	var result []*container
+	for _, c := range u.containers {
+		c.finalize()
+		if len(c.CPU) > 0 || len(c.Memory) > 0 {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		pi, pj := peak(result[i].Memory), peak(result[j].Memory)
+		if pi != pj {
+			return pi > pj
+		}
+		return result[i].ID() < result[j].ID()
+	})
+	return result
+}
+
+func sortPoints(points []point) {
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+}
+
+func (c *container) finalize() {
+	for _, points := range [][]point{c.CPU, c.Memory, c.cpuSeconds, c.throttledPeriods, c.periods, c.oomEvents} {
+		sortPoints(points)
+	}
+	// Derive CPU usage from the cumulative counter, handling restarts as counter resets.
+	for i := 1; i < len(c.cpuSeconds); i++ {
+		prev, cur := c.cpuSeconds[i-1], c.cpuSeconds[i]
+		interval := cur.Time.Sub(prev.Time).Seconds()
+		if interval <= 0 {
+			continue
+		}
+		c.CPU = append(c.CPU, point{Time: cur.Time, Value: increase([]point{prev, cur}) / interval})
+	}
+	c.cpuSeconds = nil
+	sortPoints(c.CPU)
+}
+
+// increase returns the total increase of a counter, treating decreases as resets.
+func increase(points []point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		if d := points[i].Value - points[i-1].Value; d >= 0 {
+			total += d
+		} else {
+			total += points[i].Value
+		}
+	}
+	return total
+}
+
+// ThrottledFraction returns the fraction of CFS periods in which the container was throttled
+// over the observed interval, or NaN if it is unknown.
+func (c *container) ThrottledFraction() float64 {
+	periods := increase(c.periods)
+	if periods == 0 {
+		return math.NaN()
+	}
+	return increase(c.throttledPeriods) / periods
+}
+
+// OOMEvents returns the number of times the container has been OOM killed.
+func (c *container) OOMEvents() int {
+	return int(peak(c.oomEvents))
+}
+
+func peak(points []point) float64 {
+	result := 0.0
+	for _, p := range points {
+		result = math.Max(result, p.Value)
+	}
+	return result
+}
+
+func mean(points []point) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, p := range points {
+		total += p.Value
+	}
+	return total / float64(len(points))
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddSummaries(t *testing.T) {
+	summaries := `{"pods": [{"podRef": {"name": "job-pod", "namespace": "test-pods"}, "containers": [
+  {"name": "test", "cpu": {"time": "2019-03-01T10:00:00Z", "usageNanoCores": 1500000000}, "memory": {"time": "2019-03-01T10:00:00Z", "workingSetBytes": 1048576}}
+]}]}
+{"pods": [{"podRef": {"name": "job-pod", "namespace": "test-pods"}, "containers": [
+  {"name": "test", "cpu": {"time": "2019-03-01T10:00:10Z", "usageNanoCores": 500000000}, "memory": {"time": "2019-03-01T10:00:10Z", "workingSetBytes": 2097152}}
+]}]}`
+	u := newUsage()
+	if err := u.add([]byte(summaries)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	containers := u.sortedContainers()
+	if len(containers) != 1 || containers[0].ID() != "test-pods/job-pod/test" {
+		t.Fatalf("expected one container, got %v", containers)
+	}
+	start := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	expectedCPU := []point{{Time: start, Value: 1.5}, {Time: start.Add(10 * time.Second), Value: 0.5}}
+	if !reflect.DeepEqual(containers[0].CPU, expectedCPU) {
+		t.Errorf("expected CPU %v, got %v", expectedCPU, containers[0].CPU)
+	}
+	if p := peak(containers[0].Memory); p != 2097152 {
+		t.Errorf("expected peak memory of 2Mi, got %v", p)
+	}
+}
+
+const cadvisorDump = `# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="test",cpu="total",namespace="test-pods",pod="job-pod"} 10 1551434400000
+container_cpu_usage_seconds_total{container="test",cpu="total",namespace="test-pods",pod="job-pod"} 30 1551434410000
+container_cpu_usage_seconds_total{container="POD",cpu="total",namespace="test-pods",pod="job-pod"} 1 1551434410000
+container_cpu_usage_seconds_total{container="",cpu="total",namespace="test-pods",pod="job-pod"} 31 1551434410000
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{container_name="test",namespace="test-pods",pod_name="job-pod"} 950 1551434410000
+# TYPE container_spec_memory_limit_bytes gauge
+container_spec_memory_limit_bytes{container="test",namespace="test-pods",pod="job-pod"} 1000 1551434410000
+# TYPE container_cpu_cfs_periods_total counter
+container_cpu_cfs_periods_total{container="test",namespace="test-pods",pod="job-pod"} 100 1551434400000
+container_cpu_cfs_periods_total{container="test",namespace="test-pods",pod="job-pod"} 200 1551434410000
+# TYPE container_cpu_cfs_throttled_periods_total counter
+container_cpu_cfs_throttled_periods_total{container="test",namespace="test-pods",pod="job-pod"} 10 1551434400000
+container_cpu_cfs_throttled_periods_total{container="test",namespace="test-pods",pod="job-pod"} 60 1551434410000
+# TYPE container_oom_events_total counter
+container_oom_events_total{container="test",namespace="test-pods",pod="job-pod"} 1 1551434410000`
+
+func TestAddCadvisor(t *testing.T) {
+	u := newUsage()
+	if err := u.add([]byte(cadvisorDump)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	containers := u.sortedContainers()
+	if len(containers) != 1 {
+		t.Fatalf("expected only the test container, got %d containers", len(containers))
+	}
+	c := containers[0]
+	if len(c.CPU) != 1 || c.CPU[0].Value != 2 {
+		t.Errorf("expected CPU usage of 2 cores derived from the counter, got %v", c.CPU)
+	}
+	if f := c.ThrottledFraction(); f != 0.5 {
+		t.Errorf("expected half of the periods to be throttled, got %v", f)
+	}
+
+	cv, warnings := summarize(c)
+	if cv.Limit != "1000" || cv.Throttled != "50%" || cv.OOMEvents != 1 || !cv.Flagged {
+		t.Errorf("unexpected summary: %#v", cv)
+	}
+	expected := []string{"memory limit", "CPU throttled in 50%", "OOM killed"}
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w, expected[i]) {
+			t.Errorf("expected warning %q to mention %q", w, expected[i])
+		}
+	}
+}
+
+func TestIncrease(t *testing.T) {
+	points := []point{{Value: 5}, {Value: 8}, {Value: 2}, {Value: 4}}
+	if actual := increase(points); actual != 7 {
+		t.Errorf("expected an increase of 7 across the reset, got %v", actual)
+	}
+}
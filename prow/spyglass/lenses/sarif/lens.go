@@ -0,0 +1,205 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sarif provides a Spyglass lens that renders static analysis results in the SARIF format.
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "sarif"
+	title    = "Static Analysis"
+	priority = 24
+)
+
+// Lens is the implementation of a SARIF-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// SourceRoots are paths at which the repository may have been checked out, in addition to
+	// its path alias and org/repo, which are removed from absolute locations to link to the source.
+	SourceRoots []string `json:"source_roots,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// FindingView is a finding with a link to its location in the source.
+type FindingView struct {
+	Finding
+	Link string
+}
+
+// GroupView is a group of findings with links to their locations.
+type GroupView struct {
+	*Group
+	Findings []FindingView
+}
+
+type sarifView struct {
+	Errors []string
+	Total  int
+	Counts []Count
+	ByRule []GroupView
+	ByFile []GroupView
+}
+
+// Body renders the findings in the SARIF logs, grouped by rule and by file. If the lens is also
+// given the job's prowjob.json, locations link to the source at the commit that was tested.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := sarifView{}
+	var conf config
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	var refs *prowapi.Refs
+	var logs []lenses.Artifact
+	for _, a := range artifacts {
+		if a.JobPath() != "prowjob.json" {
+			logs = append(logs, a)
+			continue
+		}
+		content, err := a.ReadAll()
+		if err != nil {
+			logrus.WithError(err).Error("Failed reading prowjob.json")
+			continue
+		}
+		var pj prowapi.ProwJob
+		if err := json.Unmarshal(content, &pj); err != nil {
+			logrus.WithError(err).Error("Error unmarshaling prowjob.json")
+			continue
+		}
+		refs = pj.Spec.Refs
+	}
+
+	roots := conf.SourceRoots
+	if refs != nil {
+		if refs.PathAlias != "" {
+			roots = append(roots, refs.PathAlias)
+		}
+		roots = append(roots, refs.Org+"/"+refs.Repo)
+	}
+	report := newReport()
+	for _, a := range logs {
+		content, err := a.ReadAll()
+		if err == nil {
+			err = report.add(content, roots)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+		}
+	}
+
+	link := sourceLinker(refs)
+	withLinks := func(groups []*Group) []GroupView {
+		var views []GroupView
+		for _, g := range groups {
+			gv := GroupView{Group: g}
+			for _, f := range g.Findings {
+				gv.Findings = append(gv.Findings, FindingView{Finding: f, Link: link(f)})
+			}
+			views = append(views, gv)
+		}
+		return views
+	}
+	view.Total = len(report.Findings)
+	view.Counts = report.counts()
+	view.ByRule = withLinks(report.byRule())
+	view.ByFile = withLinks(report.byFile())
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// sourceLinker returns a function that links findings to their location in the repository at
+// the commit that was tested. Findings in pull requests link to the head of the pull request,
+// as that is what the author will change. Without refs, nothing is linked.
+func sourceLinker(refs *prowapi.Refs) func(Finding) string {
+	if refs == nil {
+		return func(Finding) string { return "" }
+	}
+	repoLink := refs.RepoLink
+	if repoLink == "" {
+		repoLink = fmt.Sprintf("https://github.com/%s/%s", refs.Org, refs.Repo)
+	}
+	commit := refs.BaseSHA
+	if len(refs.Pulls) == 1 && refs.Pulls[0].SHA != "" {
+		commit = refs.Pulls[0].SHA
+	}
+	if commit == "" {
+		commit = refs.BaseRef
+	}
+	return func(f Finding) string {
+		if !f.Relative || f.File == "" || commit == "" {
+			return ""
+		}
+		link := fmt.Sprintf("%s/blob/%s/%s", repoLink, commit, f.File)
+		if f.StartLine > 0 {
+			link += fmt.Sprintf("#L%d", f.StartLine)
+			if f.EndLine > f.StartLine {
+				link += fmt.Sprintf("-L%d", f.EndLine)
+			}
+		}
+		return link
+	}
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// log is the subset of a SARIF 2.1.0 log used by the lens.
+type log struct {
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type run struct {
+	Tool struct {
+		Driver struct {
+			Name  string `json:"name"`
+			Rules []rule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	// OriginalURIBaseIDs maps the base IDs that relative locations may refer to to their URIs.
+	OriginalURIBaseIDs map[string]struct {
+		URI string `json:"uri"`
+	} `json:"originalUriBaseIds"`
+	Results []result `json:"results"`
+}
+
+type rule struct {
+	ID                   string  `json:"id"`
+	Name                 string  `json:"name"`
+	ShortDescription     message `json:"shortDescription"`
+	HelpURI              string  `json:"helpUri"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type result struct {
+	RuleID    string  `json:"ruleId"`
+	RuleIndex *int    `json:"ruleIndex"`
+	Level     string  `json:"level"`
+	Message   message `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI       string `json:"uri"`
+				URIBaseID string `json:"uriBaseId"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+				EndLine   int `json:"endLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	} `json:"locations"`
+}
+
+// Levels of findings, from most to least severe. Results with no level take the default level
+// of their rule, or warning if it has none.
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+	levelNote    = "note"
+	levelNone    = "none"
+)
+
+func severity(level string) int {
+	switch level {
+	case levelError:
+		return 0
+	case levelWarning:
+		return 1
+	case levelNote:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Finding is a single result reported by a tool.
+type Finding struct {
+	Tool    string
+	RuleID  string
+	Level   string
+	Message string
+	// File is the path of the file the finding is in, relative to the root of the repository
+	// if it could be determined. It is empty if the finding has no location.
+	File string
+	// Relative is whether File is relative to the root of the repository.
+	Relative  bool
+	StartLine int
+	EndLine   int
+}
+
+// Location returns the file and line of the finding.
+func (f Finding) Location() string {
+	if f.StartLine == 0 {
+		return f.File
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.StartLine)
+}
+
+// Rule describes a rule that was violated.
+type Rule struct {
+	Tool        string
+	ID          string
+	Description string
+	HelpURI     string
+}
+
+// Report is the findings of every tool in one or more SARIF logs.
+type Report struct {
+	Findings []Finding
+	Rules    map[string]Rule
+}
+
+func newReport() *Report {
+	return &Report{Rules: map[string]Rule{}}
+}
+
+func ruleKey(tool, id string) string {
+	return tool + "\x00" + id
+}
+
+// add reads a SARIF log. roots are the paths of checkouts of the repository, which are
+// removed from absolute locations.
+func (r *Report) add(content []byte, roots []string) error {
+	var l log
+	if err := json.Unmarshal(content, &l); err != nil {
+		return fmt.Errorf("failed to parse SARIF: %v", err)
+	}
+	for _, run := range l.Runs {
+		tool := run.Tool.Driver.Name
+		rules := run.Tool.Driver.Rules
+		for _, rule := range rules {
+			description := rule.ShortDescription.Text
+			if description == "" {
+				description = rule.Name
+			}
+			r.Rules[ruleKey(tool, rule.ID)] = Rule{Tool: tool, ID: rule.ID, Description: description, HelpURI: rule.HelpURI}
+		}
+		for _, res := range run.Results {
+			f := Finding{Tool: tool, RuleID: res.RuleID, Level: res.Level, Message: res.Message.Text}
+			if res.RuleIndex != nil && *res.RuleIndex >= 0 && *res.RuleIndex < len(rules) {
+				rule := rules[*res.RuleIndex]
+				if f.RuleID == "" {
+					f.RuleID = rule.ID
+				}
+				if f.Level == "" {
+					f.Level = rule.DefaultConfiguration.Level
+				}
+			} else if f.Level == "" {
+				for _, rule := range rules {
+					if rule.ID == f.RuleID {
+						f.Level = rule.DefaultConfiguration.Level
+						break
+					}
+				}
+			}
+			if f.Level == "" {
+				f.Level = levelWarning
+			}
+			if len(res.Locations) > 0 {
+				loc := res.Locations[0].PhysicalLocation
+				base := ""
+				if b, ok := run.OriginalURIBaseIDs[loc.ArtifactLocation.URIBaseID]; ok {
+					base = b.URI
+				}
+				f.File, f.Relative = relativePath(loc.ArtifactLocation.URI, loc.ArtifactLocation.URIBaseID, base, roots)
+				f.StartLine = loc.Region.StartLine
+				f.EndLine = loc.Region.EndLine
+			}
+			r.Findings = append(r.Findings, f)
+		}
+	}
+	return nil
+}
+
+// relativePath returns the path of a location relative to the root of the repository, and
+// whether it could be made relative. Relative URIs are taken to be relative to the root of
+// the repository unless their base is known to be elsewhere.
+func relativePath(uri, baseID, base string, roots []string) (string, bool) {
+	if u, err := url.PathUnescape(uri); err == nil {
+		uri = u
+	}
+	if !strings.HasPrefix(uri, "file:") && !strings.HasPrefix(uri, "/") {
+		if base == "" || baseID == "SRCROOT" {
+			return path.Clean(uri), true
+		}
+		uri = strings.TrimSuffix(base, "/") + "/" + uri
+	}
+	p := path.Clean("/" + strings.TrimPrefix(strings.TrimPrefix(uri, "file://"), "file:"))
+	for _, root := range roots {
+		root = path.Clean("/"+root) + "/"
+		if i := strings.Index(p+"/", root); i >= 0 && len(p) > i+len(root) {
+			return p[i+len(root):], true
+		}
+	}
+	return p, false
+}
+
+// Group is a set of findings that share a rule or a file.
+type Group struct {
+	Name string
+	// Rule is the rule that the findings violated, when grouping by rule.
+	Rule     *Rule
+	Level    string
+	Findings []Finding
+}
+
+// groupBy groups findings by the key returned for each, most severe groups first, then the
+// groups with the most findings.
+func groupBy(findings []Finding, key func(Finding) string) []*Group {
+	groups := map[string]*Group{}
+	var ordered []*Group
+	for _, f := range findings {
+		k := key(f)
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Name: k, Level: levelNone}
+			groups[k] = g
+			ordered = append(ordered, g)
+		}
+		if severity(f.Level) < severity(g.Level) {
+			g.Level = f.Level
+		}
+		g.Findings = append(g.Findings, f)
+	}
+	for _, g := range ordered {
+		sort.SliceStable(g.Findings, func(i, j int) bool {
+			a, b := g.Findings[i], g.Findings[j]
+			if severity(a.Level) != severity(b.Level) {
+				return severity(a.Level) < severity(b.Level)
+			}
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.StartLine < b.StartLine
+		})
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if severity(a.Level) != severity(b.Level) {
+			return severity(a.Level) < severity(b.Level)
+		}
+		if len(a.Findings) != len(b.Findings) {
+			return len(a.Findings) > len(b.Findings)
+		}
+		return a.Name < b.Name
+	})
+	return ordered
+}
+
+// byRule groups the findings by the rule they violated.
+func (r *Report) byRule() []*Group {
+	groups := groupBy(r.Findings, func(f Finding) string { return ruleKey(f.Tool, f.RuleID) })
+	for _, g := range groups {
+		f := g.Findings[0]
+		rule, ok := r.Rules[g.Name]
+		if !ok {
+			rule = Rule{Tool: f.Tool, ID: f.RuleID}
+		}
+		g.Rule = &rule
+		g.Name = f.RuleID
+		if g.Name == "" {
+			g.Name = "(no rule)"
+		}
+	}
+	return groups
+}
+
+// byFile groups the findings by the file they are in.
+func (r *Report) byFile() []*Group {
+	groups := groupBy(r.Findings, func(f Finding) string { return f.File })
+	for _, g := range groups {
+		if g.Name == "" {
+			g.Name = "(no location)"
+		}
+	}
+	return groups
+}
+
+// Count is the number of findings at a level.
+type Count struct {
+	Level string
+	Count int
+}
+
+// counts returns the number of findings at each level that has any, most severe first.
+func (r *Report) counts() []Count {
+	var counts []Count
+	for _, level := range []string{levelError, levelWarning, levelNote, levelNone} {
+		c := Count{Level: level}
+		for _, f := range r.Findings {
+			if f.Level == level || (level == levelNone && severity(f.Level) == severity(levelNone)) {
+				c.Count++
+			}
+		}
+		if c.Count > 0 {
+			counts = append(counts, c)
+		}
+	}
+	return counts
+}
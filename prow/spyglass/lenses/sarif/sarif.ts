@@ -0,0 +1,20 @@
+function groupBy(byFile: boolean): void {
+  document.getElementById('sarif-rules')!.classList.toggle('hidden', byFile);
+  document.getElementById('sarif-files')!.classList.toggle('hidden', !byFile);
+  document.getElementById('sarif-by-rule')!.classList.toggle('sarif-active', !byFile);
+  document.getElementById('sarif-by-file')!.classList.toggle('sarif-active', byFile);
+  spyglass.contentUpdated();
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  const byRule = document.getElementById('sarif-by-rule');
+  if (byRule) {
+    byRule.addEventListener('click', () => groupBy(false));
+  }
+  const byFile = document.getElementById('sarif-by-file');
+  if (byFile) {
+    byFile.addEventListener('click', () => groupBy(true));
+  }
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+});
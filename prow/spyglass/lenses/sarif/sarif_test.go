@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sarif
+
+import (
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+const testLog = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "golangci-lint", "rules": [
+      {"id": "errcheck", "shortDescription": {"text": "Unchecked errors"}, "defaultConfiguration": {"level": "error"}},
+      {"id": "golint", "helpUri": "https://github.com/golang/lint"}
+    ]}},
+    "originalUriBaseIds": {"GOPATH": {"uri": "file:///go/src/"}},
+    "results": [
+      {"ruleIndex": 0, "message": {"text": "Error return value is not checked"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///home/prow/go/src/k8s.io/test-infra/prow/cmd/hook/main.go"}, "region": {"startLine": 42}}}]},
+      {"ruleId": "golint", "message": {"text": "exported function should have comment"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "prow/cmd/hook/main.go"}, "region": {"startLine": 10, "endLine": 12}}}]},
+      {"ruleId": "golint", "level": "note", "message": {"text": "package comment should be of the form"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "k8s.io/test-infra/prow/git/git.go", "uriBaseId": "GOPATH"}, "region": {"startLine": 1}}}]},
+      {"ruleId": "golint", "message": {"text": "outside the repository"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "file:///usr/local/go/src/fmt/print.go"}}}]}
+    ]
+  }]
+}`
+
+func TestReport(t *testing.T) {
+	r := newReport()
+	if err := r.add([]byte(testLog), []string{"k8s.io/test-infra"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Findings) != 4 {
+		t.Fatalf("expected 4 findings, got %d", len(r.Findings))
+	}
+	expected := []struct {
+		rule, level, file string
+		relative          bool
+	}{
+		{rule: "errcheck", level: "error", file: "prow/cmd/hook/main.go", relative: true},
+		{rule: "golint", level: "warning", file: "prow/cmd/hook/main.go", relative: true},
+		{rule: "golint", level: "note", file: "prow/git/git.go", relative: true},
+		{rule: "golint", level: "warning", file: "/usr/local/go/src/fmt/print.go"},
+	}
+	for i, e := range expected {
+		f := r.Findings[i]
+		if f.RuleID != e.rule || f.Level != e.level || f.File != e.file || f.Relative != e.relative {
+			t.Errorf("finding %d: expected %s %s in %s (relative: %t), got %s %s in %s (relative: %t)",
+				i, e.level, e.rule, e.file, e.relative, f.Level, f.RuleID, f.File, f.Relative)
+		}
+	}
+
+	byRule := r.byRule()
+	if len(byRule) != 2 || byRule[0].Name != "errcheck" || byRule[1].Name != "golint" || len(byRule[1].Findings) != 3 {
+		t.Fatalf("expected errcheck then golint, got %+v", byRule)
+	}
+	if byRule[0].Rule.Description != "Unchecked errors" || byRule[1].Rule.HelpURI != "https://github.com/golang/lint" {
+		t.Errorf("expected rule metadata, got %+v and %+v", byRule[0].Rule, byRule[1].Rule)
+	}
+	byFile := r.byFile()
+	if len(byFile) != 3 || byFile[0].Name != "prow/cmd/hook/main.go" || byFile[0].Level != "error" || len(byFile[0].Findings) != 2 {
+		t.Errorf("expected main.go first with an error, got %+v", byFile)
+	}
+	counts := r.counts()
+	if len(counts) != 3 || counts[0] != (Count{Level: "error", Count: 1}) || counts[1] != (Count{Level: "warning", Count: 2}) {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
+
+func TestSourceLinker(t *testing.T) {
+	refs := &prowapi.Refs{
+		Org:     "kubernetes",
+		Repo:    "test-infra",
+		BaseRef: "master",
+		BaseSHA: "base",
+		Pulls:   []prowapi.Pull{{Number: 1, SHA: "head"}},
+	}
+	link := sourceLinker(refs)
+	testCases := []struct {
+		name     string
+		finding  Finding
+		expected string
+	}{
+		{
+			name:     "single line",
+			finding:  Finding{File: "prow/cmd/hook/main.go", Relative: true, StartLine: 42},
+			expected: "https://github.com/kubernetes/test-infra/blob/head/prow/cmd/hook/main.go#L42",
+		},
+		{
+			name:     "range",
+			finding:  Finding{File: "prow/cmd/hook/main.go", Relative: true, StartLine: 10, EndLine: 12},
+			expected: "https://github.com/kubernetes/test-infra/blob/head/prow/cmd/hook/main.go#L10-L12",
+		},
+		{
+			name:    "outside the repository",
+			finding: Finding{File: "/usr/local/go/src/fmt/print.go", StartLine: 1},
+		},
+	}
+	for _, tc := range testCases {
+		if actual := link(tc.finding); actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, actual)
+		}
+	}
+
+	if actual := sourceLinker(nil)(testCases[0].finding); actual != "" {
+		t.Errorf("expected no link without refs, got %q", actual)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+// SiblingArtifact is an artifact of the run being viewed that the lens did not match.
+// Spyglass supplies these alongside the usual artifacts to lenses whose config sets Siblings.
+type SiblingArtifact struct {
+	Artifact
+}
+
+// NewSiblingArtifact marks an artifact as a sibling of the artifacts a lens matched.
+func NewSiblingArtifact(a Artifact) *SiblingArtifact {
+	return &SiblingArtifact{Artifact: a}
+}
+
+// SplitSiblings separates the artifacts a lens matched from their siblings.
+func SplitSiblings(artifacts []Artifact) (matched []Artifact, siblings []*SiblingArtifact) {
+	for _, a := range artifacts {
+		if s, ok := a.(*SiblingArtifact); ok {
+			siblings = append(siblings, s)
+		} else {
+			matched = append(matched, a)
+		}
+	}
+	return matched, siblings
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sonobuoy
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// maxFileBytes is how much of each file in a bundle is read.
+	maxFileBytes = 50 << 20
+	// maxMessageBytes is how much of each failure message is shown.
+	maxMessageBytes = 4 << 10
+)
+
+// Statuses of tests and plugins, as reported by Sonobuoy.
+const (
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// Failure is a test that failed.
+type Failure struct {
+	Name string
+	// Location is the file or node the test ran in.
+	Location string
+	Message  string
+}
+
+// Plugin is the results of a Sonobuoy plugin, such as e2e or systemd-logs.
+type Plugin struct {
+	Name string
+	// Status is the plugin's overall status.
+	Status                  string
+	Passed, Failed, Skipped int
+	Failures                []Failure
+	// Errors are those the plugin reported instead of results, such as timing out.
+	Errors []string
+	// JUnitFiles are the junit files found in the plugin's results.
+	JUnitFiles []string
+
+	// Results from the plugin's sonobuoy_results.yaml are only used if it has no junit files,
+	// since they describe the same tests.
+	resultsPassed, resultsFailed, resultsSkipped int
+	resultsFailures                              []Failure
+	resultsStatus                                string
+}
+
+// Bundle is the contents of a Sonobuoy results tarball.
+type Bundle struct {
+	UUID              string
+	SonobuoyVersion   string
+	KubernetesVersion string
+	Plugins           []*Plugin
+
+	plugins map[string]*Plugin
+}
+
+func (b *Bundle) plugin(name string) *Plugin {
+	if p, ok := b.plugins[name]; ok {
+		return p
+	}
+	p := &Plugin{Name: name}
+	b.plugins[name] = p
+	b.Plugins = append(b.Plugins, p)
+	return p
+}
+
+// resultItem is an item of a sonobuoy_results.yaml, the tree of results Sonobuoy writes for
+// each plugin. Leaves are tests or files.
+type resultItem struct {
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"`
+	Details map[string]interface{} `json:"details"`
+	Items   []resultItem           `json:"items"`
+}
+
+// parseBundle reads a Sonobuoy results tarball, which may be gzipped. The results read before
+// any error are returned with it, since a truncated tarball still has useful results.
+func parseBundle(r io.Reader) (*Bundle, error) {
+	b := &Bundle{plugins: map[string]*Plugin{}}
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return b, fmt.Errorf("failed to decompress tarball: %v", err)
+		}
+		r = gz
+	} else {
+		r = buffered
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.finish()
+			return b, fmt.Errorf("failed to read tarball: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := b.add(strings.TrimPrefix(path.Clean(header.Name), "./"), tr); err != nil {
+			b.finish()
+			return b, fmt.Errorf("failed to read %s: %v", header.Name, err)
+		}
+	}
+	b.finish()
+	return b, nil
+}
+
+// add reads a file of the tarball, if it is one the lens uses.
+func (b *Bundle) add(name string, r io.Reader) error {
+	parts := strings.Split(name, "/")
+	var read func(content []byte) error
+	switch {
+	case name == "meta/config.json":
+		read = func(content []byte) error {
+			var config struct {
+				UUID    string `json:"UUID"`
+				Version string `json:"Version"`
+			}
+			err := json.Unmarshal(content, &config)
+			b.UUID, b.SonobuoyVersion = config.UUID, config.Version
+			return err
+		}
+	case name == "serverversion.json":
+		read = func(content []byte) error {
+			var version struct {
+				GitVersion string `json:"gitVersion"`
+			}
+			err := json.Unmarshal(content, &version)
+			b.KubernetesVersion = version.GitVersion
+			return err
+		}
+	case len(parts) == 3 && parts[0] == "plugins" && parts[2] == "sonobuoy_results.yaml":
+		read = func(content []byte) error {
+			var item resultItem
+			if err := yaml.Unmarshal(content, &item); err != nil {
+				return err
+			}
+			p := b.plugin(parts[1])
+			p.resultsStatus = item.Status
+			for _, child := range item.Items {
+				p.addResults(child, nil)
+			}
+			return nil
+		}
+	case len(parts) > 3 && parts[0] == "plugins" && parts[2] == "errors" && path.Ext(name) == ".json":
+		read = func(content []byte) error {
+			var errs map[string]interface{}
+			if err := json.Unmarshal(content, &errs); err != nil {
+				return err
+			}
+			p := b.plugin(parts[1])
+			if msg, ok := errs["error"]; ok {
+				p.Errors = append(p.Errors, fmt.Sprintf("%s: %v", strings.Join(parts[3:len(parts)-1], "/"), msg))
+			}
+			return nil
+		}
+	case len(parts) > 3 && parts[0] == "plugins" && parts[2] == "results" && path.Ext(name) == ".xml":
+		read = func(content []byte) error {
+			suites, err := junit.Parse(content)
+			if err != nil {
+				return err
+			}
+			p := b.plugin(parts[1])
+			file := strings.Join(parts[3:], "/")
+			p.JUnitFiles = append(p.JUnitFiles, file)
+			p.addJUnit(file, suites)
+			return nil
+		}
+	default:
+		return nil
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(r, maxFileBytes))
+	if err != nil {
+		return err
+	}
+	return read(content)
+}
+
+// addResults counts the tests at the leaves of a results tree below its root.
+func (p *Plugin) addResults(item resultItem, parents []string) {
+	if len(item.Items) != 0 {
+		parents = append(parents, item.Name)
+		for _, child := range item.Items {
+			p.addResults(child, parents)
+		}
+		return
+	}
+	switch item.Status {
+	case statusPassed:
+		p.resultsPassed++
+	case statusSkipped:
+		p.resultsSkipped++
+	case statusFailed:
+		p.resultsFailed++
+		message := ""
+		if failure, ok := item.Details["failure"]; ok {
+			message = fmt.Sprint(failure)
+		}
+		p.resultsFailures = append(p.resultsFailures, Failure{
+			Name:     item.Name,
+			Location: strings.Join(parents, "/"),
+			Message:  truncate(message),
+		})
+	}
+}
+
+// addJUnit counts the tests in a junit file.
+func (p *Plugin) addJUnit(file string, suites junit.Suites) {
+	for _, suite := range suites.Suites {
+		for _, result := range suite.Results {
+			switch {
+			case result.Failure != nil:
+				p.Failed++
+				p.Failures = append(p.Failures, Failure{Name: result.Name, Location: file, Message: result.Message(maxMessageBytes)})
+			case result.Skipped != nil:
+				p.Skipped++
+			default:
+				p.Passed++
+			}
+		}
+	}
+}
+
+// finish settles the status of each plugin and orders plugins with failures first.
+func (b *Bundle) finish() {
+	for _, p := range b.Plugins {
+		if len(p.JUnitFiles) == 0 {
+			p.Passed, p.Failed, p.Skipped, p.Failures = p.resultsPassed, p.resultsFailed, p.resultsSkipped, p.resultsFailures
+		}
+		switch {
+		case len(p.Errors) != 0 || p.Failed != 0:
+			p.Status = statusFailed
+		case p.resultsStatus != "":
+			p.Status = p.resultsStatus
+		case p.Passed != 0:
+			p.Status = statusPassed
+		default:
+			p.Status = "unknown"
+		}
+	}
+	sort.SliceStable(b.Plugins, func(i, j int) bool {
+		if fi, fj := b.Plugins[i].Status == statusFailed, b.Plugins[j].Status == statusFailed; fi != fj {
+			return fi
+		}
+		return b.Plugins[i].Name < b.Plugins[j].Name
+	})
+}
+
+func truncate(message string) string {
+	if len(message) <= maxMessageBytes {
+		return message
+	}
+	return message[:maxMessageBytes] + "..."
+}
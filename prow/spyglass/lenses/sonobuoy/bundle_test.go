@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sonobuoy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tarball: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseBundle(t *testing.T) {
+	content := tarball(t, map[string]string{
+		"meta/config.json":   `{"UUID": "abc-123", "Version": "v0.16.2"}`,
+		"serverversion.json": `{"gitVersion": "v1.16.0"}`,
+		"plugins/e2e/results/global/junit_01.xml": `<testsuite tests="3">
+  <testcase name="[sig-node] Pods should run" classname="Kubernetes e2e suite"/>
+  <testcase name="[sig-network] DNS should resolve" classname="Kubernetes e2e suite"><failure>timed out</failure></testcase>
+  <testcase name="[sig-storage] CSI" classname="Kubernetes e2e suite"><skipped/></testcase>
+</testsuite>`,
+		// The e2e results are also in its sonobuoy_results.yaml, but junit takes precedence.
+		"plugins/e2e/sonobuoy_results.yaml": `name: e2e
+status: failed
+items:
+- name: junit_01.xml
+  status: failed
+  items:
+  - name: "[sig-network] DNS should resolve"
+    status: failed
+`,
+		"plugins/systemd-logs/sonobuoy_results.yaml": `name: systemd-logs
+status: failed
+items:
+- name: node-1
+  status: passed
+  items:
+  - name: systemd_logs
+    status: passed
+- name: node-2
+  status: failed
+  items:
+  - name: systemd_logs
+    status: failed
+    details:
+      failure: no logs collected
+`,
+		"plugins/custom/errors/global/error.json": `{"error": "Plugin timed out while waiting for results"}`,
+		"plugins/custom/definition.json":          `{}`,
+		"resources/cluster/Nodes.json":            `[]`,
+	})
+	b, err := parseBundle(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.UUID != "abc-123" || b.SonobuoyVersion != "v0.16.2" || b.KubernetesVersion != "v1.16.0" {
+		t.Errorf("unexpected metadata: %q, %q, %q", b.UUID, b.SonobuoyVersion, b.KubernetesVersion)
+	}
+	if len(b.Plugins) != 3 || b.Plugins[0].Name != "custom" || b.Plugins[1].Name != "e2e" || b.Plugins[2].Name != "systemd-logs" {
+		t.Fatalf("expected custom, e2e and systemd-logs plugins, got %v", b.Plugins)
+	}
+	custom, e2e, logs := b.Plugins[0], b.Plugins[1], b.Plugins[2]
+	if custom.Status != statusFailed || len(custom.Errors) != 1 || custom.Errors[0] != "global: Plugin timed out while waiting for results" {
+		t.Errorf("expected custom to fail with its error, got %s and %v", custom.Status, custom.Errors)
+	}
+	if e2e.Status != statusFailed || e2e.Passed != 1 || e2e.Failed != 1 || e2e.Skipped != 1 {
+		t.Errorf("expected e2e to fail with 1 passed, failed and skipped, got %s with %d, %d and %d", e2e.Status, e2e.Passed, e2e.Failed, e2e.Skipped)
+	}
+	if len(e2e.Failures) != 1 || e2e.Failures[0] != (Failure{Name: "[sig-network] DNS should resolve", Location: "global/junit_01.xml", Message: "timed out"}) {
+		t.Errorf("unexpected e2e failures: %v", e2e.Failures)
+	}
+	if logs.Passed != 1 || logs.Failed != 1 || len(logs.Failures) != 1 || logs.Failures[0] != (Failure{Name: "systemd_logs", Location: "node-2", Message: "no logs collected"}) {
+		t.Errorf("unexpected systemd-logs results: %#v", logs)
+	}
+}
+
+func TestParseTruncatedBundle(t *testing.T) {
+	content := tarball(t, map[string]string{"serverversion.json": `{"gitVersion": "v1.16.0"}`})
+	b, err := parseBundle(bytes.NewReader(content[:len(content)/2]))
+	if err == nil {
+		t.Error("expected an error for a truncated tarball")
+	}
+	if b == nil {
+		t.Fatal("expected partial results")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sonobuoy provides a Spyglass lens that summarizes Sonobuoy results tarballs, such as
+// those of conformance runs.
+package sonobuoy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "sonobuoy"
+	title    = "Conformance Results"
+	priority = 35
+
+	// defaultMaxBytes is how much of each tarball is read if not configured.
+	defaultMaxBytes = 500 << 20
+)
+
+// Lens is the implementation of a Sonobuoy results-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each tarball is read. Defaults to 500MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// BundleView is a tarball and its results.
+type BundleView struct {
+	*Bundle
+	Name  string
+	Link  string
+	Error string
+	// Passed is whether every plugin passed.
+	Passed bool
+}
+
+type sonobuoyView struct {
+	Errors  []string
+	Bundles []BundleView
+}
+
+// Body renders the plugins of each tarball and the tests that failed.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := sonobuoyView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		bv := BundleView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			bv.Bundle, err = parseBundle(r)
+		}
+		if err != nil {
+			bv.Error = err.Error()
+		}
+		if bv.Bundle != nil {
+			bv.Passed = bv.Error == "" && len(bv.Plugins) != 0
+			for _, p := range bv.Plugins {
+				if p.Status == statusFailed {
+					bv.Passed = false
+				}
+			}
+		}
+		view.Bundles = append(view.Bundles, bv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
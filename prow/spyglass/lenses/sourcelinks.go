@@ -0,0 +1,181 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/clone"
+)
+
+// sourceRefRE matches references to lines of source files, such as "pkg/foo/bar.go:12" or
+// "/go/src/k8s.io/kubernetes/test/e2e/e2e.go:123:4". Only files with a directory and a
+// well-known extension are matched, so that hosts and ports are not mistaken for files.
+var sourceRefRE = regexp.MustCompile(`(?:^|[\s("'=\[])((?:/|\./)?(?:[\w.@+-]+/)+[\w.@+-]+\.(?:go|py|sh|bash|js|ts|java|kt|scala|rs|c|cc|cpp|h|hpp|rb|bzl|bazel|proto|tf|yaml|yml|json)):(\d+)(?::\d+)?`)
+
+// SourceSegment is a piece of text, linked to the source it refers to if it refers to any.
+type SourceSegment struct {
+	Text string
+	Link string
+}
+
+// sourceRepo is a repository a job checked out.
+type sourceRepo struct {
+	// prefixes are the paths the repository is checked out at below a GOPATH, such as
+	// "github.com/org/repo" or a path alias.
+	prefixes []string
+	// blob is the link to the repository's files at the tested commit, ending in "/blob/<sha>".
+	blob string
+}
+
+// SourceLinker links references to lines of source files in job output to those lines at the
+// commit the job tested. Relative paths are taken to be in the job's main repository; absolute
+// paths are matched to a repository by where it is checked out below a GOPATH.
+type SourceLinker struct {
+	repos []sourceRepo
+}
+
+// NewSourceLinker returns a linker for the repositories a job checked out, main repository
+// first. Pull requests are linked at the head of the pull request, as that is what the author
+// will change.
+func NewSourceLinker(refs []prowapi.Refs) *SourceLinker {
+	l := &SourceLinker{}
+	for _, r := range refs {
+		repoLink := r.RepoLink
+		if repoLink == "" {
+			repoLink = fmt.Sprintf("https://github.com/%s/%s", r.Org, r.Repo)
+		}
+		commit := r.BaseSHA
+		if len(r.Pulls) == 1 && r.Pulls[0].SHA != "" {
+			commit = r.Pulls[0].SHA
+		}
+		if commit == "" {
+			commit = r.BaseRef
+		}
+		if commit == "" {
+			continue
+		}
+		repo := sourceRepo{blob: fmt.Sprintf("%s/blob/%s", strings.TrimSuffix(repoLink, "/"), commit)}
+		if r.PathAlias != "" {
+			repo.prefixes = append(repo.prefixes, strings.Trim(r.PathAlias, "/"))
+		}
+		repo.prefixes = append(repo.prefixes, fmt.Sprintf("github.com/%s/%s", r.Org, r.Repo))
+		if host := strings.TrimPrefix(strings.TrimPrefix(repoLink, "https://"), "http://"); host != repoLink {
+			repo.prefixes = append(repo.prefixes, strings.TrimSuffix(host, "/"))
+		}
+		l.repos = append(l.repos, repo)
+	}
+	return l
+}
+
+// ReadSourceLinker returns a linker for the repositories the job checked out, read from its
+// prowjob.json or, failing that, its clone records. The linker links nothing if neither was
+// found among the artifacts.
+func ReadSourceLinker(artifacts []Artifact) *SourceLinker {
+	var records []clone.Record
+	for _, a := range artifacts {
+		switch a.JobPath() {
+		case "prowjob.json":
+			content, err := a.ReadAll()
+			if err != nil {
+				continue
+			}
+			var pj prowapi.ProwJob
+			if err := json.Unmarshal(content, &pj); err != nil {
+				continue
+			}
+			var refs []prowapi.Refs
+			if pj.Spec.Refs != nil {
+				refs = append(refs, *pj.Spec.Refs)
+			}
+			if refs = append(refs, pj.Spec.ExtraRefs...); len(refs) != 0 {
+				return NewSourceLinker(refs)
+			}
+		case "clone-records.json":
+			if content, err := a.ReadAll(); err == nil {
+				json.Unmarshal(content, &records)
+			}
+		}
+	}
+	var refs []prowapi.Refs
+	for _, r := range records {
+		refs = append(refs, r.Refs)
+	}
+	return NewSourceLinker(refs)
+}
+
+// Link returns the link to a line of a file, or "" if the file is not in a known repository.
+func (l *SourceLinker) Link(file string, line int) string {
+	if l == nil || len(l.repos) == 0 {
+		return ""
+	}
+	var blob, rel string
+	best := -1
+	for _, repo := range l.repos {
+		for _, prefix := range repo.prefixes {
+			i := strings.LastIndex(file, "/"+prefix+"/")
+			if i >= 0 {
+				i++
+			} else if strings.HasPrefix(file, prefix+"/") {
+				i = 0
+			} else {
+				continue
+			}
+			if i > best {
+				best, blob, rel = i, repo.blob, file[i+len(prefix)+1:]
+			}
+		}
+	}
+	if best < 0 {
+		if strings.HasPrefix(file, "/") || strings.HasPrefix(file, "../") {
+			return ""
+		}
+		blob, rel = l.repos[0].blob, strings.TrimPrefix(file, "./")
+	}
+	return fmt.Sprintf("%s/%s#L%d", blob, rel, line)
+}
+
+// Split splits text into the references to source it contains, linked, and the text between
+// them, unlinked.
+func (l *SourceLinker) Split(text string) []SourceSegment {
+	if l == nil || len(l.repos) == 0 {
+		return []SourceSegment{{Text: text}}
+	}
+	var segments []SourceSegment
+	last := 0
+	for _, m := range sourceRefRE.FindAllStringSubmatchIndex(text, -1) {
+		line, _ := strconv.Atoi(text[m[4]:m[5]])
+		link := l.Link(text[m[2]:m[3]], line)
+		if link == "" {
+			continue
+		}
+		if m[2] > last {
+			segments = append(segments, SourceSegment{Text: text[last:m[2]]})
+		}
+		segments = append(segments, SourceSegment{Text: text[m[2]:m[1]], Link: link})
+		last = m[1]
+	}
+	if last < len(text) || len(segments) == 0 {
+		segments = append(segments, SourceSegment{Text: text[last:]})
+	}
+	return segments
+}
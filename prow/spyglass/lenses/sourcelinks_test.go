@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lenses
+
+import (
+	"reflect"
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestSourceLinker(t *testing.T) {
+	l := NewSourceLinker([]prowapi.Refs{
+		{
+			Org:       "kubernetes",
+			Repo:      "test-infra",
+			BaseSHA:   "base",
+			PathAlias: "k8s.io/test-infra",
+			Pulls:     []prowapi.Pull{{Number: 1, SHA: "head"}},
+		},
+		{Org: "kubernetes", Repo: "kubernetes", BaseRef: "master", PathAlias: "k8s.io/kubernetes"},
+	})
+	testCases := []struct {
+		file     string
+		expected string
+	}{
+		{"prow/cmd/deck/main.go", "https://github.com/kubernetes/test-infra/blob/head/prow/cmd/deck/main.go#L12"},
+		{"./prow/cmd/deck/main.go", "https://github.com/kubernetes/test-infra/blob/head/prow/cmd/deck/main.go#L12"},
+		{"/home/prow/go/src/k8s.io/test-infra/prow/hook/server.go", "https://github.com/kubernetes/test-infra/blob/head/prow/hook/server.go#L12"},
+		{"/go/src/github.com/kubernetes/kubernetes/test/e2e/e2e.go", "https://github.com/kubernetes/kubernetes/blob/master/test/e2e/e2e.go#L12"},
+		{"k8s.io/kubernetes/test/e2e/e2e.go", "https://github.com/kubernetes/kubernetes/blob/master/test/e2e/e2e.go#L12"},
+		{"/usr/local/go/src/runtime/panic.go", ""},
+		{"../other/file.go", ""},
+	}
+	for _, tc := range testCases {
+		if actual := l.Link(tc.file, 12); actual != tc.expected {
+			t.Errorf("expected %s to link to %q, got %q", tc.file, tc.expected, actual)
+		}
+	}
+}
+
+func TestSourceLinkerSplit(t *testing.T) {
+	l := NewSourceLinker([]prowapi.Refs{{Org: "org", Repo: "repo", BaseSHA: "abc"}})
+	text := "panic at (pkg/a/b.go:3:5) and /usr/lib/go/x/y.go:4, see http://host.example.com:80/c.go:9"
+	expected := []SourceSegment{
+		{Text: "panic at ("},
+		{Text: "pkg/a/b.go:3:5", Link: "https://github.com/org/repo/blob/abc/pkg/a/b.go#L3"},
+		{Text: ") and /usr/lib/go/x/y.go:4, see http://host.example.com:80/c.go:9"},
+	}
+	if actual := l.Split(text); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if actual := NewSourceLinker(nil).Split("pkg/a/b.go:3"); !reflect.DeepEqual(actual, []SourceSegment{{Text: "pkg/a/b.go:3"}}) {
+		t.Errorf("expected nothing to be linked without refs, got %+v", actual)
+	}
+}
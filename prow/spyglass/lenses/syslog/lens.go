@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package syslog provides a Spyglass lens that shows system logs, such as those exported from
+// journald, filtered by severity, unit and time on the server so that large logs can be browsed.
+package syslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "syslog"
+	title    = "System Logs"
+	priority = 37
+
+	// defaultMaxBytes is how much of each log is read if not configured.
+	defaultMaxBytes = 500 << 20
+	// pageSize is the number of entries rendered at once.
+	pageSize = 1000
+)
+
+// Lens is the implementation of a system log-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each log is read. Defaults to 500MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+func parseConfig(rawConfig json.RawMessage) (config, error) {
+	conf := config{MaxBytes: defaultMaxBytes}
+	var err error
+	if len(rawConfig) != 0 {
+		err = json.Unmarshal(rawConfig, &conf)
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+	return conf, err
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Page is a page of the entries matching a filter.
+type Page struct {
+	Entries []Entry
+	// Next is the line to continue from if there are more entries.
+	Next int
+}
+
+// SeverityCount is the number of entries of a severity.
+type SeverityCount struct {
+	Priority int
+	Name     string
+	Count    int
+}
+
+// LogView is a log with the controls to filter it.
+type LogView struct {
+	Name       string
+	Link       string
+	Error      string
+	Summary    Summary
+	Severities []SeverityCount
+	From, To   string
+	Page       Page
+}
+
+type syslogView struct {
+	Errors []string
+	Logs   []LogView
+}
+
+// Body summarizes each log and renders its first entries.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := syslogView{}
+	conf, err := parseConfig(rawConfig)
+	if err != nil {
+		view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		lv := LogView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		s := newSummarizer()
+		var page Page
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			err = scanLog(r, 0, func(e Entry) bool {
+				s.add(e)
+				if len(page.Entries) < pageSize {
+					page.Entries = append(page.Entries, e)
+				} else if page.Next == 0 {
+					page.Next = e.Line
+				}
+				return true
+			})
+		}
+		if err != nil {
+			lv.Error = fmt.Sprintf("Failed to read log: %v", err)
+		}
+		lv.Summary = s.finish()
+		for p := len(severities) - 1; p >= 0; p-- {
+			if count := lv.Summary.Priorities[p]; count != 0 {
+				lv.Severities = append(lv.Severities, SeverityCount{Priority: p, Name: severities[p], Count: count})
+			}
+		}
+		if !lv.Summary.First.IsZero() {
+			lv.From = lv.Summary.First.Format(rangeLayout)
+			lv.To = lv.Summary.Last.Format(rangeLayout)
+		}
+		lv.Page = page
+		view.Logs = append(view.Logs, lv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// request is a request for a page of entries matching a filter.
+type request struct {
+	Artifact string `json:"artifact"`
+	// MaxPriority is the least severe priority to show.
+	MaxPriority int      `json:"maxPriority"`
+	Units       []string `json:"units"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	// Offset is the line to start from.
+	Offset int `json:"offset"`
+}
+
+// filter returns the filter the request selects.
+func (req request) filter() (filter, error) {
+	f := filter{MaxPriority: req.MaxPriority, Units: map[string]bool{}}
+	for _, unit := range req.Units {
+		f.Units[unit] = true
+	}
+	var err error
+	if f.From, err = parseRange(req.From); err != nil {
+		return f, fmt.Errorf("invalid start time: %v", err)
+	}
+	if f.To, err = parseRange(req.To); err != nil {
+		return f, fmt.Errorf("invalid end time: %v", err)
+	}
+	if !f.To.IsZero() {
+		// The range includes the whole of its last second.
+		f.To = f.To.Add(time.Second - time.Nanosecond)
+	}
+	return f, nil
+}
+
+// Callback renders the page of entries matching the requested filter that starts at the
+// requested line.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	var req request
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		return executeTemplate(resourceDir, "error", fmt.Sprintf("Failed to parse request: %v", err))
+	}
+	f, err := req.filter()
+	if err != nil {
+		return executeTemplate(resourceDir, "error", err.Error())
+	}
+	conf, _ := parseConfig(rawConfig)
+	for _, a := range artifacts {
+		if a.JobPath() != req.Artifact {
+			continue
+		}
+		var page Page
+		r, err := lenses.NewChunkedReader(a, conf.MaxBytes)
+		if err == nil {
+			err = scanLog(r, req.Offset, func(e Entry) bool {
+				if !f.match(e) {
+					return true
+				}
+				if len(page.Entries) == pageSize {
+					page.Next = e.Line
+					return false
+				}
+				page.Entries = append(page.Entries, e)
+				return true
+			})
+		}
+		if err != nil {
+			return executeTemplate(resourceDir, "error", fmt.Sprintf("Failed to read log: %v", err))
+		}
+		return executeTemplate(resourceDir, "entries", page)
+	}
+	return executeTemplate(resourceDir, "error", fmt.Sprintf("No artifact named %q.", req.Artifact))
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// severities are the names of syslog severities, indexed by their priority.
+var severities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Priorities of the severities the lens infers from messages.
+const (
+	priorityCrit    = 2
+	priorityErr     = 3
+	priorityWarning = 4
+	priorityInfo    = 6
+	priorityDebug   = 7
+)
+
+var (
+	// shortRE matches a line in the format of syslog and `journalctl -o short`, such as
+	// "Oct 01 12:00:00.123456 node-1 kubelet[1234]: message".
+	shortRE = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d(?:\.\d+)?) (\S+) ([^\s\[:]+)(?:\[\d+\])?: ?(.*)$`)
+	// isoRE matches a line in the format of `journalctl -o short-iso`, such as
+	// "2019-10-01T12:00:00+0000 node-1 kubelet[1234]: message".
+	isoRE = regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:?\d\d)) (\S+) ([^\s\[:]+)(?:\[\d+\])?: ?(.*)$`)
+	// klogRE matches the severity of a message logged by klog or glog, such as "E1001 12:00:00.000".
+	klogRE = regexp.MustCompile(`^([IWEF])\d{4} \d\d:\d\d:\d\d`)
+	// levelRE matches the severity of a message logged by logrus, such as `level=error`.
+	levelRE = regexp.MustCompile(`\blevel=(\w+)`)
+)
+
+// Layouts of the timestamps matched by shortRE and isoRE.
+var (
+	shortLayouts = []string{"Jan _2 15:04:05.999999999"}
+	isoLayouts   = []string{"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999-0700"}
+)
+
+// Entry is a message in a log.
+type Entry struct {
+	// Line is the line of the log the entry is on.
+	Line int
+	// Time is when the message was logged. The year is not known for logs in the syslog format.
+	Time     time.Time
+	Host     string
+	Unit     string
+	Priority int
+	Message  string
+}
+
+// Timestamp returns the entry's time as shown to the user.
+func (e Entry) Timestamp() string {
+	switch {
+	case e.Time.IsZero():
+		return ""
+	case e.Time.Year() == 0:
+		return e.Time.Format("Jan _2 15:04:05.000")
+	}
+	return e.Time.Format("2006-01-02 15:04:05.000")
+}
+
+// Severity returns the name of the entry's severity.
+func (e Entry) Severity() string {
+	return severities[e.Priority]
+}
+
+// journalEntry is an entry exported by `journalctl -o json`.
+type journalEntry struct {
+	RealtimeTimestamp string          `json:"__REALTIME_TIMESTAMP"`
+	Priority          string          `json:"PRIORITY"`
+	Unit              string          `json:"_SYSTEMD_UNIT"`
+	Identifier        string          `json:"SYSLOG_IDENTIFIER"`
+	Hostname          string          `json:"_HOSTNAME"`
+	Message           json.RawMessage `json:"MESSAGE"`
+}
+
+// message returns the entry's message, which journald exports as an array of bytes if it is not
+// valid UTF-8.
+func (j journalEntry) message() string {
+	var s string
+	if json.Unmarshal(j.Message, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(j.Message, &ints) == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return string(b)
+}
+
+// inferPriority returns the priority of a message from the severity its logger wrote in it.
+func inferPriority(message string) int {
+	if m := klogRE.FindStringSubmatch(message); m != nil {
+		switch m[1] {
+		case "W":
+			return priorityWarning
+		case "E":
+			return priorityErr
+		case "F":
+			return priorityCrit
+		}
+		return priorityInfo
+	}
+	if m := levelRE.FindStringSubmatch(message); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "debug", "trace":
+			return priorityDebug
+		case "warn", "warning":
+			return priorityWarning
+		case "error":
+			return priorityErr
+		case "fatal", "panic":
+			return priorityCrit
+		}
+	}
+	return priorityInfo
+}
+
+func parseTime(value string, layouts []string) time.Time {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// unitName drops the suffix of systemd service units, which is the same for almost every unit.
+func unitName(unit string) string {
+	return strings.TrimSuffix(unit, ".service")
+}
+
+// parser reads entries a line at a time.
+type parser struct {
+	last Entry
+}
+
+// parse returns the entry on a line. Lines that are not in a known format, such as the rest of a
+// multi-line message, are given the time, host, unit and severity of the entry before them.
+func (p *parser) parse(number int, text string) Entry {
+	e := Entry{Line: number, Message: text}
+	var j journalEntry
+	switch m := shortRE.FindStringSubmatch(text); {
+	case strings.HasPrefix(text, "{") && json.Unmarshal([]byte(text), &j) == nil && j.Message != nil:
+		e.Message = j.message()
+		e.Host = j.Hostname
+		e.Unit = unitName(j.Unit)
+		if e.Unit == "" {
+			e.Unit = j.Identifier
+		}
+		if us, err := strconv.ParseInt(j.RealtimeTimestamp, 10, 64); err == nil {
+			e.Time = time.Unix(0, us*int64(time.Microsecond)).UTC()
+		}
+		if priority, err := strconv.Atoi(j.Priority); err == nil && priority >= 0 && priority < len(severities) {
+			e.Priority = priority
+		} else {
+			e.Priority = inferPriority(e.Message)
+		}
+	case m != nil:
+		e.Time = parseTime(m[1], shortLayouts)
+		e.Host, e.Unit, e.Message = m[2], unitName(m[3]), m[4]
+		e.Priority = inferPriority(e.Message)
+	default:
+		if m := isoRE.FindStringSubmatch(text); m != nil {
+			e.Time = parseTime(m[1], isoLayouts).UTC()
+			e.Host, e.Unit, e.Message = m[2], unitName(m[3]), m[4]
+			e.Priority = inferPriority(e.Message)
+		} else if p.last.Line != 0 {
+			e.Time, e.Host, e.Unit, e.Priority = p.last.Time, p.last.Host, p.last.Unit, p.last.Priority
+		} else {
+			e.Priority = priorityInfo
+		}
+	}
+	p.last = e
+	return e
+}
+
+// scanLog calls fn with each entry of a log, starting at the given line, until fn returns false.
+func scanLog(r io.Reader, fromLine int, fn func(Entry) bool) error {
+	p := &parser{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		// Lines before the start are parsed anyway, so that continuation lines are attributed.
+		e := p.parse(number, text)
+		if number < fromLine {
+			continue
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// clock returns the month, day and time of t, which are all that logs in the syslog format
+// record.
+func clock(t time.Time) time.Time {
+	return time.Date(0, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// filter selects the entries to show.
+type filter struct {
+	// MaxPriority is the least severe priority shown.
+	MaxPriority int
+	// Units are the units shown, or all units if empty.
+	Units map[string]bool
+	// From and To are the range of clock times shown, if not zero. Entries without times are
+	// always shown.
+	From, To time.Time
+}
+
+func (f filter) match(e Entry) bool {
+	if e.Priority > f.MaxPriority {
+		return false
+	}
+	if len(f.Units) != 0 && !f.Units[e.Unit] {
+		return false
+	}
+	if !e.Time.IsZero() {
+		c := clock(e.Time)
+		if !f.From.IsZero() && c.Before(f.From) || !f.To.IsZero() && c.After(f.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeLayout is the layout of the times the user enters to select a time range.
+const rangeLayout = "Jan _2 15:04:05"
+
+// parseRange parses a time entered by the user. An empty time is zero.
+func parseRange(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(rangeLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return clock(t), nil
+}
+
+// UnitCount is the number of entries logged by a unit.
+type UnitCount struct {
+	Unit  string
+	Count int
+}
+
+// Summary is the units, severities and time range of a log.
+type Summary struct {
+	Entries int
+	Units   []UnitCount
+	// Priorities counts the entries of each priority.
+	Priorities []int
+	First      time.Time
+	Last       time.Time
+}
+
+// summarizer accumulates a Summary.
+type summarizer struct {
+	Summary
+	units map[string]int
+}
+
+func newSummarizer() *summarizer {
+	return &summarizer{Summary: Summary{Priorities: make([]int, len(severities))}, units: map[string]int{}}
+}
+
+func (s *summarizer) add(e Entry) {
+	s.Entries++
+	s.units[e.Unit]++
+	s.Priorities[e.Priority]++
+	if !e.Time.IsZero() {
+		if s.First.IsZero() {
+			s.First = e.Time
+		}
+		s.Last = e.Time
+	}
+}
+
+// finish returns the summary with units sorted by the number of entries, most first.
+func (s *summarizer) finish() Summary {
+	for unit, count := range s.units {
+		s.Units = append(s.Units, UnitCount{Unit: unit, Count: count})
+	}
+	sort.Slice(s.Units, func(i, j int) bool {
+		if s.Units[i].Count != s.Units[j].Count {
+			return s.Units[i].Count > s.Units[j].Count
+		}
+		return s.Units[i].Unit < s.Units[j].Unit
+	})
+	return s.Summary
+}
@@ -0,0 +1,75 @@
+interface Filter {
+  artifact: string;
+  maxPriority: number;
+  units: string[];
+  from: string;
+  to: string;
+}
+
+// The filter each log's entries were last fetched with, so that more entries can be fetched with it.
+const filters = new Map<HTMLElement, Filter>();
+
+function readFilter(log: HTMLElement): Filter {
+  const checkboxes = Array.from(log.querySelectorAll<HTMLInputElement>('.syslog-unit-checkbox'));
+  const checked = checkboxes.filter((c) => c.checked).map((c) => c.value);
+  return {
+    artifact: log.dataset.artifact!,
+    maxPriority: Number(log.querySelector<HTMLSelectElement>('.syslog-severity-select')!.value),
+    // No units means all of them.
+    units: checked.length === checkboxes.length ? [] : checked,
+    from: log.querySelector<HTMLInputElement>('.syslog-from')!.value,
+    to: log.querySelector<HTMLInputElement>('.syslog-to')!.value,
+  };
+}
+
+async function fetchEntries(filter: Filter, offset: number): Promise<string> {
+  return spyglass.request(JSON.stringify({
+    artifact: filter.artifact,
+    from: filter.from,
+    maxPriority: filter.maxPriority,
+    offset,
+    to: filter.to,
+    units: filter.units,
+  }));
+}
+
+async function applyFilter(log: HTMLElement): Promise<void> {
+  const filter = readFilter(log);
+  const entries = log.querySelector<HTMLElement>('.syslog-entries')!;
+  if (filter.units.length === 0 && log.querySelectorAll('.syslog-unit-checkbox:checked').length === 0) {
+    entries.innerHTML = '<div class="syslog-muted">No units are selected.</div>';
+    spyglass.contentUpdated();
+    return;
+  }
+  filters.set(log, filter);
+  entries.innerHTML = await fetchEntries(filter, 0);
+  bindMore(entries);
+  spyglass.contentUpdated();
+}
+
+async function showMore(button: HTMLButtonElement): Promise<void> {
+  button.disabled = true;
+  const log = button.closest<HTMLElement>('.syslog-log')!;
+  const filter = filters.get(log) || {artifact: log.dataset.artifact!, maxPriority: 7, units: [], from: '', to: ''};
+  const template = document.createElement('template');
+  template.innerHTML = await fetchEntries(filter, Number(button.dataset.offset));
+  bindMore(template.content);
+  button.parentElement!.replaceChild(template.content, button);
+  spyglass.contentUpdated();
+}
+
+function bindMore(root: ParentNode): void {
+  for (const button of Array.from(root.querySelectorAll<HTMLButtonElement>('button.syslog-more'))) {
+    button.addEventListener('click', () => showMore(button));
+  }
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  for (const log of Array.from(document.querySelectorAll<HTMLElement>('.syslog-log'))) {
+    log.querySelector<HTMLFormElement>('.syslog-filter')!.addEventListener('submit', (e) => {
+      e.preventDefault();
+      applyFilter(log);
+    });
+  }
+  bindMore(document);
+});
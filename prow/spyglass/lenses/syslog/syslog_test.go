@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syslog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `Oct 01 12:00:00.100000 node-1 kubelet[1234]: I1001 12:00:00.100000    1234 server.go:10] Started kubelet
+Oct 01 12:00:01.000000 node-1 containerd[99]: time="2019-10-01T12:00:01Z" level=warning msg="slow pull"
+Oct 01 12:00:02.000000 node-1 kubelet[1234]: E1001 12:00:02.000000    1234 pod_workers.go:20] Error syncing pod
+goroutine 1 [running]:
+2019-10-01T12:00:03+0000 node-1 systemd[1]: Started kubelet.service.
+{"__REALTIME_TIMESTAMP":"1569931204000000","PRIORITY":"2","_SYSTEMD_UNIT":"docker.service","_HOSTNAME":"node-1","MESSAGE":"crashed"}
+{"__REALTIME_TIMESTAMP":"1569931205000000","PRIORITY":"6","SYSLOG_IDENTIFIER":"kernel","MESSAGE":[104,105]}
+`
+
+func entries(t *testing.T, fromLine int) []Entry {
+	var result []Entry
+	if err := scanLog(strings.NewReader(testLog), fromLine, func(e Entry) bool {
+		result = append(result, e)
+		return true
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestScanLog(t *testing.T) {
+	es := entries(t, 0)
+	if len(es) != 7 {
+		t.Fatalf("expected 7 entries, got %d", len(es))
+	}
+	expected := []struct {
+		unit     string
+		priority int
+		message  string
+	}{
+		{"kubelet", priorityInfo, "I1001 12:00:00.100000    1234 server.go:10] Started kubelet"},
+		{"containerd", priorityWarning, `time="2019-10-01T12:00:01Z" level=warning msg="slow pull"`},
+		{"kubelet", priorityErr, "E1001 12:00:02.000000    1234 pod_workers.go:20] Error syncing pod"},
+		// Lines that are not entries continue the one before.
+		{"kubelet", priorityErr, "goroutine 1 [running]:"},
+		{"systemd", priorityInfo, "Started kubelet.service."},
+		{"docker", priorityCrit, "crashed"},
+		{"kernel", priorityInfo, "hi"},
+	}
+	for i, e := range expected {
+		if es[i].Line != i+1 || es[i].Unit != e.unit || es[i].Priority != e.priority || es[i].Message != e.message {
+			t.Errorf("entry %d: expected %s %d %q, got %#v", i, e.unit, e.priority, e.message, es[i])
+		}
+	}
+	if es[0].Timestamp() != "Oct  1 12:00:00.100" || es[3].Time != es[2].Time {
+		t.Errorf("unexpected times %q and %s", es[0].Timestamp(), es[3].Time)
+	}
+	if !es[4].Time.Equal(time.Date(2019, 10, 1, 12, 0, 3, 0, time.UTC)) || !es[5].Time.Equal(time.Date(2019, 10, 1, 12, 0, 4, 0, time.UTC)) {
+		t.Errorf("unexpected times %s and %s", es[4].Time, es[5].Time)
+	}
+
+	if later := entries(t, 4); len(later) != 4 || later[0].Unit != "kubelet" || later[0].Priority != priorityErr {
+		t.Errorf("expected a continuation line to keep its entry's unit when starting from it, got %v", later)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	es := entries(t, 0)
+	req := request{MaxPriority: priorityErr, Units: []string{"kubelet", "docker"}, From: "Oct  1 12:00:02", To: "Oct 1 12:00:04"}
+	f, err := req.filter()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var lines []int
+	for _, e := range es {
+		if f.match(e) {
+			lines = append(lines, e.Line)
+		}
+	}
+	if len(lines) != 3 || lines[0] != 3 || lines[1] != 4 || lines[2] != 6 {
+		t.Errorf("expected lines 3, 4 and 6 to match, got %v", lines)
+	}
+	if _, err := (request{From: "yesterday"}).filter(); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
+
+func TestSummarizer(t *testing.T) {
+	s := newSummarizer()
+	for _, e := range entries(t, 0) {
+		s.add(e)
+	}
+	summary := s.finish()
+	if summary.Entries != 7 || summary.Units[0] != (UnitCount{Unit: "kubelet", Count: 3}) {
+		t.Errorf("expected 7 entries with kubelet first, got %d and %v", summary.Entries, summary.Units)
+	}
+	if summary.Priorities[priorityErr] != 2 || summary.Priorities[priorityInfo] != 3 {
+		t.Errorf("unexpected priorities %v", summary.Priorities)
+	}
+}
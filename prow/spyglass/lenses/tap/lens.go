@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tap provides a Spyglass lens that renders Test Anything Protocol results.
+package tap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "tap"
+	title    = "TAP"
+	priority = 21
+)
+
+// Lens is the implementation of a TAP-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// TestResult is a test and the artifact it was reported in.
+type TestResult struct {
+	Test
+	// File is the artifact the test came from, if there are several.
+	File string
+	Link string
+}
+
+// Section is a group of tests with the same outcome.
+type Section struct {
+	Status Status
+	Title  string
+	Tests  []TestResult
+	// Open sections are expanded by default.
+	Open bool
+}
+
+type tapView struct {
+	NumTests int
+	Sections []Section
+	Problems []string
+}
+
+// Body renders the results of every TAP stream, grouped by outcome.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	view := tapView{}
+	byStatus := map[Status][]TestResult{}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		content, err := a.ReadAll()
+		if err != nil {
+			view.Problems = append(view.Problems, fmt.Sprintf("%s: failed to read: %v", a.JobPath(), err))
+			continue
+		}
+		results := Parse(string(content))
+		for _, p := range results.Problems {
+			view.Problems = append(view.Problems, fmt.Sprintf("%s: %s", a.JobPath(), p))
+		}
+		for _, t := range results.Tests {
+			tr := TestResult{Test: t, Link: a.CanonicalLink()}
+			if len(artifacts) > 1 {
+				tr.File = a.JobPath()
+			}
+			byStatus[t.Status] = append(byStatus[t.Status], tr)
+			view.NumTests++
+		}
+	}
+
+	for _, s := range []struct {
+		status Status
+		title  string
+	}{
+		{status: Failed, title: "Tests Failed."},
+		{status: Passed, title: "Tests Passed!"},
+		{status: Skipped, title: "Tests Skipped."},
+		{status: Todo, title: "Tests To Do."},
+	} {
+		if tests := byStatus[s.status]; len(tests) > 0 {
+			view.Sections = append(view.Sections, Section{
+				Status: s.status,
+				Title:  fmt.Sprintf("%d/%d %s", len(tests), view.NumTests, s.title),
+				Tests:  tests,
+				Open:   s.status == Failed,
+			})
+		}
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
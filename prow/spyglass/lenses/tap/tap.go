@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Status is the outcome of a test.
+type Status string
+
+const (
+	// Passed tests are reported with "ok".
+	Passed Status = "passed"
+	// Failed tests are reported with "not ok".
+	Failed Status = "failed"
+	// Skipped tests have a SKIP directive.
+	Skipped Status = "skipped"
+	// Todo tests have a TODO directive, and do not count as failures either way.
+	Todo Status = "todo"
+)
+
+// Test is a single test point.
+type Test struct {
+	Number      int
+	Description string
+	Status      Status
+	// Reason is the explanation given with a SKIP or TODO directive.
+	Reason string
+	// Diagnostics is the YAML block, comments and subtest output attached to the test.
+	Diagnostics string
+}
+
+// Name returns the description of the test, or its number if it has none.
+func (t Test) Name() string {
+	if t.Description != "" {
+		return t.Description
+	}
+	return fmt.Sprintf("test %d", t.Number)
+}
+
+// Results is a parsed TAP stream.
+type Results struct {
+	Version int
+	// Planned is the number of tests in the plan, or -1 if there was none.
+	Planned int
+	Tests   []Test
+	// BailOut is the reason given if the run was aborted.
+	BailOut string
+	// Problems describes anything wrong with the run as a whole.
+	Problems []string
+}
+
+var (
+	versionRE = regexp.MustCompile(`^TAP version (\d+)\s*$`)
+	planRE    = regexp.MustCompile(`^1\.\.(\d+)\s*(?:#.*)?$`)
+	testRE    = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?(.*)$`)
+	// directiveRE finds a SKIP or TODO directive after an unescaped #.
+	directiveRE = regexp.MustCompile(`(?i)(?:^|[^\\])#\s*(skip|todo)\S*\s*(.*)$`)
+	bailOutRE   = regexp.MustCompile(`^Bail out!\s*(.*)$`)
+)
+
+// Parse reads a TAP stream. Lines it does not recognize are kept as diagnostics rather than
+// rejected, since many producers interleave other output.
+func Parse(content string) Results {
+	r := Results{Version: 12, Planned: -1}
+	// pending holds output that belongs to the next test point.
+	var pending []string
+	inYAML, bailed := false, false
+	yamlIndent := ""
+	// attach adds a line to the diagnostics of the previous test point.
+	attach := func(line string) {
+		if n := len(r.Tests); n > 0 && len(pending) == 0 {
+			t := &r.Tests[n-1]
+			if t.Diagnostics != "" {
+				t.Diagnostics += "\n"
+			}
+			t.Diagnostics += line
+			return
+		}
+		pending = append(pending, line)
+	}
+
+	for _, line := range strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n") {
+		if inYAML {
+			if strings.TrimSpace(line) == "..." && strings.HasPrefix(line, yamlIndent) {
+				inYAML = false
+				continue
+			}
+			attach(strings.TrimPrefix(line, yamlIndent))
+			continue
+		}
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed == "---" && len(trimmed) < len(line) && len(r.Tests) > 0 && len(pending) == 0 {
+			inYAML = true
+			yamlIndent = line[:len(line)-len(trimmed)]
+			continue
+		}
+		if bailed {
+			continue
+		}
+		if m := versionRE.FindStringSubmatch(line); m != nil {
+			r.Version, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := planRE.FindStringSubmatch(line); m != nil {
+			r.Planned, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := bailOutRE.FindStringSubmatch(line); m != nil {
+			r.BailOut = m[1]
+			bailed = true
+			continue
+		}
+		if m := testRE.FindStringSubmatch(line); m != nil {
+			t := Test{Number: len(r.Tests) + 1, Status: Passed, Description: m[3]}
+			if m[2] != "" {
+				t.Number, _ = strconv.Atoi(m[2])
+			}
+			if m[1] != "" {
+				t.Status = Failed
+			}
+			if d := directiveRE.FindStringSubmatchIndex(t.Description); d != nil {
+				if strings.EqualFold(t.Description[d[2]:d[3]], "skip") {
+					t.Status = Skipped
+				} else {
+					t.Status = Todo
+				}
+				t.Reason = strings.TrimSpace(t.Description[d[4]:d[5]])
+				// The match may include the character before the #.
+				hash := strings.LastIndex(t.Description[:d[2]], "#")
+				t.Description = t.Description[:hash]
+			}
+			t.Description = strings.Replace(strings.TrimSpace(t.Description), `\#`, "#", -1)
+			// Output that came before the test point, such as that of its subtests, belongs to it.
+			t.Diagnostics = strings.Join(pending, "\n")
+			pending = nil
+			r.Tests = append(r.Tests, t)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// Indented lines are subtests, which are reported before the test point that sums them up.
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "# Subtest") {
+			pending = append(pending, line)
+			continue
+		}
+		attach(line)
+	}
+
+	if bailed {
+		reason := "Bail out!"
+		if r.BailOut != "" {
+			reason += " " + r.BailOut
+		}
+		r.Problems = append(r.Problems, reason)
+	}
+	if r.Planned >= 0 && r.Planned != len(r.Tests) {
+		r.Problems = append(r.Problems, fmt.Sprintf("Planned %d tests but %d ran.", r.Planned, len(r.Tests)))
+	}
+	return r
+}
@@ -0,0 +1,29 @@
+function toggle(element: Element, hiddenClass: string, icon: HTMLElement | null): void {
+  const hidden = element.classList.toggle(hiddenClass);
+  if (icon) {
+    icon.innerText = hidden ? 'expand_more' : 'expand_less';
+  }
+  spyglass.contentUpdated();
+}
+
+function addSectionExpanders(): void {
+  for (const expander of Array.from(document.querySelectorAll<HTMLTableRowElement>('tr.section-expander'))) {
+    expander.onclick = () => {
+      const tbody = expander.parentElement!.nextElementSibling!;
+      toggle(tbody, 'hidden-tests', expander.querySelector('i'));
+    };
+  }
+}
+
+function addTestExpanders(): void {
+  for (const row of Array.from(document.querySelectorAll<HTMLTableRowElement>('tr.test-name.expandable'))) {
+    row.onclick = () => {
+      toggle(row.nextElementSibling!, 'hidden', row.querySelector('i'));
+    };
+  }
+}
+
+window.addEventListener('DOMContentLoaded', () => {
+  addSectionExpanders();
+  addTestExpanders();
+});
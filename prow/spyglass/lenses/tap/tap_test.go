@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected Results
+	}{
+		{
+			name: "version 13 with YAML diagnostics",
+			input: `TAP version 13
+1..4
+ok 1 - input file opened
+not ok 2 - first line of the input valid
+  ---
+  message: 'First line invalid'
+  severity: fail
+  data:
+    got: 'Flirble'
+    expect: 'Fnible'
+  ...
+ok 3 - read the rest of the file # SKIP no file
+not ok 4 - summarized correctly # TODO Not written yet
+`,
+			expected: Results{
+				Version: 13,
+				Planned: 4,
+				Tests: []Test{
+					{Number: 1, Description: "input file opened", Status: Passed},
+					{Number: 2, Description: "first line of the input valid", Status: Failed, Diagnostics: "message: 'First line invalid'\nseverity: fail\ndata:\n  got: 'Flirble'\n  expect: 'Fnible'"},
+					{Number: 3, Description: "read the rest of the file", Status: Skipped, Reason: "no file"},
+					{Number: 4, Description: "summarized correctly", Status: Todo, Reason: "Not written yet"},
+				},
+			},
+		},
+		{
+			name: "version 12 with comments, escapes and a trailing plan",
+			input: `ok - the \# of tests
+not ok 2
+# expected 3
+# got 4
+1..3
+`,
+			expected: Results{
+				Version: 12,
+				Planned: 3,
+				Tests: []Test{
+					{Number: 1, Description: "the # of tests", Status: Passed},
+					{Number: 2, Status: Failed, Diagnostics: "# expected 3\n# got 4"},
+				},
+				Problems: []string{"Planned 3 tests but 2 ran."},
+			},
+		},
+		{
+			name: "subtests belong to the following test point",
+			input: `TAP version 13
+# Subtest: parent
+    ok 1 - child
+    1..1
+ok 1 - parent
+`,
+			expected: Results{
+				Version: 13,
+				Planned: -1,
+				Tests: []Test{
+					{Number: 1, Description: "parent", Status: Passed, Diagnostics: "# Subtest: parent\n    ok 1 - child\n    1..1"},
+				},
+			},
+		},
+		{
+			name: "bail out",
+			input: `1..2
+ok 1
+Bail out! Database unavailable
+ok 2
+`,
+			expected: Results{
+				Version:  12,
+				Planned:  2,
+				Tests:    []Test{{Number: 1, Status: Passed}},
+				BailOut:  "Database unavailable",
+				Problems: []string{"Bail out! Database unavailable", "Planned 2 tests but 1 ran."},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := Parse(tc.input); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
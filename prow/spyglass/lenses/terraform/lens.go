@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package terraform provides a Spyglass lens that renders Terraform plans.
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "terraform"
+	title    = "Terraform Plan"
+	priority = 25
+)
+
+// Lens is the implementation of a Terraform plan-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// ChangeView is a resource change and the attributes worth showing.
+type ChangeView struct {
+	Change
+	// Symbol is the marker Terraform uses for the action.
+	Symbol string
+	Shown  []Attribute
+	// Unchanged is the number of attributes of an updated resource that are not shown.
+	Unchanged int
+}
+
+// PlanView summarizes a plan.
+type PlanView struct {
+	Name             string
+	Error            string
+	TerraformVersion string
+	Detailed         bool
+	// Counts as reported by Terraform, where replacements count as both an add and a destroy.
+	Add, Change, Destroy, Read, Noop int
+	Changes                          []ChangeView
+}
+
+type terraformView struct {
+	Plans []PlanView
+}
+
+// Body renders the changes in each plan.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	view := terraformView{}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		pv := PlanView{Name: a.JobPath()}
+		content, err := a.ReadAll()
+		if err != nil {
+			pv.Error = fmt.Sprintf("Failed to read: %v", err)
+			view.Plans = append(view.Plans, pv)
+			continue
+		}
+		p, err := parsePlan(content)
+		if err != nil {
+			pv.Error = err.Error()
+			view.Plans = append(view.Plans, pv)
+			continue
+		}
+		view.Plans = append(view.Plans, summarize(pv, p))
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+var symbols = map[string]string{
+	actionCreate:  "+",
+	actionUpdate:  "~",
+	actionDelete:  "-",
+	actionReplace: "-/+",
+	actionRead:    "<=",
+}
+
+func summarize(pv PlanView, p *Plan) PlanView {
+	pv.TerraformVersion = p.TerraformVersion
+	pv.Detailed = p.Detailed
+	for _, c := range p.Changes {
+		switch c.Action {
+		case actionCreate:
+			pv.Add++
+		case actionUpdate:
+			pv.Change++
+		case actionDelete:
+			pv.Destroy++
+		case actionReplace:
+			pv.Add++
+			pv.Destroy++
+		case actionRead:
+			pv.Read++
+		default:
+			pv.Noop++
+			continue
+		}
+		cv := ChangeView{Change: c, Symbol: symbols[c.Action]}
+		for _, attr := range c.Attributes {
+			// Only the differences in updates are interesting; created and destroyed resources
+			// are shown in full.
+			if (c.Action == actionUpdate || c.Action == actionReplace) && !attr.Changed {
+				cv.Unchanged++
+				continue
+			}
+			cv.Shown = append(cv.Shown, attr)
+		}
+		pv.Changes = append(pv.Changes, cv)
+	}
+	return pv
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
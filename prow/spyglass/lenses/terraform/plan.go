@@ -0,0 +1,263 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Actions of resource changes. Replacements are listed in the plan as a delete and a create, in
+// the order they will happen.
+const (
+	actionCreate  = "create"
+	actionUpdate  = "update"
+	actionDelete  = "delete"
+	actionReplace = "replace"
+	actionRead    = "read"
+	actionNoop    = "no-op"
+)
+
+// plan is the subset of the JSON representation of a plan, written by `terraform show -json`,
+// used by the lens.
+type plan struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	ResourceChanges  []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions         []string        `json:"actions"`
+			Before          json.RawMessage `json:"before"`
+			After           json.RawMessage `json:"after"`
+			AfterUnknown    json.RawMessage `json:"after_unknown"`
+			BeforeSensitive json.RawMessage `json:"before_sensitive"`
+			AfterSensitive  json.RawMessage `json:"after_sensitive"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// message is the subset of a line of the machine-readable UI, written by `terraform plan -json`,
+// used by the lens. It only describes which resources change, not how.
+type message struct {
+	Type   string `json:"type"`
+	Change struct {
+		Resource struct {
+			Addr         string `json:"addr"`
+			ResourceType string `json:"resource_type"`
+		} `json:"resource"`
+		Action string `json:"action"`
+	} `json:"change"`
+}
+
+// Attribute is a change to an attribute of a resource, in flattened form such as
+// "tags.env" or "ingress[0].port".
+type Attribute struct {
+	Path   string
+	Before string
+	After  string
+	// Changed is whether the value differs. Unchanged attributes are kept for context.
+	Changed bool
+}
+
+// Change is a planned change to a resource.
+type Change struct {
+	Address    string
+	Type       string
+	Action     string
+	Attributes []Attribute
+}
+
+// Plan is the set of changes in a plan.
+type Plan struct {
+	TerraformVersion string
+	Changes          []Change
+	// Detailed is whether the plan describes how attributes change.
+	Detailed bool
+}
+
+// errBinaryPlan is returned for plan files, which are in a format internal to Terraform.
+var errBinaryPlan = errors.New("this is a binary plan file; upload the output of `terraform show -json` instead")
+
+// parsePlan reads either the JSON representation of a plan or the machine-readable UI output of
+// `terraform plan -json`.
+func parsePlan(content []byte) (*Plan, error) {
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		return nil, errBinaryPlan
+	}
+	var p plan
+	if err := json.Unmarshal(content, &p); err == nil && p.FormatVersion != "" {
+		return fromPlan(p)
+	}
+	return fromMessages(content)
+}
+
+func fromPlan(p plan) (*Plan, error) {
+	result := &Plan{TerraformVersion: p.TerraformVersion, Detailed: true}
+	for _, rc := range p.ResourceChanges {
+		c := Change{Address: rc.Address, Type: rc.Type, Action: action(rc.Change.Actions)}
+		var err error
+		c.Attributes, err = diff(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown, rc.Change.BeforeSensitive, rc.Change.AfterSensitive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the change to %s: %v", rc.Address, err)
+		}
+		result.Changes = append(result.Changes, c)
+	}
+	return result, nil
+}
+
+func fromMessages(content []byte) (*Plan, error) {
+	result := &Plan{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var m message
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, fmt.Errorf("not a Terraform JSON plan: %v", err)
+		}
+		if m.Type != "planned_change" {
+			continue
+		}
+		result.Changes = append(result.Changes, Change{
+			Address: m.Change.Resource.Addr,
+			Type:    m.Change.Resource.ResourceType,
+			Action:  m.Change.Action,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read plan: %v", err)
+	}
+	return result, nil
+}
+
+// action summarizes the actions of a resource change.
+func action(actions []string) string {
+	if len(actions) == 2 {
+		return actionReplace
+	}
+	if len(actions) == 1 {
+		return actions[0]
+	}
+	return actionNoop
+}
+
+const (
+	unknownValue   = "(known after apply)"
+	sensitiveValue = "(sensitive value)"
+)
+
+// diff compares the flattened attributes of a resource before and after a change. Sensitive
+// values are hidden, but still reported as changed if they differ.
+func diff(before, after, afterUnknown, beforeSensitive, afterSensitive json.RawMessage) ([]Attribute, error) {
+	b, a := map[string]string{}, map[string]string{}
+	shownB, shownA := map[string]string{}, map[string]string{}
+	for _, f := range []struct {
+		raw    json.RawMessage
+		values []map[string]string
+		mark   string
+	}{
+		{raw: before, values: []map[string]string{b, shownB}},
+		{raw: after, values: []map[string]string{a, shownA}},
+		{raw: afterUnknown, values: []map[string]string{a, shownA}, mark: unknownValue},
+		{raw: beforeSensitive, values: []map[string]string{shownB}, mark: sensitiveValue},
+		{raw: afterSensitive, values: []map[string]string{shownA}, mark: sensitiveValue},
+	} {
+		if len(f.raw) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(f.raw, &v); err != nil {
+			return nil, err
+		}
+		for _, values := range f.values {
+			flatten("", v, values, f.mark)
+		}
+	}
+
+	var attributes []Attribute
+	for p := range shownB {
+		if _, ok := shownA[p]; !ok {
+			attributes = append(attributes, Attribute{Path: p, Before: shownB[p], Changed: true})
+		}
+	}
+	for p, after := range shownA {
+		before, ok := shownB[p]
+		_, inAfter := a[p]
+		_, inBefore := b[p]
+		changed := !ok || inBefore != inAfter || b[p] != a[p]
+		attributes = append(attributes, Attribute{Path: p, Before: before, After: after, Changed: changed})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Path < attributes[j].Path })
+	return attributes, nil
+}
+
+// flatten records the leaf values of v by their path. If mark is set, v is a mask as found in
+// after_unknown and the *_sensitive fields, and the paths where it is true are set to mark.
+func flatten(prefix string, v interface{}, values map[string]string, mark string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flatten(p, child, values, mark)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, values, mark)
+		}
+	default:
+		if mark != "" {
+			if v == true {
+				// The mask may cover a whole object or list, or the whole resource.
+				covered := false
+				for p := range values {
+					if prefix == "" || strings.HasPrefix(p, prefix+".") || strings.HasPrefix(p, prefix+"[") {
+						values[p] = mark
+						covered = true
+					}
+				}
+				if prefix != "" && !covered {
+					values[prefix] = mark
+				}
+			}
+			return
+		}
+		if prefix == "" || v == nil {
+			return
+		}
+		if s, ok := v.(string); ok {
+			// Show multi-line values such as policies as they are, rather than escaped.
+			if strings.Contains(s, "\n") {
+				values[prefix] = s
+				return
+			}
+		}
+		encoded, _ := json.Marshal(v)
+		values[prefix] = string(encoded)
+	}
+}
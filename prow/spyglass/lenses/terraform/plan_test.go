@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testPlan = `{
+  "format_version": "0.1",
+  "terraform_version": "0.12.6",
+  "resource_changes": [
+    {
+      "address": "google_compute_instance.bastion",
+      "type": "google_compute_instance",
+      "change": {
+        "actions": ["update"],
+        "before": {"name": "bastion", "machine_type": "n1-standard-1", "tags": ["ssh"], "labels": {"env": "prod"}},
+        "after": {"name": "bastion", "machine_type": "n1-standard-2", "tags": ["ssh", "http"], "labels": {"env": "prod"}},
+        "after_unknown": {"tags": [false, false]}
+      }
+    },
+    {
+      "address": "google_sql_user.admin",
+      "type": "google_sql_user",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"name": "admin", "password": "hunter2"},
+        "after": {"name": "admin", "password": "hunter3"},
+        "after_unknown": {"id": true},
+        "before_sensitive": {"password": true},
+        "after_sensitive": {"password": true}
+      }
+    },
+    {
+      "address": "google_storage_bucket.logs",
+      "type": "google_storage_bucket",
+      "change": {"actions": ["no-op"], "before": {"name": "logs"}, "after": {"name": "logs"}}
+    }
+  ]
+}`
+
+func TestParsePlan(t *testing.T) {
+	p, err := parsePlan([]byte(testPlan))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.Detailed || p.TerraformVersion != "0.12.6" || len(p.Changes) != 3 {
+		t.Fatalf("expected a detailed plan with 3 changes, got %+v", p)
+	}
+	var changed []Attribute
+	for _, a := range p.Changes[0].Attributes {
+		if a.Changed {
+			changed = append(changed, a)
+		}
+	}
+	expected := []Attribute{
+		{Path: "machine_type", Before: `"n1-standard-1"`, After: `"n1-standard-2"`, Changed: true},
+		{Path: "tags[1]", After: `"http"`, Changed: true},
+	}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expected changes %+v, got %+v", expected, changed)
+	}
+
+	replace := p.Changes[1]
+	if replace.Action != actionReplace {
+		t.Errorf("expected a replacement, got %s", replace.Action)
+	}
+	for _, a := range replace.Attributes {
+		switch a.Path {
+		case "password":
+			if a.Before != sensitiveValue || a.After != sensitiveValue || !a.Changed {
+				t.Errorf("expected the password to be hidden but changed, got %+v", a)
+			}
+		case "id":
+			if a.After != unknownValue || !a.Changed {
+				t.Errorf("expected the id to be unknown, got %+v", a)
+			}
+		}
+	}
+
+	pv := summarize(PlanView{}, p)
+	if pv.Add != 1 || pv.Change != 1 || pv.Destroy != 1 || pv.Noop != 1 || len(pv.Changes) != 2 {
+		t.Errorf("expected 1 to add, change and destroy and 1 unchanged, got %+v", pv)
+	}
+	if pv.Changes[1].Unchanged != 1 || pv.Changes[0].Unchanged != 3 || len(pv.Changes[0].Shown) != 2 {
+		t.Errorf("expected 2 attributes shown and 3 hidden, got %d and %d", len(pv.Changes[0].Shown), pv.Changes[0].Unchanged)
+	}
+}
+
+func TestParseMessages(t *testing.T) {
+	content := `{"@level":"info","@message":"Terraform 1.0.0","type":"version","terraform":"1.0.0"}
+{"@level":"info","type":"planned_change","change":{"resource":{"addr":"google_compute_network.main","resource_type":"google_compute_network"},"action":"create"}}
+{"@level":"info","type":"change_summary","changes":{"add":1,"change":0,"remove":0,"operation":"plan"}}
+`
+	p, err := parsePlan([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Detailed || len(p.Changes) != 1 || p.Changes[0].Address != "google_compute_network.main" || p.Changes[0].Action != actionCreate {
+		t.Errorf("expected one created network, got %+v", p)
+	}
+}
+
+func TestParseBinaryPlan(t *testing.T) {
+	if _, err := parsePlan([]byte("PK\x03\x04tfplan")); err != errBinaryPlan {
+		t.Errorf("expected %v, got %v", errBinaryPlan, err)
+	}
+}
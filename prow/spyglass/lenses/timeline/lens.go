@@ -0,0 +1,311 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package timeline provides a Spyglass lens that shows where a job spent its wall-clock time.
+package timeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/pod-utils/clone"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+const (
+	name     = "timeline"
+	title    = "Timeline"
+	priority = 1
+)
+
+// Phase kinds, which are also used as CSS classes.
+const (
+	kindClone    = "clone"
+	kindSetup    = "setup"
+	kindTest     = "test"
+	kindTeardown = "teardown"
+)
+
+// junitTimeLayouts are the timestamp formats junit producers are known to use.
+// Timestamps without a zone are assumed to be UTC.
+var junitTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// Lens is the implementation of a timeline-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// Body renders a Gantt-style chart of the phases of the job.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "body", buildView(readJob(artifacts)))
+}
+
+// phase is a named span of wall-clock time within a job.
+type phase struct {
+	name     string
+	kind     string
+	start    time.Time
+	duration time.Duration
+}
+
+func (p phase) end() time.Time {
+	return p.start.Add(p.duration)
+}
+
+// jobTimes holds everything we know about the timing of a job.
+type jobTimes struct {
+	started  time.Time
+	finished time.Time
+	clones   []clone.Record
+	suites   []namedSuite
+}
+
+type namedSuite struct {
+	junit.Suite
+	artifact string
+}
+
+func readJob(artifacts []lenses.Artifact) jobTimes {
+	var job jobTimes
+	for _, a := range artifacts {
+		log := logrus.WithField("artifact", a.JobPath())
+		content, err := a.ReadAll()
+		if err != nil {
+			log.WithError(err).Warn("Error reading artifact")
+			continue
+		}
+		switch base := path.Base(a.JobPath()); {
+		case base == "started.json":
+			var started gcs.Started
+			if err := json.Unmarshal(content, &started); err != nil {
+				log.WithError(err).Info("Error unmarshaling started.json")
+				continue
+			}
+			job.started = time.Unix(started.Timestamp, 0)
+		case base == "finished.json":
+			var finished gcs.Finished
+			if err := json.Unmarshal(content, &finished); err != nil {
+				log.WithError(err).Info("Error unmarshaling finished.json")
+				continue
+			}
+			if finished.Timestamp != nil {
+				job.finished = time.Unix(*finished.Timestamp, 0)
+			}
+		case base == "clone-records.json":
+			if err := json.Unmarshal(content, &job.clones); err != nil {
+				log.WithError(err).Info("Error unmarshaling clone records")
+			}
+		case strings.HasSuffix(base, ".xml"):
+			suites, err := junit.Parse(content)
+			if err != nil {
+				log.WithError(err).Info("Error parsing junit file")
+				continue
+			}
+			for _, s := range suites.Suites {
+				job.suites = append(job.suites, namedSuite{Suite: s, artifact: a.JobPath()})
+			}
+		}
+	}
+	return job
+}
+
+func parseJunitTime(s string) (time.Time, bool) {
+	for _, layout := range junitTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// phases lays out the known parts of the job on a single time axis. Suites that did not
+// record a start time cannot be placed, and are returned separately.
+func (job jobTimes) phases() (placed, unplaced []phase) {
+	// Clonerefs runs to completion before the job is considered to have started,
+	// so we line its commands up to end at the start time.
+	var cloneTotal time.Duration
+	for _, r := range job.clones {
+		for _, c := range r.Commands {
+			cloneTotal += c.Duration
+		}
+	}
+	if !job.started.IsZero() && cloneTotal > 0 {
+		cursor := job.started.Add(-cloneTotal)
+		for _, r := range job.clones {
+			var d time.Duration
+			for _, c := range r.Commands {
+				d += c.Duration
+			}
+			placed = append(placed, phase{
+				name:     fmt.Sprintf("clone %s/%s", r.Refs.Org, r.Refs.Repo),
+				kind:     kindClone,
+				start:    cursor,
+				duration: d,
+			})
+			cursor = cursor.Add(d)
+		}
+	}
+
+	var tests []phase
+	for _, s := range job.suites {
+		p := phase{
+			name:     s.Name,
+			kind:     kindTest,
+			duration: time.Duration(s.Time * float64(time.Second)),
+		}
+		if p.name == "" {
+			p.name = s.artifact
+		}
+		if start, ok := parseJunitTime(s.Timestamp); ok {
+			p.start = start
+			tests = append(tests, p)
+		} else {
+			unplaced = append(unplaced, p)
+		}
+	}
+	sort.SliceStable(tests, func(i, j int) bool { return tests[i].start.Before(tests[j].start) })
+
+	if job.started.IsZero() {
+		return append(placed, tests...), unplaced
+	}
+	if len(tests) == 0 {
+		if !job.finished.IsZero() {
+			placed = append(placed, phase{name: "job", kind: kindSetup, start: job.started, duration: job.finished.Sub(job.started)})
+		}
+		return placed, unplaced
+	}
+
+	firstStart := tests[0].start
+	lastEnd := tests[0].end()
+	for _, t := range tests {
+		if t.end().After(lastEnd) {
+			lastEnd = t.end()
+		}
+	}
+	if firstStart.After(job.started) {
+		placed = append(placed, phase{name: "setup and build", kind: kindSetup, start: job.started, duration: firstStart.Sub(job.started)})
+	}
+	placed = append(placed, tests...)
+	if !job.finished.IsZero() && job.finished.After(lastEnd) {
+		placed = append(placed, phase{name: "teardown", kind: kindTeardown, start: lastEnd, duration: job.finished.Sub(lastEnd)})
+	}
+	return placed, unplaced
+}
+
+// Bar is a single row of the rendered timeline.
+type Bar struct {
+	Name     string
+	Kind     string
+	Offset   time.Duration
+	Duration time.Duration
+	// Left and Width are percentages of the whole timeline.
+	Left  float64
+	Width float64
+}
+
+type timelineView struct {
+	Start    time.Time
+	Total    time.Duration
+	Running  bool
+	Bars     []Bar
+	Unplaced []Bar
+}
+
+func buildView(job jobTimes) timelineView {
+	placed, unplaced := job.phases()
+	view := timelineView{Running: job.finished.IsZero()}
+	for _, p := range unplaced {
+		view.Unplaced = append(view.Unplaced, Bar{Name: p.name, Kind: p.kind, Duration: p.duration.Round(time.Second)})
+	}
+	if len(placed) == 0 {
+		return view
+	}
+
+	start, end := placed[0].start, placed[0].end()
+	for _, p := range placed {
+		if p.start.Before(start) {
+			start = p.start
+		}
+		if p.end().After(end) {
+			end = p.end()
+		}
+	}
+	view.Start = start
+	view.Total = end.Sub(start)
+	for _, p := range placed {
+		b := Bar{
+			Name:     p.name,
+			Kind:     p.kind,
+			Offset:   p.start.Sub(start).Round(time.Second),
+			Duration: p.duration.Round(time.Second),
+		}
+		if view.Total > 0 {
+			b.Left = 100 * float64(p.start.Sub(start)) / float64(view.Total)
+			b.Width = 100 * float64(p.duration) / float64(view.Total)
+		}
+		view.Bars = append(view.Bars, b)
+	}
+	view.Total = view.Total.Round(time.Second)
+	return view
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
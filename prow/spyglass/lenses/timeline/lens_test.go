@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/clone"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+func TestPhases(t *testing.T) {
+	started := time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name             string
+		job              jobTimes
+		expectedPlaced   []phase
+		expectedUnplaced []phase
+	}{
+		{
+			name: "no information",
+		},
+		{
+			name: "only start and finish",
+			job: jobTimes{
+				started:  started,
+				finished: started.Add(time.Hour),
+			},
+			expectedPlaced: []phase{
+				{name: "job", kind: kindSetup, start: started, duration: time.Hour},
+			},
+		},
+		{
+			name: "clone, setup, tests and teardown",
+			job: jobTimes{
+				started:  started,
+				finished: started.Add(90 * time.Minute),
+				clones: []clone.Record{
+					{
+						Refs: prowapi.Refs{Org: "kubernetes", Repo: "test-infra"},
+						Commands: []clone.Command{
+							{Command: "git init", Duration: time.Second},
+							{Command: "git fetch", Duration: 59 * time.Second},
+						},
+					},
+				},
+				suites: []namedSuite{
+					{Suite: junit.Suite{Name: "second", Time: 600, Timestamp: "2019-04-01T10:50:00"}},
+					{Suite: junit.Suite{Name: "first", Time: 1200, Timestamp: "2019-04-01T10:30:00Z"}},
+				},
+			},
+			expectedPlaced: []phase{
+				{name: "clone kubernetes/test-infra", kind: kindClone, start: started.Add(-time.Minute), duration: time.Minute},
+				{name: "setup and build", kind: kindSetup, start: started, duration: 30 * time.Minute},
+				{name: "first", kind: kindTest, start: started.Add(30 * time.Minute), duration: 20 * time.Minute},
+				{name: "second", kind: kindTest, start: started.Add(50 * time.Minute), duration: 10 * time.Minute},
+				{name: "teardown", kind: kindTeardown, start: started.Add(time.Hour), duration: 30 * time.Minute},
+			},
+		},
+		{
+			name: "suites without timestamps are not placed",
+			job: jobTimes{
+				started: started,
+				suites: []namedSuite{
+					{Suite: junit.Suite{Time: 5}, artifact: "artifacts/junit_01.xml"},
+				},
+			},
+			expectedUnplaced: []phase{
+				{name: "artifacts/junit_01.xml", kind: kindTest, duration: 5 * time.Second},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			placed, unplaced := tc.job.phases()
+			if !reflect.DeepEqual(placed, tc.expectedPlaced) {
+				t.Errorf("expected placed phases %#v, got %#v", tc.expectedPlaced, placed)
+			}
+			if !reflect.DeepEqual(unplaced, tc.expectedUnplaced) {
+				t.Errorf("expected unplaced phases %#v, got %#v", tc.expectedUnplaced, unplaced)
+			}
+		})
+	}
+}
+
+func TestBuildView(t *testing.T) {
+	started := time.Date(2019, 4, 1, 10, 0, 0, 0, time.UTC)
+	view := buildView(jobTimes{
+		started:  started,
+		finished: started.Add(40 * time.Minute),
+		suites: []namedSuite{
+			{Suite: junit.Suite{Name: "tests", Time: 1200, Timestamp: "2019-04-01T10:10:00Z"}},
+		},
+	})
+	if view.Total != 40*time.Minute {
+		t.Errorf("expected total of 40m, got %v", view.Total)
+	}
+	if len(view.Bars) != 3 {
+		t.Fatalf("expected 3 bars, got %d: %#v", len(view.Bars), view.Bars)
+	}
+	test := view.Bars[1]
+	if test.Left != 25 || test.Width != 50 {
+		t.Errorf("expected test bar at 25%% with width 50%%, got %v%% and %v%%", test.Left, test.Width)
+	}
+}
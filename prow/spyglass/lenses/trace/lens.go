@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trace provides a Spyglass lens that renders distributed traces exported by tests.
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/chart"
+)
+
+const (
+	name     = "trace"
+	title    = "Traces"
+	priority = 28
+
+	// defaultMaxSpans is how many spans of each trace are shown if not configured.
+	defaultMaxSpans = 2000
+)
+
+// Lens is the implementation of a trace-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxSpans is how many spans of each trace are shown. Defaults to 2000.
+	MaxSpans int `json:"max_spans,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// SpanView is a span positioned on the waterfall of its trace.
+type SpanView struct {
+	*Span
+	// Offset is how long after the start of the trace the span started.
+	Offset   string
+	Duration string
+	// Bar positions and colors the span's bar.
+	Bar template.CSS
+}
+
+// Service is a service in the legend of a trace.
+type Service struct {
+	Name  string
+	Color template.CSS
+}
+
+// TraceView is the waterfall of one trace.
+type TraceView struct {
+	*Trace
+	Duration string
+	Services []Service
+	Spans    []SpanView
+	// Hidden is the number of spans not shown.
+	Hidden int
+}
+
+// FileView is the traces in one artifact.
+type FileView struct {
+	Name   string
+	Error  string
+	Traces []TraceView
+}
+
+type traceView struct {
+	Error string
+	Files []FileView
+}
+
+// Body renders a waterfall of the spans of each trace.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := traceView{}
+	conf := config{MaxSpans: defaultMaxSpans}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Error = fmt.Sprintf("Invalid lens configuration: %v", err)
+		}
+	}
+	if conf.MaxSpans <= 0 {
+		conf.MaxSpans = defaultMaxSpans
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		fv := FileView{Name: a.JobPath()}
+		content, err := a.ReadAll()
+		if err != nil {
+			fv.Error = fmt.Sprintf("Failed to read: %v", err)
+			view.Files = append(view.Files, fv)
+			continue
+		}
+		t := newTraces()
+		if err := t.read(content); err != nil {
+			fv.Error = err.Error()
+		}
+		for _, trace := range t.build() {
+			fv.Traces = append(fv.Traces, waterfall(trace, conf.MaxSpans))
+		}
+		if fv.Error == "" && len(fv.Traces) == 0 {
+			fv.Error = "No spans found."
+		}
+		view.Files = append(view.Files, fv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// waterfall positions the first maxSpans spans of a trace relative to its duration, and colors
+// them by service.
+func waterfall(t *Trace, maxSpans int) TraceView {
+	tv := TraceView{Trace: t, Duration: formatDuration(t.Duration())}
+	colors := map[string]string{}
+	for _, s := range t.Spans {
+		if _, ok := colors[s.Service]; !ok {
+			color := chart.Colors[len(tv.Services)%len(chart.Colors)]
+			colors[s.Service] = color
+			tv.Services = append(tv.Services, Service{Name: s.Service, Color: template.CSS("background-color:" + color)})
+		}
+	}
+	spans := t.Spans
+	if len(spans) > maxSpans {
+		tv.Hidden = len(spans) - maxSpans
+		spans = spans[:maxSpans]
+	}
+	total := float64(t.Duration())
+	for _, s := range spans {
+		left, width := 0.0, 100.0
+		if total > 0 {
+			left = 100 * float64(s.Start.Sub(t.Start)) / total
+			width = 100 * float64(s.Duration()) / total
+		}
+		tv.Spans = append(tv.Spans, SpanView{
+			Span:     s,
+			Offset:   formatDuration(s.Start.Sub(t.Start)),
+			Duration: formatDuration(s.Duration()),
+			Bar:      template.CSS(fmt.Sprintf("left:%.3f%%;width:%.3f%%;background-color:%s", left, width, colors[s.Service])),
+		})
+	}
+	return tv
+}
+
+// formatDuration rounds a duration to a precision that suits its magnitude.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second || d <= -time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond || d <= -time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.String()
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// jsonInt is an int64, which the JSON form of protobuf messages encodes as a string.
+type jsonInt int64
+
+func (i *jsonInt) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt(v)
+	return nil
+}
+
+// otlpValue is an OTLP AnyValue. Only scalar values are shown.
+type otlpValue struct {
+	StringValue *string  `json:"stringValue"`
+	IntValue    *jsonInt `json:"intValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+}
+
+func (v otlpValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	}
+	return ""
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano jsonInt         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   jsonInt         `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		// Code is 2, or "STATUS_CODE_ERROR" in older exporters, for errors.
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	} `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Spans []otlpSpan `json:"spans"`
+}
+
+// otlpExport is an OTLP ExportTraceServiceRequest, as written by the OpenTelemetry collector's
+// file exporter.
+type otlpExport struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+		// InstrumentationLibrarySpans is the name of ScopeSpans before OTLP 0.15.
+		InstrumentationLibrarySpans []otlpScopeSpans `json:"instrumentationLibrarySpans"`
+	} `json:"resourceSpans"`
+}
+
+// jaegerExport is the JSON returned by the Jaeger query API and its UI's download button.
+type jaegerExport struct {
+	Data []struct {
+		TraceID string `json:"traceID"`
+		Spans   []struct {
+			TraceID       string `json:"traceID"`
+			SpanID        string `json:"spanID"`
+			OperationName string `json:"operationName"`
+			References    []struct {
+				RefType string `json:"refType"`
+				SpanID  string `json:"spanID"`
+			} `json:"references"`
+			// StartTime and Duration are in microseconds.
+			StartTime jsonInt `json:"startTime"`
+			Duration  jsonInt `json:"duration"`
+			ProcessID string  `json:"processID"`
+			Tags      []struct {
+				Key   string      `json:"key"`
+				Value interface{} `json:"value"`
+			} `json:"tags"`
+		} `json:"spans"`
+		Processes map[string]struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"processes"`
+	} `json:"data"`
+}
+
+// Attribute is a key and value attached to a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Span is a single operation in a trace.
+type Span struct {
+	ID         string
+	ParentID   string
+	Name       string
+	Service    string
+	Start      time.Time
+	End        time.Time
+	Error      bool
+	Attributes []Attribute
+	Children   []*Span
+
+	// Depth is the number of ancestors the span has in the trace.
+	Depth int
+	// Critical is whether the span is on the critical path of the trace.
+	Critical bool
+}
+
+// Duration returns how long the span took.
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trace is the spans of one trace, in the order of a depth-first walk from its roots.
+type Trace struct {
+	ID    string
+	Spans []*Span
+	Start time.Time
+	End   time.Time
+	// Errors is the number of spans that failed.
+	Errors int
+}
+
+// Duration returns how long the trace took.
+func (t *Trace) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// traces collects spans by trace.
+type traces struct {
+	order []string
+	spans map[string][]*Span
+}
+
+func newTraces() *traces {
+	return &traces{spans: map[string][]*Span{}}
+}
+
+func (t *traces) add(traceID string, s *Span) {
+	if _, ok := t.spans[traceID]; !ok {
+		t.order = append(t.order, traceID)
+	}
+	t.spans[traceID] = append(t.spans[traceID], s)
+}
+
+// read adds the spans in a stream of OTLP or Jaeger JSON documents.
+func (t *traces) read(content []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc struct {
+			otlpExport
+			jaegerExport
+		}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse trace: %v", err)
+		}
+		t.addOTLP(doc.otlpExport)
+		t.addJaeger(doc.jaegerExport)
+	}
+}
+
+func (t *traces) addOTLP(export otlpExport) {
+	for _, rs := range export.ResourceSpans {
+		service := ""
+		for _, a := range rs.Resource.Attributes {
+			if a.Key == "service.name" {
+				service = a.Value.String()
+			}
+		}
+		for _, ss := range append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...) {
+			for _, span := range ss.Spans {
+				s := &Span{
+					ID:       span.SpanID,
+					ParentID: span.ParentSpanID,
+					Name:     span.Name,
+					Service:  service,
+					Start:    time.Unix(0, int64(span.StartTimeUnixNano)),
+					End:      time.Unix(0, int64(span.EndTimeUnixNano)),
+				}
+				code := string(bytes.Trim(span.Status.Code, `"`))
+				s.Error = code == "2" || code == "STATUS_CODE_ERROR"
+				for _, a := range span.Attributes {
+					s.Attributes = append(s.Attributes, Attribute{Key: a.Key, Value: a.Value.String()})
+				}
+				if span.Status.Message != "" {
+					s.Attributes = append(s.Attributes, Attribute{Key: "status.message", Value: span.Status.Message})
+				}
+				t.add(span.TraceID, s)
+			}
+		}
+	}
+}
+
+func (t *traces) addJaeger(export jaegerExport) {
+	for _, data := range export.Data {
+		for _, js := range data.Spans {
+			start := time.Unix(0, int64(js.StartTime)*int64(time.Microsecond))
+			s := &Span{
+				ID:      js.SpanID,
+				Name:    js.OperationName,
+				Service: data.Processes[js.ProcessID].ServiceName,
+				Start:   start,
+				End:     start.Add(time.Duration(js.Duration) * time.Microsecond),
+			}
+			for _, ref := range js.References {
+				if ref.RefType == "CHILD_OF" || s.ParentID == "" {
+					s.ParentID = ref.SpanID
+				}
+			}
+			for _, tag := range js.Tags {
+				value := fmt.Sprint(tag.Value)
+				if tag.Key == "error" && value == "true" {
+					s.Error = true
+				}
+				s.Attributes = append(s.Attributes, Attribute{Key: tag.Key, Value: value})
+			}
+			traceID := js.TraceID
+			if traceID == "" {
+				traceID = data.TraceID
+			}
+			t.add(traceID, s)
+		}
+	}
+}
+
+// build arranges the spans of each trace into trees and finds their critical paths.
+func (t *traces) build() []*Trace {
+	var result []*Trace
+	for _, id := range t.order {
+		spans := t.spans[id]
+		byID := map[string]*Span{}
+		for _, s := range spans {
+			byID[s.ID] = s
+		}
+		var roots []*Span
+		for _, s := range spans {
+			// Spans whose parents weren't exported are shown as roots.
+			if parent, ok := byID[s.ParentID]; ok && s.ParentID != "" && parent != s {
+				parent.Children = append(parent.Children, s)
+			} else {
+				roots = append(roots, s)
+			}
+		}
+		trace := &Trace{ID: id}
+		byStart := func(spans []*Span) {
+			sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+		}
+		byStart(roots)
+		visited := map[*Span]bool{}
+		var walk func(s *Span, depth int)
+		walk = func(s *Span, depth int) {
+			visited[s] = true
+			s.Depth = depth
+			trace.Spans = append(trace.Spans, s)
+			byStart(s.Children)
+			for _, c := range s.Children {
+				if !visited[c] {
+					walk(c, depth+1)
+				}
+			}
+		}
+		for _, r := range roots {
+			walk(r, 0)
+		}
+		// Spans whose parents form a cycle are unreachable from any root.
+		for _, s := range spans {
+			if !visited[s] {
+				roots = append(roots, s)
+				walk(s, 0)
+			}
+		}
+		for _, s := range trace.Spans {
+			if trace.Start.IsZero() || s.Start.Before(trace.Start) {
+				trace.Start = s.Start
+			}
+			if s.End.After(trace.End) {
+				trace.End = s.End
+			}
+			if s.Error {
+				trace.Errors++
+			}
+		}
+		for _, r := range roots {
+			markCritical(r)
+		}
+		result = append(result, trace)
+	}
+	return result
+}
+
+// markCritical marks the spans that the end of s waited on: working back from its end, the
+// child that finished last, then whichever child finished last before that one started, and
+// so on, along with the same for each of them.
+func markCritical(s *Span) {
+	if s.Critical {
+		return
+	}
+	s.Critical = true
+	children := append([]*Span(nil), s.Children...)
+	sort.SliceStable(children, func(i, j int) bool { return children[i].End.After(children[j].End) })
+	cursor := s.End
+	for _, c := range children {
+		if c.End.After(cursor) && c != children[0] {
+			continue
+		}
+		markCritical(c)
+		cursor = c.Start
+	}
+}
@@ -0,0 +1,13 @@
+window.addEventListener('DOMContentLoaded', () => {
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+  for (const checkbox of Array.from(document.querySelectorAll<HTMLInputElement>('.trace-critical-only'))) {
+    checkbox.addEventListener('change', () => {
+      const trace = checkbox.closest('.trace');
+      if (trace) {
+        trace.classList.toggle('critical-only', checkbox.checked);
+        spyglass.contentUpdated();
+      }
+    });
+  }
+});
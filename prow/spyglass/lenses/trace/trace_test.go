@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"testing"
+	"time"
+)
+
+// otlpTrace is a root span whose end waits on b, which waits on d, and which started b after e.
+const otlpTrace = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"e2e"}}]},"scopeSpans":[{"spans":[
+{"traceId":"t1","spanId":"root","name":"test","startTimeUnixNano":"1000000000","endTimeUnixNano":"1100000000"},
+{"traceId":"t1","spanId":"a","parentSpanId":"root","name":"a","startTimeUnixNano":"1000000000","endTimeUnixNano":"1030000000"},
+{"traceId":"t1","spanId":"b","parentSpanId":"root","name":"b","startTimeUnixNano":"1010000000","endTimeUnixNano":"1090000000","status":{"code":2,"message":"boom"}},
+{"traceId":"t1","spanId":"c","parentSpanId":"root","name":"c","startTimeUnixNano":"1040000000","endTimeUnixNano":"1060000000"},
+{"traceId":"t1","spanId":"d","parentSpanId":"b","name":"d","startTimeUnixNano":"1020000000","endTimeUnixNano":"1085000000","attributes":[{"key":"attempt","value":{"intValue":"3"}}]},
+{"traceId":"t1","spanId":"e","parentSpanId":"root","name":"e","startTimeUnixNano":"1000000000","endTimeUnixNano":"1005000000"}
+]}]}]}
+{"resourceSpans":[{"instrumentationLibrarySpans":[{"spans":[
+{"traceId":"t2","spanId":"x","parentSpanId":"y","name":"x","startTimeUnixNano":"0","endTimeUnixNano":"10"},
+{"traceId":"t2","spanId":"y","parentSpanId":"x","name":"y","startTimeUnixNano":"0","endTimeUnixNano":"10"}
+]}]}]}`
+
+const jaegerTrace = `{"data":[{"traceID":"j1","spans":[
+{"traceID":"j1","spanID":"1","operationName":"GET /","startTime":1000,"duration":500,"processID":"p1","tags":[{"key":"error","value":true}]},
+{"traceID":"j1","spanID":"2","operationName":"query","references":[{"refType":"CHILD_OF","spanID":"1"}],"startTime":1100,"duration":200,"processID":"p2"}
+],"processes":{"p1":{"serviceName":"frontend"},"p2":{"serviceName":"db"}}}]}`
+
+func names(spans []*Span, include func(*Span) bool) []string {
+	var result []string
+	for _, s := range spans {
+		if include(s) {
+			result = append(result, s.Name)
+		}
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOTLP(t *testing.T) {
+	tr := newTraces()
+	if err := tr.read([]byte(otlpTrace)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	traces := tr.build()
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(traces))
+	}
+	trace := traces[0]
+	all := func(*Span) bool { return true }
+	if order := names(trace.Spans, all); !equal(order, []string{"test", "a", "e", "b", "d", "c"}) {
+		t.Errorf("expected spans in depth-first order of start, got %v", order)
+	}
+	if critical := names(trace.Spans, func(s *Span) bool { return s.Critical }); !equal(critical, []string{"test", "e", "b", "d"}) {
+		t.Errorf("expected critical path test, e, b, d, got %v", critical)
+	}
+	if trace.Duration() != 100*time.Millisecond || trace.Errors != 1 {
+		t.Errorf("expected 100ms with 1 error, got %s with %d", trace.Duration(), trace.Errors)
+	}
+	d := trace.Spans[4]
+	if d.Depth != 2 || d.Service != "e2e" || len(d.Attributes) != 1 || d.Attributes[0].Value != "3" {
+		t.Errorf("unexpected span d: %#v", d)
+	}
+
+	if cycle := names(traces[1].Spans, all); !equal(cycle, []string{"x", "y"}) {
+		t.Errorf("expected spans with cyclic parents to be shown, got %v", cycle)
+	}
+}
+
+func TestJaeger(t *testing.T) {
+	tr := newTraces()
+	if err := tr.read([]byte(jaegerTrace)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	traces := tr.build()
+	if len(traces) != 1 || len(traces[0].Spans) != 2 {
+		t.Fatalf("expected 1 trace of 2 spans, got %v", traces)
+	}
+	root, child := traces[0].Spans[0], traces[0].Spans[1]
+	if root.Service != "frontend" || !root.Error || root.Duration() != 500*time.Microsecond {
+		t.Errorf("unexpected root span: %#v", root)
+	}
+	if child.Depth != 1 || child.Service != "db" || child.Start.Sub(root.Start) != 100*time.Microsecond {
+		t.Errorf("unexpected child span: %#v", child)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	if err := newTraces().read([]byte(`{"resourceSpans": [`)); err == nil {
+		t.Error("expected an error for a truncated trace")
+	}
+}
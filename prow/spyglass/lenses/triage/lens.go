@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package triage provides a Spyglass lens that recognizes failures caused by known issues.
+package triage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+const (
+	name     = "triage"
+	title    = "Known Issues"
+	priority = 30
+
+	// defaultMaxLogBytes is how much of each log is searched if not configured.
+	defaultMaxLogBytes = 20 << 20
+)
+
+// Lens is the implementation of a known issue-matching Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config. Signatures from
+// every source are used together.
+type config struct {
+	// Signatures are known issues listed in the configuration itself.
+	Signatures []Signature `json:"signatures,omitempty"`
+	// SignaturesFile is the path to a YAML list of signatures, such as one mounted from a
+	// ConfigMap.
+	SignaturesFile string `json:"signatures_file,omitempty"`
+	// SignaturesURL is an http(s) or gs:// URL of a YAML list of signatures.
+	SignaturesURL string `json:"signatures_url,omitempty"`
+	// MaxLogBytes is how much of each log is searched. Defaults to 20MiB.
+	MaxLogBytes int64 `json:"max_log_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+type triageView struct {
+	Errors     []string
+	Signatures int
+	Issues     []Issue
+	// Unmatched lists the failed tests that matched no known issue.
+	Unmatched []string
+}
+
+// Body lists the known issues whose signatures appear in the logs and junit failures.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := triageView{}
+	conf := config{MaxLogBytes: defaultMaxLogBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxLogBytes <= 0 {
+		conf.MaxLogBytes = defaultMaxLogBytes
+	}
+
+	all := conf.Signatures
+	if conf.SignaturesFile != "" {
+		signatures, err := cache.load(conf.SignaturesFile, readFile)
+		if err != nil {
+			view.Errors = append(view.Errors, err.Error())
+		}
+		all = append(all, signatures...)
+	}
+	if conf.SignaturesURL != "" {
+		signatures, err := cache.load(conf.SignaturesURL, fetchURL)
+		if err != nil {
+			view.Errors = append(view.Errors, err.Error())
+		}
+		all = append(all, signatures...)
+	}
+	signatures, problems := compile(all)
+	view.Errors = append(view.Errors, problems...)
+	view.Signatures = len(signatures)
+
+	m := newMatcher(signatures)
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].JobPath() < artifacts[j].JobPath() })
+	for _, a := range artifacts {
+		if strings.HasSuffix(a.JobPath(), ".xml") {
+			content, err := a.ReadAll()
+			if err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err))
+				continue
+			}
+			suites, err := junit.Parse(content)
+			if err != nil {
+				view.Errors = append(view.Errors, fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err))
+				continue
+			}
+			m.scanJUnit(suites)
+			continue
+		}
+		r, err := lenses.NewChunkedReader(a, conf.MaxLogBytes)
+		if err == nil {
+			err = m.scanLog(a.JobPath(), r)
+		}
+		if err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Failed to search %s: %v", a.JobPath(), err))
+		}
+	}
+	view.Issues = m.found()
+	view.Unmatched = m.unmatched
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
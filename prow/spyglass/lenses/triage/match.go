@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triage
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+const (
+	// maxExamples is the number of matches kept for each known issue.
+	maxExamples = 5
+	// maxExampleLength is how much of a matching line or failure is kept.
+	maxExampleLength = 500
+	// maxLineLength is the length of the longest log line that can be searched.
+	maxLineLength = 1 << 20
+)
+
+// Match is a place a known issue's signature was found.
+type Match struct {
+	// Source is the log or junit test that matched.
+	Source string
+	// Line is the line number of the match in a log, or 0 for a junit test.
+	Line int
+	Text string
+}
+
+// Issue is a known issue that matched, and where.
+type Issue struct {
+	Signature
+	// Count is the number of log lines and tests that matched.
+	Count    int
+	Examples []Match
+}
+
+// matcher finds the known issues in logs and junit results.
+type matcher struct {
+	signatures []signature
+	issues     []*Issue
+	// unmatched lists failed tests that matched no known issue.
+	unmatched []string
+}
+
+func newMatcher(signatures []signature) *matcher {
+	m := &matcher{signatures: signatures}
+	for _, s := range signatures {
+		m.issues = append(m.issues, &Issue{Signature: s.Signature})
+	}
+	return m
+}
+
+func (m *matcher) record(i int, match Match) {
+	issue := m.issues[i]
+	issue.Count++
+	if len(issue.Examples) < maxExamples {
+		match.Text = truncate(strings.TrimSpace(match.Text))
+		issue.Examples = append(issue.Examples, match)
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > maxExampleLength {
+		return s[:maxExampleLength] + "..."
+	}
+	return s
+}
+
+// scanLog matches each line of a log against the signatures.
+func (m *matcher) scanLog(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		for i, s := range m.signatures {
+			if s.re.MatchString(text) {
+				m.record(i, Match{Source: name, Line: line, Text: text})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanJUnit matches the failure message of each failed test against the signatures.
+func (m *matcher) scanJUnit(suites junit.Suites) {
+	for _, suite := range suites.Suites {
+		for _, result := range suite.Results {
+			if result.Failure == nil {
+				continue
+			}
+			name := result.Name
+			if result.ClassName != "" {
+				name = result.ClassName + " " + name
+			}
+			matched := false
+			for i, s := range m.signatures {
+				if loc := s.re.FindStringIndex(*result.Failure); loc != nil {
+					m.record(i, Match{Source: name, Text: matchingLine(*result.Failure, loc)})
+					matched = true
+				}
+			}
+			if !matched {
+				m.unmatched = append(m.unmatched, name)
+			}
+		}
+	}
+}
+
+// matchingLine returns the line of text containing the start of the match at loc.
+func matchingLine(text string, loc []int) string {
+	start := strings.LastIndex(text[:loc[0]], "\n") + 1
+	end := strings.Index(text[loc[0]:], "\n")
+	if end < 0 {
+		return text[start:]
+	}
+	return text[start : loc[0]+end]
+}
+
+// found returns the known issues that matched, most frequent first.
+func (m *matcher) found() []Issue {
+	var result []Issue
+	for _, issue := range m.issues {
+		if issue.Count > 0 {
+			result = append(result, *issue)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result
+}
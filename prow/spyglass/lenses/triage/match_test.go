@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triage
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/testgrid/metadata/junit"
+)
+
+func TestMatcher(t *testing.T) {
+	signatures, problems := compile([]Signature{
+		{Issue: "https://github.com/kubernetes/kubernetes/issues/1234", Title: "DNS flake", Pattern: `lookup \S+ on .*: no such host`},
+		{Issue: "https://github.com/kubernetes/test-infra/issues/99", Pattern: `quota exceeded`},
+		{Issue: "https://example.com/never", Pattern: `never matches`},
+	})
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	m := newMatcher(signatures)
+	log := "starting\ndial tcp: lookup api.example.com on 10.0.0.10:53: no such host\nok\nlookup foo on 10.0.0.10:53: no such host\n"
+	if err := m.scanLog("build-log.txt", strings.NewReader(log)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	quota := "Error creating cluster:\nGCE quota exceeded for CPUS\nat main.go:12"
+	other := "expected 1, got 2"
+	m.scanJUnit(junit.Suites{Suites: []junit.Suite{{Results: []junit.Result{
+		{Name: "Create", ClassName: "Up", Failure: &quota},
+		{Name: "Passes"},
+		{Name: "Compare", Failure: &other},
+	}}}})
+
+	issues := m.found()
+	if len(issues) != 2 || issues[0].Name() != "#1234" || issues[1].Name() != "#99" {
+		t.Fatalf("expected #1234 then #99, got %v", issues)
+	}
+	if issues[0].Count != 2 || issues[0].Examples[0].Line != 2 || issues[0].Examples[1].Line != 4 {
+		t.Errorf("expected #1234 to match lines 2 and 4, got %v", issues[0].Examples)
+	}
+	if example := issues[1].Examples[0]; example.Source != "Up Create" || example.Text != "GCE quota exceeded for CPUS" {
+		t.Errorf("expected the matching line of the failure, got %#v", example)
+	}
+	if len(m.unmatched) != 1 || m.unmatched[0] != "Compare" {
+		t.Errorf("expected Compare to be unmatched, got %v", m.unmatched)
+	}
+}
+
+func TestCompileProblems(t *testing.T) {
+	signatures, problems := compile([]Signature{
+		{Issue: "a", Pattern: `(`},
+		{Issue: "b"},
+		{Issue: "c", Pattern: `c`},
+	})
+	if len(signatures) != 1 || len(problems) != 2 {
+		t.Errorf("expected 1 valid signature and 2 problems, got %d and %v", len(signatures), problems)
+	}
+}
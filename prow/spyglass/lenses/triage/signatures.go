@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// cacheTTL is how long signatures loaded from a file or URL are used before being reloaded.
+	cacheTTL = 5 * time.Minute
+	// fetchTimeout bounds how long loading signatures from a URL may take.
+	fetchTimeout = 30 * time.Second
+)
+
+// Signature identifies failures caused by a known issue.
+type Signature struct {
+	// Issue is a link to the issue tracking the failure.
+	Issue string `json:"issue"`
+	// Title briefly describes the failure.
+	Title string `json:"title,omitempty"`
+	// Pattern is a regular expression matched against each line of logs and against the
+	// failure messages of junit tests.
+	Pattern string `json:"pattern"`
+}
+
+// signature is a Signature with its pattern compiled.
+type signature struct {
+	Signature
+	re *regexp.Regexp
+}
+
+// issueNumberRE finds the number of a GitHub issue or pull request in its URL.
+var issueNumberRE = regexp.MustCompile(`/(?:issues|pull)/(\d+)/?$`)
+
+// Name returns a short name for the issue, such as #1234 for a GitHub issue.
+func (s Signature) Name() string {
+	if m := issueNumberRE.FindStringSubmatch(s.Issue); m != nil {
+		return "#" + m[1]
+	}
+	return s.Issue
+}
+
+// compile compiles the patterns of signatures, returning those that are valid and a
+// description of the problem with each that is not.
+func compile(signatures []Signature) ([]signature, []string) {
+	var result []signature
+	var problems []string
+	for _, s := range signatures {
+		if s.Pattern == "" {
+			problems = append(problems, fmt.Sprintf("Signature for %s has no pattern.", s.Issue))
+			continue
+		}
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Signature for %s has an invalid pattern: %v", s.Issue, err))
+			continue
+		}
+		result = append(result, signature{Signature: s, re: re})
+	}
+	return result, problems
+}
+
+// parseSignatures reads a YAML or JSON list of signatures.
+func parseSignatures(content []byte) ([]Signature, error) {
+	var signatures []Signature
+	if err := yaml.Unmarshal(content, &signatures); err != nil {
+		return nil, fmt.Errorf("failed to parse signatures: %v", err)
+	}
+	return signatures, nil
+}
+
+// readFile loads signatures from a file, such as one mounted from a ConfigMap.
+func readFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// fetchURL loads signatures from an http(s) URL. gs:// URLs are fetched from the public GCS
+// endpoint, so the object must be publicly readable.
+func fetchURL(url string) ([]byte, error) {
+	if strings.HasPrefix(url, "gs://") {
+		url = "https://storage.googleapis.com/" + strings.TrimPrefix(url, "gs://")
+	}
+	client := http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+type cacheEntry struct {
+	signatures []Signature
+	err        error
+	loaded     time.Time
+}
+
+// signatureCache holds signatures loaded from files and URLs, so that they are not reloaded
+// every time the lens is rendered.
+type signatureCache struct {
+	lock    sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+var cache = &signatureCache{entries: map[string]cacheEntry{}, now: time.Now}
+
+// load returns the signatures at location, loading them with read if they have not been
+// loaded recently. If reloading fails, the signatures loaded last are returned with the error.
+func (c *signatureCache) load(location string, read func(string) ([]byte, error)) ([]Signature, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[location]
+	if ok && c.now().Sub(entry.loaded) < cacheTTL {
+		return entry.signatures, entry.err
+	}
+	content, err := read(location)
+	if err == nil {
+		var signatures []Signature
+		signatures, err = parseSignatures(content)
+		if err == nil {
+			entry.signatures = signatures
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to load signatures from %s: %v", location, err)
+	}
+	entry.err = err
+	entry.loaded = c.now()
+	c.entries[location] = entry
+	return entry.signatures, entry.err
+}
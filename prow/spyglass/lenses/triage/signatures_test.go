@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheLoad(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := &signatureCache{entries: map[string]cacheEntry{}, now: func() time.Time { return now }}
+	reads := 0
+	content := "- issue: https://github.com/org/repo/issues/1\n  pattern: boom\n"
+	var readErr error
+	read := func(string) ([]byte, error) {
+		reads++
+		return []byte(content), readErr
+	}
+
+	signatures, err := c.load("file", read)
+	if err != nil || len(signatures) != 1 || signatures[0].Pattern != "boom" {
+		t.Fatalf("expected one signature, got %v and %v", signatures, err)
+	}
+	c.load("file", read)
+	if reads != 1 {
+		t.Errorf("expected signatures to be cached, but read %d times", reads)
+	}
+
+	now = now.Add(cacheTTL)
+	readErr = errors.New("unavailable")
+	signatures, err = c.load("file", read)
+	if reads != 2 || err == nil || len(signatures) != 1 {
+		t.Errorf("expected the old signatures and an error after a failed reload, got %v and %v", signatures, err)
+	}
+
+	now = now.Add(cacheTTL)
+	readErr = nil
+	content = "not: a list"
+	if _, err := c.load("file", read); err == nil {
+		t.Error("expected an error for a malformed signature list")
+	}
+}
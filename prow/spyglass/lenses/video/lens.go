@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package video provides a Spyglass lens that plays back video artifacts, such as browser test recordings.
+package video
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "video"
+	title    = "Videos"
+	priority = 12
+)
+
+// videoExtensions are the extensions of the artifacts that are played back.
+var videoExtensions = map[string]bool{".mp4": true, ".m4v": true, ".webm": true, ".ogv": true}
+
+// Lens is the implementation of a video-playing Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// Video is a single playable artifact.
+type Video struct {
+	Name string
+	Path string
+	Link string
+	Size string
+}
+
+// Body renders a player for each video. The players stream from the artifact proxy, which
+// supports byte ranges, so seeking doesn't require downloading the whole file.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	var videos []Video
+	for _, a := range artifacts {
+		if !isVideo(a.JobPath()) {
+			continue
+		}
+		v := Video{
+			Name: path.Base(a.JobPath()),
+			Path: a.JobPath(),
+			Link: a.CanonicalLink(),
+		}
+		if size, err := a.Size(); err != nil {
+			logrus.WithError(err).WithField("artifact", a.JobPath()).Info("Failed to get video size.")
+		} else {
+			v.Size = formatSize(size)
+		}
+		videos = append(videos, v)
+	}
+	sort.Slice(videos, func(i, j int) bool { return videos[i].Path < videos[j].Path })
+	return executeTemplate(resourceDir, "body", videos)
+}
+
+// isVideo returns whether the named artifact is a video that can be played back.
+func isVideo(name string) bool {
+	return videoExtensions[strings.ToLower(path.Ext(name))]
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	f := float64(size)
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		f /= unit
+		if f < unit {
+			return fmt.Sprintf("%.1f %s", f, suffix)
+		}
+	}
+	return fmt.Sprintf("%.1f TiB", f/unit)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package video
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func TestIsVideo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "artifacts/recording.mp4", expected: true},
+		{name: "artifacts/recording.m4v", expected: true},
+		{name: "artifacts/recording.webm", expected: true},
+		{name: "artifacts/recording.ogv", expected: true},
+		{name: "artifacts/RECORDING.WEBM", expected: true},
+		{name: "artifacts/recording.mp4.log"},
+		{name: "artifacts/screenshot.png"},
+		{name: "artifacts/mp4"},
+	}
+	for _, tc := range testCases {
+		if actual := isVideo(tc.name); actual != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/b.webm", Content: make([]byte, 2048)},
+		&fakeartifact.Artifact{Path: "artifacts/a.mp4", Content: make([]byte, 10)},
+		&fakeartifact.Artifact{Path: "artifacts/missing.mp4", SizeErr: errors.New("not found")},
+		&fakeartifact.Artifact{Path: "artifacts/build-log.txt", Content: []byte("log")},
+	}
+	body := (Lens{}).Body(artifacts, ".", "", nil)
+
+	a, b := strings.Index(body, `data-artifact="artifacts/a.mp4"`), strings.Index(body, `data-artifact="artifacts/b.webm"`)
+	if a == -1 || b == -1 {
+		t.Fatalf("expected players for both videos, got %s", body)
+	}
+	if a > b {
+		t.Errorf("expected videos to be sorted by path")
+	}
+	for _, expected := range []string{
+		`href="https://example.com/artifacts/a.mp4"`,
+		`href="https://example.com/artifacts/b.webm"`,
+		`href="https://example.com/artifacts/missing.mp4"`,
+		`<span class="video-size">10 B</span>`,
+		`<span class="video-size">2.0 KiB</span>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %s in the body, got %s", expected, body)
+		}
+	}
+	if strings.Count(body, `class="video-size"`) != 2 {
+		t.Errorf("expected no size for the video whose size is unknown, got %s", body)
+	}
+	if strings.Contains(body, "build-log.txt") {
+		t.Errorf("expected artifacts that aren't videos to be ignored, got %s", body)
+	}
+}
+
+func TestBodyWithoutVideos(t *testing.T) {
+	artifacts := []lenses.Artifact{
+		&fakeartifact.Artifact{Path: "artifacts/build-log.txt", Content: []byte("log")},
+	}
+	body := (Lens{}).Body(artifacts, ".", "", nil)
+	if !strings.Contains(body, "No videos were found.") {
+		t.Errorf("expected a message when there are no videos, got %s", body)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 1023, expected: "1023 B"},
+		{size: 1536, expected: "1.5 KiB"},
+		{size: 5 << 20, expected: "5.0 MiB"},
+		{size: 3 << 30, expected: "3.0 GiB"},
+		{size: 2 << 40, expected: "2.0 TiB"},
+	}
+	for _, tc := range testCases {
+		if actual := formatSize(tc.size); actual != tc.expected {
+			t.Errorf("%d: expected %q, got %q", tc.size, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+window.addEventListener('DOMContentLoaded', () => {
+  for (const video of Array.from(document.querySelectorAll<HTMLVideoElement>('video[data-artifact]'))) {
+    // The frame needs resizing once we know the dimensions of the video.
+    video.addEventListener('loadedmetadata', () => spyglass.contentUpdated());
+    video.src = spyglass.artifactURL(video.dataset.artifact!);
+  }
+});
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webtests
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	kindScreenshot = "screenshot"
+	kindVideo      = "video"
+	kindTrace      = "trace"
+)
+
+var (
+	// screenshotSuffixRE matches what Cypress appends to the names of screenshots it takes of
+	// failures, such as " (failed)" or " (attempt 2)".
+	screenshotSuffixRE = regexp.MustCompile(`( \((failed|attempt \d+)\))+$`)
+	imageExtensions    = map[string]bool{".png": true, ".jpg": true, ".jpeg": true}
+	videoExtensions    = map[string]bool{".mp4": true, ".webm": true}
+)
+
+// Attachment is an artifact holding a test's screenshot, video, trace or other attachment.
+type Attachment struct {
+	Name string
+	Kind string
+	Path string
+	Link string
+}
+
+// attachmentIndex finds the artifacts that tests' attachments were uploaded as.
+type attachmentIndex struct {
+	// bySuffix has each artifact by the last one or more elements of its path.
+	bySuffix map[string]lenses.Artifact
+	// conflicts are the suffixes of more than one artifact.
+	conflicts map[string]bool
+	artifacts []lenses.Artifact
+}
+
+func newAttachmentIndex(artifacts []lenses.Artifact) *attachmentIndex {
+	idx := &attachmentIndex{bySuffix: map[string]lenses.Artifact{}, conflicts: map[string]bool{}, artifacts: artifacts}
+	for _, a := range artifacts {
+		parts := strings.Split(a.JobPath(), "/")
+		for i := range parts {
+			suffix := strings.Join(parts[i:], "/")
+			if _, ok := idx.bySuffix[suffix]; ok {
+				idx.conflicts[suffix] = true
+			}
+			idx.bySuffix[suffix] = a
+		}
+	}
+	return idx
+}
+
+// find returns the artifact whose path has the longest unambiguous suffix in common with a path on
+// the machine that ran the tests. Attachments are usually in directories named for their tests
+// with names such as "test-failed-1.png", so the file name alone is not enough to match.
+func (idx *attachmentIndex) find(p string) lenses.Artifact {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	minimum := 2
+	if len(parts) < minimum {
+		minimum = len(parts)
+	}
+	for i := 0; i <= len(parts)-minimum; i++ {
+		suffix := strings.Join(parts[i:], "/")
+		if a, ok := idx.bySuffix[suffix]; ok && !idx.conflicts[suffix] {
+			return a
+		}
+	}
+	return nil
+}
+
+// attach finds the artifacts holding a test's attachments. Playwright reports where it wrote
+// them; Cypress does not, but names screenshots of failures after the test and videos after the
+// test's file.
+func (idx *attachmentIndex) attach(t *Test, tool string) {
+	for _, raw := range t.attachments {
+		a := idx.find(raw.path)
+		if a == nil {
+			continue
+		}
+		name := raw.name
+		if raw.retry > 0 {
+			name = fmt.Sprintf("%s (retry %d)", name, raw.retry)
+		}
+		t.Attachments = append(t.Attachments, newAttachment(a, name, kindOf(raw.name)))
+	}
+	if tool != toolCypress || t.File == "" {
+		return
+	}
+	title := normalize(strings.Join(t.Titles, " "))
+	file := path.Base(t.File)
+	for _, a := range idx.artifacts {
+		name := path.Base(a.JobPath())
+		ext := strings.ToLower(path.Ext(name))
+		base := strings.TrimSuffix(name, path.Ext(name))
+		switch {
+		case imageExtensions[ext] && strings.Contains(a.JobPath(), "/"+file+"/"):
+			// Long names are truncated, so a prefix of the title will do.
+			shot := normalize(screenshotSuffixRE.ReplaceAllString(base, ""))
+			if shot == title || len(shot) >= 40 && strings.HasPrefix(title, shot) {
+				t.Attachments = append(t.Attachments, newAttachment(a, name, kindScreenshot))
+			}
+		case videoExtensions[ext] && base == file:
+			t.Attachments = append(t.Attachments, newAttachment(a, name, kindVideo))
+		}
+	}
+}
+
+func newAttachment(a lenses.Artifact, name, kind string) Attachment {
+	return Attachment{Name: name, Kind: kind, Path: a.JobPath(), Link: a.CanonicalLink()}
+}
+
+// kindOf returns the kind of a Playwright attachment from its name.
+func kindOf(name string) string {
+	switch name {
+	case kindScreenshot, kindVideo, kindTrace:
+		return name
+	}
+	return ""
+}
+
+// normalize drops everything but letters and digits from a title, as Cypress replaces some
+// characters in the names of screenshots and the mocha JSON reporter joins titles with spaces
+// rather than the " -- " Cypress uses.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webtests
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+	"k8s.io/test-infra/prow/spyglass/lenses/fakeartifact"
+)
+
+func artifacts(paths ...string) []lenses.Artifact {
+	var result []lenses.Artifact
+	for _, p := range paths {
+		result = append(result, &fakeartifact.Artifact{Path: p})
+	}
+	return result
+}
+
+func attachmentPaths(t *Test) []string {
+	var paths []string
+	for _, a := range t.Attachments {
+		paths = append(paths, a.Kind+" "+a.Path)
+	}
+	return paths
+}
+
+func TestAttachPlaywright(t *testing.T) {
+	report, err := parseReport([]byte(playwrightJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	idx := newAttachmentIndex(artifacts(
+		"artifacts/test-results/login-with-a-bad-password-shows-an-error-firefox/test-failed-1.png",
+		"artifacts/test-results/login-with-a-bad-password-shows-an-error-firefox/trace.zip",
+		"artifacts/test-results/login-shows-the-form-chromium/test-failed-1.png",
+		"artifacts/junit.xml",
+	))
+	for _, test := range report.Tests {
+		idx.attach(test, report.Tool)
+	}
+	expected := []string{
+		"screenshot artifacts/test-results/login-with-a-bad-password-shows-an-error-firefox/test-failed-1.png",
+		"trace artifacts/test-results/login-with-a-bad-password-shows-an-error-firefox/trace.zip",
+	}
+	if paths := attachmentPaths(report.Tests[1]); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+	if paths := attachmentPaths(report.Tests[0]); len(paths) != 0 {
+		t.Errorf("expected the passing test to have no attachments, got %v", paths)
+	}
+}
+
+func TestAttachCypress(t *testing.T) {
+	siblings := artifacts(
+		"artifacts/cypress/screenshots/login.cy.js/Login -- rejects a bad password (failed).png",
+		"artifacts/cypress/screenshots/login.cy.js/Login -- rejects a bad password (failed) (attempt 2).png",
+		"artifacts/cypress/screenshots/signup.cy.js/Login -- rejects a bad password (failed).png",
+		"artifacts/cypress/videos/login.cy.js.mp4",
+		"artifacts/cypress/videos/signup.cy.js.mp4",
+	)
+	for _, report := range []string{mochawesomeJSON, mochaJSON} {
+		r, err := parseReport([]byte(report))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		idx := newAttachmentIndex(siblings)
+		for _, test := range r.Tests {
+			idx.attach(test, r.Tool)
+		}
+		expected := []string{
+			"screenshot artifacts/cypress/screenshots/login.cy.js/Login -- rejects a bad password (failed).png",
+			"screenshot artifacts/cypress/screenshots/login.cy.js/Login -- rejects a bad password (failed) (attempt 2).png",
+			"video artifacts/cypress/videos/login.cy.js.mp4",
+		}
+		if paths := attachmentPaths(r.Tests[1]); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("expected %v, got %v", expected, paths)
+		}
+	}
+}
+
+func TestFindAmbiguousSuffix(t *testing.T) {
+	idx := newAttachmentIndex(artifacts("a/results/test-failed-1.png", "b/results/test-failed-1.png"))
+	if a := idx.find("/src/results/test-failed-1.png"); a != nil {
+		t.Errorf("expected no match for an ambiguous path, got %s", a.JobPath())
+	}
+	if a := idx.find("/src/b/results/test-failed-1.png"); a == nil || a.JobPath() != "b/results/test-failed-1.png" {
+		t.Errorf("expected b/results/test-failed-1.png, got %v", a)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webtests provides a Spyglass lens that renders Playwright and Cypress JSON reports,
+// linking each failed test to its screenshots, videos and traces among the job's artifacts.
+package webtests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "webtests"
+	title    = "Browser Tests"
+	priority = 42
+
+	// defaultMaxBytes is how much of each report is read if not configured.
+	defaultMaxBytes = 100 << 20
+)
+
+// Lens is the implementation of a browser test report-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// config is the lens's configuration, read from the Spyglass lens config.
+type config struct {
+	// MaxBytes is how much of each report is read. Defaults to 100MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Config returns the lens's configuration. It asks for the job's other artifacts, which hold the
+// tests' attachments.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+		Siblings: true,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, rawConfig json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	return ""
+}
+
+// StatusCount is the number of tests with a status.
+type StatusCount struct {
+	Status string
+	Count  int
+}
+
+// TestView is a test with its duration formatted for display.
+type TestView struct {
+	*Test
+	Time string
+}
+
+// FileView is the tests of one file.
+type FileView struct {
+	Name   string
+	Failed bool
+	Tests  []TestView
+}
+
+// ReportView is the tests of one report.
+type ReportView struct {
+	Name     string
+	Link     string
+	Tool     string
+	Error    string
+	Counts   []StatusCount
+	Failures []TestView
+	Files    []FileView
+}
+
+type webTestsView struct {
+	Errors  []string
+	Reports []ReportView
+}
+
+// Body renders the failed tests of each report with their attachments, followed by every test
+// grouped by file.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, rawConfig json.RawMessage) string {
+	view := webTestsView{}
+	conf := config{MaxBytes: defaultMaxBytes}
+	if len(rawConfig) != 0 {
+		if err := json.Unmarshal(rawConfig, &conf); err != nil {
+			view.Errors = append(view.Errors, fmt.Sprintf("Invalid lens configuration: %v", err))
+		}
+	}
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = defaultMaxBytes
+	}
+
+	reports, siblings := lenses.SplitSiblings(artifacts)
+	var others []lenses.Artifact
+	for _, s := range siblings {
+		others = append(others, s)
+	}
+	idx := newAttachmentIndex(others)
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].JobPath() < reports[j].JobPath() })
+	for _, a := range reports {
+		rv := ReportView{Name: a.JobPath(), Link: a.CanonicalLink()}
+		content, err := a.ReadAtMost(conf.MaxBytes)
+		if err != nil && err != io.EOF {
+			rv.Error = fmt.Sprintf("Failed to read %s: %v", a.JobPath(), err)
+			view.Reports = append(view.Reports, rv)
+			continue
+		}
+		report, err := parseReport(content)
+		if err != nil {
+			rv.Error = fmt.Sprintf("Failed to parse %s: %v", a.JobPath(), err)
+			view.Reports = append(view.Reports, rv)
+			continue
+		}
+		rv.Tool = report.Tool
+		counts := map[string]int{}
+		files := map[string]*FileView{}
+		var names []string
+		for _, t := range report.Tests {
+			idx.attach(t, report.Tool)
+			tv := TestView{Test: t, Time: t.Duration.Round(time.Millisecond).String()}
+			counts[t.Status]++
+			if t.Failed() {
+				rv.Failures = append(rv.Failures, tv)
+			}
+			f, ok := files[t.File]
+			if !ok {
+				f = &FileView{Name: t.File}
+				files[t.File] = f
+				names = append(names, t.File)
+			}
+			f.Tests = append(f.Tests, tv)
+			f.Failed = f.Failed || t.Status == statusFailed
+		}
+		for _, s := range statuses {
+			if counts[s] != 0 {
+				rv.Counts = append(rv.Counts, StatusCount{Status: s, Count: counts[s]})
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			rv.Files = append(rv.Files, *files[n])
+		}
+		view.Reports = append(view.Reports, rv)
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webtests
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	toolPlaywright = "Playwright"
+	toolCypress    = "Cypress"
+
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusFlaky   = "flaky"
+	statusSkipped = "skipped"
+)
+
+// statuses are the statuses of tests in the order they are summarized.
+var statuses = []string{statusFailed, statusFlaky, statusPassed, statusSkipped}
+
+// ansiRE matches the terminal colors Playwright puts in its error messages.
+var ansiRE = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Test is a test in a report. Playwright runs each test once for each of its projects.
+type Test struct {
+	File string
+	// Titles are the titles of the suites the test is in, followed by its own.
+	Titles   []string
+	Project  string
+	Status   string
+	Duration time.Duration
+	// Retries is how many times the test was retried.
+	Retries int
+	Error   string
+	Stack   string
+	// Attachments are the files the test's attachments were uploaded as.
+	Attachments []Attachment
+	// attachments are the paths of the test's attachments on the machine that ran it.
+	attachments []rawAttachment
+}
+
+// Title returns the test's titles joined.
+func (t *Test) Title() string {
+	return strings.Join(t.Titles, " › ")
+}
+
+// Failed reports whether the test failed, or failed before passing on a retry.
+func (t *Test) Failed() bool {
+	return t.Status == statusFailed || t.Status == statusFlaky
+}
+
+type rawAttachment struct {
+	name  string
+	path  string
+	retry int
+}
+
+// Report is the tests of a Playwright or Cypress run.
+type Report struct {
+	Tool  string
+	Tests []*Test
+}
+
+// parseReport parses a report written by Playwright's JSON reporter, or by Cypress with the
+// mochawesome or mocha JSON reporter.
+func parseReport(content []byte) (*Report, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, err
+	}
+	_, hasSuites := keys["suites"]
+	_, hasConfig := keys["config"]
+	_, hasResults := keys["results"]
+	_, hasStats := keys["stats"]
+	_, hasTests := keys["tests"]
+	switch {
+	case hasSuites && hasConfig:
+		return parsePlaywright(content)
+	case hasResults && hasStats:
+		return parseMochawesome(content)
+	case hasTests && hasStats:
+		return parseMochaJSON(content)
+	}
+	return nil, errors.New("not a Playwright or Cypress JSON report")
+}
+
+type playwrightReport struct {
+	Suites []playwrightSuite `json:"suites"`
+}
+
+type playwrightSuite struct {
+	Title  string            `json:"title"`
+	File   string            `json:"file"`
+	Specs  []playwrightSpec  `json:"specs"`
+	Suites []playwrightSuite `json:"suites"`
+}
+
+type playwrightSpec struct {
+	Title string           `json:"title"`
+	File  string           `json:"file"`
+	Tests []playwrightTest `json:"tests"`
+}
+
+type playwrightTest struct {
+	ProjectName    string             `json:"projectName"`
+	ExpectedStatus string             `json:"expectedStatus"`
+	Status         string             `json:"status"`
+	Results        []playwrightResult `json:"results"`
+}
+
+type playwrightResult struct {
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration"`
+	Retry    int     `json:"retry"`
+	Error    *struct {
+		Message string `json:"message"`
+		Stack   string `json:"stack"`
+	} `json:"error"`
+	Attachments []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"attachments"`
+}
+
+func parsePlaywright(content []byte) (*Report, error) {
+	var r playwrightReport
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, err
+	}
+	report := &Report{Tool: toolPlaywright}
+	for _, s := range r.Suites {
+		// The outermost suites are files, which are titled by their names.
+		report.addPlaywrightSuite(s, nil)
+	}
+	return report, nil
+}
+
+func (r *Report) addPlaywrightSuite(s playwrightSuite, titles []string) {
+	for _, spec := range s.Specs {
+		for _, pt := range spec.Tests {
+			t := &Test{
+				File:    spec.File,
+				Titles:  append(append([]string(nil), titles...), spec.Title),
+				Project: pt.ProjectName,
+			}
+			switch pt.Status {
+			case "expected":
+				t.Status = statusPassed
+				if pt.ExpectedStatus == statusSkipped {
+					t.Status = statusSkipped
+				}
+			case "unexpected":
+				t.Status = statusFailed
+			case "flaky":
+				t.Status = statusFlaky
+			default:
+				t.Status = statusSkipped
+			}
+			for _, result := range pt.Results {
+				t.Duration += time.Duration(result.Duration * float64(time.Millisecond))
+				if result.Retry > t.Retries {
+					t.Retries = result.Retry
+				}
+				if result.Error != nil {
+					t.Error = ansiRE.ReplaceAllString(result.Error.Message, "")
+					t.Stack = ansiRE.ReplaceAllString(result.Error.Stack, "")
+				}
+				for _, a := range result.Attachments {
+					// Small attachments can be inlined in the report instead.
+					if a.Path != "" {
+						t.attachments = append(t.attachments, rawAttachment{name: a.Name, path: a.Path, retry: result.Retry})
+					}
+				}
+			}
+			r.Tests = append(r.Tests, t)
+		}
+	}
+	for _, nested := range s.Suites {
+		r.addPlaywrightSuite(nested, append(append([]string(nil), titles...), nested.Title))
+	}
+}
+
+type mochaError struct {
+	Message string `json:"message"`
+	Stack   string `json:"stack"`
+	// EStack is the stack as mochawesome writes it.
+	EStack string `json:"estack"`
+}
+
+type mochawesomeReport struct {
+	Results []mochawesomeSuite `json:"results"`
+}
+
+type mochawesomeSuite struct {
+	Title  string             `json:"title"`
+	File   string             `json:"file"`
+	Tests  []mochawesomeTest  `json:"tests"`
+	Suites []mochawesomeSuite `json:"suites"`
+}
+
+type mochawesomeTest struct {
+	Title    string     `json:"title"`
+	Duration float64    `json:"duration"`
+	State    string     `json:"state"`
+	Pending  bool       `json:"pending"`
+	Skipped  bool       `json:"skipped"`
+	Err      mochaError `json:"err"`
+}
+
+func parseMochawesome(content []byte) (*Report, error) {
+	var r mochawesomeReport
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, err
+	}
+	report := &Report{Tool: toolCypress}
+	for _, s := range r.Results {
+		report.addMochawesomeSuite(s, "", nil)
+	}
+	return report, nil
+}
+
+func (r *Report) addMochawesomeSuite(s mochawesomeSuite, file string, titles []string) {
+	if s.File != "" {
+		file = s.File
+	}
+	// The suite of each file is untitled.
+	if s.Title != "" {
+		titles = append(append([]string(nil), titles...), s.Title)
+	}
+	for _, mt := range s.Tests {
+		t := &Test{
+			File:     file,
+			Titles:   append(append([]string(nil), titles...), mt.Title),
+			Duration: time.Duration(mt.Duration * float64(time.Millisecond)),
+			Error:    mt.Err.Message,
+			Stack:    mt.Err.EStack,
+		}
+		switch {
+		case mt.State == statusFailed:
+			t.Status = statusFailed
+		case mt.Pending || mt.Skipped || mt.State == "pending":
+			t.Status = statusSkipped
+		default:
+			t.Status = statusPassed
+		}
+		r.Tests = append(r.Tests, t)
+	}
+	for _, nested := range s.Suites {
+		r.addMochawesomeSuite(nested, file, titles)
+	}
+}
+
+type mochaJSONReport struct {
+	Tests   []mochaJSONTest `json:"tests"`
+	Pending []mochaJSONTest `json:"pending"`
+}
+
+type mochaJSONTest struct {
+	FullTitle string     `json:"fullTitle"`
+	File      string     `json:"file"`
+	Duration  float64    `json:"duration"`
+	Err       mochaError `json:"err"`
+}
+
+func parseMochaJSON(content []byte) (*Report, error) {
+	var r mochaJSONReport
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, err
+	}
+	pending := map[string]bool{}
+	for _, mt := range r.Pending {
+		pending[mt.FullTitle] = true
+	}
+	report := &Report{Tool: toolCypress}
+	for _, mt := range r.Tests {
+		// The mocha JSON reporter does not say which titles are the suites'.
+		t := &Test{
+			File:     mt.File,
+			Titles:   []string{mt.FullTitle},
+			Duration: time.Duration(mt.Duration * float64(time.Millisecond)),
+			Error:    mt.Err.Message,
+			Stack:    mt.Err.Stack,
+		}
+		switch {
+		case t.Error != "":
+			t.Status = statusFailed
+		case pending[mt.FullTitle]:
+			t.Status = statusSkipped
+		default:
+			t.Status = statusPassed
+		}
+		report.Tests = append(report.Tests, t)
+	}
+	return report, nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webtests
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const playwrightJSON = `{
+  "config": {"version": "1.40.0"},
+  "suites": [{
+    "title": "login.spec.ts",
+    "file": "login.spec.ts",
+    "specs": [{
+      "title": "shows the form",
+      "file": "login.spec.ts",
+      "tests": [{
+        "projectName": "chromium",
+        "expectedStatus": "passed",
+        "status": "expected",
+        "results": [{"status": "passed", "duration": 120, "retry": 0, "attachments": []}]
+      }]
+    }],
+    "suites": [{
+      "title": "with a bad password",
+      "file": "login.spec.ts",
+      "specs": [{
+        "title": "shows an error",
+        "file": "login.spec.ts",
+        "tests": [{
+          "projectName": "firefox",
+          "expectedStatus": "passed",
+          "status": "unexpected",
+          "results": [{
+            "status": "failed",
+            "duration": 1500,
+            "retry": 0,
+            "error": {"message": "\u001b[31mError:\u001b[39m expect(locator).toBeVisible()", "stack": "Error: expect(locator).toBeVisible()\n    at login.spec.ts:12:5"},
+            "attachments": [
+              {"name": "screenshot", "contentType": "image/png", "path": "/src/test-results/login-with-a-bad-password-shows-an-error-firefox/test-failed-1.png"},
+              {"name": "trace", "contentType": "application/zip", "path": "/src/test-results/login-with-a-bad-password-shows-an-error-firefox/trace.zip"},
+              {"name": "stdout", "contentType": "text/plain", "body": "aGVsbG8="}
+            ]
+          }]
+        }, {
+          "projectName": "webkit",
+          "expectedStatus": "passed",
+          "status": "flaky",
+          "results": [
+            {"status": "failed", "duration": 900, "retry": 0, "error": {"message": "Timeout"}, "attachments": []},
+            {"status": "passed", "duration": 800, "retry": 1, "attachments": []}
+          ]
+        }]
+      }]
+    }]
+  }, {
+    "title": "skipped.spec.ts",
+    "file": "skipped.spec.ts",
+    "specs": [{
+      "title": "is skipped",
+      "file": "skipped.spec.ts",
+      "tests": [{"projectName": "chromium", "expectedStatus": "skipped", "status": "skipped", "results": []}]
+    }]
+  }],
+  "stats": {"expected": 1, "unexpected": 1, "flaky": 1, "skipped": 1}
+}`
+
+const mochawesomeJSON = `{
+  "stats": {"suites": 1, "tests": 3, "passes": 1, "pending": 1, "failures": 1},
+  "results": [{
+    "title": "",
+    "file": "cypress/e2e/login.cy.js",
+    "tests": [],
+    "suites": [{
+      "title": "Login",
+      "file": "",
+      "tests": [
+        {"title": "logs in", "duration": 250, "state": "passed", "pending": false, "skipped": false, "err": {}},
+        {"title": "rejects a bad password", "duration": 4000, "state": "failed", "pending": false, "skipped": false,
+         "err": {"message": "AssertionError: expected to find element .error", "estack": "AssertionError: expected to find element .error\n    at Context.eval (login.cy.js:9:8)"}},
+        {"title": "resets the password", "duration": 0, "state": "pending", "pending": true, "skipped": false, "err": {}}
+      ],
+      "suites": []
+    }]
+  }]
+}`
+
+const mochaJSON = `{
+  "stats": {"suites": 1, "tests": 2, "passes": 1, "pending": 0, "failures": 1},
+  "tests": [
+    {"title": "logs in", "fullTitle": "Login logs in", "file": "cypress/e2e/login.cy.js", "duration": 250, "err": {}},
+    {"title": "rejects a bad password", "fullTitle": "Login rejects a bad password", "file": "cypress/e2e/login.cy.js", "duration": 4000,
+     "err": {"message": "AssertionError: expected to find element .error", "stack": "at login.cy.js:9:8"}}
+  ],
+  "pending": [],
+  "failures": [],
+  "passes": []
+}`
+
+// testSummary is the parts of a test the tests check.
+type testSummary struct {
+	File     string
+	Titles   []string
+	Project  string
+	Status   string
+	Duration time.Duration
+	Retries  int
+	Error    string
+}
+
+func summarize(r *Report) []testSummary {
+	var tests []testSummary
+	for _, t := range r.Tests {
+		tests = append(tests, testSummary{File: t.File, Titles: t.Titles, Project: t.Project, Status: t.Status, Duration: t.Duration, Retries: t.Retries, Error: t.Error})
+	}
+	return tests
+}
+
+func TestParseReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		report   string
+		tool     string
+		expected []testSummary
+		err      bool
+	}{
+		{
+			name:   "Playwright",
+			report: playwrightJSON,
+			tool:   toolPlaywright,
+			expected: []testSummary{
+				{File: "login.spec.ts", Titles: []string{"shows the form"}, Project: "chromium", Status: statusPassed, Duration: 120 * time.Millisecond},
+				{File: "login.spec.ts", Titles: []string{"with a bad password", "shows an error"}, Project: "firefox", Status: statusFailed, Duration: 1500 * time.Millisecond, Error: "Error: expect(locator).toBeVisible()"},
+				{File: "login.spec.ts", Titles: []string{"with a bad password", "shows an error"}, Project: "webkit", Status: statusFlaky, Duration: 1700 * time.Millisecond, Retries: 1, Error: "Timeout"},
+				{File: "skipped.spec.ts", Titles: []string{"is skipped"}, Project: "chromium", Status: statusSkipped},
+			},
+		},
+		{
+			name:   "Cypress with mochawesome",
+			report: mochawesomeJSON,
+			tool:   toolCypress,
+			expected: []testSummary{
+				{File: "cypress/e2e/login.cy.js", Titles: []string{"Login", "logs in"}, Status: statusPassed, Duration: 250 * time.Millisecond},
+				{File: "cypress/e2e/login.cy.js", Titles: []string{"Login", "rejects a bad password"}, Status: statusFailed, Duration: 4 * time.Second, Error: "AssertionError: expected to find element .error"},
+				{File: "cypress/e2e/login.cy.js", Titles: []string{"Login", "resets the password"}, Status: statusSkipped},
+			},
+		},
+		{
+			name:   "Cypress with the mocha JSON reporter",
+			report: mochaJSON,
+			tool:   toolCypress,
+			expected: []testSummary{
+				{File: "cypress/e2e/login.cy.js", Titles: []string{"Login logs in"}, Status: statusPassed, Duration: 250 * time.Millisecond},
+				{File: "cypress/e2e/login.cy.js", Titles: []string{"Login rejects a bad password"}, Status: statusFailed, Duration: 4 * time.Second, Error: "AssertionError: expected to find element .error"},
+			},
+		},
+		{
+			name:   "something else",
+			report: `{"kind": "Pod"}`,
+			err:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := parseReport([]byte(tc.report))
+			if err != nil {
+				if !tc.err {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tc.err {
+				t.Fatal("expected an error")
+			}
+			if report.Tool != tc.tool {
+				t.Errorf("expected tool %s, got %s", tc.tool, report.Tool)
+			}
+			if tests := summarize(report); !reflect.DeepEqual(tests, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, tests)
+			}
+		})
+	}
+}
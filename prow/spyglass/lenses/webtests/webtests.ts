@@ -0,0 +1,4 @@
+window.addEventListener('DOMContentLoaded', () => {
+  // The toggle event does not bubble, so listen in the capture phase.
+  document.addEventListener('toggle', () => spyglass.contentUpdated(), true);
+});
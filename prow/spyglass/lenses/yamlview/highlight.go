@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamlview
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Token classes, which are also used as CSS classes.
+const (
+	classPlain   = ""
+	classComment = "comment"
+	classKey     = "key"
+	classDash    = "dash"
+	classString  = "string"
+	classNumber  = "number"
+	classLiteral = "literal"
+	classAnchor  = "anchor"
+	classBlock   = "block"
+	classMarker  = "marker"
+)
+
+var (
+	keyRe     = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#'"\-\[{][^#]*?|-[^\s#][^#]*?)(:)(\s|$)`)
+	numberRe  = regexp.MustCompile(`^[-+]?(\d[\d_]*(\.\d*)?([eE][-+]?\d+)?|\.\d+([eE][-+]?\d+)?|0x[0-9a-fA-F]+|0o[0-7]+|\.inf|\.nan)$`)
+	literalRe = regexp.MustCompile(`^(true|false|yes|no|on|off|null|~|True|False|Yes|No|On|Off|Null|TRUE|FALSE|NULL)$`)
+	blockRe   = regexp.MustCompile(`^[|>][-+0-9]*$`)
+)
+
+// Token is a highlighted piece of a line.
+type Token struct {
+	Class string
+	Text  string
+}
+
+// highlighter tokenizes YAML a line at a time. It doesn't parse YAML, it only needs
+// to know enough to colour things sensibly, and tracks block scalars so their contents
+// aren't mistaken for keys.
+type highlighter struct {
+	// blockIndent is the indentation of the key that introduced the current block scalar,
+	// or -1 if we're not in one.
+	blockIndent int
+}
+
+func newHighlighter() *highlighter {
+	return &highlighter{blockIndent: -1}
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func (h *highlighter) line(line string) []Token {
+	indent := indentation(line)
+	if h.blockIndent >= 0 {
+		if strings.TrimSpace(line) == "" || indent > h.blockIndent {
+			return []Token{{Class: classString, Text: line}}
+		}
+		h.blockIndent = -1
+	}
+	if line == "---" || strings.HasPrefix(line, "--- ") || line == "..." {
+		return []Token{{Class: classMarker, Text: line}}
+	}
+
+	tokens := []Token{{Class: classPlain, Text: line[:indent]}}
+	rest := line[indent:]
+	// Each "- " starts a list item, and a key can follow on the same line.
+	itemIndent := indent
+	for strings.HasPrefix(rest, "- ") || rest == "-" {
+		dash := rest
+		if len(dash) > 2 {
+			dash = dash[:2]
+		}
+		tokens = append(tokens, Token{Class: classDash, Text: dash})
+		rest = rest[len(dash):]
+		itemIndent += len(dash)
+	}
+	if strings.HasPrefix(rest, "#") {
+		return append(tokens, Token{Class: classComment, Text: rest})
+	}
+	blockOwner := indent
+	if m := keyRe.FindStringSubmatch(rest); m != nil {
+		tokens = append(tokens, Token{Class: classKey, Text: m[1]}, Token{Class: classPlain, Text: m[2]})
+		rest = rest[len(m[1])+len(m[2]):]
+		blockOwner = itemIndent
+	}
+	valueTokens, block := value(rest)
+	if block {
+		h.blockIndent = blockOwner
+	}
+	return append(tokens, valueTokens...)
+}
+
+// value tokenizes a scalar value along with any trailing comment.
+func value(s string) ([]Token, bool) {
+	leading := s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+	s = s[len(leading):]
+	var comment string
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		// Find the closing quote, so that a # inside the string isn't taken for a comment.
+		end := strings.IndexByte(s[1:], s[0])
+		if end >= 0 {
+			rest := s[end+2:]
+			if i := strings.Index(rest, "#"); i >= 0 {
+				comment = rest[i:]
+				s = s[:end+2] + rest[:i]
+			}
+		}
+	} else if strings.HasPrefix(s, "#") {
+		comment, s = s, ""
+	} else if i := strings.Index(s, " #"); i >= 0 {
+		comment = s[i+1:]
+		s = s[:i+1]
+	}
+
+	var tokens []Token
+	if leading != "" {
+		tokens = append(tokens, Token{Class: classPlain, Text: leading})
+	}
+	trimmed := strings.TrimRight(s, " \t")
+	trailing := s[len(trimmed):]
+	block := false
+	if trimmed != "" {
+		class := classString
+		switch {
+		case trimmed[0] == '&' || trimmed[0] == '*':
+			class = classAnchor
+		case blockRe.MatchString(trimmed):
+			class = classBlock
+			block = true
+		case numberRe.MatchString(trimmed):
+			class = classNumber
+		case literalRe.MatchString(trimmed):
+			class = classLiteral
+		}
+		tokens = append(tokens, Token{Class: class, Text: trimmed})
+	}
+	if trailing != "" {
+		tokens = append(tokens, Token{Class: classPlain, Text: trailing})
+	}
+	if comment != "" {
+		tokens = append(tokens, Token{Class: classComment, Text: comment})
+	}
+	return tokens, block
+}
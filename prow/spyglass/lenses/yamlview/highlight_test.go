@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamlview
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lines    []string
+		expected [][]Token
+	}{
+		{
+			name:  "keys and scalars",
+			lines: []string{`replicas: 3`, `  enabled: true # on by default`, `image: "gcr.io/foo:v1"`},
+			expected: [][]Token{
+				{{Text: ""}, {Class: classKey, Text: "replicas"}, {Text: ":"}, {Text: " "}, {Class: classNumber, Text: "3"}},
+				{{Text: "  "}, {Class: classKey, Text: "enabled"}, {Text: ":"}, {Text: " "}, {Class: classLiteral, Text: "true"}, {Text: " "}, {Class: classComment, Text: "# on by default"}},
+				{{Text: ""}, {Class: classKey, Text: "image"}, {Text: ":"}, {Text: " "}, {Class: classString, Text: `"gcr.io/foo:v1"`}},
+			},
+		},
+		{
+			name:  "list items with keys",
+			lines: []string{`- name: foo`, `  - bar`},
+			expected: [][]Token{
+				{{Text: ""}, {Class: classDash, Text: "- "}, {Class: classKey, Text: "name"}, {Text: ":"}, {Text: " "}, {Class: classString, Text: "foo"}},
+				{{Text: "  "}, {Class: classDash, Text: "- "}, {Class: classString, Text: "bar"}},
+			},
+		},
+		{
+			name:  "block scalars are not parsed",
+			lines: []string{`script: |`, `  key: not really`, `  # nor a comment`, `next: 1`},
+			expected: [][]Token{
+				{{Text: ""}, {Class: classKey, Text: "script"}, {Text: ":"}, {Text: " "}, {Class: classBlock, Text: "|"}},
+				{{Class: classString, Text: "  key: not really"}},
+				{{Class: classString, Text: "  # nor a comment"}},
+				{{Text: ""}, {Class: classKey, Text: "next"}, {Text: ":"}, {Text: " "}, {Class: classNumber, Text: "1"}},
+			},
+		},
+		{
+			name:     "document markers",
+			lines:    []string{`---`},
+			expected: [][]Token{{{Class: classMarker, Text: "---"}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHighlighter()
+			for i, line := range tc.lines {
+				if actual := h.line(line); !reflect.DeepEqual(actual, tc.expected[i]) {
+					t.Errorf("line %d: expected %#v, got %#v", i, tc.expected[i], actual)
+				}
+			}
+		})
+	}
+}
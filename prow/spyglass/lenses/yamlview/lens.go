@@ -0,0 +1,295 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package yamlview provides a Spyglass lens that renders YAML artifacts, such as rendered manifests.
+package yamlview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "yamlview"
+	title    = "YAML"
+	priority = 14
+
+	// linesPerPage is the number of lines we aim to render per page. Documents are never split
+	// across pages, so a page holding a single large document may be longer.
+	linesPerPage = 3000
+	// maxDocumentLines bounds the lines rendered for a single document.
+	maxDocumentLines = 20000
+)
+
+// Lens is the implementation of a YAML-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
+	return executeTemplate(resourceDir, "header", nil)
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	return ""
+}
+
+// request is sent by the frontend to select an artifact and page.
+type request struct {
+	Artifact string `json:"artifact"`
+	Page     int    `json:"page"`
+}
+
+// Line is a single highlighted line.
+type Line struct {
+	Number int
+	Tokens []Token
+}
+
+// Section is a foldable part of a document, usually a single top-level key.
+type Section struct {
+	// Summary is the line that remains visible when the section is folded.
+	Summary Line
+	Lines   []Line
+	// Foldable is false for the lines preceding the first top-level key, which have nothing to fold under.
+	Foldable bool
+}
+
+// Page is a link to a page of documents.
+type Page struct {
+	Index int
+	Label int
+}
+
+// Document is a single YAML document within the artifact.
+type Document struct {
+	Index     int
+	Title     string
+	Sections  []Section
+	Truncated int
+}
+
+type yamlView struct {
+	Artifacts []string
+	Artifact  string
+	Link      string
+	Page      int
+	Pages     []Page
+	Documents []Document
+	Error     string
+}
+
+// Body renders a page of the selected YAML artifact.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string, config json.RawMessage) string {
+	if len(artifacts) == 0 {
+		return executeTemplate(resourceDir, "body", yamlView{Error: "no YAML files found"})
+	}
+	var req request
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &req); err != nil {
+			logrus.WithError(err).Info("Failed to parse YAML lens request.")
+		}
+	}
+	view := yamlView{}
+	selected := artifacts[0]
+	for _, a := range artifacts {
+		view.Artifacts = append(view.Artifacts, a.JobPath())
+		if a.JobPath() == req.Artifact {
+			selected = a
+		}
+	}
+	view.Artifact = selected.JobPath()
+	view.Link = selected.CanonicalLink()
+
+	content, err := selected.ReadAll()
+	if err == lenses.ErrFileTooLarge {
+		view.Error = fmt.Sprintf("%s is too large to display", selected.JobPath())
+		return executeTemplate(resourceDir, "body", view)
+	}
+	if err != nil {
+		view.Error = fmt.Sprintf("failed to read %s: %v", selected.JobPath(), err)
+		return executeTemplate(resourceDir, "body", view)
+	}
+
+	docs := splitDocuments(string(content))
+	pages := paginate(docs)
+	for i := range pages {
+		view.Pages = append(view.Pages, Page{Index: i, Label: i + 1})
+	}
+	if req.Page >= 0 && req.Page < len(pages) {
+		view.Page = req.Page
+	}
+	if len(pages) > 0 {
+		for _, d := range pages[view.Page] {
+			view.Documents = append(view.Documents, renderDocument(d))
+		}
+	}
+	return executeTemplate(resourceDir, "body", view)
+}
+
+// document is a raw YAML document and its position in the file.
+type document struct {
+	index     int
+	firstLine int
+	lines     []string
+}
+
+// splitDocuments splits content on document markers. The markers themselves are kept as the
+// first line of the document they introduce, so line numbers match the file.
+func splitDocuments(content string) []document {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	var docs []document
+	current := document{firstLine: 1}
+	for i, line := range lines {
+		if (line == "---" || strings.HasPrefix(line, "--- ")) && i > 0 {
+			docs = append(docs, current)
+			current = document{index: len(docs), firstLine: i + 1}
+		}
+		current.lines = append(current.lines, strings.TrimSuffix(line, "\r"))
+	}
+	docs = append(docs, current)
+
+	// Drop documents with nothing in them, which are usually the result of leading or trailing separators.
+	var nonEmpty []document
+	for _, d := range docs {
+		for _, l := range d.lines {
+			if t := strings.TrimSpace(l); t != "" && t != "---" && t != "..." {
+				d.index = len(nonEmpty)
+				nonEmpty = append(nonEmpty, d)
+				break
+			}
+		}
+	}
+	return nonEmpty
+}
+
+// paginate groups documents into pages of roughly linesPerPage lines.
+func paginate(docs []document) [][]document {
+	var pages [][]document
+	var page []document
+	lines := 0
+	for _, d := range docs {
+		if len(page) > 0 && lines+len(d.lines) > linesPerPage {
+			pages = append(pages, page)
+			page, lines = nil, 0
+		}
+		page = append(page, d)
+		lines += len(d.lines)
+	}
+	if len(page) > 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// resource holds the fields that identify a Kubernetes object, which make a much better
+// document title than its index.
+type resource struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func documentTitle(d document) string {
+	title := fmt.Sprintf("Document %d", d.index+1)
+	var r resource
+	if err := yaml.Unmarshal([]byte(strings.Join(d.lines, "\n")), &r); err != nil || r.Kind == "" {
+		return title
+	}
+	title += ": " + r.Kind
+	if r.Metadata.Name != "" {
+		name := r.Metadata.Name
+		if r.Metadata.Namespace != "" {
+			name = r.Metadata.Namespace + "/" + name
+		}
+		title += " " + name
+	}
+	return title
+}
+
+// isTopLevel reports whether a line starts a new top-level section: a key or list item at column zero.
+func isTopLevel(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+		return false
+	}
+	return line != "---" && !strings.HasPrefix(line, "--- ") && line != "..."
+}
+
+func renderDocument(d document) Document {
+	doc := Document{Index: d.index, Title: documentTitle(d)}
+	lines := d.lines
+	if len(lines) > maxDocumentLines {
+		doc.Truncated = len(lines) - maxDocumentLines
+		lines = lines[:maxDocumentLines]
+	}
+	h := newHighlighter()
+	var current *Section
+	for i, l := range lines {
+		line := Line{Number: d.firstLine + i, Tokens: h.line(l)}
+		// A top-level line always ends any block scalar, so this can't split one.
+		if isTopLevel(l) {
+			if current != nil {
+				doc.Sections = append(doc.Sections, *current)
+			}
+			current = &Section{Summary: line, Foldable: true}
+			continue
+		}
+		if current == nil {
+			current = &Section{}
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	if current != nil {
+		doc.Sections = append(doc.Sections, *current)
+	}
+	return doc
+}
+
+func executeTemplate(resourceDir, templateName string, data interface{}) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING TEMPLATE: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, templateName, data); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamlview
+
+import (
+	"strings"
+	"testing"
+)
+
+const manifests = `# leading comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: test-pods
+data:
+  key: value
+---
+just: a map
+---
+`
+
+func TestSplitDocuments(t *testing.T) {
+	docs := splitDocuments(manifests)
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, got %d: %#v", len(docs), docs)
+	}
+	if docs[1].firstLine != 2 || docs[2].firstLine != 10 {
+		t.Errorf("expected documents to start on lines 2 and 10, got %d and %d", docs[1].firstLine, docs[2].firstLine)
+	}
+	if titles := []string{documentTitle(docs[1]), documentTitle(docs[2])}; titles[0] != "Document 2: ConfigMap test-pods/config" || titles[1] != "Document 3" {
+		t.Errorf("unexpected titles %q", titles)
+	}
+}
+
+func TestRenderDocumentSections(t *testing.T) {
+	doc := renderDocument(splitDocuments(manifests)[1])
+	// The separator, then one section per top-level key.
+	if len(doc.Sections) != 5 {
+		t.Fatalf("expected 5 sections, got %d", len(doc.Sections))
+	}
+	if doc.Sections[0].Foldable {
+		t.Errorf("expected the separator not to be foldable")
+	}
+	metadata := doc.Sections[3]
+	if !metadata.Foldable || metadata.Summary.Number != 5 || len(metadata.Lines) != 2 {
+		t.Errorf("expected metadata section starting on line 5 with two more lines, got %#v", metadata)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		b.WriteString("---\n")
+		for j := 0; j < linesPerPage/4; j++ {
+			b.WriteString("key: value\n")
+		}
+	}
+	pages := paginate(splitDocuments(b.String()))
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 pages, got %d", len(pages))
+	}
+	if len(pages[0]) != 3 || len(pages[3]) != 1 {
+		t.Errorf("expected 3 documents on the first page and 1 on the last, got %d and %d", len(pages[0]), len(pages[3]))
+	}
+}
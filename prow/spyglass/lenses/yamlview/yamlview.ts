@@ -0,0 +1,42 @@
+function selectedArtifact(): string {
+  const el = document.querySelector<HTMLInputElement | HTMLSelectElement>('#yaml-artifact');
+  return el ? el.value : '';
+}
+
+function setAllOpen(open: boolean): void {
+  for (const details of Array.from(document.querySelectorAll<HTMLDetailsElement>('.yaml-code details'))) {
+    details.open = open;
+  }
+  spyglass.contentUpdated();
+}
+
+function bind(): void {
+  const select = document.querySelector<HTMLSelectElement>('select#yaml-artifact');
+  if (select) {
+    select.addEventListener('change', () => {
+      spyglass.updatePage(JSON.stringify({artifact: select.value, page: 0})).then(bind);
+    });
+  }
+  for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>('button.yaml-page'))) {
+    button.addEventListener('click', () => {
+      const page = Number(button.dataset.page);
+      spyglass.updatePage(JSON.stringify({artifact: selectedArtifact(), page})).then(() => {
+        bind();
+        window.scrollTo(0, 0);
+      });
+    });
+  }
+  const collapse = document.getElementById('yaml-collapse');
+  if (collapse) {
+    collapse.addEventListener('click', () => setAllOpen(false));
+  }
+  const expand = document.getElementById('yaml-expand');
+  if (expand) {
+    expand.addEventListener('click', () => setAllOpen(true));
+  }
+  for (const details of Array.from(document.querySelectorAll<HTMLDetailsElement>('.yaml-code details'))) {
+    details.addEventListener('toggle', () => spyglass.contentUpdated());
+  }
+}
+
+window.addEventListener('DOMContentLoaded', bind);
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	// runningListingTTL is how long the artifacts listed for a run that has not finished are
+	// reused for. It only needs to cover the requests made while a page loads.
+	runningListingTTL = 30 * time.Second
+	// maxCachedListings bounds how many runs' artifacts are remembered.
+	maxCachedListings = 1000
+)
+
+// artifactListing is the names and attributes of the artifacts of a run, in the order they were
+// listed.
+type artifactListing struct {
+	names []string
+	attrs map[string]*storage.ObjectAttrs
+	// finished is whether the run had finished when it was listed, so its artifacts won't change.
+	finished bool
+	listed   time.Time
+}
+
+// listingCache remembers the artifacts listed for runs, so that the requests made for the lenses
+// of a page don't each list them again. Listings of finished runs are kept until evicted to make
+// room for others, while those of running jobs expire after runningListingTTL.
+type listingCache struct {
+	lock     sync.Mutex
+	now      func() time.Time
+	listings map[string]*artifactListing
+}
+
+func newListingCache() *listingCache {
+	return &listingCache{now: time.Now, listings: map[string]*artifactListing{}}
+}
+
+// get returns the listing of the artifacts of a run, if it is cached and current.
+func (c *listingCache) get(key string) (*artifactListing, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	listing, ok := c.listings[key]
+	if !ok {
+		return nil, false
+	}
+	if !listing.finished && c.now().Sub(listing.listed) > runningListingTTL {
+		delete(c.listings, key)
+		return nil, false
+	}
+	return listing, true
+}
+
+// add caches the listing of the artifacts of a run, evicting the oldest listing if the cache is
+// full.
+func (c *listingCache) add(key string, names []string, attrs map[string]*storage.ObjectAttrs) *artifactListing {
+	listing := &artifactListing{names: names, attrs: attrs, listed: c.now()}
+	for _, name := range names {
+		if name == "finished.json" {
+			listing.finished = true
+			break
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.listings[key]; !ok && len(c.listings) >= maxCachedListings {
+		var oldest string
+		for k, l := range c.listings {
+			if oldest == "" || l.listed.Before(c.listings[oldest].listed) {
+				oldest = k
+			}
+		}
+		delete(c.listings, oldest)
+	}
+	c.listings[key] = listing
+	return listing
+}
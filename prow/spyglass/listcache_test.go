@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+func TestListingCache(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := newListingCache()
+	cache.now = func() time.Time { return now }
+
+	cache.add("bucket/logs/job/1/", []string{"started.json", "finished.json"}, map[string]*storage.ObjectAttrs{"started.json": {Size: 10}, "finished.json": {Size: 20}})
+	cache.add("bucket/logs/job/2/", []string{"started.json"}, map[string]*storage.ObjectAttrs{"started.json": {Size: 10}})
+	if listing, ok := cache.get("bucket/logs/job/1/"); !ok || !listing.finished || listing.attrs["finished.json"].Size != 20 {
+		t.Errorf("expected the finished run's listing, got %+v", listing)
+	}
+	if listing, ok := cache.get("bucket/logs/job/2/"); !ok || listing.finished {
+		t.Errorf("expected the running job's listing, got %+v", listing)
+	}
+	if _, ok := cache.get("bucket/logs/job/3/"); ok {
+		t.Error("expected no listing for a run that was never listed")
+	}
+
+	now = now.Add(runningListingTTL + time.Second)
+	if _, ok := cache.get("bucket/logs/job/1/"); !ok {
+		t.Error("expected the finished run's listing to be kept")
+	}
+	if _, ok := cache.get("bucket/logs/job/2/"); ok {
+		t.Error("expected the running job's listing to expire")
+	}
+
+	for i := 0; i < maxCachedListings; i++ {
+		now = now.Add(time.Second)
+		cache.add(fmt.Sprintf("bucket/logs/other-job/%d/", i), []string{"finished.json"}, nil)
+	}
+	if len(cache.listings) != maxCachedListings {
+		t.Errorf("expected %d cached listings, got %d", maxCachedListings, len(cache.listings))
+	}
+	if _, ok := cache.get("bucket/logs/job/1/"); ok {
+		t.Error("expected the oldest listing to be evicted")
+	}
+	if _, ok := cache.get("bucket/logs/other-job/0/"); !ok {
+		t.Error("expected newer listings to be kept")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+// LocalArtifact represents some output of a prow job that has been downloaded to a local directory
+type LocalArtifact struct {
+	// file is the path of the artifact on disk
+	file string
+
+	// path is the path of the artifact within the job
+	path string
+
+	// sizeLimit is the max size to read before failing
+	sizeLimit int64
+}
+
+// NewLocalArtifact returns a new LocalArtifact for a file, with the given path within the job
+func NewLocalArtifact(file, path string, sizeLimit int64) *LocalArtifact {
+	return &LocalArtifact{
+		file:      file,
+		path:      path,
+		sizeLimit: sizeLimit,
+	}
+}
+
+// ListLocalArtifacts returns the paths of the files under a directory holding a job's artifacts,
+// relative to the directory, which are the paths of the artifacts within the job.
+func ListLocalArtifacts(dir string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing artifacts in %s: %v", dir, err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Size returns the size of the artifact on disk
+func (a *LocalArtifact) Size() (int64, error) {
+	info, err := os.Stat(a.file)
+	if err != nil {
+		return 0, fmt.Errorf("error getting size of artifact: %v", err)
+	}
+	return info.Size(), nil
+}
+
+// JobPath gets the path of the artifact within the job
+func (a *LocalArtifact) JobPath() string {
+	return a.path
+}
+
+// CanonicalLink gets a file URL of the artifact
+func (a *LocalArtifact) CanonicalLink() string {
+	file, err := filepath.Abs(a.file)
+	if err != nil {
+		file = a.file
+	}
+	return "file://" + filepath.ToSlash(file)
+}
+
+// ReadAt reads len(p) bytes from the artifact at offset off
+func (a *LocalArtifact) ReadAt(p []byte, off int64) (int, error) {
+	f, err := os.Open(a.file)
+	if err != nil {
+		return 0, fmt.Errorf("error opening artifact: %v", err)
+	}
+	defer f.Close()
+	return f.ReadAt(p, off)
+}
+
+// ReadAtMost reads at most n bytes from the beginning of the artifact
+func (a *LocalArtifact) ReadAtMost(n int64) ([]byte, error) {
+	f, err := os.Open(a.file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening artifact: %v", err)
+	}
+	defer f.Close()
+	p, err := ioutil.ReadAll(io.LimitReader(f, n))
+	if err != nil {
+		return nil, fmt.Errorf("error reading from artifact: %v", err)
+	}
+	if int64(len(p)) < n {
+		return p, io.EOF
+	}
+	return p, nil
+}
+
+// ReadAll will either read the entire file or throw an error if file size is too big
+func (a *LocalArtifact) ReadAll() ([]byte, error) {
+	size, err := a.Size()
+	if err != nil {
+		return nil, err
+	}
+	if size > a.sizeLimit {
+		return nil, lenses.ErrFileTooLarge
+	}
+	p, err := ioutil.ReadFile(a.file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading all from artifact: %v", err)
+	}
+	return p, nil
+}
+
+// ReadTail reads the last n bytes from the artifact
+func (a *LocalArtifact) ReadTail(n int64) ([]byte, error) {
+	size, err := a.Size()
+	if err != nil {
+		return nil, err
+	}
+	if n > size {
+		n = size
+	}
+	p := make([]byte, n)
+	read, err := a.ReadAt(p, size-n)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading tail of artifact: %v", err)
+	}
+	return p[:read], nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestLocalArtifact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-artifacts")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"build-log.txt":              "line one\nline two\n",
+		"artifacts/junit_01.xml":     "<testsuite/>",
+		"artifacts/logs/kubelet.log": "",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	names, err := ListLocalArtifacts(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"artifacts/junit_01.xml", "artifacts/logs/kubelet.log", "build-log.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected artifacts %q, got %q", expected, names)
+	}
+
+	a := NewLocalArtifact(filepath.Join(dir, "build-log.txt"), "build-log.txt", 10)
+	if size, err := a.Size(); err != nil || size != 18 {
+		t.Errorf("expected a size of 18, got %d (%v)", size, err)
+	}
+	if _, err := a.ReadAll(); err != lenses.ErrFileTooLarge {
+		t.Errorf("expected reading more than the size limit to fail, got %v", err)
+	}
+	if p, err := a.ReadAtMost(4); err != nil || string(p) != "line" {
+		t.Errorf("expected the first 4 bytes to be %q, got %q (%v)", "line", p, err)
+	}
+	if p, err := a.ReadTail(9); err != nil || string(p) != "line two\n" {
+		t.Errorf("expected the last 9 bytes to be %q, got %q (%v)", "line two\n", p, err)
+	}
+	p := make([]byte, 3)
+	if n, err := a.ReadAt(p, 5); err != nil || string(p[:n]) != "one" {
+		t.Errorf("expected the bytes at offset 5 to be %q, got %q (%v)", "one", p[:n], err)
+	}
+
+	a = NewLocalArtifact(filepath.Join(dir, "build-log.txt"), "build-log.txt", 100)
+	if p, err := a.ReadAll(); err != nil || string(p) != files["build-log.txt"] {
+		t.Errorf("expected the whole log, got %q (%v)", p, err)
+	}
+	if p, err := a.ReadAtMost(100); len(p) != 18 || err == nil {
+		t.Errorf("expected reading past the end to return the whole log and EOF, got %q (%v)", p, err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+// LensArtifactPage is a page of the artifacts a lens matches.
+type LensArtifactPage struct {
+	Artifacts []string `json:"artifacts"`
+	// More is whether the lens matches artifacts after those on the page.
+	More bool `json:"more"`
+}
+
+// ArtifactPage returns a page, counted from zero, of the artifacts a lens matches. If pageSize is
+// zero, the first page has all of them.
+func ArtifactPage(artifacts []string, page, pageSize int) LensArtifactPage {
+	if pageSize <= 0 {
+		if page > 0 {
+			return LensArtifactPage{Artifacts: []string{}}
+		}
+		return LensArtifactPage{Artifacts: artifacts}
+	}
+	start := page * pageSize
+	if page < 0 || start >= len(artifacts) {
+		return LensArtifactPage{Artifacts: []string{}}
+	}
+	end := start + pageSize
+	if end >= len(artifacts) {
+		return LensArtifactPage{Artifacts: artifacts[start:]}
+	}
+	return LensArtifactPage{Artifacts: artifacts[start:end], More: true}
+}
+
+// FirstArtifactPages returns the first page of the artifacts each lens matches, given all of the
+// artifacts each matches, along with how many artifacts the lenses that match more than one page
+// match.
+func FirstArtifactPages(matches map[string][]string, pageSize int) (map[string][]string, map[string]int) {
+	pages := map[string][]string{}
+	counts := map[string]int{}
+	for lens, artifacts := range matches {
+		page := ArtifactPage(artifacts, 0, pageSize)
+		pages[lens] = page.Artifacts
+		if page.More {
+			counts[lens] = len(artifacts)
+		}
+	}
+	return pages, counts
+}
+
+// ArtifactPageFor returns a page, counted from zero, of the artifacts of the run referenced by src
+// that the named lens matches, in pages of the configured artifact_page_size. Only the lens's own
+// patterns are matched against the run's artifacts.
+func (s *Spyglass) ArtifactPageFor(src, lens string, page int) (LensArtifactPage, error) {
+	artifactNames, err := s.ListArtifacts(src)
+	if err != nil {
+		return LensArtifactPage{}, err
+	}
+	matches := s.matchLenses(artifactNames, lens)[lens]
+	return ArtifactPage(matches, page, s.config().Deck.Spyglass.ArtifactPageSize), nil
+}
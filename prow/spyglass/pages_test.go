@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestArtifactPage(t *testing.T) {
+	artifacts := []string{"a", "b", "c", "d", "e"}
+	testCases := []struct {
+		name     string
+		page     int
+		pageSize int
+		expected LensArtifactPage
+	}{
+		{
+			name:     "first page",
+			pageSize: 2,
+			expected: LensArtifactPage{Artifacts: []string{"a", "b"}, More: true},
+		},
+		{
+			name:     "middle page",
+			page:     1,
+			pageSize: 2,
+			expected: LensArtifactPage{Artifacts: []string{"c", "d"}, More: true},
+		},
+		{
+			name:     "last page is short",
+			page:     2,
+			pageSize: 2,
+			expected: LensArtifactPage{Artifacts: []string{"e"}},
+		},
+		{
+			name:     "last page is full",
+			page:     0,
+			pageSize: 5,
+			expected: LensArtifactPage{Artifacts: artifacts},
+		},
+		{
+			name:     "pages after the last are empty",
+			page:     3,
+			pageSize: 2,
+			expected: LensArtifactPage{Artifacts: []string{}},
+		},
+		{
+			name:     "without a page size the first page has everything",
+			expected: LensArtifactPage{Artifacts: artifacts},
+		},
+		{
+			name:     "without a page size later pages are empty",
+			page:     1,
+			expected: LensArtifactPage{Artifacts: []string{}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ArtifactPage(artifacts, tc.page, tc.pageSize); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFirstArtifactPages(t *testing.T) {
+	pages, counts := FirstArtifactPages(map[string][]string{
+		"junit":    {"a.xml", "b.xml", "c.xml"},
+		"buildlog": {"build-log.txt"},
+	}, 2)
+	expectedPages := map[string][]string{
+		"junit":    {"a.xml", "b.xml"},
+		"buildlog": {"build-log.txt"},
+	}
+	if !reflect.DeepEqual(pages, expectedPages) {
+		t.Errorf("expected pages %v, got %v", expectedPages, pages)
+	}
+	if expectedCounts := map[string]int{"junit": 3}; !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("expected counts %v, got %v", expectedCounts, counts)
+	}
+}
+
+func TestArtifactPageFor(t *testing.T) {
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						ArtifactPageSize: 1,
+						Viewers: map[string][]string{
+							`\.txt$`: {"buildlog"},
+							`\.xml$`: {"junit"},
+						},
+						RegexCache: map[string]*regexp.Regexp{
+							`\.txt$`: regexp.MustCompile(`\.txt$`),
+							`\.xml$`: regexp.MustCompile(`\.xml$`),
+						},
+					},
+				},
+			},
+		},
+	}
+	sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSServer.Client(), context.Background())
+	src := "gcs/test-bucket/logs/example-ci-run/403"
+	first, err := sg.ArtifactPageFor(src, "buildlog", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Artifacts) != 1 || !first.More {
+		t.Errorf("expected one of the logs and more, got %+v", first)
+	}
+	second, err := sg.ArtifactPageFor(src, "buildlog", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second.Artifacts) != 1 || second.Artifacts[0] == first.Artifacts[0] {
+		t.Errorf("expected the other log on the second page, got %+v after %+v", second, first)
+	}
+	junit, err := sg.ArtifactPageFor(src, "junit", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (LensArtifactPage{Artifacts: []string{"junit_01.xml"}}); !reflect.DeepEqual(junit, expected) {
+		t.Errorf("expected %+v, got %+v", expected, junit)
+	}
+}
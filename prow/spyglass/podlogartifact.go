@@ -172,6 +172,15 @@ func (a *PodLogArtifact) Size() (int64, error) {
 
 }
 
+// Live returns whether the job is still running, so that its pod log may still grow.
+func (a *PodLogArtifact) Live() bool {
+	job, err := a.jobAgent.GetProwJob(a.name, a.buildID)
+	if err != nil {
+		return false
+	}
+	return !job.Complete()
+}
+
 // isProwJobSource returns true if the provided string is a valid Prowjob source and false otherwise
 func isProwJobSource(src string) bool {
 	return strings.HasPrefix(src, "prowjob/")
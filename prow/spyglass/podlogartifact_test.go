@@ -22,6 +22,7 @@ import (
 	"io"
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
@@ -31,6 +32,9 @@ type fakePodLogJAgent struct {
 }
 
 func (j *fakePodLogJAgent) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	if job == "Fantastic Mr. Fox" && id == "4" {
+		return prowapi.ProwJob{Status: prowapi.ProwJobStatus{CompletionTime: &metav1.Time{}}}, nil
+	}
 	return prowapi.ProwJob{}, nil
 }
 
@@ -320,3 +324,37 @@ func TestReadAll_PodLog(t *testing.T) {
 	}
 
 }
+
+func TestLive_PodLog(t *testing.T) {
+	fakePodLogAgent := &fakePodLogJAgent{}
+	testCases := []struct {
+		name     string
+		jobName  string
+		buildID  string
+		expected bool
+	}{
+		{
+			name:     "running job is live",
+			jobName:  "BFG",
+			buildID:  "435",
+			expected: true,
+		},
+		{
+			name:     "completed job is not live",
+			jobName:  "Fantastic Mr. Fox",
+			buildID:  "4",
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifact, err := NewPodLogArtifact(tc.jobName, tc.buildID, 500e6, fakePodLogAgent)
+			if err != nil {
+				t.Fatalf("Pod Log Tests failed to create pod log artifact, err %v", err)
+			}
+			if live := lenses.IsLive(artifact); live != tc.expected {
+				t.Errorf("expected live to be %t, got %t", tc.expected, live)
+			}
+		})
+	}
+}
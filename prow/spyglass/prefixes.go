@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// IsPrefix returns whether src is a storage prefix rather than the run of a job.
+func IsPrefix(src string) bool {
+	keyType, _, err := splitSrc(src)
+	return err == nil && keyType == prefixKeyType
+}
+
+// prefixKey returns the storage path of the prefix a prefix src names, such as
+// "my-bucket/debug-bundles/2019-06-01/bundle", or an error if it isn't under any of the
+// browsable prefixes.
+func (s *Spyglass) prefixKey(key string) (string, error) {
+	// Artifact paths are joined onto the prefix, which cleans out any ".." in it.
+	key = path.Clean(strings.Trim(key, "/"))
+	if strings.Count(key, "/") < 2 {
+		return "", fmt.Errorf("invalid prefix %s: expected <bucket-name>/<path> with at least two path components", key)
+	}
+	storagePath := s.StoragePath(key)
+	for _, prefix := range s.config().Deck.Spyglass.BrowsablePrefixes {
+		// Prefixes name directories, so "bucket/debug" doesn't take in "bucket/debugging".
+		prefix = strings.TrimPrefix(prefix, "gs://")
+		if storagePath == prefix || strings.HasPrefix(storagePath, strings.TrimSuffix(prefix, "/")+"/") {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not under any of the prefixes Spyglass can browse", key)
+}
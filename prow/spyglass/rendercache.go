@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// renderTTL is how long the artifacts fetched for a page render are kept after they were last
+	// used. It covers the lenses of a page loading and the callbacks they make as people use them.
+	renderTTL = 5 * time.Minute
+	// maxCachedRenders bounds how many renders' artifacts are remembered.
+	maxCachedRenders = 200
+	// maxRenderReadBytes bounds how much of what a render's lenses read is kept for the others.
+	maxRenderReadBytes = 4 * 1024 * 1024
+)
+
+// render is the artifacts fetched for the lenses of one page render, keyed by run, name and size
+// limit.
+type render struct {
+	lock      sync.Mutex
+	artifacts map[string]*GCSArtifact
+	// readBytes is how much content the render's artifacts keep.
+	readBytes int64
+	used      time.Time
+}
+
+// reserve reports whether an artifact of the render may keep n bytes of content it read.
+func (r *render) reserve(n int64) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.readBytes+n > maxRenderReadBytes {
+		return false
+	}
+	r.readBytes += n
+	return true
+}
+
+// renderCache remembers the artifacts fetched for page renders, so that the header, body and
+// callbacks of the lenses of a page share the handles of the artifacts they read, along with their
+// attributes and small contents, instead of fetching each again.
+type renderCache struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	renders map[string]*render
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{now: time.Now, renders: map[string]*render{}}
+}
+
+// artifact returns the artifact of a render with the given key, making it with newArtifact if
+// the render hasn't fetched it yet.
+func (c *renderCache) artifact(renderID, key string, newArtifact func(*render) (*GCSArtifact, error)) (*GCSArtifact, error) {
+	r := c.render(renderID)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if artifact, ok := r.artifacts[key]; ok {
+		return artifact, nil
+	}
+	artifact, err := newArtifact(r)
+	if err != nil {
+		return nil, err
+	}
+	r.artifacts[key] = artifact
+	return artifact, nil
+}
+
+// render returns the render with the given ID, starting a new one if it expired or was never
+// seen, and evicting the least recently used render if the cache is full.
+func (c *renderCache) render(renderID string) *render {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	r, ok := c.renders[renderID]
+	if ok && now.Sub(r.used) > renderTTL {
+		delete(c.renders, renderID)
+		ok = false
+	}
+	if !ok {
+		if len(c.renders) >= maxCachedRenders {
+			var oldest string
+			for id, other := range c.renders {
+				if oldest == "" || other.used.Before(c.renders[oldest].used) {
+					oldest = id
+				}
+			}
+			delete(c.renders, oldest)
+		}
+		r = &render{artifacts: map[string]*GCSArtifact{}}
+		c.renders[renderID] = r
+	}
+	r.used = now
+	return r
+}
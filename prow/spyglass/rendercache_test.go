@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// countingArtifactHandle counts the requests made of the handle it wraps.
+type countingArtifactHandle struct {
+	artifactHandle
+	attrs, reads int
+}
+
+func (h *countingArtifactHandle) Attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+	h.attrs++
+	return h.artifactHandle.Attrs(ctx)
+}
+
+func (h *countingArtifactHandle) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	h.reads++
+	return h.artifactHandle.NewRangeReader(ctx, offset, length)
+}
+
+func (h *countingArtifactHandle) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	h.reads++
+	return h.artifactHandle.NewReader(ctx)
+}
+
+func TestRenderCache(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := newRenderCache()
+	cache.now = func() time.Time { return now }
+	made := 0
+	newArtifact := func(r *render) (*GCSArtifact, error) {
+		made++
+		artifact := NewGCSArtifact(context.Background(), &fakeArtifactHandle{}, "", "build-log.txt", 500e6)
+		artifact.render = r
+		return artifact, nil
+	}
+
+	first, _ := cache.artifact("render-1", "bucket/logs/job/1/build-log.txt", newArtifact)
+	again, _ := cache.artifact("render-1", "bucket/logs/job/1/build-log.txt", newArtifact)
+	if first != again || made != 1 {
+		t.Errorf("expected requests for the same render to share the artifact, made %d", made)
+	}
+	if other, _ := cache.artifact("render-2", "bucket/logs/job/1/build-log.txt", newArtifact); other == first {
+		t.Error("expected another render to get its own artifact")
+	}
+	if _, err := cache.artifact("render-1", "bucket/logs/job/2/build-log.txt", func(*render) (*GCSArtifact, error) {
+		return nil, fmt.Errorf("no such run")
+	}); err == nil {
+		t.Error("expected the error making an artifact to be returned")
+	}
+	if _, ok := cache.renders["render-1"].artifacts["bucket/logs/job/2/build-log.txt"]; ok {
+		t.Error("expected artifacts that failed to be made not to be kept")
+	}
+
+	now = now.Add(renderTTL + time.Second)
+	if expired, _ := cache.artifact("render-1", "bucket/logs/job/1/build-log.txt", newArtifact); expired == first {
+		t.Error("expected the render's artifacts to expire")
+	}
+
+	for i := 0; i < maxCachedRenders; i++ {
+		now = now.Add(time.Second)
+		cache.artifact(fmt.Sprintf("other-render-%d", i), "bucket/logs/job/1/build-log.txt", newArtifact)
+	}
+	if len(cache.renders) != maxCachedRenders {
+		t.Errorf("expected %d cached renders, got %d", maxCachedRenders, len(cache.renders))
+	}
+	if _, ok := cache.renders["render-2"]; ok {
+		t.Error("expected the least recently used render to be evicted")
+	}
+}
+
+func TestRenderArtifactRequests(t *testing.T) {
+	handle := &countingArtifactHandle{artifactHandle: &fakeArtifactHandle{
+		oAttrs:   &storage.ObjectAttrs{Size: 25},
+		contents: []byte("Oh wow\nlogs\nthis is\ncrazy"),
+	}}
+	artifact := NewGCSArtifact(context.Background(), handle, "", "build-log.txt", 500e6)
+	artifact.render = &render{artifacts: map[string]*GCSArtifact{}}
+	for i := 0; i < 3; i++ {
+		if _, err := artifact.ReadAtMost(4); err != nil {
+			t.Fatalf("Failed to read artifact: %v", err)
+		}
+		if content, err := artifact.ReadAll(); err != nil || string(content) != "Oh wow\nlogs\nthis is\ncrazy" {
+			t.Fatalf("expected to read the whole artifact, got %q (err: %v)", content, err)
+		}
+	}
+	if handle.attrs != 1 {
+		t.Errorf("expected the artifact's attributes to be fetched once, fetched %d times", handle.attrs)
+	}
+	if handle.reads != 4 {
+		t.Errorf("expected the artifact to be read in full once and from its start every time, made %d reads", handle.reads)
+	}
+	if artifact.render.readBytes != 25 {
+		t.Errorf("expected the render to count the 25 bytes kept, counted %d", artifact.render.readBytes)
+	}
+
+	large := &render{artifacts: map[string]*GCSArtifact{}, readBytes: maxRenderReadBytes}
+	handle = &countingArtifactHandle{artifactHandle: handle.artifactHandle}
+	artifact = NewGCSArtifact(context.Background(), handle, "", "build-log.txt", 500e6)
+	artifact.render = large
+	artifact.ReadAll()
+	artifact.ReadAll()
+	if handle.reads != 2 {
+		t.Errorf("expected content not to be kept once the render has no room, made %d reads", handle.reads)
+	}
+}
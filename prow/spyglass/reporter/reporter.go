@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reporter asks Deck to snapshot the lenses of finished jobs.
+package reporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// snapshotTimeout is how long Deck is given to render and store the lenses of a job.
+const snapshotTimeout = 5 * time.Minute
+
+// Client is a reporter client fed to crier controller
+type Client struct {
+	deckURL        string
+	tokenGenerator func() []byte
+	httpClient     *http.Client
+}
+
+// NewReporter creates a new spyglass snapshot reporter for the Deck at deckURL, which accepts
+// the token from tokenGenerator as the bearer token of snapshot requests.
+func NewReporter(deckURL string, tokenGenerator func() []byte) *Client {
+	return &Client{
+		deckURL:        strings.TrimSuffix(deckURL, "/"),
+		tokenGenerator: tokenGenerator,
+		httpClient:     &http.Client{Timeout: snapshotTimeout},
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return "spyglass-snapshot-reporter"
+}
+
+// ShouldReport tells if a prowjob should be reported by this reporter. Only finished
+// jobs that uploaded their artifacts can be snapshotted.
+func (c *Client) ShouldReport(pj *prowapi.ProwJob) bool {
+	return pj.Complete() && pj.Spec.DecorationConfig != nil && pj.Status.BuildID != ""
+}
+
+// Report asks Deck to render the lenses of a finished prowjob and store them as snapshots
+func (c *Client) Report(pj *prowapi.ProwJob) ([]*prowapi.ProwJob, error) {
+	u := fmt.Sprintf("%s/spyglass/snapshot?src=%s", c.deckURL, url.QueryEscape(snapshotSource(pj)))
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot request for %s: %v", pj.Name, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+string(c.tokenGenerator()))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request snapshot of %s: %v", pj.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to snapshot %s: %s: %s", pj.Name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return []*prowapi.ProwJob{pj}, nil
+}
+
+// snapshotSource returns the spyglass source of a prowjob's artifacts, preferring the one in
+// its job URL so that custom storage locations are respected.
+func snapshotSource(pj *prowapi.ProwJob) string {
+	if i := strings.Index(pj.Status.URL, "/view/"); i != -1 {
+		return pj.Status.URL[i+len("/view/"):]
+	}
+	return path.Join("prowjob", pj.Spec.Job, pj.Status.BuildID)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestShouldReport(t *testing.T) {
+	now := metav1.Now()
+	testCases := []struct {
+		name     string
+		pj       prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name: "finished decorated job is reported",
+			pj: prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{DecorationConfig: &prowapi.DecorationConfig{}},
+				Status: prowapi.ProwJobStatus{CompletionTime: &now, BuildID: "123"},
+			},
+			expected: true,
+		},
+		{
+			name: "running job is not reported",
+			pj: prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{DecorationConfig: &prowapi.DecorationConfig{}},
+				Status: prowapi.ProwJobStatus{BuildID: "123"},
+			},
+		},
+		{
+			name: "undecorated job is not reported",
+			pj: prowapi.ProwJob{
+				Status: prowapi.ProwJobStatus{CompletionTime: &now, BuildID: "123"},
+			},
+		},
+		{
+			name: "job without a build ID is not reported",
+			pj: prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{DecorationConfig: &prowapi.DecorationConfig{}},
+				Status: prowapi.ProwJobStatus{CompletionTime: &now},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		if actual := NewReporter("", nil).ShouldReport(&tc.pj); actual != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	testCases := []struct {
+		name        string
+		url         string
+		status      int
+		expectedSrc string
+		expectErr   bool
+	}{
+		{
+			name:        "source is taken from the job URL",
+			url:         "https://prow.example.com/view/gcs/bucket/logs/job/123",
+			status:      http.StatusOK,
+			expectedSrc: "gcs/bucket/logs/job/123",
+		},
+		{
+			name:        "source falls back to the prowjob",
+			url:         "https://example.com/job/123",
+			status:      http.StatusOK,
+			expectedSrc: "prowjob/job/123",
+		},
+		{
+			name:        "failed snapshot is an error",
+			status:      http.StatusConflict,
+			expectedSrc: "prowjob/job/123",
+			expectErr:   true,
+		},
+	}
+	for _, tc := range testCases {
+		var src string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.Path != "/spyglass/snapshot" {
+				t.Errorf("%s: unexpected request %s %s", tc.name, r.Method, r.URL.Path)
+			}
+			if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("%s: expected the token as a bearer token, got %q", tc.name, auth)
+			}
+			src = r.URL.Query().Get("src")
+			w.WriteHeader(tc.status)
+		}))
+		pj := &prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Job: "job"},
+			Status: prowapi.ProwJobStatus{URL: tc.url, BuildID: "123"},
+		}
+		reported, err := NewReporter(server.URL+"/", func() []byte { return []byte("secret") }).Report(pj)
+		server.Close()
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if src != tc.expectedSrc {
+			t.Errorf("%s: expected src %q, got %q", tc.name, tc.expectedSrc, src)
+		}
+		if len(reported) != 1 || reported[0] != pj {
+			t.Errorf("%s: expected the job to be reported, got %v", tc.name, reported)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"k8s.io/test-infra/prow/spyglass/sanitize"
+)
+
+// BodyPolicy returns the policy that the bodies the named lens renders are sanitized with, or
+// nil if the lens isn't one of the sanitized_lenses.
+func (s *Spyglass) BodyPolicy(lens string) *sanitize.Policy {
+	sanitization, ok := s.config().Deck.Spyglass.SanitizedLenses[lens]
+	if !ok {
+		return nil
+	}
+	return sanitize.NewPolicy(sanitization.Elements, sanitization.Attributes)
+}
+
+// CallbackPolicy returns the policy that the responses to the named lens's callbacks are
+// sanitized with, or nil if they aren't sanitized.
+func (s *Spyglass) CallbackPolicy(lens string) *sanitize.Policy {
+	if !s.config().Deck.Spyglass.SanitizedLenses[lens].Callbacks {
+		return nil
+	}
+	return s.BodyPolicy(lens)
+}
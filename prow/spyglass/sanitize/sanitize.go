@@ -0,0 +1,352 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sanitize filters HTML through an allow-list of elements and attributes, so that markup
+// lenses build from artifacts can't run scripts in the pages they are shown in.
+package sanitize
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// DefaultElements are the elements every policy allows, which format text, lists and tables.
+var DefaultElements = []string{
+	"a", "abbr", "b", "blockquote", "br", "caption", "code", "col", "colgroup", "dd", "del",
+	"details", "div", "dl", "dt", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img",
+	"ins", "kbd", "li", "mark", "ol", "p", "pre", "q", "s", "samp", "small", "span", "strong",
+	"sub", "summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "time", "tr", "u",
+	"ul", "var", "wbr",
+}
+
+// DefaultAttributes are the attributes every policy allows on the elements it allows.
+var DefaultAttributes = []string{
+	"alt", "class", "colspan", "datetime", "dir", "height", "href", "id", "lang", "open",
+	"rowspan", "span", "src", "start", "title", "width",
+}
+
+// forbiddenElements are never allowed, since they run or load code, or change how the rest of
+// the page is read. The content of svg and math is read differently from HTML's, so what is
+// kept of it could be read as other markup than the sanitizer saw.
+var forbiddenElements = setOf(
+	"applet", "base", "embed", "frame", "frameset", "iframe", "link", "math", "meta", "noembed",
+	"noframes", "noscript", "object", "plaintext", "script", "svg", "template", "xmp",
+)
+
+// rawTextElements hold text that isn't read as markup, up to their end tag.
+var rawTextElements = setOf(
+	"iframe", "noembed", "noframes", "noscript", "plaintext", "script", "style", "textarea",
+	"title", "xmp",
+)
+
+// voidElements have no content or end tag.
+var voidElements = setOf(
+	"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param",
+	"source", "track", "wbr",
+)
+
+// urlAttributes hold URLs, which must be relative or have a safeSchemes scheme.
+var urlAttributes = setOf(
+	"action", "background", "cite", "formaction", "href", "longdesc", "poster", "src",
+	"xlink:href",
+)
+
+var safeSchemes = setOf("http", "https", "mailto")
+
+var (
+	// attributeNameRE matches the attribute names that can be written back out.
+	attributeNameRE = regexp.MustCompile(`^[a-z][a-z0-9_:.-]*$`)
+	// entityRE matches a character reference at the start of some text.
+	entityRE = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+)
+
+// Policy is the elements and attributes allowed in sanitized HTML.
+type Policy struct {
+	elements          map[string]bool
+	attributes        map[string]bool
+	attributePrefixes []string
+}
+
+// NewPolicy returns a policy that allows the given elements and attributes along with the
+// defaults. Attributes ending in "*", such as "data-*", allow every attribute starting with
+// what comes before it. Elements that run or load code, such as script and iframe, and event
+// handler attributes are never allowed.
+func NewPolicy(elements, attributes []string) *Policy {
+	p := &Policy{elements: map[string]bool{}, attributes: map[string]bool{}}
+	for _, e := range append(append([]string{}, DefaultElements...), elements...) {
+		if e = strings.ToLower(e); !forbiddenElements[e] {
+			p.elements[e] = true
+		}
+	}
+	for _, a := range append(append([]string{}, DefaultAttributes...), attributes...) {
+		a = strings.ToLower(a)
+		if strings.HasPrefix(a, "on") {
+			continue
+		}
+		if strings.HasSuffix(a, "*") {
+			p.attributePrefixes = append(p.attributePrefixes, strings.TrimSuffix(a, "*"))
+		} else {
+			p.attributes[a] = true
+		}
+	}
+	return p
+}
+
+// Sanitize returns the HTML with every element and attribute the policy doesn't allow removed,
+// along with comments. The text of removed elements is kept, apart from that of elements whose
+// content isn't markup, such as scripts. Everything that is kept is written back out escaped,
+// and elements left open are closed. A nil policy returns the HTML as it is.
+func (p *Policy) Sanitize(s string) string {
+	if p == nil {
+		return s
+	}
+	var out strings.Builder
+	var open []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			writeText(&out, s)
+			break
+		}
+		writeText(&out, s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			s = skipPast(s[len("<!--"):], "-->")
+		case strings.HasPrefix(s, "</") && len(s) > 2 && isLetter(s[2]):
+			var name string
+			name, s = readName(s[len("</"):])
+			_, s = readAttributes(s)
+			open = closeElement(&out, open, name)
+		case len(s) > 1 && isLetter(s[1]):
+			var name, content string
+			var attrs []attribute
+			name, s = readName(s[len("<"):])
+			attrs, s = readAttributes(s)
+			if rawTextElements[name] {
+				content, s = readRawText(s, name)
+			}
+			if !p.elements[name] {
+				continue
+			}
+			p.writeStartTag(&out, name, attrs)
+			switch {
+			case name == "style":
+				// The content ends at the first thing that could end the element.
+				out.WriteString(content)
+				out.WriteString("</style>")
+			case rawTextElements[name]:
+				writeText(&out, content)
+				out.WriteString("</" + name + ">")
+			case !voidElements[name]:
+				open = append(open, name)
+			}
+		case len(s) > 1 && (s[1] == '!' || s[1] == '?' || s[1] == '/'):
+			// Doctypes, processing instructions and malformed end tags are dropped.
+			s = skipPast(s[2:], ">")
+		default:
+			out.WriteString("&lt;")
+			s = s[1:]
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
+
+type attribute struct {
+	name, value string
+}
+
+// writeStartTag writes the start tag of an allowed element with the attributes the policy
+// allows. Only the first of each attribute is kept, as browsers do.
+func (p *Policy) writeStartTag(out *strings.Builder, name string, attrs []attribute) {
+	out.WriteString("<" + name)
+	seen := map[string]bool{}
+	for _, a := range attrs {
+		if seen[a.name] || !p.allowsAttribute(a) {
+			continue
+		}
+		seen[a.name] = true
+		out.WriteString(" " + a.name + `="` + html.EscapeString(a.value) + `"`)
+	}
+	out.WriteString(">")
+}
+
+func (p *Policy) allowsAttribute(a attribute) bool {
+	if !attributeNameRE.MatchString(a.name) || strings.HasPrefix(a.name, "on") {
+		return false
+	}
+	if urlAttributes[a.name] && !safeURL(a.value) {
+		return false
+	}
+	if p.attributes[a.name] {
+		return true
+	}
+	for _, prefix := range p.attributePrefixes {
+		if strings.HasPrefix(a.name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeURL returns whether a URL is relative or has a safe scheme. Browsers ignore whitespace
+// and control characters in schemes, so they are ignored here too.
+func safeURL(u string) bool {
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	return safeSchemes[strings.ToLower(u[:i])]
+}
+
+// closeElement closes the most recently opened element with the given name, along with those
+// opened inside it. End tags of elements that aren't open are dropped.
+func closeElement(out *strings.Builder, open []string, name string) []string {
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] != name {
+			continue
+		}
+		for j := len(open) - 1; j >= i; j-- {
+			out.WriteString("</" + open[j] + ">")
+		}
+		return open[:i]
+	}
+	return open
+}
+
+// writeText writes text escaped, keeping the character references in it.
+func writeText(out *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		case '&':
+			if entity := entityRE.FindString(s[i:]); entity != "" {
+				out.WriteString(entity)
+				i += len(entity) - 1
+			} else {
+				out.WriteString("&amp;")
+			}
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+}
+
+// readName reads a lowercased tag name, returning it and the rest of the HTML.
+func readName(s string) (string, string) {
+	i := strings.IndexAny(s, " \t\n\f\r/>")
+	if i < 0 {
+		i = len(s)
+	}
+	return strings.ToLower(s[:i]), s[i:]
+}
+
+// readAttributes reads the attributes of a tag up to its end, returning them with their values
+// unescaped and the rest of the HTML after the tag.
+func readAttributes(s string) ([]attribute, string) {
+	var attrs []attribute
+	for {
+		s = strings.TrimLeft(s, " \t\n\f\r/")
+		if s == "" {
+			return attrs, s
+		}
+		if s[0] == '>' {
+			return attrs, s[1:]
+		}
+		i := strings.IndexAny(s[1:], " \t\n\f\r/>=") + 1
+		if i == 0 {
+			i = len(s)
+		}
+		a := attribute{name: strings.ToLower(s[:i])}
+		s = strings.TrimLeft(s[i:], " \t\n\f\r")
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t\n\f\r")
+			var value string
+			if s != "" && (s[0] == '"' || s[0] == '\'') {
+				end := strings.IndexByte(s[1:], s[0])
+				if end < 0 {
+					value, s = s[1:], ""
+				} else {
+					value, s = s[1:end+1], s[end+2:]
+				}
+			} else {
+				end := strings.IndexAny(s, " \t\n\f\r>")
+				if end < 0 {
+					end = len(s)
+				}
+				value, s = s[:end], s[end:]
+			}
+			a.value = html.UnescapeString(value)
+		}
+		attrs = append(attrs, a)
+	}
+}
+
+// readRawText reads the content of a raw text element up to its end tag, which is left in the
+// rest of the HTML returned.
+func readRawText(s, name string) (string, string) {
+	end := strings.Index(lowerASCII(s), "</"+name)
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// skipPast returns what comes after the first occurrence of end in s, or nothing if it doesn't
+// occur.
+func skipPast(s, end string) string {
+	i := strings.Index(s, end)
+	if i < 0 {
+		return ""
+	}
+	return s[i+len(end):]
+}
+
+// lowerASCII lowercases only ASCII letters, so that indexes into the result are indexes into s.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func setOf(items ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sanitize
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		elements   []string
+		attributes []string
+		html       string
+		expected   string
+	}{
+		{
+			name:     "allowed markup is kept",
+			html:     `<div class="log"><span title="a &amp; b">line &lt;1&gt;</span><br/></div>`,
+			expected: `<div class="log"><span title="a &amp; b">line &lt;1&gt;</span><br></div>`,
+		},
+		{
+			name:     "scripts are removed with their content",
+			html:     `<p>before<script>alert("hi")</script>after</p>`,
+			expected: `<p>beforeafter</p>`,
+		},
+		{
+			name:     "scripts can't be allowed",
+			elements: []string{"script", "SVG"},
+			html:     `<script>alert(1)</script><svg><style><img src=x onerror=alert(1)></style></svg>ok`,
+			expected: `ok`,
+		},
+		{
+			name:     "event handlers are removed",
+			html:     `<img src="a.png" onerror="alert(1)" ONLOAD=alert(1)>`,
+			expected: `<img src="a.png">`,
+		},
+		{
+			name:       "event handlers can't be allowed",
+			attributes: []string{"onclick", "on*"},
+			html:       `<b onclick="alert(1)">b</b>`,
+			expected:   `<b>b</b>`,
+		},
+		{
+			name:     "unsafe urls are removed",
+			html:     `<a href="java&#x09;script:alert(1)">a</a><a href=" JavaScript:alert(1)">b</a><a href="data:text/html,x">c</a>`,
+			expected: `<a>a</a><a>b</a><a>c</a>`,
+		},
+		{
+			name:     "safe urls are kept",
+			html:     `<a href="https://example.com/?a=1&amp;b=2">a</a><a href="artifacts/log.txt#L2">b</a><a href="a:b/c">c</a>`,
+			expected: `<a href="https://example.com/?a=1&amp;b=2">a</a><a href="artifacts/log.txt#L2">b</a><a>c</a>`,
+		},
+		{
+			name:     "unknown elements are removed with their text kept",
+			html:     `<font color="red">red</font> <blink>text</blink>`,
+			expected: `red text`,
+		},
+		{
+			name:       "configured elements and attributes are allowed",
+			elements:   []string{"Button"},
+			attributes: []string{"data-*", "style"},
+			html:       `<button data-line="2" style="color: red" type="submit">go</button>`,
+			expected:   `<button data-line="2" style="color: red">go</button>`,
+		},
+		{
+			name:     "comments and doctypes are removed",
+			html:     `<!DOCTYPE html><!-- <script>alert(1)</script> -->text<?xml version="1.0"?>`,
+			expected: `text`,
+		},
+		{
+			name:     "quoted angle brackets don't end tags",
+			html:     `<span title="a > b"><b>c</b></span>`,
+			expected: `<span title="a &gt; b"><b>c</b></span>`,
+		},
+		{
+			name:     "stray angle brackets and ampersands are escaped",
+			html:     `1 < 2 && 3 > 2 &copy; <3 </ 4>`,
+			expected: `1 &lt; 2 &amp;&amp; 3 &gt; 2 &copy; &lt;3 `,
+		},
+		{
+			name:     "unclosed elements are closed",
+			html:     `<div><table><tr><td>cell`,
+			expected: `<div><table><tr><td>cell</td></tr></table></div>`,
+		},
+		{
+			name:     "end tags of elements that aren't open are removed",
+			html:     `</div></body><p>text</b></p>`,
+			expected: `<p>text</p>`,
+		},
+		{
+			name:     "end tags close the elements inside them",
+			html:     `<div><span><b>text</div>after`,
+			expected: `<div><span><b>text</b></span></div>after`,
+		},
+		{
+			name:     "duplicate attributes keep the first",
+			html:     `<span class="a" class="b">x</span>`,
+			expected: `<span class="a">x</span>`,
+		},
+		{
+			name:     "attribute values are escaped",
+			html:     `<span title='"><script>alert(1)</script>'>x</span>`,
+			expected: `<span title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</span>`,
+		},
+		{
+			name:     "raw text of disallowed elements ends at its end tag in any case",
+			html:     `<style>b { color: red } </b></STYLE>text`,
+			expected: `text`,
+		},
+		{
+			name:     "allowed styles keep their content",
+			elements: []string{"style"},
+			html:     `<style>.a > b { color: red }</style ><b>x</b>`,
+			expected: `<style>.a > b { color: red }</style><b>x</b>`,
+		},
+		{
+			name:     "allowed text areas have their content escaped",
+			elements: []string{"textarea"},
+			html:     `<textarea><b>x</b></textarea>`,
+			expected: `<textarea>&lt;b&gt;x&lt;/b&gt;</textarea>`,
+		},
+		{
+			name:     "unterminated tags are closed",
+			html:     `<span title="x`,
+			expected: `<span title="x"></span>`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := NewPolicy(tc.elements, tc.attributes).Sanitize(tc.html); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSanitizeWithoutPolicy(t *testing.T) {
+	var p *Policy
+	if actual := p.Sanitize("<script>alert(1)</script>"); actual != "<script>alert(1)</script>" {
+		t.Errorf("expected HTML to be kept without a policy, got %q", actual)
+	}
+}
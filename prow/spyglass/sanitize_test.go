@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestLensPolicies(t *testing.T) {
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						SanitizedLenses: map[string]config.LensSanitization{
+							"junit":    {Elements: []string{"button"}},
+							"buildlog": {Callbacks: true},
+						},
+					},
+				},
+			},
+		},
+	}
+	sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSServer.Client(), context.Background())
+	html := `<button onclick="alert(1)">x</button><script>alert(2)</script>`
+	testCases := []struct {
+		lens     string
+		body     string
+		callback string
+	}{
+		{
+			lens:     "junit",
+			body:     `<button>x</button>`,
+			callback: html,
+		},
+		{
+			lens:     "buildlog",
+			body:     `x`,
+			callback: `x`,
+		},
+		{
+			lens:     "metadata",
+			body:     html,
+			callback: html,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.lens, func(t *testing.T) {
+			if body := sg.BodyPolicy(tc.lens).Sanitize(html); body != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+			if callback := sg.CallbackPolicy(tc.lens).Sanitize(html); callback != tc.callback {
+				t.Errorf("expected callback response %q, got %q", tc.callback, callback)
+			}
+		})
+	}
+}
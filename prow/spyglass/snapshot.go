@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+)
+
+// ErrNoSnapshot is returned by ReadSnapshot when a lens has no snapshot for a run.
+var ErrNoSnapshot = errors.New("no snapshot")
+
+// MatchLenses returns the artifacts each configured lens matches, by lens name.
+func (s *Spyglass) MatchLenses(artifactNames []string) map[string][]string {
+	return s.matchLenses(artifactNames, "")
+}
+
+// matchLenses returns the artifacts each configured lens matches like MatchLenses, or only those
+// the named lens matches if lens isn't empty, without matching the artifacts of other lenses.
+func (s *Spyglass) matchLenses(artifactNames []string, lens string) map[string][]string {
+	viewerCache := map[string][]string{}
+	viewersRegistry := s.config().Deck.Spyglass.Viewers
+	regexCache := s.config().Deck.Spyglass.RegexCache
+
+	for re, viewerNames := range viewersRegistry {
+		if lens != "" && !hasLens(viewerNames, lens) {
+			continue
+		}
+		matches := []string{}
+		for _, a := range artifactNames {
+			if regexCache[re].MatchString(a) {
+				matches = append(matches, a)
+			}
+		}
+		if len(matches) > 0 {
+			for _, vName := range viewerNames {
+				if lens == "" || vName == lens {
+					viewerCache[vName] = matches
+				}
+			}
+		}
+	}
+	return viewerCache
+}
+
+func hasLens(viewerNames []string, lens string) bool {
+	for _, name := range viewerNames {
+		if name == lens {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotObject returns the object a lens's snapshot for the run is stored in. Snapshots are
+// kept in the configured snapshot location, under the kind and path of the storage holding the
+// run's artifacts, rather than beside them, where the run could have written a page of its own.
+func (s *Spyglass) snapshotObject(src, lens string) (*storage.ObjectHandle, error) {
+	_, keyType, key, err := s.storage(src)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(lens, "/") {
+		return nil, fmt.Errorf("invalid lens name %q", lens)
+	}
+	if strings.Contains("/"+key+"/", "/../") {
+		return nil, fmt.Errorf("invalid src: %v", src)
+	}
+	location := strings.Trim(s.config().Deck.Spyglass.SnapshotLocation, "/")
+	if location == "" {
+		return nil, errors.New("no snapshot location is configured")
+	}
+	bucketName, prefix := extractBucketPrefixPair(location + "/")
+	bkt, err := s.Bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	return bkt.Object(path.Join(prefix, keyType, key, lens+".html")), nil
+}
+
+// ReadSnapshot returns the page a lens rendered for the run when it finished, or ErrNoSnapshot if
+// none was stored.
+func (s *Spyglass) ReadSnapshot(src, lens string) ([]byte, error) {
+	obj, err := s.snapshotObject(src, lens)
+	if err != nil {
+		return nil, err
+	}
+	r, err := obj.NewReader(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %v", err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// WriteSnapshot stores the page a lens rendered for the run, to be served instead of rendering
+// the lens again.
+func (s *Spyglass) WriteSnapshot(src, lens string, page []byte) error {
+	obj, err := s.snapshotObject(src, lens)
+	if err != nil {
+		return err
+	}
+	w := obj.NewWriter(context.Background())
+	w.ContentType = "text/html; charset=utf-8"
+	if _, err := w.Write(page); err != nil {
+		w.Close()
+		return fmt.Errorf("error writing snapshot: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error writing snapshot: %v", err)
+	}
+	return nil
+}
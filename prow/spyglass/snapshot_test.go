@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/deck/jobs"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestMatchLenses(t *testing.T) {
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						Viewers: map[string][]string{
+							`\.html$`:            {"html"},
+							`^build-log\.txt$`:   {"buildlog"},
+							`^artifacts/.*\.xml`: {"junit"},
+						},
+						RegexCache: map[string]*regexp.Regexp{
+							`\.html$`:            regexp.MustCompile(`\.html$`),
+							`^build-log\.txt$`:   regexp.MustCompile(`^build-log\.txt$`),
+							`^artifacts/.*\.xml`: regexp.MustCompile(`^artifacts/.*\.xml`),
+						},
+					},
+				},
+			},
+		},
+	}
+	sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSServer.Client(), context.Background())
+	actual := sg.MatchLenses([]string{
+		"build-log.txt",
+		"artifacts/report.html",
+	})
+	expected := map[string][]string{
+		"buildlog": {"build-log.txt"},
+		"html":     {"artifacts/report.html"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected matches %v, got %v", expected, actual)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	gcsServer := fakestorage.NewServer([]fakestorage.Object{
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/example-ci-run/403/build-log.txt",
+			Content:    []byte("Oh wow"),
+		},
+	})
+	defer gcsServer.Stop()
+	gcsServer.CreateBucket("deck-bucket")
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						Snapshots:        true,
+						SnapshotLocation: "deck-bucket/snapshots",
+					},
+				},
+			},
+		},
+	}
+	ja := jobs.NewJobAgent(fkc{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
+	sg := New(ja, fakeConfigAgent.Config, gcsServer.Client(), context.Background())
+
+	src := "gcs/test-bucket/logs/example-ci-run/403"
+	if _, err := sg.ReadSnapshot(src, "buildlog"); err != ErrNoSnapshot {
+		t.Fatalf("expected ErrNoSnapshot before writing, got %v", err)
+	}
+	if err := sg.WriteSnapshot(src, "buildlog", []byte("<html>snapshot</html>")); err != nil {
+		t.Fatalf("unexpected error writing snapshot: %v", err)
+	}
+	page, err := sg.ReadSnapshot(src+"/", "buildlog")
+	if err != nil {
+		t.Fatalf("unexpected error reading snapshot: %v", err)
+	}
+	if string(page) != "<html>snapshot</html>" {
+		t.Errorf("expected the stored page, got %q", page)
+	}
+	if _, err := sg.ReadSnapshot(src, "junit"); err != ErrNoSnapshot {
+		t.Errorf("expected ErrNoSnapshot for another lens, got %v", err)
+	}
+
+	artifacts, err := sg.ListArtifacts(src)
+	if err != nil {
+		t.Fatalf("unexpected error listing artifacts: %v", err)
+	}
+	sort.Strings(artifacts)
+	expected := []string{"build-log.txt"}
+	if !reflect.DeepEqual(artifacts, expected) {
+		t.Errorf("expected snapshots not to be stored with the run's artifacts, got %v", artifacts)
+	}
+	if _, err := gcsServer.GetObject("deck-bucket", "snapshots/gcs/test-bucket/logs/example-ci-run/403/buildlog.html"); err != nil {
+		t.Errorf("expected the snapshot in the snapshot location: %v", err)
+	}
+
+	if err := sg.WriteSnapshot(src, "../buildlog", nil); err == nil {
+		t.Error("expected an error for a lens name containing a slash")
+	}
+	if err := sg.WriteSnapshot("invalid/src", "buildlog", nil); err == nil {
+		t.Error("expected an error for an invalid src")
+	}
+	if err := sg.WriteSnapshot("gcs/test-bucket/logs/../../other-bucket/403", "buildlog", nil); err == nil {
+		t.Error("expected an error for a src leaving its bucket")
+	}
+
+	unconfigured := New(ja, fca{}.Config, gcsServer.Client(), context.Background())
+	if _, err := unconfigured.ReadSnapshot(src, "buildlog"); err == nil || err == ErrNoSnapshot {
+		t.Errorf("expected an error without a snapshot location, got %v", err)
+	}
+}
@@ -21,8 +21,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/url"
 	"path"
 	"sort"
 	"strconv"
@@ -43,6 +41,9 @@ import (
 const (
 	gcsKeyType  = "gcs"
 	prowKeyType = "prowjob"
+	// prefixKeyType is for storage prefixes under the browsable prefixes that hold artifacts
+	// other than the runs of ProwJobs, such as debug bundles uploaded by hand.
+	prefixKeyType = "prefix"
 )
 
 // Spyglass records which sets of artifacts need views for a Prow job. The metaphor
@@ -57,6 +58,12 @@ type Spyglass struct {
 
 	config   config.Getter
 	testgrid *TestGrid
+	// renderID identifies the page render the artifacts are fetched for, if any.
+	renderID string
+	// fetchers are the artifact fetchers registered for each key type, and schemes are the key
+	// types that storage URLs of each scheme resolve to.
+	fetchers map[string]ArtifactFetcher
+	schemes  map[string]string
 
 	*GCSArtifactFetcher
 	*PodLogArtifactFetcher
@@ -66,6 +73,16 @@ type Spyglass struct {
 type LensRequest struct {
 	Source    string   `json:"src"`
 	Artifacts []string `json:"artifacts"`
+	// CompareSource and CompareArtifacts are set when the lens compares the run in Source to
+	// another run of the job.
+	CompareSource    string   `json:"compare_src,omitempty"`
+	CompareArtifacts []string `json:"compare_artifacts,omitempty"`
+	// RenderID identifies the page render the request is made for. Requests made for the same
+	// render share the artifacts they fetch.
+	RenderID string `json:"render_id,omitempty"`
+	// Page is the page of the artifacts the lens matches that Artifacts are, when the lens matches
+	// more than are shown at once. Snapshots, which show all of them, are only shown on the first.
+	Page int `json:"page,omitempty"`
 }
 
 // ExtraLink represents an extra link to be added to the Spyglass page.
@@ -77,23 +94,41 @@ type ExtraLink struct {
 
 // New constructs a Spyglass object from a JobAgent, a config.Agent, and a storage Client.
 func New(ja *jobs.JobAgent, cfg config.Getter, c *storage.Client, ctx context.Context) *Spyglass {
-	return &Spyglass{
+	af := NewGCSArtifactFetcher(c)
+	af.config = cfg
+	sg := &Spyglass{
 		JobAgent:              ja,
 		config:                cfg,
 		PodLogArtifactFetcher: NewPodLogArtifactFetcher(ja),
-		GCSArtifactFetcher:    NewGCSArtifactFetcher(c),
+		GCSArtifactFetcher:    af,
 		testgrid: &TestGrid{
 			conf:   cfg,
 			client: c,
 			ctx:    ctx,
 		},
+		fetchers: map[string]ArtifactFetcher{},
+		schemes:  map[string]string{},
 	}
+	sg.RegisterFetcher(gcsKeyType, gcsFetcher{af: af}, "gs")
+	return sg
 }
 
 func (sg *Spyglass) Start() {
 	sg.testgrid.Start()
 }
 
+// ForRender returns a Spyglass that fetches artifacts for the page render with the given ID,
+// sharing their handles, attributes and small contents with every other request made for the
+// render. An empty ID returns s, which fetches artifacts afresh for every request.
+func (s *Spyglass) ForRender(renderID string) *Spyglass {
+	if renderID == "" {
+		return s
+	}
+	rendered := *s
+	rendered.renderID = renderID
+	return &rendered
+}
+
 // Lenses gets all views of all artifact files matching each regexp with a registered lens
 func (s *Spyglass) Lenses(matchCache map[string][]string) []lenses.Lens {
 	ls := []lenses.Lens{}
@@ -124,7 +159,15 @@ func (s *Spyglass) Lenses(matchCache map[string][]string) []lenses.Lens {
 	return ls
 }
 
+// ResolveSymlink returns the src of the run that src links to, if it is a symlink to another run,
+// or src otherwise. Runs may also be given as storage URLs, which are resolved to their srcs.
 func (s *Spyglass) ResolveSymlink(src string) (string, error) {
+	if isStorageURL(src) {
+		var err error
+		if src, err = s.SourceForURL(src); err != nil {
+			return "", err
+		}
+	}
 	src = strings.TrimSuffix(src, "/")
 	keyType, key, err := splitSrc(src)
 	if err != nil {
@@ -133,39 +176,28 @@ func (s *Spyglass) ResolveSymlink(src string) (string, error) {
 	switch keyType {
 	case prowKeyType:
 		return src, nil // prowjob keys cannot be symlinks.
-	case gcsKeyType:
-		parts := strings.SplitN(key, "/", 2)
-		if len(parts) != 2 {
-			return "", fmt.Errorf("gcs path should have both a bucket and a path")
-		}
-		bucketName := parts[0]
-		prefix := parts[1]
-		bkt := s.client.Bucket(bucketName)
-		obj := bkt.Object(prefix + ".txt")
-		reader, err := obj.NewReader(context.Background())
-		if err != nil {
-			return src, nil
+	case prefixKeyType:
+		if _, err := s.prefixKey(key); err != nil {
+			return "", err
 		}
-		// Avoid using ReadAll here to prevent an attacker forcing us to read a giant file into memory.
-		bytes := make([]byte, 4096) // assume we won't get more than 4 kB of symlink to read
-		n, err := reader.Read(bytes)
-		if err != nil && err != io.EOF {
-			return "", fmt.Errorf("failed to read symlink file (which does seem to exist): %v", err)
-		}
-		if n == len(bytes) {
-			return "", fmt.Errorf("symlink destination exceeds length limit of %d bytes", len(bytes)-1)
-		}
-		u, err := url.Parse(string(bytes[:n]))
-		if err != nil {
-			return "", fmt.Errorf("failed to parse URL: %v", err)
-		}
-		if u.Scheme != "gs" {
-			return "", fmt.Errorf("expected gs:// symlink, got '%s://'", u.Scheme)
-		}
-		return path.Join(gcsKeyType, u.Host, u.Path), nil
-	default:
+		return src, nil
+	}
+	fetcher, ok := s.fetchers[keyType]
+	if !ok {
 		return "", fmt.Errorf("unknown src key type %q", keyType)
 	}
+	reader, ok := fetcher.(symlinkReader)
+	if !ok {
+		return src, nil
+	}
+	target, err := reader.readSymlink(key)
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return src, nil
+	}
+	return s.SourceForURL(target)
 }
 
 // JobPath returns a link to the GCS directory for the job specified in src
@@ -177,20 +209,6 @@ func (s *Spyglass) JobPath(src string) (string, error) {
 	}
 	split := strings.Split(key, "/")
 	switch keyType {
-	case gcsKeyType:
-		if len(split) < 4 {
-			return "", fmt.Errorf("invalid key %s: expected <bucket-name>/<log-type>/.../<job-name>/<build-id>", key)
-		}
-		// see https://github.com/kubernetes/test-infra/tree/master/gubernator
-		bktName := split[0]
-		logType := split[1]
-		jobName := split[len(split)-2]
-		if logType == gcs.NonPRLogs {
-			return path.Dir(key), nil
-		} else if logType == gcs.PRLogs {
-			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
-		}
-		return "", fmt.Errorf("unrecognized GCS key: %s", key)
 	case prowKeyType:
 		if len(split) < 2 {
 			return "", fmt.Errorf("invalid key %s: expected <job-name>/<build-id>", key)
@@ -212,26 +230,32 @@ func (s *Spyglass) JobPath(src string) (string, error) {
 			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
 		}
 		return path.Join(bktName, gcs.NonPRLogs, jobName), nil
+	case prefixKeyType:
+		return "", fmt.Errorf("%s is a storage prefix, not the run of a job", src)
 	default:
-		return "", fmt.Errorf("unrecognized key type for src: %v", src)
+		if _, ok := s.fetchers[keyType]; !ok {
+			return "", fmt.Errorf("unrecognized key type for src: %v", src)
+		}
+		if len(split) < 4 {
+			return "", fmt.Errorf("invalid key %s: expected <bucket-name>/<log-type>/.../<job-name>/<build-id>", key)
+		}
+		// see https://github.com/kubernetes/test-infra/tree/master/gubernator
+		bktName := split[0]
+		logType := split[1]
+		jobName := split[len(split)-2]
+		if logType == gcs.NonPRLogs {
+			return path.Dir(key), nil
+		} else if logType == gcs.PRLogs {
+			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
+		}
+		return "", fmt.Errorf("unrecognized storage key: %s", key)
 	}
 }
 
-// RunPath returns the path to the GCS directory for the job run specified in src.
+// RunPath returns the path to the storage directory for the job run specified in src.
 func (s *Spyglass) RunPath(src string) (string, error) {
-	src = strings.TrimSuffix(src, "/")
-	keyType, key, err := splitSrc(src)
-	if err != nil {
-		return "", fmt.Errorf("error parsing src: %v", src)
-	}
-	switch keyType {
-	case gcsKeyType:
-		return key, nil
-	case prowKeyType:
-		return s.prowToGCS(key)
-	default:
-		return "", fmt.Errorf("unrecognized key type for src: %v", src)
-	}
+	_, _, key, err := s.storage(src)
+	return key, err
 }
 
 // RunToPR returns the (org, repo, pr#) tuple referenced by the provided src.
@@ -247,7 +271,26 @@ func (s *Spyglass) RunToPR(src string) (string, string, int, error) {
 		return "", "", 0, fmt.Errorf("expected more URL components in %q", src)
 	}
 	switch keyType {
-	case gcsKeyType:
+	case prowKeyType:
+		if len(split) < 2 {
+			return "", "", 0, fmt.Errorf("invalid key %s: expected <job-name>/<build-id>", key)
+		}
+		jobName := split[0]
+		buildID := split[1]
+		job, err := s.jobAgent.GetProwJob(jobName, buildID)
+		if err != nil {
+			return "", "", 0, fmt.Errorf("failed to get prow job from src %q: %v", key, err)
+		}
+		if job.Spec.Refs == nil || len(job.Spec.Refs.Pulls) == 0 {
+			return "", "", 0, fmt.Errorf("no PRs on job %q", job.Name)
+		}
+		return job.Spec.Refs.Org, job.Spec.Refs.Repo, job.Spec.Refs.Pulls[0].Number, nil
+	case prefixKeyType:
+		return "", "", 0, fmt.Errorf("%s is a storage prefix, not the run of a job", src)
+	default:
+		if _, ok := s.fetchers[keyType]; !ok {
+			return "", "", 0, fmt.Errorf("unrecognized key type for src: %v", src)
+		}
 		// In theory, we could derive this information without trying to parse the URL by instead fetching the
 		// data from uploaded artifacts. In practice, that would not be a great solution: it would require us
 		// to try pulling two different metadata files (one for bootstrap and one for podutils), then parse them
@@ -294,23 +337,98 @@ func (s *Spyglass) RunToPR(src string) (string, string, int, error) {
 		} else {
 			return "", "", 0, fmt.Errorf("unknown log type: %q", logType)
 		}
-	case prowKeyType:
-		if len(split) < 2 {
-			return "", "", 0, fmt.Errorf("invalid key %s: expected <job-name>/<build-id>", key)
+	}
+}
+
+// RunToRepo returns the (org, repo) pair that the job referenced by the provided src ran against.
+// For jobs without an associated PR, this is read from the refs in the job's prowjob.json.
+func (s *Spyglass) RunToRepo(src string) (string, string, error) {
+	if org, repo, _, err := s.RunToPR(src); err == nil {
+		return org, repo, nil
+	}
+	job, err := s.prowJob(src)
+	if err != nil {
+		return "", "", err
+	}
+	return jobRepo(job)
+}
+
+// jobRepo returns the (org, repo) pair that a ProwJob ran against.
+func jobRepo(job *prowapi.ProwJob) (string, string, error) {
+	if job.Spec.Refs != nil {
+		return job.Spec.Refs.Org, job.Spec.Refs.Repo, nil
+	}
+	if len(job.Spec.ExtraRefs) > 0 {
+		return job.Spec.ExtraRefs[0].Org, job.Spec.ExtraRefs[0].Repo, nil
+	}
+	return "", "", fmt.Errorf("job %s has no refs", job.Spec.Job)
+}
+
+// LensConfig returns the configuration for the named lens when viewing the job referenced
+// by src, taking into account any overrides for the repo the job ran against, the options
+// for the lens in the job's annotations and which of the lens's features are turned on.
+func (s *Spyglass) LensConfig(lens, src string) json.RawMessage {
+	sc := s.config().Deck.Spyglass
+	job, err := s.prowJob(src)
+	if err != nil {
+		logrus.WithError(err).WithField("src", src).Debug("Couldn't find ProwJob for lens config.")
+		job = &prowapi.ProwJob{}
+	}
+	var org, repo string
+	if len(sc.RepoLensConfig) > 0 || len(sc.LensFeatures[lens]) > 0 {
+		if org, repo, _, err = s.RunToPR(src); err != nil {
+			if org, repo, err = jobRepo(job); err != nil {
+				logrus.WithError(err).WithField("src", src).Debug("Couldn't determine repo for lens config.")
+			}
 		}
-		jobName := split[0]
-		buildID := split[1]
-		job, err := s.jobAgent.GetProwJob(jobName, buildID)
-		if err != nil {
-			return "", "", 0, fmt.Errorf("failed to get prow job from src %q: %v", key, err)
+	}
+	rawConfig := MergeLensConfig(lens, sc.LensConfigFor(lens, org, repo), job.Annotations)
+	return withLensFeatures(lens, rawConfig, sc.LensFeatures[lens], org, repo)
+}
+
+// AccessRules returns the access rules that restrict the run referenced by src, following it if
+// it is a symlink. The org of the run's job is only looked up when some rule restricts orgs. When
+// a rule needs where the run is stored or the org of its job and that can't be determined, an
+// error is returned, so that the run is refused rather than escaping the rule. Jobs without refs
+// belong to no org.
+func (s *Spyglass) AccessRules(src string) ([]config.SpyglassAccessRule, error) {
+	sc := s.config().Deck.Spyglass
+	if len(sc.Access) == 0 {
+		return nil, nil
+	}
+	src, err := s.ResolveSymlink(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve src: %v", err)
+	}
+	// Rules are matched against the path and org the src appears to name, which a "." or ".."
+	// segment could make differ from the run that is read.
+	if hasDotSegment(src) {
+		return nil, fmt.Errorf("invalid src: %v", src)
+	}
+	var needsPath, needsOrg bool
+	for _, rule := range sc.Access {
+		needsPath = needsPath || len(rule.BucketPrefixes) > 0
+		needsOrg = needsOrg || len(rule.Orgs) > 0
+	}
+	var runPath string
+	if needsPath {
+		if runPath, err = s.RunPath(src); err != nil {
+			return nil, fmt.Errorf("failed to determine where the run is stored: %v", err)
 		}
-		if job.Spec.Refs == nil || len(job.Spec.Refs.Pulls) == 0 {
-			return "", "", 0, fmt.Errorf("no PRs on job %q", job.Name)
+		// Rules match where runs are stored, whichever bucket alias they are linked to under.
+		runPath = s.StoragePath(path.Clean(runPath))
+	}
+	var org string
+	if needsOrg {
+		if org, _, _, err = s.RunToPR(src); err != nil {
+			job, err := s.prowJob(src)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine the org of the run's job: %v", err)
+			}
+			org, _, _ = jobRepo(job)
 		}
-		return job.Spec.Refs.Org, job.Spec.Refs.Repo, job.Spec.Refs.Pulls[0].Number, nil
-	default:
-		return "", "", 0, fmt.Errorf("unrecognized key type for src: %v", src)
 	}
+	return sc.AccessRulesFor(runPath, org), nil
 }
 
 // ExtraLinks fetches started.json and extracts links from metadata.links.
@@ -18,6 +18,7 @@ package spyglass
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
@@ -30,6 +31,7 @@ import (
 	"github.com/fsouza/fake-gcs-server/fakestorage"
 	"github.com/sirupsen/logrus"
 	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
@@ -77,6 +79,11 @@ func TestMain(m *testing.M) {
 		longLog += "here a log\nthere a log\neverywhere a log log\n"
 	}
 	fakeGCSServer = fakestorage.NewServer([]fakestorage.Object{
+		{
+			BucketName: "test-bucket",
+			Name:       "debug-bundles/2019-06-01/node-a/kubelet.log",
+			Content:    []byte("kubelet started"),
+		},
 		{
 			BucketName: "test-bucket",
 			Name:       "logs/example-ci-run/403/build-log.txt",
@@ -181,11 +188,11 @@ func (dumpLens) Config() lenses.LensConfig {
 	}
 }
 
-func (dumpLens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+func (dumpLens) Header(artifacts []lenses.Artifact, resourceDir string, config json.RawMessage) string {
 	return ""
 }
 
-func (dumpLens) Body(artifacts []lenses.Artifact, resourceDir, data string) string {
+func (dumpLens) Body(artifacts []lenses.Artifact, resourceDir, data string, config json.RawMessage) string {
 	var view []byte
 	for _, a := range artifacts {
 		data, err := a.ReadAll()
@@ -198,7 +205,7 @@ func (dumpLens) Body(artifacts []lenses.Artifact, resourceDir, data string) stri
 	return string(view)
 }
 
-func (dumpLens) Callback(artifacts []lenses.Artifact, resourceDir, data string) string {
+func (dumpLens) Callback(artifacts []lenses.Artifact, resourceDir, data string, config json.RawMessage) string {
 	return ""
 }
 
@@ -1119,6 +1126,201 @@ func TestFetchArtifactsPodLog(t *testing.T) {
 	}
 }
 
+func TestFetchSiblingArtifacts(t *testing.T) {
+	sg := New(fakeJa, fca{}.Config, fakeGCSServer.Client(), context.Background())
+	siblings, err := sg.FetchSiblingArtifacts("gcs/test-bucket/logs/example-ci-run/403", 500e6, []string{"build-log.txt", "junit_01.xml"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := map[string]bool{}
+	for _, a := range siblings {
+		if _, ok := a.(*lenses.SiblingArtifact); !ok {
+			t.Errorf("Expected %s to be a sibling artifact, got %T", a.JobPath(), a)
+		}
+		found[a.JobPath()] = true
+	}
+	for _, name := range []string{"long-log.txt", "started.json", "finished.json"} {
+		if !found[name] {
+			t.Errorf("Expected sibling %s, got %v", name, found)
+		}
+	}
+	for _, name := range []string{"build-log.txt", "junit_01.xml"} {
+		if found[name] {
+			t.Errorf("Expected matched artifact %s not to be a sibling", name)
+		}
+	}
+}
+
+func TestListArtifactsStorageUnavailable(t *testing.T) {
+	c := fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		BucketAliases: map[string]config.BucketAlias{"unreadable": {Bucket: "test-bucket", CredentialsFile: "/no/such/credentials.json"}},
+	}}}}}
+	sg := New(fakeJa, c.Config, fakeGCSServer.Client(), context.Background())
+	if names, err := sg.ListArtifacts("gcs/unreadable/logs/example-ci-run/403"); err == nil {
+		t.Errorf("Expected an error listing unreadable storage, got %v", names)
+	}
+	names, err := sg.ListArtifacts("gcs/test-bucket/logs/no-such-run/1")
+	if err != nil {
+		t.Fatalf("Unexpected error listing a run without artifacts: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"build-log.txt"}) {
+		t.Errorf("Expected only the build log to be listed for a run without artifacts, got %v", names)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	c := fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		BrowsablePrefixes: []string{"gs://test-bucket/debug-bundles/", "gs://test-bucket/debug"},
+	}}}}}
+	sg := New(fakeJa, c.Config, fakeGCSServer.Client(), context.Background())
+	src := "prefix/test-bucket/debug-bundles/2019-06-01"
+	if !IsPrefix(src) || IsPrefix("gcs/test-bucket/debug-bundles/2019-06-01") {
+		t.Error("Expected only prefix srcs to be prefixes")
+	}
+
+	names, err := sg.ListArtifacts(src)
+	if err != nil {
+		t.Fatalf("Unexpected error listing a browsable prefix: %v", err)
+	}
+	if expected := []string{"node-a/kubelet.log"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the artifacts under the prefix without a build log, %v, got %v", expected, names)
+	}
+	artifacts, err := sg.FetchArtifacts(src, "", 500e6, []string{"node-a/kubelet.log", "build-log.txt"})
+	if err != nil {
+		t.Fatalf("Unexpected error fetching artifacts under a browsable prefix: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].JobPath() != "node-a/kubelet.log" {
+		t.Errorf("Expected only the artifact found under the prefix, got %v", artifacts)
+	}
+	if resolved, err := sg.ResolveSymlink(src + "/"); err != nil || resolved != src {
+		t.Errorf("Expected the prefix to resolve to itself, got %q (err: %v)", resolved, err)
+	}
+	if runPath, err := sg.RunPath(src); err != nil || runPath != "test-bucket/debug-bundles/2019-06-01" {
+		t.Errorf("Expected the prefix to be stored under its path, got %q (err: %v)", runPath, err)
+	}
+	if _, err := sg.JobPath(src); err == nil {
+		t.Error("Expected prefixes to have no job")
+	}
+
+	for _, other := range []string{
+		"prefix/test-bucket/logs/example-ci-run/403",
+		"prefix/test-bucket/debug-bundles/../logs/example-ci-run/403",
+		"prefix/test-bucket/debug-bundles",
+		"prefix/test-bucket/debugging/2019-06-01",
+	} {
+		if _, err := sg.ListArtifacts(other); err == nil {
+			t.Errorf("Expected an error listing %s, which isn't under a browsable prefix", other)
+		}
+		if _, err := sg.ResolveSymlink(other); err == nil {
+			t.Errorf("Expected an error resolving %s, which isn't under a browsable prefix", other)
+		}
+	}
+}
+
+func TestStatArtifacts(t *testing.T) {
+	sg := New(fakeJa, fca{}.Config, fakeGCSServer.Client(), context.Background())
+	stats, err := sg.StatArtifacts("gcs/test-bucket/logs/example-ci-run/403", []string{"started.json", "no-such-artifact.txt", "build-log.txt"})
+	if err != nil {
+		t.Fatalf("Unexpected error stating artifacts: %v", err)
+	}
+	var names []string
+	for _, stat := range stats {
+		names = append(names, stat.Name)
+	}
+	if expected := []string{"started.json", "build-log.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected the artifacts that exist in the order named, %v, got %v", expected, names)
+	}
+	if len(stats) == 2 && stats[1].Size != 25 {
+		t.Errorf("Expected build-log.txt to be 25 bytes, got %d", stats[1].Size)
+	}
+
+	all, err := sg.StatArtifacts("gcs/test-bucket/logs/example-ci-run/403", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error stating every artifact: %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("Expected every artifact of the run to be stated, got %v", all)
+	}
+
+	if _, err := sg.StatArtifacts("wtf/what-is-this/send-help", nil); err == nil {
+		t.Error("Expected an error stating the artifacts of an invalid src")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := metav1.Now()
+	kc := fkc{
+		prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Agent: prowapi.KubernetesAgent, Job: "finished-job"},
+			Status: prowapi.ProwJobStatus{BuildID: "1", State: prowapi.FailureState, CompletionTime: &now},
+		},
+		prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Agent: prowapi.KubernetesAgent, Job: "pending-job"},
+			Status: prowapi.ProwJobStatus{BuildID: "2", State: prowapi.PendingState},
+		},
+	}
+	agent := config.Agent{}
+	ja := jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, agent.Config)
+	ja.Start()
+	c := fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		BucketAliases: map[string]config.BucketAlias{"unreadable": {Bucket: "test-bucket", CredentialsFile: "/no/such/credentials.json"}},
+	}}}}}
+	sg := New(ja, c.Config, fakeGCSServer.Client(), context.Background())
+
+	testCases := []struct {
+		name       string
+		src        string
+		expExpired bool
+		expState   prowapi.ProwJobState
+	}{
+		{
+			name: "runs with artifacts have not expired",
+			src:  "gcs/test-bucket/logs/example-ci-run/403",
+		},
+		{
+			name:       "runs without artifacts or a ProwJob have expired",
+			src:        "gcs/test-bucket/logs/example-ci-run/402",
+			expExpired: true,
+		},
+		{
+			name:       "finished runs without artifacts have expired",
+			src:        "gcs/test-bucket/logs/finished-job/1",
+			expExpired: true,
+			expState:   prowapi.FailureState,
+		},
+		{
+			name: "runs that haven't started yet have not expired",
+			src:  "gcs/test-bucket/logs/pending-job/2",
+		},
+		{
+			name: "runs whose storage can't be read have not expired",
+			src:  "gcs/unreadable/logs/example-ci-run/402",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run, expired := sg.Expired(tc.src)
+			if expired != tc.expExpired {
+				t.Fatalf("Expected expired to be %t, got %t", tc.expExpired, expired)
+			}
+			if !expired {
+				return
+			}
+			jobName, buildID, _ := sg.KeyToJob(tc.src)
+			if run.JobName != jobName || run.BuildID != buildID {
+				t.Errorf("Expected run %s #%s, got %s #%s", jobName, buildID, run.JobName, run.BuildID)
+			}
+			var state prowapi.ProwJobState
+			if run.ProwJob != nil {
+				state = run.ProwJob.Status.State
+			}
+			if state != tc.expState {
+				t.Errorf("Expected the ProwJob's state to be %q, got %q", tc.expState, state)
+			}
+		})
+	}
+}
+
 func TestKeyToJob(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -1240,6 +1442,21 @@ func TestResolveSymlink(t *testing.T) {
 			path:      "gcs/hi",
 			expectErr: true,
 		},
+		{
+			name:   "gs:// URL of a symlink is resolved",
+			path:   "gs://test-bucket/logs/symlink-party/123",
+			result: "gcs/test-bucket/logs/the-actual-place/123",
+		},
+		{
+			name:   "https URL of a run is resolved",
+			path:   "https://storage.googleapis.com/test-bucket/better-logs/42/",
+			result: "gcs/test-bucket/better-logs/42",
+		},
+		{
+			name:      "URL of storage Spyglass can't read is an error",
+			path:      "s3://test-bucket/better-logs/42",
+			expectErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1358,3 +1575,355 @@ func TestExtraLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestPreviousRun(t *testing.T) {
+	passed := []byte(`{"passed": true}`)
+	failed := []byte(`{"passed": false}`)
+	objects := []fakestorage.Object{
+		{BucketName: "test-bucket", Name: "logs/periodic/100/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "logs/periodic/101/finished.json", Content: failed},
+		{BucketName: "test-bucket", Name: "logs/periodic/102/finished.json", Content: failed},
+		{BucketName: "test-bucket", Name: "logs/periodic/103/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "pr-logs/directory/presubmit/7.txt", Content: []byte("gs://test-bucket/pr-logs/pull/org_repo/1/presubmit/7")},
+		{BucketName: "test-bucket", Name: "pr-logs/directory/presubmit/9.txt", Content: []byte("gs://test-bucket/pr-logs/pull/org_repo/2/presubmit/9")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/1/presubmit/7/finished.json", Content: []byte(`{"result": "SUCCESS"}`)},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/2/presubmit/9/finished.json", Content: failed},
+	}
+	testCases := []struct {
+		name        string
+		src         string
+		passing     bool
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "previous run",
+			src:      "gcs/test-bucket/logs/periodic/103",
+			expected: "gcs/test-bucket/logs/periodic/102",
+		},
+		{
+			name:     "previous passing run",
+			src:      "gcs/test-bucket/logs/periodic/103",
+			passing:  true,
+			expected: "gcs/test-bucket/logs/periodic/100",
+		},
+		{
+			name:        "no earlier run",
+			src:         "gcs/test-bucket/logs/periodic/100",
+			expectedErr: true,
+		},
+		{
+			name:     "presubmit runs are found through the directory links",
+			src:      "gcs/test-bucket/pr-logs/pull/org_repo/3/presubmit/10",
+			passing:  true,
+			expected: "gcs/test-bucket/pr-logs/pull/org_repo/1/presubmit/7",
+		},
+	}
+	gcsServer := fakestorage.NewServer(objects)
+	defer gcsServer.Stop()
+	fakeConfigAgent := fca{}
+	ja := jobs.NewJobAgent(fkc{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
+	sg := New(ja, fakeConfigAgent.Config, gcsServer.Client(), context.Background())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := sg.PreviousRun(tc.src, tc.passing)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBaselineRun(t *testing.T) {
+	passed := []byte(`{"passed": true}`)
+	failed := []byte(`{"passed": false}`)
+	started := func(timestamp int) []byte { return []byte(fmt.Sprintf(`{"timestamp": %d}`, timestamp)) }
+	presubmit := func(job, annotation string) []byte {
+		return []byte(fmt.Sprintf(`{"metadata": {"annotations": {"spyglass.prow.k8s.io/baseline-job": %q}}, "spec": {"type": "presubmit", "job": %q}, "status": {"startTime": "2019-06-01T00:00:00Z"}}`, annotation, job))
+	}
+	june := 1559347200 // 2019-06-01T00:00:00Z
+	objects := []fakestorage.Object{
+		{BucketName: "test-bucket", Name: "logs/ci-job/1/started.json", Content: started(june - 7200)},
+		{BucketName: "test-bucket", Name: "logs/ci-job/1/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "logs/ci-job/2/started.json", Content: started(june - 3600)},
+		{BucketName: "test-bucket", Name: "logs/ci-job/2/finished.json", Content: failed},
+		{BucketName: "test-bucket", Name: "logs/ci-job/3/started.json", Content: started(june + 3600)},
+		{BucketName: "test-bucket", Name: "logs/ci-job/3/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "logs/other-ci-job/5/started.json", Content: started(june - 60)},
+		{BucketName: "test-bucket", Name: "logs/other-ci-job/5/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "logs/periodic/100/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "logs/periodic/101/finished.json", Content: failed},
+		{BucketName: "test-bucket", Name: "pr-logs/directory/presubmit/7.txt", Content: []byte("gs://test-bucket/pr-logs/pull/org_repo/1/presubmit/7")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/1/presubmit/7/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/2/presubmit/8/prowjob.json", Content: presubmit("presubmit", "")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/2/presubmit/9/prowjob.json", Content: presubmit("presubmit", "other-ci-job")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/2/unmapped/9/prowjob.json", Content: presubmit("unmapped", "")},
+		{BucketName: "test-bucket", Name: "pr-logs/directory/unmapped/4.txt", Content: []byte("gs://test-bucket/pr-logs/pull/org_repo/1/unmapped/4")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/1/unmapped/4/finished.json", Content: passed},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/2/broken/9/prowjob.json", Content: presubmit("broken", "missing-ci-job")},
+		{BucketName: "test-bucket", Name: "pr-logs/directory/broken/3.txt", Content: []byte("gs://test-bucket/pr-logs/pull/org_repo/1/broken/3")},
+		{BucketName: "test-bucket", Name: "pr-logs/pull/org_repo/1/broken/3/finished.json", Content: passed},
+	}
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "periodic runs are compared to their previous passing run",
+			src:      "gcs/test-bucket/logs/periodic/101",
+			expected: "gcs/test-bucket/logs/periodic/100",
+		},
+		{
+			name:     "presubmits are compared to the latest passing run of their baseline job started before them",
+			src:      "gcs/test-bucket/pr-logs/pull/org_repo/2/presubmit/8",
+			expected: "gcs/test-bucket/logs/ci-job/1",
+		},
+		{
+			name:     "the baseline job annotation overrides the config",
+			src:      "gcs/test-bucket/pr-logs/pull/org_repo/2/presubmit/9",
+			expected: "gcs/test-bucket/logs/other-ci-job/5",
+		},
+		{
+			name:     "presubmits without a baseline job are compared to their previous passing run",
+			src:      "gcs/test-bucket/pr-logs/pull/org_repo/2/unmapped/9",
+			expected: "gcs/test-bucket/pr-logs/pull/org_repo/1/unmapped/4",
+		},
+		{
+			name:     "presubmits whose baseline job has no passing run are compared to their previous passing run",
+			src:      "gcs/test-bucket/pr-logs/pull/org_repo/2/broken/9",
+			expected: "gcs/test-bucket/pr-logs/pull/org_repo/1/broken/3",
+		},
+	}
+	gcsServer := fakestorage.NewServer(objects)
+	defer gcsServer.Stop()
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						BaselineJobs: map[string]string{"presubmit": "ci-job"},
+					},
+				},
+			},
+		},
+	}
+	sg := New(nil, fakeConfigAgent.Config, gcsServer.Client(), context.Background())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := sg.BaselineRun(tc.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLensConfig(t *testing.T) {
+	objects := []fakestorage.Object{
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/periodic/1/prowjob.json",
+			Content:    []byte(`{"spec": {"extra_refs": [{"org": "kubernetes", "repo": "test-infra"}]}}`),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/periodic/2/prowjob.json",
+			Content:    []byte(`{"spec": {"refs": {"org": "kubernetes", "repo": "kubernetes"}}}`),
+		},
+	}
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "repo is read from the extra refs in prowjob.json",
+			src:      "gcs/test-bucket/logs/periodic/1",
+			expected: `{"a":"repo"}`,
+		},
+		{
+			name:     "repo is read from the refs in prowjob.json",
+			src:      "gcs/test-bucket/logs/periodic/2",
+			expected: `{"a":"org"}`,
+		},
+		{
+			name:     "jobs without prowjob.json get the default",
+			src:      "gcs/test-bucket/logs/periodic/3",
+			expected: `{"a":"default"}`,
+		},
+	}
+	gcsServer := fakestorage.NewServer(objects)
+	defer gcsServer.Stop()
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					Spyglass: config.Spyglass{
+						LensConfig: map[string]json.RawMessage{"buildlog": json.RawMessage(`{"a":"default"}`)},
+						RepoLensConfig: map[string]map[string]json.RawMessage{
+							"kubernetes":            {"buildlog": json.RawMessage(`{"a":"org"}`)},
+							"kubernetes/test-infra": {"buildlog": json.RawMessage(`{"a":"repo"}`)},
+						},
+					},
+				},
+			},
+		},
+	}
+	ja := jobs.NewJobAgent(fkc{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
+	sg := New(ja, fakeConfigAgent.Config, gcsServer.Client(), context.Background())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := string(sg.LensConfig("buildlog", tc.src)); actual != tc.expected {
+				t.Errorf("expected config %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAccessRules(t *testing.T) {
+	objects := []fakestorage.Object{
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/periodic/1/prowjob.json",
+			Content:    []byte(`{"spec": {"extra_refs": [{"org": "private-org", "repo": "secrets"}]}}`),
+		},
+		{
+			BucketName: "test-bucket",
+			Name:       "logs/periodic/2/prowjob.json",
+			Content:    []byte(`{"spec": {"job": "no-refs"}}`),
+		},
+	}
+	gcsServer := fakestorage.NewServer(objects)
+	defer gcsServer.Stop()
+	orgRule := config.SpyglassAccessRule{Orgs: []string{"private-org"}, Users: []string{"alice"}}
+	prefixRule := config.SpyglassAccessRule{BucketPrefixes: []string{"test-bucket/logs/team-a-"}, Users: []string{"bob"}}
+	testCases := []struct {
+		name        string
+		rules       []config.SpyglassAccessRule
+		src         string
+		expected    []config.SpyglassAccessRule
+		expectedErr bool
+	}{
+		{
+			name: "no rules",
+			src:  "gcs/test-bucket/logs/periodic/3",
+		},
+		{
+			name:     "org is read from prowjob.json",
+			rules:    []config.SpyglassAccessRule{orgRule},
+			src:      "gcs/test-bucket/logs/periodic/1",
+			expected: []config.SpyglassAccessRule{orgRule},
+		},
+		{
+			name:  "jobs without refs belong to no org",
+			rules: []config.SpyglassAccessRule{orgRule},
+			src:   "gcs/test-bucket/logs/periodic/2",
+		},
+		{
+			name:        "runs whose org can't be determined are refused",
+			rules:       []config.SpyglassAccessRule{orgRule},
+			src:         "gcs/test-bucket/logs/periodic/3",
+			expectedErr: true,
+		},
+		{
+			name:     "runs are matched by where they are stored",
+			rules:    []config.SpyglassAccessRule{prefixRule},
+			src:      "gcs/test-bucket/logs/team-a-job/3",
+			expected: []config.SpyglassAccessRule{prefixRule},
+		},
+		{
+			name:        "runs whose path can't be determined are refused",
+			rules:       []config.SpyglassAccessRule{prefixRule},
+			src:         "prowjob/no-such-job/1",
+			expectedErr: true,
+		},
+		{
+			name:        "srcs that could name another run than they appear to are refused",
+			rules:       []config.SpyglassAccessRule{orgRule},
+			src:         "gcs/test-bucket/pr-logs/pull/public-org_repo/1/job/2/../../../../../../logs/periodic/1",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeConfigAgent := fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{Access: tc.rules}}}}}
+			ja := jobs.NewJobAgent(fkc{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
+			sg := New(ja, fakeConfigAgent.Config, gcsServer.Client(), context.Background())
+			actual, err := sg.AccessRules(tc.src)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got rules %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected rules %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckVisibleWith(t *testing.T) {
+	teamA := config.SpyglassAccessRule{BucketPrefixes: []string{"test-bucket/logs/team-a-"}, MembersOf: []string{"team-a"}}
+	teamB := config.SpyglassAccessRule{BucketPrefixes: []string{"test-bucket/logs/team-b-"}, MembersOf: []string{"team-b"}}
+	testCases := []struct {
+		name        string
+		other       string
+		src         string
+		expectedErr bool
+	}{
+		{
+			name:  "unrestricted runs are visible with any run",
+			other: "gcs/test-bucket/logs/public/1",
+			src:   "gcs/test-bucket/logs/team-a-job/2",
+		},
+		{
+			name:  "runs are visible with runs restricted by the same rules",
+			other: "gcs/test-bucket/logs/team-a-job/1",
+			src:   "gcs/test-bucket/logs/team-a-other-job/2",
+		},
+		{
+			name:        "restricted runs aren't visible with unrestricted runs",
+			other:       "gcs/test-bucket/logs/team-a-job/1",
+			src:         "gcs/test-bucket/logs/public/2",
+			expectedErr: true,
+		},
+		{
+			name:        "restricted runs aren't visible with runs restricted by other rules",
+			other:       "gcs/test-bucket/logs/team-a-job/1",
+			src:         "gcs/test-bucket/logs/team-b-job/2",
+			expectedErr: true,
+		},
+	}
+	c := fca{c: config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Spyglass: config.Spyglass{
+		Access: []config.SpyglassAccessRule{teamA, teamB},
+	}}}}}
+	sg := New(fakeJa, c.Config, fakeGCSServer.Client(), context.Background())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sg.CheckVisibleWith(tc.other, tc.src)
+			if tc.expectedErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
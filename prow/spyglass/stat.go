@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"time"
+)
+
+// ArtifactStat is what storage knows about an artifact without reading it.
+type ArtifactStat struct {
+	Name            string    `json:"name"`
+	Size            int64     `json:"size"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentEncoding string    `json:"content_encoding,omitempty"`
+	Generation      int64     `json:"generation"`
+	Updated         time.Time `json:"updated"`
+}
+
+// StatArtifacts returns what storage knows about the named artifacts of the run in src that
+// exist, in the order they are named, or about every artifact of the run if none are named. The
+// artifacts are stated together, mostly from one listing of the run's artifacts, rather than with
+// a request for each.
+func (s *Spyglass) StatArtifacts(src string, artifactNames []string) ([]ArtifactStat, error) {
+	fetcher, _, key, err := s.storage(src)
+	if err != nil {
+		return nil, err
+	}
+	stater, ok := fetcher.(artifactStater)
+	if !ok {
+		return nil, fmt.Errorf("the storage of %s can't tell what it knows about artifacts", src)
+	}
+	if len(artifactNames) == 0 {
+		if artifactNames, err = fetcher.Artifacts(key); err != nil {
+			return nil, fmt.Errorf("failed to read storage: %v", err)
+		}
+	}
+	attrs, err := stater.stat(key, artifactNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage: %v", err)
+	}
+	stats := []ArtifactStat{}
+	for _, name := range artifactNames {
+		a, ok := attrs[name]
+		if !ok {
+			continue
+		}
+		stats = append(stats, ArtifactStat{
+			Name:            name,
+			Size:            a.Size,
+			ContentType:     a.ContentType,
+			ContentEncoding: a.ContentEncoding,
+			Generation:      a.Generation,
+			Updated:         a.Updated,
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// gcsHosts are the hosts serving GCS objects over HTTPS, whose paths start with the bucket.
+var gcsHosts = map[string]bool{
+	"storage.googleapis.com":   true,
+	"storage.cloud.google.com": true,
+}
+
+// isStorageURL returns whether a run is given as a storage URL rather than as a src.
+func isStorageURL(src string) bool {
+	return strings.Contains(src, "://")
+}
+
+// SourceForURL returns the src of the run stored under a storage URL, such as
+// gs://bucket/logs/job/123 or https://storage.googleapis.com/bucket/logs/job/123. URLs of storage
+// that no fetcher is registered for, such as s3:// and file:// URLs by default, are rejected.
+func (s *Spyglass) SourceForURL(storageURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(storageURL))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %v", err)
+	}
+	bucket, object := u.Host, u.Path
+	keyType, ok := s.schemes[u.Scheme]
+	if u.Scheme == httpsScheme && gcsHosts[u.Host] {
+		keyType, ok = gcsKeyType, true
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		bucket, object = parts[0], ""
+		if len(parts) == 2 {
+			object = parts[1]
+		}
+	}
+	if !ok {
+		return "", s.unsupportedStorage(storageURL)
+	}
+	if bucket == "" || strings.Trim(object, "/") == "" {
+		return "", fmt.Errorf("storage URL %s should name both a bucket and a path", storageURL)
+	}
+	return path.Join(keyType, bucket, object), nil
+}
+
+// bucketName returns the name of a bucket that may be given as a gs:// URL.
+func bucketName(bucket string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(bucket, "gs://"), "/")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSourceForURL(t *testing.T) {
+	testCases := []struct {
+		name      string
+		url       string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "gs:// URL",
+			url:      "gs://kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+			expected: "gcs/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+		},
+		{
+			name:     "gs:// URL with a trailing slash",
+			url:      "gs://kubernetes-jenkins/logs/ci-kubernetes-e2e/123/",
+			expected: "gcs/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+		},
+		{
+			name:     "https URL of GCS",
+			url:      "https://storage.googleapis.com/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+			expected: "gcs/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+		},
+		{
+			name:     "https URL of the GCS console",
+			url:      "https://storage.cloud.google.com/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+			expected: "gcs/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+		},
+		{
+			name:      "https URL of another site",
+			url:       "https://example.com/kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+			expectErr: true,
+		},
+		{
+			name:      "s3:// URL",
+			url:       "s3://kubernetes-jenkins/logs/ci-kubernetes-e2e/123",
+			expectErr: true,
+		},
+		{
+			name:      "file:// URL",
+			url:       "file:///tmp/logs/ci-kubernetes-e2e/123",
+			expectErr: true,
+		},
+		{
+			name:      "URL of a bucket",
+			url:       "gs://kubernetes-jenkins/",
+			expectErr: true,
+		},
+	}
+	sg := New(nil, fca{}.Config, nil, context.Background())
+	for _, tc := range testCases {
+		src, err := sg.SourceForURL(tc.url)
+		if err != nil {
+			if !tc.expectErr {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if tc.expectErr {
+			t.Errorf("%s: expected an error, got %q", tc.name, src)
+			continue
+		}
+		if src != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, src)
+		}
+	}
+}
@@ -1070,7 +1070,10 @@ func TestTakeAction(t *testing.T) {
 			presubmits: map[int][]config.Presubmit{
 				100: {
 					{Reporter: config.Reporter{Context: "foo"}},
-					{Reporter: config.Reporter{Context: "if-changed"}},
+					{
+						JobBase:  config.JobBase{Annotations: map[string]string{"spyglass.prow.k8s.io/lenses": "junit"}},
+						Reporter: config.Reporter{Context: "if-changed"},
+					},
 				},
 			},
 			merged:    0,
@@ -1298,9 +1301,25 @@ func TestTakeAction(t *testing.T) {
 			switch action := action.(type) {
 			case clienttesting.CreateActionImpl:
 				numCreated++
-				if prowJob, ok := action.Object.(*prowapi.ProwJob); ok && prowJob.Spec.Type == prowapi.BatchJob {
+				prowJob, ok := action.Object.(*prowapi.ProwJob)
+				if !ok {
+					continue
+				}
+				if prowJob.Spec.Type == prowapi.BatchJob {
 					batchJobs = append(batchJobs, prowJob)
 				}
+				for _, presubmits := range tc.presubmits {
+					for _, ps := range presubmits {
+						if ps.Context != prowJob.Spec.Context {
+							continue
+						}
+						for k, v := range ps.Annotations {
+							if actual := prowJob.Annotations[k]; actual != v {
+								t.Errorf("Expected annotation %s=%s on the %s job, got %q.", k, v, ps.Context, actual)
+							}
+						}
+					}
+				}
 			}
 		}
 		if tc.triggered != numCreated {
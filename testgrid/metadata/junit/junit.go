@@ -33,26 +33,38 @@ type Suites struct {
 
 // Suite holds <testsuite/> results
 type Suite struct {
-	XMLName  xml.Name `xml:"testsuite"`
-	Name     string   `xml:"name,attr"`
-	Time     float64  `xml:"time,attr"` // Seconds
-	Failures int      `xml:"failures,attr"`
-	Tests    int      `xml:"tests,attr"`
-	Results  []Result `xml:"testcase"`
-	/*
-	* <properties><property name="go.version" value="go1.8.3"/></properties>
-	 */
+	XMLName   xml.Name `xml:"testsuite"`
+	Name      string   `xml:"name,attr"`
+	Time      float64  `xml:"time,attr"`      // Seconds
+	Timestamp string   `xml:"timestamp,attr"` // Start time, if recorded
+	Failures  int      `xml:"failures,attr"`
+	Tests     int      `xml:"tests,attr"`
+	Results   []Result `xml:"testcase"`
+	// <properties><property name="go.version" value="go1.8.3"/></properties>
+	Properties *Properties `xml:"properties,omitempty"`
+}
+
+// Properties holds a <properties/> list of Property values
+type Properties struct {
+	PropertyList []Property `xml:"property"`
+}
+
+// Property holds a <property/> name and value
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
 // Result holds <testcase/> results
 type Result struct {
-	Name      string  `xml:"name,attr"`
-	Time      float64 `xml:"time,attr"`
-	ClassName string  `xml:"classname,attr"`
-	Failure   *string `xml:"failure,omitempty"`
-	Output    *string `xml:"system-out,omitempty"`
-	Error     *string `xml:"system-err,omitempty"`
-	Skipped   *string `xml:"skipped,omitempty"`
+	Name       string      `xml:"name,attr"`
+	Time       float64     `xml:"time,attr"`
+	ClassName  string      `xml:"classname,attr"`
+	Failure    *string     `xml:"failure,omitempty"`
+	Output     *string     `xml:"system-out,omitempty"`
+	Error      *string     `xml:"system-err,omitempty"`
+	Skipped    *string     `xml:"skipped,omitempty"`
+	Properties *Properties `xml:"properties,omitempty"`
 }
 
 // Message extracts the message for the junit test case.